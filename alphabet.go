@@ -0,0 +1,147 @@
+package bitstream
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Alphabet maps fixed-size groups of bits to characters, like the alphabets of Base64 and Base32.
+// The n-bit group of value v is represented by the v-th character of the alphabet.
+type Alphabet struct {
+	nBits uint8
+	chars string
+	index [256]int16 // character -> value, or -1
+}
+
+// Well-known alphabets.
+var (
+	// Base64Alphabet is the standard Base64 alphabet (RFC 4648), 6 bits per character.
+	Base64Alphabet = MustNewAlphabet("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/")
+
+	// Base64URLAlphabet is the URL and filename safe Base64 alphabet (RFC 4648), 6 bits per character.
+	Base64URLAlphabet = MustNewAlphabet("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_")
+
+	// Base32Alphabet is the standard Base32 alphabet (RFC 4648), 5 bits per character.
+	Base32Alphabet = MustNewAlphabet("ABCDEFGHIJKLMNOPQRSTUVWXYZ234567")
+
+	// Base32HexAlphabet is the "Extended Hex" Base32 alphabet (RFC 4648), 5 bits per character.
+	Base32HexAlphabet = MustNewAlphabet("0123456789ABCDEFGHIJKLMNOPQRSTUV")
+
+	// GeohashAlphabet is the alphabet of Geohash, 5 bits per character.
+	GeohashAlphabet = MustNewAlphabet("0123456789bcdefghjkmnpqrstuvwxyz")
+
+	// HexAlphabet is the lower case hexadecimal digits, 4 bits per character.
+	HexAlphabet = MustNewAlphabet("0123456789abcdef")
+)
+
+// NewAlphabet creates a new Alphabet from its characters (bytes) in the order of their values.
+// The number of characters must be a power of 2 from 2 to 256, which determines the number of bits per character,
+// and the characters must be distinct. Otherwise it returns ErrInvalidAlphabet.
+func NewAlphabet(chars string) (*Alphabet, error) {
+	n := len(chars)
+	if n < 2 || n > 256 || n&(n-1) != 0 {
+		return nil, fmt.Errorf("%w: %d characters", ErrInvalidAlphabet, n)
+	}
+
+	a := &Alphabet{
+		nBits: uint8(bits.TrailingZeros(uint(n))),
+		chars: chars,
+	}
+	for i := range a.index {
+		a.index[i] = -1
+	}
+	for i := 0; i < n; i++ {
+		c := chars[i]
+		if a.index[c] >= 0 {
+			return nil, fmt.Errorf("%w: duplicate character %q", ErrInvalidAlphabet, c)
+		}
+		a.index[c] = int16(i)
+	}
+	return a, nil
+}
+
+// MustNewAlphabet is like NewAlphabet but panics if the alphabet is invalid.
+func MustNewAlphabet(chars string) *Alphabet {
+	a, err := NewAlphabet(chars)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Bits returns the number of bits per character.
+func (a *Alphabet) Bits() uint8 {
+	return a.nBits
+}
+
+// Char returns the character which represents `v`. `v` must be less than 2^Bits().
+func (a *Alphabet) Char(v uint8) byte {
+	return a.chars[v]
+}
+
+// Value returns the value represented by `c`, or false if `c` is not in the alphabet.
+func (a *Alphabet) Value(c byte) (uint8, bool) {
+	v := a.index[c]
+	return uint8(v), v >= 0
+}
+
+// ReadAlphabet reads `n` groups of bits and returns them as characters of the alphabet `a`.
+func (r *Reader) ReadAlphabet(a *Alphabet, n int) (string, error) {
+	pos := r.BitPosition()
+	s, err := r.readAlphabet(a, n)
+	if err != nil {
+		return "", r.wrapError("ReadAlphabet", pos, err)
+	}
+	r.trace("", pos, uint(n)*uint(a.nBits), s)
+	return s, nil
+}
+
+func (r *Reader) readAlphabet(a *Alphabet, n int) (string, error) {
+	err := r.checkBitsPerCall(uint64(n) * uint64(a.nBits))
+	if err == nil {
+		err = r.allocate(uint64(n))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	s := make([]byte, n)
+	for i := range s {
+		v, err := r.readUint(a.nBits, 8)
+		if err != nil {
+			if i > 0 {
+				return "", unexpectedEOF(err)
+			}
+			return "", err
+		}
+		s[i] = a.chars[v]
+	}
+	return string(s), nil
+}
+
+// WriteAlphabet writes each character of `s` as a group of bits of its value in the alphabet `a`.
+// If `s` has a character which is not in the alphabet, it returns ErrInvalidCharacter without writing anything.
+func (w *Writer) WriteAlphabet(a *Alphabet, s string) error {
+	pos := w.bitPosition()
+	err := w.writeAlphabet(a, s)
+	if err != nil {
+		return w.wrapError("WriteAlphabet", pos, err)
+	}
+	w.trace("", pos, uint(len(s))*uint(a.nBits), s)
+	return nil
+}
+
+func (w *Writer) writeAlphabet(a *Alphabet, s string) error {
+	for i := 0; i < len(s); i++ {
+		if a.index[s[i]] < 0 {
+			return fmt.Errorf("%w: %q at %d", ErrInvalidCharacter, s[i], i)
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		err := w.writeNBitsOfUint8(a.nBits, uint8(a.index[s[i]]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
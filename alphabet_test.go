@@ -0,0 +1,77 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadAlphabet(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Data     []byte
+		Alphabet *Alphabet
+		N        int
+		Expected string
+	}{
+		{Name: "pattern 1", Data: []byte("Man"), Alphabet: Base64Alphabet, N: 4, Expected: "TWFu"},
+		{Name: "pattern 2", Data: []byte{0xfb, 0xff}, Alphabet: Base64URLAlphabet, N: 2, Expected: "-_"},              // 1111 1011  1111 1111
+		{Name: "pattern 3", Data: []byte("f"), Alphabet: Base32Alphabet, N: 1, Expected: "M"},                         // 01100 110
+		{Name: "pattern 4", Data: []byte{0x6f, 0xf0, 0x41, 0x00}, Alphabet: GeohashAlphabet, N: 5, Expected: "ezs42"}, // 01101 11111 11000 00100 00010 0...
+		{Name: "pattern 5", Data: []byte{0xde, 0xad}, Alphabet: HexAlphabet, N: 4, Expected: "dead"},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data.Data), nil)
+			actual, err := r.ReadAlphabet(data.Alphabet, data.N)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.Expected != actual {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, actual)
+			}
+
+			// round trip
+			buf := bytes.NewBuffer([]byte{})
+			w := NewWriter(buf)
+			err = w.WriteAlphabet(data.Alphabet, actual)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if uint64(data.N)*uint64(data.Alphabet.Bits()) != w.WrittenBits() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", uint64(data.N)*uint64(data.Alphabet.Bits()), w.WrittenBits())
+			}
+			w.Finalize()
+			r = NewReader(buf, nil)
+			actual, err = r.ReadAlphabet(data.Alphabet, data.N)
+			if err != nil || data.Expected != actual {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v, %+v\n", data.Expected, actual, err)
+			}
+		})
+	}
+}
+
+func TestAlphabetError(t *testing.T) {
+	for _, chars := range []string{"", "a", "abc", "abca"} {
+		_, err := NewAlphabet(chars)
+		if !errors.Is(err, ErrInvalidAlphabet) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidAlphabet, err)
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	w := NewWriter(buf)
+	err := w.WriteAlphabet(GeohashAlphabet, "ezs4a") // 'a' is not used by geohash
+	if !errors.Is(err, ErrInvalidCharacter) || w.WrittenBits() != 0 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", ErrInvalidCharacter, 0, err, w.WrittenBits())
+	}
+
+	r := NewReader(bytes.NewReader([]byte{0x12}), nil)
+	_, err = r.ReadAlphabet(Base32Alphabet, 2)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
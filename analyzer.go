@@ -0,0 +1,164 @@
+package bitstream
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// defaultAnalyzerMaxValues is the default number of distinct values kept in the histogram of a field.
+const defaultAnalyzerMaxValues = 1 << 16
+
+// Analyzer collects the bit usage of the fields read from a Reader or written to a Writer, e.g. to find which fields
+// of a codec waste bits. Set its Trace method as the TraceHook of the Reader or the Writer; the fields are grouped
+// by their names, and the unnamed ones by the call sites if CallSites is true.
+//
+// For each group, it counts the bits used and keeps the histogram of the values, from which it estimates the entropy,
+// i.e. the bits an ideal code for the values seen would use. An Analyzer must not be used by multiple goroutines.
+type Analyzer struct {
+	// CallSites makes the fields without names grouped by the file and the line which called the method of the Reader
+	// or the Writer, e.g. "parser.go:42". Otherwise they are all grouped as "".
+	CallSites bool
+
+	// MaxValues is the number of distinct values kept in the histogram of a group. The values seen after it is full
+	// are counted only in the bits; the entropy is then underestimated, see FieldUsage.Truncated. Default: 65536.
+	MaxValues int
+
+	usages []*FieldUsage
+	index  map[string]*FieldUsage
+}
+
+// FieldUsage is the bit usage of a group of fields collected by an Analyzer.
+type FieldUsage struct {
+	Name      string         // name of the fields, or their call site
+	Count     uint64         // number of fields
+	Bits      uint64         // total number of bits of the fields
+	Histogram map[any]uint64 // number of the fields for each value
+	Truncated bool           // true if some values are not in Histogram as it is full
+}
+
+// Trace records a field. It has the signature of TraceHook.
+func (a *Analyzer) Trace(name string, bitOffset uint64, nBits uint, value any) {
+	if name == "" && a.CallSites {
+		name = callSite()
+	}
+	u, ok := a.index[name]
+	if !ok {
+		if a.index == nil {
+			a.index = map[string]*FieldUsage{}
+		}
+		u = &FieldUsage{Name: name, Histogram: map[any]uint64{}}
+		a.index[name] = u
+		a.usages = append(a.usages, u)
+	}
+
+	u.Count++
+	u.Bits += uint64(nBits)
+	key := histogramKey(value)
+	if _, ok := u.Histogram[key]; ok || len(u.Histogram) < a.maxValues() {
+		u.Histogram[key]++
+	} else {
+		u.Truncated = true
+	}
+}
+
+func (a *Analyzer) maxValues() int {
+	if a.MaxValues <= 0 {
+		return defaultAnalyzerMaxValues
+	}
+	return a.MaxValues
+}
+
+// Usages returns the bit usage of each group in the order of their first fields.
+func (a *Analyzer) Usages() []*FieldUsage {
+	return a.usages
+}
+
+// Reset discards the usages collected so far.
+func (a *Analyzer) Reset() {
+	a.usages = nil
+	a.index = nil
+}
+
+// WriteReport writes a table of the usages to `w`, one line for each group:
+//
+//	FIELD    COUNT  BITS  BITS/FIELD  ENTROPY  ESTIMATED  WASTE
+//	version  100    400   4.00        0.00     0          400
+//
+// ENTROPY is in bits per field, ESTIMATED is the bits an ideal code would use (ENTROPY * COUNT),
+// and WASTE is BITS - ESTIMATED. A group whose histogram is truncated is marked with '*'.
+func (a *Analyzer) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tCOUNT\tBITS\tBITS/FIELD\tENTROPY\tESTIMATED\tWASTE")
+	for _, u := range a.usages {
+		name := u.Name
+		if u.Truncated {
+			name += "*"
+		}
+		est := u.EstimatedBits()
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%.0f\t%.0f\n", name, u.Count, u.Bits,
+			float64(u.Bits)/float64(u.Count), u.Entropy(), est, float64(u.Bits)-est)
+	}
+	return tw.Flush()
+}
+
+// Entropy returns the Shannon entropy of the values in bits per field, i.e. the average number of bits
+// an ideal code for the distribution of the values seen would use.
+func (u *FieldUsage) Entropy() float64 {
+	total := uint64(0)
+	for _, n := range u.Histogram {
+		total += n
+	}
+	h := 0.0
+	for _, n := range u.Histogram {
+		p := float64(n) / float64(total)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// EstimatedBits returns the number of bits an ideal code would use for the fields, i.e. Entropy * Count.
+func (u *FieldUsage) EstimatedBits() float64 {
+	return u.Entropy() * float64(u.Count)
+}
+
+// histogramKey returns `v` as a key of a histogram. []byte values are converted to their hexadecimal form,
+// and the other values which cannot be a map key to their fmt.Sprint form.
+func histogramKey(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return hex.EncodeToString(v)
+	case nil, bool, string, uint8, uint16, uint32, uint64, uint, int8, int16, int32, int64, int, float32, float64:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// analyzerPackagePrefix is the prefix of the names of the functions in this package.
+var analyzerPackagePrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name() // e.g. github.com/bearmini/bitstream-go.init.func1
+	slash := strings.LastIndex(name, "/")
+	return name[:slash+strings.Index(name[slash:], ".")+1]
+}()
+
+// callSite returns the file and the line of the first caller outside this package, e.g. "parser.go:42".
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs) // skip runtime.Callers, callSite and Analyzer.Trace
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, analyzerPackagePrefix) || strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", filepath.Base(f.File), f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
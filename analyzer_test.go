@@ -0,0 +1,134 @@
+package bitstream
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzer(t *testing.T) {
+	a := &Analyzer{}
+	buf := &bytes.Buffer{}
+	w := NewWriterWithOptions(buf, &WriterOptions{TraceHook: a.Trace})
+	for i := 0; i < 8; i++ {
+		// version is always the same, kind has 4 values of the same frequency, and len has 8 values
+		sw := NewStickyWriter(w)
+		sw.WriteNamed("version", 4, 2).WriteNamed("kind", 8, uint64(i%4)).WriteNamed("len", 16, uint64(i))
+		if sw.Err() != nil {
+			t.Fatalf("unexpected error: %+v\n", sw.Err())
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	expected := []struct {
+		Name    string
+		Count   uint64
+		Bits    uint64
+		Entropy float64
+	}{
+		{Name: "version", Count: 8, Bits: 32, Entropy: 0},
+		{Name: "kind", Count: 8, Bits: 64, Entropy: 2},
+		{Name: "len", Count: 8, Bits: 128, Entropy: 3},
+	}
+	usages := a.Usages()
+	if len(expected) != len(usages) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", len(expected), len(usages))
+	}
+	for i, e := range expected {
+		u := usages[i]
+		if e.Name != u.Name || e.Count != u.Count || e.Bits != u.Bits || math.Abs(e.Entropy-u.Entropy()) > 1e-9 {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v %+v\n", e, u, u.Entropy())
+		}
+	}
+	if usages[1].EstimatedBits() != 16 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 16, usages[1].EstimatedBits())
+	}
+
+	// the same statistics for the reader side
+	ra := &Analyzer{}
+	r := NewReader(bytes.NewReader(buf.Bytes()), &ReaderOptions{TraceHook: ra.Trace})
+	for i := 0; i < 8; i++ {
+		for _, f := range []struct {
+			name  string
+			nBits uint8
+		}{{"version", 4}, {"kind", 8}, {"len", 16}} {
+			_, err := r.ReadNamed(f.name, f.nBits)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+		}
+	}
+	for i, u := range ra.Usages() {
+		if u.Name != usages[i].Name || u.Bits != usages[i].Bits || u.Entropy() != usages[i].Entropy() {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", usages[i], u)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	err = a.WriteReport(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expectedReport := `FIELD    COUNT  BITS  BITS/FIELD  ENTROPY  ESTIMATED  WASTE
+version  8      32    4.00        0.00     0          32
+kind     8      64    8.00        2.00     16         48
+len      8      128   16.00       3.00     24         104
+`
+	if expectedReport != out.String() {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expectedReport, out.String())
+	}
+
+	a.Reset()
+	if len(a.Usages()) != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, len(a.Usages()))
+	}
+}
+
+func TestAnalyzerCallSites(t *testing.T) {
+	a := &Analyzer{CallSites: true}
+	r := NewReader(bytes.NewReader([]byte{0x12, 0x34, 0x56, 0x78}), &ReaderOptions{TraceHook: a.Trace})
+	for i := 0; i < 2; i++ {
+		_, err := r.ReadUint8() // the first call site
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		_, err = r.ReadBytes(1) // the second call site
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+
+	usages := a.Usages()
+	if len(usages) != 2 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 2, len(usages))
+	}
+	for _, u := range usages {
+		if !strings.HasPrefix(u.Name, "analyzer_test.go:") || u.Count != 2 || u.Bits != 16 {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "analyzer_test.go:N with 2 fields of 16 bits", u)
+		}
+	}
+	if usages[0].Name == usages[1].Name {
+		t.Fatalf("the call sites are not distinguished: %+v\n", usages[0].Name)
+	}
+	if usages[1].Histogram["34"] != 1 || usages[1].Histogram["78"] != 1 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "34 and 78", usages[1].Histogram)
+	}
+}
+
+func TestAnalyzerMaxValues(t *testing.T) {
+	a := &Analyzer{MaxValues: 2}
+	for i := 0; i < 4; i++ {
+		a.Trace("f", 0, 8, uint8(i%3))
+	}
+	u := a.Usages()[0]
+	if !u.Truncated || len(u.Histogram) != 2 || u.Count != 4 || u.Bits != 32 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "truncated histogram of 2 values", u)
+	}
+	if u.Histogram[uint8(0)] != 2 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 2, u.Histogram[uint8(0)])
+	}
+}
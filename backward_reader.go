@@ -0,0 +1,122 @@
+package bitstream
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+)
+
+// BackwardReader is a bit stream reader which consumes the bits of a buffer from the end toward the beginning,
+// as required by the bit streams of zstd/FSE and some audio codecs.
+//
+// The buffer is regarded as a little endian integer, i.e. bit 0 is the LSB of the first byte, and the bits are read
+// from the most significant one down to bit 0. The first bit read by a call is the MSB of the value returned, so the fields
+// written by BackwardWriter are read in the reverse order with the same values.
+//
+// A BackwardReader is not safe for concurrent use by multiple goroutines.
+type BackwardReader struct {
+	data []byte
+	pos  uint64 // number of bits which have not been read yet, i.e. the index of the next bit to be read + 1
+	size uint64 // number of bits in the bit stream
+}
+
+// NewBackwardReader creates a new BackwardReader which reads all the bits of `data` from the last one.
+// `data` is read in place and must not be modified while the BackwardReader is in use.
+func NewBackwardReader(data []byte) *BackwardReader {
+	size := uint64(len(data)) * 8
+	return &BackwardReader{
+		data: data,
+		pos:  size,
+		size: size,
+	}
+}
+
+// NewBackwardReaderSentinel creates a new BackwardReader for a bit stream finalized with a sentinel,
+// like the ones of zstd: the padding zeros and the '1' bit at the top of the last byte are skipped.
+// It returns ErrNoSentinel if `data` is empty or its last byte is 0.
+func NewBackwardReaderSentinel(data []byte) (*BackwardReader, error) {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		return nil, ErrNoSentinel
+	}
+	br := NewBackwardReader(data)
+	br.pos -= uint64(bits.LeadingZeros8(data[len(data)-1])) + 1
+	br.size = br.pos
+	return br, nil
+}
+
+// BitPosition returns the number of bits that has been consumed from the end of the bit stream.
+func (br *BackwardReader) BitPosition() uint64 {
+	return br.size - br.pos
+}
+
+// BitsRemaining returns the number of bits which have not been read yet.
+func (br *BackwardReader) BitsRemaining() uint64 {
+	return br.pos
+}
+
+// ReadBit reads a single bit from the bit stream.
+// The bit read from the stream will be set in the LSB of the return value.
+func (br *BackwardReader) ReadBit() (byte, error) {
+	if br.pos == 0 {
+		return 0, wrapError("ReadBit", br.BitPosition(), io.EOF)
+	}
+	br.pos--
+	return br.data[br.pos/8] >> (br.pos % 8) & 0x01, nil
+}
+
+// ReadBits reads `nBits` (<= 64) bits from the bit stream and returns them LSB aligned.
+// If fewer than `nBits` bits are left, it returns io.ErrUnexpectedEOF (or io.EOF if no bits are left) and nothing is consumed.
+// If `nBits` == 0, this function always returns 0.
+func (br *BackwardReader) ReadBits(nBits uint8) (uint64, error) {
+	v, err := br.peekBits(nBits)
+	if err != nil {
+		return 0, wrapError("ReadBits", br.BitPosition(), err)
+	}
+	br.pos -= uint64(nBits)
+	return v, nil
+}
+
+// PeekBits returns the next `nBits` (<= 64) bits of the bit stream (LSB aligned) without consuming them.
+// It returns the same errors as ReadBits.
+func (br *BackwardReader) PeekBits(nBits uint8) (uint64, error) {
+	v, err := br.peekBits(nBits)
+	return v, wrapError("PeekBits", br.BitPosition(), err)
+}
+
+func (br *BackwardReader) peekBits(nBits uint8) (uint64, error) {
+	if nBits > 64 {
+		return 0, errTooManyBitsForUint64
+	}
+	if nBits == 0 {
+		return 0, nil
+	}
+	if br.pos == 0 {
+		return 0, io.EOF
+	}
+	if uint64(nBits) > br.pos {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	// load 8 bytes from the byte which contains the lowest bit of the field into a 64-bit register.
+	// the 9th byte is needed only when the field straddles it.
+	start := br.pos - uint64(nBits)
+	i := start / 8
+	shift := start % 8
+	v := loadUint64LE(br.data, i) >> shift
+	if shift+uint64(nBits) > 64 {
+		v |= uint64(br.data[i+8]) << (64 - shift)
+	}
+	return v & (1<<nBits - 1), nil
+}
+
+// loadUint64LE loads up to 8 bytes of `data` from `i` as a little endian integer. The bytes beyond the end are zeros.
+func loadUint64LE(data []byte, i uint64) uint64 {
+	if i+8 <= uint64(len(data)) {
+		return binary.LittleEndian.Uint64(data[i:])
+	}
+	v := uint64(0)
+	for j := uint64(len(data)); j > i; j-- {
+		v = v<<8 | uint64(data[j-1])
+	}
+	return v
+}
@@ -0,0 +1,108 @@
+package bitstream
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBackwardReader(t *testing.T) {
+	// as a little endian integer: 001 1110010 1001010000 1111 (0x3ca50f)
+	data := []byte{0x0f, 0xa5, 0x3c}
+	br := NewBackwardReader(data)
+
+	testData := []struct {
+		NBits    uint8
+		Expected uint64
+	}{
+		{NBits: 3, Expected: 0x1},    // 001
+		{NBits: 7, Expected: 0x72},   // 111 0010
+		{NBits: 0, Expected: 0},      //
+		{NBits: 10, Expected: 0x250}, // 10 0101 0000
+		{NBits: 4, Expected: 0xf},    // 1111
+	}
+	for _, data := range testData {
+		v, err := br.ReadBits(data.NBits)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if v != data.Expected {
+			t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Expected, v)
+		}
+	}
+	if br.BitPosition() != 24 || br.BitsRemaining() != 0 {
+		t.Fatalf("\nExpected: 24, 0\nActual:   %+v, %+v\n", br.BitPosition(), br.BitsRemaining())
+	}
+	_, err := br.ReadBit()
+	if err != io.EOF {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+}
+
+func TestBackwardReaderWide(t *testing.T) {
+	data := []byte{0xef, 0xcd, 0xab, 0x89, 0x67, 0x45, 0x23, 0x01, 0xff, 0x80}
+	for skip := uint8(0); skip < 16; skip++ {
+		br := NewBackwardReader(data)
+		_, err := br.ReadBits(skip)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+
+		// compare with the bits read one by one
+		expected := NewBackwardReader(data)
+		expected.ReadBits(skip)
+		e := uint64(0)
+		for i := 0; i < 64; i++ {
+			b, err := expected.ReadBit()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			e = e<<1 | uint64(b)
+		}
+
+		v, err := br.ReadBits(64)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if v != e {
+			t.Fatalf("skip %d\nExpected: %#x\nActual:   %#x\n", skip, e, v)
+		}
+	}
+}
+
+func TestBackwardReaderSentinel(t *testing.T) {
+	// 0000 0101 1000 0001: 3 bits of padding and the sentinel
+	br, err := NewBackwardReaderSentinel([]byte{0x81, 0x05})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if br.BitsRemaining() != 10 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 10, br.BitsRemaining())
+	}
+	v, err := br.PeekBits(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0x3 {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x3, v)
+	}
+
+	_, err = br.ReadBits(11)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+	v, err = br.ReadBits(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0x181 {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x181, v)
+	}
+
+	for _, data := range [][]byte{nil, {0x01, 0x00}} {
+		_, err = NewBackwardReaderSentinel(data)
+		if !errors.Is(err, ErrNoSentinel) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNoSentinel, err)
+		}
+	}
+}
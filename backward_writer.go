@@ -0,0 +1,93 @@
+package bitstream
+
+import (
+	"encoding/binary"
+)
+
+// BackwardWriter is a bit stream writer which builds a buffer to be read by BackwardReader, as the encoders of zstd/FSE do.
+// The fields are packed from bit 0 of the buffer (the LSB of the first byte) upward, so that BackwardReader reads them
+// in the reverse order, i.e. the last field written is the first one read.
+//
+// A BackwardWriter is not safe for concurrent use by multiple goroutines.
+type BackwardWriter struct {
+	buf  []byte
+	acc  uint64 // pending bits, LSB first
+	nAcc uint8  // number of pending bits (0 - 7 between calls)
+}
+
+// NewBackwardWriter creates a new BackwardWriter instance which appends the bytes to `buf`.
+// `buf` may be nil; pass a slice with enough capacity to avoid allocations.
+func NewBackwardWriter(buf []byte) *BackwardWriter {
+	return &BackwardWriter{
+		buf: buf,
+	}
+}
+
+// Reset discards the bits written so far and makes the BackwardWriter append the bytes to `buf`.
+func (bw *BackwardWriter) Reset(buf []byte) {
+	bw.buf = buf
+	bw.acc = 0
+	bw.nAcc = 0
+}
+
+// WrittenBits returns the number of bits written to the BackwardWriter since it was created or reset.
+func (bw *BackwardWriter) WrittenBits() uint64 {
+	return uint64(len(bw.buf))*8 + uint64(bw.nAcc)
+}
+
+// WriteBit writes a single bit to the bit stream.
+// Uses the LSB bit in `bit`.
+func (bw *BackwardWriter) WriteBit(bit uint8) error {
+	return bw.WriteBits(1, uint64(bit))
+}
+
+// WriteBits writes the LSB `nBits` (<= 64) bits of `val` to the bit stream; the upper bits are ignored.
+// BackwardReader.ReadBits(nBits) returns the same value.
+func (bw *BackwardWriter) WriteBits(nBits uint8, val uint64) error {
+	if nBits > 64 {
+		return wrapError("WriteBits", bw.WrittenBits(), errTooManyBitsForUint64)
+	}
+	if nBits == 0 {
+		return nil
+	}
+
+	val &= ^uint64(0) >> (64 - nBits)
+	bw.acc |= val << bw.nAcc
+	total := uint(bw.nAcc) + uint(nBits)
+	if total >= 64 {
+		bw.buf = binary.LittleEndian.AppendUint64(bw.buf, bw.acc)
+		total -= 64
+		bw.acc = 0
+		if bw.nAcc > 0 {
+			bw.acc = val >> (64 - bw.nAcc)
+		}
+	}
+	for ; total >= 8; total -= 8 {
+		bw.buf = append(bw.buf, uint8(bw.acc))
+		bw.acc >>= 8
+	}
+	bw.nAcc = uint8(total)
+	return nil
+}
+
+// Bytes returns the bits written so far, padding the last byte with zeros.
+// It does not finalize the bit stream, so more bits can be written after it; use NewBackwardReader to read the bytes.
+// The slice is valid only until the next write.
+func (bw *BackwardWriter) Bytes() []byte {
+	if bw.nAcc == 0 {
+		return bw.buf
+	}
+	return append(bw.buf, uint8(bw.acc))
+}
+
+// Finish writes the sentinel bit '1' and pads the last byte with zeros, as zstd does, and returns the bit stream,
+// which can be read by NewBackwardReaderSentinel. No bits can be written after Finish until Reset is called.
+func (bw *BackwardWriter) Finish() []byte {
+	bw.WriteBit(1)
+	if bw.nAcc > 0 {
+		bw.buf = append(bw.buf, uint8(bw.acc))
+		bw.acc = 0
+		bw.nAcc = 0
+	}
+	return bw.buf
+}
@@ -0,0 +1,83 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestBackwardWriter(t *testing.T) {
+	bw := NewBackwardWriter(nil)
+	bw.WriteBits(4, 0xf)
+	bw.WriteBits(10, 0x250)
+	bw.WriteBits(0, 0xff)
+	bw.WriteBits(7, 0xf2) // the upper bit is ignored
+	bw.WriteBits(3, 0x1)
+	if bw.WrittenBits() != 24 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 24, bw.WrittenBits())
+	}
+	expected := []byte{0x0f, 0xa5, 0x3c}
+	if !bytes.Equal(bw.Bytes(), expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, bw.Bytes())
+	}
+
+	err := bw.WriteBits(65, 0)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+}
+
+func TestBackwardWriterSentinel(t *testing.T) {
+	bw := NewBackwardWriter(nil)
+	bw.WriteBits(10, 0x181)
+	data := bw.Finish()
+	expected := []byte{0x81, 0x05}
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, data)
+	}
+
+	// the sentinel makes a whole byte when the bit stream is byte aligned
+	bw.Reset(nil)
+	bw.WriteBits(8, 0x00)
+	data = bw.Finish()
+	expected = []byte{0x00, 0x01}
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, data)
+	}
+}
+
+func TestBackwardRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	widths := make([]uint8, 1000)
+	values := make([]uint64, len(widths))
+	bw := NewBackwardWriter(nil)
+	for i := range widths {
+		widths[i] = uint8(rnd.Intn(65))
+		values[i] = rnd.Uint64() & (^uint64(0) >> (64 - widths[i]))
+		if widths[i] == 0 {
+			values[i] = 0
+		}
+		err := bw.WriteBits(widths[i], values[i])
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+
+	br, err := NewBackwardReaderSentinel(bw.Finish())
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	for i := len(widths) - 1; i >= 0; i-- {
+		v, err := br.ReadBits(widths[i])
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if v != values[i] {
+			t.Fatalf("field %d\nExpected: %#x\nActual:   %#x\n", i, values[i], v)
+		}
+	}
+	if br.BitsRemaining() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, br.BitsRemaining())
+	}
+}
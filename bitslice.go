@@ -0,0 +1,171 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// BitSlice is a sequence of bits with its exact length.
+// The bits are stored from the MSB of the first byte, and the bits beyond the length in the last byte are always '0'.
+// A BitSlice is immutable; Slice and Append return a new BitSlice.
+type BitSlice struct {
+	data  []byte
+	nBits uint64
+}
+
+// NewBitSlice creates a new BitSlice which has the first `nBits` bits of `data`.
+// The data is copied. It returns ErrInsufficientData if `data` has fewer bits than `nBits`.
+func NewBitSlice(data []byte, nBits uint64) (BitSlice, error) {
+	if uint64(len(data))*8 < nBits {
+		return BitSlice{}, ErrInsufficientData
+	}
+	return BitSlice{
+		data:  extractBits(data, 0, nBits),
+		nBits: nBits,
+	}, nil
+}
+
+// Len returns the number of bits in the BitSlice.
+func (bs BitSlice) Len() uint64 {
+	return bs.nBits
+}
+
+// Bytes returns the bits packed in bytes (left aligned). The bits beyond Len in the last byte are '0'.
+// The returned slice must not be modified.
+func (bs BitSlice) Bytes() []byte {
+	return bs.data
+}
+
+// TrailingBits returns the number of valid bits in the last byte (1 to 8), or 0 if the BitSlice is empty.
+func (bs BitSlice) TrailingBits() uint8 {
+	if bs.nBits == 0 {
+		return 0
+	}
+	if n := uint8(bs.nBits % 8); n != 0 {
+		return n
+	}
+	return 8
+}
+
+// Bit returns the `i`-th bit (0 or 1). It panics if `i` is out of range.
+func (bs BitSlice) Bit(i uint64) uint8 {
+	if i >= bs.nBits {
+		panic("bitstream: BitSlice index out of range")
+	}
+	return (bs.data[i/8] >> (7 - i%8)) & 0x01
+}
+
+// Slice returns the bits from `start` (inclusive) to `end` (exclusive) as a new BitSlice.
+// It panics if the range is invalid, like slicing a Go slice.
+func (bs BitSlice) Slice(start, end uint64) BitSlice {
+	if start > end || end > bs.nBits {
+		panic("bitstream: BitSlice bounds out of range")
+	}
+	return BitSlice{
+		data:  extractBits(bs.data, start, end-start),
+		nBits: end - start,
+	}
+}
+
+// Append returns a new BitSlice which has the bits of `other` after the bits of `bs`.
+func (bs BitSlice) Append(other BitSlice) BitSlice {
+	nBits := bs.nBits + other.nBits
+	data := make([]byte, (nBits+7)/8)
+	copy(data, bs.data)
+
+	base := bs.nBits / 8
+	shift := bs.nBits % 8
+	for i, b := range other.data {
+		data[base+uint64(i)] |= b >> shift
+		if shift > 0 && base+uint64(i)+1 < uint64(len(data)) {
+			data[base+uint64(i)+1] |= b << (8 - shift)
+		}
+	}
+	return BitSlice{
+		data:  data,
+		nBits: nBits,
+	}
+}
+
+// Equal returns true if `bs` and `other` have the same bits.
+func (bs BitSlice) Equal(other BitSlice) bool {
+	return bs.nBits == other.nBits && bytes.Equal(bs.data, other.data)
+}
+
+// String returns the bits in binary, e.g. "1010 11".
+func (bs BitSlice) String() string {
+	sb := &strings.Builder{}
+	for i := uint64(0); i < bs.nBits; i++ {
+		if i > 0 && i%4 == 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteByte('0' + bs.Bit(i))
+	}
+	return sb.String()
+}
+
+// extractBits returns `nBits` bits from the `start`-th bit of `data` left aligned.
+// The bits beyond `nBits` in the last byte are cleared.
+func extractBits(data []byte, start, nBits uint64) []byte {
+	out := make([]byte, (nBits+7)/8)
+	base := start / 8
+	shift := start % 8
+	for i := range out {
+		j := base + uint64(i)
+		b := data[j] << shift
+		if shift > 0 && j+1 < uint64(len(data)) {
+			b |= data[j+1] >> (8 - shift)
+		}
+		out[i] = b
+	}
+	if rest := nBits % 8; rest != 0 {
+		out[len(out)-1] &= 0xff << (8 - rest)
+	}
+	return out
+}
+
+// ReadBitSlice reads `nBits` bits from the bit stream and returns them as a BitSlice.
+// In the lenient EOF mode, the BitSlice returned together with a PartialFieldError has the bits read so far.
+func (r *Reader) ReadBitSlice(nBits uint) (BitSlice, error) {
+	pos := r.BitPosition()
+	data, err := r.readNBits(nBits, nil)
+	if err != nil {
+		var pfe *PartialFieldError
+		if errors.As(err, &pfe) {
+			bs, _ := NewBitSlice(data, uint64(pfe.ReadBits))
+			return bs, r.wrapError("ReadBitSlice", pos, err)
+		}
+		return BitSlice{}, r.wrapError("ReadBitSlice", pos, err)
+	}
+
+	bs := BitSlice{
+		data:  extractBits(data, 0, uint64(nBits)),
+		nBits: uint64(nBits),
+	}
+	r.trace("", pos, nBits, bs)
+	return bs, nil
+}
+
+// WriteBitSlice writes all the bits in `bs` to the bit stream.
+func (w *Writer) WriteBitSlice(bs BitSlice) error {
+	pos := w.bitPosition()
+	err := w.writeBitSlice(bs)
+	if err != nil {
+		return w.wrapError("WriteBitSlice", pos, err)
+	}
+	w.trace("", pos, uint(bs.nBits), bs)
+	return nil
+}
+
+func (w *Writer) writeBitSlice(bs BitSlice) error {
+	whole := bs.nBits / 8
+	err := w.writeBytes(bs.data[:whole])
+	if err != nil {
+		return err
+	}
+	if rest := uint8(bs.nBits % 8); rest != 0 {
+		return w.writeNBitsOfUint8(rest, bs.data[whole]>>(8-rest))
+	}
+	return nil
+}
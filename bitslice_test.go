@@ -0,0 +1,119 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestBitSlice(t *testing.T) {
+	// 1010 1011 1100 1101 111
+	bs, err := NewBitSlice([]byte{0xab, 0xcd, 0xef}, 19)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if bs.Len() != 19 || bs.TrailingBits() != 3 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 19, 3, bs.Len(), bs.TrailingBits())
+	}
+	if !bytes.Equal([]byte{0xab, 0xcd, 0xe0}, bs.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xab, 0xcd, 0xe0}, bs.Bytes())
+	}
+	if bs.String() != "1010 1011 1100 1101 111" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "1010 1011 1100 1101 111", bs.String())
+	}
+
+	_, err = NewBitSlice([]byte{0xab}, 9)
+	if !errors.Is(err, ErrInsufficientData) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInsufficientData, err)
+	}
+}
+
+func TestBitSliceSlice(t *testing.T) {
+	// 1010 1011 1100 1101 111
+	bs, _ := NewBitSlice([]byte{0xab, 0xcd, 0xef}, 19)
+
+	testData := []struct {
+		Name     string
+		Start    uint64
+		End      uint64
+		Expected string
+	}{
+		{Name: "pattern 1", Start: 0, End: 19, Expected: "1010 1011 1100 1101 111"},
+		{Name: "pattern 2", Start: 3, End: 14, Expected: "0101 1110 011"},
+		{Name: "pattern 3", Start: 8, End: 16, Expected: "1100 1101"},
+		{Name: "pattern 4", Start: 18, End: 19, Expected: "1"},
+		{Name: "pattern 5", Start: 19, End: 19, Expected: ""},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			s := bs.Slice(data.Start, data.End)
+			if data.Expected != s.String() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, s.String())
+			}
+			if data.End-data.Start != s.Len() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.End-data.Start, s.Len())
+			}
+		})
+	}
+}
+
+func TestBitSliceAppend(t *testing.T) {
+	a, _ := NewBitSlice([]byte{0xa0}, 3)       // 101
+	b, _ := NewBitSlice([]byte{0xff, 0x80}, 9) // 1111 1111 1
+	c := a.Append(b).Append(a)
+
+	if c.String() != "1011 1111 1111 101" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "1011 1111 1111 101", c.String())
+	}
+	expected, _ := NewBitSlice([]byte{0xbf, 0xfa}, 15)
+	if !c.Equal(expected) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, c)
+	}
+	if c.Equal(c.Slice(0, 14)) {
+		t.Fatalf("slices of different lengths should not be equal\n")
+	}
+	if !c.Slice(12, 15).Equal(a) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", a, c.Slice(12, 15))
+	}
+}
+
+func TestReadWriteBitSlice(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0xab, 0xcd, 0xef}), nil)
+	r.Skip(2)
+	bs, err := r.ReadBitSlice(13)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	// xx10 1011 1100 110x
+	if bs.String() != "1010 1111 0011 0" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "1010 1111 0011 0", bs.String())
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	w := NewWriter(buf)
+	w.WriteBit(1)
+	err = w.WriteBitSlice(bs)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.WriteBitSlice(bs.Slice(0, 2))
+	w.Finalize()
+	// 1101 0111 1001 1010
+	if !bytes.Equal([]byte{0xd7, 0x9a}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xd7, 0x9a}, buf.Bytes())
+	}
+
+	// lenient EOF mode
+	r = NewReader(bytes.NewReader([]byte{0xab}), &ReaderOptions{EOFMode: EOFLenient})
+	r.Skip(3)
+	bs, err = r.ReadBitSlice(8)
+	var pfe *PartialFieldError
+	if !errors.As(err, &pfe) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", &PartialFieldError{}, err)
+	}
+	if bs.String() != "0101 1" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "0101 1", bs.String())
+	}
+}
@@ -0,0 +1,26 @@
+/*
+Package bitstream2 is the v2 API of bitstream-go: a thin layer over the v1 Reader and Writer
+with consistent signatures, meant to be easier to call from hand-written and generated code.
+It is a package of the v1 module rather than a /v2 module, so both APIs can be used from the same module version.
+
+  - Every number of bits or bytes is an int, and every position is an int64.
+  - Every method is named after the type, the width and the byte order of its value, and the Reader and the Writer
+    have the same set: ReadUint16LE and WriteUint16LE, ReadInt(nBits) and WriteInt(nBits, v), and so on.
+    The methods without a width read or write any width up to 64 bits.
+  - Every error wraps a sentinel error, e.g. io.ErrUnexpectedEOF or ErrTooManyBits, so it can be tested with errors.Is.
+    The errors of the underlying Reader and Writer also record the position in a *PositionError.
+  - The options are functional options, e.g. NewReader(src, WithEOFMode(EOFLenient), WithBufferSize(4096)).
+
+The v1 API keeps working as it is. FromV1Reader and FromV1Writer wrap the v1 types, and V1 unwraps them,
+so a codec can be migrated a function at a time and the v1 methods, e.g. the transforms, stay available.
+The v1 methods correspond to the v2 methods as follows:
+
+	ReadNBitsAsUint8(n), ReadNBitsAsUint16BE(n), ...   ReadUint(n)
+	ReadNBitsAsUint64WithOrder(n, MSBFirstLittleEndian) ReadUintLE(n)
+	ReadNBitsAsInt32BE(n)                               ReadInt(n)
+	ReadUint16BE(), ReadUint32BE(), ReadUint64BE()      the same, plus the LE and Int versions
+	ReadNBits(n, nil), ReadBytes(n)                     ReadBits(n), ReadBytes(n)
+	WriteNBitsOfUint8(n, v), WriteNBitsOfUint16BE(n, v) WriteUint(n, v)
+	WriteNBits(n, data), AlignByte(0)                   WriteBits(n, data), Align()
+*/
+package bitstream2
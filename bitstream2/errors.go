@@ -0,0 +1,44 @@
+package bitstream2
+
+import (
+	"errors"
+	"fmt"
+
+	v1 "github.com/bearmini/bitstream-go"
+)
+
+// Errors returned by Reader and Writer, in addition to io.EOF and io.ErrUnexpectedEOF.
+// They are wrapped with the details, so use errors.Is to test for them.
+var (
+	// ErrInvalidNBits is returned when the number of bits or bytes is negative.
+	ErrInvalidNBits = errors.New("bitstream: invalid number of bits")
+
+	// ErrTooManyBits is returned when the number of bits is larger than the width of the value.
+	ErrTooManyBits = v1.ErrTooManyBits
+
+	// ErrValueOutOfRange is returned in the strict mode when a value does not fit in the number of bits.
+	ErrValueOutOfRange = v1.ErrValueOutOfRange
+
+	// ErrNotAligned is returned when a Writer is closed in the middle of a byte with the PadNone padding policy.
+	ErrNotAligned = v1.ErrNotAligned
+
+	// ErrLimitExceeded is returned when a read exceeds a limit of ReaderLimits.
+	ErrLimitExceeded = v1.ErrLimitExceeded
+
+	// ErrClosed is returned when a Reader is read after it has been closed.
+	ErrClosed = v1.ErrClosed
+)
+
+// PositionError records an error and the position in the bit stream where the failed operation started.
+type PositionError = v1.PositionError
+
+// checkNBits returns an error if `nBits` is negative or larger than `max`.
+func checkNBits(op string, nBits, max int) error {
+	if nBits < 0 {
+		return fmt.Errorf("bitstream: %s: %w: %d", op, ErrInvalidNBits, nBits)
+	}
+	if nBits > max {
+		return fmt.Errorf("bitstream: %s: %w: %d bits for %d bits", op, ErrTooManyBits, nBits, max)
+	}
+	return nil
+}
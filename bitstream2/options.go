@@ -0,0 +1,143 @@
+package bitstream2
+
+import (
+	"log/slog"
+
+	v1 "github.com/bearmini/bitstream-go"
+)
+
+// TraceHook is a function which is called for each field read from or written to the bit stream.
+// See the TraceHook of v1 for the details.
+type TraceHook = v1.TraceHook
+
+// EOFMode specifies how a Reader handles the end of the bit stream in the middle of a field.
+type EOFMode = v1.EOFMode
+
+// EOF modes.
+const (
+	EOFStrict  = v1.EOFStrict
+	EOFLenient = v1.EOFLenient
+)
+
+// ReaderLimits bounds the sizes a Reader accepts, for parsing untrusted input. See the ReaderLimits of v1 for the details.
+type ReaderLimits = v1.ReaderLimits
+
+// PaddingPolicy specifies how a Writer fills the last byte when the bit stream is closed in the middle of a byte.
+type PaddingPolicy = v1.PaddingPolicy
+
+// Padding policies.
+const (
+	PadZeros   = v1.PadZeros
+	PadOnes    = v1.PadOnes
+	PadNone    = v1.PadNone
+	PadPattern = v1.PadPattern
+)
+
+// PaddingPattern is a pattern of bits repeated by the PadPattern padding policy. See the PaddingPattern of v1 for the details.
+type PaddingPattern = v1.PaddingPattern
+
+// ReaderOption configures a Reader.
+type ReaderOption interface {
+	applyReader(opt *v1.ReaderOptions)
+}
+
+// WriterOption configures a Writer.
+type WriterOption interface {
+	applyWriter(opt *v1.WriterOptions)
+}
+
+// Option configures both a Reader and a Writer.
+type Option struct {
+	reader func(opt *v1.ReaderOptions)
+	writer func(opt *v1.WriterOptions)
+}
+
+func (o Option) applyReader(opt *v1.ReaderOptions) { o.reader(opt) }
+func (o Option) applyWriter(opt *v1.WriterOptions) { o.writer(opt) }
+
+type readerOption func(opt *v1.ReaderOptions)
+
+func (f readerOption) applyReader(opt *v1.ReaderOptions) { f(opt) }
+
+type writerOption func(opt *v1.WriterOptions)
+
+func (f writerOption) applyWriter(opt *v1.WriterOptions) { f(opt) }
+
+// WithBufferSize sets the size of the buffer in bytes. The default is used if it is not positive.
+func WithBufferSize(n int) Option {
+	size := uint(max(n, 0))
+	return Option{
+		reader: func(opt *v1.ReaderOptions) { opt.BufferSize = size },
+		writer: func(opt *v1.WriterOptions) { opt.BufferSize = size },
+	}
+}
+
+// WithTraceHook sets the hook called for each field read or written.
+func WithTraceHook(hook TraceHook) Option {
+	return Option{
+		reader: func(opt *v1.ReaderOptions) { opt.TraceHook = hook },
+		writer: func(opt *v1.WriterOptions) { opt.TraceHook = hook },
+	}
+}
+
+// WithLogger sets the logger of the events at the Debug level.
+func WithLogger(l *slog.Logger) Option {
+	return Option{
+		reader: func(opt *v1.ReaderOptions) { opt.Logger = l },
+		writer: func(opt *v1.WriterOptions) { opt.Logger = l },
+	}
+}
+
+// WithPlainErrors makes the errors returned as they are, without the position, so that they cost no allocations.
+// The errors of this package, e.g. ErrInvalidNBits, are still wrapped with the details.
+func WithPlainErrors() Option {
+	return Option{
+		reader: func(opt *v1.ReaderOptions) { opt.PlainErrors = true },
+		writer: func(opt *v1.WriterOptions) { opt.PlainErrors = true },
+	}
+}
+
+// WithEOFMode sets how the end of the bit stream in the middle of a field is handled. The default is EOFStrict.
+func WithEOFMode(m EOFMode) ReaderOption {
+	return readerOption(func(opt *v1.ReaderOptions) { opt.EOFMode = m })
+}
+
+// WithLimits bounds the sizes accepted by the Reader.
+func WithLimits(l ReaderLimits) ReaderOption {
+	return readerOption(func(opt *v1.ReaderOptions) { opt.Limits = l })
+}
+
+// WithPadding sets how the last byte is filled on Close. The default is PadZeros.
+func WithPadding(p PaddingPolicy) WriterOption {
+	return writerOption(func(opt *v1.WriterOptions) { opt.Padding = p })
+}
+
+// WithPaddingPattern sets the padding policy to PadPattern with the pattern `p`, e.g. PaddingPattern{Bits: 0x7e, NBits: 8}.
+func WithPaddingPattern(p PaddingPattern) WriterOption {
+	return writerOption(func(opt *v1.WriterOptions) {
+		opt.Padding = PadPattern
+		opt.PaddingPattern = p
+	})
+}
+
+// WithStrictValues makes the write methods fail with ErrValueOutOfRange if a value does not fit in the number of bits,
+// instead of silently dropping the upper bits.
+func WithStrictValues() WriterOption {
+	return writerOption(func(opt *v1.WriterOptions) { opt.StrictValues = true })
+}
+
+func readerOptions(opts []ReaderOption) *v1.ReaderOptions {
+	o := &v1.ReaderOptions{}
+	for _, opt := range opts {
+		opt.applyReader(o)
+	}
+	return o
+}
+
+func writerOptions(opts []WriterOption) *v1.WriterOptions {
+	o := &v1.WriterOptions{}
+	for _, opt := range opts {
+		opt.applyWriter(o)
+	}
+	return o
+}
@@ -0,0 +1,74 @@
+package bitstream2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReaderOptions(t *testing.T) {
+	var fields []string
+	hook := func(name string, bitOffset uint64, nBits uint, value any) { fields = append(fields, name) }
+	r := NewReader(bytes.NewReader([]byte{0x12, 0x34}),
+		WithBufferSize(1), WithEOFMode(EOFLenient), WithTraceHook(hook), WithLimits(ReaderLimits{MaxBitsPerCall: 16}))
+
+	_, err := r.ReadBits(17)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrLimitExceeded, err)
+	}
+	_, err = r.ReadUint8()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	// lenient
+	v, err := r.ReadUint(12)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || v != 0x340 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0x340, io.ErrUnexpectedEOF, v, err)
+	}
+	if len(fields) != 1 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 1, fields)
+	}
+	if r.V1().BufferedBits() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, r.V1().BufferedBits())
+	}
+}
+
+func TestWriterOptions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, WithPadding(PadOnes), WithPlainErrors())
+	err := w.WriteUint(4, 0x5)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !bytes.Equal([]byte{0x5f}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0x5f}, buf.Bytes())
+	}
+
+	buf.Reset()
+	w = NewWriter(buf, WithPaddingPattern(PaddingPattern{Bits: 0x7e, NBits: 8}))
+	w.WriteUint(3, 0x5)
+	n, err := w.Pad()
+	if err != nil || n != 5 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 5, nil, n, err)
+	}
+	w.WriteUint(3, 0x3)
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !bytes.Equal([]byte{0xbe, 0x7e}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xbe, 0x7e}, buf.Bytes())
+	}
+
+	w = NewWriter(&bytes.Buffer{}, WithPadding(PadNone), WithPlainErrors())
+	w.WriteBool(true)
+	err = w.Close()
+	if err != ErrNotAligned {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNotAligned, err)
+	}
+}
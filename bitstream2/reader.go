@@ -0,0 +1,208 @@
+package bitstream2
+
+import (
+	"io"
+
+	v1 "github.com/bearmini/bitstream-go"
+)
+
+// Reader reads values of any number of bits from a bit stream, MSB first.
+type Reader struct {
+	r *v1.Reader
+}
+
+// NewReader creates a new Reader which reads from `src`.
+func NewReader(src io.Reader, opts ...ReaderOption) *Reader {
+	return &Reader{r: v1.NewReader(src, readerOptions(opts))}
+}
+
+// NewReaderBytes creates a new Reader which reads `data` in place.
+func NewReaderBytes(data []byte, opts ...ReaderOption) *Reader {
+	return &Reader{r: v1.NewReaderBytes(data, readerOptions(opts))}
+}
+
+// FromV1Reader wraps a v1 Reader. Reading from either of them advances both.
+func FromV1Reader(r *v1.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// V1 returns the underlying v1 Reader.
+func (r *Reader) V1() *v1.Reader {
+	return r.r
+}
+
+// Reset discards the state of the Reader and makes it read from `src`, keeping its options.
+func (r *Reader) Reset(src io.Reader) {
+	r.r.Reset(src)
+}
+
+// Close stops the background prefetch and releases the resources of the Reader, if any.
+func (r *Reader) Close() error {
+	return r.r.Close()
+}
+
+// BitPosition returns the number of bits read so far.
+func (r *Reader) BitPosition() int64 {
+	return int64(r.r.BitPosition())
+}
+
+// ReadBool reads a bit and returns true if it is '1'.
+func (r *Reader) ReadBool() (bool, error) {
+	return r.r.ReadBool()
+}
+
+// ReadUint reads `nBits` (0 - 64) bits as a big endian unsigned integer.
+func (r *Reader) ReadUint(nBits int) (uint64, error) {
+	return r.readUint("ReadUint", nBits, v1.MSBFirstBigEndian)
+}
+
+// ReadUintLE reads `nBits` (0 - 64) bits as a little endian unsigned integer, i.e. its bytes from the least significant
+// one, each MSB first. If `nBits` is not a multiple of 8, the last group has the remaining upper bits.
+func (r *Reader) ReadUintLE(nBits int) (uint64, error) {
+	return r.readUint("ReadUintLE", nBits, v1.MSBFirstLittleEndian)
+}
+
+// ReadInt reads `nBits` (0 - 64) bits as a big endian two's complement signed integer.
+func (r *Reader) ReadInt(nBits int) (int64, error) {
+	v, err := r.readUint("ReadInt", nBits, v1.MSBFirstBigEndian)
+	return signExtend(v, nBits), err
+}
+
+// ReadIntLE reads `nBits` (0 - 64) bits as a little endian two's complement signed integer. See ReadUintLE for the order.
+func (r *Reader) ReadIntLE(nBits int) (int64, error) {
+	v, err := r.readUint("ReadIntLE", nBits, v1.MSBFirstLittleEndian)
+	return signExtend(v, nBits), err
+}
+
+func (r *Reader) readUint(op string, nBits int, order v1.BitOrder) (uint64, error) {
+	err := checkNBits(op, nBits, 64)
+	if err != nil {
+		return 0, err
+	}
+	if order == v1.MSBFirstBigEndian {
+		return r.r.ReadNBitsAsUint64BE(uint8(nBits))
+	}
+	return r.r.ReadNBitsAsUint64WithOrder(uint8(nBits), order)
+}
+
+// signExtend extends the sign bit of the `nBits` bits of `v`.
+func signExtend(v uint64, nBits int) int64 {
+	if nBits == 0 || nBits >= 64 {
+		return int64(v)
+	}
+	shift := 64 - nBits
+	return int64(v<<shift) >> shift
+}
+
+// ReadUint8 reads 8 bits as an unsigned integer.
+func (r *Reader) ReadUint8() (uint8, error) {
+	v, err := r.ReadUint(8)
+	return uint8(v), err
+}
+
+// ReadUint16BE reads 16 bits as a big endian unsigned integer.
+func (r *Reader) ReadUint16BE() (uint16, error) {
+	v, err := r.ReadUint(16)
+	return uint16(v), err
+}
+
+// ReadUint16LE reads 16 bits as a little endian unsigned integer.
+func (r *Reader) ReadUint16LE() (uint16, error) {
+	v, err := r.ReadUintLE(16)
+	return uint16(v), err
+}
+
+// ReadUint32BE reads 32 bits as a big endian unsigned integer.
+func (r *Reader) ReadUint32BE() (uint32, error) {
+	v, err := r.ReadUint(32)
+	return uint32(v), err
+}
+
+// ReadUint32LE reads 32 bits as a little endian unsigned integer.
+func (r *Reader) ReadUint32LE() (uint32, error) {
+	v, err := r.ReadUintLE(32)
+	return uint32(v), err
+}
+
+// ReadUint64BE reads 64 bits as a big endian unsigned integer.
+func (r *Reader) ReadUint64BE() (uint64, error) {
+	return r.ReadUint(64)
+}
+
+// ReadUint64LE reads 64 bits as a little endian unsigned integer.
+func (r *Reader) ReadUint64LE() (uint64, error) {
+	return r.ReadUintLE(64)
+}
+
+// ReadInt8 reads 8 bits as a signed integer.
+func (r *Reader) ReadInt8() (int8, error) {
+	v, err := r.ReadInt(8)
+	return int8(v), err
+}
+
+// ReadInt16BE reads 16 bits as a big endian signed integer.
+func (r *Reader) ReadInt16BE() (int16, error) {
+	v, err := r.ReadInt(16)
+	return int16(v), err
+}
+
+// ReadInt16LE reads 16 bits as a little endian signed integer.
+func (r *Reader) ReadInt16LE() (int16, error) {
+	v, err := r.ReadIntLE(16)
+	return int16(v), err
+}
+
+// ReadInt32BE reads 32 bits as a big endian signed integer.
+func (r *Reader) ReadInt32BE() (int32, error) {
+	v, err := r.ReadInt(32)
+	return int32(v), err
+}
+
+// ReadInt32LE reads 32 bits as a little endian signed integer.
+func (r *Reader) ReadInt32LE() (int32, error) {
+	v, err := r.ReadIntLE(32)
+	return int32(v), err
+}
+
+// ReadInt64BE reads 64 bits as a big endian signed integer.
+func (r *Reader) ReadInt64BE() (int64, error) {
+	return r.ReadInt(64)
+}
+
+// ReadInt64LE reads 64 bits as a little endian signed integer.
+func (r *Reader) ReadInt64LE() (int64, error) {
+	return r.ReadIntLE(64)
+}
+
+// ReadBits reads `nBits` bits and returns them left aligned in a slice of bytes, the last one padded with '0' bits.
+func (r *Reader) ReadBits(nBits int) ([]byte, error) {
+	err := checkNBits("ReadBits", nBits, nBits)
+	if err != nil {
+		return nil, err
+	}
+	return r.r.ReadNBits(uint(nBits), nil)
+}
+
+// ReadBytes reads `n` bytes. The bit stream does not have to be byte aligned.
+func (r *Reader) ReadBytes(n int) ([]byte, error) {
+	err := checkNBits("ReadBytes", n, n)
+	if err != nil {
+		return nil, err
+	}
+	return r.r.ReadBytes(uint(n))
+}
+
+// Skip discards `nBits` bits.
+func (r *Reader) Skip(nBits int) error {
+	err := checkNBits("Skip", nBits, nBits)
+	if err != nil {
+		return err
+	}
+	return r.r.Skip(uint(nBits))
+}
+
+// Align skips the bits up to the next byte boundary and returns the number of bits skipped.
+func (r *Reader) Align() (int, error) {
+	n := int(-r.BitPosition() & 7)
+	return n, r.Skip(n)
+}
@@ -0,0 +1,154 @@
+package bitstream2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	v1 "github.com/bearmini/bitstream-go"
+)
+
+func TestReaderFixedWidth(t *testing.T) {
+	data := []byte{0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10}
+
+	testData := []struct {
+		Name     string
+		Read     func(r *Reader) (any, error)
+		Expected any
+	}{
+		{Name: "ReadUint8", Read: func(r *Reader) (any, error) { return r.ReadUint8() }, Expected: uint8(0xfe)},
+		{Name: "ReadUint16BE", Read: func(r *Reader) (any, error) { return r.ReadUint16BE() }, Expected: uint16(0xfedc)},
+		{Name: "ReadUint16LE", Read: func(r *Reader) (any, error) { return r.ReadUint16LE() }, Expected: uint16(0xdcfe)},
+		{Name: "ReadUint32BE", Read: func(r *Reader) (any, error) { return r.ReadUint32BE() }, Expected: uint32(0xfedcba98)},
+		{Name: "ReadUint32LE", Read: func(r *Reader) (any, error) { return r.ReadUint32LE() }, Expected: uint32(0x98badcfe)},
+		{Name: "ReadUint64BE", Read: func(r *Reader) (any, error) { return r.ReadUint64BE() }, Expected: uint64(0xfedcba9876543210)},
+		{Name: "ReadUint64LE", Read: func(r *Reader) (any, error) { return r.ReadUint64LE() }, Expected: uint64(0x1032547698badcfe)},
+		{Name: "ReadInt8", Read: func(r *Reader) (any, error) { return r.ReadInt8() }, Expected: int8(-2)},
+		{Name: "ReadInt16BE", Read: func(r *Reader) (any, error) { return r.ReadInt16BE() }, Expected: int16(-0x0124)},
+		{Name: "ReadInt16LE", Read: func(r *Reader) (any, error) { return r.ReadInt16LE() }, Expected: int16(-0x2302)},
+		{Name: "ReadInt32BE", Read: func(r *Reader) (any, error) { return r.ReadInt32BE() }, Expected: int32(-0x01234568)},
+		{Name: "ReadInt32LE", Read: func(r *Reader) (any, error) { return r.ReadInt32LE() }, Expected: int32(-0x67452302)},
+		{Name: "ReadInt64BE", Read: func(r *Reader) (any, error) { return r.ReadInt64BE() }, Expected: int64(-0x0123456789abcdf0)},
+		{Name: "ReadInt64LE", Read: func(r *Reader) (any, error) { return r.ReadInt64LE() }, Expected: int64(0x1032547698badcfe)},
+	}
+
+	for _, data2 := range testData {
+		data2 := data2 // capture
+		t.Run(data2.Name, func(t *testing.T) {
+			v, err := data2.Read(NewReaderBytes(data))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data2.Expected != v {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data2.Expected, v)
+			}
+		})
+	}
+}
+
+func TestReaderAnyWidth(t *testing.T) {
+	r := NewReaderBytes([]byte{0xfe, 0xdc, 0xba})
+
+	u, err := r.ReadUint(4)
+	if err != nil || u != 0xf {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0xf, nil, u, err)
+	}
+	i, err := r.ReadInt(4)
+	if err != nil || i != -2 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", -2, nil, i, err)
+	}
+	i, err = r.ReadIntLE(12) // 0xdc then 0xb as the upper bits
+	if err != nil || i != -0x424 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", -0x424, nil, i, err)
+	}
+	u, err = r.ReadUint(0)
+	if err != nil || u != 0 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0, nil, u, err)
+	}
+	if r.BitPosition() != 20 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 20, r.BitPosition())
+	}
+
+	n, err := r.Align()
+	if err != nil || n != 4 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 4, nil, n, err)
+	}
+	_, err = r.ReadBool()
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+}
+
+func TestReaderBits(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0xab, 0xcd, 0xef}))
+	err := r.Skip(4)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	v, err := r.ReadBits(12)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual([]byte{0xbc, 0xd0}, v) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xbc, 0xd0}, v)
+	}
+	v, err = r.ReadBytes(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual([]byte{0xef}, v) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xef}, v)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Read     func(r *Reader) error
+		Expected error
+	}{
+		{Name: "ReadUint", Read: func(r *Reader) error { _, err := r.ReadUint(65); return err }, Expected: ErrTooManyBits},
+		{Name: "ReadInt", Read: func(r *Reader) error { _, err := r.ReadInt(-1); return err }, Expected: ErrInvalidNBits},
+		{Name: "ReadBits", Read: func(r *Reader) error { _, err := r.ReadBits(-8); return err }, Expected: ErrInvalidNBits},
+		{Name: "ReadBytes", Read: func(r *Reader) error { _, err := r.ReadBytes(-1); return err }, Expected: ErrInvalidNBits},
+		{Name: "Skip", Read: func(r *Reader) error { return r.Skip(-1) }, Expected: ErrInvalidNBits},
+		{Name: "ReadUint32BE", Read: func(r *Reader) error { _, err := r.ReadUint32BE(); return err }, Expected: io.ErrUnexpectedEOF},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			err := data.Read(NewReaderBytes([]byte{0x12, 0x34}))
+			if !errors.Is(err, data.Expected) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+		})
+	}
+
+	// the errors of the underlying Reader have the position
+	r := NewReaderBytes([]byte{0x12, 0x34})
+	r.Skip(4)
+	_, err := r.ReadUint16BE()
+	var pe *PositionError
+	if !errors.As(err, &pe) || pe.BitOffset != 4 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "PositionError at bit 4", err)
+	}
+}
+
+func TestFromV1Reader(t *testing.T) {
+	r1 := v1.NewReaderBytes([]byte{0x12, 0x34}, nil)
+	r := FromV1Reader(r1)
+	if r.V1() != r1 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", r1, r.V1())
+	}
+	v, err := r.ReadUint(4)
+	if err != nil || v != 0x1 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0x1, nil, v, err)
+	}
+	v8, err := r1.ReadUint8()
+	if err != nil || v8 != 0x23 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0x23, nil, v8, err)
+	}
+}
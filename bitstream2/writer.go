@@ -0,0 +1,198 @@
+package bitstream2
+
+import (
+	"fmt"
+	"io"
+
+	v1 "github.com/bearmini/bitstream-go"
+)
+
+// Writer writes values of any number of bits to a bit stream, MSB first.
+type Writer struct {
+	w *v1.Writer
+}
+
+// NewWriter creates a new Writer which writes to `dst`. Close must be called to write the last byte.
+func NewWriter(dst io.Writer, opts ...WriterOption) *Writer {
+	return &Writer{w: v1.NewWriterWithOptions(dst, writerOptions(opts))}
+}
+
+// FromV1Writer wraps a v1 Writer. Writing to either of them advances both.
+func FromV1Writer(w *v1.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// V1 returns the underlying v1 Writer.
+func (w *Writer) V1() *v1.Writer {
+	return w.w
+}
+
+// Reset discards the state of the Writer and makes it write to `dst`, keeping its options.
+func (w *Writer) Reset(dst io.Writer) {
+	w.w.Reset(dst)
+}
+
+// Flush writes the complete bytes buffered to the destination.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Close pads the last byte according to the padding policy and writes everything buffered to the destination.
+func (w *Writer) Close() error {
+	return w.w.Close()
+}
+
+// BitPosition returns the number of bits written so far.
+func (w *Writer) BitPosition() int64 {
+	return int64(w.w.WrittenBits())
+}
+
+// WriteBool writes '1' if `b` is true, or '0' otherwise.
+func (w *Writer) WriteBool(b bool) error {
+	return w.w.WriteBool(b)
+}
+
+// WriteUint writes the lower `nBits` (0 - 64) bits of `v` as a big endian unsigned integer.
+func (w *Writer) WriteUint(nBits int, v uint64) error {
+	return w.writeUint("WriteUint", nBits, v, v1.MSBFirstBigEndian)
+}
+
+// WriteUintLE writes the lower `nBits` (0 - 64) bits of `v` as a little endian unsigned integer.
+// See Reader.ReadUintLE for the order.
+func (w *Writer) WriteUintLE(nBits int, v uint64) error {
+	return w.writeUint("WriteUintLE", nBits, v, v1.MSBFirstLittleEndian)
+}
+
+// WriteInt writes `v` in `nBits` (0 - 64) bits as a big endian two's complement signed integer.
+// In the strict mode, it fails with ErrValueOutOfRange if `v` does not fit in `nBits` bits.
+func (w *Writer) WriteInt(nBits int, v int64) error {
+	return w.writeInt("WriteInt", nBits, v, v1.MSBFirstBigEndian)
+}
+
+// WriteIntLE writes `v` in `nBits` (0 - 64) bits as a little endian two's complement signed integer.
+// See Reader.ReadUintLE for the order.
+func (w *Writer) WriteIntLE(nBits int, v int64) error {
+	return w.writeInt("WriteIntLE", nBits, v, v1.MSBFirstLittleEndian)
+}
+
+func (w *Writer) writeUint(op string, nBits int, v uint64, order v1.BitOrder) error {
+	err := checkNBits(op, nBits, 64)
+	if err != nil {
+		return err
+	}
+	if order == v1.MSBFirstBigEndian {
+		return w.w.WriteNBitsOfUint64BE(uint8(nBits), v)
+	}
+	return w.w.WriteNBitsOfUint64WithOrder(uint8(nBits), v, order)
+}
+
+func (w *Writer) writeInt(op string, nBits int, v int64, order v1.BitOrder) error {
+	err := checkNBits(op, nBits, 64)
+	if err != nil {
+		return err
+	}
+	if nBits < 64 {
+		if w.w.StrictValues() && signExtend(uint64(v)&(1<<nBits-1), nBits) != v {
+			return fmt.Errorf("bitstream: %s: %w: %d does not fit in %d bits", op, ErrValueOutOfRange, v, nBits)
+		}
+		v &= 1<<nBits - 1
+	}
+	return w.writeUint(op, nBits, uint64(v), order)
+}
+
+// WriteUint8 writes `v` in 8 bits.
+func (w *Writer) WriteUint8(v uint8) error {
+	return w.WriteUint(8, uint64(v))
+}
+
+// WriteUint16BE writes `v` in 16 bits in big endian.
+func (w *Writer) WriteUint16BE(v uint16) error {
+	return w.WriteUint(16, uint64(v))
+}
+
+// WriteUint16LE writes `v` in 16 bits in little endian.
+func (w *Writer) WriteUint16LE(v uint16) error {
+	return w.WriteUintLE(16, uint64(v))
+}
+
+// WriteUint32BE writes `v` in 32 bits in big endian.
+func (w *Writer) WriteUint32BE(v uint32) error {
+	return w.WriteUint(32, uint64(v))
+}
+
+// WriteUint32LE writes `v` in 32 bits in little endian.
+func (w *Writer) WriteUint32LE(v uint32) error {
+	return w.WriteUintLE(32, uint64(v))
+}
+
+// WriteUint64BE writes `v` in 64 bits in big endian.
+func (w *Writer) WriteUint64BE(v uint64) error {
+	return w.WriteUint(64, v)
+}
+
+// WriteUint64LE writes `v` in 64 bits in little endian.
+func (w *Writer) WriteUint64LE(v uint64) error {
+	return w.WriteUintLE(64, v)
+}
+
+// WriteInt8 writes `v` in 8 bits.
+func (w *Writer) WriteInt8(v int8) error {
+	return w.WriteInt(8, int64(v))
+}
+
+// WriteInt16BE writes `v` in 16 bits in big endian.
+func (w *Writer) WriteInt16BE(v int16) error {
+	return w.WriteInt(16, int64(v))
+}
+
+// WriteInt16LE writes `v` in 16 bits in little endian.
+func (w *Writer) WriteInt16LE(v int16) error {
+	return w.WriteIntLE(16, int64(v))
+}
+
+// WriteInt32BE writes `v` in 32 bits in big endian.
+func (w *Writer) WriteInt32BE(v int32) error {
+	return w.WriteInt(32, int64(v))
+}
+
+// WriteInt32LE writes `v` in 32 bits in little endian.
+func (w *Writer) WriteInt32LE(v int32) error {
+	return w.WriteIntLE(32, int64(v))
+}
+
+// WriteInt64BE writes `v` in 64 bits in big endian.
+func (w *Writer) WriteInt64BE(v int64) error {
+	return w.WriteInt(64, v)
+}
+
+// WriteInt64LE writes `v` in 64 bits in little endian.
+func (w *Writer) WriteInt64LE(v int64) error {
+	return w.WriteIntLE(64, v)
+}
+
+// WriteBits writes the first `nBits` bits of `data`, i.e. the bits as returned by Reader.ReadBits.
+func (w *Writer) WriteBits(nBits int, data []byte) error {
+	err := checkNBits("WriteBits", nBits, nBits)
+	if err != nil {
+		return err
+	}
+	return w.w.WriteNBits(uint(nBits), data)
+}
+
+// WriteBytes writes `p`. The bit stream does not have to be byte aligned.
+func (w *Writer) WriteBytes(p []byte) error {
+	return w.w.WriteBytes(p)
+}
+
+// Align writes '0' bits up to the next byte boundary and returns the number of bits written.
+func (w *Writer) Align() (int, error) {
+	n, err := w.w.AlignByte(0)
+	return int(n), err
+}
+
+// Pad writes the padding bits of the padding policy up to the next byte boundary, as Close does at the end,
+// and returns the number of bits written.
+func (w *Writer) Pad() (int, error) {
+	n, err := w.w.Pad()
+	return int(n), err
+}
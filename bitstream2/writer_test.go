@@ -0,0 +1,165 @@
+package bitstream2
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	v1 "github.com/bearmini/bitstream-go"
+)
+
+func TestWriterFixedWidth(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Write    func(w *Writer) error
+		Expected []byte
+	}{
+		{Name: "WriteUint8", Write: func(w *Writer) error { return w.WriteUint8(0xfe) }, Expected: []byte{0xfe}},
+		{Name: "WriteUint16BE", Write: func(w *Writer) error { return w.WriteUint16BE(0xfedc) }, Expected: []byte{0xfe, 0xdc}},
+		{Name: "WriteUint16LE", Write: func(w *Writer) error { return w.WriteUint16LE(0xfedc) }, Expected: []byte{0xdc, 0xfe}},
+		{Name: "WriteUint32BE", Write: func(w *Writer) error { return w.WriteUint32BE(0xfedcba98) }, Expected: []byte{0xfe, 0xdc, 0xba, 0x98}},
+		{Name: "WriteUint32LE", Write: func(w *Writer) error { return w.WriteUint32LE(0xfedcba98) }, Expected: []byte{0x98, 0xba, 0xdc, 0xfe}},
+		{Name: "WriteUint64BE", Write: func(w *Writer) error { return w.WriteUint64BE(0x0102030405060708) }, Expected: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{Name: "WriteUint64LE", Write: func(w *Writer) error { return w.WriteUint64LE(0x0102030405060708) }, Expected: []byte{8, 7, 6, 5, 4, 3, 2, 1}},
+		{Name: "WriteInt8", Write: func(w *Writer) error { return w.WriteInt8(-2) }, Expected: []byte{0xfe}},
+		{Name: "WriteInt16BE", Write: func(w *Writer) error { return w.WriteInt16BE(-2) }, Expected: []byte{0xff, 0xfe}},
+		{Name: "WriteInt16LE", Write: func(w *Writer) error { return w.WriteInt16LE(-2) }, Expected: []byte{0xfe, 0xff}},
+		{Name: "WriteInt32BE", Write: func(w *Writer) error { return w.WriteInt32BE(-2) }, Expected: []byte{0xff, 0xff, 0xff, 0xfe}},
+		{Name: "WriteInt32LE", Write: func(w *Writer) error { return w.WriteInt32LE(-2) }, Expected: []byte{0xfe, 0xff, 0xff, 0xff}},
+		{Name: "WriteInt64BE", Write: func(w *Writer) error { return w.WriteInt64BE(-2) }, Expected: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}},
+		{Name: "WriteInt64LE", Write: func(w *Writer) error { return w.WriteInt64LE(-2) }, Expected: []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := NewWriter(buf)
+			err := data.Write(w)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			err = w.Close()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(data.Expected, buf.Bytes()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	for _, err := range []error{
+		w.WriteUint(4, 0xf),
+		w.WriteInt(4, -2),
+		w.WriteIntLE(12, -0x524),
+		w.WriteUintLE(20, 0xabcde),
+		w.WriteBool(true),
+		w.WriteBits(5, []byte{0xa8}),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	if w.BitPosition() != 46 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 46, w.BitPosition())
+	}
+	n, err := w.Align()
+	if err != nil || n != 2 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 2, nil, n, err)
+	}
+	err = w.WriteBytes([]byte{0x55})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	r := NewReaderBytes(buf.Bytes())
+	u, _ := r.ReadUint(4)
+	i, _ := r.ReadInt(4)
+	ile, _ := r.ReadIntLE(12)
+	ule, _ := r.ReadUintLE(20)
+	b, _ := r.ReadBool()
+	bits, _ := r.ReadBits(5)
+	r.Align()
+	p, err := r.ReadBytes(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	actual := []any{u, i, ile, ule, b, bits, p}
+	expected := []any{uint64(0xf), int64(-2), int64(-0x524), uint64(0xabcde), true, []byte{0xa8}, []byte{0x55}}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, actual)
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Write    func(w *Writer) error
+		Expected error
+	}{
+		{Name: "WriteUint", Write: func(w *Writer) error { return w.WriteUint(65, 0) }, Expected: ErrTooManyBits},
+		{Name: "WriteInt", Write: func(w *Writer) error { return w.WriteInt(-1, 0) }, Expected: ErrInvalidNBits},
+		{Name: "WriteBits", Write: func(w *Writer) error { return w.WriteBits(-1, nil) }, Expected: ErrInvalidNBits},
+		{Name: "WriteUint strict", Write: func(w *Writer) error { return w.WriteUint(4, 0x10) }, Expected: ErrValueOutOfRange},
+		{Name: "WriteInt strict 1", Write: func(w *Writer) error { return w.WriteInt(4, 8) }, Expected: ErrValueOutOfRange},
+		{Name: "WriteInt strict 2", Write: func(w *Writer) error { return w.WriteInt(4, -9) }, Expected: ErrValueOutOfRange},
+		{Name: "WriteInt strict 3", Write: func(w *Writer) error { return w.WriteIntLE(0, -1) }, Expected: ErrValueOutOfRange},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			err := data.Write(NewWriter(&bytes.Buffer{}, WithStrictValues()))
+			if !errors.Is(err, data.Expected) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+		})
+	}
+
+	// the signed range is inclusive, and not checked without the strict mode
+	w := NewWriter(&bytes.Buffer{}, WithStrictValues())
+	for _, v := range []int64{-8, 7} {
+		err := w.WriteInt(4, v)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	err := NewWriter(&bytes.Buffer{}).WriteInt(4, 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+}
+
+func TestFromV1Writer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w1 := v1.NewWriter(buf)
+	w := FromV1Writer(w1)
+	if w.V1() != w1 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", w1, w.V1())
+	}
+	err := w.WriteUint(4, 0x1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = w1.WriteNBitsOfUint8(4, 0x2)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual([]byte{0x12}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0x12}, buf.Bytes())
+	}
+}
@@ -0,0 +1,138 @@
+// Package bitstreamtest provides helpers for testing codecs built on top of bitstream.Reader and bitstream.Writer:
+// round trip assertions, random sequences of fields, and golden files compared bit by bit.
+//
+// A codec of a type T is tested with its encode and decode functions:
+//
+//	func TestHeader(t *testing.T) {
+//		data := bitstreamtest.RoundTrip(t, Header{Version: 4}, EncodeHeader, DecodeHeader)
+//		bitstreamtest.Golden(t, "testdata/header.bin", data)
+//	}
+//
+// and a sequence of fields with WriteFields and ReadFields:
+//
+//	fields := bitstreamtest.RandomFields(rand.New(rand.NewSource(1)), 100, 64)
+//	bitstreamtest.RoundTrip(t, fields, bitstreamtest.WriteFields, bitstreamtest.Reads(fields))
+//
+// The golden files are written instead of compared if the environment variable BITSTREAMTEST_UPDATE is set, e.g.
+//
+//	BITSTREAMTEST_UPDATE=1 go test ./...
+package bitstreamtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+// ErrMismatch is returned when a decoded value or a bit stream differs from the expected one.
+var ErrMismatch = errors.New("bitstreamtest: mismatch")
+
+// UpdateEnv is the environment variable which makes CheckGolden and Golden write the golden files.
+const UpdateEnv = "BITSTREAMTEST_UPDATE"
+
+// CheckRoundTrip encodes `v` with `encode`, decodes the result with `decode`, and verifies that the decoded value
+// equals `v` by reflect.DeepEqual and that `decode` consumed exactly the bits written by `encode`.
+// It returns the encoded bit stream, padded to a byte boundary, together with the error wrapping ErrMismatch if not.
+func CheckRoundTrip[T any](v T, encode func(w *bitstream.Writer, v T) error, decode func(r *bitstream.Reader) (T, error)) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := bitstream.NewWriter(buf)
+	err := encode(w, v)
+	if err != nil {
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+	nBits := w.WrittenBits()
+	err = w.Close()
+	if err != nil {
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+	data := buf.Bytes()
+
+	r := bitstream.NewReaderBytes(data, nil)
+	actual, err := decode(r)
+	if err != nil {
+		return data, fmt.Errorf("decode: %w", err)
+	}
+	if !reflect.DeepEqual(v, actual) {
+		return data, fmt.Errorf("%w: expected %+v, actual %+v", ErrMismatch, v, actual)
+	}
+	if r.BitPosition() != nBits {
+		return data, fmt.Errorf("%w: %d bits written, %d bits read", ErrMismatch, nBits, r.BitPosition())
+	}
+	return data, nil
+}
+
+// RoundTrip is CheckRoundTrip which fails the test on error.
+func RoundTrip[T any](t testing.TB, v T, encode func(w *bitstream.Writer, v T) error, decode func(r *bitstream.Reader) (T, error)) []byte {
+	t.Helper()
+	data, err := CheckRoundTrip(v, encode, decode)
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	return data
+}
+
+// CheckGolden compares `actual` with the content of the golden file at `path`, and returns an error wrapping
+// ErrMismatch which reports the first differing bit and the bytes around it in both if they differ.
+// If the environment variable UpdateEnv is set, it writes `actual` to the file instead, creating its directory.
+func CheckGolden(path string, actual []byte) error {
+	if os.Getenv(UpdateEnv) != "" {
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, actual, 0o644)
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return Diff(expected, actual)
+}
+
+// Golden is CheckGolden which fails the test on error.
+func Golden(t testing.TB, path string, actual []byte) {
+	t.Helper()
+	err := CheckGolden(path, actual)
+	if err != nil {
+		t.Fatalf("golden %s: %v", path, err)
+	}
+}
+
+// Diff returns nil if `expected` and `actual` are identical, or an error wrapping ErrMismatch which reports
+// the first differing bit and dumps the bytes around it in both.
+func Diff(expected, actual []byte) error {
+	off, same, err := bitstream.CompareBits(bitstream.NewReaderBytes(expected, nil), bitstream.NewReaderBytes(actual, nil))
+	if err != nil {
+		return err
+	}
+	if same {
+		return nil
+	}
+	return fmt.Errorf("%w: first difference at bit %d (byte %d, bit %d) of %d expected and %d actual bytes\nexpected:\n%sactual:\n%s",
+		ErrMismatch, off, off/8, off%8, len(expected), len(actual), dumpAround(expected, off), dumpAround(actual, off))
+}
+
+// dumpAroundBytes is the number of bytes in a line of the dump of dumpAround.
+const dumpAroundBytes = 8
+
+// dumpAround dumps the line of `data` containing the bit at `off` and the line before it, with the bit marked.
+func dumpAround(data []byte, off uint64) string {
+	line := off / 8 / dumpAroundBytes * dumpAroundBytes
+	start := line - min(line, dumpAroundBytes)
+	end := min(line+dumpAroundBytes, uint64(len(data)))
+	if start >= end {
+		return "  (ends before the difference)\n"
+	}
+	var fields []bitstream.Field
+	if off/8 < uint64(len(data)) {
+		fields = []bitstream.Field{{Name: "first difference", BitOffset: off, NBits: 1}}
+	}
+	return bitstream.Dump(data[start:end], fields, &bitstream.DumpOptions{BytesPerLine: dumpAroundBytes, BitOffset: start * 8})
+}
@@ -0,0 +1,156 @@
+package bitstreamtest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+type header struct {
+	Version uint8
+	Length  uint16
+}
+
+func encodeHeader(w *bitstream.Writer, h header) error {
+	err := w.WriteNBitsOfUint8(4, h.Version)
+	if err != nil {
+		return err
+	}
+	return w.WriteNBitsOfUint16BE(12, h.Length)
+}
+
+func decodeHeader(r *bitstream.Reader) (header, error) {
+	v, err := r.ReadNBitsAsUint8(4)
+	if err != nil {
+		return header{}, err
+	}
+	l, err := r.ReadNBitsAsUint16BE(12)
+	return header{Version: v, Length: l}, err
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := RoundTrip(t, header{Version: 4, Length: 0x123}, encodeHeader, decodeHeader)
+	expected := []byte{0x41, 0x23}
+	err := Diff(expected, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+}
+
+func TestCheckRoundTripMismatch(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Decode   func(r *bitstream.Reader) (header, error)
+		Expected string
+	}{
+		{
+			Name: "value",
+			Decode: func(r *bitstream.Reader) (header, error) {
+				h, err := decodeHeader(r)
+				h.Length++
+				return h, err
+			},
+			Expected: "expected {Version:4 Length:291}, actual {Version:4 Length:292}",
+		},
+		{
+			Name: "bits",
+			Decode: func(r *bitstream.Reader) (header, error) {
+				v, err := r.ReadNBitsAsUint8(4)
+				return header{Version: v, Length: 0x123}, err // does not read the length
+			},
+			Expected: "16 bits written, 4 bits read",
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			_, err := CheckRoundTrip(header{Version: 4, Length: 0x123}, encodeHeader, data.Decode)
+			if !errors.Is(err, ErrMismatch) || !strings.Contains(err.Error(), data.Expected) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+		})
+	}
+}
+
+func TestCheckRoundTripError(t *testing.T) {
+	errEncode := errors.New("encode error")
+	_, err := CheckRoundTrip(header{}, func(w *bitstream.Writer, h header) error { return errEncode }, decodeHeader)
+	if !errors.Is(err, errEncode) || !strings.HasPrefix(err.Error(), "encode: ") {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", errEncode, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Expected []byte
+		Actual   []byte
+		Message  string
+	}{
+		{Name: "pattern 1", Expected: []byte{0x12, 0x34}, Actual: []byte{0x12, 0x34}},
+		{Name: "pattern 2", Expected: []byte{0x12, 0x34}, Actual: []byte{0x12, 0x35}, Message: "first difference at bit 15 (byte 1, bit 7) of 2 expected and 2 actual bytes"},
+		{Name: "pattern 3", Expected: []byte{0x12, 0x34}, Actual: []byte{0x12}, Message: "first difference at bit 8 (byte 1, bit 0) of 2 expected and 1 actual bytes"},
+		{Name: "pattern 4", Expected: make([]byte, 20), Actual: append(make([]byte, 19), 0x80), Message: "first difference at bit 152 (byte 19, bit 0)"},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			err := Diff(data.Expected, data.Actual)
+			if data.Message == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrMismatch) || !strings.Contains(err.Error(), data.Message) || !strings.Contains(err.Error(), "first difference\n") {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Message, err)
+			}
+		})
+	}
+}
+
+func TestDiffDump(t *testing.T) {
+	// the dump starts at the line before the difference
+	err := Diff(make([]byte, 20), append(make([]byte, 19), 0x80))
+	lines := strings.Split(err.Error(), "\n")
+	if !strings.HasPrefix(lines[2], "    64  00") || !strings.HasPrefix(lines[3], "   128  00") {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "lines at bits 64 and 128", err)
+	}
+
+	// the actual bit stream is empty
+	err = Diff(make([]byte, 20), nil)
+	if !strings.Contains(err.Error(), "actual:\n  (ends before the difference)\n") {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "(ends before the difference)", err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "header.bin")
+
+	t.Setenv(UpdateEnv, "1")
+	Golden(t, path, []byte{0x41, 0x23})
+	actual, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if string(actual) != "\x41\x23" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0x41, 0x23}, actual)
+	}
+
+	t.Setenv(UpdateEnv, "")
+	Golden(t, path, []byte{0x41, 0x23})
+	err = CheckGolden(path, []byte{0x41, 0x22})
+	if !errors.Is(err, ErrMismatch) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrMismatch, err)
+	}
+	err = CheckGolden(filepath.Join(t.TempDir(), "missing.bin"), nil)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", os.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,69 @@
+package bitstreamtest
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+// Field is a field of a sequence written by WriteFields and read by ReadFields.
+type Field struct {
+	Name  string
+	NBits uint8  // 1 - 64
+	Value uint64 // LSB aligned
+}
+
+// WriteFields writes each of `fields` with WriteNamed.
+func WriteFields(w *bitstream.Writer, fields []Field) error {
+	for _, f := range fields {
+		err := w.WriteNamed(f.Name, f.NBits, f.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFields reads the fields of the names and the widths of `layout` with ReadNamed, and returns them with
+// the values read. On error, it returns the fields read so far.
+func ReadFields(r *bitstream.Reader, layout []Field) ([]Field, error) {
+	fields := make([]Field, 0, len(layout))
+	for _, f := range layout {
+		v, err := r.ReadNamed(f.Name, f.NBits)
+		if err != nil {
+			return fields, err
+		}
+		fields = append(fields, Field{Name: f.Name, NBits: f.NBits, Value: v})
+	}
+	return fields, nil
+}
+
+// Reads returns a decode function for RoundTrip which reads the fields of the layout of `fields` with ReadFields.
+func Reads(fields []Field) func(r *bitstream.Reader) ([]Field, error) {
+	return func(r *bitstream.Reader) ([]Field, error) {
+		return ReadFields(r, fields)
+	}
+}
+
+// RandomFields returns `n` fields named "f0", "f1", ... with random widths of 1 to `maxBits` (at most 64) bits.
+// A quarter of the values are the edge values, i.e. 0 or all ones, and the others are uniformly random.
+func RandomFields(rnd *rand.Rand, n int, maxBits uint8) []Field {
+	maxBits = min(max(maxBits, 1), 64)
+	fields := make([]Field, n)
+	for i := range fields {
+		nBits := uint8(rnd.Intn(int(maxBits))) + 1
+		mask := ^uint64(0) >> (64 - nBits)
+		var v uint64
+		switch rnd.Intn(8) {
+		case 0:
+			v = 0
+		case 1:
+			v = mask
+		default:
+			v = rnd.Uint64() & mask
+		}
+		fields[i] = Field{Name: fmt.Sprintf("f%d", i), NBits: nBits, Value: v}
+	}
+	return fields
+}
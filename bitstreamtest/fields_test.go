@@ -0,0 +1,58 @@
+package bitstreamtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func TestFieldsRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		fields := RandomFields(rnd, rnd.Intn(50), 64)
+		RoundTrip(t, fields, WriteFields, Reads(fields))
+	}
+}
+
+func TestRandomFields(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	fields := RandomFields(rnd, 1000, 5)
+	edges := 0
+	for i, f := range fields {
+		if f.NBits < 1 || f.NBits > 5 || f.Value >= 1<<f.NBits {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "1 - 5 bits", f)
+		}
+		if f.Name != fmt.Sprintf("f%d", i) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", fmt.Sprintf("f%d", i), f.Name)
+		}
+		if f.Value == 0 || f.Value == 1<<f.NBits-1 {
+			edges++
+		}
+	}
+	if edges < 250 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ">= 250 edge values", edges)
+	}
+
+	// the widths are clamped
+	for _, f := range RandomFields(rnd, 100, 0) {
+		if f.NBits != 1 {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 1, f.NBits)
+		}
+	}
+}
+
+func TestReadFieldsError(t *testing.T) {
+	layout := []Field{{Name: "a", NBits: 4}, {Name: "b", NBits: 8}}
+	fields, err := ReadFields(bitstream.NewReaderBytes([]byte{0x5a}, nil), layout)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+	expected := []Field{{Name: "a", NBits: 4, Value: 5}}
+	if len(fields) != 1 || fields[0] != expected[0] {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, fields)
+	}
+}
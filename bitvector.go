@@ -0,0 +1,133 @@
+package bitstream
+
+// BitVector is a growable sequence of bits in memory which can be accessed randomly.
+// The bits are stored from the MSB of the first byte in the same way as BitSlice.
+//
+// A BitVector is not safe for concurrent use by multiple goroutines.
+type BitVector struct {
+	data  []byte
+	nBits uint64
+}
+
+// NewBitVector creates a new BitVector instance which has `nBits` '0' bits.
+func NewBitVector(nBits uint64) *BitVector {
+	return &BitVector{
+		data:  make([]byte, (nBits+7)/8),
+		nBits: nBits,
+	}
+}
+
+// NewBitVectorFromBitSlice creates a new BitVector instance which has a copy of the bits in `bs`.
+func NewBitVectorFromBitSlice(bs BitSlice) *BitVector {
+	return &BitVector{
+		data:  append([]byte{}, bs.data...),
+		nBits: bs.nBits,
+	}
+}
+
+// Len returns the number of bits in the BitVector.
+func (v *BitVector) Len() uint64 {
+	return v.nBits
+}
+
+// Get returns the `i`-th bit (0 or 1). It panics if `i` is out of range.
+func (v *BitVector) Get(i uint64) uint8 {
+	if i >= v.nBits {
+		panic("bitstream: BitVector index out of range")
+	}
+	return (v.data[i/8] >> (7 - i%8)) & 0x01
+}
+
+// Set sets the `i`-th bit to the LSB of `bit`. It panics if `i` is out of range.
+func (v *BitVector) Set(i uint64, bit uint8) {
+	if i >= v.nBits {
+		panic("bitstream: BitVector index out of range")
+	}
+	mask := uint8(0x80) >> (i % 8)
+	if bit&0x01 != 0 {
+		v.data[i/8] |= mask
+	} else {
+		v.data[i/8] &^= mask
+	}
+}
+
+// Append appends a single bit (the LSB of `bit`) to the end of the BitVector.
+func (v *BitVector) Append(bit uint8) {
+	if v.nBits%8 == 0 {
+		v.data = append(v.data, 0)
+	}
+	v.nBits++
+	v.Set(v.nBits-1, bit)
+}
+
+// AppendBits appends the LSB `nBits` bits of `val` to the end of the BitVector.
+// `nBits` must be less than or equal to 64, otherwise returns an error.
+func (v *BitVector) AppendBits(nBits uint8, val uint64) error {
+	if nBits > 64 {
+		return ErrTooManyBits
+	}
+	for i := int(nBits) - 1; i >= 0; i-- {
+		v.Append(uint8(val >> uint(i)))
+	}
+	return nil
+}
+
+// AppendBitSlice appends all the bits in `bs` to the end of the BitVector.
+func (v *BitVector) AppendBitSlice(bs BitSlice) {
+	s := v.BitSlice().Append(bs)
+	v.data = s.data
+	v.nBits = s.nBits
+}
+
+// Slice returns a copy of the bits from `start` (inclusive) to `end` (exclusive) as a new BitVector.
+// It panics if the range is invalid, like slicing a Go slice.
+func (v *BitVector) Slice(start, end uint64) *BitVector {
+	return NewBitVectorFromBitSlice(v.BitSlice().Slice(start, end))
+}
+
+// BitSlice returns the bits in the BitVector as a BitSlice.
+// The BitSlice shares the memory with the BitVector, so it must not be used after the BitVector is modified.
+func (v *BitVector) BitSlice() BitSlice {
+	return BitSlice{
+		data:  v.data,
+		nBits: v.nBits,
+	}
+}
+
+// Bytes returns the bits packed in bytes (left aligned). The bits beyond Len in the last byte are '0'.
+// The returned slice shares the memory with the BitVector.
+func (v *BitVector) Bytes() []byte {
+	return v.data
+}
+
+// String returns the bits in binary, e.g. "1010 11".
+func (v *BitVector) String() string {
+	return v.BitSlice().String()
+}
+
+// ReadBitVector reads `nBits` bits from the bit stream and returns them as a BitVector.
+func (r *Reader) ReadBitVector(nBits uint) (*BitVector, error) {
+	pos := r.BitPosition()
+	data, err := r.readNBits(nBits, nil)
+	if err != nil {
+		return nil, r.wrapError("ReadBitVector", pos, err)
+	}
+
+	v := &BitVector{
+		data:  extractBits(data, 0, uint64(nBits)),
+		nBits: uint64(nBits),
+	}
+	r.trace("", pos, nBits, v)
+	return v, nil
+}
+
+// WriteBitVector writes all the bits in `v` to the bit stream.
+func (w *Writer) WriteBitVector(v *BitVector) error {
+	pos := w.bitPosition()
+	err := w.writeBitSlice(v.BitSlice())
+	if err != nil {
+		return w.wrapError("WriteBitVector", pos, err)
+	}
+	w.trace("", pos, uint(v.nBits), v)
+	return nil
+}
@@ -0,0 +1,81 @@
+package bitstream
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBitVector(t *testing.T) {
+	v := NewBitVector(10)
+	v.Set(0, 1)
+	v.Set(9, 1)
+	v.Set(3, 1)
+	v.Set(3, 0)
+	if v.String() != "1000 0000 01" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "1000 0000 01", v.String())
+	}
+
+	v.Append(1)
+	v.AppendBits(7, 0x55)
+	if v.Len() != 18 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 18, v.Len())
+	}
+	// 1000 0000 0111 0101 01
+	if !bytes.Equal([]byte{0x80, 0x75, 0x40}, v.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0x80, 0x75, 0x40}, v.Bytes())
+	}
+	if v.Get(10) != 1 || v.Get(11) != 1 || v.Get(12) != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []uint8{1, 1, 0}, []uint8{v.Get(10), v.Get(11), v.Get(12)})
+	}
+
+	s := v.Slice(9, 13)
+	if s.String() != "1110" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "1110", s.String())
+	}
+	// the slice is a copy
+	s.Set(0, 0)
+	if v.Get(9) != 1 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 1, v.Get(9))
+	}
+
+	bs, _ := NewBitSlice([]byte{0xe0}, 3)
+	s.AppendBitSlice(bs)
+	if s.String() != "0110 111" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "0110 111", s.String())
+	}
+}
+
+func TestBitVectorPanic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Get should panic\n")
+		}
+	}()
+	NewBitVector(8).Get(8)
+}
+
+func TestReadWriteBitVector(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x12, 0x34}), nil)
+	r.Skip(4)
+	v, err := r.ReadBitVector(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	// xxxx 0010 0011 01xx
+	if v.String() != "0010 0011 01" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "0010 0011 01", v.String())
+	}
+
+	v.Set(0, 1)
+	buf := bytes.NewBuffer([]byte{})
+	w := NewWriter(buf)
+	w.WriteNBitsOfUint8(6, 0)
+	err = w.WriteBitVector(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	// 0000 0010 1000 1101
+	if !bytes.Equal([]byte{0x02, 0x8d}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0x02, 0x8d}, buf.Bytes())
+	}
+}
@@ -0,0 +1,319 @@
+// Package cabac implements the context-adaptive binary arithmetic coding engine of H.264/AVC and H.265/HEVC
+// on top of bitstream.Writer / bitstream.Reader.
+//
+// Each bin (binary symbol) is coded either with an adaptive Context, which estimates the probability of the bin
+// with a 6-bit state and the most probable value, or in the bypass mode with the probability fixed to 1/2.
+// The end of the coded data is signalled with a terminating bin; encoding it as 1 flushes the encoder,
+// after which the last bit written is 1 and the Reader is positioned right after it when the decoder has decoded it.
+//
+// The state transition and the LPS range tables are the ones defined in the standards,
+// so the bins coded with the same contexts in the same order are bit-exact with them.
+package cabac
+
+import (
+	"errors"
+	"io"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+var (
+	// ErrFinished is returned when a bin is coded after the terminating bin 1.
+	ErrFinished = errors.New("cabac: coding already finished")
+
+	// ErrInvalidOffset is returned when the first 9 bits of the coded data are not a valid initial offset (510 or 511).
+	ErrInvalidOffset = errors.New("cabac: invalid initial offset")
+)
+
+// Context is an adaptive probability model of a bin.
+type Context struct {
+	State uint8 // probability state index (0 - 63), where a larger state means a more probable MPS
+	MPS   uint8 // value of the most probable symbol (0 or 1)
+}
+
+// NewContext initializes a context with the initialization values `m` and `n` given by the standards for the slice QP `qp`.
+func NewContext(m, n, qp int) Context {
+	pre := clip3(1, 126, (m*clip3(0, 51, qp))>>4+n)
+	if pre <= 63 {
+		return Context{State: uint8(63 - pre), MPS: 0}
+	}
+	return Context{State: uint8(pre - 64), MPS: 1}
+}
+
+func clip3(lo, hi, v int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// update updates the state of the context after a bin is coded.
+func (c *Context) update(isMPS bool) {
+	if isMPS {
+		c.State = transIdxMPS[c.State]
+		return
+	}
+	if c.State == 0 {
+		c.MPS = 1 - c.MPS
+	}
+	c.State = transIdxLPS[c.State]
+}
+
+// Encoder is a binary arithmetic encoder which writes to a bitstream.Writer.
+type Encoder struct {
+	w           *bitstream.Writer
+	low         uint32 // 10 bits
+	rng         uint32 // 9 bits
+	outstanding uint64 // number of bits whose value depends on a carry
+	first       bool   // the first bit is not written since it is always 0
+	finished    bool
+}
+
+// NewEncoder creates a new Encoder which writes to `w`.
+func NewEncoder(w *bitstream.Writer) *Encoder {
+	return &Encoder{
+		w:     w,
+		low:   0,
+		rng:   510,
+		first: true,
+	}
+}
+
+// EncodeDecision encodes `bin` (0 or 1) with the probability estimated by `ctx`, and updates `ctx`.
+func (e *Encoder) EncodeDecision(ctx *Context, bin uint8) error {
+	if e.finished {
+		return ErrFinished
+	}
+
+	lps := uint32(rangeTabLPS[ctx.State][(e.rng>>6)&3])
+	e.rng -= lps
+	if bin&1 != ctx.MPS {
+		e.low += e.rng
+		e.rng = lps
+		ctx.update(false)
+	} else {
+		ctx.update(true)
+	}
+	return e.renorm()
+}
+
+// EncodeBypass encodes `bin` (0 or 1) with the probability 1/2.
+func (e *Encoder) EncodeBypass(bin uint8) error {
+	if e.finished {
+		return ErrFinished
+	}
+
+	e.low <<= 1
+	if bin&1 == 1 {
+		e.low += e.rng
+	}
+	switch {
+	case e.low >= 1024:
+		e.low -= 1024
+		return e.putBit(1)
+	case e.low < 512:
+		return e.putBit(0)
+	default:
+		e.low -= 512
+		e.outstanding++
+		return nil
+	}
+}
+
+// EncodeBypassBits encodes the lower `nBits` bits of `val` in the bypass mode, MSB first.
+func (e *Encoder) EncodeBypassBits(nBits uint8, val uint64) error {
+	for i := int(nBits) - 1; i >= 0; i-- {
+		err := e.EncodeBypass(uint8(val >> i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeTerminate encodes the terminating bin. If `bin` is 1, the encoder is flushed and no more bins can be encoded.
+// The Writer is not finalized; the caller may continue writing other bits to it.
+func (e *Encoder) EncodeTerminate(bin uint8) error {
+	if e.finished {
+		return ErrFinished
+	}
+
+	e.rng -= 2
+	if bin&1 == 0 {
+		return e.renorm()
+	}
+
+	e.low += e.rng
+	e.finished = true
+	e.rng = 2
+	err := e.renorm()
+	if err != nil {
+		return err
+	}
+	err = e.putBit(uint8(e.low>>9) & 1)
+	if err != nil {
+		return err
+	}
+	return e.w.WriteNBitsOfUint8(2, uint8(e.low>>7)&3|1)
+}
+
+// Finish encodes the terminating bin 1, which flushes the encoder.
+func (e *Encoder) Finish() error {
+	return e.EncodeTerminate(1)
+}
+
+func (e *Encoder) renorm() error {
+	for e.rng < 256 {
+		var err error
+		switch {
+		case e.low < 256:
+			err = e.putBit(0)
+		case e.low >= 512:
+			e.low -= 512
+			err = e.putBit(1)
+		default:
+			e.low -= 256
+			e.outstanding++
+		}
+		if err != nil {
+			return err
+		}
+		e.rng <<= 1
+		e.low <<= 1
+	}
+	return nil
+}
+
+// putBit writes `bit` followed by the outstanding bits, which are the opposite of `bit`.
+func (e *Encoder) putBit(bit uint8) error {
+	if e.first {
+		e.first = false
+	} else {
+		err := e.w.WriteBit(bit)
+		if err != nil {
+			return err
+		}
+	}
+	if e.outstanding > 0 {
+		err := e.w.WriteRun(1-bit, e.outstanding)
+		if err != nil {
+			return err
+		}
+		e.outstanding = 0
+	}
+	return nil
+}
+
+// Decoder is a binary arithmetic decoder which reads from a bitstream.Reader.
+type Decoder struct {
+	r        *bitstream.Reader
+	offset   uint32 // 9 bits
+	rng      uint32 // 9 bits
+	finished bool
+}
+
+// NewDecoder creates a new Decoder which reads from `r`. It reads the first 9 bits of the coded data.
+func NewDecoder(r *bitstream.Reader) (*Decoder, error) {
+	offset, err := r.ReadNBitsAsUint16BE(9)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= 510 {
+		return nil, ErrInvalidOffset
+	}
+	return &Decoder{
+		r:      r,
+		offset: uint32(offset),
+		rng:    510,
+	}, nil
+}
+
+// DecodeDecision decodes a bin with the probability estimated by `ctx`, and updates `ctx`.
+func (d *Decoder) DecodeDecision(ctx *Context) (uint8, error) {
+	if d.finished {
+		return 0, ErrFinished
+	}
+
+	lps := uint32(rangeTabLPS[ctx.State][(d.rng>>6)&3])
+	d.rng -= lps
+	bin := ctx.MPS
+	if d.offset >= d.rng {
+		bin = 1 - ctx.MPS
+		d.offset -= d.rng
+		d.rng = lps
+		ctx.update(false)
+	} else {
+		ctx.update(true)
+	}
+	return bin, d.renorm()
+}
+
+// DecodeBypass decodes a bin coded in the bypass mode.
+func (d *Decoder) DecodeBypass() (uint8, error) {
+	if d.finished {
+		return 0, ErrFinished
+	}
+
+	b, err := d.readBit()
+	if err != nil {
+		return 0, err
+	}
+	d.offset = d.offset<<1 | uint32(b)
+	if d.offset >= d.rng {
+		d.offset -= d.rng
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// DecodeBypassBits decodes `nBits` bins coded in the bypass mode and returns them as an integer, MSB first.
+func (d *Decoder) DecodeBypassBits(nBits uint8) (uint64, error) {
+	v := uint64(0)
+	for i := uint8(0); i < nBits; i++ {
+		b, err := d.DecodeBypass()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | uint64(b)
+	}
+	return v, nil
+}
+
+// DecodeTerminate decodes the terminating bin.
+// If it is 1, the decoding is finished and the Reader is positioned right after the coded data.
+func (d *Decoder) DecodeTerminate() (uint8, error) {
+	if d.finished {
+		return 0, ErrFinished
+	}
+
+	d.rng -= 2
+	if d.offset >= d.rng {
+		d.finished = true
+		return 1, nil
+	}
+	return 0, d.renorm()
+}
+
+func (d *Decoder) renorm() error {
+	for d.rng < 256 {
+		b, err := d.readBit()
+		if err != nil {
+			return err
+		}
+		d.rng <<= 1
+		d.offset = d.offset<<1 | uint32(b)
+	}
+	return nil
+}
+
+// readBit reads a bit of the coded data. The coded data never ends before the terminating bin.
+func (d *Decoder) readBit() (uint8, error) {
+	b, err := d.r.ReadBit()
+	if errors.Is(err, io.EOF) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return b, err
+}
@@ -0,0 +1,204 @@
+package cabac
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func TestNewContext(t *testing.T) {
+	testData := []struct {
+		Name     string
+		M, N, QP int
+		Expected Context
+	}{
+		{Name: "pattern 1", M: 0, N: 64, QP: 26, Expected: Context{State: 0, MPS: 1}},
+		{Name: "pattern 2", M: 0, N: 63, QP: 26, Expected: Context{State: 0, MPS: 0}},
+		{Name: "pattern 3", M: 20, N: -15, QP: 26, Expected: Context{State: 46, MPS: 0}},  // (20*26)>>4 - 15 = 17
+		{Name: "pattern 4", M: 0, N: 127, QP: 26, Expected: Context{State: 62, MPS: 1}},   // clipped to 126
+		{Name: "pattern 5", M: -28, N: 127, QP: 99, Expected: Context{State: 26, MPS: 0}}, // QP clipped to 51: -90 + 127 = 37
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			ctx := NewContext(data.M, data.N, data.QP)
+			if data.Expected != ctx {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, ctx)
+			}
+		})
+	}
+}
+
+type bin struct {
+	mode uint8 // 0: decision, 1: bypass, 2: terminate
+	ctx  int
+	val  uint8
+}
+
+func TestEncodeDecode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	testData := []struct {
+		Name string
+		P1   float64 // probability of 1 of the decision bins
+		N    int
+	}{
+		{Name: "pattern 1", P1: 0.5, N: 0},
+		{Name: "pattern 2", P1: 0.5, N: 10000},
+		{Name: "pattern 3", P1: 0.05, N: 10000},
+		{Name: "pattern 4", P1: 0.999, N: 10000},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			bins := make([]bin, data.N)
+			for i := range bins {
+				b := bin{ctx: rnd.Intn(4)}
+				if rnd.Float64() < data.P1 {
+					b.val = 1
+				}
+				switch x := rnd.Intn(100); {
+				case x < 10:
+					b.mode = 1
+					b.val = uint8(rnd.Intn(2))
+				case x < 11:
+					b.mode = 2
+					b.val = 0
+				}
+				bins[i] = b
+			}
+
+			buf := bytes.NewBuffer([]byte{})
+			w := bitstream.NewWriter(buf)
+			w.WriteNBitsOfUint8(3, 0x5) // 101
+			e := NewEncoder(w)
+			ctxs := make([]Context, 4)
+			for _, b := range bins {
+				var err error
+				switch b.mode {
+				case 0:
+					err = e.EncodeDecision(&ctxs[b.ctx], b.val)
+				case 1:
+					err = e.EncodeBypass(b.val)
+				case 2:
+					err = e.EncodeTerminate(b.val)
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+			}
+			err := e.Finish()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.WriteNBitsOfUint8(5, 0x1b) // 1 1011
+			w.Finalize()
+
+			r := bitstream.NewReader(buf, nil)
+			r.Skip(3)
+			d, err := NewDecoder(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			ctxs = make([]Context, 4)
+			for i, b := range bins {
+				var v uint8
+				switch b.mode {
+				case 0:
+					v, err = d.DecodeDecision(&ctxs[b.ctx])
+				case 1:
+					v, err = d.DecodeBypass()
+				case 2:
+					v, err = d.DecodeTerminate()
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if b.val != v {
+					t.Fatalf("\nbin %d\nExpected: %+v\nActual:   %+v\n", i, b.val, v)
+				}
+			}
+			v, err := d.DecodeTerminate()
+			if err != nil || v != 1 {
+				t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 1, nil, v, err)
+			}
+
+			// the bits after the coded data
+			trailer, err := r.ReadNBitsAsUint8(5)
+			if err != nil || trailer != 0x1b {
+				t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0x1b, nil, trailer, err)
+			}
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	e := NewEncoder(w)
+	ctx := Context{}
+	for i := 0; i < 80000; i++ {
+		b := uint8(0)
+		if rnd.Float64() < 0.02 {
+			b = 1
+		}
+		e.EncodeDecision(&ctx, b)
+	}
+	e.Finish()
+	w.Finalize()
+
+	// entropy of p = 0.02 is about 0.14 bits per bin, i.e. 1414 bytes for 80000 bins
+	if buf.Len() > 1600 {
+		t.Fatalf("\nExpected: <= %+v\nActual:   %+v\n", 1600, buf.Len())
+	}
+}
+
+func TestBypassBits(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	e := NewEncoder(w)
+	e.EncodeBypassBits(12, 0xabc)
+	e.Finish()
+	w.Finalize()
+
+	d, err := NewDecoder(bitstream.NewReader(buf, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	v, err := d.DecodeBypassBits(12)
+	if err != nil || v != 0xabc {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0xabc, nil, v, err)
+	}
+}
+
+func TestError(t *testing.T) {
+	e := NewEncoder(bitstream.NewWriter(&bytes.Buffer{}))
+	e.Finish()
+	err := e.EncodeBypass(0)
+	if !errors.Is(err, ErrFinished) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrFinished, err)
+	}
+
+	// 1111 1111 1xxx xxxx
+	_, err = NewDecoder(bitstream.NewReader(bytes.NewReader([]byte{0xff, 0x80}), nil))
+	if !errors.Is(err, ErrInvalidOffset) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidOffset, err)
+	}
+
+	// the coded data is cut off
+	d, err := NewDecoder(bitstream.NewReader(bytes.NewReader([]byte{0x00, 0x00}), nil))
+	for err == nil {
+		_, err = d.DecodeBypass()
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
@@ -0,0 +1,85 @@
+package bitstream
+
+import (
+	"io"
+	"sync"
+)
+
+// NewChanReader creates a Reader which reads the chunks of bytes received from `ch` as they arrive,
+// e.g. the packets from a socket, an SDR or a serial port pushed by a capture goroutine, without an io.Pipe in between.
+//
+// A read blocks while the Reader needs more bits than the chunks received so far; the chunks are received one by one
+// only when they are needed, so a bounded `ch` applies backpressure to the producer.
+// The Reader copies the bytes of a chunk into its own buffer and does not refer to the chunk once all of them are copied,
+// which is before the next chunk is received; with an unbuffered `ch`, a producer can reuse a chunk once the send of
+// the following chunk has completed.
+// Once the producer closes `ch` and the Reader has read all the bits, subsequent reads return io.EOF.
+func NewChanReader(ch <-chan []byte, opt *ReaderOptions) *ChanReader {
+	src := &chanSource{ch: ch, done: make(chan struct{})}
+	return &ChanReader{Reader: NewReader(src, opt), src: src}
+}
+
+// ChanReader is a Reader which reads the chunks received from a channel.
+type ChanReader struct {
+	*Reader
+	src *chanSource
+}
+
+// Close stops receiving from the channel and closes Done, so that the producer can stop sending.
+// It can be called from another goroutine to unblock a read waiting for a chunk, which then returns ErrClosed.
+// The bits already in the buffer of the Reader can still be read. It does not close the channel.
+func (r *ChanReader) Close() error {
+	r.src.close()
+	return nil
+}
+
+// Done returns a channel which is closed when the ChanReader is closed. A producer selects on it to stop sending, e.g.
+//
+//	select {
+//	case ch <- chunk:
+//	case <-r.Done():
+//		return
+//	}
+func (r *ChanReader) Done() <-chan struct{} {
+	return r.src.done
+}
+
+// chanSource is an io.Reader which reads the chunks received from a channel.
+type chanSource struct {
+	ch        <-chan []byte
+	chunk     []byte // the rest of the current chunk
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Read copies the rest of the current chunk, blocking until a chunk is received if there is none.
+func (s *chanSource) Read(b []byte) (int, error) {
+	for len(s.chunk) == 0 {
+		select {
+		case <-s.done:
+			return 0, ErrClosed
+		default:
+		}
+		if len(b) == 0 {
+			return 0, nil
+		}
+		select {
+		case chunk, ok := <-s.ch:
+			if !ok {
+				return 0, io.EOF
+			}
+			s.chunk = chunk
+		case <-s.done:
+			return 0, ErrClosed
+		}
+	}
+	n := copy(b, s.chunk)
+	s.chunk = s.chunk[n:]
+	return n, nil
+}
+
+func (s *chanSource) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
@@ -0,0 +1,82 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestChanReader(t *testing.T) {
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 2, Prefetch: true}} {
+		ch := make(chan []byte) // unbuffered, so that each chunk is sent only when the reader needs it
+		r := NewChanReader(ch, opt)
+
+		go func() {
+			// 12-bit values 0 - 999 split into chunks of various sizes, including an empty one
+			buf := &bytes.Buffer{}
+			w := NewWriter(buf)
+			for i := 0; i < 1000; i++ {
+				_ = w.WriteNBitsOfUint16BE(12, uint16(i))
+			}
+			_ = w.Close()
+			data := buf.Bytes()
+			for size := 0; len(data) > 0; size = (size + 7) % 13 {
+				n := min(size, len(data))
+				select {
+				case ch <- data[:n]:
+				case <-r.Done():
+					return
+				}
+				data = data[n:]
+			}
+			close(ch)
+		}()
+
+		for i := 0; i < 1000; i++ {
+			v, err := r.ReadNBitsAsUint16BE(12)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if uint16(i) != v {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", i, v)
+			}
+		}
+		_, err := r.ReadBit()
+		if !errors.Is(err, io.EOF) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+		}
+	}
+}
+
+func TestChanReaderClose(t *testing.T) {
+	ch := make(chan []byte, 1)
+	r := NewChanReader(ch, nil)
+	ch <- []byte{0xa5}
+
+	b, err := r.ReadUint8()
+	if err != nil || b != 0xa5 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0xa5, nil, b, err)
+	}
+
+	// Close unblocks the read waiting for the next chunk
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = r.Close()
+	}()
+	_, err = r.ReadUint8()
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrClosed, err)
+	}
+
+	select {
+	case <-r.Done():
+	default:
+		t.Fatalf("Done is not closed\n")
+	}
+	err = r.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+}
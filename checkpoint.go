@@ -0,0 +1,96 @@
+package bitstream
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Checkpoint is a position in a bit stream from which a Reader can resume reading, even in another process.
+// It can be serialized with MarshalBinary and restored with UnmarshalBinary.
+type Checkpoint struct {
+	ByteOffset uint64 // offset of the byte which contains the next bit to be read
+	BitPhase   uint8  // number of bits of that byte which have already been read (0 - 7)
+
+	// Byte is the value of that byte if BitPhase is not 0. It is used to check that the source has not been changed.
+	Byte uint8
+}
+
+// BitPosition returns the offset of the next bit to be read.
+func (c Checkpoint) BitPosition() uint64 {
+	return c.ByteOffset*8 + uint64(c.BitPhase)
+}
+
+// Checkpoint returns the current position of the Reader as a Checkpoint.
+// The offset is relative to the position of the source when the Reader was created (or to the beginning of the source
+// if the Reader was created by ResumeReader), so the source should be at its beginning when the Reader is created.
+func (r *Reader) Checkpoint() Checkpoint {
+	pos := r.BitPosition()
+	c := Checkpoint{
+		ByteOffset: pos / 8,
+		BitPhase:   uint8(pos % 8),
+	}
+	if c.BitPhase != 0 {
+		// the byte has been loaded into the buffer since some of its bits have been read
+		c.Byte = r.buf[r.currByteIndex]
+	}
+	return c
+}
+
+// ResumeReader creates a new Reader which reads `src` from the position recorded in `c`.
+// `src` is seeked to the byte offset of the checkpoint from its beginning.
+// It returns ErrCheckpointMismatch if the byte at the offset differs from the one recorded in the checkpoint.
+func ResumeReader(src io.ReadSeeker, c Checkpoint, opt *ReaderOptions) (*Reader, error) {
+	if c.BitPhase > 7 || c.ByteOffset > math.MaxInt64 {
+		return nil, ErrInvalidCheckpoint
+	}
+	_, err := src.Seek(int64(c.ByteOffset), io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewReader(src, opt)
+	r.consumedBytes = uint(c.ByteOffset)
+	if c.BitPhase == 0 {
+		return r, nil
+	}
+
+	err = r.fillBuf()
+	if err != nil {
+		return nil, wrapError("ResumeReader", c.ByteOffset*8, unexpectedEOF(err))
+	}
+	if r.buf[r.currByteIndex] != c.Byte {
+		return nil, wrapError("ResumeReader", c.ByteOffset*8, fmt.Errorf("%w: byte %#02x, expected %#02x", ErrCheckpointMismatch, r.buf[r.currByteIndex], c.Byte))
+	}
+	r.currBitIndex = 7 - c.BitPhase
+	return r, nil
+}
+
+// MarshalBinary encodes the checkpoint into a compact form: the byte offset in uvarint, followed by
+// the bit phase and the byte if the bit phase is not 0.
+func (c Checkpoint) MarshalBinary() ([]byte, error) {
+	data := binary.AppendUvarint(nil, c.ByteOffset)
+	if c.BitPhase != 0 {
+		data = append(data, c.BitPhase, c.Byte)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a checkpoint encoded by MarshalBinary.
+func (c *Checkpoint) UnmarshalBinary(data []byte) error {
+	off, n := binary.Uvarint(data)
+	if n <= 0 {
+		return ErrInvalidCheckpoint
+	}
+	rest := data[n:]
+	switch {
+	case len(rest) == 0:
+		*c = Checkpoint{ByteOffset: off}
+	case len(rest) == 2 && rest[0] > 0 && rest[0] < 8:
+		*c = Checkpoint{ByteOffset: off, BitPhase: rest[0], Byte: rest[1]}
+	default:
+		return ErrInvalidCheckpoint
+	}
+	return nil
+}
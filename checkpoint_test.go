@@ -0,0 +1,87 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	src := make([]byte, 300)
+	for i := range src {
+		src[i] = uint8(i * 37)
+	}
+
+	testData := []struct {
+		Name     string
+		Skip     uint
+		Expected []byte // serialized checkpoint
+	}{
+		{Name: "pattern 1", Skip: 0, Expected: []byte{0x00}},
+		{Name: "pattern 2", Skip: 13, Expected: []byte{0x01, 0x05, 0x25}},         // byte 1 (0x25), bit 5
+		{Name: "pattern 3", Skip: 1203, Expected: []byte{0x96, 0x01, 0x03, 0xae}}, // byte 150 (0xae), bit 3
+		{Name: "pattern 4", Skip: 2400, Expected: []byte{0xac, 0x02}},             // the end (300)
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			opt := &ReaderOptions{BufferSize: 16}
+			r := NewReader(bytes.NewReader(src), opt)
+			err := r.Skip(data.Skip)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			actual, err := r.Checkpoint().MarshalBinary()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !bytes.Equal(data.Expected, actual) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, actual)
+			}
+
+			var c Checkpoint
+			err = c.UnmarshalBinary(actual)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			resumed, err := ResumeReader(bytes.NewReader(src), c, opt)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if uint64(data.Skip) != resumed.BitPosition() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Skip, resumed.BitPosition())
+			}
+
+			// the resumed Reader reads the same bits as the original one
+			expectedRest, expectedBits, err1 := r.ReadAll()
+			actualRest, actualBits, err2 := resumed.ReadAll()
+			if !reflect.DeepEqual([]any{expectedRest, expectedBits, err1}, []any{actualRest, actualBits, err2}) {
+				t.Fatalf("\nExpected: %+v, %+v, %+v\nActual:   %+v, %+v, %+v\n", expectedRest, expectedBits, err1, actualRest, actualBits, err2)
+			}
+		})
+	}
+}
+
+func TestCheckpointError(t *testing.T) {
+	src := []byte{0x12, 0x34}
+
+	_, err := ResumeReader(bytes.NewReader(src), Checkpoint{ByteOffset: 1, BitPhase: 2, Byte: 0x35}, nil)
+	if !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrCheckpointMismatch, err)
+	}
+
+	_, err = ResumeReader(bytes.NewReader(src), Checkpoint{ByteOffset: 2, BitPhase: 2}, nil)
+	if !errors.Is(err, ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrUnexpectedEOF, err)
+	}
+
+	for _, data := range [][]byte{{}, {0x80}, {0x01, 0x00, 0x12}, {0x01, 0x08, 0x12}, {0x01, 0x01}} {
+		var c Checkpoint
+		err := c.UnmarshalBinary(data)
+		if !errors.Is(err, ErrInvalidCheckpoint) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidCheckpoint, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bearmini/bitstream-go"
+	"github.com/bearmini/bitstream-go/schema"
+)
+
+type config struct {
+	spec         string
+	schemaFile   string
+	bitRange     string
+	offset       uint64
+	bytesPerLine uint
+	json         bool
+}
+
+// run dumps `file` (or `stdin` if it is "-") to `stdout` as configured by `cfg`.
+// If the decode fails, the fields decoded so far are printed before the error is returned.
+func run(cfg *config, file string, stdin io.Reader, stdout io.Writer) error {
+	modes := 0
+	for _, s := range []string{cfg.spec, cfg.schemaFile, cfg.bitRange} {
+		if s != "" {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return errors.New("exactly one of -spec, -schema and -range is required")
+	}
+
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cfg.bitRange != "" {
+		start, nBits, err := parseRange(cfg.bitRange)
+		if err != nil {
+			return err
+		}
+		if start+nBits > uint64(len(data))*8 {
+			return fmt.Errorf("range %d:%d exceeds the %d bits of the data", start, nBits, len(data)*8)
+		}
+		fields := []bitstream.Field{{Name: fmt.Sprintf("bits %d - %d", start, start+nBits-1), BitOffset: start, NBits: uint(nBits)}}
+		return dumpFields(stdout, data, fields, cfg.bytesPerLine)
+	}
+
+	src := cfg.spec
+	if cfg.schemaFile != "" {
+		b, err := os.ReadFile(cfg.schemaFile)
+		if err != nil {
+			return err
+		}
+		src = string(b)
+	} else {
+		src, err = specToSchema(cfg.spec)
+		if err != nil {
+			return err
+		}
+	}
+	s, err := schema.Parse(src)
+	if err != nil {
+		return err
+	}
+
+	var fields bitstream.FieldLog
+	r := bitstream.NewReaderBytes(data, &bitstream.ReaderOptions{TraceHook: fields.Trace})
+	err = r.SeekBit(cfg.offset)
+	if err != nil {
+		return err
+	}
+	_, decodeErr := s.Decode(r)
+
+	if cfg.json {
+		err = bitstream.WriteReportJSON(stdout, fields, data)
+	} else {
+		err = dumpFields(stdout, data, fields, cfg.bytesPerLine)
+	}
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// specToSchema converts a spec, e.g. "version:u4,ihl:4,flag:bool,s12", to the schema DSL.
+// An unnamed field is named after its index, e.g. "field3".
+func specToSchema(spec string) (string, error) {
+	sb := &strings.Builder{}
+	for i, f := range strings.Split(spec, ",") {
+		name, typ, ok := strings.Cut(strings.TrimSpace(f), ":")
+		if !ok {
+			name, typ = fmt.Sprintf("field%d", i), name
+		}
+		if typ == "" {
+			return "", fmt.Errorf("spec: field %d has no type", i)
+		}
+		if _, err := strconv.ParseUint(typ, 10, 8); err == nil {
+			typ = "u" + typ
+		}
+		fmt.Fprintf(sb, "%s %s\n", name, typ)
+	}
+	return sb.String(), nil
+}
+
+// parseRange parses "start:nBits".
+func parseRange(s string) (uint64, uint64, error) {
+	a, b, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q: start:nBits is expected", s)
+	}
+	start, err := strconv.ParseUint(a, 0, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %w", s, err)
+	}
+	nBits, err := strconv.ParseUint(b, 0, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %w", s, err)
+	}
+	if nBits == 0 || start+nBits < start {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	return start, nBits, nil
+}
+
+// dumpFields dumps the bytes of `data` which `fields` span, with the fields marked.
+func dumpFields(w io.Writer, data []byte, fields []bitstream.Field, bytesPerLine uint) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	start, end := fields[0].BitOffset, uint64(0)
+	for _, f := range fields {
+		start = min(start, f.BitOffset)
+		end = max(end, f.BitOffset+uint64(f.NBits))
+	}
+	first, last := start/8, min((end+7)/8, uint64(len(data)))
+	_, err := io.WriteString(w, bitstream.Dump(data[first:last], fields, &bitstream.DumpOptions{
+		BytesPerLine: bytesPerLine,
+		BitOffset:    first * 8,
+	}))
+	return err
+}
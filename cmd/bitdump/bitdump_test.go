@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, data, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	return path
+}
+
+func TestRunSpec(t *testing.T) {
+	file := writeTemp(t, "data.bin", []byte{0x45, 0x00, 0x00, 0x54, 0xab})
+	out := &bytes.Buffer{}
+	err := run(&config{spec: "version:u4,ihl:4,tos:u8,length:u16", bytesPerLine: 8}, file, nil, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	for _, expected := range []string{"45 00 00 54", "version = 4", "ihl = 5", "tos = 0", "length = 84"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "ab") {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "no bytes beyond the fields", out.String())
+	}
+}
+
+func TestRunSchemaJSON(t *testing.T) {
+	file := writeTemp(t, "data.bin", []byte{0x45, 0x00})
+	schemaFile := writeTemp(t, "header.schema", []byte("version u4\nihl u4\n"))
+	out := &bytes.Buffer{}
+	err := run(&config{schemaFile: schemaFile, offset: 4, json: true}, file, nil, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	for _, expected := range []string{`"name": "version"`, `"bitOffset": 4`, `"value": 5`, `"name": "ihl"`, `"value": 0`} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, out.String())
+		}
+	}
+}
+
+func TestRunRange(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := run(&config{bitRange: "12:10", bytesPerLine: 4}, "-", bytes.NewReader([]byte{0x45, 0x00, 0x00, 0x54}), out)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	for _, expected := range []string{"00 00", "bits 12 - 21"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, out.String())
+		}
+	}
+}
+
+func TestRunDecodeError(t *testing.T) {
+	// the fields decoded before the error are printed
+	out := &bytes.Buffer{}
+	err := run(&config{spec: "a:u4,b:u16", bytesPerLine: 8}, "-", bytes.NewReader([]byte{0x12}), out)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+	if !strings.Contains(out.String(), "a = 1") {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "a = 1", out.String())
+	}
+}
+
+func TestRunError(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Config   config
+		Expected string
+	}{
+		{Name: "pattern 1", Config: config{}, Expected: "exactly one of -spec, -schema and -range is required"},
+		{Name: "pattern 2", Config: config{spec: "u4", bitRange: "0:4"}, Expected: "exactly one of -spec, -schema and -range is required"},
+		{Name: "pattern 3", Config: config{bitRange: "4"}, Expected: `invalid range "4"`},
+		{Name: "pattern 4", Config: config{bitRange: "0:0"}, Expected: `invalid range "0:0"`},
+		{Name: "pattern 5", Config: config{bitRange: "4:13"}, Expected: "range 4:13 exceeds the 16 bits of the data"},
+		{Name: "pattern 6", Config: config{spec: "a:"}, Expected: "spec: field 0 has no type"},
+		{Name: "pattern 7", Config: config{spec: "a:x7"}, Expected: "x7"},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			err := run(&data.Config, "-", bytes.NewReader([]byte{0x12, 0x34}), io.Discard)
+			if err == nil || !strings.Contains(err.Error(), data.Expected) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+		})
+	}
+}
+
+func TestSpecToSchema(t *testing.T) {
+	actual, err := specToSchema("version:u4, 4,flag:bool,s12")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expected := "version u4\nfield1 u4\nflag bool\nfield3 s12\n"
+	if expected != actual {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, actual)
+	}
+}
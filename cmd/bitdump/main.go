@@ -0,0 +1,49 @@
+// Command bitdump prints an annotated decode of a binary file with the bit offsets of the fields,
+// or a hex and binary dump of a range of bits.
+//
+// Usage:
+//
+//	bitdump -spec version:u4,ihl:u4,tos:u8,length:u16 [-offset bits] [-bytes-per-line n] [-json] file
+//	bitdump -schema header.schema [-offset bits] [-bytes-per-line n] [-json] file
+//	bitdump -range 100:24 [-bytes-per-line n] file
+//
+// A spec is a comma-separated list of fields, each of which is `name:type` or just `type` for an unnamed one,
+// where the type is uN, sN or bool as in the schema DSL of the package schema, or a bare width N for uN.
+// A schema file is decoded with schema.Decode, so it may have blocks, arrays, conditions and bytes.
+//
+// The decode starts at the bit `-offset` and the bytes which the fields span are dumped with each field marked
+// under its bits; with -json, the fields are printed as a report of bitstream.WriteReportJSON instead.
+// With -range start:nBits, the bytes which the range spans are dumped with the range marked.
+// If the file is "-", the standard input is read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.spec, "spec", "", "comma-separated list of fields, e.g. version:u4,ihl:u4,length:u16")
+	flag.StringVar(&cfg.schemaFile, "schema", "", "schema file to decode the data with")
+	flag.StringVar(&cfg.bitRange, "range", "", "range of bits to dump, start:nBits")
+	flag.Uint64Var(&cfg.offset, "offset", 0, "bit offset to start decoding at")
+	flag.UintVar(&cfg.bytesPerLine, "bytes-per-line", 8, "number of bytes per line of the dump")
+	flag.BoolVar(&cfg.json, "json", false, "print the decoded fields in JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: bitdump (-spec fields | -schema file | -range start:nBits) [options] file\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := run(&cfg, flag.Arg(0), os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bitdump: %v\n", err)
+		os.Exit(1)
+	}
+}
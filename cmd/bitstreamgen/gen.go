@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const header = "// Code generated by bitstreamgen; DO NOT EDIT.\n"
+
+// loadPackage parses and type-checks the Go files of the package in `dir`, except test files and `skip`.
+// Type errors are ignored so that the package can be loaded even if a previously generated file is stale or missing.
+func loadPackage(dir, skip string) (*types.Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == skip {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 && f.Name.Name != files[0].Name.Name {
+			return nil, fmt.Errorf("multiple packages in %s: %s and %s", dir, files[0].Name.Name, f.Name.Name)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	conf := types.Config{Importer: importer.Default(), Error: func(error) {}}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, nil)
+	return pkg, nil
+}
+
+// generator generates the methods of the types in a package.
+type generator struct {
+	pkg *types.Package
+	buf *bytes.Buffer // the output, or the code of the function being generated
+
+	// the temporary variables used by the function being generated
+	temps map[string]string
+}
+
+// generate returns the gofmt-ed source code of the MarshalBits and UnmarshalBits methods of `typeNames`.
+func generate(pkg *types.Package, typeNames []string) ([]byte, error) {
+	g := &generator{pkg: pkg, buf: &bytes.Buffer{}}
+	fmt.Fprintf(g.buf, "%s\npackage %s\n\nimport \"github.com/bearmini/bitstream-go\"\n", header, pkg.Name())
+
+	for _, name := range typeNames {
+		obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("type %s is not found", name)
+		}
+		st, ok := obj.Type().Underlying().(*types.Struct)
+		if !ok {
+			return nil, fmt.Errorf("type %s is not a struct", name)
+		}
+		err := g.marshal(name, st)
+		if err != nil {
+			return nil, err
+		}
+		err = g.unmarshal(name, st)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code is broken: %w", err)
+	}
+	return src, nil
+}
+
+// field is a struct field to be encoded.
+type field struct {
+	name  string
+	typ   types.Type
+	nBits uint8 // 0 if not specified by the tag
+}
+
+// fields returns the fields of `st` to be encoded, in the order of declaration.
+func fields(typeName string, st *types.Struct) ([]field, error) {
+	var fs []field
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup("bits")
+		if tag == "-" {
+			continue
+		}
+		f := field{name: v.Name(), typ: v.Type()}
+		if ok {
+			n, err := strconv.ParseUint(tag, 10, 8)
+			if err != nil || n == 0 || n > 64 {
+				return nil, fmt.Errorf("%s.%s: invalid tag `bits:%q`", typeName, v.Name(), tag)
+			}
+			f.nBits = uint8(n)
+		}
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(g.buf, format, args...)
+}
+
+func (g *generator) printCheck() {
+	g.printf("if err != nil {\nreturn err\n}\n")
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(g.pkg))
+}
+
+// convertTo returns `x` of type `t` converted to the basic type `typ`.
+func convertTo(typ *types.Basic, t types.Type, x string) string {
+	if types.Identical(t, typ) {
+		return x
+	}
+	return typ.Name() + "(" + x + ")"
+}
+
+// convertFrom returns `x` of the basic type `typ` converted to `t`.
+func (g *generator) convertFrom(t types.Type, typ *types.Basic, x string) string {
+	if types.Identical(t, typ) {
+		return x
+	}
+	return g.typeString(t) + "(" + x + ")"
+}
+
+// body writes the function body which consists of `code`, declaring `err` and the temporary variables if used.
+func (g *generator) body(code string) {
+	if code == "" {
+		g.printf("return nil\n}\n")
+		return
+	}
+	g.printf("var err error\n")
+	names := make([]string, 0, len(g.temps))
+	for name := range g.temps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.printf("var %s %s\n", name, g.temps[name])
+	}
+	g.printf("%sreturn nil\n}\n", code)
+}
+
+// emit returns the code generated by `gen` for each field of `st`, and collects the temporary variables used by it.
+func (g *generator) emit(typeName string, st *types.Struct, gen func(f field, x string) error) (string, error) {
+	fs, err := fields(typeName, st)
+	if err != nil {
+		return "", err
+	}
+
+	out := g.buf
+	g.buf = &bytes.Buffer{}
+	g.temps = map[string]string{}
+	for _, f := range fs {
+		x := "v." + f.name
+		err := gen(f, x)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", typeName, f.name, err)
+		}
+	}
+	code := g.buf.String()
+	g.buf = out
+	return code, nil
+}
+
+func (g *generator) marshal(typeName string, st *types.Struct) error {
+	code, err := g.emit(typeName, st, func(f field, x string) error {
+		if f.name == "_" {
+			return g.marshalReserved(f)
+		}
+		return g.marshalValue(f.typ, f.nBits, x, 0)
+	})
+	if err != nil {
+		return err
+	}
+	g.printf("\n// MarshalBits writes the fields of v to w.\n")
+	g.printf("func (v *%s) MarshalBits(w *bitstream.Writer) error {\n", typeName)
+	g.body(code)
+	return nil
+}
+
+func (g *generator) unmarshal(typeName string, st *types.Struct) error {
+	code, err := g.emit(typeName, st, func(f field, x string) error {
+		if f.name == "_" {
+			return g.unmarshalReserved(f)
+		}
+		return g.unmarshalValue(f.typ, f.nBits, x, 0)
+	})
+	if err != nil {
+		return err
+	}
+	g.printf("\n// UnmarshalBits reads the fields of v from r.\n")
+	g.printf("func (v *%s) UnmarshalBits(r *bitstream.Reader) error {\n", typeName)
+	g.body(code)
+	return nil
+}
+
+// scalar describes how a value of a basic type is encoded.
+type scalar struct {
+	kind   types.BasicKind // types.Bool or an integer kind
+	size   uint8           // size of the Go type in bits
+	nBits  uint8           // number of bits in the bit stream
+	signed bool
+}
+
+var scalarKinds = map[types.BasicKind]scalar{
+	types.Bool:   {kind: types.Bool, size: 1},
+	types.Uint8:  {kind: types.Uint8, size: 8},
+	types.Uint16: {kind: types.Uint16, size: 16},
+	types.Uint32: {kind: types.Uint32, size: 32},
+	types.Uint64: {kind: types.Uint64, size: 64},
+	types.Int8:   {kind: types.Int8, size: 8, signed: true},
+	types.Int16:  {kind: types.Int16, size: 16, signed: true},
+	types.Int32:  {kind: types.Int32, size: 32, signed: true},
+	types.Int64:  {kind: types.Int64, size: 64, signed: true},
+}
+
+// scalarOf returns the encoding of a value of type `t`, or false if `t` is not a supported basic type.
+func (g *generator) scalarOf(t types.Type, nBits uint8) (scalar, bool, error) {
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != g.pkg {
+		return scalar{}, false, fmt.Errorf("type %s of another package is not supported", g.typeString(t))
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return scalar{}, false, nil
+	}
+	s, ok := scalarKinds[b.Kind()]
+	if !ok {
+		return scalar{}, false, fmt.Errorf("type %s is not supported; use a sized integer type or `bits:\"-\"`", g.typeString(t))
+	}
+	s.nBits = s.size
+	if nBits != 0 {
+		if nBits > s.size {
+			return scalar{}, false, fmt.Errorf("%d bits do not fit in %s", nBits, g.typeString(t))
+		}
+		s.nBits = nBits
+	}
+	return s, true, nil
+}
+
+// uintType returns the unsigned integer type of the same size as the scalar.
+func (s scalar) uintType() *types.Basic {
+	return map[uint8]*types.Basic{8: types.Typ[types.Uint8], 16: types.Typ[types.Uint16], 32: types.Typ[types.Uint32], 64: types.Typ[types.Uint64]}[s.size]
+}
+
+// intType returns the signed integer type of the same size as the scalar.
+func (s scalar) intType() *types.Basic {
+	return map[uint8]*types.Basic{8: types.Typ[types.Int8], 16: types.Typ[types.Int16], 32: types.Typ[types.Int32], 64: types.Typ[types.Int64]}[s.size]
+}
+
+// index returns the name of the loop variable for the nesting level `depth` of arrays.
+func index(depth int) string {
+	return string(rune('i' + depth))
+}
+
+func (g *generator) marshalValue(t types.Type, nBits uint8, x string, depth int) error {
+	s, ok, err := g.scalarOf(t, nBits)
+	if err != nil {
+		return err
+	}
+	if ok {
+		g.marshalScalar(t, s, x)
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Array:
+		if depth >= 3 {
+			return fmt.Errorf("arrays nested too deeply")
+		}
+		i := index(depth)
+		g.printf("for %s := range %s {\n", i, x)
+		err := g.marshalValue(u.Elem(), nBits, x+"["+i+"]", depth+1)
+		if err != nil {
+			return err
+		}
+		g.printf("}\n")
+		return nil
+	case *types.Struct:
+		if _, ok := t.(*types.Named); !ok || nBits != 0 {
+			return fmt.Errorf("only a named struct type without `bits` tag is supported")
+		}
+		g.printf("err = %s.MarshalBits(w)\n", x)
+		g.printCheck()
+		return nil
+	}
+	return fmt.Errorf("type %s is not supported; use `bits:\"-\"` to skip it", g.typeString(t))
+}
+
+func (g *generator) marshalScalar(t types.Type, s scalar, x string) {
+	if s.kind == types.Bool {
+		g.printf("err = w.WriteBool(%s)\n", convertTo(types.Typ[types.Bool], t, x))
+		g.printCheck()
+		return
+	}
+
+	// the value to be written as an unsigned integer
+	u := convertTo(s.uintType(), t, x)
+	hi := u // the operand of >>
+	if s.signed && s.nBits < s.size {
+		u = fmt.Sprintf("%s&%#x", u, uint64(1)<<s.nBits-1)
+		hi = "(" + u + ")"
+	}
+
+	switch s.size {
+	case 8:
+		g.printf("err = w.WriteNBitsOfUint8(%d, %s)\n", s.nBits, u)
+	case 16:
+		g.printf("err = w.WriteNBitsOfUint16BE(%d, %s)\n", s.nBits, u)
+	case 32:
+		g.printf("err = w.WriteNBitsOfUint32BE(%d, %s)\n", s.nBits, u)
+	case 64:
+		// the upper half is written even if it is 0 bits long so that the strict mode can check the range
+		hiBits := uint8(0)
+		if s.nBits > 32 {
+			hiBits = s.nBits - 32
+		}
+		g.printf("err = w.WriteNBitsOfUint32BE(%d, uint32(%s>>32))\n", hiBits, hi)
+		g.printCheck()
+		g.printf("err = w.WriteNBitsOfUint32BE(%d, uint32(%s))\n", s.nBits-hiBits, u)
+	}
+	g.printCheck()
+}
+
+func (g *generator) marshalReserved(f field) error {
+	s, ok, err := g.scalarOf(f.typ, f.nBits)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("a blank field must be of a basic type")
+	}
+	g.printf("err = w.WriteRun(0, %d)\n", s.nBits)
+	g.printCheck()
+	return nil
+}
+
+func (g *generator) unmarshalValue(t types.Type, nBits uint8, x string, depth int) error {
+	s, ok, err := g.scalarOf(t, nBits)
+	if err != nil {
+		return err
+	}
+	if ok {
+		g.unmarshalScalar(t, s, x)
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Array:
+		if depth >= 3 {
+			return fmt.Errorf("arrays nested too deeply")
+		}
+		i := index(depth)
+		g.printf("for %s := range %s {\n", i, x)
+		err := g.unmarshalValue(u.Elem(), nBits, x+"["+i+"]", depth+1)
+		if err != nil {
+			return err
+		}
+		g.printf("}\n")
+		return nil
+	case *types.Struct:
+		if _, ok := t.(*types.Named); !ok || nBits != 0 {
+			return fmt.Errorf("only a named struct type without `bits` tag is supported")
+		}
+		g.printf("err = %s.UnmarshalBits(r)\n", x)
+		g.printCheck()
+		return nil
+	}
+	return fmt.Errorf("type %s is not supported; use `bits:\"-\"` to skip it", g.typeString(t))
+}
+
+func (g *generator) unmarshalScalar(t types.Type, s scalar, x string) {
+	if s.kind == types.Bool {
+		if types.Identical(t, types.Typ[types.Bool]) {
+			g.printf("%s, err = r.ReadBool()\n", x)
+			g.printCheck()
+			return
+		}
+		g.temps["b"] = "bool"
+		g.printf("b, err = r.ReadBool()\n")
+		g.printCheck()
+		g.printf("%s = %s\n", x, g.convertFrom(t, types.Typ[types.Bool], "b"))
+		return
+	}
+
+	read := map[uint8]string{8: "ReadNBitsAsUint8", 16: "ReadNBitsAsUint16BE", 32: "ReadNBitsAsUint32BE", 64: "ReadNBitsAsUint64BE"}[s.size]
+	if !s.signed && types.Identical(t, s.uintType()) {
+		g.printf("%s, err = r.%s(%d)\n", x, read, s.nBits)
+		g.printCheck()
+		return
+	}
+
+	tmp := fmt.Sprintf("u%d", s.size)
+	g.temps[tmp] = s.uintType().Name()
+	g.printf("%s, err = r.%s(%d)\n", tmp, read, s.nBits)
+	g.printCheck()
+	switch {
+	case !s.signed:
+		g.printf("%s = %s\n", x, g.convertFrom(t, s.uintType(), tmp))
+	case s.nBits < s.size:
+		// sign extension
+		shift := s.size - s.nBits
+		g.printf("%s = %s\n", x, g.convertFrom(t, s.intType(), fmt.Sprintf("%s(%s<<%d) >> %d", s.intType().Name(), tmp, shift, shift)))
+	default:
+		g.printf("%s = %s(%s)\n", x, g.typeString(t), tmp)
+	}
+}
+
+func (g *generator) unmarshalReserved(f field) error {
+	s, ok, err := g.scalarOf(f.typ, f.nBits)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("a blank field must be of a basic type")
+	}
+	g.printf("err = r.Skip(%d)\n", s.nBits)
+	g.printCheck()
+	return nil
+}
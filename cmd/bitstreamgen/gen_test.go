@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	// the generated code of the example package is up to date
+	dir := filepath.Join("internal", "example")
+	pkg, err := loadPackage(dir, "header_bits.go")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	actual, err := generate(pkg, []string{"Header", "Extension"})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expected, err := os.ReadFile(filepath.Join(dir, "header_bits.go"))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("\nExpected: %s\nActual:   %s\n", expected, actual)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Src      string
+		Expected string
+	}{
+		{Name: "pattern 1", Src: "type T struct{ A uint8 `bits:\"9\"` }", Expected: "T.A: 9 bits do not fit in uint8"},
+		{Name: "pattern 2", Src: "type T struct{ A uint8 `bits:\"x\"` }", Expected: "T.A: invalid tag"},
+		{Name: "pattern 3", Src: "type T struct{ A int }", Expected: "T.A: type int is not supported"},
+		{Name: "pattern 4", Src: "type T struct{ A []byte }", Expected: "T.A: type []byte is not supported"},
+		{Name: "pattern 5", Src: "type T int", Expected: "type T is not a struct"},
+		{Name: "pattern 6", Src: "type U struct{}", Expected: "type T is not found"},
+		{Name: "pattern 7", Src: "type T struct{ _ [2]uint8 }", Expected: "T._: a blank field must be of a basic type"},
+		{Name: "pattern 8", Src: "type T struct{ A struct{ B uint8 } }", Expected: "T.A: only a named struct type"},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := os.WriteFile(filepath.Join(dir, "t.go"), []byte("package p\n\n"+data.Src+"\n"), 0o644)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			err = run(dir, []string{"T"}, filepath.Join(dir, "t_bits.go"))
+			if err == nil || !strings.Contains(err.Error(), data.Expected) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+		})
+	}
+}
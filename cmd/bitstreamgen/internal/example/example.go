@@ -0,0 +1,29 @@
+// Package example has bit field structs encoded by the code generated by bitstreamgen.
+package example
+
+//go:generate go run github.com/bearmini/bitstream-go/cmd/bitstreamgen -type Header,Extension
+
+// Kind is a kind of a header.
+type Kind uint8
+
+// Header is a header which has fields of various types.
+type Header struct {
+	Version uint8 `bits:"4"`
+	Big     bool
+	_       uint8  `bits:"3"`
+	Delta   int16  `bits:"12"`
+	Kind    Kind   `bits:"2"`
+	Offset  int64  `bits:"40"`
+	Length  uint64 `bits:"20"`
+	Magic   [2]byte
+	Samples [3]uint16 `bits:"10"`
+	Ext     Extension
+	Note    string `bits:"-"`
+}
+
+// Extension is a struct nested in Header.
+type Extension struct {
+	Flags [2]bool
+	Level int8 `bits:"3"`
+	Seq   uint32
+}
@@ -0,0 +1,90 @@
+package example
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+var testHeader = Header{
+	Version: 5,
+	Big:     true,
+	Delta:   -2,
+	Kind:    2,
+	Offset:  -0x100,
+	Length:  0x12345,
+	Magic:   [2]byte{'B', 'S'},
+	Samples: [3]uint16{1, 0x3ff, 0x200},
+	Ext:     Extension{Flags: [2]bool{true, false}, Level: -3, Seq: 0xdeadbeef},
+}
+
+// 0101 1 000  1111 1111 1110  10  1111 1111 ... 0000 0000 (40 bits)  0001 0010 0011 0100 0101  'B' 'S'
+// 0000000001 1111111111 1000000000  1 0  101  1101 1110 1010 1101 1011 1110 1110 1111  (000)
+var testData = []byte{0x58, 0xff, 0xeb, 0xff, 0xff, 0xff, 0xfc, 0x00, 0x48, 0xd1, 0x50, 0x94, 0xc0, 0x1f, 0xfe, 0x00, 0xae, 0xf5, 0x6d, 0xf7, 0x78}
+
+func TestMarshalBits(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	err := testHeader.MarshalBits(w)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Finalize()
+	if !bytes.Equal(testData, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", testData, buf.Bytes())
+	}
+}
+
+func TestUnmarshalBits(t *testing.T) {
+	var h Header
+	err := h.UnmarshalBits(bitstream.NewReader(bytes.NewReader(testData), nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if testHeader != h {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", testHeader, h)
+	}
+
+	err = h.UnmarshalBits(bitstream.NewReader(bytes.NewReader(testData[:20]), nil))
+	if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestMarshalBitsStrict(t *testing.T) {
+	h := testHeader
+	h.Length = 1 << 20 // does not fit in 20 bits
+	w := bitstream.NewWriter(bytes.NewBuffer([]byte{}))
+	w.SetStrictValues(true)
+	err := h.MarshalBits(w)
+	if !errors.Is(err, bitstream.ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", bitstream.ErrValueOutOfRange, err)
+	}
+}
+
+func TestAllocs(t *testing.T) {
+	var out bytes.Buffer
+	out.Grow(1024)
+	w := bitstream.NewWriter(&out)
+	src := bytes.NewReader(testData)
+	r := bitstream.NewReader(src, nil)
+	var h Header
+
+	allocs := testing.AllocsPerRun(100, func() {
+		err := testHeader.MarshalBits(w)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		src.Reset(testData)
+		err = h.UnmarshalBits(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, allocs)
+	}
+}
@@ -0,0 +1,83 @@
+// Command bitstreamgen generates MarshalBits and UnmarshalBits methods of bit field structs,
+// which read and write the fields with bitstream.Reader and bitstream.Writer directly, without reflection.
+//
+// Usage:
+//
+//	bitstreamgen -type Header,Packet [-output header_bits.go] [dir]
+//
+// It is typically invoked by go generate:
+//
+//	//go:generate bitstreamgen -type Header
+//
+// The fields are encoded in the order of declaration, MSB first. The number of bits of a field is given by the
+// `bits` tag, and it is the size of the Go type (1 for bool) if omitted.
+//
+//	type Header struct {
+//	    Version  uint8   `bits:"4"`
+//	    Big      bool
+//	    _        uint8   `bits:"3"` // reserved: written as '0' bits and skipped on read
+//	    Delta    int16   `bits:"12"` // two's complement, sign extended on read
+//	    Magic    [4]byte            // arrays are encoded element by element
+//	    Samples  [2]uint16 `bits:"10"` // the tag applies to each element
+//	    Ext      Extension          // a struct type which has the generated methods as well
+//	    cache    []byte  `bits:"-"` // skipped
+//	}
+//
+// Supported types are bool, uint8 - uint64, int8 - int64 and the types defined on them in the same package,
+// arrays of the supported types, and named struct types of the same package which have the generated methods.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct type names; required")
+	output := flag.String("output", "", "output file name; default <dir>/<type>_bits.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: bitstreamgen -type T [-output file] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+	out := *output
+	if out == "" {
+		out = filepath.Join(dir, strings.ToLower(types[0])+"_bits.go")
+	}
+
+	err := run(dir, types, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bitstreamgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir string, typeNames []string, out string) error {
+	// the output is excluded so that stale methods in it do not confuse the type checker
+	skip := ""
+	if filepath.Dir(out) == filepath.Clean(dir) {
+		skip = filepath.Base(out)
+	}
+	pkg, err := loadPackage(dir, skip)
+	if err != nil {
+		return err
+	}
+	src, err := generate(pkg, typeNames)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
@@ -0,0 +1,93 @@
+package bitstream
+
+import (
+	"io"
+)
+
+// CodeReader is a bit stream reader for the variable-width codes of LZW-style streams, e.g. GIF, TIFF and UNIX compress.
+// The code width can be changed on the fly by SetCodeWidth, and a code may straddle any byte and buffer boundaries.
+//
+// In the LSB-first order, the codes are packed from the LSB of each byte and each code starts from its LSB, as in GIF and
+// UNIX compress; the bytes are reversed bitwise as they are read from the source so that the underlying Reader reads them
+// MSB first. In the MSB-first order, as in TIFF, the codes are read as they are.
+//
+// A CodeReader is not safe for concurrent use by multiple goroutines.
+type CodeReader struct {
+	r        *Reader
+	width    uint8
+	lsbFirst bool
+}
+
+// NewCodeReader creates a new CodeReader instance which reads the codes from `src`.
+// The code width is 8 until SetCodeWidth is called.
+func NewCodeReader(src io.Reader, lsbFirst bool, opt *ReaderOptions) *CodeReader {
+	if lsbFirst {
+		src = &bitReversingReader{src: src}
+	}
+	return &CodeReader{
+		r:        NewReader(src, opt),
+		width:    8,
+		lsbFirst: lsbFirst,
+	}
+}
+
+// Reader returns the underlying Reader, e.g. to skip the padding bits with it.
+// In the LSB-first order, the bits of each byte are reversed in the underlying Reader.
+func (cr *CodeReader) Reader() *Reader {
+	return cr.r
+}
+
+// SetCodeWidth sets the number of bits of the codes read by ReadCode.
+// `nBits` must be less than or equal to 64, otherwise returns an error.
+func (cr *CodeReader) SetCodeWidth(nBits uint8) error {
+	if nBits > 64 {
+		return errTooManyBitsForUint64
+	}
+	cr.width = nBits
+	return nil
+}
+
+// CodeWidth returns the number of bits of the codes read by ReadCode.
+func (cr *CodeReader) CodeWidth() uint8 {
+	return cr.width
+}
+
+// BitPosition returns the number of bits that has been consumed, i.e. the offset of the next bit to be read.
+func (cr *CodeReader) BitPosition() uint64 {
+	return cr.r.BitPosition()
+}
+
+// ReadCode reads a code of the current code width from the bit stream and returns it LSB aligned.
+func (cr *CodeReader) ReadCode() (uint64, error) {
+	r := cr.r
+	pos := r.BitPosition()
+	v, err := r.readUint(cr.width, 64)
+	if err != nil {
+		return 0, r.wrapError("ReadCode", pos, err)
+	}
+	if cr.lsbFirst {
+		v = ReverseNBits(v, cr.width)
+	}
+	if r.done(pos) {
+		r.trace("", pos, uint(cr.width), v)
+	}
+	return v, nil
+}
+
+// Close stops the background prefetch of the underlying Reader if it is running.
+func (cr *CodeReader) Close() error {
+	return cr.r.Close()
+}
+
+// bitReversingReader reverses the order of the bits in each byte read from the source.
+type bitReversingReader struct {
+	src io.Reader
+}
+
+func (br *bitReversingReader) Read(p []byte) (int, error) {
+	n, err := br.src.Read(p)
+	if n > 0 && n <= len(p) {
+		ReverseBytesBitwise(p[:n])
+	}
+	return n, err
+}
@@ -0,0 +1,81 @@
+package bitstream
+
+import (
+	"bytes"
+	"compress/lzw"
+	"testing"
+)
+
+func TestCodeReaderMSBFirst(t *testing.T) {
+	// 9-bit codes 0x100, 0x041, then 10-bit codes 0x042, 0x3ff
+	// 1000 0000 0001 0000 0100 0100 0010 1111 1111 11
+	src := []byte{0x80, 0x10, 0x44, 0x2f, 0xfc}
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}} {
+		cr := NewCodeReader(bytes.NewReader(src), false, opt)
+		cr.SetCodeWidth(9)
+		expected := []uint64{0x100, 0x041, 0x042, 0x3ff}
+		for i, e := range expected {
+			if i == 2 {
+				cr.SetCodeWidth(10)
+			}
+			v, err := cr.ReadCode()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if v != e {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", e, v)
+			}
+		}
+	}
+}
+
+// TestCodeReaderLSBFirst decodes the codes of compress/lzw, which packs them LSB first as GIF does.
+func TestCodeReaderLSBFirst(t *testing.T) {
+	var buf bytes.Buffer
+	lw := lzw.NewWriter(&buf, lzw.LSB, 8)
+	lw.Write([]byte("TOBEORNOTTOBEORTOBEORNOT"))
+	lw.Close()
+
+	cr := NewCodeReader(bytes.NewReader(buf.Bytes()), true, &ReaderOptions{BufferSize: 3})
+	width := uint8(9)
+	next := uint64(258) // the next code to be assigned, after the clear code (256) and the EOF code (257)
+	var out []byte
+	var prev []byte
+	dict := map[uint64][]byte{}
+	for {
+		cr.SetCodeWidth(width)
+		code, err := cr.ReadCode()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if code == 257 {
+			break
+		}
+		if code == 256 {
+			continue
+		}
+
+		var entry []byte
+		switch {
+		case code < 256:
+			entry = []byte{byte(code)}
+		case dict[code] != nil:
+			entry = dict[code]
+		default:
+			entry = append(append([]byte{}, prev...), prev[0])
+		}
+		out = append(out, entry...)
+		if prev != nil {
+			dict[next] = append(append([]byte{}, prev...), entry[0])
+			next++
+			if next == 1<<width-1 && width < 12 {
+				width++
+			}
+		}
+		prev = entry
+	}
+
+	if string(out) != "TOBEORNOTTOBEORTOBEORNOT" {
+		t.Fatalf("\nExpected: %s\nActual:   %s\n", "TOBEORNOTTOBEORTOBEORNOT", out)
+	}
+}
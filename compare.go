@@ -0,0 +1,196 @@
+package bitstream
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// CompareBits reads `a` and `b` until they differ, and returns the offset of the first differing bit relative to
+// the positions of the Readers when it is called.
+// If one of the bit streams ends before the other, the offset of its end is returned.
+// The second value is true if the bit streams are identical, i.e. both ended without a difference.
+//
+// Both Readers are advanced to the first differing bit (or the end of the shorter bit stream), so that the bits
+// which differ can be read from them afterwards.
+func CompareBits(a, b *Reader) (uint64, bool, error) {
+	offset := uint64(0)
+	for {
+		va, na, err := peekChunk(a)
+		if err != nil {
+			return offset, false, err
+		}
+		vb, nb, err := peekChunk(b)
+		if err != nil {
+			return offset, false, err
+		}
+
+		n := min(na, nb)
+		if n == 0 {
+			return offset, na == nb, nil
+		}
+		d := (va ^ vb) &^ (1<<(64-n) - 1)
+		if d != 0 {
+			n = uint8(bits.LeadingZeros64(d))
+		}
+		err = skipChunk(a, b, n)
+		if err != nil {
+			return offset, false, err
+		}
+		offset += uint64(n)
+		if d != 0 {
+			return offset, false, nil
+		}
+	}
+}
+
+// DiffRegion is a range of bits where two bit streams differ.
+type DiffRegion struct {
+	Offset uint64 // offset of the first bit of the region
+	Length uint64 // number of bits in the region
+
+	// Only is 0 if both bit streams have the bits of the region, or 1 (2) if only the first (second) one has them
+	// because the other has ended.
+	Only int
+}
+
+// String returns a summary of the region, e.g. "bits 12-15 differ (4 bits)".
+func (d DiffRegion) String() string {
+	s := fmt.Sprintf("bits %d-%d", d.Offset, d.Offset+d.Length-1)
+	switch d.Only {
+	case 1:
+		s += " only in a"
+	case 2:
+		s += " only in b"
+	default:
+		s += " differ"
+	}
+	return s + fmt.Sprintf(" (%d bits)", d.Length)
+}
+
+// DiffOptions is a set of options for DiffBits.
+type DiffOptions struct {
+	// MergeGap is the maximum number of identical bits between two regions which are merged into one.
+	// The default is 0, i.e. only adjacent differing bits form a region.
+	MergeGap uint64
+
+	// MaxRegions stops the comparison once this number of regions has been found. 0 means no limit.
+	MaxRegions int
+}
+
+// GetMergeGap gets configured merge gap.
+func (opt *DiffOptions) GetMergeGap() uint64 {
+	if opt == nil {
+		return 0
+	}
+	return opt.MergeGap
+}
+
+// GetMaxRegions gets configured maximum number of regions.
+func (opt *DiffOptions) GetMaxRegions() int {
+	if opt == nil {
+		return 0
+	}
+	return opt.MaxRegions
+}
+
+// DiffBits reads `a` and `b` to the end and returns the regions where they differ, with offsets relative to
+// the positions of the Readers when it is called.
+// If one of the bit streams is longer, the extra bits form the last region, whose Only field tells which one has them.
+// It returns nil if the bit streams are identical.
+func DiffBits(a, b *Reader, opt *DiffOptions) ([]DiffRegion, error) {
+	gap := opt.GetMergeGap()
+	maxRegions := opt.GetMaxRegions()
+
+	var regions []DiffRegion
+	// add adds a region, and reports whether the comparison should continue
+	add := func(d DiffRegion) bool {
+		if l := len(regions); l > 0 {
+			last := &regions[l-1]
+			if last.Only == d.Only && d.Offset <= last.Offset+last.Length+gap {
+				last.Length = d.Offset + d.Length - last.Offset
+				return true
+			}
+		}
+		if maxRegions > 0 && len(regions) >= maxRegions {
+			return false
+		}
+		regions = append(regions, d)
+		return true
+	}
+
+	offset := uint64(0)
+	for {
+		va, na, err := peekChunk(a)
+		if err != nil {
+			return regions, err
+		}
+		vb, nb, err := peekChunk(b)
+		if err != nil {
+			return regions, err
+		}
+
+		n := min(na, nb)
+		if n == 0 {
+			break
+		}
+		d := (va ^ vb) &^ (1<<(64-n) - 1)
+		for d != 0 {
+			start := bits.LeadingZeros64(d)
+			length := bits.LeadingZeros64(^(d << start))
+			if !add(DiffRegion{Offset: offset + uint64(start), Length: uint64(length)}) {
+				return regions, nil
+			}
+			d &= 1<<(64-start-length) - 1
+		}
+		err = skipChunk(a, b, n)
+		if err != nil {
+			return regions, err
+		}
+		offset += uint64(n)
+	}
+
+	// the rest of the longer bit stream
+	for i, r := range []*Reader{a, b} {
+		length := uint64(0)
+		for {
+			_, n, err := peekChunk(r)
+			if err != nil {
+				return regions, err
+			}
+			if n == 0 {
+				break
+			}
+			pos := r.BitPosition()
+			err = r.skip(uint(n))
+			if err != nil {
+				return regions, r.wrapError("Skip", pos, err)
+			}
+			length += uint64(n)
+		}
+		if length > 0 {
+			add(DiffRegion{Offset: offset, Length: length, Only: i + 1})
+		}
+	}
+	return regions, nil
+}
+
+// peekChunk peeks up to 64 bits of `r`. It returns 0 bits at the end of the bit stream.
+func peekChunk(r *Reader) (uint64, uint8, error) {
+	pos := r.BitPosition()
+	v, n, err := r.peek(64)
+	if err == io.EOF {
+		return 0, 0, nil
+	}
+	return v, n, r.wrapError("Peek", pos, err)
+}
+
+func skipChunk(a, b *Reader, nBits uint8) error {
+	pos := a.BitPosition()
+	err := a.skip(uint(nBits))
+	if err != nil {
+		return a.wrapError("Skip", pos, err)
+	}
+	pos = b.BitPosition()
+	return b.wrapError("Skip", pos, b.skip(uint(nBits)))
+}
@@ -0,0 +1,149 @@
+package bitstream
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCompareBits(t *testing.T) {
+	testData := []struct {
+		Name           string
+		A              []byte
+		B              []byte
+		Skip           uint
+		ExpectedOffset uint64
+		ExpectedEqual  bool
+	}{
+		{
+			Name:           "pattern 1",
+			A:              []byte{0x12, 0x34, 0x56},
+			B:              []byte{0x12, 0x34, 0x56},
+			ExpectedOffset: 24,
+			ExpectedEqual:  true,
+		},
+		{
+			Name:           "pattern 2",
+			A:              []byte{0x12, 0x34, 0x56}, // 0001 0010 0011 0100 0101 0110
+			B:              []byte{0x12, 0x3c, 0x56}, // 0001 0010 0011 1100 0101 0110
+			ExpectedOffset: 12,
+		},
+		{
+			Name:           "pattern 3",
+			A:              []byte{0x12, 0x34},
+			B:              []byte{0x12, 0x34, 0x00},
+			ExpectedOffset: 16,
+		},
+		{
+			Name:           "pattern 4",
+			A:              bytes.Repeat([]byte{0xaa}, 20),
+			B:              append(bytes.Repeat([]byte{0xaa}, 19), 0xab),
+			ExpectedOffset: 159,
+		},
+		{
+			Name:           "pattern 5",
+			A:              []byte{0x12, 0x34, 0x56}, // skip 3 bits: 1 0010 0011 0100 0101 0110
+			B:              []byte{0xf2, 0x34, 0x57}, // skip 3 bits: 1 0010 0011 0100 0101 0111
+			Skip:           3,
+			ExpectedOffset: 20,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			a := NewReader(bytes.NewReader(data.A), &ReaderOptions{BufferSize: 3})
+			b := NewReader(bytes.NewReader(data.B), nil)
+			a.Skip(data.Skip)
+			b.Skip(data.Skip)
+			offset, equal, err := CompareBits(a, b)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.ExpectedOffset != offset || data.ExpectedEqual != equal {
+				t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", data.ExpectedOffset, data.ExpectedEqual, offset, equal)
+			}
+			// the Readers are at the differing bit
+			if uint64(data.Skip)+offset != a.BitPosition() || uint64(data.Skip)+offset != b.BitPosition() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v, %+v\n", uint64(data.Skip)+offset, a.BitPosition(), b.BitPosition())
+			}
+		})
+	}
+}
+
+func TestDiffBits(t *testing.T) {
+	testData := []struct {
+		Name     string
+		A        []byte
+		B        []byte
+		Opt      *DiffOptions
+		Expected []DiffRegion
+	}{
+		{
+			Name:     "pattern 1",
+			A:        []byte{0x12, 0x34},
+			B:        []byte{0x12, 0x34},
+			Expected: nil,
+		},
+		{
+			Name: "pattern 2",
+			A:    []byte{0x12, 0x34, 0x56}, // 0001 0010 0011 0100 0101 0110
+			B:    []byte{0x1d, 0x34, 0x55}, // 0001 1101 0011 0100 0101 0101
+			Expected: []DiffRegion{
+				{Offset: 4, Length: 4},
+				{Offset: 22, Length: 2},
+			},
+		},
+		{
+			Name: "pattern 3",
+			A:    []byte{0x12, 0x34, 0x56},
+			B:    []byte{0x1d, 0x34, 0x55},
+			Opt:  &DiffOptions{MergeGap: 14},
+			Expected: []DiffRegion{
+				{Offset: 4, Length: 20},
+			},
+		},
+		{
+			Name: "pattern 4",
+			A:    []byte{0x12, 0x34, 0x56},
+			B:    []byte{0x1d, 0x34, 0x55},
+			Opt:  &DiffOptions{MaxRegions: 1},
+			Expected: []DiffRegion{
+				{Offset: 4, Length: 4},
+			},
+		},
+		{
+			Name: "pattern 5",
+			A:    append(bytes.Repeat([]byte{0x00}, 8), 0x01),
+			B:    append(bytes.Repeat([]byte{0x00}, 8), 0x80, 0xff, 0xff),
+			Expected: []DiffRegion{
+				{Offset: 64, Length: 1},
+				{Offset: 71, Length: 1},
+				{Offset: 72, Length: 16, Only: 2},
+			},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			a := NewReader(bytes.NewReader(data.A), nil)
+			b := NewReader(bytes.NewReader(data.B), &ReaderOptions{BufferSize: 2})
+			regions, err := DiffBits(a, b, data.Opt)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(data.Expected, regions) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, regions)
+			}
+		})
+	}
+}
+
+func TestDiffRegionString(t *testing.T) {
+	actual := DiffRegion{Offset: 12, Length: 4}.String() + ", " + DiffRegion{Offset: 100, Length: 32, Only: 1}.String()
+	expected := "bits 12-15 differ (4 bits), bits 100-131 only in a (32 bits)"
+	if expected != actual {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, actual)
+	}
+}
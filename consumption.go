@@ -0,0 +1,31 @@
+package bitstream
+
+// LastBits returns the number of bits consumed by the last read method which succeeded, e.g. to enforce a bit budget
+// on each field. For a method built on top of others, e.g. ReadPrefixedUint or CountLeadingZeros, it is the whole bits
+// consumed by the method. A method which fails does not update it, and neither do the unchecked methods such as ReadBits64.
+// Use Measure for a decoder built on top of the Reader in another package.
+func (r *Reader) LastBits() uint64 {
+	return r.lastBits
+}
+
+// Measure calls `f`, which reads from `r`, and returns the number of bits consumed by it together with its error,
+// e.g. to enforce a bit budget on a record decoded by a function built on top of the Reader such as rice.Read.
+func (r *Reader) Measure(f func() error) (uint64, error) {
+	pos := r.BitPosition()
+	err := f()
+	return r.BitPosition() - pos, err
+}
+
+// LastBits returns the number of bits written by the last write method which succeeded.
+// For a method built on top of others, e.g. WritePrefixedUint or WriteLengthPrefixedBytes, it is the whole bits
+// written by the method. A method which fails does not update it.
+func (w *Writer) LastBits() uint64 {
+	return w.lastBits
+}
+
+// Measure calls `f`, which writes to `w`, and returns the number of bits written by it together with its error.
+func (w *Writer) Measure(f func() error) (uint64, error) {
+	pos := w.bitPosition()
+	err := f()
+	return w.bitPosition() - pos, err
+}
@@ -0,0 +1,100 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReaderLastBits(t *testing.T) {
+	// 101 0000 0001 0111 1 ...
+	r := NewReader(bytes.NewReader([]byte{0xa0, 0x2f, 0xff}), nil)
+	if r.LastBits() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, r.LastBits())
+	}
+
+	_, err := r.ReadNBitsAsUint8(3)
+	if err != nil || r.LastBits() != 3 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 3, nil, r.LastBits(), err)
+	}
+	n, err := r.CountLeadingZeros() // 0000 0001
+	if err != nil || n != 7 || r.LastBits() != 8 {
+		t.Fatalf("\nExpected: %+v, %+v, %+v\nActual:   %+v, %+v, %+v\n", 7, 8, nil, n, r.LastBits(), err)
+	}
+	v, err := r.ReadPrefixedUint(2, []uint8{1, 3, 5, 7}) // 01 + 111
+	if err != nil || v != 0x7 || r.LastBits() != 5 {
+		t.Fatalf("\nExpected: %+v, %+v, %+v\nActual:   %+v, %+v, %+v\n", 0x7, 5, nil, v, r.LastBits(), err)
+	}
+
+	// a failed read does not update it
+	_, err = r.ReadUint16BE()
+	if !errors.Is(err, io.ErrUnexpectedEOF) || r.LastBits() != 5 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 5, io.ErrUnexpectedEOF, r.LastBits(), err)
+	}
+}
+
+func TestReaderMeasure(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0xa5, 0x5a}), nil)
+	_, err := r.ReadBit()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	n, err := r.Measure(func() error {
+		_, err := r.ReadNBitsAsUint8(5)
+		if err != nil {
+			return err
+		}
+		_, err = r.ReadNBitsAsUint16BE(6)
+		return err
+	})
+	if err != nil || n != 11 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 11, nil, n, err)
+	}
+
+	// the bits consumed by a failed read are counted too
+	n, err = r.Measure(func() error {
+		_, err := r.ReadUint8()
+		return err
+	})
+	if !errors.Is(err, io.ErrUnexpectedEOF) || n != 4 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 4, io.ErrUnexpectedEOF, n, err)
+	}
+}
+
+func TestWriterLastBits(t *testing.T) {
+	w := NewWriterWithOptions(io.Discard, &WriterOptions{StrictValues: true})
+	err := w.WriteBool(true)
+	if err != nil || w.LastBits() != 1 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 1, nil, w.LastBits(), err)
+	}
+	err = w.WritePrefixedUint(2, []uint8{1, 3, 5, 7}, 0x1f)
+	if err != nil || w.LastBits() != 7 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 7, nil, w.LastBits(), err)
+	}
+	err = w.WriteLengthPrefixedBytes(8, []byte{0x01, 0x02})
+	if err != nil || w.LastBits() != 24 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 24, nil, w.LastBits(), err)
+	}
+	err = w.WriteNBitsOfUint8(2, 0x4)
+	if !errors.Is(err, ErrValueOutOfRange) || w.LastBits() != 24 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 24, ErrValueOutOfRange, w.LastBits(), err)
+	}
+
+	n, err := w.Measure(func() error {
+		err := w.WriteUint16BE(0xffff)
+		if err != nil {
+			return err
+		}
+		return w.WriteBit(0)
+	})
+	if err != nil || n != 17 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 17, nil, n, err)
+	}
+
+	w.Reset(io.Discard)
+	if w.LastBits() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, w.LastBits())
+	}
+}
@@ -0,0 +1,130 @@
+package bitstream
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// CopyBits copies `nBits` bits from `src` to `dst` and returns the number of bits copied.
+// Whole bytes are copied in bulk from the buffer of `src` when it is byte aligned, and 64 bits at a time otherwise;
+// `dst` does not have to be byte aligned in either case.
+// If `src` ends before `nBits` bits are copied, it returns io.EOF if no bits are copied, io.ErrUnexpectedEOF otherwise.
+func CopyBits(dst *Writer, src *Reader, nBits uint64) (uint64, error) {
+	pos := src.BitPosition()
+	copied, err := copyBits(dst, src, nBits)
+	if err != nil {
+		return copied, src.wrapError("CopyBits", pos, err)
+	}
+	src.trace("", pos, uint(nBits), nil)
+	return copied, nil
+}
+
+func copyBits(dst *Writer, src *Reader, nBits uint64) (uint64, error) {
+	copied := uint64(0)
+
+	if src.currBitIndex == 7 {
+		// byte aligned: pass the bytes in the buffer of src to dst
+		for nBits-copied >= 8 {
+			err := src.fillBufIfNeeded()
+			if err != nil {
+				return copied, eofAfter(copied, err)
+			}
+
+			n := src.bufLen - src.currByteIndex
+			if rest := uint((nBits - copied) / 8); n > rest {
+				n = rest
+			}
+			err = dst.writeBytes(src.buf[src.currByteIndex : src.currByteIndex+n])
+			if err != nil {
+				return copied, err
+			}
+			src.currByteIndex += n
+			src.consumedBytes += n
+			copied += uint64(n) * 8
+		}
+	} else {
+		// not aligned: shift 64 bits at a time
+		chunk := make([]byte, 8)
+		for nBits-copied >= 64 {
+			v, read, err := src.readBits(64)
+			if err != nil {
+				werr := writeBits(dst, v, read)
+				if werr != nil {
+					return copied, werr
+				}
+				copied += uint64(read)
+				return copied, eofAfter(copied, err)
+			}
+
+			binary.BigEndian.PutUint64(chunk, v)
+			err = dst.writeBytes(chunk)
+			if err != nil {
+				return copied, err
+			}
+			copied += 64
+		}
+	}
+
+	// the remaining bits
+	for copied < nBits {
+		n := nBits - copied
+		if n > 8 {
+			n = 8
+		}
+		v, read, err := src.readBits(uint8(n))
+		werr := writeBits(dst, v, read)
+		if werr != nil {
+			return copied, werr
+		}
+		copied += uint64(read)
+		if err != nil {
+			return copied, eofAfter(copied, err)
+		}
+	}
+	return copied, nil
+}
+
+// writeBits writes `nBits` bits of `v` (LSB aligned) to `w`.
+func writeBits(w *Writer, v uint64, nBits uint8) error {
+	return w.writeUint(nBits, 64, v)
+}
+
+// eofAfter returns io.ErrUnexpectedEOF instead of io.EOF if some bits have already been processed.
+func eofAfter(processed uint64, err error) error {
+	if processed > 0 {
+		return unexpectedEOF(err)
+	}
+	return err
+}
+
+// ExtractBits reads `nBits` bits from the bit stream and writes them to `w` left aligned, without holding them in memory.
+// It returns the number of valid bits in the last byte written to `w` (1 to 8); the rest of the last byte is filled with '0' bits.
+// If the bit stream ends before `nBits` bits are read, the bits read so far are written to `w` and
+// io.EOF (no bits read) or io.ErrUnexpectedEOF is returned.
+func (r *Reader) ExtractBits(w io.Writer, nBits uint64) (uint8, error) {
+	pos := r.BitPosition()
+	trailingBits, err := r.extractBits(w, nBits)
+	if err != nil {
+		return trailingBits, r.wrapError("ExtractBits", pos, err)
+	}
+	r.trace("", pos, uint(nBits), nil)
+	return trailingBits, nil
+}
+
+func (r *Reader) extractBits(w io.Writer, nBits uint64) (uint8, error) {
+	bw := NewWriterWithOptions(w, nil)
+	copied, err := copyBits(bw, r, nBits)
+	ferr := bw.finalize()
+	if err == nil {
+		err = ferr
+	}
+
+	if copied == 0 {
+		return 0, err
+	}
+	trailingBits := uint8(copied % 8)
+	if trailingBits == 0 {
+		trailingBits = 8
+	}
+	return trailingBits, err
+}
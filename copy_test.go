@@ -0,0 +1,204 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestCopyBits(t *testing.T) {
+	src := make([]byte, 300)
+	rand.New(rand.NewSource(1)).Read(src)
+
+	for _, srcOffset := range []uint{0, 3, 8} {
+		for _, dstOffset := range []uint8{0, 5} {
+			for _, nBits := range []uint64{0, 5, 64, 77, 700, 2000} {
+				name := fmt.Sprintf("src offset %d, dst offset %d, %d bits", srcOffset, dstOffset, nBits)
+				t.Run(name, func(t *testing.T) {
+					// expected: ReadNBits + WriteNBits
+					eb := bytes.NewBuffer([]byte{})
+					er := NewReader(bytes.NewReader(src), nil)
+					ew := NewWriter(eb)
+					er.Skip(srcOffset)
+					ew.WriteNBitsOfUint8(dstOffset, 0xff)
+					data, _ := er.ReadNBits(uint(nBits), nil)
+					ew.WriteNBits(uint(nBits), data)
+					ew.Finalize()
+
+					ab := bytes.NewBuffer([]byte{})
+					ar := NewReader(bytes.NewReader(src), &ReaderOptions{BufferSize: 7})
+					aw := NewWriter(ab)
+					ar.Skip(srcOffset)
+					aw.WriteNBitsOfUint8(dstOffset, 0xff)
+					copied, err := CopyBits(aw, ar, nBits)
+					if err != nil {
+						t.Fatalf("unexpected error: %+v\n", err)
+					}
+					if nBits != copied {
+						t.Fatalf("\nExpected: %+v\nActual:   %+v\n", nBits, copied)
+					}
+					aw.Finalize()
+
+					if !bytes.Equal(eb.Bytes(), ab.Bytes()) {
+						t.Fatalf("\nExpected: %+v\nActual:   %+v\n", eb.Bytes(), ab.Bytes())
+					}
+					if uint64(srcOffset)+nBits != ar.BitPosition() {
+						t.Fatalf("\nExpected: %+v\nActual:   %+v\n", uint64(srcOffset)+nBits, ar.BitPosition())
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestCopyBitsEOF(t *testing.T) {
+	testData := []struct {
+		Name           string
+		SrcOffset      uint
+		NBits          uint64
+		ExpectedCopied uint64
+		ExpectedErr    error
+	}{
+		{Name: "pattern 1", SrcOffset: 0, NBits: 100, ExpectedCopied: 80, ExpectedErr: io.ErrUnexpectedEOF},
+		{Name: "pattern 2", SrcOffset: 3, NBits: 100, ExpectedCopied: 77, ExpectedErr: io.ErrUnexpectedEOF},
+		{Name: "pattern 3", SrcOffset: 80, NBits: 1, ExpectedCopied: 0, ExpectedErr: io.EOF},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			br := NewReader(bytes.NewReader(make([]byte, 10)), nil)
+			bw := NewWriter(io.Discard)
+			br.Skip(data.SrcOffset)
+
+			copied, err := CopyBits(bw, br, data.NBits)
+			if !errors.Is(err, data.ExpectedErr) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedErr, err)
+			}
+			if data.ExpectedCopied != copied {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedCopied, copied)
+			}
+			if data.ExpectedCopied != bw.WrittenBits() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedCopied, bw.WrittenBits())
+			}
+		})
+	}
+}
+
+func TestCopyBitsPlainErrors(t *testing.T) {
+	br := NewReaderBytes(make([]byte, 2), &ReaderOptions{PlainErrors: true})
+	bw := NewWriter(io.Discard)
+	br.Skip(3)
+
+	_, err := CopyBits(bw, br, 100)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestExtractBits(t *testing.T) {
+	testData := []struct {
+		Name                 string
+		SrcOffset            uint
+		NBits                uint64
+		ExpectedBytes        []byte
+		ExpectedTrailingBits uint8
+		ExpectedErr          error
+	}{
+		{
+			// 0001 0010 0011 0100
+			Name:                 "pattern 1",
+			SrcOffset:            0,
+			NBits:                16,
+			ExpectedBytes:        []byte{0x12, 0x34},
+			ExpectedTrailingBits: 8,
+		},
+		{
+			// xxx1 0010 0011 01xx => 1001 0001 101x xxxx
+			Name:                 "pattern 2",
+			SrcOffset:            3,
+			NBits:                11,
+			ExpectedBytes:        []byte{0x91, 0xa0},
+			ExpectedTrailingBits: 3,
+		},
+		{
+			// xxxx xxxx xxxx 0100 0101 0110 => 0100 0101 0110 xxxx
+			Name:                 "pattern 3",
+			SrcOffset:            12,
+			NBits:                20,
+			ExpectedBytes:        []byte{0x45, 0x60},
+			ExpectedTrailingBits: 4,
+			ExpectedErr:          io.ErrUnexpectedEOF,
+		},
+		{
+			Name:                 "pattern 4",
+			SrcOffset:            24,
+			NBits:                1,
+			ExpectedBytes:        []byte{},
+			ExpectedTrailingBits: 0,
+			ExpectedErr:          io.EOF,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			br := NewReader(bytes.NewReader([]byte{0x12, 0x34, 0x56}), nil)
+			br.Skip(data.SrcOffset)
+
+			buf := bytes.NewBuffer([]byte{})
+			trailingBits, err := br.ExtractBits(buf, data.NBits)
+			if !errors.Is(err, data.ExpectedErr) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedErr, err)
+			}
+			if data.ExpectedTrailingBits != trailingBits {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedTrailingBits, trailingBits)
+			}
+			if !bytes.Equal(data.ExpectedBytes, buf.Bytes()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedBytes, buf.Bytes())
+			}
+		})
+	}
+}
+
+func benchmarkCopy(b *testing.B, srcOffset uint, f func(w *Writer, r *Reader, nBits uint64)) {
+	src := make([]byte, 1<<20)
+	nBits := uint64(len(src)-1) * 8
+
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(bytes.NewReader(src), nil)
+		w := NewWriterWithOptions(io.Discard, nil)
+		r.Skip(srcOffset)
+		w.WriteBit(1)
+		f(w, r, nBits)
+	}
+}
+
+func benchmarkCopyBits(b *testing.B, srcOffset uint) {
+	benchmarkCopy(b, srcOffset, func(w *Writer, r *Reader, nBits uint64) {
+		CopyBits(w, r, nBits)
+	})
+}
+
+func benchmarkCopyByChunks(b *testing.B, srcOffset uint) {
+	benchmarkCopy(b, srcOffset, func(w *Writer, r *Reader, nBits uint64) {
+		for nBits > 0 {
+			n := uint(255)
+			if uint64(n) > nBits {
+				n = uint(nBits)
+			}
+			data, _ := r.ReadNBits(n, nil)
+			w.WriteNBits(n, data)
+			nBits -= uint64(n)
+		}
+	})
+}
+
+func BenchmarkCopyBitsAligned(b *testing.B)     { benchmarkCopyBits(b, 0) }
+func BenchmarkCopyBitsUnaligned(b *testing.B)   { benchmarkCopyBits(b, 3) }
+func BenchmarkCopyByChunksAligned(b *testing.B) { benchmarkCopyByChunks(b, 0) }
@@ -0,0 +1,9 @@
+package bitstream
+
+import (
+	"github.com/bearmini/bitstream-go/crc"
+)
+
+// CRCParams is a set of parameters that defines a CRC algorithm.
+// See the crc package for the details and the predefined algorithms.
+type CRCParams = crc.Params
@@ -0,0 +1,329 @@
+// Package crc implements cyclic redundancy checks which operate on bit counts rather than whole bytes.
+//
+// Any CRC algorithm which can be described by the Rocksoft model (width, polynomial, initial value,
+// input/output reflection and final XOR value) with a width from 1 to 64 bits is supported.
+// Unlike hash/crc32 or hash/crc64, the data may end in the middle of a byte, which is common in radio protocols.
+//
+// The reflected CRC-32 (IEEE, Castagnoli and Koopman) and CRC-64 (ISO and ECMA) polynomials are computed by hash/crc32
+// and hash/crc64 for the whole octets, which use the CRC instructions of the CPU where available;
+// only the bits around them are processed bit by bit.
+package crc
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"hash/crc64"
+	"math/bits"
+)
+
+var (
+	// ErrInvalidWidth is returned when the width of a CRC is out of the supported range.
+	ErrInvalidWidth = errors.New("crc: width must be between 1 and 64")
+
+	// ErrInsufficientData is returned when the data has fewer bits than requested.
+	ErrInsufficientData = errors.New("crc: insufficient data")
+)
+
+// Params is a set of parameters that defines a CRC algorithm (Rocksoft model).
+//
+// Bits are fed to the CRC in the order they appear in the bit stream (MSB first in each byte).
+// If `RefIn` is true, the bits are grouped into octets counted from the first bit covered by the CRC,
+// and each octet is processed LSB first. A trailing group shorter than 8 bits is reflected within its own length.
+type Params struct {
+	Width  uint8  // width of the CRC in bits (1 - 64)
+	Poly   uint64 // generator polynomial in normal representation (the top bit is implicit)
+	Init   uint64 // initial value of the register
+	RefIn  bool   // if true, each input octet is processed LSB first
+	RefOut bool   // if true, the final register value is reflected before XorOut is applied
+	XorOut uint64 // value to be XORed with the final register value
+}
+
+// Well-known CRC algorithms.
+var (
+	CRC3GSM      = Params{Width: 3, Poly: 0x3, XorOut: 0x7}
+	CRC5USB      = Params{Width: 5, Poly: 0x05, Init: 0x1f, RefIn: true, RefOut: true, XorOut: 0x1f}
+	CRC6GSM      = Params{Width: 6, Poly: 0x2f, XorOut: 0x3f}
+	CRC8SMBus    = Params{Width: 8, Poly: 0x07}
+	CRC16ARC     = Params{Width: 16, Poly: 0x8005, RefIn: true, RefOut: true}
+	CRC16IBM3740 = Params{Width: 16, Poly: 0x1021, Init: 0xffff}
+	CRC16Kermit  = Params{Width: 16, Poly: 0x1021, RefIn: true, RefOut: true}
+	CRC24OpenPGP = Params{Width: 24, Poly: 0x864cfb, Init: 0xb704ce}
+	CRC32        = Params{Width: 32, Poly: 0x04c11db7, Init: 0xffffffff, RefIn: true, RefOut: true, XorOut: 0xffffffff}
+	CRC32C       = Params{Width: 32, Poly: 0x1edc6f41, Init: 0xffffffff, RefIn: true, RefOut: true, XorOut: 0xffffffff}
+	CRC32MPEG2   = Params{Width: 32, Poly: 0x04c11db7, Init: 0xffffffff}
+	CRC64XZ      = Params{Width: 64, Poly: 0x42f0e1eba9ea3693, Init: 0xffffffffffffffff, RefIn: true, RefOut: true, XorOut: 0xffffffffffffffff}
+)
+
+// Mask returns a mask which covers `Width` bits.
+func (p Params) Mask() uint64 {
+	return ^uint64(0) >> (64 - p.Width)
+}
+
+// Validate returns an error if the parameters do not define a valid CRC algorithm.
+func (p Params) Validate() error {
+	if p.Width == 0 || p.Width > 64 {
+		return ErrInvalidWidth
+	}
+	return nil
+}
+
+// CRC is a running CRC computation.
+//
+// Internally the register is kept left aligned in 64 bits so that a single byte-wise table
+// can serve every width.
+type CRC struct {
+	params   Params
+	shift    uint8  // 64 - Width
+	poly     uint64 // left aligned polynomial
+	table    *[256]uint64
+	octets   func(reg uint64, p []byte) uint64 // feeds whole octets with hash/crc32 or hash/crc64, if the CRC is one of theirs
+	reg      uint64                            // left aligned register
+	pending  uint8                             // bits of the incomplete octet (used only if RefIn is true)
+	nPending uint8
+}
+
+// New creates a new CRC instance with the parameters.
+func New(params Params) (*CRC, error) {
+	err := params.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	shift := 64 - params.Width
+	c := &CRC{
+		params: params,
+		shift:  shift,
+		poly:   (params.Poly & params.Mask()) << shift,
+	}
+	c.table = makeTable(c.poly)
+	c.octets = stdlibOctets(params)
+	c.Reset()
+	return c, nil
+}
+
+// Checksum returns the CRC of the first `nBits` bits of `data` using the parameters.
+func Checksum(params Params, data []byte, nBits uint64) (uint64, error) {
+	c, err := New(params)
+	if err != nil {
+		return 0, err
+	}
+	err = c.Update(data, nBits)
+	if err != nil {
+		return 0, err
+	}
+	return c.Sum64(), nil
+}
+
+func makeTable(poly uint64) *[256]uint64 {
+	t := new([256]uint64)
+	for i := 0; i < 256; i++ {
+		reg := uint64(i) << 56
+		for j := 0; j < 8; j++ {
+			if reg&(1<<63) != 0 {
+				reg = (reg << 1) ^ poly
+			} else {
+				reg <<= 1
+			}
+		}
+		t[i] = reg
+	}
+	return t
+}
+
+// stdlibOctets returns a function which feeds octets to the left aligned register with hash/crc32 or hash/crc64,
+// or nil if they do not support the polynomial. They implement the reflected algorithms, whose register is
+// the bit reversal of ours, and they take and return the register complemented.
+func stdlibOctets(params Params) func(uint64, []byte) uint64 {
+	if !params.RefIn {
+		return nil
+	}
+
+	switch params.Width {
+	case 32:
+		poly := bits.Reverse32(uint32(params.Poly))
+		if poly != crc32.IEEE && poly != crc32.Castagnoli && poly != crc32.Koopman {
+			return nil
+		}
+		tab := crc32.MakeTable(poly)
+		return func(reg uint64, p []byte) uint64 {
+			crc := crc32.Update(^bits.Reverse32(uint32(reg>>32)), tab, p)
+			return uint64(bits.Reverse32(^crc)) << 32
+		}
+	case 64:
+		poly := bits.Reverse64(params.Poly)
+		if poly != crc64.ISO && poly != crc64.ECMA {
+			return nil
+		}
+		tab := crc64.MakeTable(poly)
+		return func(reg uint64, p []byte) uint64 {
+			return bits.Reverse64(^crc64.Update(^bits.Reverse64(reg), tab, p))
+		}
+	}
+	return nil
+}
+
+// Params returns the parameters of the CRC.
+func (c *CRC) Params() Params {
+	return c.params
+}
+
+// Reset restarts the CRC computation from the initial value.
+func (c *CRC) Reset() {
+	c.reg = (c.params.Init & c.params.Mask()) << c.shift
+	c.pending = 0
+	c.nPending = 0
+}
+
+func (c *CRC) shiftBit(reg uint64, bit uint8) uint64 {
+	top := reg >> 63
+	reg <<= 1
+	if top != uint64(bit&0x01) {
+		reg ^= c.poly
+	}
+	return reg
+}
+
+// shiftByte feeds 8 bits to the register, MSB first.
+func (c *CRC) shiftByte(b uint8) {
+	c.reg = (c.reg << 8) ^ c.table[uint8(c.reg>>56)^b]
+}
+
+// UpdateBit feeds a single bit (LSB of `bit`) to the CRC.
+func (c *CRC) UpdateBit(bit uint8) {
+	if !c.params.RefIn {
+		c.reg = c.shiftBit(c.reg, bit)
+		return
+	}
+
+	c.pending = (c.pending << 1) | (bit & 0x01)
+	c.nPending++
+	if c.nPending == 8 {
+		c.shiftByte(bits.Reverse8(c.pending))
+		c.pending = 0
+		c.nPending = 0
+	}
+}
+
+// UpdateBits feeds the lower `nBits` bits of `v` to the CRC, MSB first.
+// `nBits` must be less than or equal to 64.
+func (c *CRC) UpdateBits(v uint64, nBits uint8) {
+	if nBits > 64 {
+		nBits = 64
+	}
+
+	// complete the pending octet first
+	for c.nPending != 0 && nBits > 0 {
+		c.UpdateBit(uint8(v >> (nBits - 1)))
+		nBits--
+	}
+
+	for nBits >= 8 {
+		b := uint8(v >> (nBits - 8))
+		if c.params.RefIn {
+			b = bits.Reverse8(b)
+		}
+		c.shiftByte(b)
+		nBits -= 8
+	}
+
+	for nBits > 0 {
+		c.UpdateBit(uint8(v >> (nBits - 1)))
+		nBits--
+	}
+}
+
+// Update feeds the first `nBits` bits of `data` (MSB first in each byte) to the CRC.
+// It returns an error if `data` has less than `nBits` bits.
+func (c *CRC) Update(data []byte, nBits uint64) error {
+	if uint64(len(data))*8 < nBits {
+		return ErrInsufficientData
+	}
+
+	if c.nPending == 0 {
+		n := nBits / 8
+		c.updateOctets(data[:n])
+		data = data[n:]
+		nBits -= n * 8
+	} else if nBits >= 8 {
+		// complete the pending octet with the first bits, and realign the rest to the octets
+		k := 8 - c.nPending
+		c.UpdateBits(uint64(data[0]>>c.nPending), k)
+		nBits -= uint64(k)
+
+		var buf [512]byte
+		for nBits >= 8 {
+			m := int(min(nBits/8, uint64(len(buf))))
+			src := data[:m+1]
+			i := 0
+			for ; i+9 <= len(src); i += 8 {
+				binary.BigEndian.PutUint64(buf[i:], binary.BigEndian.Uint64(src[i:])<<k|uint64(src[i+8]>>(8-k)))
+			}
+			for ; i < m; i++ {
+				buf[i] = src[i]<<k | src[i+1]>>(8-k)
+			}
+			c.updateOctets(buf[:m])
+			data = data[m:]
+			nBits -= uint64(m) * 8
+		}
+		if nBits > 0 {
+			v := uint16(data[0]) << 8
+			if len(data) > 1 {
+				v |= uint16(data[1])
+			}
+			c.UpdateBits(uint64(v<<k>>(16-nBits)), uint8(nBits))
+		}
+		return nil
+	}
+
+	for _, b := range data {
+		if nBits == 0 {
+			break
+		}
+		n := uint8(8)
+		if nBits < 8 {
+			n = uint8(nBits)
+		}
+		c.UpdateBits(uint64(b>>(8-n)), n)
+		nBits -= uint64(n)
+	}
+	return nil
+}
+
+// updateOctets feeds the octets in `p` to the register. The pending octet must be empty.
+func (c *CRC) updateOctets(p []byte) {
+	if c.octets != nil {
+		c.reg = c.octets(c.reg, p)
+		return
+	}
+	for _, b := range p {
+		if c.params.RefIn {
+			b = bits.Reverse8(b)
+		}
+		c.shiftByte(b)
+	}
+}
+
+// Write feeds all bits of `p` to the CRC. It never returns an error.
+// It allows a CRC to be used as an io.Writer.
+func (c *CRC) Write(p []byte) (int, error) {
+	err := c.Update(p, uint64(len(p))*8)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sum64 returns the CRC value of the bits fed so far without changing the state.
+func (c *CRC) Sum64() uint64 {
+	reg := c.reg
+	for i := uint8(0); i < c.nPending; i++ {
+		reg = c.shiftBit(reg, c.pending>>i)
+	}
+	reg >>= c.shift
+
+	if c.params.RefOut {
+		reg = bits.Reverse64(reg) >> c.shift
+	}
+	return (reg ^ c.params.XorOut) & c.params.Mask()
+}
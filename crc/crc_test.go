@@ -0,0 +1,209 @@
+package crc
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+var checkInput = []byte("123456789")
+
+var crc64GoISO = Params{Width: 64, Poly: 0x1b, Init: 0xffffffffffffffff, RefIn: true, RefOut: true, XorOut: 0xffffffffffffffff}
+
+func TestChecksum(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Params   Params
+		Expected uint64
+	}{
+		{Name: "CRC-3/GSM", Params: CRC3GSM, Expected: 0x4},
+		{Name: "CRC-5/USB", Params: CRC5USB, Expected: 0x19},
+		{Name: "CRC-6/GSM", Params: CRC6GSM, Expected: 0x13},
+		{Name: "CRC-8/SMBUS", Params: CRC8SMBus, Expected: 0xf4},
+		{Name: "CRC-16/ARC", Params: CRC16ARC, Expected: 0xbb3d},
+		{Name: "CRC-16/IBM-3740", Params: CRC16IBM3740, Expected: 0x29b1},
+		{Name: "CRC-16/KERMIT", Params: CRC16Kermit, Expected: 0x2189},
+		{Name: "CRC-24/OPENPGP", Params: CRC24OpenPGP, Expected: 0x21cf02},
+		{Name: "CRC-32/ISO-HDLC", Params: CRC32, Expected: 0xcbf43926},
+		{Name: "CRC-32/ISCSI", Params: CRC32C, Expected: 0xe3069283},
+		{Name: "CRC-32/MPEG-2", Params: CRC32MPEG2, Expected: 0x0376e6e7},
+		{Name: "CRC-64/XZ", Params: CRC64XZ, Expected: 0x995dc9bbdf1939fa},
+		{Name: "CRC-64/GO-ISO", Params: crc64GoISO, Expected: 0xb90956c775a41001},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			v, err := Checksum(data.Params, checkInput, uint64(len(checkInput))*8)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.Expected != v {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Expected, v)
+			}
+		})
+	}
+}
+
+// bitwise is a straightforward reference implementation which feeds one bit at a time.
+func bitwise(p Params, data []byte, nBits uint64) uint64 {
+	mask := p.Mask()
+	top := uint64(1) << (p.Width - 1)
+	reg := p.Init & mask
+	feed := func(bit uint8) {
+		msb := reg&top != 0
+		reg = (reg << 1) & mask
+		if msb != (bit != 0) {
+			reg ^= p.Poly & mask
+		}
+	}
+
+	for i := uint64(0); i < nBits; i += 8 {
+		n := nBits - i
+		if n > 8 {
+			n = 8
+		}
+		b := data[i/8] >> (8 - n)
+		for j := uint64(0); j < n; j++ {
+			if p.RefIn {
+				feed((b >> j) & 1)
+			} else {
+				feed((b >> (n - 1 - j)) & 1)
+			}
+		}
+	}
+
+	if p.RefOut {
+		r := uint64(0)
+		for i := uint8(0); i < p.Width; i++ {
+			r = (r << 1) | ((reg >> i) & 1)
+		}
+		reg = r
+	}
+	return (reg ^ p.XorOut) & mask
+}
+
+func TestUpdateBitGranular(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	params := []Params{CRC3GSM, CRC5USB, CRC16ARC, CRC16IBM3740, CRC32, CRC64XZ}
+	data := make([]byte, 64)
+	rnd.Read(data)
+
+	for _, p := range params {
+		for nBits := uint64(0); nBits <= uint64(len(data))*8; nBits += uint64(rnd.Intn(13) + 1) {
+			expected := bitwise(p, data, nBits)
+
+			// feed bits in chunks of random sizes
+			c, err := New(p)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			for pos := uint64(0); pos < nBits; {
+				n := uint64(rnd.Intn(20) + 1)
+				if pos+n > nBits {
+					n = nBits - pos
+				}
+				var v uint64
+				for i := uint64(0); i < n; i++ {
+					bit := (data[(pos+i)/8] >> (7 - (pos+i)%8)) & 1
+					v = (v << 1) | uint64(bit)
+				}
+				c.UpdateBits(v, uint8(n))
+				pos += n
+			}
+			if expected != c.Sum64() {
+				t.Fatalf("\nwidth %d, nBits %d\nExpected: %#x\nActual:   %#x\n", p.Width, nBits, expected, c.Sum64())
+			}
+
+			v, err := Checksum(p, data, nBits)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if expected != v {
+				t.Fatalf("\nwidth %d, nBits %d\nExpected: %#x\nActual:   %#x\n", p.Width, nBits, expected, v)
+			}
+		}
+	}
+}
+
+func TestUpdateUnaligned(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	koopman := Params{Width: 32, Poly: 0x741b8cd7, RefIn: true}
+	params := []Params{CRC5USB, CRC16ARC, CRC16IBM3740, CRC32, CRC32C, koopman, CRC32MPEG2, CRC64XZ, crc64GoISO}
+	data := make([]byte, 1100) // more than the buffer to realign the octets
+	rnd.Read(data)
+
+	for _, p := range params {
+		for _, nBits := range []uint64{0, 7, 8, 9, 100, 2048, 8000, uint64(len(data)) * 8} {
+			for k := uint64(0); k < 8 && k <= nBits; k++ {
+				expected := bitwise(p, data, nBits)
+
+				// the first `k` bits leave an incomplete octet, and the rest is fed by Update
+				c, err := New(p)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				c.UpdateBits(uint64(data[0]>>(8-k)), uint8(k))
+				rest := make([]byte, len(data))
+				for i := range rest {
+					rest[i] = data[i] << k
+					if i+1 < len(data) {
+						rest[i] |= uint8(uint16(data[i+1]) >> (8 - k))
+					}
+				}
+				err = c.Update(rest, nBits-k)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if expected != c.Sum64() {
+					t.Fatalf("\nwidth %d, nBits %d, k %d\nExpected: %#x\nActual:   %#x\n", p.Width, nBits, k, expected, c.Sum64())
+				}
+			}
+		}
+	}
+}
+
+func TestNewInvalidWidth(t *testing.T) {
+	for _, w := range []uint8{0, 65} {
+		_, err := New(Params{Width: w, Poly: 0x07})
+		if !errors.Is(err, ErrInvalidWidth) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidWidth, err)
+		}
+	}
+}
+
+func TestUpdateInsufficientData(t *testing.T) {
+	c, _ := New(CRC8SMBus)
+	err := c.Update([]byte{0x00}, 9)
+	if !errors.Is(err, ErrInsufficientData) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInsufficientData, err)
+	}
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	c, _ := New(CRC32)
+	data := make([]byte, 4096)
+	b.SetBytes(int64(len(data)))
+	for n := 0; n < b.N; n++ {
+		c.Update(data, uint64(len(data))*8)
+	}
+}
+
+func BenchmarkUpdateUnaligned(b *testing.B) {
+	c, _ := New(CRC32C)
+	data := make([]byte, 4096)
+	b.SetBytes(int64(len(data)))
+	for n := 0; n < b.N; n++ {
+		c.UpdateBit(1)
+		c.Update(data, uint64(len(data))*8)
+	}
+}
+
+func BenchmarkUpdateTable(b *testing.B) {
+	c, _ := New(CRC32MPEG2)
+	data := make([]byte, 4096)
+	b.SetBytes(int64(len(data)))
+	for n := 0; n < b.N; n++ {
+		c.Update(data, uint64(len(data))*8)
+	}
+}
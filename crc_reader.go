@@ -0,0 +1,222 @@
+package bitstream
+
+import (
+	"errors"
+
+	"github.com/bearmini/bitstream-go/crc"
+)
+
+// CRCReader is a bit stream reader which maintains a running CRC over every bit read through it.
+// Bits read directly from the underlying Reader are not covered by the CRC.
+type CRCReader struct {
+	r   *Reader
+	crc *crc.CRC
+}
+
+// NewCRCReader creates a new CRCReader instance which reads bits from `r` and computes a CRC defined by `params`.
+func NewCRCReader(r *Reader, params CRCParams) (*CRCReader, error) {
+	c, err := crc.New(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CRCReader{
+		r:   r,
+		crc: c,
+	}, nil
+}
+
+// Reader returns the underlying Reader.
+func (cr *CRCReader) Reader() *Reader {
+	return cr.r
+}
+
+// Reset restarts the CRC computation from the initial value.
+func (cr *CRCReader) Reset() {
+	cr.crc.Reset()
+}
+
+// Sum returns the CRC value of the bits read since the CRCReader was created or reset.
+func (cr *CRCReader) Sum() uint64 {
+	return cr.crc.Sum64()
+}
+
+// ReadCRC reads a CRC field (`Width` bits) from the bit stream.
+// The bits of the CRC field are not fed to the running CRC.
+func (cr *CRCReader) ReadCRC() (uint64, error) {
+	return cr.r.ReadNBitsAsUint64BE(cr.crc.Params().Width)
+}
+
+// Verify reads a CRC field from the bit stream and compares it with the CRC of the bits read so far.
+// The running CRC is reset after the comparison so that the next frame can be verified.
+func (cr *CRCReader) Verify() (bool, error) {
+	sum := cr.crc.Sum64()
+	v, err := cr.ReadCRC()
+	if err != nil {
+		return false, err
+	}
+
+	cr.crc.Reset()
+	return v == sum, nil
+}
+
+// updatePartial feeds the bits of a field cut off by the end of the stream to the CRC in the lenient EOF mode.
+// `v` is the value returned together with the PartialFieldError, i.e. the bits read so far padded with zeros to `nBits` bits.
+func (cr *CRCReader) updatePartial(v uint64, nBits uint8, err error) {
+	var pe *PartialFieldError
+	if !errors.As(err, &pe) || pe.ReadBits == 0 || pe.ReadBits > uint(nBits) {
+		return
+	}
+	read := uint8(pe.ReadBits)
+	cr.crc.UpdateBits(v>>(nBits-read), read)
+}
+
+// ReadBit reads a single bit from the bit stream.
+func (cr *CRCReader) ReadBit() (byte, error) {
+	b, err := cr.r.ReadBit()
+	if err != nil {
+		return 0, err
+	}
+	cr.crc.UpdateBit(b)
+	return b, nil
+}
+
+// ReadBool reads a single bit from the bit stream and return it as a bool.
+func (cr *CRCReader) ReadBool() (bool, error) {
+	b, err := cr.ReadBit()
+	if err != nil {
+		return false, err
+	}
+	return b != 0, nil
+}
+
+// ReadNBitsAsUint8 reads `nBits` bits as a unsigned integer from the bit stream and returns it in uint8 (LSB aligned).
+func (cr *CRCReader) ReadNBitsAsUint8(nBits uint8) (uint8, error) {
+	v, err := cr.r.ReadNBitsAsUint8(nBits)
+	if err != nil {
+		cr.updatePartial(uint64(v), nBits, err)
+		return v, err
+	}
+	cr.crc.UpdateBits(uint64(v), nBits)
+	return v, nil
+}
+
+// ReadUint8 reads 8 bits from the bit stream and returns it in uint8.
+func (cr *CRCReader) ReadUint8() (uint8, error) {
+	return cr.ReadNBitsAsUint8(8)
+}
+
+// ReadNBitsAsUint16BE reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint16 (LSB aligned).
+func (cr *CRCReader) ReadNBitsAsUint16BE(nBits uint8) (uint16, error) {
+	v, err := cr.r.ReadNBitsAsUint16BE(nBits)
+	if err != nil {
+		cr.updatePartial(uint64(v), nBits, err)
+		return v, err
+	}
+	cr.crc.UpdateBits(uint64(v), nBits)
+	return v, nil
+}
+
+// ReadUint16BE reads 16 bits as a big endian unsigned integer from the bit stream and returns it in uint16.
+func (cr *CRCReader) ReadUint16BE() (uint16, error) {
+	return cr.ReadNBitsAsUint16BE(16)
+}
+
+// ReadNBitsAsUint32BE reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint32 (LSB aligned).
+func (cr *CRCReader) ReadNBitsAsUint32BE(nBits uint8) (uint32, error) {
+	v, err := cr.r.ReadNBitsAsUint32BE(nBits)
+	if err != nil {
+		cr.updatePartial(uint64(v), nBits, err)
+		return v, err
+	}
+	cr.crc.UpdateBits(uint64(v), nBits)
+	return v, nil
+}
+
+// ReadUint32BE reads 32 bits as a big endian unsigned integer from the bit stream and returns it in uint32.
+func (cr *CRCReader) ReadUint32BE() (uint32, error) {
+	return cr.ReadNBitsAsUint32BE(32)
+}
+
+// ReadNBitsAsInt32BE reads `nBits` bits as a big endian signed integer from the bit stream and returns it in int32 (LSB aligned).
+func (cr *CRCReader) ReadNBitsAsInt32BE(nBits uint8) (int32, error) {
+	v, err := cr.r.ReadNBitsAsInt32BE(nBits)
+	if err != nil {
+		cr.updatePartial(uint64(uint32(v)), nBits, err)
+		return v, err
+	}
+	cr.crc.UpdateBits(uint64(uint32(v)), nBits)
+	return v, nil
+}
+
+// ReadNBitsAsUint64BE reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint64 (LSB aligned).
+func (cr *CRCReader) ReadNBitsAsUint64BE(nBits uint8) (uint64, error) {
+	v, err := cr.r.ReadNBitsAsUint64BE(nBits)
+	if err != nil {
+		cr.updatePartial(v, nBits, err)
+		return v, err
+	}
+	cr.crc.UpdateBits(v, nBits)
+	return v, nil
+}
+
+// ReadUint64BE reads 64 bits as a big endian unsigned integer from the bit stream and returns it in uint64.
+func (cr *CRCReader) ReadUint64BE() (uint64, error) {
+	return cr.ReadNBitsAsUint64BE(64)
+}
+
+// ReadNBits reads `nBits` bits from the bit stream and returns it as a slice of bytes.
+func (cr *CRCReader) ReadNBits(nBits uint, opt *ReadOptions) ([]byte, error) {
+	data, err := cr.r.ReadNBits(nBits, opt)
+	if err != nil {
+		var pe *PartialFieldError
+		if errors.As(err, &pe) {
+			uerr := cr.crc.Update(data, uint64(pe.ReadBits))
+			if uerr != nil {
+				return nil, uerr
+			}
+		}
+		return data, err
+	}
+	err = cr.crc.Update(data, uint64(nBits))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ReadBytes reads `nBytes` bytes from the bit stream.
+func (cr *CRCReader) ReadBytes(nBytes uint) ([]byte, error) {
+	return cr.ReadNBits(nBytes*8, nil)
+}
+
+// ReadNamed reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint64 (LSB aligned).
+// `name` is passed to the trace hook of the underlying Reader.
+func (cr *CRCReader) ReadNamed(name string, nBits uint8) (uint64, error) {
+	v, err := cr.r.ReadNamed(name, nBits)
+	if err != nil {
+		cr.updatePartial(v, nBits, err)
+		return v, err
+	}
+	cr.crc.UpdateBits(v, nBits)
+	return v, nil
+}
+
+// ReadAll reads the remainder of the bit stream and returns it as a slice of bytes (left aligned).
+// `trailingBits` is the number of valid bits in the last byte.
+func (cr *CRCReader) ReadAll() ([]byte, uint8, error) {
+	data, trailingBits, err := cr.r.ReadAll()
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) == 0 {
+		return data, trailingBits, nil
+	}
+
+	nBits := uint64(len(data)-1)*8 + uint64(trailingBits)
+	err = cr.crc.Update(data, nBits)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, trailingBits, nil
+}
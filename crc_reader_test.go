@@ -0,0 +1,213 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bearmini/bitstream-go/crc"
+)
+
+var crcCheckInput = []byte("123456789")
+
+var crc16Params = crc.CRC16IBM3740
+
+func TestCRCReaderSum(t *testing.T) {
+	r := NewReader(bytes.NewReader(crcCheckInput), nil)
+	cr, err := NewCRCReader(r, crc16Params)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	// read "123456789" as fields which are not byte aligned: 3 + 10 + 1 + 32 + 20 + 6 = 72 bits
+	if _, err := cr.ReadNBitsAsUint8(3); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if _, err := cr.ReadNBitsAsUint16BE(10); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if _, err := cr.ReadBit(); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if _, err := cr.ReadUint32BE(); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if _, err := cr.ReadNBits(20, nil); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if _, err := cr.ReadNBitsAsUint64BE(6); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	expected := uint64(0x29b1)
+	if expected != cr.Sum() {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", expected, cr.Sum())
+	}
+}
+
+func TestCRCReaderLenientEOF(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Read     func(cr *CRCReader) (uint64, error)
+		Expected uint64
+	}{
+		{
+			Name: "integer",
+			Read: func(cr *CRCReader) (uint64, error) {
+				v, err := cr.ReadNBitsAsUint16BE(12)
+				return uint64(v), err
+			},
+			Expected: 0x390,
+		},
+		{
+			Name: "signed integer",
+			Read: func(cr *CRCReader) (uint64, error) {
+				v, err := cr.ReadNBitsAsInt32BE(9)
+				return uint64(uint32(v)), err
+			},
+			Expected: 0x72,
+		},
+		{
+			Name: "bytes",
+			Read: func(cr *CRCReader) (uint64, error) {
+				v, err := cr.ReadNBits(16, nil)
+				if len(v) != 2 {
+					return 0, err
+				}
+				return uint64(v[0])<<8 | uint64(v[1]), err
+			},
+			Expected: 0x3900,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(crcCheckInput), &ReaderOptions{EOFMode: EOFLenient})
+			cr, err := NewCRCReader(r, crc16Params)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+
+			if _, err := cr.ReadUint64BE(); err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			// "9" is followed by the end of the stream
+			v, err := data.Read(cr)
+			var pe *PartialFieldError
+			if !errors.As(err, &pe) || pe.ReadBits != 8 {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "PartialFieldError of 8 bits", err)
+			}
+			if data.Expected != v {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Expected, v)
+			}
+			// the bits read are covered by the CRC
+			if cr.Sum() != 0x29b1 {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x29b1, cr.Sum())
+			}
+		})
+	}
+}
+
+func TestCRCReaderVerify(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Data     []byte
+		Expected bool
+	}{
+		{
+			Name:     "valid",
+			Data:     []byte{0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0x29, 0xb1},
+			Expected: true,
+		},
+		{
+			Name:     "corrupted payload",
+			Data:     []byte{0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x38, 0x29, 0xb1},
+			Expected: false,
+		},
+		{
+			Name:     "corrupted crc",
+			Data:     []byte{0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0x29, 0xb0},
+			Expected: false,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data.Data), nil)
+			cr, err := NewCRCReader(r, crc16Params)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			for i := 0; i < 9; i++ {
+				if _, err := cr.ReadUint8(); err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+			}
+
+			ok, err := cr.Verify()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.Expected != ok {
+				t.Fatalf("\nExpected: %t\nActual:   %t\n", data.Expected, ok)
+			}
+			if cr.Sum() != crc16Params.Init {
+				t.Fatalf("running CRC should be reset after Verify: %#x\n", cr.Sum())
+			}
+		})
+	}
+}
+
+func TestCRCReaderUnalignedCRCField(t *testing.T) {
+	// 4-bit header 0xa, 8-bit payload 0x5c, followed by CRC-8/SMBUS over those 12 bits
+	c, _ := crc.New(crc.CRC8SMBus)
+	c.UpdateBits(0xa5c, 12)
+	sum := c.Sum64()
+
+	data := []byte{0xa5, 0xc0 | byte(sum>>4), byte(sum << 4)}
+	r := NewReader(bytes.NewReader(data), nil)
+	cr, err := NewCRCReader(r, CRCParams{Width: 8, Poly: 0x07})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if _, err := cr.ReadNBitsAsUint8(4); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if _, err := cr.ReadUint8(); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	v, err := cr.ReadCRC()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != sum {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", sum, v)
+	}
+}
+
+func TestCRCReaderReadAll(t *testing.T) {
+	r := NewReader(bytes.NewReader(crcCheckInput), nil)
+	cr, err := NewCRCReader(r, crc16Params)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	// the rest of the stream is not byte aligned
+	if _, err := cr.ReadNBitsAsUint8(5); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	data, trailingBits, err := cr.ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if len(data) != 9 || trailingBits != 3 {
+		t.Fatalf("\nunexpected length: %d bytes (%d bits in the last byte)\n", len(data), trailingBits)
+	}
+
+	expected := uint64(0x29b1)
+	if expected != cr.Sum() {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", expected, cr.Sum())
+	}
+}
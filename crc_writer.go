@@ -0,0 +1,193 @@
+package bitstream
+
+import (
+	"github.com/bearmini/bitstream-go/crc"
+)
+
+// CRCWriter is a bit stream writer which maintains a running CRC over every bit written through it.
+// Bits written directly to the underlying Writer are not covered by the CRC.
+type CRCWriter struct {
+	w   *Writer
+	crc *crc.CRC
+}
+
+// NewCRCWriter creates a new CRCWriter instance which writes bits to `w` and computes a CRC defined by `params`.
+func NewCRCWriter(w *Writer, params CRCParams) (*CRCWriter, error) {
+	c, err := crc.New(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CRCWriter{
+		w:   w,
+		crc: c,
+	}, nil
+}
+
+// Writer returns the underlying Writer.
+func (cw *CRCWriter) Writer() *Writer {
+	return cw.w
+}
+
+// Reset restarts the CRC computation from the initial value.
+func (cw *CRCWriter) Reset() {
+	cw.crc.Reset()
+}
+
+// Sum returns the CRC value of the bits written since the CRCWriter was created or reset.
+func (cw *CRCWriter) Sum() uint64 {
+	return cw.crc.Sum64()
+}
+
+// WriteCRC writes the CRC of the bits written so far (`Width` bits) at the current position of the bit stream.
+// The running CRC is reset afterwards so that the next frame can be written.
+func (cw *CRCWriter) WriteCRC() error {
+	sum := cw.crc.Sum64()
+	width := cw.crc.Params().Width
+
+	if width > 32 {
+		err := cw.w.WriteNBitsOfUint32BE(width-32, uint32(sum>>32))
+		if err != nil {
+			return err
+		}
+		width = 32
+	}
+
+	err := cw.w.WriteNBitsOfUint32BE(width, uint32(sum))
+	if err != nil {
+		return err
+	}
+
+	cw.crc.Reset()
+	return nil
+}
+
+// WriteBit writes a single bit to the bit stream.
+func (cw *CRCWriter) WriteBit(bit uint8) error {
+	err := cw.w.WriteBit(bit)
+	if err != nil {
+		return err
+	}
+	cw.crc.UpdateBit(bit)
+	return nil
+}
+
+// WriteBool writes a single bit to the bit stream.
+func (cw *CRCWriter) WriteBool(b bool) error {
+	bit := uint8(0)
+	if b {
+		bit = 1
+	}
+
+	return cw.WriteBit(bit)
+}
+
+// WriteNBitsOfUint8 writes `nBits` bits to the bit stream.
+func (cw *CRCWriter) WriteNBitsOfUint8(nBits, val uint8) error {
+	err := cw.w.WriteNBitsOfUint8(nBits, val)
+	if err != nil {
+		return err
+	}
+	cw.crc.UpdateBits(uint64(val), nBits)
+	return nil
+}
+
+// WriteUint8 writes a uint8 value to the bit stream.
+func (cw *CRCWriter) WriteUint8(val uint8) error {
+	return cw.WriteNBitsOfUint8(8, val)
+}
+
+// WriteNBitsOfUint16BE writes `nBits` bits to the bit stream.
+func (cw *CRCWriter) WriteNBitsOfUint16BE(nBits uint8, val uint16) error {
+	err := cw.w.WriteNBitsOfUint16BE(nBits, val)
+	if err != nil {
+		return err
+	}
+	cw.crc.UpdateBits(uint64(val), nBits)
+	return nil
+}
+
+// WriteUint16BE writes a uint16 value to the bit stream.
+func (cw *CRCWriter) WriteUint16BE(val uint16) error {
+	return cw.WriteNBitsOfUint16BE(16, val)
+}
+
+// WriteNBitsOfUint32BE writes `nBits` bits to the bit stream.
+func (cw *CRCWriter) WriteNBitsOfUint32BE(nBits uint8, val uint32) error {
+	err := cw.w.WriteNBitsOfUint32BE(nBits, val)
+	if err != nil {
+		return err
+	}
+	cw.crc.UpdateBits(uint64(val), nBits)
+	return nil
+}
+
+// WriteUint32BE writes a uint32 value to the bit stream.
+func (cw *CRCWriter) WriteUint32BE(val uint32) error {
+	return cw.WriteNBitsOfUint32BE(32, val)
+}
+
+// WriteNBitsOfUint64BE writes `nBits` bits to the bit stream.
+func (cw *CRCWriter) WriteNBitsOfUint64BE(nBits uint8, val uint64) error {
+	err := cw.w.WriteNBitsOfUint64BE(nBits, val)
+	if err != nil {
+		return err
+	}
+	cw.crc.UpdateBits(val, nBits)
+	return nil
+}
+
+// WriteUint64BE writes a uint64 value to the bit stream.
+func (cw *CRCWriter) WriteUint64BE(val uint64) error {
+	return cw.WriteNBitsOfUint64BE(64, val)
+}
+
+// WriteNBits writes specified number of bits of the bytes to the bit stream.
+func (cw *CRCWriter) WriteNBits(nBits uint, data []byte) error {
+	err := cw.w.WriteNBits(nBits, data)
+	if err != nil {
+		return err
+	}
+	return cw.crc.Update(data, uint64(nBits))
+}
+
+// Flush flushes the underlying Writer.
+func (cw *CRCWriter) Flush() error {
+	return cw.w.Flush()
+}
+
+// WriteBytes writes all the bytes in `p` to the bit stream.
+func (cw *CRCWriter) WriteBytes(p []byte) error {
+	err := cw.w.WriteBytes(p)
+	if err != nil {
+		return err
+	}
+	_, err = cw.crc.Write(p)
+	return err
+}
+
+// WriteString writes all the bytes in `s` to the bit stream.
+func (cw *CRCWriter) WriteString(s string) error {
+	return cw.WriteBytes([]byte(s))
+}
+
+// AlignByte pads the current byte with `padBit` up to the byte boundary and writes it to the destination.
+// The pad bits are covered by the CRC.
+func (cw *CRCWriter) AlignByte(padBit uint8) (uint8, error) {
+	padded, err := cw.w.AlignByte(padBit)
+	if err != nil {
+		return 0, err
+	}
+	cw.crc.UpdateBits(uint64(0xff*(padBit&0x01)), padded)
+	return padded, nil
+}
+
+// Finalize finalizes the underlying Writer.
+func (cw *CRCWriter) Finalize() error {
+	return cw.w.Finalize()
+}
+
+// Close closes the underlying Writer.
+func (cw *CRCWriter) Close() error {
+	return cw.w.Close()
+}
@@ -0,0 +1,144 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/bearmini/bitstream-go/crc"
+)
+
+func TestCRCWriterWriteCRC(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	cw, err := NewCRCWriter(NewWriter(buf), crc16Params)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	// write "123456789" as fields which are not byte aligned: 3 + 10 + 1 + 32 + 20 + 6 = 72 bits
+	data := crcCheckInput
+	if err := cw.WriteNBitsOfUint8(3, data[0]>>5); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if err := cw.WriteNBitsOfUint16BE(10, (uint16(data[0])<<8|uint16(data[1]))>>3); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if err := cw.WriteBit(data[1] >> 2); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if err := cw.WriteNBitsOfUint32BE(32, (uint32(data[1])<<30)|(uint32(data[2])<<22)|(uint32(data[3])<<14)|(uint32(data[4])<<6)|(uint32(data[5])>>2)); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if err := cw.WriteNBits(20, []byte{data[5]<<6 | data[6]>>2, data[6]<<6 | data[7]>>2, data[7]<<6 | data[8]>>2}); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if err := cw.WriteNBitsOfUint8(6, data[8]); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	if err := cw.WriteCRC(); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	expected := append(append([]byte{}, crcCheckInput...), 0x29, 0xb1)
+	if !reflect.DeepEqual(expected, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, buf.Bytes())
+	}
+	if cw.Sum() != crc16Params.Init {
+		t.Fatalf("running CRC should be reset after WriteCRC: %#x\n", cw.Sum())
+	}
+}
+
+func TestCRCWriterUint64(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	cw, err := NewCRCWriter(NewWriter(buf), crc16Params)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	// write "123456789" as 5 + 64 + 3 bits
+	data := crcCheckInput
+	if err := cw.WriteNBitsOfUint8(5, data[0]>>3); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	v := uint64(data[0])<<61 | binary.BigEndian.Uint64(data[1:])>>3
+	if err := cw.WriteUint64BE(v); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if err := cw.WriteNBitsOfUint64BE(3, uint64(data[8])); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	if err := cw.WriteCRC(); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expected := append(append([]byte{}, crcCheckInput...), 0x29, 0xb1)
+	if !reflect.DeepEqual(expected, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, buf.Bytes())
+	}
+}
+
+func TestCRCWriterRoundTrip(t *testing.T) {
+	params := CRCParams{Width: 40, Poly: 0x0004820009, XorOut: 0xffffffffff} // CRC-40/GSM
+	buf := bytes.NewBuffer([]byte{})
+	cw, err := NewCRCWriter(NewWriter(buf), params)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	cw.WriteNBitsOfUint8(5, 0x15)
+	cw.WriteBool(true)
+	cw.WriteUint16BE(0xbeef)
+	cw.WriteNBitsOfUint32BE(23, 0x123456)
+	if err := cw.WriteCRC(); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	cw.Finalize()
+
+	cr, err := NewCRCReader(NewReader(bytes.NewReader(buf.Bytes()), nil), params)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	cr.ReadNBitsAsUint8(5)
+	cr.ReadBool()
+	cr.ReadUint16BE()
+	cr.ReadNBitsAsUint32BE(23)
+	ok, err := cr.Verify()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !ok {
+		t.Fatal("CRC should match\n")
+	}
+}
+
+func TestCRCWriterAlignByte(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	cw, err := NewCRCWriter(NewWriter(buf), crc16Params)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	if err := cw.WriteNBitsOfUint8(3, 0x05); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	padded, err := cw.AlignByte(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if padded != 5 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 5, padded)
+	}
+	if err := cw.WriteString("12"); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	expected, err := crc.Checksum(crc16Params, buf.Bytes(), uint64(buf.Len())*8)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if expected != cw.Sum() {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", expected, cw.Sum())
+	}
+}
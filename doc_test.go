@@ -94,7 +94,7 @@ func ExampleWriter() {
 		log.Fatalf("%+v", err)
 	}
 
-	w.Flush()
+	w.Finalize()
 
 	// we have written the following bits:
 	// 1
@@ -109,3 +109,52 @@ func ExampleWriter() {
 	// Output:
 	// a53cb43d68
 }
+
+func ExampleReader_ReadNamed() {
+	data := []byte{0x45, 0x00, 0x00, 0x54}
+
+	opt := &bitstream.ReaderOptions{
+		TraceHook: func(name string, bitOffset uint64, nBits uint, value any) {
+			fmt.Printf("%3d %2d %-8s %v\n", bitOffset, nBits, name, value)
+		},
+	}
+	r := bitstream.NewReader(bytes.NewReader(data), opt)
+
+	for _, f := range []struct {
+		name  string
+		nBits uint8
+	}{{"version", 4}, {"ihl", 4}, {"dscp", 6}, {"ecn", 2}, {"length", 16}} {
+		_, err := r.ReadNamed(f.name, f.nBits)
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+	}
+
+	// Output:
+	//   0  4 version  4
+	//   4  4 ihl      5
+	//   8  6 dscp     0
+	//  14  2 ecn      0
+	//  16 16 length   84
+}
+
+func ExampleStickyWriter() {
+	dst := bytes.NewBuffer([]byte{})
+	w := bitstream.NewStickyWriter(bitstream.NewWriter(dst))
+
+	// the error is checked only once at the end
+	w.WriteBit(1).
+		WriteBool(false).
+		WriteNBitsOfUint8(2, 0x02).
+		WriteUint8(0x53).
+		WriteNBitsOfUint16BE(10, 0x032d).
+		WriteUint16BE(0x0f5a).
+		Finalize()
+	if err := w.Err(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	fmt.Printf("%s", hex.EncodeToString(dst.Bytes()))
+	// Output:
+	// a53cb43d68
+}
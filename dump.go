@@ -0,0 +1,135 @@
+package bitstream
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultDumpBytesPerLine = 4
+)
+
+// Field is a range of bits in a bit stream, which is annotated in a dump.
+type Field struct {
+	Name      string
+	BitOffset uint64 // offset of the first bit of the field from the beginning of the bit stream
+	NBits     uint
+	Value     any // optional. printed next to the name if not nil
+}
+
+// FieldLog collects the fields read from a Reader or written to a Writer.
+// Set its Trace method as the TraceHook of the Reader or the Writer to record the fields, and pass it to Dump to annotate them.
+type FieldLog []Field
+
+// Trace appends a field to the log. It has the signature of TraceHook.
+func (l *FieldLog) Trace(name string, bitOffset uint64, nBits uint, value any) {
+	*l = append(*l, Field{Name: name, BitOffset: bitOffset, NBits: nBits, Value: value})
+}
+
+// DumpOptions is a set of options for Dump.
+type DumpOptions struct {
+	BytesPerLine uint   // default: 4
+	BitOffset    uint64 // bit offset of the first byte of the data in the bit stream. must be a multiple of 8
+
+	// Ruler adds a header line which numbers the bits of each byte in BitNumbering.
+	Ruler        bool
+	BitNumbering BitNumbering
+}
+
+// GetBytesPerLine gets configured number of bytes per line.
+func (opt *DumpOptions) GetBytesPerLine() uint {
+	if opt == nil || opt.BytesPerLine == 0 {
+		return defaultDumpBytesPerLine
+	}
+	return opt.BytesPerLine
+}
+
+// GetBitOffset gets configured bit offset of the data.
+func (opt *DumpOptions) GetBitOffset() uint64 {
+	if opt == nil {
+		return 0
+	}
+	return opt.BitOffset
+}
+
+// Dump renders `data` as a hexdump with bit offsets and binary expression.
+// Each of `fields` is marked with '^' under its bits, followed by its name (and value if any) on every line it spans:
+//
+//	0  45 00 00 54  0100 0101 0000 0000 0000 0000 0101 0100
+//	                ^^^^                                     version = 4
+//	                     ^^^^                                ihl = 5
+func Dump(data []byte, fields []Field, opt *DumpOptions) string {
+	bpl := opt.GetBytesPerLine()
+	base := opt.GetBitOffset()
+
+	sb := &strings.Builder{}
+	if opt != nil && opt.Ruler {
+		ruler := "0123 4567"
+		if opt.GetBitNumbering() == LSB0 {
+			ruler = "7654 3210"
+		}
+		fmt.Fprintf(sb, "%6s  %s  %s\n", "", strings.Repeat(" ", int(bpl)*3-1), strings.TrimRight(strings.Repeat(ruler+" ", int(bpl)), " "))
+	}
+	for start := uint(0); start < uint(len(data)); start += bpl {
+		end := start + bpl
+		if end > uint(len(data)) {
+			end = uint(len(data))
+		}
+		line := data[start:end]
+
+		hex := make([]string, bpl)
+		bin := make([]string, bpl)
+		for i := range hex {
+			if i < len(line) {
+				hex[i] = fmt.Sprintf("%02x", line[i])
+				bin[i] = fmt.Sprintf("%04b %04b", line[i]>>4, line[i]&0x0f)
+			} else {
+				hex[i] = "  "
+				bin[i] = "         "
+			}
+		}
+		fmt.Fprintf(sb, "%6d  %s  %s\n", base+uint64(start)*8, strings.Join(hex, " "), strings.TrimRight(strings.Join(bin, " "), " "))
+
+		lineFirst := base + uint64(start)*8
+		lineLast := base + uint64(end)*8 // exclusive
+		for _, f := range fields {
+			first := f.BitOffset
+			last := f.BitOffset + uint64(f.NBits) // exclusive
+			if f.NBits == 0 || last <= lineFirst || first >= lineLast {
+				continue
+			}
+
+			marker := []byte(strings.Repeat(" ", len(bin)*10))
+			for b := first; b < last; b++ {
+				if b < lineFirst || b >= lineLast {
+					continue
+				}
+				i := b - lineFirst // bit index in the line
+				marker[i/8*10+i%8+i%8/4] = '^'
+			}
+
+			label := f.Name
+			if f.Value != nil {
+				label = fmt.Sprintf("%s = %v", f.Name, f.Value)
+			}
+			fmt.Fprintf(sb, "%6s  %s  %s %s\n", "", strings.Repeat(" ", len(hex)*3-1), string(marker), label)
+		}
+	}
+	return sb.String()
+}
+
+// DumpBuffer renders the bytes in the buffer of the Reader which have not been consumed yet (including the current byte) in the same way as Dump.
+// The bit offsets are the ones from the beginning of the bit stream, so the fields recorded with FieldLog can be annotated as they are.
+func (r *Reader) DumpBuffer(fields []Field, opt *DumpOptions) string {
+	if r.isBufEmpty() {
+		return ""
+	}
+
+	o := DumpOptions{BitOffset: uint64(r.consumedBytes) * 8}
+	if opt != nil {
+		o.BytesPerLine = opt.BytesPerLine
+		o.Ruler = opt.Ruler
+		o.BitNumbering = opt.BitNumbering
+	}
+	return Dump(r.buf[r.currByteIndex:r.bufLen], fields, &o)
+}
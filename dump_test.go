@@ -0,0 +1,85 @@
+package bitstream
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	data := []byte{0x45, 0x00, 0x00, 0x54, 0x12, 0x34, 0x56}
+
+	testData := []struct {
+		Name     string
+		Fields   []Field
+		Opt      *DumpOptions
+		Expected string
+	}{
+		{
+			Name:   "no fields",
+			Fields: nil,
+			Opt:    nil,
+			Expected: "" +
+				"     0  45 00 00 54  0100 0101 0000 0000 0000 0000 0101 0100\n" +
+				"    32  12 34 56     0001 0010 0011 0100 0101 0110\n",
+		},
+		{
+			Name: "fields across lines",
+			Fields: []Field{
+				{Name: "version", BitOffset: 0, NBits: 4, Value: uint64(4)},
+				{Name: "dscp", BitOffset: 8, NBits: 6},
+				{Name: "x", BitOffset: 30, NBits: 11},
+			},
+			Opt: nil,
+			Expected: "" +
+				"     0  45 00 00 54  0100 0101 0000 0000 0000 0000 0101 0100\n" +
+				"                     ^^^^                                     version = 4\n" +
+				"                               ^^^^ ^^                        dscp\n" +
+				"                                                          ^^  x\n" +
+				"    32  12 34 56     0001 0010 0011 0100 0101 0110\n" +
+				"                     ^^^^ ^^^^ ^                              x\n",
+		},
+		{
+			Name: "bit offset and bytes per line",
+			Fields: []Field{
+				{Name: "a", BitOffset: 20, NBits: 3},
+				{Name: "out of range", BitOffset: 0, NBits: 16},
+			},
+			Opt: &DumpOptions{BytesPerLine: 2, BitOffset: 16},
+			Expected: "" +
+				"    16  45 00  0100 0101 0000 0000\n" +
+				"                    ^^^             a\n" +
+				"    32  00 54  0000 0000 0101 0100\n" +
+				"    48  12 34  0001 0010 0011 0100\n" +
+				"    64  56     0101 0110\n",
+		},
+	}
+
+	for _, data2 := range testData {
+		data2 := data2 // capture
+		t.Run(data2.Name, func(t *testing.T) {
+			d := Dump(data, data2.Fields, data2.Opt)
+			if d != data2.Expected {
+				t.Fatalf("\nExpected:\n%s\nActual:\n%s\n", data2.Expected, d)
+			}
+		})
+	}
+}
+
+func TestDumpBuffer(t *testing.T) {
+	var fields FieldLog
+	r := NewReader(bytes.NewReader([]byte{0x45, 0x00, 0x00, 0x54, 0x12, 0x34}), &ReaderOptions{TraceHook: fields.Trace})
+	for _, nBits := range []uint8{4, 4, 6, 2, 16, 3} {
+		_, err := r.ReadNamed("f", nBits)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+
+	d := r.DumpBuffer(fields[len(fields)-1:], &DumpOptions{BytesPerLine: 2})
+	expected := "" +
+		"    32  12 34  0001 0010 0011 0100\n" +
+		"               ^^^                  f = 0\n"
+	if d != expected {
+		t.Fatalf("\nExpected:\n%s\nActual:\n%s\n", expected, d)
+	}
+}
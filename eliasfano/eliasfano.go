@@ -0,0 +1,237 @@
+// Package eliasfano implements the Elias-Fano encoding of monotone (non-decreasing) sequences of uint64 values.
+//
+// Each value is split into the low `L` bits, which are stored as they are, and the high bits, which are stored in unary
+// as the gaps in a bit vector. A sequence of n values less than u takes about n * (2 + log2(u/n)) bits.
+// The encoded sequence is written to and read from a bitstream.Writer / bitstream.Reader,
+// and the decoded Sequence supports random access and NextGEQ queries without expanding the values.
+//
+// The encoded format is:
+//
+//	n        64 bits  number of values
+//	L        8 bits   number of the low bits of each value
+//	upperLen 64 bits  number of bits of the high part
+//	low      n*L bits low bits of the values
+//	upper    upperLen bits
+package eliasfano
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+var (
+	// ErrNotSorted is returned when the values to be encoded are not in non-decreasing order.
+	ErrNotSorted = errors.New("eliasfano: values are not sorted")
+
+	// ErrInvalidHeader is returned when the header of an encoded sequence is broken.
+	ErrInvalidHeader = errors.New("eliasfano: invalid header")
+)
+
+// Encode writes `values` to `w` with the Elias-Fano encoding.
+// `values` must be in non-decreasing order, otherwise returns ErrNotSorted.
+func Encode(w *bitstream.Writer, values []uint64) error {
+	for i := 1; i < len(values); i++ {
+		if values[i] < values[i-1] {
+			return ErrNotSorted
+		}
+	}
+
+	n := uint64(len(values))
+	l := lowBits(values)
+	upperLen := uint64(0)
+	if n > 0 {
+		upperLen = n + values[n-1]>>l + 1
+	}
+
+	low := bitstream.NewBitVector(0)
+	upper := bitstream.NewBitVector(upperLen)
+	for i, v := range values {
+		low.AppendBits(l, v)
+		upper.Set(v>>l+uint64(i), 1)
+	}
+
+	sw := bitstream.NewStickyWriter(w)
+	sw.WriteNamed("n", 64, n).
+		WriteNamed("L", 8, uint64(l)).
+		WriteNamed("upperLen", 64, upperLen)
+	if sw.Err() != nil {
+		return sw.Err()
+	}
+	err := w.WriteBitVector(low)
+	if err != nil {
+		return err
+	}
+	return w.WriteBitVector(upper)
+}
+
+// lowBits returns the number of the low bits for `values`, i.e. floor(log2(u/n)).
+func lowBits(values []uint64) uint8 {
+	n := uint64(len(values))
+	if n == 0 {
+		return 0
+	}
+	q := values[n-1] / n // approximately u/n, avoiding the overflow of max+1
+	if q == 0 {
+		return 0
+	}
+	return uint8(bits.Len64(q) - 1)
+}
+
+// Sequence is a decoded Elias-Fano sequence which supports random access.
+type Sequence struct {
+	n     uint64
+	l     uint8
+	low   []byte
+	upper *bitstream.RankSelect
+}
+
+// Decode reads a sequence encoded by Encode from `r`.
+func Decode(r *bitstream.Reader) (*Sequence, error) {
+	n, err := r.ReadUint64BE()
+	if err != nil {
+		return nil, err
+	}
+	l, err := r.ReadUint8()
+	if err != nil {
+		return nil, err
+	}
+	upperLen, err := r.ReadUint64BE()
+	if err != nil {
+		return nil, err
+	}
+	if l > 64 || (n == 0) != (upperLen == 0) || upperLen < n {
+		return nil, ErrInvalidHeader
+	}
+
+	low, err := r.ReadBitSlice(uint(n * uint64(l)))
+	if err != nil {
+		return nil, err
+	}
+	upper, err := r.ReadBitVector(uint(upperLen))
+	if err != nil {
+		return nil, err
+	}
+
+	rs := bitstream.NewRankSelect(upper)
+	if rs.Ones() != n {
+		return nil, ErrInvalidHeader
+	}
+	return &Sequence{
+		n:     n,
+		l:     l,
+		low:   low.Bytes(),
+		upper: rs,
+	}, nil
+}
+
+// Len returns the number of values in the sequence.
+func (s *Sequence) Len() int {
+	return int(s.n)
+}
+
+// Get returns the `i`-th value. It panics if `i` is out of range.
+func (s *Sequence) Get(i int) uint64 {
+	if i < 0 || uint64(i) >= s.n {
+		panic("eliasfano: index out of range")
+	}
+	pos, _ := s.upper.Select1(uint64(i))
+	high := pos - uint64(i)
+	return high<<s.l | s.lowAt(uint64(i))
+}
+
+// lowAt returns the low bits of the `i`-th value.
+func (s *Sequence) lowAt(i uint64) uint64 {
+	if s.l == 0 {
+		return 0
+	}
+
+	off := i * uint64(s.l)
+	v := uint64(0)
+	for read := uint8(0); read < s.l; {
+		b := s.low[off/8]
+		avail := 8 - uint8(off%8)
+		n := s.l - read
+		if n > avail {
+			n = avail
+		}
+		v = v<<n | uint64(b>>(avail-n))&(1<<n-1)
+		read += n
+		off += uint64(n)
+	}
+	return v
+}
+
+// NextGEQ returns the index and the value of the first element which is greater than or equal to `x`.
+// It returns false if there is no such element. It takes O(log n) time.
+func (s *Sequence) NextGEQ(x uint64) (int, uint64, bool) {
+	return s.nextGEQ(0, x)
+}
+
+// nextGEQ searches the elements from `from` for NextGEQ.
+func (s *Sequence) nextGEQ(from int, x uint64) (int, uint64, bool) {
+	i := from + sort.Search(int(s.n)-from, func(j int) bool { return s.Get(from+j) >= x })
+	if i >= int(s.n) {
+		return i, 0, false
+	}
+	return i, s.Get(i), true
+}
+
+// Values returns all the values in the sequence.
+func (s *Sequence) Values() []uint64 {
+	values := make([]uint64, 0, s.n)
+	it := s.Iter()
+	for {
+		v, ok := it.Next()
+		if !ok {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+// Iter returns an Iterator which iterates over the sequence from the first element.
+func (s *Sequence) Iter() *Iterator {
+	return &Iterator{
+		s: s,
+	}
+}
+
+// Iterator iterates over a Sequence.
+type Iterator struct {
+	s   *Sequence
+	i   int
+	pos uint64 // position of the next '1' bit to be searched from in the upper bits
+}
+
+// Next returns the next value. It returns false at the end of the sequence.
+func (it *Iterator) Next() (uint64, bool) {
+	if uint64(it.i) >= it.s.n {
+		return 0, false
+	}
+
+	// scan the upper bits sequentially instead of Select1
+	for it.s.upper.Get(it.pos) == 0 {
+		it.pos++
+	}
+	high := it.pos - uint64(it.i)
+	v := high<<it.s.l | it.s.lowAt(uint64(it.i))
+	it.i++
+	it.pos++
+	return v, true
+}
+
+// NextGEQ skips the elements less than `x` and returns the next value which is greater than or equal to `x`.
+// It returns false if there is no such element.
+func (it *Iterator) NextGEQ(x uint64) (uint64, bool) {
+	i, _, ok := it.s.nextGEQ(it.i, x)
+	if !ok {
+		it.i = int(it.s.n)
+		return 0, false
+	}
+	it.i = i
+	it.pos, _ = it.s.upper.Select1(uint64(i))
+	return it.Next()
+}
@@ -0,0 +1,158 @@
+package eliasfano
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func encodeDecode(t *testing.T, values []uint64) (*Sequence, int) {
+	t.Helper()
+
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	err := Encode(w, values)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Finalize()
+	size := buf.Len()
+
+	s, err := Decode(bitstream.NewReader(buf, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	return s, size
+}
+
+func TestEncodeDecode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	testData := []struct {
+		Name   string
+		Values []uint64
+	}{
+		{Name: "pattern 1", Values: []uint64{}},
+		{Name: "pattern 2", Values: []uint64{0}},
+		{Name: "pattern 3", Values: []uint64{5, 8, 8, 15, 32}},
+		{Name: "pattern 4", Values: []uint64{0, 0, 0, 0}},
+		{Name: "pattern 5", Values: []uint64{1, math.MaxUint64}},
+		{Name: "pattern 6", Values: []uint64{math.MaxUint64}},
+	}
+	values := make([]uint64, 10000)
+	for i := range values {
+		values[i] = uint64(rnd.Int63n(1 << 30))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	testData = append(testData, struct {
+		Name   string
+		Values []uint64
+	}{Name: "pattern 7", Values: values})
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			s, _ := encodeDecode(t, data.Values)
+			if len(data.Values) != s.Len() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", len(data.Values), s.Len())
+			}
+			for i, v := range data.Values {
+				if v != s.Get(i) {
+					t.Fatalf("\nGet(%d)\nExpected: %+v\nActual:   %+v\n", i, v, s.Get(i))
+				}
+			}
+			if !reflect.DeepEqual(data.Values, s.Values()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Values, s.Values())
+			}
+		})
+	}
+}
+
+func TestSize(t *testing.T) {
+	values := make([]uint64, 10000)
+	for i := range values {
+		values[i] = uint64(i) * 100
+	}
+	_, size := encodeDecode(t, values)
+
+	// about n * (2 + log2(u/n)) bits, where log2(u/n) = log2(100) < 7
+	if expected := 10000 * (2 + 7) / 8; size > expected+32 {
+		t.Fatalf("\nExpected: <= %+v\nActual:   %+v\n", expected+32, size)
+	}
+}
+
+func TestNextGEQ(t *testing.T) {
+	s, _ := encodeDecode(t, []uint64{5, 8, 8, 15, 32})
+
+	testData := []struct {
+		X             uint64
+		ExpectedIndex int
+		ExpectedValue uint64
+		ExpectedOK    bool
+	}{
+		{X: 0, ExpectedIndex: 0, ExpectedValue: 5, ExpectedOK: true},
+		{X: 5, ExpectedIndex: 0, ExpectedValue: 5, ExpectedOK: true},
+		{X: 6, ExpectedIndex: 1, ExpectedValue: 8, ExpectedOK: true},
+		{X: 9, ExpectedIndex: 3, ExpectedValue: 15, ExpectedOK: true},
+		{X: 32, ExpectedIndex: 4, ExpectedValue: 32, ExpectedOK: true},
+		{X: 33, ExpectedIndex: 5, ExpectedValue: 0, ExpectedOK: false},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(fmt.Sprintf("x = %d", data.X), func(t *testing.T) {
+			i, v, ok := s.NextGEQ(data.X)
+			if data.ExpectedIndex != i || data.ExpectedValue != v || data.ExpectedOK != ok {
+				t.Fatalf("\nExpected: %+v, %+v, %+v\nActual:   %+v, %+v, %+v\n", data.ExpectedIndex, data.ExpectedValue, data.ExpectedOK, i, v, ok)
+			}
+		})
+	}
+}
+
+func TestIterator(t *testing.T) {
+	s, _ := encodeDecode(t, []uint64{5, 8, 8, 15, 32, 40})
+	it := s.Iter()
+
+	var actual []uint64
+	v, _ := it.Next()
+	actual = append(actual, v)
+	v, _ = it.NextGEQ(8)
+	actual = append(actual, v)
+	v, _ = it.NextGEQ(8) // the iterator does not go back
+	actual = append(actual, v)
+	v, _ = it.Next()
+	actual = append(actual, v)
+	v, _ = it.NextGEQ(33)
+	actual = append(actual, v)
+
+	if !reflect.DeepEqual([]uint64{5, 8, 8, 15, 40}, actual) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []uint64{5, 8, 8, 15, 40}, actual)
+	}
+	if _, ok := it.Next(); ok {
+		t.Fatalf("iterator should be at the end\n")
+	}
+	if _, ok := it.NextGEQ(0); ok {
+		t.Fatalf("iterator should be at the end\n")
+	}
+}
+
+func TestError(t *testing.T) {
+	err := Encode(bitstream.NewWriter(&bytes.Buffer{}), []uint64{2, 1})
+	if !errors.Is(err, ErrNotSorted) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNotSorted, err)
+	}
+
+	// n = 1, L = 65
+	header := []byte{0, 0, 0, 0, 0, 0, 0, 1, 65, 0, 0, 0, 0, 0, 0, 0, 2}
+	_, err = Decode(bitstream.NewReader(bytes.NewReader(header), nil))
+	if !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidHeader, err)
+	}
+}
@@ -0,0 +1,202 @@
+package bitstream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors returned by Reader and Writer.
+// They may be wrapped with additional context, so use errors.Is to test for them.
+var (
+	// ErrTooManyBits is returned when `nBits` is larger than the width of the value to be read or written.
+	ErrTooManyBits = errors.New("bitstream: nBits too large")
+
+	// ErrValueOutOfRange is returned in the strict mode when a value to be written does not fit in the specified number of bits.
+	ErrValueOutOfRange = errors.New("bitstream: value out of range")
+
+	// ErrInsufficientData is returned when the data passed to a write method has fewer bits than requested.
+	ErrInsufficientData = errors.New("bitstream: insufficient data")
+
+	// ErrInvalidRead is returned when the source returns an invalid count from Read.
+	ErrInvalidRead = errors.New("bitstream: source returned invalid count from Read")
+
+	// ErrNotImplemented is returned when a requested option is not supported yet.
+	ErrNotImplemented = errors.New("bitstream: not implemented yet")
+
+	// ErrNotAligned is returned when a Writer is closed in the middle of a byte with the PadNone padding policy.
+	ErrNotAligned = errors.New("bitstream: bit stream is not byte aligned")
+
+	// ErrNotPatchable is returned when bits which have already been written cannot be overwritten,
+	// e.g. the destination is not seekable or the rest of a partially overwritten byte cannot be read back.
+	ErrNotPatchable = errors.New("bitstream: reserved bits cannot be patched")
+
+	// ErrPendingReservation is returned when a Writer is finalized before all the reservations are patched.
+	ErrPendingReservation = errors.New("bitstream: reserved bits have not been patched")
+
+	// ErrNoTransaction is returned when Commit or Rollback is called without Begin.
+	ErrNoTransaction = errors.New("bitstream: no transaction in progress")
+
+	// ErrInTransaction is returned when a Writer is finalized in the middle of a transaction.
+	ErrInTransaction = errors.New("bitstream: transaction in progress")
+
+	// ErrClosed is returned when a Reader is read after it has been closed.
+	ErrClosed = errors.New("bitstream: reader closed")
+
+	// ErrInvalidAlphabet is returned when an alphabet does not have 2, 4, ..., 256 distinct characters.
+	ErrInvalidAlphabet = errors.New("bitstream: invalid alphabet")
+
+	// ErrInvalidCharacter is returned when a character to be written is not in the alphabet.
+	ErrInvalidCharacter = errors.New("bitstream: character not in alphabet")
+
+	// ErrNotSeekable is returned when SeekBit is called on a Reader whose source cannot seek.
+	ErrNotSeekable = errors.New("bitstream: source is not seekable")
+
+	// ErrInvalidCheckpoint is returned when a serialized checkpoint is malformed.
+	ErrInvalidCheckpoint = errors.New("bitstream: invalid checkpoint")
+
+	// ErrLimitExceeded is returned, wrapped in a *LimitError, when a call exceeds a limit of ReaderLimits.
+	ErrLimitExceeded = errors.New("bitstream: limit exceeded")
+
+	// ErrFixtureMismatch is returned when a replayed decoder does not read the same fields as the ones in a Fixture.
+	ErrFixtureMismatch = errors.New("bitstream: fixture mismatch")
+
+	// ErrCheckpointMismatch is returned when the source does not have the byte recorded in a checkpoint at its offset,
+	// e.g. the file has been modified since the checkpoint was taken.
+	ErrCheckpointMismatch = errors.New("bitstream: source does not match checkpoint")
+
+	// ErrInvalidUnread is returned when UnreadNBits is asked to push back more bits than the buffer still holds.
+	ErrInvalidUnread = errors.New("bitstream: invalid use of UnreadNBits")
+
+	// ErrNoSentinel is returned when the last byte of a backward bit stream does not have the sentinel bit.
+	ErrNoSentinel = errors.New("bitstream: no sentinel bit in the last byte")
+
+	// ErrInvalidPrefix is returned when the prefix of a variable-width field does not select any width.
+	ErrInvalidPrefix = errors.New("bitstream: invalid prefix")
+
+	// ErrInvalidMarker is returned when a marker bit of a field, e.g. the MPEG-2 PES timestamp, is not '1'.
+	ErrInvalidMarker = errors.New("bitstream: invalid marker bit")
+
+	// ErrInvalidFlags is returned when the struct given to ReadFlagsInto or WriteFlagsFrom has a field other than bool.
+	ErrInvalidFlags = errors.New("bitstream: invalid flags struct")
+
+	// ErrUnexpectedEOF is returned when the stream ends in the middle of a field.
+	// It is the same value as io.ErrUnexpectedEOF.
+	ErrUnexpectedEOF = io.ErrUnexpectedEOF
+
+	// ErrShortWrite is returned when the destination accepts fewer bytes than requested without returning an error.
+	// It is the same value as io.ErrShortWrite.
+	ErrShortWrite = io.ErrShortWrite
+)
+
+// errors returned from the hot path, preallocated so that returning them costs no allocations.
+var (
+	errTooManyBitsForUint8  = fmt.Errorf("%w for uint8", ErrTooManyBits)
+	errTooManyBitsForUint16 = fmt.Errorf("%w for uint16", ErrTooManyBits)
+	errTooManyBitsForUint32 = fmt.Errorf("%w for uint32", ErrTooManyBits)
+	errTooManyBitsForUint64 = fmt.Errorf("%w for uint64", ErrTooManyBits)
+)
+
+// tooManyBits returns the error for `nBits` larger than `maxBits`, the width of the value to be read or written.
+func tooManyBits(maxBits uint8) error {
+	switch maxBits {
+	case 8:
+		return errTooManyBitsForUint8
+	case 16:
+		return errTooManyBitsForUint16
+	case 32:
+		return errTooManyBitsForUint32
+	case 64:
+		return errTooManyBitsForUint64
+	}
+	return fmt.Errorf("%w for %d bits", ErrTooManyBits, maxBits)
+}
+
+// PartialFieldError is returned in the lenient EOF mode when the stream ends in the middle of a field.
+// The value returned together with it contains the bits read so far, padded with zeros.
+// It matches io.ErrUnexpectedEOF with errors.Is.
+type PartialFieldError struct {
+	RequestedBits uint // number of bits requested
+	ReadBits      uint // number of bits actually read
+}
+
+func (e *PartialFieldError) Error() string {
+	return fmt.Sprintf("bitstream: stream ended after %d of %d bits of a field", e.ReadBits, e.RequestedBits)
+}
+
+// Unwrap returns io.ErrUnexpectedEOF.
+func (e *PartialFieldError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// PositionError records an error and the position in the bit stream where the failed operation started.
+type PositionError struct {
+	Op         string // operation which failed, e.g. "ReadNBitsAsUint16BE"
+	BitOffset  uint64 // offset of the first bit of the operation from the beginning of the stream
+	ByteOffset uint64 // offset of the byte which contains the first bit of the operation
+	Err        error
+
+	// Numbering is the numbering of the bits in a byte used in the message, configured in the options of the Reader or the Writer.
+	Numbering BitNumbering
+}
+
+func (e *PositionError) Error() string {
+	_, bit := e.Numbering.Split(e.BitOffset)
+	if e.Numbering == LSB0 {
+		return fmt.Sprintf("bitstream: %s at bit %d (byte %d, LSB-0 bit %d): %v", e.Op, e.BitOffset, e.ByteOffset, bit, e.Err)
+	}
+	return fmt.Sprintf("bitstream: %s at bit %d (byte %d, bit %d): %v", e.Op, e.BitOffset, e.ByteOffset, bit, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// wrapError wraps `err` into a PositionError unless the Reader is configured with PlainErrors.
+func (r *Reader) wrapError(op string, pos uint64, err error) error {
+	if err != nil && r.logging() {
+		r.logDebug("bitstream: error", pos, "op", op, "error", err)
+	}
+	if err == nil || r.opt.GetPlainErrors() {
+		return err
+	}
+	return wrapErrorNumbered(op, pos, err, r.opt.GetBitNumbering())
+}
+
+// wrapError wraps `err` into a PositionError unless the Writer is configured with PlainErrors.
+func (w *Writer) wrapError(op string, pos uint64, err error) error {
+	if err != nil && w.logging() {
+		w.logDebug("bitstream: error", pos, "op", op, "error", err)
+	}
+	if err == nil || w.plainErrors {
+		return err
+	}
+	return wrapErrorNumbered(op, pos, err, w.numbering)
+}
+
+// wrapError wraps `err`, which occurred in the operation `op` started at the bit offset `pos`, into a PositionError.
+// io.EOF is returned as is so that callers can compare it with ==, as the convention of the io package.
+func wrapError(op string, pos uint64, err error) error {
+	return wrapErrorNumbered(op, pos, err, MSB0)
+}
+
+// wrapErrorNumbered is the same as wrapError except that the bits in a byte are numbered by `numbering` in the message.
+func wrapErrorNumbered(op string, pos uint64, err error, numbering BitNumbering) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+
+	var pe *PositionError
+	if errors.As(err, &pe) {
+		return err
+	}
+
+	return &PositionError{
+		Op:         op,
+		BitOffset:  pos,
+		ByteOffset: pos / 8,
+		Err:        err,
+		Numbering:  numbering,
+	}
+}
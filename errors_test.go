@@ -0,0 +1,190 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// shortWriter accepts no bytes without returning an error.
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) {
+	return 0, nil
+}
+
+func TestErrors(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Do       func() error
+		Expected error
+	}{
+		{
+			Name: "read too many bits for uint8",
+			Do: func() error {
+				_, err := NewReader(bytes.NewReader([]byte{0x00, 0x00}), nil).ReadNBitsAsUint8(9)
+				return err
+			},
+			Expected: ErrTooManyBits,
+		},
+		{
+			Name: "read too many bits for uint64",
+			Do: func() error {
+				_, err := NewReader(bytes.NewReader(make([]byte, 10)), nil).ReadNBitsAsUint64BE(65)
+				return err
+			},
+			Expected: ErrTooManyBits,
+		},
+		{
+			Name: "write too many bits for uint32",
+			Do: func() error {
+				return NewWriter(bytes.NewBuffer(nil)).WriteNBitsOfUint32BE(33, 0)
+			},
+			Expected: ErrTooManyBits,
+		},
+		{
+			Name: "read in the middle of a field",
+			Do: func() error {
+				_, err := NewReader(bytes.NewReader([]byte{0x00}), nil).ReadUint16BE()
+				return err
+			},
+			Expected: ErrUnexpectedEOF,
+		},
+		{
+			Name: "write insufficient data",
+			Do: func() error {
+				return NewWriter(bytes.NewBuffer(nil)).WriteNBits(9, []byte{0x00})
+			},
+			Expected: ErrInsufficientData,
+		},
+		{
+			Name: "short write",
+			Do: func() error {
+				return NewWriter(shortWriter{}).WriteUint8(0xff)
+			},
+			Expected: ErrShortWrite,
+		},
+		{
+			Name: "align right",
+			Do: func() error {
+				_, err := NewReader(bytes.NewReader([]byte{0x00}), nil).ReadNBits(3, &ReadOptions{AlignRight: true})
+				return err
+			},
+			Expected: ErrNotImplemented,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			err := data.Do()
+			if !errors.Is(err, data.Expected) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+		})
+	}
+
+	if !errors.Is(ErrUnexpectedEOF, io.ErrUnexpectedEOF) || !errors.Is(ErrShortWrite, io.ErrShortWrite) {
+		t.Fatal("sentinel errors should be compatible with the ones in the io package\n")
+	}
+}
+
+func TestPositionError(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x12, 0x34, 0x56}), nil)
+	r.ReadNBitsAsUint16BE(13)
+	_, err := r.ReadUint16BE()
+
+	var pe *PositionError
+	if !errors.As(err, &pe) {
+		t.Fatalf("PositionError is expected: %+v\n", err)
+	}
+	if pe.Op != "ReadNBitsAsUint16BE" || pe.BitOffset != 13 || pe.ByteOffset != 1 {
+		t.Fatalf("unexpected PositionError: %+v\n", pe)
+	}
+	if !errors.Is(err, ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrUnexpectedEOF, err)
+	}
+
+	w := NewWriter(shortWriter{})
+	w.WriteNBitsOfUint8(5, 0x1f)
+	err = w.WriteNBitsOfUint16BE(12, 0x0fff)
+	if !errors.As(err, &pe) {
+		t.Fatalf("PositionError is expected: %+v\n", err)
+	}
+	if pe.Op != "WriteNBitsOfUint16BE" || pe.BitOffset != 5 || pe.ByteOffset != 0 {
+		t.Fatalf("unexpected PositionError: %+v\n", pe)
+	}
+	if !errors.Is(err, ErrShortWrite) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrShortWrite, err)
+	}
+}
+
+func TestPositionErrorEOF(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x12}), nil)
+	r.ReadUint8()
+	_, err := r.ReadUint8()
+	if err != io.EOF {
+		t.Fatalf("io.EOF should be returned as is: %+v\n", err)
+	}
+}
+
+func TestPlainErrors(t *testing.T) {
+	data := []byte{0x12, 0x34}
+	r := NewReaderBytes(data, &ReaderOptions{PlainErrors: true})
+	w := NewWriterWithOptions(io.Discard, &WriterOptions{StrictValues: true, PlainErrors: true})
+
+	testData := []struct {
+		Name     string
+		Do       func() error
+		Expected error
+	}{
+		{
+			Name: "read too many bits",
+			Do: func() error {
+				_, err := r.ReadNBitsAsUint8(9)
+				return err
+			},
+			Expected: errTooManyBitsForUint8,
+		},
+		{
+			Name: "read beyond the end of the stream",
+			Do: func() error {
+				r.ResetBytes(data)
+				_, err := r.ReadNBitsAsUint32BE(17)
+				return err
+			},
+			Expected: ErrUnexpectedEOF,
+		},
+		{
+			Name: "write too many bits",
+			Do: func() error {
+				return w.WriteNBitsOfUint16BE(17, 0)
+			},
+			Expected: errTooManyBitsForUint16,
+		},
+		{
+			Name: "write a value out of range",
+			Do: func() error {
+				return w.WriteNBitsOfUint8(3, 0x08)
+			},
+			Expected: ErrValueOutOfRange,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			err := data.Do()
+			if err != data.Expected {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+			allocs := testing.AllocsPerRun(100, func() {
+				data.Do()
+			})
+			if allocs != 0 {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, allocs)
+			}
+		})
+	}
+}
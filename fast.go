@@ -0,0 +1,79 @@
+package bitstream
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Refill makes sure that the buffer holds at least `minBits` bits which have not been read yet,
+// so that they can be read by ReadBits64 without any checks. The buffer is grown if it is smaller than `minBits` bits.
+//
+// It returns io.EOF if the stream ends before `minBits` bits are buffered; BufferedBits tells how many bits are still
+// available to ReadBits64 in that case. An error from the source is returned as it is, and is reported again by the
+// next read.
+//
+// A typical decoder loop refills once for several fields:
+//
+//	for {
+//		if err := r.Refill(56); err != nil {
+//			// fall back to the checked read methods near the end of the stream
+//		}
+//		sym := table[r.ReadBits64(8)]
+//		...
+//	}
+func (r *Reader) Refill(minBits uint) error {
+	pos := r.BitPosition()
+	return r.wrapError("Refill", pos, r.refill(minBits))
+}
+
+func (r *Reader) refill(minBits uint) error {
+	if r.BufferedBits() >= minBits {
+		return nil
+	}
+
+	skip := uint(7 - r.currBitIndex)
+	err := r.fillBufAhead((skip + minBits + 7) / 8)
+	if err != nil {
+		return err
+	}
+	if r.BufferedBits() >= minBits {
+		return nil
+	}
+
+	switch {
+	case r.srcErr != nil:
+		return r.srcErr
+	case r.closed:
+		return ErrClosed
+	}
+	return io.EOF
+}
+
+// BufferedBits returns the number of bits in the buffer which have not been read yet,
+// i.e. the number of bits which can be read by ReadBits64 without calling Refill.
+func (r *Reader) BufferedBits() uint {
+	if r.isBufEmpty() {
+		return 0
+	}
+	return (r.bufLen-r.currByteIndex)*8 - uint(7-r.currBitIndex)
+}
+
+// ReadBits64 reads `nBits` (<= 64) bits from the buffer and returns them LSB aligned, without returning an error.
+// It is an unchecked fast path for the inner loops of codecs: the caller must make sure that the buffer has `nBits` bits
+// by Refill or BufferedBits beforehand. Otherwise, or if `nBits` > 64, the result is undefined.
+// The bits read by ReadBits64 are not reported to the trace hook.
+func (r *Reader) ReadBits64(nBits uint) uint64 {
+	skip := uint(7 - r.currBitIndex)
+	end := skip + nBits
+	if end <= 64 && r.currByteIndex+8 <= uint(len(r.buf)) {
+		v := binary.BigEndian.Uint64(r.buf[r.currByteIndex:]) << skip >> (64 - nBits)
+		r.currByteIndex += end / 8
+		r.consumedBytes += end / 8
+		r.currBitIndex = 7 - uint8(end%8)
+		return v
+	}
+
+	// near the end of the buffer, or the bits straddle the 9th byte
+	v, _, _ := r.readBits(uint8(nBits))
+	return v
+}
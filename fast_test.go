@@ -0,0 +1,93 @@
+package bitstream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadBits64(t *testing.T) {
+	src := []byte{0xaa, 0xf0, 0xcc, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x55}
+	widths := []uint{3, 15, 0, 7, 64, 1, 6}
+
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 3}, {BufferSize: 2, Prefetch: true}} {
+		expected := NewReader(bytes.NewReader(src), nil)
+		r := NewReader(bytes.NewReader(src), opt)
+		for _, n := range widths {
+			err := r.Refill(n)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if r.BufferedBits() < n {
+				t.Fatalf("\nExpected: >= %+v\nActual:   %+v\n", n, r.BufferedBits())
+			}
+			pos := r.BitPosition()
+			v := r.ReadBits64(n)
+			e, err := expected.ReadNBitsAsUint64BE(uint8(n))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if e != v {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", e, v)
+			}
+			if r.BitPosition() != pos+uint64(n) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", pos+uint64(n), r.BitPosition())
+			}
+		}
+		r.Close()
+	}
+}
+
+func TestRefillEOF(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0xaa, 0xf0}), &ReaderOptions{BufferSize: 1})
+	_, err := r.ReadNBitsAsUint8(3)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	err = r.Refill(16)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+	if r.BufferedBits() != 13 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 13, r.BufferedBits())
+	}
+
+	// the bits buffered are still readable
+	v := r.ReadBits64(13)
+	if v != 0x0af0 {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x0af0, v)
+	}
+	if r.BufferedBits() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, r.BufferedBits())
+	}
+}
+
+func TestRefillBytes(t *testing.T) {
+	r := NewReaderBytes([]byte{0x12, 0x34}, nil)
+	if r.BufferedBits() != 16 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 16, r.BufferedBits())
+	}
+	err := r.Refill(17)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+	v := r.ReadBits64(12)
+	if v != 0x123 {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x123, v)
+	}
+}
+
+func BenchmarkReadBits64(b *testing.B) {
+	var v uint64
+	r := NewReader(rand.Reader, nil)
+	for n := 0; n < b.N; n++ {
+		if r.BufferedBits() < 11 {
+			r.Refill(56)
+		}
+		v += r.ReadBits64(11)
+	}
+	toEliminateCompilerOptimizationUint64 = v
+}
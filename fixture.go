@@ -0,0 +1,142 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Fixture is a recorded sequence of the fields read from a bit stream by a decoder, and the error it returned.
+// Replay verifies that the decoder still reads the same fields with the same values, e.g. to pin the behavior of
+// a codec across upgrades of this package or refactors of the codec. It is serialized by WriteFixtureJSON.
+type Fixture struct {
+	Data   []byte         `json:"data"` // the bit stream
+	Fields []FixtureField `json:"fields"`
+	Err    string         `json:"error,omitempty"` // the error returned by the decoder, if any
+}
+
+// FixtureField is a field of a Fixture, as reported to the trace hook.
+type FixtureField struct {
+	Name      string `json:"name"`
+	BitOffset uint64 `json:"bitOffset"`
+	NBits     uint   `json:"nBits"`
+
+	// Value is the value in JSON, converted in the same way as ReportNode.Value.
+	Value json.RawMessage `json:"value"`
+}
+
+// RecordFixture runs `decode` on a Reader of `data` and records the fields it reads, with their names given to
+// ReadNamed or ReadNBitsNamed. The TraceHook of `opt` is replaced by the recorder. The error returned by `decode`
+// is recorded as well, so RecordFixture returns an error only if a value cannot be encoded in JSON.
+func RecordFixture(data []byte, opt *ReaderOptions, decode func(r *Reader) error) (*Fixture, error) {
+	f := &Fixture{Data: data, Fields: []FixtureField{}}
+	var encErr error
+	err := runFixture(data, opt, decode, func(name string, bitOffset uint64, nBits uint, value any) {
+		field, err := newFixtureField(name, bitOffset, nBits, value)
+		if err != nil {
+			encErr = err
+			return
+		}
+		f.Fields = append(f.Fields, field)
+	})
+	if encErr != nil {
+		return nil, encErr
+	}
+	if err != nil {
+		f.Err = err.Error()
+	}
+	return f, nil
+}
+
+// Replay runs `decode` on a Reader of the data of `f` and verifies that it reads the same fields as recorded,
+// and returns the same error. It returns ErrFixtureMismatch wrapped with the first difference if not.
+// The TraceHook of `opt` is replaced by the verifier.
+func (f *Fixture) Replay(opt *ReaderOptions, decode func(r *Reader) error) error {
+	var mismatch error
+	i := 0
+	err := runFixture(f.Data, opt, decode, func(name string, bitOffset uint64, nBits uint, value any) {
+		if mismatch != nil {
+			return
+		}
+		if i >= len(f.Fields) {
+			mismatch = fmt.Errorf("%w: extra field %d %q at bit %d", ErrFixtureMismatch, i, name, bitOffset)
+			return
+		}
+		actual, err := newFixtureField(name, bitOffset, nBits, value)
+		if err != nil {
+			mismatch = err
+			return
+		}
+		if !actual.equal(f.Fields[i]) {
+			mismatch = fmt.Errorf("%w: field %d: expected %s, actual %s", ErrFixtureMismatch, i, f.Fields[i], actual)
+		}
+		i++
+	})
+	if mismatch != nil {
+		return mismatch
+	}
+	if i < len(f.Fields) {
+		return fmt.Errorf("%w: missing field %d %s", ErrFixtureMismatch, i, f.Fields[i])
+	}
+
+	actualErr := ""
+	if err != nil {
+		actualErr = err.Error()
+	}
+	if actualErr != f.Err {
+		return fmt.Errorf("%w: expected error %q, actual %q", ErrFixtureMismatch, f.Err, actualErr)
+	}
+	return nil
+}
+
+func runFixture(data []byte, opt *ReaderOptions, decode func(r *Reader) error, hook TraceHook) error {
+	o := ReaderOptions{}
+	if opt != nil {
+		o = *opt
+	}
+	o.TraceHook = hook
+	return decode(NewReaderBytes(data, &o))
+}
+
+func newFixtureField(name string, bitOffset uint64, nBits uint, value any) (FixtureField, error) {
+	v, err := json.Marshal(reportValue(value))
+	if err != nil {
+		return FixtureField{}, fmt.Errorf("field %q at bit %d: %w", name, bitOffset, err)
+	}
+	return FixtureField{Name: name, BitOffset: bitOffset, NBits: nBits, Value: v}, nil
+}
+
+// equal reports whether the fields are the same. The values are compared in their compact JSON forms,
+// as the ones read by ReadFixtureJSON may be indented.
+func (ff FixtureField) equal(other FixtureField) bool {
+	if ff.Name != other.Name || ff.BitOffset != other.BitOffset || ff.NBits != other.NBits {
+		return false
+	}
+	a, b := &bytes.Buffer{}, &bytes.Buffer{}
+	if json.Compact(a, ff.Value) != nil || json.Compact(b, other.Value) != nil {
+		return false
+	}
+	return bytes.Equal(a.Bytes(), b.Bytes())
+}
+
+func (ff FixtureField) String() string {
+	return fmt.Sprintf("%q at bit %d (%d bits) = %s", ff.Name, ff.BitOffset, ff.NBits, ff.Value)
+}
+
+// WriteFixtureJSON writes `f` to `w` as indented JSON, e.g. to a file under testdata.
+func WriteFixtureJSON(w io.Writer, f *Fixture) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// ReadFixtureJSON reads a fixture written by WriteFixtureJSON.
+func ReadFixtureJSON(r io.Reader) (*Fixture, error) {
+	var f Fixture
+	err := json.NewDecoder(r).Decode(&f)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
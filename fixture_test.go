@@ -0,0 +1,139 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func decodeFixtureHeader(r *Reader) error {
+	_, err := r.ReadNamed("version", 4)
+	if err != nil {
+		return err
+	}
+	_, err = r.ReadNamed("ihl", 4)
+	if err != nil {
+		return err
+	}
+	_, err = r.ReadNBitsNamed("payload", 16, nil)
+	if err != nil {
+		return err
+	}
+	_, err = r.ReadFlags([]string{"a", "", "b"})
+	return err
+}
+
+func TestFixture(t *testing.T) {
+	data := []byte{0x45, 0x12, 0x34, 0xa0}
+	f, err := RecordFixture(data, nil, decodeFixtureHeader)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if len(f.Fields) != 4 || f.Err != "" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "4 fields", f)
+	}
+	expected := FixtureField{Name: "payload", BitOffset: 8, NBits: 16, Value: []byte(`"1234"`)}
+	if !expected.equal(f.Fields[2]) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, f.Fields[2])
+	}
+
+	buf := &bytes.Buffer{}
+	err = WriteFixtureJSON(buf, f)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	f, err = ReadFixtureJSON(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	err = f.Replay(nil, decodeFixtureHeader)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	// the options other than the trace hook do not matter as long as the fields are the same
+	err = f.Replay(&ReaderOptions{BufferSize: 1}, decodeFixtureHeader)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+}
+
+func TestFixtureMismatch(t *testing.T) {
+	data := []byte{0x45, 0x12, 0x34, 0xa0}
+	f, err := RecordFixture(data, nil, decodeFixtureHeader)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	testData := []struct {
+		Name     string
+		Decode   func(r *Reader) error
+		Expected string
+	}{
+		{
+			Name: "width",
+			Decode: func(r *Reader) error {
+				_, err := r.ReadNamed("version", 8)
+				return err
+			},
+			Expected: `field 0: expected "version" at bit 0 (4 bits) = 4, actual "version" at bit 0 (8 bits) = 69`,
+		},
+		{
+			Name: "missing",
+			Decode: func(r *Reader) error {
+				_, err := r.ReadNamed("version", 4)
+				return err
+			},
+			Expected: `missing field 1 "ihl" at bit 4 (4 bits) = 5`,
+		},
+		{
+			Name: "extra",
+			Decode: func(r *Reader) error {
+				err := decodeFixtureHeader(r)
+				if err != nil {
+					return err
+				}
+				_, err = r.ReadBit()
+				return err
+			},
+			Expected: `extra field 4 "" at bit 27`,
+		},
+		{
+			Name: "error",
+			Decode: func(r *Reader) error {
+				err := decodeFixtureHeader(r)
+				if err != nil {
+					return err
+				}
+				return errors.New("invalid header")
+			},
+			Expected: `expected error "", actual "invalid header"`,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			err := f.Replay(nil, data.Decode)
+			if !errors.Is(err, ErrFixtureMismatch) || !strings.HasSuffix(err.Error(), data.Expected) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+		})
+	}
+}
+
+func TestFixtureDecodeError(t *testing.T) {
+	// the stream ends in the middle of the payload
+	f, err := RecordFixture([]byte{0x45, 0x12}, nil, decodeFixtureHeader)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if len(f.Fields) != 2 || !strings.Contains(f.Err, "unexpected EOF") {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "2 fields and unexpected EOF", f)
+	}
+	err = f.Replay(nil, decodeFixtureHeader)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+}
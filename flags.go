@@ -0,0 +1,142 @@
+package bitstream
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ReadFlags reads len(`names`) bits as flags and returns them mapped to `names`, e.g. for a register or a flag word,
+// where the first bit read is names[0]. A bit whose name is empty is reserved; it is consumed but not in the map.
+func (r *Reader) ReadFlags(names []string) (map[string]bool, error) {
+	pos := r.BitPosition()
+	flags := make(map[string]bool, len(names))
+	err := r.readFlags(len(names), func(i int, b bool) {
+		if names[i] != "" {
+			flags[names[i]] = b
+		}
+	})
+	if err != nil {
+		return nil, r.wrapError("ReadFlags", pos, err)
+	}
+	r.trace("", pos, uint(len(names)), flags)
+	return flags, nil
+}
+
+// ReadFlagsInto reads the flags into the bool fields of the struct pointed to by `dst`, one bit for each field
+// in the order of declaration. A blank field (`_ bool`) is a reserved bit, which is consumed but not stored.
+// It returns ErrInvalidFlags if `dst` is not a pointer to a struct which has only bool fields.
+func (r *Reader) ReadFlagsInto(dst any) error {
+	pos := r.BitPosition()
+	v, err := flagsStruct(dst)
+	if err == nil {
+		err = r.readFlags(v.NumField(), func(i int, b bool) {
+			if v.Type().Field(i).Name != "_" {
+				v.Field(i).SetBool(b)
+			}
+		})
+	}
+	if err != nil {
+		return r.wrapError("ReadFlagsInto", pos, err)
+	}
+	r.trace("", pos, uint(v.NumField()), dst)
+	return nil
+}
+
+// readFlags reads `n` bits in chunks of 64 bits and calls `set` for each of them.
+func (r *Reader) readFlags(n int, set func(i int, b bool)) error {
+	for i := 0; i < n; {
+		nBits := uint8(min(n-i, 64))
+		word, err := r.readUint(nBits, 64)
+		if err != nil {
+			if i > 0 {
+				return unexpectedEOF(err)
+			}
+			return err
+		}
+		for j := nBits; j > 0; j-- {
+			set(i, word>>(j-1)&0x01 == 1)
+			i++
+		}
+	}
+	return nil
+}
+
+// WriteFlags writes the flags in `flags` as len(`names`) bits, in the layout read by ReadFlags.
+// A name which is not in `flags` is written as '0', and so is a reserved bit whose name is empty.
+func (w *Writer) WriteFlags(names []string, flags map[string]bool) error {
+	pos := w.bitPosition()
+	err := w.writeFlags(len(names), func(i int) bool {
+		return names[i] != "" && flags[names[i]]
+	})
+	if err != nil {
+		return w.wrapError("WriteFlags", pos, err)
+	}
+	w.trace("", pos, uint(len(names)), flags)
+	return nil
+}
+
+// WriteFlagsFrom writes the bool fields of the struct `src` (or the struct pointed to by it), one bit for each field
+// in the order of declaration, in the layout read by ReadFlagsInto. A blank field is a reserved bit written as '0'.
+// It returns ErrInvalidFlags if `src` is not a struct which has only bool fields.
+func (w *Writer) WriteFlagsFrom(src any) error {
+	pos := w.bitPosition()
+	v := reflect.ValueOf(src)
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	err := checkFlagsStruct(v)
+	if err == nil {
+		err = w.writeFlags(v.NumField(), func(i int) bool {
+			return v.Type().Field(i).Name != "_" && v.Field(i).Bool()
+		})
+	}
+	if err != nil {
+		return w.wrapError("WriteFlagsFrom", pos, err)
+	}
+	w.trace("", pos, uint(v.NumField()), src)
+	return nil
+}
+
+// writeFlags writes `n` bits returned by `get` in chunks of 64 bits.
+func (w *Writer) writeFlags(n int, get func(i int) bool) error {
+	for i := 0; i < n; {
+		nBits := uint8(min(n-i, 64))
+		word := uint64(0)
+		for j := uint8(0); j < nBits; j++ {
+			word <<= 1
+			if get(i) {
+				word |= 1
+			}
+			i++
+		}
+		err := w.writeUint(nBits, 64, word)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagsStruct returns the struct pointed to by `dst`.
+func flagsStruct(dst any) (reflect.Value, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return reflect.Value{}, fmt.Errorf("%w: %T is not a pointer to a struct", ErrInvalidFlags, dst)
+	}
+	v = v.Elem()
+	return v, checkFlagsStruct(v)
+}
+
+func checkFlagsStruct(v reflect.Value) error {
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %s is not a struct", ErrInvalidFlags, v.Kind())
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() != reflect.Bool || (!f.IsExported() && f.Name != "_") {
+			return fmt.Errorf("%w: field %s of %s", ErrInvalidFlags, f.Name, t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// the flags of a TCP header, from CWR to FIN
+var tcpFlagNames = []string{"CWR", "ECE", "URG", "ACK", "PSH", "RST", "SYN", "FIN"}
+
+type tcpFlags struct {
+	CWR, ECE, URG, ACK, PSH, RST, SYN, FIN bool
+}
+
+func TestFlags(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteNibble(0x5)
+	err := w.WriteFlags(tcpFlagNames, map[string]bool{"SYN": true, "ACK": true, "unknown": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = w.WriteFlagsFrom(tcpFlags{ECE: true, FIN: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Close()
+
+	expected := []byte{0x51, 0x24, 0x10} // 0101 0001 0010 0100 0001
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, buf.Bytes())
+	}
+
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 2, Prefetch: true}} {
+		r := NewReader(bytes.NewReader(expected), opt)
+		r.ReadNibble()
+		flags, err := r.ReadFlags(tcpFlagNames)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		expectedFlags := map[string]bool{"CWR": false, "ECE": false, "URG": false, "ACK": true, "PSH": false, "RST": false, "SYN": true, "FIN": false}
+		if !reflect.DeepEqual(flags, expectedFlags) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expectedFlags, flags)
+		}
+
+		var f tcpFlags
+		err = r.ReadFlagsInto(&f)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if f != (tcpFlags{ECE: true, FIN: true}) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", tcpFlags{ECE: true, FIN: true}, f)
+		}
+	}
+}
+
+func TestFlagsReserved(t *testing.T) {
+	// IPv4 flags: reserved, DF and MF
+	type ipv4Flags struct {
+		_             bool
+		DontFragment  bool
+		MoreFragments bool
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteFlagsFrom(&ipv4Flags{DontFragment: true})
+	w.WriteFlags([]string{"", "DF", "MF"}, map[string]bool{"": true, "MF": true})
+	w.Close()
+
+	expected := []byte{0x44} // 010 001
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, buf.Bytes())
+	}
+
+	r := NewReaderBytes([]byte{0xe0}, nil)
+	var f ipv4Flags
+	err := r.ReadFlagsInto(&f)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !f.DontFragment || !f.MoreFragments {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ipv4Flags{DontFragment: true, MoreFragments: true}, f)
+	}
+	if r.BitPosition() != 3 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 3, r.BitPosition())
+	}
+
+	r = NewReaderBytes([]byte{0xe0}, nil)
+	flags, err := r.ReadFlags([]string{"", "DF", "MF"})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if len(flags) != 2 || !flags["DF"] || !flags["MF"] {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", map[string]bool{"DF": true, "MF": true}, flags)
+	}
+}
+
+func TestFlagsWide(t *testing.T) {
+	names := make([]string, 100)
+	flags := map[string]bool{}
+	for i := range names {
+		names[i] = string(rune('A'+i%26)) + string(rune('a'+i/26))
+		flags[names[i]] = i%3 == 0
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	err := w.WriteFlags(names, flags)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if w.WrittenBits() != 100 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 100, w.WrittenBits())
+	}
+	w.Close()
+
+	r := NewReader(&buf, nil)
+	actual, err := r.ReadFlags(names)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual(actual, flags) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", flags, actual)
+	}
+
+	r = NewReaderBytes(make([]byte, 12), nil)
+	_, err = r.ReadFlags(names)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestFlagsInvalid(t *testing.T) {
+	type mixed struct {
+		A bool
+		B int
+	}
+	type unexported struct {
+		a bool
+	}
+	var n int
+	r := NewReaderBytes([]byte{0xff}, nil)
+	w := NewWriter(&bytes.Buffer{})
+	for _, v := range []any{nil, n, &n, tcpFlags{}, &mixed{}, &unexported{}} {
+		err := r.ReadFlagsInto(v)
+		if !errors.Is(err, ErrInvalidFlags) {
+			t.Fatalf("%T:\nExpected: %+v\nActual:   %+v\n", v, ErrInvalidFlags, err)
+		}
+	}
+	for _, v := range []any{nil, n, &n, mixed{}, &unexported{}} {
+		err := w.WriteFlagsFrom(v)
+		if !errors.Is(err, ErrInvalidFlags) {
+			t.Fatalf("%T:\nExpected: %+v\nActual:   %+v\n", v, ErrInvalidFlags, err)
+		}
+	}
+	if r.BitPosition() != 0 || w.WrittenBits() != 0 {
+		t.Fatalf("\nExpected: 0, 0\nActual:   %+v, %+v\n", r.BitPosition(), w.WrittenBits())
+	}
+}
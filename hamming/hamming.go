@@ -0,0 +1,245 @@
+// Package hamming implements Hamming codes and extended Hamming (SECDED) codes on top of bitstream.Writer / bitstream.Reader.
+//
+// A Hamming code with m parity bits protects 2^m-m-1 data bits with a codeword of 2^m-1 bits and corrects a single bit error.
+// The extended code adds an overall parity bit, which makes it possible to detect (but not correct) double bit errors.
+//
+// The bits of a codeword are written in the order of their positions 1, 2, ..., 2^m-1, where the parity bits are at the
+// positions of powers of 2 and the data bits fill the other positions MSB first. The overall parity bit of an extended
+// code follows them. For example, the data 1011 is coded as 0110011 with Hamming(7,4), and as 01100110 with SECDED(8,4).
+package hamming
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/bits"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+var (
+	// ErrInvalidParityBits is returned when the number of parity bits is out of the supported range.
+	ErrInvalidParityBits = errors.New("hamming: number of parity bits must be between 2 and 6")
+
+	// ErrUncorrectable is returned when a double bit error is detected in a codeword of an extended code.
+	ErrUncorrectable = errors.New("hamming: uncorrectable error detected")
+)
+
+// Code is a Hamming code or an extended Hamming (SECDED) code.
+type Code struct {
+	m        uint8 // number of parity bits, excluding the overall parity bit
+	extended bool
+}
+
+// Well-known codes.
+var (
+	Hamming74   = Code{m: 3}
+	Hamming1511 = Code{m: 4}
+	Hamming3126 = Code{m: 5}
+	Hamming6357 = Code{m: 6}
+	SECDED84    = Code{m: 3, extended: true}
+	SECDED1611  = Code{m: 4, extended: true}
+	SECDED3226  = Code{m: 5, extended: true}
+	SECDED6457  = Code{m: 6, extended: true}
+)
+
+// New returns a Hamming code with `parityBits` (2 - 6) parity bits.
+// If `extended` is true, the code has an overall parity bit in addition.
+func New(parityBits uint8, extended bool) (Code, error) {
+	if parityBits < 2 || parityBits > 6 {
+		return Code{}, ErrInvalidParityBits
+	}
+	return Code{m: parityBits, extended: extended}, nil
+}
+
+// N returns the number of bits of a codeword.
+func (c Code) N() uint8 {
+	n := uint8(1)<<c.m - 1
+	if c.extended {
+		n++
+	}
+	return n
+}
+
+// K returns the number of data bits in a codeword.
+func (c Code) K() uint8 {
+	return uint8(1)<<c.m - 1 - c.m
+}
+
+// Extended returns true if the code has an overall parity bit.
+func (c Code) Extended() bool {
+	return c.extended
+}
+
+// Status is the result of decoding a codeword.
+type Status int
+
+const (
+	// NoError means that the codeword has no error.
+	NoError Status = iota
+
+	// Corrected means that a single bit error has been corrected.
+	Corrected
+
+	// Detected means that a double bit error has been detected, which cannot be corrected.
+	Detected
+)
+
+func (s Status) String() string {
+	switch s {
+	case NoError:
+		return "no error"
+	case Corrected:
+		return "corrected"
+	case Detected:
+		return "detected"
+	}
+	return "unknown"
+}
+
+// Result reports the errors found in a codeword.
+type Result struct {
+	Status Status
+	Bit    int // offset of the corrected bit from the beginning of the codeword, or -1 if no bit is corrected
+}
+
+// Encode returns the codeword (LSB aligned, N bits) of the low K bits of `data`.
+func (c Code) Encode(data uint64) uint64 {
+	n := uint(1)<<c.m - 1
+
+	// place the data bits and compute the syndrome of them, which gives the parity bits
+	cw := uint64(0)
+	syndrome := uint(0)
+	d := int(c.K()) - 1
+	for p := uint(1); p <= n; p++ {
+		if p&(p-1) == 0 {
+			continue
+		}
+		if data>>d&1 == 1 {
+			cw |= 1 << (n - p)
+			syndrome ^= p
+		}
+		d--
+	}
+	for i := uint8(0); i < c.m; i++ {
+		if syndrome>>i&1 == 1 {
+			cw |= 1 << (n - 1<<i)
+		}
+	}
+
+	if c.extended {
+		cw = cw<<1 | uint64(bits.OnesCount64(cw)&1)
+	}
+	return cw
+}
+
+// Decode corrects a single bit error in `cw` (LSB aligned, N bits) and returns the data bits.
+// For an extended code, it returns ErrUncorrectable together with the uncorrected data bits when a double bit error is detected.
+func (c Code) Decode(cw uint64) (uint64, Result, error) {
+	n := uint(1)<<c.m - 1
+	res := Result{Status: NoError, Bit: -1}
+
+	ham := cw
+	parityOK := true
+	if c.extended {
+		ham = cw >> 1
+		parityOK = bits.OnesCount64(cw&(1<<(n+1)-1))&1 == 0
+	}
+
+	syndrome := uint(0)
+	for p := uint(1); p <= n; p++ {
+		if ham>>(n-p)&1 == 1 {
+			syndrome ^= p
+		}
+	}
+
+	var err error
+	switch {
+	case syndrome == 0 && parityOK:
+	case syndrome == 0:
+		// the overall parity bit itself is flipped
+		res = Result{Status: Corrected, Bit: int(n)}
+	case !c.extended || !parityOK:
+		ham ^= 1 << (n - syndrome)
+		res = Result{Status: Corrected, Bit: int(syndrome) - 1}
+	default:
+		res.Status = Detected
+		err = ErrUncorrectable
+	}
+	return c.extract(ham), res, err
+}
+
+// extract returns the data bits in the Hamming codeword `ham` (without the overall parity bit).
+func (c Code) extract(ham uint64) uint64 {
+	n := uint(1)<<c.m - 1
+	data := uint64(0)
+	for p := uint(1); p <= n; p++ {
+		if p&(p-1) == 0 {
+			continue
+		}
+		data = data<<1 | ham>>(n-p)&1
+	}
+	return data
+}
+
+// Write writes the codeword of the low K bits of `data` to `w`.
+func (c Code) Write(w *bitstream.Writer, data uint64) error {
+	return w.WriteNamed("codeword", c.N(), c.Encode(data))
+}
+
+// Read reads a codeword from `r` and returns the corrected data bits. See Decode for the details.
+func (c Code) Read(r *bitstream.Reader) (uint64, Result, error) {
+	cw, err := r.ReadNBitsAsUint64BE(c.N())
+	if err != nil {
+		return 0, Result{Bit: -1}, err
+	}
+	return c.Decode(cw)
+}
+
+// WriteBits writes `nBits` bits of `data` (left aligned) to `w`, split into codewords.
+// The last codeword is padded with '0' bits if `nBits` is not a multiple of K.
+func (c Code) WriteBits(w *bitstream.Writer, nBits uint, data []byte) error {
+	r := bitstream.NewReader(bytes.NewReader(data), nil)
+	k := c.K()
+	for nBits > 0 {
+		n := uint8(min(nBits, uint(k)))
+		v, err := r.ReadNBitsAsUint64BE(n)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return bitstream.ErrInsufficientData
+			}
+			return err
+		}
+		err = c.Write(w, v<<(k-n))
+		if err != nil {
+			return err
+		}
+		nBits -= uint(n)
+	}
+	return nil
+}
+
+// ReadBits reads the codewords written by WriteBits for `nBits` bits and returns the corrected bits (left aligned).
+// It also returns the results of all the codewords. It stops at the first uncorrectable codeword and returns ErrUncorrectable.
+func (c Code) ReadBits(r *bitstream.Reader, nBits uint) ([]byte, []Result, error) {
+	v := bitstream.NewBitVector(0)
+	var results []Result
+	k := c.K()
+	for nBits > 0 {
+		data, res, err := c.Read(r)
+		if err != nil {
+			if res.Status == Detected {
+				results = append(results, res)
+			}
+			if errors.Is(err, io.EOF) && len(results) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, results, err
+		}
+		results = append(results, res)
+		n := uint8(min(nBits, uint(k)))
+		v.AppendBits(n, data>>(k-n))
+		nBits -= uint(n)
+	}
+	return v.Bytes(), results, nil
+}
@@ -0,0 +1,125 @@
+package hamming
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func TestEncode(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Code     Code
+		Data     uint64
+		Expected uint64
+	}{
+		{Name: "pattern 1", Code: Hamming74, Data: 0xb, Expected: 0x33},     // 1011 -> 011 0011
+		{Name: "pattern 2", Code: SECDED84, Data: 0xb, Expected: 0x66},      // 1011 -> 0110 0110
+		{Name: "pattern 3", Code: Hamming74, Data: 0x0, Expected: 0x00},     // 0000 -> 000 0000
+		{Name: "pattern 4", Code: SECDED84, Data: 0xf, Expected: 0xff},      // 1111 -> 1111 1111
+		{Name: "pattern 5", Code: Hamming1511, Data: 0x1, Expected: 0x6881}, // 000 0000 0001 -> 110 1000 1000 0001
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			cw := data.Code.Encode(data.Data)
+			if data.Expected != cw {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, cw)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, code := range []Code{Hamming74, Hamming1511, Hamming3126, Hamming6357, SECDED84, SECDED1611, SECDED3226, SECDED6457} {
+		code := code // capture
+		t.Run(fmt.Sprintf("(%d,%d)", code.N(), code.K()), func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				data := rnd.Uint64() & (1<<code.K() - 1)
+				cw := code.Encode(data)
+				n := int(code.N())
+
+				actual, res, err := code.Decode(cw)
+				if err != nil || actual != data || res.Status != NoError || res.Bit != -1 {
+					t.Fatalf("\nExpected: %+v, %+v, %+v\nActual:   %+v, %+v, %+v\n", data, Result{Bit: -1}, nil, actual, res, err)
+				}
+
+				// a single bit error is corrected
+				b := rnd.Intn(n)
+				actual, res, err = code.Decode(cw ^ 1<<(n-1-b))
+				if err != nil || actual != data || res.Status != Corrected || res.Bit != b {
+					t.Fatalf("\nExpected: %+v, %+v, %+v\nActual:   %+v, %+v, %+v\n", data, Result{Status: Corrected, Bit: b}, nil, actual, res, err)
+				}
+
+				// a double bit error is detected with the extended code
+				if !code.Extended() {
+					continue
+				}
+				b2 := (b + 1 + rnd.Intn(n-1)) % n
+				_, res, err = code.Decode(cw ^ 1<<(n-1-b) ^ 1<<(n-1-b2))
+				if !errors.Is(err, ErrUncorrectable) || res.Status != Detected {
+					t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", Detected, ErrUncorrectable, res.Status, err)
+				}
+			}
+		})
+	}
+}
+
+func TestReadWriteBits(t *testing.T) {
+	src := []byte("hamming")
+	nBits := uint(len(src)*8 - 3)
+
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	err := SECDED84.WriteBits(w, nBits, src)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Finalize()
+
+	// 53 bits -> 14 codewords of 8 bits. flip a bit of the 3rd codeword
+	coded := buf.Bytes()
+	if len(coded) != 14 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 14, len(coded))
+	}
+	coded[2] ^= 0x10
+
+	actual, results, err := SECDED84.ReadBits(bitstream.NewReader(bytes.NewReader(coded), nil), nBits)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expected := append([]byte{}, src...)
+	expected[len(expected)-1] &= 0xf8
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, actual)
+	}
+	if len(results) != 14 || results[2] != (Result{Status: Corrected, Bit: 3}) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", Result{Status: Corrected, Bit: 3}, results[2])
+	}
+
+	// restore the bit and flip 2 other bits of the 3rd codeword
+	coded[2] ^= 0x10
+	coded[2] ^= 0x21
+	_, results, err = SECDED84.ReadBits(bitstream.NewReader(bytes.NewReader(coded), nil), nBits)
+	if !errors.Is(err, ErrUncorrectable) || len(results) != 3 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", ErrUncorrectable, 3, err, len(results))
+	}
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(3, true)
+	if err != nil || c != SECDED84 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v, %+v\n", SECDED84, c, err)
+	}
+	_, err = New(7, false)
+	if !errors.Is(err, ErrInvalidParityBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidParityBits, err)
+	}
+}
@@ -0,0 +1,340 @@
+// Package huffman implements canonical Huffman codes which are written to and read from a bit stream.
+//
+// A Code is built from the code length of each symbol, which can be computed from the symbol frequencies with BuildLengths.
+// The codes are assigned canonically as in DEFLATE: shorter codes come first, and codes of the same length are
+// assigned in the order of the symbols. So only the code lengths are needed to reconstruct the code.
+//
+// The code table written by WriteTable is:
+//
+//	n        16 bits   number of symbols
+//	lengths  n*6 bits  code length of each symbol (0 for the symbols which do not appear)
+//
+// Decode looks up the first bits of the code in a table, and falls back to the canonical search only for long codes.
+package huffman
+
+import (
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+const (
+	// MaxCodeLen is the maximum length of a code.
+	MaxCodeLen = 32
+
+	// MaxSymbols is the maximum number of symbols in a code.
+	MaxSymbols = 1<<16 - 1
+
+	maxTableBits = 10 // codes up to this length are decoded with a single table lookup
+)
+
+var (
+	// ErrInvalidLengths is returned when the code lengths do not form a prefix code, i.e. the code is oversubscribed,
+	// or a length is larger than MaxCodeLen.
+	ErrInvalidLengths = errors.New("huffman: invalid code lengths")
+
+	// ErrTooManySymbols is returned when there are more than MaxSymbols symbols,
+	// or the symbols cannot be coded within the maximum code length.
+	ErrTooManySymbols = errors.New("huffman: too many symbols")
+
+	// ErrInvalidSymbol is returned when a symbol to be encoded is out of range or has no code.
+	ErrInvalidSymbol = errors.New("huffman: invalid symbol")
+
+	// ErrInvalidCode is returned when the bits read do not match any code.
+	ErrInvalidCode = errors.New("huffman: invalid code")
+)
+
+// BuildLengths computes the code length of each symbol from its frequency, where the length of each code is `maxLen` bits at most.
+// Symbols with zero frequency get no code (length 0). If only one symbol appears, its code is 1 bit long.
+// The lengths are optimal unless they are limited by `maxLen`.
+func BuildLengths(freqs []uint64, maxLen uint8) ([]uint8, error) {
+	if len(freqs) > MaxSymbols {
+		return nil, ErrTooManySymbols
+	}
+	if maxLen == 0 || maxLen > MaxCodeLen {
+		return nil, ErrInvalidLengths
+	}
+
+	// symbols which appear, in ascending order of frequency
+	syms := make([]int, 0, len(freqs))
+	for s, f := range freqs {
+		if f > 0 {
+			syms = append(syms, s)
+		}
+	}
+	sort.SliceStable(syms, func(i, j int) bool { return freqs[syms[i]] < freqs[syms[j]] })
+
+	lengths := make([]uint8, len(freqs))
+	n := len(syms)
+	switch {
+	case n == 0:
+		return lengths, nil
+	case n == 1:
+		lengths[syms[0]] = 1
+		return lengths, nil
+	case maxLen < 16 && n > 1<<maxLen:
+		return nil, ErrTooManySymbols
+	}
+
+	// build the tree with 2 queues: the leaves sorted by frequency and the internal nodes, which are created in ascending order of frequency.
+	// nodes[:n] are the leaves and nodes[n:] are the internal nodes.
+	freq := make([]uint64, 2*n-1)
+	parent := make([]int, 2*n-1)
+	for i, s := range syms {
+		freq[i] = freqs[s]
+	}
+	leaf, internal := 0, n
+	pop := func(next int) int {
+		if leaf < n && (internal >= next || freq[leaf] <= freq[internal]) {
+			leaf++
+			return leaf - 1
+		}
+		internal++
+		return internal - 1
+	}
+	for next := n; next < 2*n-1; next++ {
+		a := pop(next)
+		b := pop(next)
+		freq[next] = freq[a] + freq[b]
+		parent[a] = next
+		parent[b] = next
+	}
+
+	// count the leaves at each depth. the parent of a node always comes after it.
+	depth := make([]int, 2*n-1)
+	count := make([]int, 2*n)
+	maxDepth := 0
+	for i := 2*n - 3; i >= 0; i-- {
+		depth[i] = depth[parent[i]] + 1
+		if i < n {
+			count[depth[i]]++
+			maxDepth = max(maxDepth, depth[i])
+		}
+	}
+
+	// limit the depth by moving the deepest pairs of leaves up (JPEG, Annex K.3)
+	for i := maxDepth; i > int(maxLen); i-- {
+		for count[i] > 0 {
+			j := i - 2
+			for count[j] == 0 {
+				j--
+			}
+			count[i] -= 2
+			count[i-1]++
+			count[j+1] += 2
+			count[j]--
+		}
+	}
+
+	// the least frequent symbols get the longest codes
+	k := 0
+	for l := min(maxDepth, int(maxLen)); l > 0; l-- {
+		for c := 0; c < count[l]; c++ {
+			lengths[syms[k]] = uint8(l)
+			k++
+		}
+	}
+	return lengths, nil
+}
+
+// entry is an entry of the lookup table. `len` == 0 means that the code is longer than the table bits.
+type entry struct {
+	sym uint16
+	len uint8
+}
+
+// Code is a canonical Huffman code.
+type Code struct {
+	lengths   []uint8
+	codes     []uint32               // code of each symbol
+	maxLen    uint8                  // length of the longest code
+	count     [MaxCodeLen + 1]uint32 // number of codes of each length
+	first     [MaxCodeLen + 1]uint32 // first code of each length
+	offset    [MaxCodeLen + 1]uint32 // index in `symbols` of the first code of each length
+	symbols   []uint16               // symbols in the order of their codes
+	tableBits uint8
+	table     []entry
+}
+
+// New creates a canonical Huffman code from the code length of each symbol.
+// A symbol with length 0 has no code. The code may be incomplete, in which case the unused codes are rejected by Decode.
+func New(lengths []uint8) (*Code, error) {
+	if len(lengths) > MaxSymbols {
+		return nil, ErrTooManySymbols
+	}
+
+	c := &Code{
+		lengths: append([]uint8{}, lengths...),
+		codes:   make([]uint32, len(lengths)),
+	}
+	for _, l := range lengths {
+		if l > MaxCodeLen {
+			return nil, ErrInvalidLengths
+		}
+		c.count[l]++
+		c.maxLen = max(c.maxLen, l)
+	}
+	c.count[0] = 0
+
+	// check that the code is not oversubscribed (Kraft's inequality)
+	left := uint64(1)
+	for l := 1; l <= MaxCodeLen; l++ {
+		left <<= 1
+		if uint64(c.count[l]) > left {
+			return nil, ErrInvalidLengths
+		}
+		left -= uint64(c.count[l])
+	}
+
+	code := uint32(0)
+	for l := 1; l <= MaxCodeLen; l++ {
+		code = (code + c.count[l-1]) << 1
+		c.first[l] = code
+		c.offset[l] = c.offset[l-1] + c.count[l-1]
+	}
+
+	c.symbols = make([]uint16, c.offset[MaxCodeLen]+c.count[MaxCodeLen])
+	next := c.first
+	pos := c.offset
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c.codes[s] = next[l]
+		next[l]++
+		c.symbols[pos[l]] = uint16(s)
+		pos[l]++
+	}
+
+	c.tableBits = min(c.maxLen, maxTableBits)
+	c.table = make([]entry, 1<<c.tableBits)
+	for s, l := range lengths {
+		if l == 0 || l > c.tableBits {
+			continue
+		}
+		start := c.codes[s] << (c.tableBits - l)
+		for i := uint32(0); i < 1<<(c.tableBits-l); i++ {
+			c.table[start+i] = entry{sym: uint16(s), len: l}
+		}
+	}
+	return c, nil
+}
+
+// FromFrequencies creates a canonical Huffman code from the frequency of each symbol. See BuildLengths for the details.
+func FromFrequencies(freqs []uint64, maxLen uint8) (*Code, error) {
+	lengths, err := BuildLengths(freqs, maxLen)
+	if err != nil {
+		return nil, err
+	}
+	return New(lengths)
+}
+
+// NumSymbols returns the number of symbols, including the ones which have no code.
+func (c *Code) NumSymbols() int {
+	return len(c.lengths)
+}
+
+// Lengths returns the code length of each symbol.
+func (c *Code) Lengths() []uint8 {
+	return append([]uint8{}, c.lengths...)
+}
+
+// Codeword returns the code of `sym` (LSB aligned) and its length. The length is 0 if `sym` has no code.
+func (c *Code) Codeword(sym int) (uint32, uint8) {
+	if sym < 0 || sym >= len(c.lengths) {
+		return 0, 0
+	}
+	return c.codes[sym], c.lengths[sym]
+}
+
+// WriteTable writes the code lengths to `w` so that the code can be reconstructed with ReadTable.
+func (c *Code) WriteTable(w *bitstream.Writer) error {
+	err := w.WriteNBitsOfUint16BE(16, uint16(len(c.lengths)))
+	if err != nil {
+		return err
+	}
+	for _, l := range c.lengths {
+		err = w.WriteNBitsOfUint8(6, l)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadTable reads the code lengths written by WriteTable from `r` and creates the code.
+func ReadTable(r *bitstream.Reader) (*Code, error) {
+	n, err := r.ReadUint16BE()
+	if err != nil {
+		return nil, err
+	}
+	lengths := make([]uint8, n)
+	for i := range lengths {
+		lengths[i], err = r.ReadNBitsAsUint8(6)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return New(lengths)
+}
+
+// Encode writes the code of `sym` to `w`. It returns ErrInvalidSymbol if `sym` has no code.
+func (c *Code) Encode(w *bitstream.Writer, sym int) error {
+	code, l := c.Codeword(sym)
+	if l == 0 {
+		return ErrInvalidSymbol
+	}
+	return w.WriteNBitsOfUint32BE(l, code)
+}
+
+// Decode reads a code from `r` and returns its symbol.
+// It returns io.EOF if the bit stream ends before a code, and io.ErrUnexpectedEOF if it ends in the middle of a code.
+// The bits of an invalid code are not consumed.
+func (c *Code) Decode(r *bitstream.Reader) (int, error) {
+	if c.maxLen == 0 {
+		return 0, ErrInvalidCode
+	}
+
+	v, avail, err := r.Peek(c.maxLen)
+	if err != nil {
+		return 0, err
+	}
+
+	sym, l := c.lookup(v)
+	if l == 0 {
+		if avail < c.maxLen {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, ErrInvalidCode
+	}
+	if l > avail {
+		return 0, io.ErrUnexpectedEOF
+	}
+	err = r.Skip(uint(l))
+	if err != nil {
+		return 0, err
+	}
+	return sym, nil
+}
+
+// lookup finds the code at the head of `v`, which has `maxLen` bits, and returns its symbol and length.
+// The length is 0 if no code matches.
+func (c *Code) lookup(v uint64) (int, uint8) {
+	e := c.table[v>>(c.maxLen-c.tableBits)]
+	if e.len > 0 {
+		return int(e.sym), e.len
+	}
+
+	for l := c.tableBits + 1; l <= c.maxLen; l++ {
+		code := uint32(v >> (c.maxLen - l))
+		if code >= c.first[l] && code-c.first[l] < c.count[l] {
+			return int(c.symbols[c.offset[l]+code-c.first[l]]), l
+		}
+	}
+	return 0, 0
+}
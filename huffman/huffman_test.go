@@ -0,0 +1,233 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func TestNew(t *testing.T) {
+	c, err := New([]uint8{2, 1, 3, 3, 0})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	testData := []struct {
+		Sym          int
+		ExpectedCode uint32
+		ExpectedLen  uint8
+	}{
+		{Sym: 0, ExpectedCode: 0x2, ExpectedLen: 2}, // 10
+		{Sym: 1, ExpectedCode: 0x0, ExpectedLen: 1}, // 0
+		{Sym: 2, ExpectedCode: 0x6, ExpectedLen: 3}, // 110
+		{Sym: 3, ExpectedCode: 0x7, ExpectedLen: 3}, // 111
+		{Sym: 4, ExpectedCode: 0x0, ExpectedLen: 0},
+		{Sym: 5, ExpectedCode: 0x0, ExpectedLen: 0},
+	}
+	for _, data := range testData {
+		code, l := c.Codeword(data.Sym)
+		if data.ExpectedCode != code || data.ExpectedLen != l {
+			t.Fatalf("\nsymbol %d\nExpected: %+v, %+v\nActual:   %+v, %+v\n", data.Sym, data.ExpectedCode, data.ExpectedLen, code, l)
+		}
+	}
+
+	_, err = New([]uint8{1, 1, 2})
+	if !errors.Is(err, ErrInvalidLengths) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidLengths, err)
+	}
+	_, err = New([]uint8{MaxCodeLen + 1})
+	if !errors.Is(err, ErrInvalidLengths) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidLengths, err)
+	}
+}
+
+func TestBuildLengths(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Freqs    []uint64
+		MaxLen   uint8
+		Expected []uint8
+	}{
+		{Name: "pattern 1", Freqs: []uint64{}, MaxLen: 15, Expected: []uint8{}},
+		{Name: "pattern 2", Freqs: []uint64{0, 5, 0}, MaxLen: 15, Expected: []uint8{0, 1, 0}},
+		{Name: "pattern 3", Freqs: []uint64{1, 1, 2, 4}, MaxLen: 15, Expected: []uint8{3, 3, 2, 1}},
+		{Name: "pattern 4", Freqs: []uint64{3, 3, 3, 3}, MaxLen: 15, Expected: []uint8{2, 2, 2, 2}},
+		// 1, 2, ..., 6, 7, 7 without the limit
+		{Name: "pattern 5", Freqs: []uint64{1, 1, 2, 3, 5, 8, 13, 21}, MaxLen: 4, Expected: []uint8{4, 4, 4, 4, 4, 4, 3, 1}},
+		{Name: "pattern 6", Freqs: []uint64{1, 1, 2, 3, 5, 8, 13, 21}, MaxLen: 3, Expected: []uint8{3, 3, 3, 3, 3, 3, 3, 3}},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			lengths, err := BuildLengths(data.Freqs, data.MaxLen)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(data.Expected, lengths) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, lengths)
+			}
+		})
+	}
+
+	_, err := BuildLengths([]uint64{1, 1, 1, 1, 1}, 2)
+	if !errors.Is(err, ErrTooManySymbols) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManySymbols, err)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	// fibonacci frequencies make codes longer than the lookup table
+	fib := make([]uint64, 24)
+	fib[0], fib[1] = 1, 1
+	for i := 2; i < len(fib); i++ {
+		fib[i] = fib[i-1] + fib[i-2]
+	}
+	testData := []struct {
+		Name   string
+		Freqs  []uint64
+		MaxLen uint8
+	}{
+		{Name: "pattern 1", Freqs: []uint64{10, 20, 30, 40}, MaxLen: 15},
+		{Name: "pattern 2", Freqs: []uint64{0, 1, 0}, MaxLen: 15},
+		{Name: "pattern 3", Freqs: fib, MaxLen: 32},
+		{Name: "pattern 4", Freqs: fib, MaxLen: 12},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			c, err := FromFrequencies(data.Freqs, data.MaxLen)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+
+			var syms []int
+			for i := 0; i < 1000; i++ {
+				s := rnd.Intn(len(data.Freqs))
+				if data.Freqs[s] > 0 {
+					syms = append(syms, s)
+				}
+			}
+
+			buf := bytes.NewBuffer([]byte{})
+			w := bitstream.NewWriter(buf)
+			err = c.WriteTable(w)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			for _, s := range syms {
+				err = c.Encode(w, s)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+			}
+			w.Finalize()
+
+			r := bitstream.NewReader(buf, &bitstream.ReaderOptions{BufferSize: 3})
+			c2, err := ReadTable(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(c.Lengths(), c2.Lengths()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", c.Lengths(), c2.Lengths())
+			}
+			for i, s := range syms {
+				actual, err := c2.Decode(r)
+				if err != nil {
+					t.Fatalf("unexpected error at %d: %+v\n", i, err)
+				}
+				if s != actual {
+					t.Fatalf("\nsymbol %d\nExpected: %+v\nActual:   %+v\n", i, s, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	// 0: 0, 1: 10, 2: 110, 111 is not used
+	c, _ := New([]uint8{1, 2, 3})
+
+	testData := []struct {
+		Name     string
+		Src      []byte
+		Skip     uint
+		Expected error
+	}{
+		{
+			Name: "pattern 1",
+			// 1111 1111
+			Src:      []byte{0xff},
+			Expected: ErrInvalidCode,
+		},
+		{
+			Name: "pattern 2",
+			// 1111 1111
+			//        ^^
+			Src:      []byte{0xff},
+			Skip:     6,
+			Expected: io.ErrUnexpectedEOF,
+		},
+		{
+			Name: "pattern 3",
+			// 1111 1111
+			Src:      []byte{0xff},
+			Skip:     8,
+			Expected: io.EOF,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := bitstream.NewReader(bytes.NewReader(data.Src), nil)
+			r.Skip(data.Skip)
+			_, err := c.Decode(r)
+			if !errors.Is(err, data.Expected) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+			if r.BitPosition() != uint64(data.Skip) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Skip, r.BitPosition())
+			}
+		})
+	}
+
+	err := c.Encode(bitstream.NewWriter(&bytes.Buffer{}), 3)
+	if !errors.Is(err, ErrInvalidSymbol) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidSymbol, err)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	freqs := make([]uint64, 256)
+	for i := range freqs {
+		freqs[i] = uint64(rnd.Intn(1000)) + 1
+	}
+	c, _ := FromFrequencies(freqs, 15)
+
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriterWithOptions(buf, &bitstream.WriterOptions{BufferSize: 4096})
+	for i := 0; i < 1<<16; i++ {
+		c.Encode(w, rnd.Intn(len(freqs)))
+	}
+	w.Finalize()
+	data := buf.Bytes()
+
+	b.ResetTimer()
+	r := bitstream.NewReader(bytes.NewReader(data), &bitstream.ReaderOptions{BufferSize: 4096})
+	for i := 0; i < b.N; i++ {
+		if i%(1<<16) == 0 {
+			r = bitstream.NewReader(bytes.NewReader(data), &bitstream.ReaderOptions{BufferSize: 4096})
+		}
+		c.Decode(r)
+	}
+}
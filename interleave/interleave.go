@@ -0,0 +1,281 @@
+// Package interleave implements block and convolutional interleavers on top of bitstream.Writer / bitstream.Reader,
+// which spread burst errors over many codewords of a forward error correction code.
+//
+// The interleavers permute symbols of a fixed number of bits (1 - 64), e.g. single bits or bytes.
+//
+// A block interleaver writes the symbols into a matrix row by row and reads them out column by column.
+// For example, the symbols 0 1 2 3 4 5 are sent as 0 3 1 4 2 5 by a block interleaver of 2 rows and 3 columns.
+//
+// A convolutional (Forney) interleaver distributes the symbols to its branches in turn, and the branch j delays
+// the symbols by j * Depth symbols of the branch. The deinterleaver has the complementary delays, so that every symbol is
+// delayed by Branches * (Branches - 1) * Depth symbols in total. The DVB outer interleaver is Branches 12, Depth 17 of bytes.
+package interleave
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+var (
+	// ErrInvalidConfig is returned when the parameters of an interleaver are out of range.
+	ErrInvalidConfig = errors.New("interleave: invalid configuration")
+)
+
+// Block is the configuration of a block interleaver.
+type Block struct {
+	Rows       int
+	Cols       int
+	SymbolBits uint8 // number of bits of a symbol (1 - 64). 0 means 1.
+}
+
+func (b Block) validate() error {
+	if b.Rows <= 0 || b.Cols <= 0 || b.SymbolBits > 64 {
+		return fmt.Errorf("%w: %d x %d block of %d bit symbols", ErrInvalidConfig, b.Rows, b.Cols, b.SymbolBits)
+	}
+	return nil
+}
+
+func (b Block) symbolBits() uint8 {
+	if b.SymbolBits == 0 {
+		return 1
+	}
+	return b.SymbolBits
+}
+
+// BlockWriter interleaves the symbols written to it and writes them to a bitstream.Writer block by block.
+type BlockWriter struct {
+	w     *bitstream.Writer
+	b     Block
+	block []uint64
+	n     int // number of symbols in block
+}
+
+// NewBlockWriter creates a new BlockWriter instance which writes the interleaved symbols to `w`.
+func NewBlockWriter(w *bitstream.Writer, b Block) (*BlockWriter, error) {
+	err := b.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &BlockWriter{w: w, b: b, block: make([]uint64, b.Rows*b.Cols)}, nil
+}
+
+// WriteSymbol writes the LSB SymbolBits bits of `v` as a symbol.
+// The symbols are written to the underlying Writer when a block is complete.
+func (bw *BlockWriter) WriteSymbol(v uint64) error {
+	bw.block[bw.n] = v
+	bw.n++
+	if bw.n < len(bw.block) {
+		return nil
+	}
+	return bw.writeBlock()
+}
+
+// Flush fills the incomplete block, if any, with 0 symbols and writes it to the underlying Writer.
+func (bw *BlockWriter) Flush() error {
+	if bw.n == 0 {
+		return nil
+	}
+	clear(bw.block[bw.n:])
+	return bw.writeBlock()
+}
+
+func (bw *BlockWriter) writeBlock() error {
+	bw.n = 0
+	for c := 0; c < bw.b.Cols; c++ {
+		for r := 0; r < bw.b.Rows; r++ {
+			err := writeSymbol(bw.w, bw.b.symbolBits(), bw.block[r*bw.b.Cols+c])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BlockReader reads the symbols interleaved by a block interleaver from a bitstream.Reader and deinterleaves them.
+type BlockReader struct {
+	r     *bitstream.Reader
+	b     Block
+	block []uint64
+	next  int // index of the next symbol in block
+}
+
+// NewBlockReader creates a new BlockReader instance which reads the interleaved symbols from `r`.
+func NewBlockReader(r *bitstream.Reader, b Block) (*BlockReader, error) {
+	err := b.validate()
+	if err != nil {
+		return nil, err
+	}
+	block := make([]uint64, b.Rows*b.Cols)
+	return &BlockReader{r: r, b: b, block: block, next: len(block)}, nil
+}
+
+// ReadSymbol returns the next deinterleaved symbol. A whole block is read from the underlying Reader at once.
+// It returns io.EOF if the bit stream ends at a block boundary, or io.ErrUnexpectedEOF if it ends in a block.
+func (br *BlockReader) ReadSymbol() (uint64, error) {
+	if br.next == len(br.block) {
+		err := br.readBlock()
+		if err != nil {
+			return 0, err
+		}
+		br.next = 0
+	}
+	v := br.block[br.next]
+	br.next++
+	return v, nil
+}
+
+func (br *BlockReader) readBlock() error {
+	i := 0
+	for c := 0; c < br.b.Cols; c++ {
+		for r := 0; r < br.b.Rows; r++ {
+			v, err := br.r.ReadNBitsAsUint64BE(br.b.symbolBits())
+			if err != nil {
+				if i > 0 {
+					return unexpectedEOF(err)
+				}
+				return err
+			}
+			br.block[r*br.b.Cols+c] = v
+			i++
+		}
+	}
+	return nil
+}
+
+// Convolutional is the configuration of a convolutional interleaver.
+type Convolutional struct {
+	Branches   int   // number of branches
+	Depth      int   // the delay of the branch j is j * Depth symbols of the branch
+	SymbolBits uint8 // number of bits of a symbol (1 - 64). 0 means 1.
+}
+
+func (c Convolutional) validate() error {
+	if c.Branches <= 0 || c.Depth < 0 || c.SymbolBits > 64 {
+		return fmt.Errorf("%w: %d branches of depth %d of %d bit symbols", ErrInvalidConfig, c.Branches, c.Depth, c.SymbolBits)
+	}
+	return nil
+}
+
+func (c Convolutional) symbolBits() uint8 {
+	if c.SymbolBits == 0 {
+		return 1
+	}
+	return c.SymbolBits
+}
+
+// Delay returns the number of symbols by which a symbol is delayed through the interleaver and the deinterleaver.
+func (c Convolutional) Delay() int {
+	return c.Branches * (c.Branches - 1) * c.Depth
+}
+
+// delayLines is the set of the branches of a convolutional interleaver or deinterleaver.
+type delayLines struct {
+	lines  [][]uint64 // FIFO of each branch as a ring buffer
+	heads  []int
+	branch int // the branch of the next symbol
+}
+
+func newDelayLines(branches int, delay func(j int) int) *delayLines {
+	d := &delayLines{lines: make([][]uint64, branches), heads: make([]int, branches)}
+	for j := range d.lines {
+		d.lines[j] = make([]uint64, delay(j))
+	}
+	return d
+}
+
+// push passes `v` to the current branch and returns the symbol which comes out of it.
+func (d *delayLines) push(v uint64) uint64 {
+	line := d.lines[d.branch]
+	if len(line) > 0 {
+		h := d.heads[d.branch]
+		line[h], v = v, line[h]
+		d.heads[d.branch] = (h + 1) % len(line)
+	}
+	d.branch = (d.branch + 1) % len(d.lines)
+	return v
+}
+
+// ConvolutionalWriter interleaves the symbols written to it and writes them to a bitstream.Writer.
+// The branches are initially filled with 0 symbols.
+type ConvolutionalWriter struct {
+	w     *bitstream.Writer
+	c     Convolutional
+	lines *delayLines
+}
+
+// NewConvolutionalWriter creates a new ConvolutionalWriter instance which writes the interleaved symbols to `w`.
+func NewConvolutionalWriter(w *bitstream.Writer, c Convolutional) (*ConvolutionalWriter, error) {
+	err := c.validate()
+	if err != nil {
+		return nil, err
+	}
+	lines := newDelayLines(c.Branches, func(j int) int { return j * c.Depth })
+	return &ConvolutionalWriter{w: w, c: c, lines: lines}, nil
+}
+
+// WriteSymbol writes the LSB SymbolBits bits of `v` as a symbol; a symbol coming out of the interleaver is written
+// to the underlying Writer for each symbol written.
+func (cw *ConvolutionalWriter) WriteSymbol(v uint64) error {
+	return writeSymbol(cw.w, cw.c.symbolBits(), cw.lines.push(v))
+}
+
+// Flush writes Delay() 0 symbols, so that all the symbols written so far come out of the deinterleaver.
+func (cw *ConvolutionalWriter) Flush() error {
+	for i := 0; i < cw.c.Delay(); i++ {
+		err := cw.WriteSymbol(0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvolutionalReader reads the symbols interleaved by a convolutional interleaver from a bitstream.Reader and
+// deinterleaves them. The branches are initially filled with 0 symbols, so the first Delay() symbols read from it
+// are not the ones written to the interleaver.
+type ConvolutionalReader struct {
+	r     *bitstream.Reader
+	c     Convolutional
+	lines *delayLines
+}
+
+// NewConvolutionalReader creates a new ConvolutionalReader instance which reads the interleaved symbols from `r`.
+func NewConvolutionalReader(r *bitstream.Reader, c Convolutional) (*ConvolutionalReader, error) {
+	err := c.validate()
+	if err != nil {
+		return nil, err
+	}
+	lines := newDelayLines(c.Branches, func(j int) int { return (c.Branches - 1 - j) * c.Depth })
+	return &ConvolutionalReader{r: r, c: c, lines: lines}, nil
+}
+
+// ReadSymbol reads a symbol from the underlying Reader and returns the symbol which comes out of the deinterleaver.
+func (cr *ConvolutionalReader) ReadSymbol() (uint64, error) {
+	v, err := cr.r.ReadNBitsAsUint64BE(cr.c.symbolBits())
+	if err != nil {
+		return 0, err
+	}
+	return cr.lines.push(v), nil
+}
+
+func writeSymbol(w *bitstream.Writer, nBits uint8, v uint64) error {
+	if nBits > 32 {
+		err := w.WriteNBitsOfUint32BE(nBits-32, uint32(v>>32))
+		if err != nil {
+			return err
+		}
+		nBits = 32
+	}
+	return w.WriteNBitsOfUint32BE(nBits, uint32(v))
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
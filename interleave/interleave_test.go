@@ -0,0 +1,165 @@
+package interleave
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func TestBlock(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Block    Block
+		Symbols  []uint64
+		Expected []byte
+	}{
+		{
+			Name: "pattern 1",
+			// 1 1 0
+			// 0 1 0 -> 1011 00xx
+			Block:    Block{Rows: 2, Cols: 3},
+			Symbols:  []uint64{1, 1, 0, 0, 1, 0},
+			Expected: []byte{0xb0},
+		},
+		{
+			Name:     "pattern 2",
+			Block:    Block{Rows: 2, Cols: 3, SymbolBits: 8},
+			Symbols:  []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+			Expected: []byte{0, 3, 1, 4, 2, 5, 6, 9, 7, 10, 8, 11},
+		},
+		{
+			Name: "pattern 3",
+			// 1 1
+			// 1 (0) -> 1110 xxxx
+			Block:    Block{Rows: 2, Cols: 2},
+			Symbols:  []uint64{1, 1, 1},
+			Expected: []byte{0xe0},
+		},
+		{
+			Name: "pattern 4",
+			// 0x0123456789 0x0abcdef012
+			// 0x0fedcba987 0x0000000001
+			Block:    Block{Rows: 2, Cols: 2, SymbolBits: 36},
+			Symbols:  []uint64{0x0123456789, 0x0abcdef012, 0x0fedcba987, 0x0000000001},
+			Expected: []byte{0x12, 0x34, 0x56, 0x78, 0x9f, 0xed, 0xcb, 0xa9, 0x87, 0xab, 0xcd, 0xef, 0x01, 0x20, 0x00, 0x00, 0x00, 0x01},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			w := bitstream.NewWriter(buf)
+			bw, err := NewBlockWriter(w, data.Block)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			for _, v := range data.Symbols {
+				err = bw.WriteSymbol(v)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+			}
+			err = bw.Flush()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.Finalize()
+			if !bytes.Equal(data.Expected, buf.Bytes()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, buf.Bytes())
+			}
+
+			br, err := NewBlockReader(bitstream.NewReader(buf, nil), data.Block)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			actual := make([]uint64, len(data.Symbols))
+			for i := range actual {
+				actual[i], err = br.ReadSymbol()
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+			}
+			if !reflect.DeepEqual(data.Symbols, actual) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Symbols, actual)
+			}
+		})
+	}
+}
+
+func TestConvolutional(t *testing.T) {
+	c := Convolutional{Branches: 3, Depth: 1, SymbolBits: 8}
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	cw, err := NewConvolutionalWriter(w, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	symbols := []uint64{1, 2, 3, 4, 5, 6, 7}
+	for _, v := range symbols {
+		err = cw.WriteSymbol(v)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	// the branches delay the symbols by 0, 1 and 2 symbols of the branch
+	expected := []byte{1, 0, 0, 4, 2, 0, 7}
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, buf.Bytes())
+	}
+	err = cw.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if buf.Len() != len(symbols)+c.Delay() {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", len(symbols)+c.Delay(), buf.Len())
+	}
+
+	cr, err := NewConvolutionalReader(bitstream.NewReader(buf, nil), c)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	actual := []uint64{}
+	for {
+		v, err := cr.ReadSymbol()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		actual = append(actual, v)
+	}
+	expectedSymbols := append(make([]uint64, c.Delay()), symbols...)
+	if !reflect.DeepEqual(expectedSymbols, actual) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expectedSymbols, actual)
+	}
+}
+
+func TestError(t *testing.T) {
+	for _, b := range []Block{{Rows: 0, Cols: 1}, {Rows: 1, Cols: -1}, {Rows: 1, Cols: 1, SymbolBits: 65}} {
+		_, err := NewBlockWriter(nil, b)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidConfig, err)
+		}
+	}
+	for _, c := range []Convolutional{{Branches: 0}, {Branches: 1, Depth: -1}, {Branches: 1, SymbolBits: 65}} {
+		_, err := NewConvolutionalReader(nil, c)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidConfig, err)
+		}
+	}
+
+	br, err := NewBlockReader(bitstream.NewReader(bytes.NewReader([]byte{0x01, 0x02, 0x03}), nil), Block{Rows: 2, Cols: 2, SymbolBits: 8})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, err = br.ReadSymbol()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
@@ -0,0 +1,78 @@
+package bitstream
+
+import (
+	"fmt"
+	"math"
+)
+
+// lengthPrefixedChunkSize is the number of bytes ReadLengthPrefixedBytes allocates at a time,
+// so that a corrupted length field does not allocate more memory than the bit stream has.
+const lengthPrefixedChunkSize = 64 * 1024
+
+// ReadLengthPrefixedBytes reads a `lenBits`-bit big endian length field followed by that many bytes, and returns the bytes.
+// The bit stream does not have to be byte aligned. If the length is 0, it returns an empty slice.
+// A length which does not fit in int, or in bits in uint, is rejected with ErrValueOutOfRange,
+// and the memory is allocated as the bytes are read, so a corrupted length ends with io.ErrUnexpectedEOF
+// without allocating the whole length.
+func (r *Reader) ReadLengthPrefixedBytes(lenBits uint8) ([]byte, error) {
+	pos := r.BitPosition()
+	p, err := r.readLengthPrefixedBytes(lenBits)
+	if err != nil {
+		return nil, r.wrapError("ReadLengthPrefixedBytes", pos, err)
+	}
+	r.trace("", pos, uint(r.BitPosition()-pos), p)
+	return p, nil
+}
+
+func (r *Reader) readLengthPrefixedBytes(lenBits uint8) ([]byte, error) {
+	n, err := r.readUint(lenBits, 64)
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxInt || n > uint64(^uint(0)/8) {
+		return nil, fmt.Errorf("%w: length %d", ErrValueOutOfRange, n)
+	}
+	err = r.checkBitsPerCall(n * 8)
+	if err != nil {
+		return nil, err
+	}
+
+	p := make([]byte, 0, min(n, lengthPrefixedChunkSize))
+	for uint64(len(p)) < n {
+		c := min(n-uint64(len(p)), lengthPrefixedChunkSize)
+		data, err := r.readNBits(uint(c)*8, nil)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		p = append(p, data...)
+	}
+	return p, nil
+}
+
+// WriteLengthPrefixedBytes writes the length of `p` as a `lenBits`-bit big endian field followed by the bytes in `p`.
+// It returns ErrValueOutOfRange without writing anything if the length does not fit in `lenBits` bits.
+func (w *Writer) WriteLengthPrefixedBytes(lenBits uint8, p []byte) error {
+	pos := w.bitPosition()
+	err := w.writeLengthPrefixedBytes(lenBits, p)
+	if err != nil {
+		return w.wrapError("WriteLengthPrefixedBytes", pos, err)
+	}
+	w.trace("", pos, uint(lenBits)+uint(len(p))*8, p)
+	return nil
+}
+
+func (w *Writer) writeLengthPrefixedBytes(lenBits uint8, p []byte) error {
+	if lenBits > 64 {
+		return errTooManyBitsForUint64
+	}
+	n := uint64(len(p))
+	if lenBits < 64 && n>>lenBits != 0 {
+		return fmt.Errorf("%w: length %d does not fit in %d bits", ErrValueOutOfRange, n, lenBits)
+	}
+
+	err := w.writeUint(lenBits, 64, n)
+	if err != nil {
+		return err
+	}
+	return w.writeBytes(p)
+}
@@ -0,0 +1,60 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestLengthPrefixedBytes(t *testing.T) {
+	payloads := [][]byte{{}, {0xde, 0xad, 0xbe, 0xef}, bytes.Repeat([]byte{0x5a}, lengthPrefixedChunkSize+3)}
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, nil)
+	w.WriteBit(1) // not byte aligned
+	for _, p := range payloads {
+		err := w.WriteLengthPrefixedBytes(20, p)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	w.Close()
+
+	r := NewReader(&buf, nil)
+	r.Skip(1)
+	for _, expected := range payloads {
+		p, err := r.ReadLengthPrefixedBytes(20)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if !bytes.Equal(p, expected) {
+			t.Fatalf("\nExpected: %d bytes\nActual:   %d bytes\n", len(expected), len(p))
+		}
+	}
+}
+
+func TestLengthPrefixedBytesErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	err := w.WriteLengthPrefixedBytes(2, []byte{1, 2, 3, 4})
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+	}
+	if w.WrittenBits() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, w.WrittenBits())
+	}
+
+	// the length is larger than the rest of the bit stream
+	r := NewReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0x01, 0x02}), nil)
+	_, err = r.ReadLengthPrefixedBytes(32)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+
+	r = NewReader(bytes.NewReader(bytes.Repeat([]byte{0xff}, 8)), nil)
+	_, err = r.ReadLengthPrefixedBytes(64)
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+	}
+}
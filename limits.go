@@ -0,0 +1,73 @@
+package bitstream
+
+import (
+	"fmt"
+)
+
+// ReaderLimits bounds the sizes a Reader accepts from its callers, for parsing untrusted input where the sizes
+// come from length fields which an attacker controls. A zero field means no limit.
+type ReaderLimits struct {
+	// MaxBitsPerCall is the maximum number of bits read by a single call of ReadNBits, ReadBytes, ReadNBitsNamed,
+	// ReadLengthPrefixedBytes, ReadAlphabet or Lookahead.
+	MaxBitsPerCall uint64
+
+	// MaxAllocBytes is the maximum total number of bytes of the slices allocated by ReadNBits, ReadBytes, ReadNBitsNamed,
+	// ReadLengthPrefixedBytes, ReadAlphabet, ReadAll and Lookahead since the Reader is created or reset.
+	MaxAllocBytes uint64
+
+	// MaxSkipBits is the maximum number of bits skipped by a single call of Skip.
+	MaxSkipBits uint64
+}
+
+// LimitError is returned when a call exceeds a limit of ReaderLimits. The call reads nothing if its own size exceeds
+// the limit, but ReadAll and ReadLengthPrefixedBytes may have consumed some bits when the total exceeds MaxAllocBytes.
+type LimitError struct {
+	Limit     string // name of the limit, e.g. "MaxBitsPerCall"
+	Requested uint64 // the size requested by the call, or the total size for MaxAllocBytes
+	Max       uint64 // the limit
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("bitstream: %d exceeds %s %d", e.Requested, e.Limit, e.Max)
+}
+
+// Unwrap returns ErrLimitExceeded.
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// GetLimits gets configured limits.
+func (opt *ReaderOptions) GetLimits() ReaderLimits {
+	if opt == nil {
+		return ReaderLimits{}
+	}
+	return opt.Limits
+}
+
+// checkBitsPerCall returns a *LimitError if `nBits` exceeds MaxBitsPerCall.
+func (r *Reader) checkBitsPerCall(nBits uint64) error {
+	max := r.opt.GetLimits().MaxBitsPerCall
+	if max != 0 && nBits > max {
+		return &LimitError{Limit: "MaxBitsPerCall", Requested: nBits, Max: max}
+	}
+	return nil
+}
+
+// allocate counts `nBytes` to be allocated, or returns a *LimitError if the total exceeds MaxAllocBytes.
+func (r *Reader) allocate(nBytes uint64) error {
+	max := r.opt.GetLimits().MaxAllocBytes
+	if max != 0 && r.allocated+nBytes > max {
+		return &LimitError{Limit: "MaxAllocBytes", Requested: r.allocated + nBytes, Max: max}
+	}
+	r.allocated += nBytes
+	return nil
+}
+
+// checkSkip returns a *LimitError if `nBits` exceeds MaxSkipBits.
+func (r *Reader) checkSkip(nBits uint64) error {
+	max := r.opt.GetLimits().MaxSkipBits
+	if max != 0 && nBits > max {
+		return &LimitError{Limit: "MaxSkipBits", Requested: nBits, Max: max}
+	}
+	return nil
+}
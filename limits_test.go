@@ -0,0 +1,127 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestReaderLimitsBitsPerCall(t *testing.T) {
+	data := bytes.Repeat([]byte{0xa5}, 32)
+	opt := &ReaderOptions{Limits: ReaderLimits{MaxBitsPerCall: 64}}
+	hex, err := NewAlphabet("0123456789abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	testData := []struct {
+		Name string
+		Read func(r *Reader) error
+	}{
+		{Name: "ReadNBits", Read: func(r *Reader) error { _, err := r.ReadNBits(65, nil); return err }},
+		{Name: "ReadBytes", Read: func(r *Reader) error { _, err := r.ReadBytes(9); return err }},
+		{Name: "ReadNBitsNamed", Read: func(r *Reader) error { _, err := r.ReadNBitsNamed("payload", 100, nil); return err }},
+		{Name: "ReadAlphabet", Read: func(r *Reader) error { _, err := r.ReadAlphabet(hex, 17); return err }},
+		{Name: "Lookahead", Read: func(r *Reader) error { _, err := r.Lookahead(128); return err }},
+	}
+
+	for _, td := range testData {
+		td := td // capture
+		t.Run(td.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data), opt)
+			err := td.Read(r)
+			var le *LimitError
+			if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &le) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrLimitExceeded, err)
+			}
+			if le.Limit != "MaxBitsPerCall" || le.Max != 64 {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "MaxBitsPerCall 64", le)
+			}
+			if r.BitPosition() != 0 {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, r.BitPosition())
+			}
+
+			// the limit is inclusive
+			v, err := r.ReadNBits(64, nil)
+			if err != nil || len(v) != 8 {
+				t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 8, nil, len(v), err)
+			}
+		})
+	}
+}
+
+func TestReaderLimitsLengthPrefixed(t *testing.T) {
+	// a length field of 0xffff bytes in front of 4 bytes
+	r := NewReader(bytes.NewReader([]byte{0xff, 0xff, 0x01, 0x02, 0x03, 0x04}), &ReaderOptions{Limits: ReaderLimits{MaxBitsPerCall: 1024}})
+	_, err := r.ReadLengthPrefixedBytes(16)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrLimitExceeded, err)
+	}
+}
+
+func TestReaderLimitsAllocBytes(t *testing.T) {
+	data := bytes.Repeat([]byte{0xa5}, 32)
+	r := NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: 4, Limits: ReaderLimits{MaxAllocBytes: 10}})
+
+	_, err := r.ReadBytes(6)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, err = r.ReadNBits(33, nil) // 5 bytes, 11 in total
+	var le *LimitError
+	if !errors.As(err, &le) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrLimitExceeded, err)
+	}
+	expected := LimitError{Limit: "MaxAllocBytes", Requested: 11, Max: 10}
+	if expected != *le {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, *le)
+	}
+	_, err = r.ReadBytes(4)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	// ReadAll stops at the limit
+	_, _, err = r.ReadAll()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrLimitExceeded, err)
+	}
+
+	// Reset clears the total
+	r.Reset(bytes.NewReader(data))
+	_, err = r.ReadBytes(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+}
+
+func TestReaderLimitsSkip(t *testing.T) {
+	r := NewReader(bytes.NewReader(make([]byte, 16)), &ReaderOptions{Limits: ReaderLimits{MaxSkipBits: 32}})
+	err := r.Skip(33)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrLimitExceeded, err)
+	}
+	err = r.Skip(32)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if r.BitPosition() != 32 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 32, r.BitPosition())
+	}
+}
+
+func TestReaderLimitsInherited(t *testing.T) {
+	r := NewReader(bytes.NewReader(make([]byte, 16)), &ReaderOptions{Limits: ReaderLimits{MaxBitsPerCall: 64}})
+	lr, err := r.Lookahead(64)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, err = lr.ReadNBits(65, nil)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrLimitExceeded, err)
+	}
+	_, err = r.With(NewScrambler(0x3)).ReadNBits(65, nil)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrLimitExceeded, err)
+	}
+}
@@ -0,0 +1,249 @@
+package linecode
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+var (
+	// ErrInvalidSymbol is returned when a 10-bit symbol is not a valid 8b/10b code.
+	ErrInvalidSymbol = errors.New("linecode: invalid 8b/10b symbol")
+
+	// ErrDisparity is returned when a valid 8b/10b symbol does not match the running disparity.
+	ErrDisparity = errors.New("linecode: running disparity error")
+
+	// ErrInvalidControl is returned when a control character other than K.28.y, K.23.7, K.27.7, K.29.7 and K.30.7 is written.
+	ErrInvalidControl = errors.New("linecode: invalid 8b/10b control character")
+)
+
+// Control characters (K codes) of 8b/10b, e.g. K28_5 is the comma used for the symbol alignment.
+const (
+	K28_0 uint8 = 0x1c
+	K28_1 uint8 = 0x3c
+	K28_2 uint8 = 0x5c
+	K28_3 uint8 = 0x7c
+	K28_4 uint8 = 0x9c
+	K28_5 uint8 = 0xbc
+	K28_6 uint8 = 0xdc
+	K28_7 uint8 = 0xfc
+	K23_7 uint8 = 0xf7
+	K27_7 uint8 = 0xfb
+	K29_7 uint8 = 0xfd
+	K30_7 uint8 = 0xfe
+)
+
+// sub-blocks for the negative running disparity, transmitted from the MSB (abcdei and fghj).
+// the ones for the positive running disparity are their complements if they are not balanced (or 111000 / 1100).
+var (
+	code5b6b = [32]uint8{
+		0x27, 0x1d, 0x2d, 0x31, 0x35, 0x29, 0x19, 0x38, 0x39, 0x25, 0x15, 0x34, 0x0d, 0x2c, 0x1c, 0x17,
+		0x1b, 0x23, 0x13, 0x32, 0x0b, 0x2a, 0x1a, 0x3a, 0x33, 0x26, 0x16, 0x36, 0x0e, 0x2e, 0x1e, 0x2b,
+	}
+	codeK28 uint8 = 0x0f // 001111
+
+	code3b4b  = [8]uint8{0xb, 0x9, 0x5, 0xc, 0xd, 0xa, 0x6, 0xe}
+	code3b4bK = [8]uint8{0xb, 0x6, 0xa, 0xc, 0xd, 0x5, 0x9, 0x7}
+
+	codeA7 uint8 = 0x7 // 0111, the alternate D.x.7
+)
+
+// symbol8b10b is the decoded value of a 10-bit symbol.
+type symbol8b10b struct {
+	value uint8
+	k     bool
+	valid [2]bool  // valid for the negative (0) and the positive (1) running disparity
+	next  [2]uint8 // running disparity after the symbol, for each of them
+}
+
+var decodeTable8b10b = func() (t [1024]*symbol8b10b) {
+	for rd := uint8(0); rd < 2; rd++ {
+		for i := 0; i < 512; i++ {
+			v, k := uint8(i), i >= 256
+			if k && !validControl(v) {
+				continue
+			}
+			code, next := encode8b10b(v, k, rd)
+			s := t[code]
+			if s == nil {
+				s = &symbol8b10b{value: v, k: k}
+				t[code] = s
+			}
+			s.valid[rd] = true
+			s.next[rd] = next
+		}
+	}
+	return t
+}()
+
+// validControl reports whether `v` is one of the 12 control characters.
+func validControl(v uint8) bool {
+	x, y := v&0x1f, v>>5
+	return x == 28 || (y == 7 && (x == 23 || x == 27 || x == 29 || x == 30))
+}
+
+// encode8b10b returns the 10-bit symbol of `v` for the running disparity `rd` (0: negative, 1: positive),
+// and the running disparity after it.
+func encode8b10b(v uint8, k bool, rd uint8) (uint16, uint8) {
+	x, y := v&0x1f, v>>5
+
+	c6 := code5b6b[x]
+	if k && x == 28 {
+		c6 = codeK28
+	}
+	c6, rd = applyDisparity(c6, 6, rd, c6 == 0x38)
+
+	var c4 uint8
+	switch {
+	case k:
+		c4 = code3b4bK[y]
+	case y == 7 && ((rd == 0 && (x == 17 || x == 18 || x == 20)) || (rd == 1 && (x == 11 || x == 13 || x == 14))):
+		c4 = codeA7
+	default:
+		c4 = code3b4b[y]
+	}
+	c4, rd = applyDisparity(c4, 4, rd, k || c4 == 0xc)
+
+	return uint16(c6)<<4 | uint16(c4), rd
+}
+
+// applyDisparity returns the `nBits`-bit sub-block `c` for the running disparity `rd`, and the running disparity after it.
+// An unbalanced sub-block is complemented for the positive running disparity and flips it; a balanced one is
+// complemented only if `alt` is true, i.e. 111000, 1100 and the sub-blocks of the control characters.
+func applyDisparity(c uint8, nBits uint8, rd uint8, alt bool) (uint8, uint8) {
+	ones := uint8(0)
+	for i := uint8(0); i < nBits; i++ {
+		ones += c >> i & 0x01
+	}
+	balanced := ones*2 == nBits
+	if rd == 1 && (!balanced || alt) {
+		c = ^c & (1<<nBits - 1)
+	}
+	if !balanced {
+		rd ^= 1
+	}
+	return c, rd
+}
+
+// Encoder8b10b writes bytes to a bitstream.Writer in 8b/10b code, tracking the running disparity.
+// Each byte HGFEDCBA is written as a 10-bit symbol abcdeifghj, from a to j.
+type Encoder8b10b struct {
+	w  *bitstream.Writer
+	rd uint8 // 0: negative, 1: positive
+}
+
+// NewEncoder8b10b creates a new Encoder8b10b instance which writes the symbols to `w`.
+// The initial running disparity is negative.
+func NewEncoder8b10b(w *bitstream.Writer) *Encoder8b10b {
+	return &Encoder8b10b{w: w}
+}
+
+// RunningDisparity returns the current running disparity, -1 or +1.
+func (e *Encoder8b10b) RunningDisparity() int {
+	return int(e.rd)*2 - 1
+}
+
+// WriteData writes the symbol of a data character (D code).
+func (e *Encoder8b10b) WriteData(b uint8) error {
+	return e.write(b, false)
+}
+
+// WriteControl writes the symbol of a control character (K code), e.g. K28_5.
+// It returns ErrInvalidControl if `b` is not a valid control character.
+func (e *Encoder8b10b) WriteControl(b uint8) error {
+	if !validControl(b) {
+		return fmt.Errorf("%w: %#02x", ErrInvalidControl, b)
+	}
+	return e.write(b, true)
+}
+
+// WriteBytes writes the symbols of the data characters in `p`.
+func (e *Encoder8b10b) WriteBytes(p []byte) error {
+	for _, b := range p {
+		err := e.write(b, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder8b10b) write(b uint8, k bool) error {
+	code, rd := encode8b10b(b, k, e.rd)
+	err := e.w.WriteNBitsOfUint16BE(10, code)
+	if err != nil {
+		return err
+	}
+	e.rd = rd
+	return nil
+}
+
+// Decoder8b10b reads bytes from a bitstream.Reader in 8b/10b code, tracking the running disparity.
+type Decoder8b10b struct {
+	r  *bitstream.Reader
+	rd uint8 // 0: negative, 1: positive
+}
+
+// NewDecoder8b10b creates a new Decoder8b10b instance which reads the symbols from `r`.
+// The initial running disparity is negative; use SetRunningDisparity for a capture which starts in the middle of a stream.
+func NewDecoder8b10b(r *bitstream.Reader) *Decoder8b10b {
+	return &Decoder8b10b{r: r}
+}
+
+// RunningDisparity returns the current running disparity, -1 or +1.
+func (d *Decoder8b10b) RunningDisparity() int {
+	return int(d.rd)*2 - 1
+}
+
+// SetRunningDisparity sets the current running disparity; a negative `rd` means -1, otherwise +1.
+func (d *Decoder8b10b) SetRunningDisparity(rd int) {
+	d.rd = 0
+	if rd >= 0 {
+		d.rd = 1
+	}
+}
+
+// ReadSymbol reads a 10-bit symbol and returns the character and whether it is a control character (K code).
+//
+// It returns an error which wraps ErrInvalidSymbol if the symbol is not a valid code; the running disparity is kept then.
+// If the symbol is valid but does not match the running disparity, the character is returned together with an error
+// which wraps ErrDisparity, and the running disparity is resynchronized with the symbol.
+func (d *Decoder8b10b) ReadSymbol() (uint8, bool, error) {
+	pos := d.r.BitPosition()
+	code, err := d.r.ReadNBitsAsUint16BE(10)
+	if err != nil {
+		return 0, false, err
+	}
+
+	s := decodeTable8b10b[code]
+	if s == nil {
+		return 0, false, fmt.Errorf("%w: %010b at bit %d", ErrInvalidSymbol, code, pos)
+	}
+	if !s.valid[d.rd] {
+		d.rd = s.next[d.rd^1]
+		return s.value, s.k, fmt.Errorf("%w: %010b at bit %d", ErrDisparity, code, pos)
+	}
+	d.rd = s.next[d.rd]
+	return s.value, s.k, nil
+}
+
+// ReadBytes reads the symbols of `nBytes` data characters.
+// It returns an error which wraps ErrInvalidSymbol if a control character is found.
+func (d *Decoder8b10b) ReadBytes(nBytes uint) ([]byte, error) {
+	p := make([]byte, nBytes)
+	for i := range p {
+		b, k, err := d.ReadSymbol()
+		if err != nil {
+			if i > 0 {
+				return nil, unexpectedEOF(err)
+			}
+			return nil, err
+		}
+		if k {
+			return nil, fmt.Errorf("%w: unexpected control character %#02x", ErrInvalidSymbol, b)
+		}
+		p[i] = b
+	}
+	return p, nil
+}
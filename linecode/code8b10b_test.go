@@ -0,0 +1,132 @@
+package linecode
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func Test8b10bSymbols(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Value    uint8
+		K        bool
+		RD       uint8
+		Expected uint16
+	}{
+		{Name: "D.0.0 RD-", Value: 0x00, RD: 0, Expected: 0x274},            // 100111 0100
+		{Name: "D.0.0 RD+", Value: 0x00, RD: 1, Expected: 0x18b},            // 011000 1011
+		{Name: "D.21.5 RD-", Value: 0xb5, RD: 0, Expected: 0x2aa},           // 101010 1010
+		{Name: "D.7.3 RD-", Value: 0x67, RD: 0, Expected: 0x38c},            // 111000 1100
+		{Name: "D.7.3 RD+", Value: 0x67, RD: 1, Expected: 0x073},            // 000111 0011
+		{Name: "D.17.7 RD-", Value: 0xf1, RD: 0, Expected: 0x237},           // 100011 0111
+		{Name: "D.11.7 RD+", Value: 0xeb, RD: 1, Expected: 0x348},           // 110100 1000
+		{Name: "K.28.5 RD-", Value: K28_5, K: true, RD: 0, Expected: 0x0fa}, // 001111 1010
+		{Name: "K.28.5 RD+", Value: K28_5, K: true, RD: 1, Expected: 0x305}, // 110000 0101
+		{Name: "K.23.7 RD-", Value: K23_7, K: true, RD: 0, Expected: 0x3a8}, // 111010 1000
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			code, _ := encode8b10b(data.Value, data.K, data.RD)
+			if code != data.Expected {
+				t.Fatalf("\nExpected: %010b\nActual:   %010b\n", data.Expected, code)
+			}
+		})
+	}
+}
+
+func Test8b10bRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	e := NewEncoder8b10b(w)
+
+	// every data and control character, twice so that each is coded with both running disparities
+	type char struct {
+		v uint8
+		k bool
+	}
+	var chars []char
+	for i := 0; i < 2; i++ {
+		for v := 0; v < 256; v++ {
+			chars = append(chars, char{v: uint8(v)})
+		}
+		for _, k := range []uint8{K28_0, K28_1, K28_2, K28_3, K28_4, K28_5, K28_6, K28_7, K23_7, K27_7, K29_7, K30_7} {
+			chars = append(chars, char{v: k, k: true})
+		}
+		chars = append(chars, char{v: 0x00}) // flips the running disparity for the second round
+	}
+	for _, c := range chars {
+		var err error
+		if c.k {
+			err = e.WriteControl(c.v)
+		} else {
+			err = e.WriteData(c.v)
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	w.Close()
+
+	d := NewDecoder8b10b(bitstream.NewReader(&buf, nil))
+	for _, c := range chars {
+		v, k, err := d.ReadSymbol()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if v != c.v || k != c.k {
+			t.Fatalf("\nExpected: %#02x, %t\nActual:   %#02x, %t\n", c.v, c.k, v, k)
+		}
+	}
+	if d.RunningDisparity() != e.RunningDisparity() {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", e.RunningDisparity(), d.RunningDisparity())
+	}
+}
+
+func Test8b10bErrors(t *testing.T) {
+	e := NewEncoder8b10b(bitstream.NewWriter(&bytes.Buffer{}))
+	err := e.WriteControl(0x1b)
+	if !errors.Is(err, ErrInvalidControl) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidControl, err)
+	}
+
+	// 0000000000, then K.28.5 for RD+ while RD is negative
+	d := NewDecoder8b10b(bitstream.NewReader(bytes.NewReader([]byte{0x00, 0x30, 0x50}), nil))
+	_, _, err = d.ReadSymbol()
+	if !errors.Is(err, ErrInvalidSymbol) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidSymbol, err)
+	}
+	v, k, err := d.ReadSymbol()
+	if !errors.Is(err, ErrDisparity) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrDisparity, err)
+	}
+	if v != K28_5 || !k || d.RunningDisparity() != -1 {
+		t.Fatalf("\nExpected: %#02x, true, -1\nActual:   %#02x, %t, %+v\n", K28_5, v, k, d.RunningDisparity())
+	}
+}
+
+func Test8b10bReadBytes(t *testing.T) {
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	e := NewEncoder8b10b(w)
+	e.WriteBytes([]byte("8b10b"))
+	e.WriteControl(K28_5)
+	w.Close()
+
+	d := NewDecoder8b10b(bitstream.NewReader(&buf, nil))
+	p, err := d.ReadBytes(5)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if string(p) != "8b10b" {
+		t.Fatalf("\nExpected: %s\nActual:   %s\n", "8b10b", p)
+	}
+	_, err = d.ReadBytes(1)
+	if !errors.Is(err, ErrInvalidSymbol) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidSymbol, err)
+	}
+}
@@ -0,0 +1,356 @@
+// Package linecode implements the Manchester, NRZI and 8b/10b line codes on top of bitstream.Writer / bitstream.Reader,
+// e.g. to decode the bits captured from an RF or IR receiver.
+//
+// Each data bit is coded in two half-bit symbols by Manchester code, so that the signal has a transition in the middle of every bit:
+//
+//	IEEE 802.3      '0' -> 10, '1' -> 01
+//	G. E. Thomas    '0' -> 01, '1' -> 10
+//
+// NRZI codes each data bit in one symbol and represents one of the bit values by a transition of the level:
+//
+//	NRZ-M (mark)    '1' toggles the level, '0' keeps it
+//	NRZ-S (space)   '0' toggles the level, '1' keeps it (USB, HDLC)
+//
+// 8b/10b codes each byte in a 10-bit symbol keeping the DC balance by the running disparity, and adds control characters
+// (K codes) such as the K28_5 comma, as used by Gigabit Ethernet, PCI Express 1.x/2.x and SATA.
+package linecode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+var (
+	// ErrCodeViolation is returned when a Manchester symbol has no transition in the middle, i.e. it is 00 or 11.
+	ErrCodeViolation = errors.New("linecode: code violation")
+)
+
+// Convention is the mapping of the data bits to the Manchester symbols.
+type Convention int
+
+const (
+	// IEEE8023 codes '0' as 10 and '1' as 01.
+	IEEE8023 Convention = iota
+	// Thomas codes '0' as 01 and '1' as 10.
+	Thomas
+)
+
+// NRZIMode is the data bit value which is represented by a transition in NRZI.
+type NRZIMode int
+
+const (
+	// NRZMark toggles the level for '1'.
+	NRZMark NRZIMode = iota
+	// NRZSpace toggles the level for '0'.
+	NRZSpace
+)
+
+// manchesterChunk is the number of data bits coded at once; their symbols fit in 32 bits.
+const manchesterChunk = 16
+
+// ManchesterEncoder writes data bits to a bitstream.Writer in Manchester code.
+type ManchesterEncoder struct {
+	w    *bitstream.Writer
+	conv Convention
+}
+
+// NewManchesterEncoder creates a new ManchesterEncoder instance which writes the symbols to `w`.
+func NewManchesterEncoder(w *bitstream.Writer, conv Convention) *ManchesterEncoder {
+	return &ManchesterEncoder{w: w, conv: conv}
+}
+
+// WriteBit writes the symbol of a single bit (0 or 1).
+func (e *ManchesterEncoder) WriteBit(bit uint8) error {
+	return e.WriteNBits(1, uint64(bit))
+}
+
+// WriteNBits writes the symbols of the LSB `nBits` (<= 64) bits of `v`, MSB first.
+func (e *ManchesterEncoder) WriteNBits(nBits uint8, v uint64) error {
+	if nBits > 64 {
+		return fmt.Errorf("%w for uint64", bitstream.ErrTooManyBits)
+	}
+	for nBits > 0 {
+		n := min(nBits, manchesterChunk)
+		nBits -= n
+		err := e.w.WriteNBitsOfUint32BE(n*2, uint32(e.symbols(uint32(v>>nBits), n)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteBytes writes the symbols of `p`.
+func (e *ManchesterEncoder) WriteBytes(p []byte) error {
+	for _, b := range p {
+		err := e.WriteNBits(8, uint64(b))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// symbols returns the symbols of the LSB `nBits` bits of `v`.
+// The first half of each symbol is the upper (odd) bit of the pair in the Morton code of the two halves.
+func (e *ManchesterEncoder) symbols(v uint32, nBits uint8) uint64 {
+	var m uint64
+	if e.conv == Thomas {
+		m = bitstream.Interleave2(^v, v)
+	} else {
+		m = bitstream.Interleave2(v, ^v)
+	}
+	return m & (1<<(nBits*2) - 1)
+}
+
+// ManchesterDecoder reads data bits from a bitstream.Reader in Manchester code.
+type ManchesterDecoder struct {
+	r    *bitstream.Reader
+	conv Convention
+}
+
+// NewManchesterDecoder creates a new ManchesterDecoder instance which reads the symbols from `r`.
+func NewManchesterDecoder(r *bitstream.Reader, conv Convention) *ManchesterDecoder {
+	return &ManchesterDecoder{r: r, conv: conv}
+}
+
+// RecoverPhase aligns the decoder to the symbol boundaries, for a capture which may start in the middle of a symbol.
+// It checks up to 64 half-bits ahead for code violations at both phases, and skips a half-bit if the symbols
+// start at the odd half-bits, which is reported by the returned value of 1.
+//
+// The phase cannot be recovered from the symbols of a run of the same bit values, such as 0000 (10101010),
+// because they are valid at both phases; the current phase is kept then. Protocols have a preamble of alternating
+// bits or a sync word for this reason, and RecoverPhase should be called on it.
+func (d *ManchesterDecoder) RecoverPhase() (uint8, error) {
+	v, n, err := d.r.Peek(64)
+	if err != nil {
+		return 0, err
+	}
+	violations := [2]int{}
+	for i := uint8(0); i+1 < n; i++ {
+		pair := v >> (62 - i) & 0x03
+		if pair == 0x00 || pair == 0x03 {
+			violations[i%2]++
+		}
+	}
+	if violations[1] >= violations[0] {
+		return 0, nil
+	}
+	return 1, d.r.Skip(1)
+}
+
+// ReadBit reads the symbol of a single bit and returns the bit.
+func (d *ManchesterDecoder) ReadBit() (uint8, error) {
+	v, err := d.ReadNBits(1)
+	return uint8(v), err
+}
+
+// ReadNBits reads the symbols of `nBits` (<= 64) bits and returns the bits in uint64 (LSB aligned).
+// It returns an error which wraps ErrCodeViolation if a symbol is invalid. The Reader is left at the invalid symbol then,
+// so that RecoverPhase can be called to resynchronize.
+func (d *ManchesterDecoder) ReadNBits(nBits uint8) (uint64, error) {
+	if nBits > 64 {
+		return 0, fmt.Errorf("%w for uint64", bitstream.ErrTooManyBits)
+	}
+	v := uint64(0)
+	for read := uint8(0); read < nBits; {
+		n := min(nBits-read, manchesterChunk)
+		m, avail, err := d.r.Peek(n * 2)
+		if err == nil && avail < n*2 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			if read > 0 {
+				return 0, unexpectedEOF(err)
+			}
+			return 0, err
+		}
+		lower, upper := bitstream.Deinterleave2(m)
+		if bad := ^(lower ^ upper) & (1<<n - 1); bad != 0 {
+			i := uint8(0)
+			for bad>>(n-1-i) == 0 {
+				i++
+			}
+			pos := d.r.BitPosition() + uint64(i)*2
+			err = d.r.Skip(uint(i) * 2)
+			if err != nil {
+				return 0, err
+			}
+			return 0, fmt.Errorf("%w at bit %d", ErrCodeViolation, pos)
+		}
+		_, err = d.r.ReadNBitsAsUint32BE(n * 2)
+		if err != nil {
+			return 0, err
+		}
+		bits := lower
+		if d.conv == Thomas {
+			bits = upper
+		}
+		v = v<<n | uint64(bits)
+		read += n
+	}
+	return v, nil
+}
+
+// ReadBytes reads the symbols of `nBytes` bytes.
+func (d *ManchesterDecoder) ReadBytes(nBytes uint) ([]byte, error) {
+	p := make([]byte, nBytes)
+	for i := range p {
+		b, err := d.ReadNBits(8)
+		if err != nil {
+			if i > 0 {
+				return nil, unexpectedEOF(err)
+			}
+			return nil, err
+		}
+		p[i] = uint8(b)
+	}
+	return p, nil
+}
+
+// nrziChunk is the number of bits coded at once by NRZIEncoder and NRZIDecoder.
+const nrziChunk = 32
+
+// NRZIEncoder writes data bits to a bitstream.Writer in NRZI.
+type NRZIEncoder struct {
+	w          *bitstream.Writer
+	transition uint8 // the data bit value which toggles the level
+	level      uint8
+}
+
+// NewNRZIEncoder creates a new NRZIEncoder instance which writes the levels to `w`. The initial level is 0.
+func NewNRZIEncoder(w *bitstream.Writer, mode NRZIMode) *NRZIEncoder {
+	return &NRZIEncoder{w: w, transition: transitionBit(mode)}
+}
+
+// SetLevel sets the current level (0 or 1), from which the level of the next bit is determined.
+func (e *NRZIEncoder) SetLevel(level uint8) {
+	e.level = level & 0x01
+}
+
+// WriteBit writes the level of a single bit (0 or 1).
+func (e *NRZIEncoder) WriteBit(bit uint8) error {
+	return e.WriteNBits(1, uint64(bit))
+}
+
+// WriteNBits writes the levels of the LSB `nBits` (<= 64) bits of `v`, MSB first.
+func (e *NRZIEncoder) WriteNBits(nBits uint8, v uint64) error {
+	if nBits > 64 {
+		return fmt.Errorf("%w for uint64", bitstream.ErrTooManyBits)
+	}
+	for nBits > 0 {
+		n := min(nBits, nrziChunk)
+		nBits -= n
+		level := e.level
+		levels := uint32(0)
+		for i := n; i > 0; i-- {
+			if uint8(v>>(nBits+i-1))&0x01 == e.transition {
+				level ^= 1
+			}
+			levels = levels<<1 | uint32(level)
+		}
+		err := e.w.WriteNBitsOfUint32BE(n, levels)
+		if err != nil {
+			return err
+		}
+		e.level = level
+	}
+	return nil
+}
+
+// WriteBytes writes the levels of `p`.
+func (e *NRZIEncoder) WriteBytes(p []byte) error {
+	for _, b := range p {
+		err := e.WriteNBits(8, uint64(b))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NRZIDecoder reads data bits from a bitstream.Reader in NRZI.
+type NRZIDecoder struct {
+	r          *bitstream.Reader
+	transition uint8 // the data bit value which toggles the level
+	level      uint8
+}
+
+// NewNRZIDecoder creates a new NRZIDecoder instance which reads the levels from `r`.
+// The initial level is 0; only the first bit is affected if the actual level before the capture is different.
+func NewNRZIDecoder(r *bitstream.Reader, mode NRZIMode) *NRZIDecoder {
+	return &NRZIDecoder{r: r, transition: transitionBit(mode)}
+}
+
+// SetLevel sets the current level (0 or 1), with which the level of the next bit is compared.
+func (d *NRZIDecoder) SetLevel(level uint8) {
+	d.level = level & 0x01
+}
+
+// ReadBit reads the level of a single bit and returns the bit.
+func (d *NRZIDecoder) ReadBit() (uint8, error) {
+	v, err := d.ReadNBits(1)
+	return uint8(v), err
+}
+
+// ReadNBits reads the levels of `nBits` (<= 64) bits and returns the bits in uint64 (LSB aligned).
+func (d *NRZIDecoder) ReadNBits(nBits uint8) (uint64, error) {
+	if nBits > 64 {
+		return 0, fmt.Errorf("%w for uint64", bitstream.ErrTooManyBits)
+	}
+	v := uint64(0)
+	for read := uint8(0); read < nBits; {
+		n := min(nBits-read, nrziChunk)
+		levels, err := d.r.ReadNBitsAsUint32BE(n)
+		if err != nil {
+			if read > 0 {
+				return 0, unexpectedEOF(err)
+			}
+			return 0, err
+		}
+		for i := n; i > 0; i-- {
+			level := uint8(levels>>(i-1)) & 0x01
+			bit := d.transition ^ 1
+			if level != d.level {
+				bit = d.transition
+			}
+			v = v<<1 | uint64(bit)
+			d.level = level
+		}
+		read += n
+	}
+	return v, nil
+}
+
+// ReadBytes reads the levels of `nBytes` bytes.
+func (d *NRZIDecoder) ReadBytes(nBytes uint) ([]byte, error) {
+	p := make([]byte, nBytes)
+	for i := range p {
+		b, err := d.ReadNBits(8)
+		if err != nil {
+			if i > 0 {
+				return nil, unexpectedEOF(err)
+			}
+			return nil, err
+		}
+		p[i] = uint8(b)
+	}
+	return p, nil
+}
+
+func transitionBit(mode NRZIMode) uint8 {
+	if mode == NRZSpace {
+		return 0
+	}
+	return 1
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
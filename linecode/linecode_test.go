@@ -0,0 +1,268 @@
+package linecode
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func TestManchester(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Conv     Convention
+		Data     []byte
+		Expected []byte
+	}{
+		{
+			Name: "pattern 1",
+			// 1010 0101 -> 01 10 01 10  10 01 10 01
+			Conv:     IEEE8023,
+			Data:     []byte{0xa5},
+			Expected: []byte{0x66, 0x99},
+		},
+		{
+			Name: "pattern 2",
+			// 1010 0101 -> 10 01 10 01  01 10 01 10
+			Conv:     Thomas,
+			Data:     []byte{0xa5},
+			Expected: []byte{0x99, 0x66},
+		},
+		{
+			Name: "pattern 3",
+			// 0000 0000 1111 1111 0000 0001 -> 1010 ... 0101 ... 1010 ... 1001
+			Conv:     IEEE8023,
+			Data:     []byte{0x00, 0xff, 0x01},
+			Expected: []byte{0xaa, 0xaa, 0x55, 0x55, 0xaa, 0xa9},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			w := bitstream.NewWriter(buf)
+			err := NewManchesterEncoder(w, data.Conv).WriteBytes(data.Data)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.Finalize()
+			if !bytes.Equal(data.Expected, buf.Bytes()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, buf.Bytes())
+			}
+
+			d := NewManchesterDecoder(bitstream.NewReader(buf, nil), data.Conv)
+			actual, err := d.ReadBytes(uint(len(data.Data)))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !bytes.Equal(data.Data, actual) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Data, actual)
+			}
+		})
+	}
+}
+
+func TestManchesterNBits(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	e := NewManchesterEncoder(w, Thomas)
+	err := e.WriteNBits(40, 0xfedcba9876)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = e.WriteBit(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Finalize()
+
+	d := NewManchesterDecoder(bitstream.NewReader(buf, nil), Thomas)
+	v, err := d.ReadNBits(40)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0xfedcba9876 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0xfedcba9876, v)
+	}
+	b, err := d.ReadBit()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if b != 1 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 1, b)
+	}
+}
+
+func TestRecoverPhase(t *testing.T) {
+	testData := []struct {
+		Name          string
+		Data          []byte
+		ExpectedPhase uint8
+		Expected      uint64
+	}{
+		{
+			Name: "pattern 1",
+			// 0110 0110 1001 1001 = 1010 0101
+			Data:          []byte{0x66, 0x99},
+			ExpectedPhase: 0,
+			Expected:      0xa5,
+		},
+		{
+			Name: "pattern 2",
+			// 1|011 0011 0100 1100 1 = 1010 0101
+			Data:          []byte{0xb3, 0x4c, 0x80},
+			ExpectedPhase: 1,
+			Expected:      0xa5,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			d := NewManchesterDecoder(bitstream.NewReader(bytes.NewReader(data.Data), nil), IEEE8023)
+			phase, err := d.RecoverPhase()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.ExpectedPhase != phase {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedPhase, phase)
+			}
+			v, err := d.ReadNBits(8)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.Expected != v {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, v)
+			}
+		})
+	}
+}
+
+func TestManchesterError(t *testing.T) {
+	// 01 10 11 11
+	r := bitstream.NewReader(bytes.NewReader([]byte{0x6f}), nil)
+	d := NewManchesterDecoder(r, IEEE8023)
+	_, err := d.ReadNBits(4)
+	if !errors.Is(err, ErrCodeViolation) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrCodeViolation, err)
+	}
+	if r.BitPosition() != 4 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 4, r.BitPosition())
+	}
+
+	d = NewManchesterDecoder(bitstream.NewReader(bytes.NewReader([]byte{0x66}), nil), IEEE8023)
+	_, err = d.ReadNBits(8)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+	_, err = d.ReadBytes(1)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestNRZI(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Mode     NRZIMode
+		Level    uint8
+		Data     []byte
+		Expected []byte
+	}{
+		{
+			Name: "pattern 1",
+			// 1010 0101 -> 1100 0110
+			Mode:     NRZMark,
+			Data:     []byte{0xa5},
+			Expected: []byte{0xc6},
+		},
+		{
+			Name: "pattern 2",
+			// 1010 0101 -> 0110 1100
+			Mode:     NRZSpace,
+			Data:     []byte{0xa5},
+			Expected: []byte{0x6c},
+		},
+		{
+			Name: "pattern 3",
+			// (1) 0000 0000 1111 1111 -> 0101 0101 1111 1111
+			Mode:     NRZSpace,
+			Level:    1,
+			Data:     []byte{0x00, 0xff},
+			Expected: []byte{0x55, 0xff},
+		},
+		{
+			Name: "pattern 4",
+			// 0000 0001 0000 0001 0000 0001 0000 0001 0000 0001 -> 0000 0001 1111 1110 0000 0001 1111 1110 0000 0001
+			Mode:     NRZMark,
+			Data:     []byte{0x01, 0x01, 0x01, 0x01, 0x01},
+			Expected: []byte{0x01, 0xfe, 0x01, 0xfe, 0x01},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			w := bitstream.NewWriter(buf)
+			e := NewNRZIEncoder(w, data.Mode)
+			e.SetLevel(data.Level)
+			err := e.WriteBytes(data.Data)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.Finalize()
+			if !bytes.Equal(data.Expected, buf.Bytes()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, buf.Bytes())
+			}
+
+			d := NewNRZIDecoder(bitstream.NewReader(buf, nil), data.Mode)
+			d.SetLevel(data.Level)
+			actual, err := d.ReadBytes(uint(len(data.Data)))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !bytes.Equal(data.Data, actual) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Data, actual)
+			}
+		})
+	}
+}
+
+func TestNRZINBits(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	e := NewNRZIEncoder(w, NRZSpace)
+	err := e.WriteNBits(64, 0x0123456789abcdef)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = e.WriteBit(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Finalize()
+
+	d := NewNRZIDecoder(bitstream.NewReader(buf, nil), NRZSpace)
+	v, err := d.ReadNBits(64)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0x0123456789abcdef {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", uint64(0x0123456789abcdef), v)
+	}
+	b, err := d.ReadBit()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if b != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, b)
+	}
+	_, err = d.ReadNBits(8)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
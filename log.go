@@ -0,0 +1,48 @@
+package bitstream
+
+import (
+	"context"
+	"log/slog"
+)
+
+// GetLogger gets configured logger.
+func (opt *ReaderOptions) GetLogger() *slog.Logger {
+	if opt == nil {
+		return nil
+	}
+	return opt.Logger
+}
+
+// logging reports whether the logger is set and logs the Debug level.
+// Callers check it before calling logDebug so that the arguments are not allocated otherwise.
+func (r *Reader) logging() bool {
+	l := r.opt.GetLogger()
+	return l != nil && l.Enabled(context.Background(), slog.LevelDebug)
+}
+
+func (r *Reader) logDebug(msg string, bitOffset uint64, args ...any) {
+	r.opt.GetLogger().Debug(msg, append([]any{slog.Uint64("bit_offset", bitOffset)}, args...)...)
+}
+
+// GetLogger gets configured logger.
+func (opt *WriterOptions) GetLogger() *slog.Logger {
+	if opt == nil {
+		return nil
+	}
+	return opt.Logger
+}
+
+// SetLogger sets the logger of the Writer, overriding WriterOptions.Logger.
+// Pass nil to remove it.
+func (w *Writer) SetLogger(l *slog.Logger) {
+	w.logger = l
+}
+
+// logging reports whether the logger is set and logs the Debug level.
+func (w *Writer) logging() bool {
+	return w.logger != nil && w.logger.Enabled(context.Background(), slog.LevelDebug)
+}
+
+func (w *Writer) logDebug(msg string, bitOffset uint64, args ...any) {
+	w.logger.Debug(msg, append([]any{slog.Uint64("bit_offset", bitOffset)}, args...)...)
+}
@@ -0,0 +1,126 @@
+package bitstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+// recordHandler is a slog.Handler which keeps the records as lines of the message and the attributes.
+type recordHandler struct {
+	level   slog.Level
+	records []string
+}
+
+func (h *recordHandler) Enabled(_ context.Context, level slog.Level) bool { return level >= h.level }
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler               { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler                    { return h }
+
+func (h *recordHandler) Handle(_ context.Context, rec slog.Record) error {
+	line := rec.Message
+	rec.Attrs(func(a slog.Attr) bool {
+		line += " " + a.String()
+		return true
+	})
+	h.records = append(h.records, line)
+	return nil
+}
+
+func TestReaderLogger(t *testing.T) {
+	h := &recordHandler{level: slog.LevelDebug}
+	r := NewReader(bytes.NewReader([]byte{0x12, 0x34, 0x56}), &ReaderOptions{BufferSize: 2, Logger: slog.New(h)})
+
+	_, err := r.ReadNBitsAsUint8(4)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, err = r.ReadBytes(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, err = r.ReadUint16BE()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+
+	expected := []string{
+		"bitstream: refill bit_offset=0 bytes=2 read_calls=1",
+		"bitstream: unaligned bytes bit_offset=4 bytes=1",
+		"bitstream: refill bit_offset=16 bytes=1 read_calls=1",
+		"bitstream: refill bit_offset=24 bytes=0 read_calls=1",
+		"bitstream: error bit_offset=12 op=ReadNBitsAsUint16BE error=unexpected EOF",
+	}
+	if len(expected) != len(h.records) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, h.records)
+	}
+	for i := range expected {
+		if expected[i] != h.records[i] {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected[i], h.records[i])
+		}
+	}
+}
+
+func TestWriterLogger(t *testing.T) {
+	h := &recordHandler{level: slog.LevelDebug}
+	buf := &bytes.Buffer{}
+	w := NewWriterWithOptions(buf, &WriterOptions{BufferSize: 2, Logger: slog.New(h), StrictValues: true})
+
+	err := w.WriteNBitsOfUint8(3, 0x5)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, err = w.AlignByte(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = w.WriteNBitsOfUint8(2, 0x4)
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+	}
+	err = w.WriteUint16BE(0xabcd)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	expected := []string{
+		"bitstream: align bit_offset=3 pad_bits=5",
+		"bitstream: error bit_offset=8 op=WriteNBitsOfUint8 error=bitstream: value out of range: 4 does not fit in 2 bits",
+		"bitstream: write bit_offset=0 bytes=2 error=<nil>",
+		"bitstream: flush bit_offset=24 buffered=1",
+		"bitstream: write bit_offset=16 bytes=1 error=<nil>",
+	}
+	if len(expected) != len(h.records) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, h.records)
+	}
+	for i := range expected {
+		if expected[i] != h.records[i] {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected[i], h.records[i])
+		}
+	}
+}
+
+func TestLoggerDisabled(t *testing.T) {
+	// nothing is logged above the Debug level
+	h := &recordHandler{level: slog.LevelInfo}
+	r := NewReader(bytes.NewReader([]byte{0x12}), &ReaderOptions{Logger: slog.New(h)})
+	_, err := r.ReadUint16BE()
+	if err == nil {
+		t.Fatalf("expected an error\n")
+	}
+	w := NewWriter(io.Discard)
+	w.SetLogger(slog.New(h))
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if len(h.records) != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, h.records)
+	}
+}
@@ -0,0 +1,58 @@
+package bitstream
+
+// Lookahead returns a new Reader which reads the next `nBits` bits of the bit stream without consuming them from `r`,
+// e.g. to parse an optional extension speculatively and consume it from `r` by Skip only once it turns out to be valid.
+// The bits are copied, so the returned Reader can be used independently of `r`; it returns io.EOF at the end of the window,
+// and its BitPosition starts from 0. It inherits the EOF mode, PlainErrors, Limits and ReadOptions of `r`, but not the trace hook or the I/O hooks.
+//
+// The buffer of `r` is grown to hold the bits if needed. If the bit stream has fewer bits than `nBits`, it returns
+// io.ErrUnexpectedEOF (or io.EOF if no bits are left) and `r` is left as it is.
+func (r *Reader) Lookahead(nBits uint) (*Reader, error) {
+	pos := r.BitPosition()
+	lr, err := r.lookahead(nBits)
+	return lr, r.wrapError("Lookahead", pos, err)
+}
+
+func (r *Reader) lookahead(nBits uint) (*Reader, error) {
+	err := r.checkBitsPerCall(uint64(nBits))
+	if err == nil {
+		err = r.allocate(uint64(nBits+7) / 8)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.refill(nBits)
+	if err != nil {
+		if r.BufferedBits() > 0 {
+			return nil, unexpectedEOF(err)
+		}
+		return nil, err
+	}
+
+	// the window is right aligned in the bytes so that it ends at a byte boundary, and the padding bits
+	// in the first byte are skipped by origin.
+	data := make([]byte, (nBits+7)/8)
+	pad := uint8(uint(len(data))*8 - nBits)
+	byteIndex, bitIndex, consumedBytes := r.currByteIndex, r.currBitIndex, r.consumedBytes
+	for i := range data {
+		n := uint8(8)
+		if i == 0 {
+			n -= pad
+		}
+		data[i] = uint8(r.ReadBits64(uint(n)))
+	}
+	r.currByteIndex, r.currBitIndex, r.consumedBytes = byteIndex, bitIndex, consumedBytes
+
+	lr := NewReaderBytes(data, &ReaderOptions{
+		EOFMode:     r.opt.GetEOFMode(),
+		PlainErrors: r.opt.GetPlainErrors(),
+		Limits:      r.opt.GetLimits(),
+		ReadOptions: r.opt.GetReadOptions(),
+	})
+	lr.origin = uint64(pad)
+	if len(data) > 0 {
+		lr.currBitIndex = 7 - pad
+	}
+	return lr, nil
+}
@@ -0,0 +1,107 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestLookahead(t *testing.T) {
+	// 1010 0101 0011 1100 1111 0000
+	src := []byte{0xa5, 0x3c, 0xf0}
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 1, Prefetch: true}} {
+		r := NewReader(bytes.NewReader(src), opt)
+		err := r.Skip(3)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+
+		lr, err := r.Lookahead(10)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if r.BitPosition() != 3 {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 3, r.BitPosition())
+		}
+		if lr.BitPosition() != 0 || lr.ConsumedBytes() != 0 {
+			t.Fatalf("\nExpected: 0, 0\nActual:   %+v, %+v\n", lr.BitPosition(), lr.ConsumedBytes())
+		}
+
+		// 0 0101 0011 (1)
+		v, err := lr.ReadNBitsAsUint16BE(9)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if v != 0x053 {
+			t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x053, v)
+		}
+		_, _, err = lr.Peek(2)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		b, err := lr.ReadBit()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if b != 1 || lr.BitPosition() != 10 || lr.ConsumedBytes() != 2 {
+			t.Fatalf("\nExpected: 1, 10, 2\nActual:   %+v, %+v, %+v\n", b, lr.BitPosition(), lr.ConsumedBytes())
+		}
+		_, err = lr.ReadBit()
+		if !errors.Is(err, io.EOF) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+		}
+
+		// the parent reads the same bits
+		u, err := r.ReadNBitsAsUint16BE(10)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if u != 0x0a7 {
+			t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x0a7, u)
+		}
+		r.Close()
+	}
+}
+
+func TestLookaheadSeekBit(t *testing.T) {
+	r := NewReaderBytes([]byte{0xa5, 0x3c}, nil)
+	r.Skip(1)
+	lr, err := r.Lookahead(12)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	err = lr.SeekBit(4)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	// 0100 1010 0111 1
+	//      ^^^^ ^^^^
+	v, err := lr.ReadUint8()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0xa7 {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0xa7, v)
+	}
+}
+
+func TestLookaheadEOF(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0xa5}), nil)
+	r.Skip(2)
+
+	_, err := r.Lookahead(7)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+	if r.BitPosition() != 2 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 2, r.BitPosition())
+	}
+
+	r.Skip(6)
+	_, err = r.Lookahead(1)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+}
@@ -0,0 +1,108 @@
+package bitstream
+
+// ReaderStats is a set of counters of a Reader.
+type ReaderStats struct {
+	BitsRead  uint64 // number of bits consumed from the bit stream
+	BytesRead uint64 // number of bytes read from the source
+	Refills   uint64 // number of times the buffer was refilled
+	ReadCalls uint64 // number of calls to the Read method of the source
+}
+
+// ReaderHooks is a set of optional callbacks which are called from the goroutine reading from the Reader.
+type ReaderHooks struct {
+	// OnRefill is called each time the Reader tries to refill the buffer,
+	// with the number of bytes read from the source and the number of calls to the Read method of the source it took.
+	OnRefill func(nBytes, readCalls int)
+}
+
+// GetHooks gets configured callbacks.
+func (opt *ReaderOptions) GetHooks() *ReaderHooks {
+	if opt == nil {
+		return nil
+	}
+	return opt.Hooks
+}
+
+// Stats returns the counters of the Reader.
+func (r *Reader) Stats() ReaderStats {
+	s := r.stats
+	s.BitsRead = r.BitPosition()
+	return s
+}
+
+func (r *Reader) countRefill(nBytes, readCalls int) {
+	if readCalls == 0 {
+		return
+	}
+	if nBytes > 0 {
+		r.stats.Refills++
+	}
+	r.stats.BytesRead += uint64(nBytes)
+	r.stats.ReadCalls += uint64(readCalls)
+	if r.logging() {
+		r.logDebug("bitstream: refill", r.BitPosition(), "bytes", nBytes, "read_calls", readCalls)
+	}
+
+	hooks := r.opt.GetHooks()
+	if hooks != nil && hooks.OnRefill != nil {
+		hooks.OnRefill(nBytes, readCalls)
+	}
+}
+
+// WriterStats is a set of counters of a Writer.
+type WriterStats struct {
+	BitsWritten  uint64 // number of bits written to the bit stream
+	BytesWritten uint64 // number of bytes written to the destination
+	Flushes      uint64 // number of calls to Flush, Finalize and Close
+	WriteCalls   uint64 // number of calls to the Write method of the destination
+}
+
+// WriterHooks is a set of optional callbacks which are called from the goroutine writing to the Writer.
+type WriterHooks struct {
+	// OnWrite is called after each call to the Write method of the destination with its results.
+	OnWrite func(nBytes int, err error)
+
+	// OnFlush is called each time Flush, Finalize or Close is called.
+	OnFlush func()
+}
+
+// SetHooks sets callbacks to observe the I/O of the Writer, overriding WriterOptions.Hooks.
+// Pass nil to remove them.
+func (w *Writer) SetHooks(hooks *WriterHooks) {
+	w.hooks = hooks
+}
+
+// Stats returns the counters of the Writer.
+func (w *Writer) Stats() WriterStats {
+	s := w.stats
+	s.BitsWritten = w.writtenBits
+	return s
+}
+
+// write writes `p` to the destination and counts it.
+func (w *Writer) write(p []byte) (int, error) {
+	start := w.stats.BytesWritten
+	n, err := w.dst.Write(p)
+	w.stats.WriteCalls++
+	if n > 0 {
+		w.stats.BytesWritten += uint64(n)
+	}
+	if w.logging() {
+		w.logDebug("bitstream: write", start*8, "bytes", n, "error", err)
+	}
+
+	if w.hooks != nil && w.hooks.OnWrite != nil {
+		w.hooks.OnWrite(n, err)
+	}
+	return n, err
+}
+
+func (w *Writer) countFlush() {
+	w.stats.Flushes++
+	if w.logging() {
+		w.logDebug("bitstream: flush", w.bitPosition(), "buffered", len(w.buf))
+	}
+	if w.hooks != nil && w.hooks.OnFlush != nil {
+		w.hooks.OnFlush()
+	}
+}
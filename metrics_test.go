@@ -0,0 +1,107 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReaderStats(t *testing.T) {
+	data := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd}
+
+	var refills [][2]int
+	r := NewReader(&stutteringReader{src: bytes.NewReader(data)}, &ReaderOptions{
+		BufferSize: 3,
+		Hooks: &ReaderHooks{
+			OnRefill: func(nBytes, readCalls int) {
+				refills = append(refills, [2]int{nBytes, readCalls})
+			},
+		},
+	})
+
+	_, err := r.ReadNBitsAsUint32BE(20)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	expected := ReaderStats{BitsRead: 20, BytesRead: 3, Refills: 1, ReadCalls: 2}
+	if !reflect.DeepEqual(expected, r.Stats()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, r.Stats())
+	}
+
+	_, _, err = r.ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	expected = ReaderStats{BitsRead: 56, BytesRead: 7, Refills: 3, ReadCalls: 8}
+	if !reflect.DeepEqual(expected, r.Stats()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, r.Stats())
+	}
+
+	// the last refill hits the end of the stream
+	expectedRefills := [][2]int{{3, 2}, {3, 2}, {1, 2}, {0, 2}}
+	if !reflect.DeepEqual(expectedRefills, refills) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expectedRefills, refills)
+	}
+}
+
+func TestWriterStats(t *testing.T) {
+	var writes []int
+	flushes := 0
+	w := NewWriter(&bytes.Buffer{})
+	w.SetHooks(&WriterHooks{
+		OnWrite: func(nBytes int, err error) {
+			writes = append(writes, nBytes)
+		},
+		OnFlush: func() {
+			flushes++
+		},
+	})
+
+	w.WriteNBitsOfUint16BE(12, 0xabc)
+	w.WriteRun(1, 28)
+	w.Flush()
+
+	expected := WriterStats{BitsWritten: 40, BytesWritten: 5, Flushes: 1, WriteCalls: 3}
+	if !reflect.DeepEqual(expected, w.Stats()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, w.Stats())
+	}
+	if !reflect.DeepEqual([]int{1, 1, 3}, writes) || flushes != 1 {
+		t.Fatalf("\nunexpected calls to the hooks: %+v, %d\n", writes, flushes)
+	}
+}
+
+// errWriter always fails.
+type errWriter struct {
+	err error
+}
+
+func (ew errWriter) Write(p []byte) (int, error) {
+	return 0, ew.err
+}
+
+func TestWriterStatsError(t *testing.T) {
+	var errs []error
+	w := NewWriter(errWriter{err: io.ErrClosedPipe})
+	w.SetHooks(&WriterHooks{
+		OnWrite: func(nBytes int, err error) {
+			errs = append(errs, err)
+		},
+	})
+
+	err := w.WriteUint8(0x01)
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrClosedPipe, err)
+	}
+
+	expected := WriterStats{BitsWritten: 8, BytesWritten: 0, Flushes: 0, WriteCalls: 1}
+	if !reflect.DeepEqual(expected, w.Stats()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, w.Stats())
+	}
+	if !reflect.DeepEqual([]error{io.ErrClosedPipe}, errs) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []error{io.ErrClosedPipe}, errs)
+	}
+}
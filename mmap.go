@@ -0,0 +1,47 @@
+package bitstream
+
+import (
+	"fmt"
+	"math"
+	"os"
+)
+
+// NewReaderBytes creates a new Reader which reads `data` in place, without copying it into a buffer.
+// `data` must not be modified while the Reader is in use. BufferSize and Prefetch of `opt` are ignored.
+// The Reader supports SeekBit for random access.
+func NewReaderBytes(data []byte, opt *ReaderOptions) *Reader {
+	r := NewReader(nil, opt)
+	r.buf = data
+	r.bufLen = uint(len(data))
+	r.srcEOF = true
+	r.inMemory = true
+	return r
+}
+
+// NewReaderMmap creates a new Reader which reads the file at `path` mapped into memory (on Unix-like systems;
+// the file is read into memory on the other systems).
+// Large files can be parsed with random access by SeekBit without copying them through the buffer.
+// The Reader must be closed to unmap the file.
+func NewReaderMmap(path string, opt *ReaderOptions) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // the mapping remains valid after the file is closed
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() > math.MaxInt {
+		return nil, fmt.Errorf("bitstream: %s is too large to be mapped: %d bytes", path, fi.Size())
+	}
+
+	data, release, err := mmapFile(f, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+	r := NewReaderBytes(data, opt)
+	r.release = release
+	return r, nil
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package bitstream
+
+import (
+	"io"
+	"os"
+)
+
+// mmapFile reads the first `size` bytes of `f` into memory since memory mapping is not supported on this system.
+func mmapFile(f *os.File, size int) ([]byte, func() error, error) {
+	data := make([]byte, size)
+	_, err := io.ReadFull(f, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,123 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewReaderMmap(t *testing.T) {
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = uint8(i * 37)
+	}
+	path := filepath.Join(t.TempDir(), "data.bin")
+	err := os.WriteFile(path, data, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	r, err := NewReaderMmap(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	for _, i := range []uint64{9999, 0, 5000, 1234} {
+		err := r.SeekBit(i*8 + 4)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		v, err := r.ReadNBitsAsUint8(4)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if data[i]&0x0f != v || i*8+8 != r.BitPosition() {
+			t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", data[i]&0x0f, i*8+8, v, r.BitPosition())
+		}
+	}
+
+	err = r.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, err = r.ReadBit()
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrClosed, err)
+	}
+
+	// an empty file
+	empty := filepath.Join(t.TempDir(), "empty.bin")
+	err = os.WriteFile(empty, nil, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	r, err = NewReaderMmap(empty, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, err = r.ReadBit()
+	if err != io.EOF {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+	r.Close()
+}
+
+func TestSeekBit(t *testing.T) {
+	// 0001 0010  0011 0100  0101 0110  0111 1000
+	data := []byte{0x12, 0x34, 0x56, 0x78}
+
+	testData := []struct {
+		Name     string
+		Reader   func() *Reader
+		Offset   uint64
+		Expected uint16
+	}{
+		{Name: "pattern 1", Reader: func() *Reader { return NewReaderBytes(data, nil) }, Offset: 4, Expected: 0x234},
+		{Name: "pattern 2", Reader: func() *Reader { return NewReaderBytes(data, nil) }, Offset: 19, Expected: 0xb3c},
+		{Name: "pattern 3", Reader: func() *Reader { return NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: 1}) }, Offset: 19, Expected: 0xb3c},
+		{Name: "pattern 4", Reader: func() *Reader { return NewReader(bytes.NewReader(data), nil) }, Offset: 8, Expected: 0x345},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := data.Reader()
+			r.ReadNBitsAsUint8(7)
+			err := r.SeekBit(data.Offset)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			v, err := r.ReadNBitsAsUint16BE(12)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.Expected != v {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, v)
+			}
+			if data.Offset+12 != r.BitPosition() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Offset+12, r.BitPosition())
+			}
+		})
+	}
+}
+
+func TestSeekBitError(t *testing.T) {
+	r := NewReader(io.LimitReader(bytes.NewReader([]byte{0x12}), 1), nil)
+	err := r.SeekBit(3)
+	if !errors.Is(err, ErrNotSeekable) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNotSeekable, err)
+	}
+
+	// beyond the end
+	r = NewReaderBytes([]byte{0x12}, nil)
+	err = r.SeekBit(13)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, err = r.ReadBit()
+	if err != io.EOF {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package bitstream
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the first `size` bytes of `f` into memory read-only, and returns a function to unmap it.
+func mmapFile(f *os.File, size int) ([]byte, func() error, error) {
+	if size == 0 {
+		// mmap fails for an empty file
+		return []byte{}, func() error { return nil }, nil
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "mmap", Path: f.Name(), Err: err}
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,153 @@
+package bitstream
+
+import "fmt"
+
+// Interleave2 interleaves the bits of `x` and `y` into a Morton code (Z-order curve):
+// bit i of `x` becomes bit 2i of the code and bit i of `y` becomes bit 2i+1.
+func Interleave2(x, y uint32) uint64 {
+	return spread2(x) | spread2(y)<<1
+}
+
+// Deinterleave2 splits the Morton code `m` made by Interleave2 into `x` and `y`.
+func Deinterleave2(m uint64) (x, y uint32) {
+	return compact2(m), compact2(m >> 1)
+}
+
+// Interleave3 interleaves the lower 21 bits of `x`, `y` and `z` into a Morton code:
+// bit i of `x`, `y` and `z` becomes bit 3i, 3i+1 and 3i+2 of the code respectively. The upper bits are ignored.
+func Interleave3(x, y, z uint32) uint64 {
+	return spread3(x) | spread3(y)<<1 | spread3(z)<<2
+}
+
+// Deinterleave3 splits the Morton code `m` made by Interleave3 into `x`, `y` and `z`.
+func Deinterleave3(m uint64) (x, y, z uint32) {
+	return compact3(m), compact3(m >> 1), compact3(m >> 2)
+}
+
+// spread2 inserts a '0' bit above each bit of `v`.
+func spread2(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000ffff0000ffff
+	x = (x | x<<8) & 0x00ff00ff00ff00ff
+	x = (x | x<<4) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+// compact2 is the inverse of spread2; the odd bits of `x` are ignored.
+func compact2(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0f0f0f0f0f0f0f0f
+	x = (x | x>>4) & 0x00ff00ff00ff00ff
+	x = (x | x>>8) & 0x0000ffff0000ffff
+	x = (x | x>>16) & 0x00000000ffffffff
+	return uint32(x)
+}
+
+// spread3 inserts two '0' bits above each of the lower 21 bits of `v`.
+func spread3(v uint32) uint64 {
+	x := uint64(v) & 0x1fffff
+	x = (x | x<<32) & 0x001f00000000ffff
+	x = (x | x<<16) & 0x001f0000ff0000ff
+	x = (x | x<<8) & 0x100f00f00f00f00f
+	x = (x | x<<4) & 0x10c30c30c30c30c3
+	x = (x | x<<2) & 0x1249249249249249
+	return x
+}
+
+// compact3 is the inverse of spread3; the bits other than every third bit of `x` are ignored.
+func compact3(x uint64) uint32 {
+	x &= 0x1249249249249249
+	x = (x | x>>2) & 0x10c30c30c30c30c3
+	x = (x | x>>4) & 0x100f00f00f00f00f
+	x = (x | x>>8) & 0x001f0000ff0000ff
+	x = (x | x>>16) & 0x001f00000000ffff
+	x = (x | x>>32) & 0x00000000001fffff
+	return uint32(x)
+}
+
+// ReadMorton2 reads a Morton code of `nBits` * 2 bits from the bit stream and returns the coordinates of `nBits` bits each.
+// `nBits` must be less than or equal to 32, otherwise returns an error.
+func (r *Reader) ReadMorton2(nBits uint8) (x, y uint32, err error) {
+	pos := r.BitPosition()
+	if nBits > 32 {
+		return 0, 0, r.wrapError("ReadMorton2", pos, fmt.Errorf("%w for 2D Morton code", ErrTooManyBits))
+	}
+	m, err := r.readUint(nBits*2, 64)
+	if err != nil {
+		return 0, 0, r.wrapError("ReadMorton2", pos, err)
+	}
+	if r.done(pos) {
+		r.trace("", pos, uint(nBits)*2, m)
+	}
+	x, y = Deinterleave2(m)
+	return x, y, nil
+}
+
+// ReadMorton3 reads a Morton code of `nBits` * 3 bits from the bit stream and returns the coordinates of `nBits` bits each.
+// `nBits` must be less than or equal to 21, otherwise returns an error.
+func (r *Reader) ReadMorton3(nBits uint8) (x, y, z uint32, err error) {
+	pos := r.BitPosition()
+	if nBits > 21 {
+		return 0, 0, 0, r.wrapError("ReadMorton3", pos, fmt.Errorf("%w for 3D Morton code", ErrTooManyBits))
+	}
+	m, err := r.readUint(nBits*3, 64)
+	if err != nil {
+		return 0, 0, 0, r.wrapError("ReadMorton3", pos, err)
+	}
+	if r.done(pos) {
+		r.trace("", pos, uint(nBits)*3, m)
+	}
+	x, y, z = Deinterleave3(m)
+	return x, y, z, nil
+}
+
+// WriteMorton2 writes the lower `nBits` bits of `x` and `y` to the bit stream as a Morton code of `nBits` * 2 bits.
+// `nBits` must be less than or equal to 32, otherwise returns an error.
+func (w *Writer) WriteMorton2(nBits uint8, x, y uint32) error {
+	pos := w.bitPosition()
+	err := w.writeMorton(nBits, 32, "2D", x, y)
+	if err != nil {
+		return w.wrapError("WriteMorton2", pos, err)
+	}
+	if w.done(pos) {
+		w.trace("", pos, uint(nBits)*2, maskBits(nBits*2, Interleave2(x, y)))
+	}
+	return nil
+}
+
+// WriteMorton3 writes the lower `nBits` bits of `x`, `y` and `z` to the bit stream as a Morton code of `nBits` * 3 bits.
+// `nBits` must be less than or equal to 21, otherwise returns an error.
+func (w *Writer) WriteMorton3(nBits uint8, x, y, z uint32) error {
+	pos := w.bitPosition()
+	err := w.writeMorton(nBits, 21, "3D", x, y, z)
+	if err != nil {
+		return w.wrapError("WriteMorton3", pos, err)
+	}
+	if w.done(pos) {
+		w.trace("", pos, uint(nBits)*3, maskBits(nBits*3, Interleave3(x, y, z)))
+	}
+	return nil
+}
+
+func (w *Writer) writeMorton(nBits, maxBits uint8, kind string, coords ...uint32) error {
+	if nBits > maxBits {
+		return fmt.Errorf("%w for %s Morton code", ErrTooManyBits, kind)
+	}
+	for _, c := range coords {
+		err := w.checkRange(nBits, uint64(c))
+		if err != nil {
+			return err
+		}
+	}
+	var m uint64
+	if len(coords) == 2 {
+		m = Interleave2(coords[0], coords[1])
+	} else {
+		m = Interleave3(coords[0], coords[1], coords[2])
+	}
+	n := nBits * uint8(len(coords))
+	return writeBits(w, maskBits(n, m), n)
+}
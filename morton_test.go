@@ -0,0 +1,136 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestInterleave(t *testing.T) {
+	testData := []struct {
+		Name     string
+		X, Y, Z  uint32
+		Is3D     bool
+		Expected uint64
+	}{
+		{Name: "pattern 1", X: 0x3, Y: 0x1, Expected: 0x7},                                        // y1 x1 y0 x0 = 0 1 1 1
+		{Name: "pattern 2", X: 0xffffffff, Y: 0, Expected: 0x5555555555555555},                    // all bits of x
+		{Name: "pattern 3", X: 0, Y: 0xffffffff, Expected: 0xaaaaaaaaaaaaaaaa},                    // all bits of y
+		{Name: "pattern 4", X: 0x1, Y: 0x2, Z: 0x4, Is3D: true, Expected: 0x111},                  // x0 -> bit 0, y1 -> bit 4, z2 -> bit 8
+		{Name: "pattern 5", X: 0x1fffff, Is3D: true, Expected: 0x1249249249249249},                // all bits of x
+		{Name: "pattern 6", X: 0xffe00000, Y: 0xffe00000, Z: 0xffe00000, Is3D: true, Expected: 0}, // upper bits are ignored
+		{Name: "pattern 7", X: 0x12345, Y: 0x1abcde, Z: 0x0f0f0f, Is3D: true, Expected: Interleave3(0x12345, 0x1abcde, 0x0f0f0f)},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			if data.Is3D {
+				actual := Interleave3(data.X, data.Y, data.Z)
+				if data.Expected != actual {
+					t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Expected, actual)
+				}
+				x, y, z := Deinterleave3(actual)
+				if x != data.X&0x1fffff || y != data.Y&0x1fffff || z != data.Z&0x1fffff {
+					t.Fatalf("\nExpected: %#x, %#x, %#x\nActual:   %#x, %#x, %#x\n", data.X&0x1fffff, data.Y&0x1fffff, data.Z&0x1fffff, x, y, z)
+				}
+				return
+			}
+			actual := Interleave2(data.X, data.Y)
+			if data.Expected != actual {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Expected, actual)
+			}
+			x, y := Deinterleave2(actual)
+			if x != data.X || y != data.Y {
+				t.Fatalf("\nExpected: %#x, %#x\nActual:   %#x, %#x\n", data.X, data.Y, x, y)
+			}
+		})
+	}
+}
+
+func TestWriteMorton(t *testing.T) {
+	testData := []struct {
+		Name     string
+		NBits    uint8
+		Coords   []uint32
+		Expected []byte
+	}{
+		{Name: "pattern 1", NBits: 4, Coords: []uint32{0xa, 0x6}, Expected: []byte{0x6c}},                        // y3x3 y2x2 y1x1 y0x0 = 01 10 11 00
+		{Name: "pattern 2", NBits: 2, Coords: []uint32{0x1, 0x2, 0x3}, Expected: []byte{0xd4}},                   // z1y1x1 z0y0x0 = 110 101 (00)
+		{Name: "pattern 3", NBits: 32, Coords: []uint32{0xffffffff, 0}, Expected: bytes.Repeat([]byte{0x55}, 8)}, // 0101 0101 ...
+		{Name: "pattern 4", NBits: 0, Coords: []uint32{0x1, 0x1}, Expected: []byte{}},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			w := NewWriter(buf)
+			var err error
+			if len(data.Coords) == 2 {
+				err = w.WriteMorton2(data.NBits, data.Coords[0], data.Coords[1])
+			} else {
+				err = w.WriteMorton3(data.NBits, data.Coords[0], data.Coords[1], data.Coords[2])
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			err = w.Finalize()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !bytes.Equal(data.Expected, buf.Bytes()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, buf.Bytes())
+			}
+
+			r := NewReader(bytes.NewReader(buf.Bytes()), nil)
+			var actual []uint32
+			if len(data.Coords) == 2 {
+				x, y, err := r.ReadMorton2(data.NBits)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				actual = []uint32{x, y}
+			} else {
+				x, y, z, err := r.ReadMorton3(data.NBits)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				actual = []uint32{x, y, z}
+			}
+			for i, c := range data.Coords {
+				if expected := uint32(maskBits(data.NBits, uint64(c))); actual[i] != expected {
+					t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, actual[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMortonError(t *testing.T) {
+	w := NewWriter(bytes.NewBuffer([]byte{}))
+	err := w.WriteMorton2(33, 0, 0)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+	err = w.WriteMorton3(22, 0, 0, 0)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+
+	w = NewWriterWithOptions(bytes.NewBuffer([]byte{}), &WriterOptions{StrictValues: true})
+	err = w.WriteMorton3(4, 0x1, 0x10, 0x1)
+	if !errors.Is(err, ErrValueOutOfRange) || w.WrittenBits() != 0 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", ErrValueOutOfRange, 0, err, w.WrittenBits())
+	}
+
+	r := NewReader(bytes.NewReader([]byte{0xff}), nil)
+	_, _, err = r.ReadMorton2(33)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+	_, _, _, err = r.ReadMorton3(22)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+}
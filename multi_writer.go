@@ -0,0 +1,135 @@
+package bitstream
+
+// MultiWriter is a bit stream writer which duplicates its writes to all the Writers, like io.MultiWriter.
+// Each write is passed to the Writers in order; if one of them returns an error, the write stops and the error is returned.
+type MultiWriter struct {
+	ws []*Writer
+}
+
+// MultiBitWriter creates a new MultiWriter instance which duplicates its writes to all of `ws`.
+func MultiBitWriter(ws ...*Writer) *MultiWriter {
+	return &MultiWriter{
+		ws: append([]*Writer{}, ws...),
+	}
+}
+
+// Writers returns the underlying Writers.
+func (mw *MultiWriter) Writers() []*Writer {
+	return mw.ws
+}
+
+// each calls `f` for each Writer until it returns an error.
+func (mw *MultiWriter) each(f func(w *Writer) error) error {
+	for _, w := range mw.ws {
+		err := f(w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteBit writes a single bit to the bit streams.
+func (mw *MultiWriter) WriteBit(bit uint8) error {
+	return mw.each(func(w *Writer) error { return w.WriteBit(bit) })
+}
+
+// WriteBool writes a single bit to the bit streams. (true: 1, false: 0)
+func (mw *MultiWriter) WriteBool(b bool) error {
+	return mw.each(func(w *Writer) error { return w.WriteBool(b) })
+}
+
+// WriteRun writes `n` copies of a bit (the LSB of `bit`) to the bit streams.
+func (mw *MultiWriter) WriteRun(bit uint8, n uint64) error {
+	return mw.each(func(w *Writer) error { return w.WriteRun(bit, n) })
+}
+
+// WriteRuns decodes the run-length encoded bits and writes them to the bit streams.
+func (mw *MultiWriter) WriteRuns(runs []Run) error {
+	return mw.each(func(w *Writer) error { return w.WriteRuns(runs) })
+}
+
+// WriteNBitsOfUint8 writes `nBits` bits of `val` (LSB aligned) to the bit streams.
+func (mw *MultiWriter) WriteNBitsOfUint8(nBits, val uint8) error {
+	return mw.each(func(w *Writer) error { return w.WriteNBitsOfUint8(nBits, val) })
+}
+
+// WriteUint8 writes a uint8 value to the bit streams.
+func (mw *MultiWriter) WriteUint8(val uint8) error {
+	return mw.WriteNBitsOfUint8(8, val)
+}
+
+// WriteNBitsOfUint16BE writes `nBits` bits of `val` (LSB aligned) to the bit streams.
+func (mw *MultiWriter) WriteNBitsOfUint16BE(nBits uint8, val uint16) error {
+	return mw.each(func(w *Writer) error { return w.WriteNBitsOfUint16BE(nBits, val) })
+}
+
+// WriteUint16BE writes a uint16 value to the bit streams.
+func (mw *MultiWriter) WriteUint16BE(val uint16) error {
+	return mw.WriteNBitsOfUint16BE(16, val)
+}
+
+// WriteNBitsOfUint32BE writes `nBits` bits of `val` (LSB aligned) to the bit streams.
+func (mw *MultiWriter) WriteNBitsOfUint32BE(nBits uint8, val uint32) error {
+	return mw.each(func(w *Writer) error { return w.WriteNBitsOfUint32BE(nBits, val) })
+}
+
+// WriteUint32BE writes a uint32 value to the bit streams.
+func (mw *MultiWriter) WriteUint32BE(val uint32) error {
+	return mw.WriteNBitsOfUint32BE(32, val)
+}
+
+// WriteNBitsOfUint64BE writes `nBits` bits of `val` (LSB aligned) to the bit streams.
+func (mw *MultiWriter) WriteNBitsOfUint64BE(nBits uint8, val uint64) error {
+	return mw.each(func(w *Writer) error { return w.WriteNBitsOfUint64BE(nBits, val) })
+}
+
+// WriteUint64BE writes a uint64 value to the bit streams.
+func (mw *MultiWriter) WriteUint64BE(val uint64) error {
+	return mw.WriteNBitsOfUint64BE(64, val)
+}
+
+// WriteNBits writes `nBits` bits of `data` to the bit streams.
+func (mw *MultiWriter) WriteNBits(nBits uint, data []byte) error {
+	return mw.each(func(w *Writer) error { return w.WriteNBits(nBits, data) })
+}
+
+// WriteBytes writes all the bytes in `p` to the bit streams.
+func (mw *MultiWriter) WriteBytes(p []byte) error {
+	return mw.each(func(w *Writer) error { return w.WriteBytes(p) })
+}
+
+// WriteString writes all the bytes in `s` to the bit streams.
+func (mw *MultiWriter) WriteString(s string) error {
+	return mw.each(func(w *Writer) error { return w.WriteString(s) })
+}
+
+// AlignByte pads the current byte of each bit stream with `padBit` up to the byte boundary.
+// It returns the number of pad bits written to the first Writer.
+func (mw *MultiWriter) AlignByte(padBit uint8) (uint8, error) {
+	var padded []uint8
+	err := mw.each(func(w *Writer) error {
+		n, err := w.AlignByte(padBit)
+		padded = append(padded, n)
+		return err
+	})
+	if err != nil || len(padded) == 0 {
+		return 0, err
+	}
+	return padded[0], nil
+}
+
+// Flush writes the complete bytes of each Writer to its destination.
+func (mw *MultiWriter) Flush() error {
+	return mw.each((*Writer).Flush)
+}
+
+// Finalize ends each bit stream. See Writer.Finalize.
+func (mw *MultiWriter) Finalize() error {
+	return mw.each((*Writer).Finalize)
+}
+
+// Close finalizes each bit stream and closes its destination. See Writer.Close.
+func (mw *MultiWriter) Close() error {
+	return mw.each((*Writer).Close)
+}
@@ -0,0 +1,57 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestMultiBitWriter(t *testing.T) {
+	buf1 := bytes.NewBuffer([]byte{})
+	buf2 := bytes.NewBuffer([]byte{})
+	mw := MultiBitWriter(NewWriter(buf1), NewWriterWithOptions(buf2, nil))
+
+	mw.WriteNBitsOfUint8(4, 0x0a)
+	mw.WriteBool(true)
+	mw.WriteRun(0, 3)
+	mw.WriteUint16BE(0x1234)
+	mw.WriteString("a")
+	mw.WriteUint64BE(0x0123456789abcdef)
+	mw.WriteNBitsOfUint64BE(4, 0x0c)
+	padded, err := mw.AlignByte(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if padded != 4 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 4, padded)
+	}
+	mw.WriteNBitsOfUint8(3, 0x05)
+	err = mw.Finalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	// 1010 1000 | 0001 0010 | 0011 0100 | 0110 0001 | 0x0123456789abcdef | 1100 1111 | 1010 0000
+	expected := []byte{0xa8, 0x12, 0x34, 0x61, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0xcf, 0xa0}
+	for _, buf := range []*bytes.Buffer{buf1, buf2} {
+		if !bytes.Equal(expected, buf.Bytes()) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, buf.Bytes())
+		}
+	}
+}
+
+func TestMultiBitWriterError(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	w := NewWriter(buf)
+	mw := MultiBitWriter(NewWriter(errWriter{err: io.ErrClosedPipe}), w)
+
+	err := mw.WriteUint8(0xff)
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrClosedPipe, err)
+	}
+	// the write stops at the first error
+	if w.WrittenBits() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, w.WrittenBits())
+	}
+}
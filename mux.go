@@ -0,0 +1,139 @@
+package bitstream
+
+import (
+	"fmt"
+	"io"
+)
+
+// MuxSlot is a slot of a multiplexing schedule: `NBits` bits are taken from the input at the index `Input`.
+// For Demux, `Input` is the index of the output the bits are routed to.
+type MuxSlot struct {
+	Input int
+	NBits uint64
+}
+
+// MuxSchedule returns the `n`-th slot (starting from 0) of a multiplexing schedule.
+// Any function can be used as a schedule, e.g. one which looks at the data already written to build a frame.
+type MuxSchedule func(n uint64) MuxSlot
+
+// RoundRobin returns a schedule which takes `nBits` bits from each of `nInputs` inputs in turn.
+func RoundRobin(nInputs int, nBits uint64) MuxSchedule {
+	return func(n uint64) MuxSlot {
+		return MuxSlot{Input: int(n % uint64(nInputs)), NBits: nBits}
+	}
+}
+
+// CyclicSchedule returns a schedule which repeats `slots`, e.g. a fixed pattern of fields in a TDM frame.
+func CyclicSchedule(slots ...MuxSlot) MuxSchedule {
+	slots = append([]MuxSlot{}, slots...)
+	return func(n uint64) MuxSlot {
+		return slots[n%uint64(len(slots))]
+	}
+}
+
+// Mux interleaves the bits from multiple Readers into one Writer according to a schedule.
+type Mux struct {
+	dst      *Writer
+	srcs     []*Reader
+	schedule MuxSchedule
+	n        uint64 // number of slots written
+}
+
+// NewMux creates a new Mux instance which writes the bits taken from `srcs` to `dst` according to `schedule`.
+func NewMux(dst *Writer, schedule MuxSchedule, srcs ...*Reader) *Mux {
+	return &Mux{
+		dst:      dst,
+		srcs:     append([]*Reader{}, srcs...),
+		schedule: schedule,
+	}
+}
+
+// Slots returns the number of slots written so far.
+func (m *Mux) Slots() uint64 {
+	return m.n
+}
+
+// Step writes the next slot of the schedule.
+// It returns io.EOF if the input of the slot has no bits left, and io.ErrUnexpectedEOF if it ends in the middle of the slot.
+// It returns ErrValueOutOfRange if the slot refers to an input which does not exist.
+func (m *Mux) Step() error {
+	slot := m.schedule(m.n)
+	if slot.Input < 0 || slot.Input >= len(m.srcs) {
+		return fmt.Errorf("%w: slot %d refers to input %d of %d inputs", ErrValueOutOfRange, m.n, slot.Input, len(m.srcs))
+	}
+	_, err := CopyBits(m.dst, m.srcs[slot.Input], slot.NBits)
+	if err != nil {
+		return err
+	}
+	m.n++
+	return nil
+}
+
+// Run writes the slots of the schedule until the input of a slot has no bits left, and returns the number of slots written.
+// The end of an input at a slot boundary is not an error.
+func (m *Mux) Run() (uint64, error) {
+	start := m.n
+	for {
+		err := m.Step()
+		if err == io.EOF {
+			return m.n - start, nil
+		}
+		if err != nil {
+			return m.n - start, err
+		}
+	}
+}
+
+// Demux routes the bits from one Reader to multiple Writers according to a schedule. It is the inverse of Mux.
+type Demux struct {
+	src      *Reader
+	dsts     []*Writer
+	schedule MuxSchedule
+	n        uint64 // number of slots read
+}
+
+// NewDemux creates a new Demux instance which routes the bits read from `src` to `dsts` according to `schedule`,
+// where MuxSlot.Input is the index of the output.
+func NewDemux(src *Reader, schedule MuxSchedule, dsts ...*Writer) *Demux {
+	return &Demux{
+		src:      src,
+		dsts:     append([]*Writer{}, dsts...),
+		schedule: schedule,
+	}
+}
+
+// Slots returns the number of slots read so far.
+func (d *Demux) Slots() uint64 {
+	return d.n
+}
+
+// Step routes the next slot of the schedule.
+// It returns io.EOF if the input has no bits left, and io.ErrUnexpectedEOF if it ends in the middle of the slot.
+// It returns ErrValueOutOfRange if the slot refers to an output which does not exist.
+func (d *Demux) Step() error {
+	slot := d.schedule(d.n)
+	if slot.Input < 0 || slot.Input >= len(d.dsts) {
+		return fmt.Errorf("%w: slot %d refers to output %d of %d outputs", ErrValueOutOfRange, d.n, slot.Input, len(d.dsts))
+	}
+	_, err := CopyBits(d.dsts[slot.Input], d.src, slot.NBits)
+	if err != nil {
+		return err
+	}
+	d.n++
+	return nil
+}
+
+// Run routes the slots of the schedule until the input has no bits left, and returns the number of slots read.
+// The end of the input at a slot boundary is not an error.
+func (d *Demux) Run() (uint64, error) {
+	start := d.n
+	for {
+		err := d.Step()
+		if err == io.EOF {
+			return d.n - start, nil
+		}
+		if err != nil {
+			return d.n - start, err
+		}
+	}
+}
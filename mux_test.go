@@ -0,0 +1,100 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestMuxRoundRobin(t *testing.T) {
+	a := NewReader(bytes.NewReader([]byte{0xff}), nil)
+	b := NewReader(bytes.NewReader([]byte{0x00}), nil)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	m := NewMux(w, RoundRobin(2, 1), a, b)
+	n, err := m.Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Close()
+
+	if n != 16 || m.Slots() != 16 {
+		t.Fatalf("\nExpected: 16, 16\nActual:   %+v, %+v\n", n, m.Slots())
+	}
+	expected := []byte{0xaa, 0xaa}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, buf.Bytes())
+	}
+}
+
+func TestMuxCyclic(t *testing.T) {
+	// a frame of a 4-bit header from the first input and 12 bits from the second one
+	hdr := NewReader(bytes.NewReader([]byte{0x5a}), nil)
+	payload := NewReader(bytes.NewReader([]byte{0x12, 0x34, 0x56}), nil)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	m := NewMux(w, CyclicSchedule(MuxSlot{Input: 0, NBits: 4}, MuxSlot{Input: 1, NBits: 12}), hdr, payload)
+	_, err := m.Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Close()
+
+	expected := []byte{0x51, 0x23, 0xa4, 0x56}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, buf.Bytes())
+	}
+}
+
+func TestMuxErrors(t *testing.T) {
+	a := NewReader(bytes.NewReader([]byte{0xff}), nil)
+	m := NewMux(NewWriter(&bytes.Buffer{}), func(n uint64) MuxSlot { return MuxSlot{Input: int(n), NBits: 3} }, a)
+	_, err := m.Run()
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+	}
+
+	a = NewReader(bytes.NewReader([]byte{0xff}), nil)
+	m = NewMux(NewWriter(&bytes.Buffer{}), RoundRobin(1, 3), a)
+	n, err := m.Run()
+	if !errors.Is(err, io.ErrUnexpectedEOF) || n != 2 {
+		t.Fatalf("\nExpected: 2, %+v\nActual:   %+v, %+v\n", io.ErrUnexpectedEOF, n, err)
+	}
+}
+
+func TestDemux(t *testing.T) {
+	src := NewReader(bytes.NewReader([]byte{0x51, 0x23, 0xa4, 0x56}), nil)
+
+	var hdr, payload bytes.Buffer
+	wh := NewWriter(&hdr)
+	wp := NewWriter(&payload)
+	d := NewDemux(src, CyclicSchedule(MuxSlot{Input: 0, NBits: 4}, MuxSlot{Input: 1, NBits: 12}), wh, wp)
+	n, err := d.Run()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	wh.Close()
+	wp.Close()
+
+	if n != 4 || d.Slots() != 4 {
+		t.Fatalf("\nExpected: 4, 4\nActual:   %+v, %+v\n", n, d.Slots())
+	}
+	if !bytes.Equal(hdr.Bytes(), []byte{0x5a}) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", []byte{0x5a}, hdr.Bytes())
+	}
+	if !bytes.Equal(payload.Bytes(), []byte{0x12, 0x34, 0x56}) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", []byte{0x12, 0x34, 0x56}, payload.Bytes())
+	}
+}
+
+func TestDemuxErrors(t *testing.T) {
+	src := NewReader(bytes.NewReader([]byte{0xff}), nil)
+	d := NewDemux(src, RoundRobin(3, 1), NewWriter(&bytes.Buffer{}))
+	_, err := d.Run()
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+	}
+}
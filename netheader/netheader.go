@@ -0,0 +1,395 @@
+// Package netheader implements codecs for the IPv4, TCP and UDP headers and the IEEE 802.1Q VLAN tag on top of
+// bitstream.Writer / bitstream.Reader.
+//
+// The headers are read and written field by field with ReadNamed / WriteNamed, e.g. "ipv4.ihl" or "tcp.flags",
+// so a decode or encode log can be annotated with the field names by the trace hook. The fields which are not aligned
+// to bytes, such as version/IHL, DSCP/ECN and flags/fragment offset of IPv4, are exposed as separate fields.
+//
+// The length fields which are derived from the options (IHL of IPv4 and data offset of TCP) are computed on Write,
+// while the other fields, including the checksums, are written as they are.
+package netheader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+// ErrInvalidHeader is returned when a header has a field which is not valid for the protocol,
+// e.g. a version other than 4 in an IPv4 header, or when a field to be written does not fit in its width.
+var ErrInvalidHeader = errors.New("netheader: invalid header")
+
+// IPv4 flags.
+const (
+	IPv4FlagDontFragment  uint8 = 0x2
+	IPv4FlagMoreFragments uint8 = 0x1
+)
+
+// IPv4 is an IPv4 header (RFC 791).
+type IPv4 struct {
+	DSCP           uint8  // 6 bits
+	ECN            uint8  // 2 bits
+	TotalLength    uint16 // length of the datagram including the header, in bytes
+	ID             uint16
+	Flags          uint8  // 3 bits: reserved, DF and MF
+	FragmentOffset uint16 // 13 bits, in units of 8 bytes
+	TTL            uint8
+	Protocol       uint8
+	Checksum       uint16
+	Src            netip.Addr
+	Dst            netip.Addr
+	Options        []byte // a multiple of 4 bytes, up to 40 bytes
+}
+
+// HeaderLength returns the length of the header in bytes, i.e. IHL * 4.
+func (h *IPv4) HeaderLength() int {
+	return 20 + len(h.Options)
+}
+
+// ReadIPv4 reads an IPv4 header including the options from `r`.
+// It returns ErrInvalidHeader if the version is not 4 or IHL is less than 5.
+func ReadIPv4(r *bitstream.Reader) (*IPv4, error) {
+	fr := newFieldReader(r, "ipv4")
+	h := &IPv4{}
+	version := fr.uint("version", 4)
+	ihl := fr.uint("ihl", 4)
+	if fr.err == nil && version != 4 {
+		return nil, fmt.Errorf("%w: IPv4 version %d", ErrInvalidHeader, version)
+	}
+	if fr.err == nil && ihl < 5 {
+		return nil, fmt.Errorf("%w: IPv4 IHL %d", ErrInvalidHeader, ihl)
+	}
+	h.DSCP = uint8(fr.uint("dscp", 6))
+	h.ECN = uint8(fr.uint("ecn", 2))
+	h.TotalLength = uint16(fr.uint("total_length", 16))
+	h.ID = uint16(fr.uint("id", 16))
+	h.Flags = uint8(fr.uint("flags", 3))
+	h.FragmentOffset = uint16(fr.uint("fragment_offset", 13))
+	h.TTL = uint8(fr.uint("ttl", 8))
+	h.Protocol = uint8(fr.uint("protocol", 8))
+	h.Checksum = uint16(fr.uint("checksum", 16))
+	h.Src = fr.addr4("src")
+	h.Dst = fr.addr4("dst")
+	if ihl > 5 {
+		h.Options = fr.bytes("options", uint(ihl-5)*4)
+	}
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	return h, nil
+}
+
+// Write writes the header to `w`. IHL is computed from the length of the options.
+// It returns ErrInvalidHeader if a field does not fit in its width, the addresses are not IPv4 addresses,
+// or the options are not a multiple of 4 bytes up to 40 bytes.
+func (h *IPv4) Write(w *bitstream.Writer) error {
+	switch {
+	case h.DSCP > 0x3f || h.ECN > 0x3 || h.Flags > 0x7 || h.FragmentOffset > 0x1fff:
+		return fmt.Errorf("%w: IPv4 DSCP %d, ECN %d, flags %d, fragment offset %d", ErrInvalidHeader, h.DSCP, h.ECN, h.Flags, h.FragmentOffset)
+	case !h.Src.Is4() || !h.Dst.Is4():
+		return fmt.Errorf("%w: IPv4 addresses %v, %v", ErrInvalidHeader, h.Src, h.Dst)
+	case len(h.Options)%4 != 0 || len(h.Options) > 40:
+		return fmt.Errorf("%w: IPv4 options of %d bytes", ErrInvalidHeader, len(h.Options))
+	}
+
+	fw := newFieldWriter(w, "ipv4")
+	fw.uint("version", 4, 4)
+	fw.uint("ihl", 4, uint64(h.HeaderLength()/4))
+	fw.uint("dscp", 6, uint64(h.DSCP))
+	fw.uint("ecn", 2, uint64(h.ECN))
+	fw.uint("total_length", 16, uint64(h.TotalLength))
+	fw.uint("id", 16, uint64(h.ID))
+	fw.uint("flags", 3, uint64(h.Flags))
+	fw.uint("fragment_offset", 13, uint64(h.FragmentOffset))
+	fw.uint("ttl", 8, uint64(h.TTL))
+	fw.uint("protocol", 8, uint64(h.Protocol))
+	fw.uint("checksum", 16, uint64(h.Checksum))
+	fw.addr4("src", h.Src)
+	fw.addr4("dst", h.Dst)
+	fw.bytes("options", h.Options)
+	return fw.err
+}
+
+// ComputeChecksum returns the header checksum of `h`, i.e. the one's complement of the one's complement sum of
+// the 16-bit words of the header with the checksum field set to 0. The Checksum field of `h` is not modified.
+func (h *IPv4) ComputeChecksum() (uint16, error) {
+	c := *h
+	c.Checksum = 0
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	err := c.Write(w)
+	if err != nil {
+		return 0, err
+	}
+	err = w.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	sum := uint32(0)
+	p := buf.Bytes()
+	for i := 0; i+1 < len(p); i += 2 {
+		sum += uint32(p[i])<<8 | uint32(p[i+1])
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum), nil
+}
+
+// TCP flags.
+const (
+	TCPFlagCWR uint8 = 0x80
+	TCPFlagECE uint8 = 0x40
+	TCPFlagURG uint8 = 0x20
+	TCPFlagACK uint8 = 0x10
+	TCPFlagPSH uint8 = 0x08
+	TCPFlagRST uint8 = 0x04
+	TCPFlagSYN uint8 = 0x02
+	TCPFlagFIN uint8 = 0x01
+)
+
+// TCP is a TCP header (RFC 9293).
+type TCP struct {
+	SrcPort       uint16
+	DstPort       uint16
+	Seq           uint32
+	Ack           uint32
+	Reserved      uint8 // 4 bits
+	Flags         uint8 // CWR, ECE, URG, ACK, PSH, RST, SYN and FIN from the MSB
+	Window        uint16
+	Checksum      uint16
+	UrgentPointer uint16
+	Options       []byte // a multiple of 4 bytes, up to 40 bytes
+}
+
+// HeaderLength returns the length of the header in bytes, i.e. data offset * 4.
+func (h *TCP) HeaderLength() int {
+	return 20 + len(h.Options)
+}
+
+// ReadTCP reads a TCP header including the options from `r`.
+// It returns ErrInvalidHeader if the data offset is less than 5.
+func ReadTCP(r *bitstream.Reader) (*TCP, error) {
+	fr := newFieldReader(r, "tcp")
+	h := &TCP{}
+	h.SrcPort = uint16(fr.uint("src_port", 16))
+	h.DstPort = uint16(fr.uint("dst_port", 16))
+	h.Seq = uint32(fr.uint("seq", 32))
+	h.Ack = uint32(fr.uint("ack", 32))
+	dataOffset := fr.uint("data_offset", 4)
+	if fr.err == nil && dataOffset < 5 {
+		return nil, fmt.Errorf("%w: TCP data offset %d", ErrInvalidHeader, dataOffset)
+	}
+	h.Reserved = uint8(fr.uint("reserved", 4))
+	h.Flags = uint8(fr.uint("flags", 8))
+	h.Window = uint16(fr.uint("window", 16))
+	h.Checksum = uint16(fr.uint("checksum", 16))
+	h.UrgentPointer = uint16(fr.uint("urgent_pointer", 16))
+	if dataOffset > 5 {
+		h.Options = fr.bytes("options", uint(dataOffset-5)*4)
+	}
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	return h, nil
+}
+
+// Write writes the header to `w`. The data offset is computed from the length of the options.
+// It returns ErrInvalidHeader if Reserved does not fit in 4 bits or the options are not a multiple of 4 bytes up to 40 bytes.
+func (h *TCP) Write(w *bitstream.Writer) error {
+	switch {
+	case h.Reserved > 0xf:
+		return fmt.Errorf("%w: TCP reserved %d", ErrInvalidHeader, h.Reserved)
+	case len(h.Options)%4 != 0 || len(h.Options) > 40:
+		return fmt.Errorf("%w: TCP options of %d bytes", ErrInvalidHeader, len(h.Options))
+	}
+
+	fw := newFieldWriter(w, "tcp")
+	fw.uint("src_port", 16, uint64(h.SrcPort))
+	fw.uint("dst_port", 16, uint64(h.DstPort))
+	fw.uint("seq", 32, uint64(h.Seq))
+	fw.uint("ack", 32, uint64(h.Ack))
+	fw.uint("data_offset", 4, uint64(h.HeaderLength()/4))
+	fw.uint("reserved", 4, uint64(h.Reserved))
+	fw.uint("flags", 8, uint64(h.Flags))
+	fw.uint("window", 16, uint64(h.Window))
+	fw.uint("checksum", 16, uint64(h.Checksum))
+	fw.uint("urgent_pointer", 16, uint64(h.UrgentPointer))
+	fw.bytes("options", h.Options)
+	return fw.err
+}
+
+// UDP is a UDP header (RFC 768).
+type UDP struct {
+	SrcPort  uint16
+	DstPort  uint16
+	Length   uint16 // length of the datagram including the header, in bytes
+	Checksum uint16
+}
+
+// ReadUDP reads a UDP header from `r`.
+func ReadUDP(r *bitstream.Reader) (*UDP, error) {
+	fr := newFieldReader(r, "udp")
+	h := &UDP{}
+	h.SrcPort = uint16(fr.uint("src_port", 16))
+	h.DstPort = uint16(fr.uint("dst_port", 16))
+	h.Length = uint16(fr.uint("length", 16))
+	h.Checksum = uint16(fr.uint("checksum", 16))
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	return h, nil
+}
+
+// Write writes the header to `w`.
+func (h *UDP) Write(w *bitstream.Writer) error {
+	fw := newFieldWriter(w, "udp")
+	fw.uint("src_port", 16, uint64(h.SrcPort))
+	fw.uint("dst_port", 16, uint64(h.DstPort))
+	fw.uint("length", 16, uint64(h.Length))
+	fw.uint("checksum", 16, uint64(h.Checksum))
+	return fw.err
+}
+
+// Tag protocol identifiers of VLAN tags.
+const (
+	TPIDCustomer uint16 = 0x8100 // IEEE 802.1Q C-tag
+	TPIDService  uint16 = 0x88a8 // IEEE 802.1ad S-tag
+)
+
+// VLANTag is an IEEE 802.1Q VLAN tag, i.e. the tag protocol identifier followed by the tag control information.
+type VLANTag struct {
+	TPID uint16
+	PCP  uint8  // priority code point, 3 bits
+	DEI  bool   // drop eligible indicator
+	VID  uint16 // VLAN identifier, 12 bits
+}
+
+// ReadVLANTag reads a VLAN tag from `r`. The TPID is not checked, so compare it with TPIDCustomer etc. if needed.
+func ReadVLANTag(r *bitstream.Reader) (*VLANTag, error) {
+	fr := newFieldReader(r, "vlan")
+	t := &VLANTag{}
+	t.TPID = uint16(fr.uint("tpid", 16))
+	t.PCP = uint8(fr.uint("pcp", 3))
+	t.DEI = fr.uint("dei", 1) == 1
+	t.VID = uint16(fr.uint("vid", 12))
+	if fr.err != nil {
+		return nil, fr.err
+	}
+	return t, nil
+}
+
+// Write writes the tag to `w`.
+// It returns ErrInvalidHeader if PCP does not fit in 3 bits or VID does not fit in 12 bits.
+func (t *VLANTag) Write(w *bitstream.Writer) error {
+	if t.PCP > 0x7 || t.VID > 0xfff {
+		return fmt.Errorf("%w: VLAN PCP %d, VID %d", ErrInvalidHeader, t.PCP, t.VID)
+	}
+
+	dei := uint64(0)
+	if t.DEI {
+		dei = 1
+	}
+	fw := newFieldWriter(w, "vlan")
+	fw.uint("tpid", 16, uint64(t.TPID))
+	fw.uint("pcp", 3, uint64(t.PCP))
+	fw.uint("dei", 1, dei)
+	fw.uint("vid", 12, uint64(t.VID))
+	return fw.err
+}
+
+// fieldReader reads the fields of a header, keeping the first error so that the fields can be read without checking
+// the error of each of them. io.EOF after the first field is turned into io.ErrUnexpectedEOF.
+type fieldReader struct {
+	r      *bitstream.Reader
+	prefix string
+	n      int // number of fields read
+	err    error
+}
+
+func newFieldReader(r *bitstream.Reader, prefix string) *fieldReader {
+	return &fieldReader{r: r, prefix: prefix}
+}
+
+func (fr *fieldReader) fail(err error) {
+	if fr.n > 0 {
+		err = unexpectedEOF(err)
+	}
+	fr.err = err
+}
+
+func (fr *fieldReader) uint(name string, nBits uint8) uint64 {
+	if fr.err != nil {
+		return 0
+	}
+	v, err := fr.r.ReadNamed(fr.prefix+"."+name, nBits)
+	if err != nil {
+		fr.fail(err)
+		return 0
+	}
+	fr.n++
+	return v
+}
+
+func (fr *fieldReader) bytes(name string, nBytes uint) []byte {
+	if fr.err != nil {
+		return nil
+	}
+	p, err := fr.r.ReadNBitsNamed(fr.prefix+"."+name, nBytes*8, nil)
+	if err != nil {
+		fr.fail(err)
+		return nil
+	}
+	fr.n++
+	return p
+}
+
+func (fr *fieldReader) addr4(name string) netip.Addr {
+	p := fr.bytes(name, 4)
+	if p == nil {
+		return netip.Addr{}
+	}
+	return netip.AddrFrom4([4]byte(p))
+}
+
+// fieldWriter writes the fields of a header, keeping the first error.
+type fieldWriter struct {
+	w      *bitstream.Writer
+	prefix string
+	err    error
+}
+
+func newFieldWriter(w *bitstream.Writer, prefix string) *fieldWriter {
+	return &fieldWriter{w: w, prefix: prefix}
+}
+
+func (fw *fieldWriter) uint(name string, nBits uint8, val uint64) {
+	if fw.err != nil {
+		return
+	}
+	fw.err = fw.w.WriteNamed(fw.prefix+"."+name, nBits, val)
+}
+
+func (fw *fieldWriter) bytes(name string, p []byte) {
+	if fw.err != nil || len(p) == 0 {
+		return
+	}
+	fw.err = fw.w.WriteNBitsNamed(fw.prefix+"."+name, uint(len(p))*8, p)
+}
+
+func (fw *fieldWriter) addr4(name string, addr netip.Addr) {
+	a := addr.As4()
+	fw.bytes(name, a[:])
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,263 @@
+package netheader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func TestIPv4(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Header   *IPv4
+		Expected []byte
+	}{
+		{
+			Name: "UDP datagram",
+			Header: &IPv4{
+				TotalLength: 0x73,
+				Flags:       IPv4FlagDontFragment,
+				TTL:         64,
+				Protocol:    17,
+				Checksum:    0xb861,
+				Src:         netip.MustParseAddr("192.168.0.1"),
+				Dst:         netip.MustParseAddr("192.168.0.199"),
+			},
+			Expected: []byte{
+				0x45, 0x00, 0x00, 0x73, 0x00, 0x00, 0x40, 0x00, 0x40, 0x11, 0xb8, 0x61,
+				0xc0, 0xa8, 0x00, 0x01, 0xc0, 0xa8, 0x00, 0xc7,
+			},
+		},
+		{
+			Name: "fragment with options and DSCP/ECN",
+			Header: &IPv4{
+				DSCP:           46,
+				ECN:            1,
+				TotalLength:    0x0234,
+				ID:             0xabcd,
+				Flags:          IPv4FlagMoreFragments,
+				FragmentOffset: 0x1234,
+				TTL:            1,
+				Protocol:       6,
+				Src:            netip.MustParseAddr("10.0.0.1"),
+				Dst:            netip.MustParseAddr("10.0.0.2"),
+				Options:        []byte{0x94, 0x04, 0x00, 0x00},
+			},
+			Expected: []byte{
+				0x46, 0xb9, 0x02, 0x34, 0xab, 0xcd, 0x32, 0x34, 0x01, 0x06, 0x00, 0x00,
+				0x0a, 0x00, 0x00, 0x01, 0x0a, 0x00, 0x00, 0x02, 0x94, 0x04, 0x00, 0x00,
+			},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bitstream.NewWriter(&buf)
+			err := data.Header.Write(w)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.Close()
+			if !bytes.Equal(buf.Bytes(), data.Expected) {
+				t.Fatalf("\nExpected: %#v\nActual:   %#v\n", data.Expected, buf.Bytes())
+			}
+
+			h, err := ReadIPv4(bitstream.NewReader(&buf, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(h, data.Header) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Header, h)
+			}
+		})
+	}
+}
+
+func TestIPv4Checksum(t *testing.T) {
+	h := &IPv4{
+		TotalLength: 0x73,
+		Flags:       IPv4FlagDontFragment,
+		TTL:         64,
+		Protocol:    17,
+		Checksum:    0xffff,
+		Src:         netip.MustParseAddr("192.168.0.1"),
+		Dst:         netip.MustParseAddr("192.168.0.199"),
+	}
+	sum, err := h.ComputeChecksum()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if sum != 0xb861 {
+		t.Fatalf("\nExpected: %#04x\nActual:   %#04x\n", 0xb861, sum)
+	}
+	if h.Checksum != 0xffff {
+		t.Fatalf("\nExpected: %#04x\nActual:   %#04x\n", 0xffff, h.Checksum)
+	}
+}
+
+func TestIPv4Errors(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x65, 0x00, 0x00, 0x14}, // version 6
+		{0x44, 0x00, 0x00, 0x14}, // IHL 4
+	} {
+		_, err := ReadIPv4(bitstream.NewReaderBytes(b, nil))
+		if !errors.Is(err, ErrInvalidHeader) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidHeader, err)
+		}
+	}
+
+	_, err := ReadIPv4(bitstream.NewReaderBytes(nil, nil))
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+	_, err = ReadIPv4(bitstream.NewReaderBytes([]byte{0x46, 0x00, 0x00, 0x18, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, nil))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+
+	src, dst := netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")
+	for _, h := range []*IPv4{
+		{DSCP: 64, Src: src, Dst: dst},
+		{FragmentOffset: 0x2000, Src: src, Dst: dst},
+		{Src: netip.MustParseAddr("::1"), Dst: dst},
+		{Src: src, Dst: dst, Options: []byte{0x01}},
+	} {
+		var buf bytes.Buffer
+		w := bitstream.NewWriter(&buf)
+		err := h.Write(w)
+		if !errors.Is(err, ErrInvalidHeader) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidHeader, err)
+		}
+		if w.WrittenBits() != 0 {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, w.WrittenBits())
+		}
+	}
+}
+
+func TestTCP(t *testing.T) {
+	h := &TCP{
+		SrcPort:  49152,
+		DstPort:  443,
+		Seq:      0x01020304,
+		Ack:      0,
+		Flags:    TCPFlagSYN | TCPFlagECE | TCPFlagCWR,
+		Window:   65535,
+		Checksum: 0x1234,
+		Options:  []byte{0x02, 0x04, 0x05, 0xb4}, // MSS 1460
+	}
+	expected := []byte{
+		0xc0, 0x00, 0x01, 0xbb, 0x01, 0x02, 0x03, 0x04, 0x00, 0x00, 0x00, 0x00,
+		0x60, 0xc2, 0xff, 0xff, 0x12, 0x34, 0x00, 0x00, 0x02, 0x04, 0x05, 0xb4,
+	}
+
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	err := h.Write(w)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Close()
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, buf.Bytes())
+	}
+	if h.HeaderLength() != 24 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 24, h.HeaderLength())
+	}
+
+	h2, err := ReadTCP(bitstream.NewReader(&buf, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual(h2, h) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", h, h2)
+	}
+
+	expected[12] = 0x40 // data offset 4
+	_, err = ReadTCP(bitstream.NewReaderBytes(expected, nil))
+	if !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidHeader, err)
+	}
+}
+
+func TestUDPInIPv4(t *testing.T) {
+	ip := &IPv4{
+		TotalLength: 28,
+		TTL:         64,
+		Protocol:    17,
+		Src:         netip.MustParseAddr("192.0.2.1"),
+		Dst:         netip.MustParseAddr("192.0.2.2"),
+	}
+	udp := &UDP{SrcPort: 5353, DstPort: 53, Length: 8, Checksum: 0}
+
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	for _, h := range []interface{ Write(*bitstream.Writer) error }{ip, udp} {
+		err := h.Write(w)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	w.Close()
+
+	var names []string
+	r := bitstream.NewReader(&buf, &bitstream.ReaderOptions{
+		TraceHook: func(name string, bitOffset uint64, nBits uint, value any) {
+			names = append(names, name)
+		},
+	})
+	ip2, err := ReadIPv4(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	udp2, err := ReadUDP(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual(ip2, ip) || !reflect.DeepEqual(udp2, udp) {
+		t.Fatalf("\nExpected: %+v %+v\nActual:   %+v %+v\n", ip, udp, ip2, udp2)
+	}
+	if len(names) != 17 || names[1] != "ipv4.ihl" || names[13] != "udp.src_port" {
+		t.Fatalf("unexpected field names: %v\n", names)
+	}
+
+	_, err = ReadUDP(r)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+}
+
+func TestVLANTag(t *testing.T) {
+	tag := &VLANTag{TPID: TPIDCustomer, PCP: 5, DEI: true, VID: 100}
+	expected := []byte{0x81, 0x00, 0xb0, 0x64}
+
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	err := tag.Write(w)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Close()
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, buf.Bytes())
+	}
+
+	tag2, err := ReadVLANTag(bitstream.NewReader(&buf, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual(tag2, tag) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", tag, tag2)
+	}
+
+	err = (&VLANTag{TPID: TPIDService, VID: 0x1000}).Write(w)
+	if !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidHeader, err)
+	}
+}
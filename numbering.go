@@ -0,0 +1,69 @@
+package bitstream
+
+// BitNumbering specifies how the bits in a byte are numbered in the positions reported to the user,
+// e.g. in the error messages and dumps, to match the convention of the specification being implemented.
+// It does not change the order in which the bits are read or written, nor BitPosition, which is the number of bits
+// consumed or written; the byte offset and the bit number within the byte are derived from it by BitNumbering.Split.
+type BitNumbering int
+
+const (
+	// MSB0 numbers the bits from the MSB of each byte: bit 0 is the first bit of the byte in the bit stream.
+	// This is the convention of most protocol specifications, e.g. the RFCs, and the default.
+	MSB0 BitNumbering = iota
+
+	// LSB0 numbers the bits from the LSB of each byte: bit 7 is the first bit of the byte in the bit stream.
+	LSB0
+)
+
+func (n BitNumbering) String() string {
+	if n == LSB0 {
+		return "LSB-0"
+	}
+	return "MSB-0"
+}
+
+// Split splits the bit offset `pos` from the beginning of the bit stream into the offset of the byte
+// which contains the bit and the number of the bit in the byte.
+func (n BitNumbering) Split(pos uint64) (uint64, uint8) {
+	bit := uint8(pos % 8)
+	if n == LSB0 {
+		bit = 7 - bit
+	}
+	return pos / 8, bit
+}
+
+// GetBitNumbering gets configured bit numbering.
+func (opt *ReaderOptions) GetBitNumbering() BitNumbering {
+	if opt == nil {
+		return MSB0
+	}
+	return opt.BitNumbering
+}
+
+// GetBitNumbering gets configured bit numbering.
+func (opt *WriterOptions) GetBitNumbering() BitNumbering {
+	if opt == nil {
+		return MSB0
+	}
+	return opt.BitNumbering
+}
+
+// GetBitNumbering gets configured bit numbering.
+func (opt *DumpOptions) GetBitNumbering() BitNumbering {
+	if opt == nil {
+		return MSB0
+	}
+	return opt.BitNumbering
+}
+
+// BytePosition returns the offset of the byte which contains the next bit to be read and the number of the bit in the byte
+// in the bit numbering configured by ReaderOptions.BitNumbering.
+func (r *Reader) BytePosition() (uint64, uint8) {
+	return r.opt.GetBitNumbering().Split(r.BitPosition())
+}
+
+// BytePosition returns the offset of the byte which contains the next bit to be written and the number of the bit in the byte
+// in the bit numbering configured by WriterOptions.BitNumbering.
+func (w *Writer) BytePosition() (uint64, uint8) {
+	return w.numbering.Split(w.bitPosition())
+}
@@ -0,0 +1,77 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestBitNumbering(t *testing.T) {
+	testData := []struct {
+		Numbering    BitNumbering
+		Pos          uint64
+		ExpectedByte uint64
+		ExpectedBit  uint8
+	}{
+		{Numbering: MSB0, Pos: 0, ExpectedByte: 0, ExpectedBit: 0},
+		{Numbering: MSB0, Pos: 13, ExpectedByte: 1, ExpectedBit: 5},
+		{Numbering: LSB0, Pos: 0, ExpectedByte: 0, ExpectedBit: 7},
+		{Numbering: LSB0, Pos: 13, ExpectedByte: 1, ExpectedBit: 2},
+	}
+	for _, data := range testData {
+		b, bit := data.Numbering.Split(data.Pos)
+		if b != data.ExpectedByte || bit != data.ExpectedBit {
+			t.Fatalf("%s\nExpected: %+v, %+v\nActual:   %+v, %+v\n", data.Numbering, data.ExpectedByte, data.ExpectedBit, b, bit)
+		}
+	}
+}
+
+func TestBytePosition(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x12, 0x34}), &ReaderOptions{BitNumbering: LSB0})
+	r.Skip(11)
+	b, bit := r.BytePosition()
+	if b != 1 || bit != 4 {
+		t.Fatalf("\nExpected: 1, 4\nActual:   %+v, %+v\n", b, bit)
+	}
+
+	w := NewWriterWithOptions(&bytes.Buffer{}, nil)
+	w.WriteNBitsOfUint8(3, 0)
+	b, bit = w.BytePosition()
+	if b != 0 || bit != 3 {
+		t.Fatalf("\nExpected: 0, 3\nActual:   %+v, %+v\n", b, bit)
+	}
+}
+
+func TestPositionErrorNumbering(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x12}), &ReaderOptions{BitNumbering: LSB0})
+	r.Skip(2)
+	_, err := r.ReadNBitsAsUint16BE(16)
+	var pe *PositionError
+	if !errors.As(err, &pe) {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expected := "bitstream: ReadNBitsAsUint16BE at bit 2 (byte 0, LSB-0 bit 5): unexpected EOF"
+	if pe.Error() != expected {
+		t.Fatalf("\nExpected: %s\nActual:   %s\n", expected, pe.Error())
+	}
+
+	w := NewWriterWithOptions(&bytes.Buffer{}, &WriterOptions{BitNumbering: LSB0})
+	w.WriteBit(1)
+	err = w.WriteNBitsOfUint8(9, 0)
+	expected = "bitstream: WriteNBitsOfUint8 at bit 1 (byte 0, LSB-0 bit 6): bitstream: nBits too large for uint8"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("\nExpected: %s\nActual:   %v\n", expected, err)
+	}
+}
+
+func TestDumpRuler(t *testing.T) {
+	data := []byte{0x45, 0x00, 0x00}
+	d := Dump(data, nil, &DumpOptions{BytesPerLine: 2, Ruler: true, BitNumbering: LSB0})
+	expected := "" +
+		"               7654 3210 7654 3210\n" +
+		"     0  45 00  0100 0101 0000 0000\n" +
+		"    16  00     0000 0000\n"
+	if d != expected {
+		t.Fatalf("\nExpected:\n%s\nActual:\n%s\n", expected, d)
+	}
+}
@@ -0,0 +1,154 @@
+package bitstream
+
+// BitOrder specifies how the bits of a field are laid out in the bit stream, bundling the order of the bits in a byte
+// and the order of the bytes in the field, like binary.ByteOrder does for the bytes.
+// It lets the same parser code handle MSB-first/big endian, LSB-first/little endian and mixed conventions
+// with ReadNBitsAsUint64WithOrder and WriteNBitsOfUint64WithOrder, or with the order configured in the options.
+//
+// The bit stream itself is always consumed MSB first within each byte; a BitOrder rearranges the bits of each field.
+// For a bit stream whose bytes are consumed LSB first, e.g. DEFLATE, reverse the bits of each byte with ReverseBytesBitwise
+// and read the fields with LSBFirstLittleEndian.
+type BitOrder interface {
+	// Decode converts the `nBits` (<= 64) bits of a field, as they appear in the bit stream (the first bit in the MSB
+	// of the LSB aligned `raw`), into the value of the field.
+	Decode(raw uint64, nBits uint8) uint64
+
+	// Encode converts the value of a field into the `nBits` (<= 64) bits to be written in the bit stream. It is the inverse of Decode.
+	Encode(val uint64, nBits uint8) uint64
+
+	String() string
+}
+
+var (
+	// MSBFirstBigEndian is the order in which the bits of a field appear from the most significant one.
+	// This is the order of the ...BE methods of Reader and Writer, and the default.
+	MSBFirstBigEndian BitOrder = msbFirstBigEndian{}
+
+	// LSBFirstLittleEndian is the order in which the bits of a field appear from the least significant one.
+	LSBFirstLittleEndian BitOrder = lsbFirstLittleEndian{}
+
+	// MSBFirstLittleEndian is the order in which the bytes of a field appear from the least significant one, each of which
+	// is MSB first. If `nBits` is not a multiple of 8, the last group has the remaining upper bits of the field.
+	MSBFirstLittleEndian BitOrder = msbFirstLittleEndian{}
+
+	// LSBFirstBigEndian is the order in which the bytes of a field appear from the most significant one, each of which
+	// is LSB first. It is the exact reverse of MSBFirstLittleEndian, so the first group has the upper bits if `nBits` is
+	// not a multiple of 8.
+	LSBFirstBigEndian BitOrder = lsbFirstBigEndian{}
+)
+
+type msbFirstBigEndian struct{}
+
+func (msbFirstBigEndian) Decode(raw uint64, nBits uint8) uint64 { return raw }
+func (msbFirstBigEndian) Encode(val uint64, nBits uint8) uint64 { return val }
+func (msbFirstBigEndian) String() string                        { return "MSBFirstBigEndian" }
+
+type lsbFirstLittleEndian struct{}
+
+func (lsbFirstLittleEndian) Decode(raw uint64, nBits uint8) uint64 { return ReverseNBits(raw, nBits) }
+func (lsbFirstLittleEndian) Encode(val uint64, nBits uint8) uint64 { return ReverseNBits(val, nBits) }
+func (lsbFirstLittleEndian) String() string                        { return "LSBFirstLittleEndian" }
+
+type msbFirstLittleEndian struct{}
+
+func (msbFirstLittleEndian) Decode(raw uint64, nBits uint8) uint64 {
+	// the groups are taken from the head of the field, i.e. the MSB side of `raw`
+	v := uint64(0)
+	for i := uint8(0); i < nBits; i += 8 {
+		n := min(8, nBits-i)
+		v |= (raw >> (nBits - i - n) & (1<<n - 1)) << i
+	}
+	return v
+}
+
+func (msbFirstLittleEndian) Encode(val uint64, nBits uint8) uint64 {
+	raw := uint64(0)
+	for i := uint8(0); i < nBits; i += 8 {
+		n := min(8, nBits-i)
+		raw |= (val >> i & (1<<n - 1)) << (nBits - i - n)
+	}
+	return raw
+}
+
+func (msbFirstLittleEndian) String() string { return "MSBFirstLittleEndian" }
+
+type lsbFirstBigEndian struct{}
+
+func (lsbFirstBigEndian) Decode(raw uint64, nBits uint8) uint64 {
+	return msbFirstLittleEndian{}.Decode(ReverseNBits(raw, nBits), nBits)
+}
+
+func (lsbFirstBigEndian) Encode(val uint64, nBits uint8) uint64 {
+	return ReverseNBits(msbFirstLittleEndian{}.Encode(val, nBits), nBits)
+}
+
+func (lsbFirstBigEndian) String() string { return "LSBFirstBigEndian" }
+
+// GetBitOrder gets configured bit order.
+func (opt *ReaderOptions) GetBitOrder() BitOrder {
+	if opt == nil || opt.BitOrder == nil {
+		return MSBFirstBigEndian
+	}
+	return opt.BitOrder
+}
+
+// GetBitOrder gets configured bit order.
+func (opt *WriterOptions) GetBitOrder() BitOrder {
+	if opt == nil || opt.BitOrder == nil {
+		return MSBFirstBigEndian
+	}
+	return opt.BitOrder
+}
+
+// ReadNBitsAsUint64 reads `nBits` bits as an unsigned integer in the bit order configured by ReaderOptions.BitOrder
+// (MSBFirstBigEndian by default) from the bit stream and returns it in uint64 (LSB aligned).
+// `nBits` must be less than or equal to 64, otherwise returns an error.
+func (r *Reader) ReadNBitsAsUint64(nBits uint8) (uint64, error) {
+	return r.readNBitsWithOrder("ReadNBitsAsUint64", nBits, r.opt.GetBitOrder())
+}
+
+// ReadNBitsAsUint64WithOrder is the same as ReadNBitsAsUint64 except that the bit order is given by `order`.
+func (r *Reader) ReadNBitsAsUint64WithOrder(nBits uint8, order BitOrder) (uint64, error) {
+	return r.readNBitsWithOrder("ReadNBitsAsUint64WithOrder", nBits, order)
+}
+
+func (r *Reader) readNBitsWithOrder(op string, nBits uint8, order BitOrder) (uint64, error) {
+	pos := r.BitPosition()
+	v, err := r.readUint(nBits, 64)
+	v = order.Decode(v, nBits)
+	if err == nil && r.done(pos) {
+		r.trace("", pos, uint(nBits), v)
+	}
+	return v, r.wrapError(op, pos, err)
+}
+
+// WriteNBitsOfUint64 writes `nBits` bits of `val` to the bit stream in the bit order configured by WriterOptions.BitOrder
+// (MSBFirstBigEndian by default).
+// `nBits` must be less than or equal to 64, otherwise returns an error.
+func (w *Writer) WriteNBitsOfUint64(nBits uint8, val uint64) error {
+	return w.writeNBitsWithOrder("WriteNBitsOfUint64", nBits, val, w.order)
+}
+
+// WriteNBitsOfUint64WithOrder is the same as WriteNBitsOfUint64 except that the bit order is given by `order`.
+func (w *Writer) WriteNBitsOfUint64WithOrder(nBits uint8, val uint64, order BitOrder) error {
+	return w.writeNBitsWithOrder("WriteNBitsOfUint64WithOrder", nBits, val, order)
+}
+
+func (w *Writer) writeNBitsWithOrder(op string, nBits uint8, val uint64, order BitOrder) error {
+	pos := w.bitPosition()
+	err := w.checkRange(nBits, val)
+	if err == nil {
+		if nBits > 64 {
+			err = errTooManyBitsForUint64
+		} else {
+			err = w.writeUint(nBits, 64, order.Encode(maskBits(nBits, val), nBits))
+		}
+	}
+	if err != nil {
+		return w.wrapError(op, pos, err)
+	}
+	if w.done(pos) {
+		w.trace("", pos, uint(nBits), maskBits(nBits, val))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package bitstream
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBitOrder(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Order    BitOrder
+		NBits    uint8
+		Raw      uint64 // bits as they appear in the bit stream
+		Expected uint64
+	}{
+		{Name: "MSB first, big endian", Order: MSBFirstBigEndian, NBits: 12, Raw: 0x123, Expected: 0x123},
+		{Name: "LSB first, little endian", Order: LSBFirstLittleEndian, NBits: 4, Raw: 0x1, Expected: 0x8},
+		{Name: "LSB first, little endian 16", Order: LSBFirstLittleEndian, NBits: 16, Raw: 0x8101, Expected: 0x8081},
+		{Name: "MSB first, little endian", Order: MSBFirstLittleEndian, NBits: 16, Raw: 0x3412, Expected: 0x1234},
+		// 0011 0100 | 0001: the lower byte first, then the upper 4 bits
+		{Name: "MSB first, little endian 12", Order: MSBFirstLittleEndian, NBits: 12, Raw: 0x341, Expected: 0x134},
+		{Name: "LSB first, big endian", Order: LSBFirstBigEndian, NBits: 16, Raw: 0x482c, Expected: 0x1234},
+		// 1000 | 0010 1100: the upper 4 bits LSB first, then the lower byte LSB first
+		{Name: "LSB first, big endian 12", Order: LSBFirstBigEndian, NBits: 12, Raw: 0x82c, Expected: 0x134},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			v := data.Order.Decode(data.Raw, data.NBits)
+			if v != data.Expected {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Expected, v)
+			}
+			raw := data.Order.Encode(data.Expected, data.NBits)
+			if raw != data.Raw {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Raw, raw)
+			}
+		})
+	}
+}
+
+func TestReadWriteWithOrder(t *testing.T) {
+	orders := []BitOrder{MSBFirstBigEndian, LSBFirstLittleEndian, MSBFirstLittleEndian, LSBFirstBigEndian}
+	widths := []uint8{1, 7, 12, 24, 64, 3}
+	values := []uint64{1, 0x55, 0xabc, 0x123456, 0x0123456789abcdef, 5}
+
+	for _, order := range orders {
+		t.Run(order.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriterWithOptions(&buf, &WriterOptions{BitOrder: order})
+			for i := range widths {
+				// every other field is written with the order given per call
+				var err error
+				if i%2 == 0 {
+					err = w.WriteNBitsOfUint64(widths[i], values[i])
+				} else {
+					err = w.WriteNBitsOfUint64WithOrder(widths[i], values[i], order)
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+			}
+			w.Close()
+
+			r := NewReader(&buf, &ReaderOptions{BitOrder: order})
+			for i := range widths {
+				var v uint64
+				var err error
+				if i%2 == 0 {
+					v, err = r.ReadNBitsAsUint64WithOrder(widths[i], order)
+				} else {
+					v, err = r.ReadNBitsAsUint64(widths[i])
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if v != values[i] {
+					t.Fatalf("\nExpected: %#x\nActual:   %#x\n", values[i], v)
+				}
+			}
+		})
+	}
+}
+
+func TestReadWithOrderDefault(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x12, 0x34}), nil)
+	v, err := r.ReadNBitsAsUint64(16)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0x1234 {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x1234, v)
+	}
+}
@@ -0,0 +1,140 @@
+package bitstream
+
+import (
+	"encoding/binary"
+)
+
+// PackUints writes each of `vals` in `width` (<= 64) bits, e.g. for the columns of a column store.
+// The values are packed into 64-bit words and written to `w` in chunks, without the overhead of a method call per value.
+// The bits of a value beyond `width` are ignored unless the Writer is in the strict mode, in which case it returns
+// ErrValueOutOfRange and writes nothing if any of the values does not fit.
+func PackUints(w *Writer, width uint8, vals []uint64) error {
+	pos := w.bitPosition()
+	err := packUints(w, width, vals)
+	if err != nil {
+		return w.wrapError("PackUints", pos, err)
+	}
+	if w.done(pos) {
+		w.trace("", pos, uint(width)*uint(len(vals)), vals)
+	}
+	return nil
+}
+
+func packUints(w *Writer, width uint8, vals []uint64) error {
+	if width > 64 {
+		return errTooManyBitsForUint64
+	}
+	if width == 0 {
+		return nil
+	}
+	for _, v := range vals {
+		err := w.checkRange(width, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	chunkSize := (uint(len(vals))*uint(width) + 63) / 64 * 8
+	if chunkSize > runChunkSize {
+		chunkSize = runChunkSize
+	}
+	chunk := make([]byte, 0, chunkSize)
+
+	// acc holds the last nAcc bits packed, LSB aligned. it is appended to the chunk whenever it has 64 bits.
+	mask := ^uint64(0) >> (64 - width)
+	acc, nAcc := uint64(0), uint8(0)
+	for _, v := range vals {
+		v &= mask
+		if nAcc+width < 64 {
+			acc = acc<<width | v
+			nAcc += width
+			continue
+		}
+
+		free := 64 - nAcc
+		acc = acc<<free | v>>(width-free)
+		chunk = binary.BigEndian.AppendUint64(chunk, acc)
+		acc, nAcc = v&(^uint64(0)>>(64-(width-free))), width-free
+		if len(chunk) == cap(chunk) {
+			err := w.writeBytes(chunk)
+			if err != nil {
+				return err
+			}
+			chunk = chunk[:0]
+		}
+	}
+
+	for ; nAcc >= 8; nAcc -= 8 {
+		chunk = append(chunk, uint8(acc>>(nAcc-8)))
+	}
+	err := w.writeBytes(chunk)
+	if err != nil {
+		return err
+	}
+	return w.writeUint(nAcc, 8, acc)
+}
+
+// UnpackUints reads `n` values of `width` (<= 64) bits each, as written by PackUints, and appends them to `dst`.
+// The values are read from the buffer of `r` in batches, without the overhead of a method call per value.
+// If the stream ends before `n` values are read, it returns the values read so far with io.ErrUnexpectedEOF
+// (or io.EOF if no bits are left).
+func UnpackUints(r *Reader, width uint8, n int, dst []uint64) ([]uint64, error) {
+	pos := r.BitPosition()
+	start := len(dst)
+	dst, err := unpackUints(r, width, n, dst)
+	if err != nil {
+		return dst, r.wrapError("UnpackUints", pos, err)
+	}
+	if r.done(pos) {
+		r.trace("", pos, uint(width)*uint(n), dst[start:])
+	}
+	return dst, nil
+}
+
+func unpackUints(r *Reader, width uint8, n int, dst []uint64) ([]uint64, error) {
+	if width > 64 {
+		return dst, errTooManyBitsForUint64
+	}
+	if width == 0 {
+		for i := 0; i < n; i++ {
+			dst = append(dst, 0)
+		}
+		return dst, nil
+	}
+
+	for i := 0; i < n; {
+		if r.BufferedBits() < uint(width) {
+			err := r.refill(uint(width))
+			if err != nil {
+				if i > 0 || r.BufferedBits() > 0 {
+					return dst, unexpectedEOF(err)
+				}
+				return dst, err
+			}
+		}
+
+		k := min(n-i, int(r.BufferedBits()/uint(width)))
+		i += k
+
+		// the values which can be loaded with 8 bytes from the buffer are extracted with the local bit offset,
+		// and the rest are read by ReadBits64 after the offset is written back to `r`.
+		buf := r.buf[:len(r.buf):len(r.buf)]
+		off := r.currByteIndex*8 + uint(7-r.currBitIndex)
+		start := r.currByteIndex
+		for ; k > 0; k-- {
+			byteIndex, skip := off/8, off%8
+			if byteIndex+8 > uint(len(buf)) || skip+uint(width) > 64 {
+				break
+			}
+			dst = append(dst, binary.BigEndian.Uint64(buf[byteIndex:])<<skip>>(64-width))
+			off += uint(width)
+		}
+		r.currByteIndex = off / 8
+		r.currBitIndex = 7 - uint8(off%8)
+		r.consumedBytes += r.currByteIndex - start
+		for ; k > 0; k-- {
+			dst = append(dst, r.ReadBits64(uint(width)))
+		}
+	}
+	return dst, nil
+}
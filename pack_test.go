@@ -0,0 +1,140 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	mathrand "math/rand"
+	"testing"
+)
+
+func TestPackUints(t *testing.T) {
+	rnd := mathrand.New(mathrand.NewSource(1))
+	for width := uint8(0); width <= 64; width++ {
+		// the lengths cover the partial words and the chunks larger than runChunkSize
+		for _, n := range []int{0, 1, 7, 65, 4000} {
+			vals := make([]uint64, n)
+			for i := range vals {
+				vals[i] = rnd.Uint64()
+			}
+
+			// the values are written after 3 bits so that they are not byte aligned
+			var expected bytes.Buffer
+			w := NewWriter(&expected)
+			w.WriteNBitsOfUint8(3, 0x5)
+			for _, v := range vals {
+				w.WriteNBitsOfUint64BE(width, v)
+			}
+			w.Close()
+
+			var buf bytes.Buffer
+			w = NewWriter(&buf)
+			w.WriteNBitsOfUint8(3, 0x5)
+			err := PackUints(w, width, vals)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if w.WrittenBits() != 3+uint64(width)*uint64(n) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 3+uint64(width)*uint64(n), w.WrittenBits())
+			}
+			w.Close()
+			if !bytes.Equal(buf.Bytes(), expected.Bytes()) {
+				t.Fatalf("width %d, n %d:\nExpected: %#v\nActual:   %#v\n", width, n, expected.Bytes(), buf.Bytes())
+			}
+
+			for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 2, Prefetch: true}} {
+				r := NewReader(bytes.NewReader(buf.Bytes()), opt)
+				r.ReadNBitsAsUint8(3)
+				dst := []uint64{42}
+				dst, err := UnpackUints(r, width, n, dst)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if len(dst) != n+1 || dst[0] != 42 {
+					t.Fatalf("\nExpected: %+v\nActual:   %+v\n", n+1, len(dst))
+				}
+				for i, v := range vals {
+					if width < 64 {
+						v &= 1<<width - 1
+					}
+					if dst[i+1] != v {
+						t.Fatalf("width %d, n %d, index %d:\nExpected: %#x\nActual:   %#x\n", width, n, i, v, dst[i+1])
+					}
+				}
+				if r.BitPosition() != 3+uint64(width)*uint64(n) {
+					t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 3+uint64(width)*uint64(n), r.BitPosition())
+				}
+				r.Close()
+			}
+		}
+	}
+}
+
+func TestPackUintsStrict(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, &WriterOptions{StrictValues: true})
+	err := PackUints(w, 4, []uint64{1, 2, 16, 3})
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+	}
+	if w.WrittenBits() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, w.WrittenBits())
+	}
+
+	err = PackUints(w, 65, []uint64{1})
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+}
+
+func TestUnpackUintsEOF(t *testing.T) {
+	r := NewReaderBytes([]byte{0x12, 0x34, 0x56}, nil)
+	dst, err := UnpackUints(r, 5, 5, nil)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+	expected := []uint64{0x02, 0x08, 0x1a, 0x05}
+	if len(dst) != len(expected) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, dst)
+	}
+	for i := range expected {
+		if dst[i] != expected[i] {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, dst)
+		}
+	}
+
+	r = NewReaderBytes([]byte{0x12}, nil)
+	r.ReadUint8()
+	_, err = UnpackUints(r, 5, 1, nil)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+}
+
+func BenchmarkPackUints(b *testing.B) {
+	vals := make([]uint64, 1<<16)
+	for i := range vals {
+		vals[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+	b.SetBytes(int64(len(vals)) * 8)
+	for n := 0; n < b.N; n++ {
+		w := NewWriter(io.Discard)
+		PackUints(w, 13, vals)
+		w.Close()
+	}
+}
+
+func BenchmarkUnpackUints(b *testing.B) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	vals := make([]uint64, 1<<16)
+	PackUints(w, 13, vals)
+	w.Close()
+
+	dst := make([]uint64, 0, len(vals))
+	b.SetBytes(int64(len(vals)) * 8)
+	for n := 0; n < b.N; n++ {
+		r := NewReaderBytes(buf.Bytes(), nil)
+		dst, _ = UnpackUints(r, 13, len(vals), dst[:0])
+	}
+}
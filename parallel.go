@@ -0,0 +1,196 @@
+package bitstream
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Segment is a byte range of a bit stream which can be decoded independently of the others.
+type Segment struct {
+	Index  int   // index of the segment
+	Offset int64 // offset of the first byte of the segment in the source
+	Length int64 // number of bytes in the segment
+}
+
+// SegmentError records an error and the segment where it occurred.
+type SegmentError struct {
+	Segment Segment
+	Err     error
+}
+
+func (e *SegmentError) Error() string {
+	return fmt.Sprintf("bitstream: segment %d (offset %d): %v", e.Segment.Index, e.Segment.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SegmentError) Unwrap() error {
+	return e.Err
+}
+
+// SegmentsAt returns the segments which start at `offsets` (in ascending order) of a source of `size` bytes.
+// Each segment ends at the start of the next one, and the last one ends at the end of the source.
+func SegmentsAt(offsets []int64, size int64) []Segment {
+	segs := make([]Segment, len(offsets))
+	for i, off := range offsets {
+		end := size
+		if i+1 < len(offsets) {
+			end = offsets[i+1]
+		}
+		segs[i] = Segment{Index: i, Offset: off, Length: end - off}
+	}
+	return segs
+}
+
+// syncScanChunkSize is the number of bytes read at once by FindSyncSegments.
+const syncScanChunkSize = 64 * 1024
+
+// FindSyncSegments scans the first `size` bytes of `src` for the byte aligned occurrences of the sync word `sync`,
+// and returns the segments which start at them. The bytes before the first sync word, if any, form the first segment.
+// Overlapping occurrences are not recognized, e.g. "AA" is found once in "AAA".
+func FindSyncSegments(src io.ReaderAt, size int64, sync []byte) ([]Segment, error) {
+	if len(sync) == 0 {
+		return nil, fmt.Errorf("bitstream: empty sync word")
+	}
+	if size <= 0 {
+		return nil, nil
+	}
+
+	var offsets []int64
+	buf := make([]byte, syncScanChunkSize+len(sync)-1)
+	next := int64(0) // the offset where the next occurrence can start
+	for base := int64(0); base < size; base += syncScanChunkSize {
+		n := int(min(int64(len(buf)), size-base))
+		_, err := src.ReadAt(buf[:n], base)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		for i := 0; i < n; {
+			j := bytes.Index(buf[i:n], sync)
+			if j < 0 {
+				break
+			}
+			off := base + int64(i+j)
+			if off >= next {
+				offsets = append(offsets, off)
+				next = off + int64(len(sync))
+			}
+			i += j + 1
+		}
+	}
+
+	if len(offsets) == 0 || offsets[0] != 0 {
+		offsets = append([]int64{0}, offsets...)
+	}
+	return SegmentsAt(offsets, size), nil
+}
+
+// ParallelOptions is a set of options for DecodeSegments and DecodeSegmentsFunc.
+type ParallelOptions struct {
+	Workers       int            // number of goroutines to decode the segments. default: runtime.GOMAXPROCS(0)
+	ReaderOptions *ReaderOptions // options of the Reader for each segment
+}
+
+// GetWorkers gets configured number of workers.
+func (opt *ParallelOptions) GetWorkers() int {
+	if opt == nil || opt.Workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return opt.Workers
+}
+
+// GetReaderOptions gets configured options of the Readers.
+func (opt *ParallelOptions) GetReaderOptions() *ReaderOptions {
+	if opt == nil {
+		return nil
+	}
+	return opt.ReaderOptions
+}
+
+type segmentResult[T any] struct {
+	v   T
+	err error
+}
+
+// DecodeSegmentsFunc decodes `segments` of `src` in parallel, and calls `emit` with the results in the order of the segments.
+// `decode` is called in a goroutine for each segment with a new Reader which reads the segment.
+// Segments are decoded ahead of `emit` by at most twice the number of workers, so that memory usage is bounded.
+//
+// It stops at the first error returned by `decode` (wrapped in a *SegmentError) or `emit` and returns it;
+// `emit` has been called for all the segments before it.
+func DecodeSegmentsFunc[T any](src io.ReaderAt, segments []Segment, opt *ParallelOptions, decode func(seg Segment, r *Reader) (T, error), emit func(seg Segment, v T) error) error {
+	if len(segments) == 0 {
+		return nil
+	}
+	workers := min(opt.GetWorkers(), len(segments))
+
+	results := make([]chan segmentResult[T], len(segments))
+	for i := range results {
+		results[i] = make(chan segmentResult[T], 1)
+	}
+	jobs := make(chan int)
+	window := make(chan struct{}, 2*workers)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(jobs)
+		for i := range segments {
+			select {
+			case window <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				seg := segments[i]
+				r := NewReader(io.NewSectionReader(src, seg.Offset, seg.Length), opt.GetReaderOptions())
+				v, err := decode(seg, r)
+				r.Close()
+				results[i] <- segmentResult[T]{v: v, err: err}
+			}
+		}()
+	}
+
+	var err error
+	for i, seg := range segments {
+		res := <-results[i]
+		<-window
+		if res.err != nil {
+			err = &SegmentError{Segment: seg, Err: res.err}
+			break
+		}
+		err = emit(seg, res.v)
+		if err != nil {
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+	return err
+}
+
+// DecodeSegments decodes `segments` of `src` in parallel in the same way as DecodeSegmentsFunc,
+// and returns the results in the order of the segments.
+// On error, the results of the segments before the failed one are returned together with the error.
+func DecodeSegments[T any](src io.ReaderAt, segments []Segment, opt *ParallelOptions, decode func(seg Segment, r *Reader) (T, error)) ([]T, error) {
+	vs := make([]T, 0, len(segments))
+	err := DecodeSegmentsFunc(src, segments, opt, decode, func(seg Segment, v T) error {
+		vs = append(vs, v)
+		return nil
+	})
+	return vs, err
+}
@@ -0,0 +1,146 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// segment: sync word (0x47 0x1f), count (8 bits), count values of 12 bits, padded to a byte boundary
+func writeTestSegments(t *testing.T, nSegments int) ([]byte, []uint64) {
+	buf := bytes.NewBuffer([]byte{})
+	w := NewWriter(buf)
+	sums := make([]uint64, nSegments)
+	for i := 0; i < nSegments; i++ {
+		w.WriteBytes([]byte{0x47, 0x1f})
+		count := uint8(i%7 + 1)
+		w.WriteUint8(count)
+		for j := 0; j < int(count); j++ {
+			v := uint16(i*100+j) & 0x0fff
+			w.WriteNBitsOfUint16BE(12, v)
+			sums[i] += uint64(v)
+		}
+		_, err := w.AlignByte(0)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	w.Finalize()
+	return buf.Bytes(), sums
+}
+
+func decodeTestSegment(seg Segment, r *Reader) (uint64, error) {
+	err := r.Skip(16)
+	if err != nil {
+		return 0, err
+	}
+	count, err := r.ReadUint8()
+	if err != nil {
+		return 0, err
+	}
+	sum := uint64(0)
+	for j := 0; j < int(count); j++ {
+		v, err := r.ReadNBitsAsUint16BE(12)
+		if err != nil {
+			return 0, err
+		}
+		sum += uint64(v)
+	}
+	return sum, nil
+}
+
+func TestDecodeSegments(t *testing.T) {
+	data, sums := writeTestSegments(t, 200)
+
+	segs, err := FindSyncSegments(bytes.NewReader(data), int64(len(data)), []byte{0x47, 0x1f})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if len(segs) != 200 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 200, len(segs))
+	}
+
+	for _, workers := range []int{1, 4, 0} {
+		actual, err := DecodeSegments(bytes.NewReader(data), segs, &ParallelOptions{Workers: workers}, decodeTestSegment)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if !reflect.DeepEqual(sums, actual) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", sums, actual)
+		}
+	}
+}
+
+func TestDecodeSegmentsError(t *testing.T) {
+	data, sums := writeTestSegments(t, 50)
+	segs, err := FindSyncSegments(bytes.NewReader(data), int64(len(data)), []byte{0x47, 0x1f})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	segs[7].Length = 4 // truncated in the middle of the first value
+
+	actual, err := DecodeSegments(bytes.NewReader(data), segs, &ParallelOptions{Workers: 4}, decodeTestSegment)
+	var se *SegmentError
+	if !errors.As(err, &se) || se.Segment.Index != 7 || !errors.Is(err, ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "segment 7", err)
+	}
+	if !reflect.DeepEqual(sums[:7], actual) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", sums[:7], actual)
+	}
+}
+
+func TestFindSyncSegments(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Data     []byte
+		Sync     []byte
+		Expected []Segment
+	}{
+		{
+			Name:     "pattern 1",
+			Data:     []byte{0x01, 0xaa, 0xbb, 0x02, 0xaa, 0xbb},
+			Sync:     []byte{0xaa, 0xbb},
+			Expected: []Segment{{Index: 0, Offset: 0, Length: 1}, {Index: 1, Offset: 1, Length: 3}, {Index: 2, Offset: 4, Length: 2}},
+		},
+		{
+			Name:     "pattern 2",
+			Data:     []byte{0xaa, 0xaa, 0xaa, 0xbb},
+			Sync:     []byte{0xaa, 0xaa},
+			Expected: []Segment{{Index: 0, Offset: 0, Length: 4}},
+		},
+		{
+			Name:     "pattern 3",
+			Data:     []byte{},
+			Sync:     []byte{0xaa, 0xbb},
+			Expected: nil,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			actual, err := FindSyncSegments(bytes.NewReader(data.Data), int64(len(data.Data)), data.Sync)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(data.Expected, actual) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, actual)
+			}
+		})
+	}
+
+	// occurrences across the chunks
+	big := make([]byte, syncScanChunkSize*2+10)
+	for _, off := range []int{100, syncScanChunkSize - 1, syncScanChunkSize*2 + 5} {
+		big[off], big[off+1] = 0xaa, 0xbb
+	}
+	actual, err := FindSyncSegments(bytes.NewReader(big), int64(len(big)), []byte{0xaa, 0xbb})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expected := SegmentsAt([]int64{0, 100, syncScanChunkSize - 1, syncScanChunkSize*2 + 5}, int64(len(big)))
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, actual)
+	}
+}
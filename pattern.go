@@ -0,0 +1,73 @@
+package bitstream
+
+import (
+	"io"
+)
+
+// ReadUntilPattern reads bits until the `patternBits`-bit (<= 64) terminator `pattern` (LSB aligned) is found,
+// and returns the bits before the terminator, e.g. for the null or flag terminated fields.
+// The terminator is consumed as well if `consume` is true; otherwise it is left to be read next.
+// The terminator is searched at every bit position, so it may start in the middle of a byte.
+//
+// If the bit stream ends before the terminator is found, it returns io.ErrUnexpectedEOF (or io.EOF if no bits are left)
+// and the bits before the end are consumed.
+func (r *Reader) ReadUntilPattern(pattern uint64, patternBits uint8, consume bool) (BitSlice, error) {
+	pos := r.BitPosition()
+	bs, err := r.readUntilPattern(pattern, patternBits, consume)
+	if err != nil {
+		return BitSlice{}, r.wrapError("ReadUntilPattern", pos, err)
+	}
+	r.trace("", pos, uint(r.BitPosition()-pos), bs)
+	return bs, nil
+}
+
+func (r *Reader) readUntilPattern(pattern uint64, patternBits uint8, consume bool) (BitSlice, error) {
+	if patternBits > 64 {
+		return BitSlice{}, errTooManyBitsForUint64
+	}
+	pattern = maskBits(patternBits, pattern)
+
+	var data []byte
+	nBits := uint64(0)
+	for {
+		v, avail, err := r.peek(patternBits)
+		if err != nil {
+			if nBits > 0 {
+				err = unexpectedEOF(err)
+			}
+			return BitSlice{}, err
+		}
+		if avail == patternBits && v == pattern {
+			break
+		}
+		if avail < patternBits {
+			// the terminator cannot be found in the rest of the bit stream
+			err = r.skip(uint(avail))
+			if err == nil {
+				err = r.fillBufIfNeeded() // tells whether the source has ended or failed
+			}
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return BitSlice{}, unexpectedEOF(err)
+		}
+
+		bit, err := r.readBit()
+		if err != nil {
+			return BitSlice{}, unexpectedEOF(err)
+		}
+		if nBits%8 == 0 {
+			data = append(data, 0)
+		}
+		data[len(data)-1] |= bit << (7 - nBits%8)
+		nBits++
+	}
+
+	if consume {
+		err := r.skip(uint(patternBits))
+		if err != nil {
+			return BitSlice{}, err
+		}
+	}
+	return BitSlice{data: data, nBits: nBits}, nil
+}
@@ -0,0 +1,70 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadUntilPattern(t *testing.T) {
+	// 1011 0111 1110 0101 0111 1110 1100
+	src := []byte{0xb7, 0xe5, 0x7e, 0xc0}
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}} {
+		r := NewReader(bytes.NewReader(src), opt)
+
+		// the flag 0111 1110 starting at bit 4
+		bs, err := r.ReadUntilPattern(0x7e, 8, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if bs.String() != "1011" || r.BitPosition() != 12 {
+			t.Fatalf("\nExpected: 1011, 12\nActual:   %s, %+v\n", bs, r.BitPosition())
+		}
+
+		bs, err = r.ReadUntilPattern(0x7e, 8, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if bs.String() != "0101" || r.BitPosition() != 16 {
+			t.Fatalf("\nExpected: 0101, 16\nActual:   %s, %+v\n", bs, r.BitPosition())
+		}
+
+		// the terminator itself is found immediately
+		bs, err = r.ReadUntilPattern(0x7e, 8, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if bs.Len() != 0 || r.BitPosition() != 24 {
+			t.Fatalf("\nExpected: 0, 24\nActual:   %+v, %+v\n", bs.Len(), r.BitPosition())
+		}
+
+		_, err = r.ReadUntilPattern(0x7e, 8, true)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+		}
+		_, err = r.ReadUntilPattern(0x7e, 8, true)
+		if err != io.EOF {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+		}
+	}
+}
+
+func TestReadUntilPatternNull(t *testing.T) {
+	// a null terminated string which is not byte aligned
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteNBitsOfUint8(3, 0x5)
+	w.WriteString("abc\x00")
+	w.Close()
+
+	r := NewReader(&buf, nil)
+	r.Skip(3)
+	bs, err := r.ReadUntilPattern(0x00, 8, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if string(bs.Bytes()) != "abc" {
+		t.Fatalf("\nExpected: %q\nActual:   %q\n", "abc", bs.Bytes())
+	}
+}
@@ -0,0 +1,97 @@
+package bitstream
+
+import (
+	"io"
+)
+
+// Peek returns the next `nBits` (<= 64) bits of the bit stream (LSB aligned) without consuming them.
+// If the bit stream has fewer bits than `nBits`, the missing bits are filled with zeros at the LSB side,
+// and the number of bits actually available is returned as the second value.
+// It returns io.EOF only when no bits are left.
+// The bits peeked are not reported to the trace hook; they are reported when they are read.
+func (r *Reader) Peek(nBits uint8) (uint64, uint8, error) {
+	pos := r.BitPosition()
+	v, n, err := r.peek(nBits)
+	return v, n, r.wrapError("Peek", pos, err)
+}
+
+func (r *Reader) peek(nBits uint8) (uint64, uint8, error) {
+	if nBits > 64 {
+		return 0, 0, errTooManyBitsForUint64
+	}
+	if nBits == 0 {
+		return 0, 0, nil
+	}
+
+	skip := uint(7 - r.currBitIndex)
+	need := (skip + uint(nBits) + 7) / 8
+	err := r.fillBufAhead(need)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	v := uint64(0)
+	avail := uint8(0)
+	for i := r.currByteIndex; i < r.bufLen && avail < nBits; i++ {
+		b := uint64(r.buf[i])
+		rb := uint8(8)
+		if i == r.currByteIndex {
+			rb = r.currBitIndex + 1
+			b &= 1<<rb - 1
+		}
+		n := nBits - avail
+		if n > rb {
+			n = rb
+		}
+		v = v<<n | b>>(rb-n)
+		avail += n
+	}
+	return v << (nBits - avail), avail, nil
+}
+
+// fillBufAhead makes sure that the buffer holds at least `nBytes` bytes from the current byte, unless the source ends.
+// Unlike fillBuf, it keeps the bytes which have not been consumed yet at the beginning of the buffer, growing it if needed.
+// An error from the source after some bytes are buffered is left for the next read.
+// It returns an error only when no bytes are buffered at all.
+func (r *Reader) fillBufAhead(nBytes uint) error {
+	if r.isBufEmpty() {
+		err := r.fillBuf()
+		if err != nil {
+			return err
+		}
+	}
+
+	for r.bufLen-r.currByteIndex < nBytes && !r.closed && !r.srcEOF && r.srcErr == nil {
+		rest := r.buf[r.currByteIndex:r.bufLen]
+
+		var buf []byte
+		var n, calls int
+		var err error
+		if r.opt.GetPrefetch() {
+			// the buffer is given back to the prefetcher, so the rest must be copied out of it
+			rest = append([]byte{}, rest...)
+			var next []byte
+			next, n, calls, err = r.prefetch.next(r.buf)
+			buf = make([]byte, max(uint(len(next)), uint(len(rest)+n)))
+			copy(buf[copy(buf, rest):], next[:n])
+		} else {
+			buf = r.buf
+			if uint(len(buf)) < nBytes {
+				buf = make([]byte, max(r.opt.GetBufferSize(), nBytes))
+			}
+			copy(buf, rest)
+			n, calls, err = readSource(r.src, buf[len(rest):])
+		}
+		r.countRefill(n, calls)
+
+		r.buf = buf
+		r.bufLen = uint(len(rest) + n)
+		r.currByteIndex = 0
+		if err == io.EOF {
+			r.srcEOF = true
+		} else if err != nil {
+			r.srcErr = err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestPeek(t *testing.T) {
+	testData := []struct {
+		Name          string
+		Src           []byte
+		Skip          uint
+		NBits         uint8
+		ExpectedValue uint64
+		ExpectedAvail uint8
+	}{
+		{
+			Name: "pattern 1",
+			// 1010 1010
+			// ^^^^
+			Src:           []byte{0xaa},
+			NBits:         4,
+			ExpectedValue: 0x0a,
+			ExpectedAvail: 4,
+		},
+		{
+			Name: "pattern 2",
+			// 1010 1010 1111 0000 1100 1100
+			//    ^ ^^^^ ^^^^ ^^^^ ^^
+			Src:           []byte{0xaa, 0xf0, 0xcc},
+			Skip:          3,
+			NBits:         15,
+			ExpectedValue: 0x2bc3, // 010 1011 1100 0011
+			ExpectedAvail: 15,
+		},
+		{
+			Name: "pattern 3",
+			// 1010 1010 1111 0000
+			//        ^^ ^^^^ ^^^^ (0000 00)
+			Src:           []byte{0xaa, 0xf0},
+			Skip:          6,
+			NBits:         16,
+			ExpectedValue: 0xbc00, // 1011 1100 0000 0000
+			ExpectedAvail: 10,
+		},
+		{
+			Name: "pattern 4",
+			// 0x01 x 9, the first bit skipped
+			Src:           []byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01},
+			Skip:          1,
+			NBits:         64,
+			ExpectedValue: 0x0202020202020202,
+			ExpectedAvail: 64,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 2, Prefetch: true}} {
+			t.Run(data.Name, func(t *testing.T) {
+				r := NewReader(bytes.NewReader(data.Src), opt)
+				defer r.Close()
+				err := r.Skip(data.Skip)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+
+				v, avail, err := r.Peek(data.NBits)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if data.ExpectedValue != v || data.ExpectedAvail != avail {
+					t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", data.ExpectedValue, data.ExpectedAvail, v, avail)
+				}
+				if r.BitPosition() != uint64(data.Skip) {
+					t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Skip, r.BitPosition())
+				}
+
+				// the peeked bits are read again
+				v2, err := r.ReadNBitsAsUint64BE(avail)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if v>>(data.NBits-avail) != v2 {
+					t.Fatalf("\nExpected: %+v\nActual:   %+v\n", v>>(data.NBits-avail), v2)
+				}
+			})
+		}
+	}
+}
+
+func TestPeekEOF(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0xff}), nil)
+	r.Skip(8)
+	_, _, err := r.Peek(1)
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+
+	_, _, err = r.Peek(65)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+}
@@ -0,0 +1,55 @@
+package bitstream
+
+import "fmt"
+
+// The 4-bit prefixes of the timestamps in the header of an MPEG-2 PES packet.
+const (
+	PESPrefixPTS        uint8 = 0x2 // '0010', the PTS when PTS_DTS_flags is '10'
+	PESPrefixPTSWithDTS uint8 = 0x3 // '0011', the PTS when PTS_DTS_flags is '11'
+	PESPrefixDTS        uint8 = 0x1 // '0001', the DTS which follows it
+)
+
+// ReadPESTimestamp reads a 33-bit timestamp (PTS or DTS) of the header of an MPEG-2 PES packet, which is split into
+// 40 bits as below, and returns the 4-bit prefix and the timestamp.
+//
+//	prefix (4) | TS[32..30] (3) | marker (1) | TS[29..15] (15) | marker (1) | TS[14..0] (15) | marker (1)
+//
+// The prefix is returned as it is, so compare it with PESPrefixPTS etc. if needed.
+// It returns ErrInvalidMarker if any of the marker bits is not '1'.
+func (r *Reader) ReadPESTimestamp() (uint8, uint64, error) {
+	pos := r.BitPosition()
+	v, err := r.readUint(40, 64)
+	if err != nil {
+		return 0, 0, r.wrapError("ReadPESTimestamp", pos, err)
+	}
+	if v&0x0100010001 != 0x0100010001 {
+		return 0, 0, r.wrapError("ReadPESTimestamp", pos, fmt.Errorf("%w: %010x", ErrInvalidMarker, v))
+	}
+
+	prefix := uint8(v >> 36)
+	ts := (v>>33&0x7)<<30 | (v>>17&0x7fff)<<15 | v>>1&0x7fff
+	if r.done(pos) {
+		r.trace("", pos, 40, ts)
+	}
+	return prefix, ts, nil
+}
+
+// WritePESTimestamp writes a 33-bit timestamp (PTS or DTS) of the header of an MPEG-2 PES packet with the 4-bit `prefix`
+// and the marker bits, in the layout read by ReadPESTimestamp.
+// It returns ErrValueOutOfRange if `prefix` does not fit in 4 bits or `ts` does not fit in 33 bits.
+func (w *Writer) WritePESTimestamp(prefix uint8, ts uint64) error {
+	pos := w.bitPosition()
+	if prefix > 0xf || ts > 1<<33-1 {
+		return w.wrapError("WritePESTimestamp", pos, fmt.Errorf("%w: prefix %#x, timestamp %d", ErrValueOutOfRange, prefix, ts))
+	}
+
+	v := uint64(prefix)<<36 | (ts>>30&0x7)<<33 | (ts>>15&0x7fff)<<17 | (ts&0x7fff)<<1 | 0x0100010001
+	err := w.writeUint(40, 64, v)
+	if err != nil {
+		return w.wrapError("WritePESTimestamp", pos, err)
+	}
+	if w.done(pos) {
+		w.trace("", pos, 40, ts)
+	}
+	return nil
+}
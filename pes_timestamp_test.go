@@ -0,0 +1,98 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPESTimestamp(t *testing.T) {
+	testData := []struct {
+		Name      string
+		Prefix    uint8
+		Timestamp uint64
+		Expected  []byte
+	}{
+		{
+			Name:      "zero",
+			Prefix:    PESPrefixPTS,
+			Timestamp: 0,
+			Expected:  []byte{0x21, 0x00, 0x01, 0x00, 0x01},
+		},
+		{
+			Name:      "max",
+			Prefix:    PESPrefixPTSWithDTS,
+			Timestamp: 1<<33 - 1,
+			Expected:  []byte{0x3f, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			Name:      "DTS",
+			Prefix:    PESPrefixDTS,
+			Timestamp: 0x123456789,
+			Expected:  []byte{0x19, 0x8d, 0x15, 0xcf, 0x13}, // 0001 100 1 100011010001010 1 110011110001001 1
+		},
+		{
+			Name:      "PTS",
+			Prefix:    PESPrefixPTS,
+			Timestamp: 0x123456789,
+			Expected:  []byte{0x29, 0x8d, 0x15, 0xcf, 0x13},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			err := w.WritePESTimestamp(data.Prefix, data.Timestamp)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.Close()
+			if !bytes.Equal(buf.Bytes(), data.Expected) {
+				t.Fatalf("\nExpected: %#v\nActual:   %#v\n", data.Expected, buf.Bytes())
+			}
+
+			for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 2, Prefetch: true}} {
+				r := NewReader(bytes.NewReader(data.Expected), opt)
+				prefix, ts, err := r.ReadPESTimestamp()
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if prefix != data.Prefix || ts != data.Timestamp {
+					t.Fatalf("\nExpected: %#x, %#x\nActual:   %#x, %#x\n", data.Prefix, data.Timestamp, prefix, ts)
+				}
+			}
+		})
+	}
+}
+
+func TestPESTimestampErrors(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x20, 0x00, 0x01, 0x00, 0x01},
+		{0x21, 0x00, 0x00, 0x00, 0x01},
+		{0x21, 0x00, 0x01, 0x00, 0x00},
+	} {
+		r := NewReaderBytes(b, nil)
+		_, _, err := r.ReadPESTimestamp()
+		if !errors.Is(err, ErrInvalidMarker) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidMarker, err)
+		}
+	}
+
+	r := NewReaderBytes([]byte{0x21, 0x00, 0x01, 0x00}, nil)
+	_, _, err := r.ReadPESTimestamp()
+	if !errors.Is(err, ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrUnexpectedEOF, err)
+	}
+
+	w := NewWriter(&bytes.Buffer{})
+	for _, err := range []error{w.WritePESTimestamp(0x10, 0), w.WritePESTimestamp(PESPrefixPTS, 1<<33)} {
+		if !errors.Is(err, ErrValueOutOfRange) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+		}
+	}
+	if w.bitPosition() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, w.bitPosition())
+	}
+}
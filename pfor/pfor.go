@@ -0,0 +1,184 @@
+// Package pfor implements the patched frame-of-reference (PFOR) packing of uint64 arrays on top of
+// bitstream.PackUints / bitstream.UnpackUints.
+//
+// The values are split into blocks of BlockSize values (the last one may be shorter), and each block is packed with
+// the bit width which minimizes its size: the values which do not fit in the width are exceptions, whose low bits are
+// packed with the others and whose high bits are patched in after them. So a few outliers in a block do not inflate
+// the width of all the values, unlike bitstream.PackUints with a fixed width.
+//
+// The encoded format is:
+//
+//	n           64 bits  number of values
+//	block       repeated ceil(n / BlockSize) times:
+//	  width     7 bits   bit width of the values (0 - 64)
+//	  nExc      8 bits   number of exceptions (0 - BlockSize)
+//	  excWidth  7 bits   only if nExc > 0: bit width of the high bits of the exceptions (1 - 64)
+//	  values    the low `width` bits of each value of the block
+//	  positions nExc * 7 bits, the indices of the exceptions in the block, in ascending order
+//	  high      nExc * excWidth bits, the bits of the exceptions above `width`
+package pfor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+const (
+	// BlockSize is the number of values in a block.
+	BlockSize = 128
+
+	positionBits = 7 // bits of the index of an exception in a block
+	excWidthBits = 7 // bits of excWidth in the header of a block
+)
+
+// ErrInvalidBlock is returned when the header of a block is broken.
+var ErrInvalidBlock = errors.New("pfor: invalid block")
+
+// Encode writes `values` to `w`, choosing the bit width of each block.
+func Encode(w *bitstream.Writer, values []uint64) error {
+	err := w.WriteNamed("n", 64, uint64(len(values)))
+	if err != nil {
+		return err
+	}
+
+	low := make([]uint64, 0, BlockSize)
+	var positions, high []uint64
+	for len(values) > 0 {
+		block := values[:min(len(values), BlockSize)]
+		values = values[len(block):]
+
+		width, excWidth := chooseWidth(block)
+		low, positions, high = low[:0], positions[:0], high[:0]
+		for i, v := range block {
+			if width < 64 && v>>width != 0 {
+				positions = append(positions, uint64(i))
+				high = append(high, v>>width)
+				v &= 1<<width - 1
+			}
+			low = append(low, v)
+		}
+
+		sw := bitstream.NewStickyWriter(w)
+		sw.WriteNamed("width", 7, uint64(width)).
+			WriteNamed("nExc", 8, uint64(len(positions)))
+		if len(positions) > 0 {
+			sw.WriteNamed("excWidth", excWidthBits, uint64(excWidth))
+		}
+		err = sw.Err()
+		if err != nil {
+			return err
+		}
+		err = bitstream.PackUints(w, width, low)
+		if err == nil {
+			err = bitstream.PackUints(w, positionBits, positions)
+		}
+		if err == nil {
+			err = bitstream.PackUints(w, excWidth, high)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chooseWidth returns the bit width which minimizes the size of `block`, and the bit width of the high bits of
+// the exceptions for it.
+func chooseWidth(block []uint64) (uint8, uint8) {
+	// counts[l] is the number of the values whose bit length is l
+	var counts [65]int
+	maxLen := 0
+	for _, v := range block {
+		l := bits.Len64(v)
+		counts[l]++
+		maxLen = max(maxLen, l)
+	}
+
+	best, bestSize := uint8(maxLen), len(block)*maxLen
+	nExc := 0
+	for width := maxLen - 1; width >= 0; width-- {
+		nExc += counts[width+1]
+		size := len(block)*width + excWidthBits + nExc*(positionBits+maxLen-width)
+		if size < bestSize {
+			best, bestSize = uint8(width), size
+		}
+	}
+	return best, uint8(maxLen) - best
+}
+
+// Decode reads values written by Encode from `r`.
+func Decode(r *bitstream.Reader) ([]uint64, error) {
+	n, err := r.ReadNamed("n", 64)
+	if err != nil {
+		return nil, err
+	}
+
+	// the values are appended block by block, so a broken n does not allocate a huge slice up front
+	values := make([]uint64, 0, min(n, 1<<16))
+	var positions, high []uint64
+	for remaining := n; remaining > 0; {
+		blockLen := min(remaining, BlockSize)
+		remaining -= blockLen
+
+		width, nExc, excWidth, err := readBlockHeader(r, blockLen)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		start := len(values)
+		values, err = bitstream.UnpackUints(r, width, int(blockLen), values)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		positions, err = bitstream.UnpackUints(r, positionBits, nExc, positions[:0])
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		high, err = bitstream.UnpackUints(r, excWidth, nExc, high[:0])
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		block := values[start:]
+		for i, p := range positions {
+			if p >= blockLen || (i > 0 && p <= positions[i-1]) {
+				return nil, fmt.Errorf("%w: exception at %d", ErrInvalidBlock, p)
+			}
+			block[p] |= high[i] << width
+		}
+	}
+	return values, nil
+}
+
+func readBlockHeader(r *bitstream.Reader, blockLen uint64) (uint8, int, uint8, error) {
+	width, err := r.ReadNamed("width", 7)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	nExc, err := r.ReadNamed("nExc", 8)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	excWidth := uint64(0)
+	if nExc > 0 {
+		excWidth, err = r.ReadNamed("excWidth", excWidthBits)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	if width > 64 || nExc > blockLen || (nExc > 0 && (excWidth == 0 || width+excWidth > 64)) {
+		return 0, 0, 0, fmt.Errorf("%w: width %d, %d exceptions of %d bits", ErrInvalidBlock, width, nExc, excWidth)
+	}
+	return uint8(width), int(nExc), uint8(excWidth), nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
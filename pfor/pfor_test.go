@@ -0,0 +1,131 @@
+package pfor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func encode(t *testing.T, values []uint64) []byte {
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	err := Encode(w, values)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	outliers := make([]uint64, 1000)
+	for i := range outliers {
+		outliers[i] = uint64(rnd.Intn(16))
+		if rnd.Intn(50) == 0 {
+			outliers[i] = rnd.Uint64() >> rnd.Intn(64)
+		}
+	}
+	random := make([]uint64, 300)
+	for i := range random {
+		random[i] = rnd.Uint64()
+	}
+
+	testData := []struct {
+		Name   string
+		Values []uint64
+	}{
+		{Name: "empty"},
+		{Name: "zeros", Values: make([]uint64, 200)},
+		{Name: "single", Values: []uint64{0xffffffffffffffff}},
+		{Name: "small with outliers", Values: outliers},
+		{Name: "random", Values: random},
+		{Name: "ascending", Values: func() []uint64 {
+			v := make([]uint64, BlockSize*3+1)
+			for i := range v {
+				v[i] = uint64(i)
+			}
+			return v
+		}()},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			encoded := encode(t, data.Values)
+			values, err := Decode(bitstream.NewReaderBytes(encoded, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if len(values) != len(data.Values) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", len(data.Values), len(values))
+			}
+			for i := range values {
+				if values[i] != data.Values[i] {
+					t.Fatalf("index %d:\nExpected: %#x\nActual:   %#x\n", i, data.Values[i], values[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeFormat(t *testing.T) {
+	// 3 bits for each value, and 2 exceptions of 7 bits above them
+	values := []uint64{1, 2, 3, 4, 5, 6, 7, 0x1ff, 0, 0x100}
+	encoded := encode(t, values)
+	r := bitstream.NewReaderBytes(encoded, nil)
+	r.ReadUint64BE()
+	width, _ := r.ReadNBitsAsUint8(7)
+	nExc, _ := r.ReadUint8()
+	excWidth, _ := r.ReadNBitsAsUint8(7)
+	if width != 3 || nExc != 2 || excWidth != 6 {
+		t.Fatalf("\nExpected: 3, 2, 6\nActual:   %d, %d, %d\n", width, nExc, excWidth)
+	}
+	if len(encoded) != 8+(7+8+7+10*3+2*7+2*6+7)/8 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 8+(7+8+7+10*3+2*7+2*6+7)/8, len(encoded))
+	}
+}
+
+func TestOutliersSize(t *testing.T) {
+	values := make([]uint64, BlockSize)
+	for i := range values {
+		values[i] = uint64(i % 8)
+	}
+	values[10] = 1 << 40
+
+	// 128 * 3 bits and an exception of 7 + 38 bits, instead of 128 * 41 bits
+	encoded := encode(t, values)
+	if len(encoded) != 8+(7+8+7+BlockSize*3+7+38+7)/8 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 8+(7+8+7+BlockSize*3+7+38+7)/8, len(encoded))
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	encoded := encode(t, []uint64{1, 2, 3, 1 << 20})
+	for n := 1; n < len(encoded); n++ {
+		_, err := Decode(bitstream.NewReaderBytes(encoded[:n], nil))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("length %d:\nExpected: %+v\nActual:   %+v\n", n, io.ErrUnexpectedEOF, err)
+		}
+	}
+	_, err := Decode(bitstream.NewReaderBytes(nil, nil))
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+
+	// width 65
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	w.WriteUint64BE(1)
+	w.WriteNBitsOfUint8(7, 65)
+	w.WriteUint8(0)
+	w.Close()
+	_, err = Decode(bitstream.NewReaderBytes(buf.Bytes(), nil))
+	if !errors.Is(err, ErrInvalidBlock) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidBlock, err)
+	}
+}
@@ -0,0 +1,152 @@
+package bitstream
+
+import (
+	"io"
+	"sync"
+)
+
+// pipeBufferSize is the number of bytes buffered in a pipe.
+const pipeBufferSize = DefaultBufferSize
+
+// Pipe creates a synchronous in-memory bit pipe, like io.Pipe.
+// The bits written to the PipeWriter can be read from the PipeReader in a different goroutine.
+//
+// Each complete byte is passed to the reader as soon as it is written, and the last incomplete byte is passed
+// (padded according to the padding policy) when the PipeWriter is closed.
+// Up to DefaultBufferSize bytes are buffered in the pipe; writes block while the buffer is full and reads block while it is empty.
+func Pipe() (*PipeReader, *PipeWriter) {
+	p := &pipe{}
+	p.cond = sync.NewCond(&p.mu)
+	return &PipeReader{Reader: NewReader(p, nil), p: p}, &PipeWriter{Writer: NewWriter(p), p: p}
+}
+
+// PipeReader is the read half of a pipe.
+type PipeReader struct {
+	*Reader
+	p *pipe
+}
+
+// Close closes the reader. Subsequent writes to the write half of the pipe return io.ErrClosedPipe.
+func (r *PipeReader) Close() error {
+	return r.CloseWithError(nil)
+}
+
+// CloseWithError closes the reader. Subsequent writes to the write half of the pipe return `err`,
+// or io.ErrClosedPipe if `err` is nil.
+func (r *PipeReader) CloseWithError(err error) error {
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	r.p.closeRead(err)
+	return r.Reader.Close()
+}
+
+// PipeWriter is the write half of a pipe.
+type PipeWriter struct {
+	*Writer
+	p *pipe
+}
+
+// Close finalizes the bit stream in the same way as Writer.Close and closes the writer.
+// Once the reader has read all the bits, subsequent reads return io.EOF.
+// If the bit stream cannot be finalized, the writer is closed with the error as CloseWithError does.
+func (w *PipeWriter) Close() error {
+	err := w.Writer.Close()
+	if err != nil {
+		w.p.closeWrite(err)
+	}
+	return err
+}
+
+// CloseWithError closes the writer without finalizing the bit stream; the bits which have not been passed to the pipe are discarded.
+// Once the reader has read the bytes in the pipe, subsequent reads return `err`, or io.EOF if `err` is nil.
+func (w *PipeWriter) CloseWithError(err error) error {
+	if err == nil {
+		err = io.EOF
+	}
+	w.p.closeWrite(err)
+	return nil
+}
+
+// pipe is a bounded byte buffer shared by a PipeReader and a PipeWriter.
+type pipe struct {
+	mu   sync.Mutex
+	cond *sync.Cond // signaled when data, space or a closure is available
+	buf  []byte
+	rerr error // error for the writer once the read half is closed
+	werr error // error for the reader once the write half is closed and the buffer is drained
+}
+
+// Read reads the buffered bytes, blocking until at least 1 byte is available or the pipe is closed.
+func (p *pipe) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.buf) == 0 {
+		if p.rerr != nil {
+			return 0, io.ErrClosedPipe
+		}
+		if p.werr != nil {
+			return 0, p.werr
+		}
+		if len(b) == 0 {
+			return 0, nil
+		}
+		p.cond.Wait()
+	}
+	n := copy(b, p.buf)
+	p.buf = p.buf[:copy(p.buf, p.buf[n:])]
+	p.cond.Broadcast()
+	return n, nil
+}
+
+// Write writes all of `b` to the buffer, blocking while it is full.
+func (p *pipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for len(b) > 0 {
+		if p.rerr != nil {
+			return n, p.rerr
+		}
+		if p.werr != nil {
+			return n, io.ErrClosedPipe
+		}
+		space := pipeBufferSize - len(p.buf)
+		if space == 0 {
+			p.cond.Wait()
+			continue
+		}
+		m := min(space, len(b))
+		p.buf = append(p.buf, b[:m]...)
+		n += m
+		b = b[m:]
+		p.cond.Broadcast()
+	}
+	return n, nil
+}
+
+// Close closes the write half. It is called by Writer.Close.
+func (p *pipe) Close() error {
+	p.closeWrite(io.EOF)
+	return nil
+}
+
+func (p *pipe) closeRead(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rerr == nil {
+		p.rerr = err
+	}
+	p.cond.Broadcast()
+}
+
+func (p *pipe) closeWrite(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.werr == nil {
+		p.werr = err
+	}
+	p.cond.Broadcast()
+}
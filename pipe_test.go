@@ -0,0 +1,125 @@
+package bitstream
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	pr, pw := Pipe()
+
+	// more than the pipe can buffer, so that the writer blocks
+	const n = 3000
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < n; i++ {
+			err := pw.WriteNBitsOfUint16BE(12, uint16(i))
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+		err := pw.WriteBit(1) // an incomplete byte is passed to the reader on Close
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- pw.Close()
+	}()
+
+	for i := 0; i < n; i++ {
+		v, err := pr.ReadNBitsAsUint16BE(12)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if uint16(i) != v {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", i, v)
+		}
+	}
+	b, err := pr.ReadUint8() // 1000 0000 (padded)
+	if err != nil || b != 0x80 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0x80, nil, b, err)
+	}
+	_, err = pr.ReadBit()
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+	err = <-done
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+}
+
+func TestPipeCloseReader(t *testing.T) {
+	pr, pw := Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		for {
+			err := pw.WriteUint8(0xa5)
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+
+	v, err := pr.ReadUint8()
+	if err != nil || v != 0xa5 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0xa5, nil, v, err)
+	}
+	pr.Close()
+
+	// the blocked writer is released
+	err = <-done
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrClosedPipe, err)
+	}
+}
+
+func TestPipeCloseWithError(t *testing.T) {
+	pr, pw := Pipe()
+	errTest := errors.New("test")
+
+	go func() {
+		pw.WriteUint8(0x12)
+		pw.WriteNBitsOfUint8(4, 0x3) // discarded
+		pw.CloseWithError(errTest)
+	}()
+
+	v, err := pr.ReadUint8()
+	if err != nil || v != 0x12 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0x12, nil, v, err)
+	}
+	_, err = pr.ReadBit()
+	if !errors.Is(err, errTest) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", errTest, err)
+	}
+}
+
+func TestPipeCloseNotAligned(t *testing.T) {
+	pr, pw := Pipe()
+	pw.SetPaddingPolicy(PadNone)
+	done := make(chan error, 1)
+
+	go func() {
+		pw.WriteUint8(0x12)
+		pw.WriteNBitsOfUint8(4, 0x3)
+		done <- pw.Close()
+	}()
+
+	v, err := pr.ReadUint8()
+	if err != nil || v != 0x12 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0x12, nil, v, err)
+	}
+	// the reader is not blocked forever
+	_, err = pr.ReadBit()
+	if !errors.Is(err, ErrNotAligned) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNotAligned, err)
+	}
+	err = <-done
+	if !errors.Is(err, ErrNotAligned) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNotAligned, err)
+	}
+}
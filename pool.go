@@ -0,0 +1,59 @@
+package bitstream
+
+import (
+	"io"
+	"sync"
+)
+
+var (
+	readerPool = sync.Pool{New: func() any { return NewReader(nil, nil) }}
+	writerPool = sync.Pool{New: func() any { return NewWriterWithOptions(nil, nil) }}
+)
+
+// GetReader returns a Reader with the default options which reads `src`, taken from a pool.
+// Together with PutReader, a Reader and its buffer are allocated only the first time, e.g. for per-message decoding:
+//
+//	r := bitstream.GetReaderBytes(msg)
+//	defer bitstream.PutReader(r)
+//
+// Use a sync.Pool of your own together with Reader.Reset for the other options.
+func GetReader(src io.Reader) *Reader {
+	r := readerPool.Get().(*Reader)
+	r.Reset(src)
+	return r
+}
+
+// GetReaderBytes returns a Reader with the default options which reads `data` in place, taken from a pool.
+func GetReaderBytes(data []byte) *Reader {
+	r := readerPool.Get().(*Reader)
+	r.ResetBytes(data)
+	return r
+}
+
+// PutReader returns `r` taken by GetReader or GetReaderBytes to the pool. `r` must not be used after that.
+func PutReader(r *Reader) {
+	r.Reset(nil)
+	readerPool.Put(r)
+}
+
+// GetWriter returns a Writer with the default options which writes to `dst`, taken from a pool.
+// The complete bytes are buffered up to DefaultWriterBufferSize bytes, as with NewWriterWithOptions;
+// Flush, Finalize or Close must be called to write them to `dst`.
+func GetWriter(dst io.Writer) *Writer {
+	w := writerPool.Get().(*Writer)
+	w.Reset(dst)
+	return w
+}
+
+// PutWriter returns `w` taken by GetWriter to the pool. The bits which have not been written to the destination are discarded,
+// and `w` must not be used after that.
+func PutWriter(w *Writer) {
+	w.Reset(nil)
+	// restore the default options which may have been changed by the setters
+	w.hooks = nil
+	w.traceHook = nil
+	w.strict = false
+	w.padding = PadZeros
+	w.pattern = PaddingPattern{}
+	writerPool.Put(w)
+}
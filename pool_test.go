@@ -0,0 +1,125 @@
+package bitstream
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReaderReset(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x12, 0x34, 0x56}), &ReaderOptions{BufferSize: 2})
+	_, err := r.ReadNBitsAsUint16BE(12)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	buf := r.buf
+
+	testData := []struct {
+		Name     string
+		Reset    func(r *Reader)
+		Expected uint32
+	}{
+		{
+			Name:     "pattern 1",
+			Reset:    func(r *Reader) { r.Reset(bytes.NewReader([]byte{0xab, 0xcd, 0xef})) },
+			Expected: 0xabcdef,
+		},
+		{
+			Name:     "pattern 2",
+			Reset:    func(r *Reader) { r.ResetBytes([]byte{0x01, 0x02, 0x03}) },
+			Expected: 0x010203,
+		},
+		{
+			Name:     "pattern 3",
+			Reset:    func(r *Reader) { r.ResetBytes([]byte{0x04, 0x05, 0x06}) },
+			Expected: 0x040506,
+		},
+		{
+			Name:     "pattern 4",
+			Reset:    func(r *Reader) { r.Reset(bytes.NewReader([]byte{0x07, 0x08, 0x09})) },
+			Expected: 0x070809,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			data.Reset(r)
+			if r.BitPosition() != 0 || r.Stats() != (ReaderStats{}) {
+				t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0, ReaderStats{}, r.BitPosition(), r.Stats())
+			}
+			v, err := r.ReadNBitsAsUint32BE(24)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.Expected != v {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Expected, v)
+			}
+			_, err = r.ReadBit()
+			if err == nil {
+				t.Fatalf("an error is expected at the end of the bit stream\n")
+			}
+		})
+	}
+
+	// the buffer is kept while reading bytes in place
+	if &r.buf[0] != &buf[0] {
+		t.Fatalf("the buffer is not reused\n")
+	}
+}
+
+func TestWriterReset(t *testing.T) {
+	first := bytes.NewBuffer([]byte{})
+	w := NewWriterWithOptions(first, nil)
+	w.WriteNBitsOfUint16BE(12, 0xabc)
+	w.Reserve(4)
+
+	second := bytes.NewBuffer([]byte{})
+	w.Reset(second)
+	if w.WrittenBits() != 0 || w.PendingBits() != 0 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0, 0, w.WrittenBits(), w.PendingBits())
+	}
+	w.WriteNBitsOfUint8(4, 0x5)
+	err := w.Finalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if first.Len() != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{}, first.Bytes())
+	}
+	if expected := []byte{0x50}; !bytes.Equal(expected, second.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, second.Bytes())
+	}
+}
+
+func TestPool(t *testing.T) {
+	msg := []byte{0x12, 0x34, 0x56, 0x78}
+	out := bytes.NewBuffer(make([]byte, 0, 16))
+	expected := []byte{0x12, 0x34, 0x50}
+
+	decode := func() {
+		r := GetReaderBytes(msg)
+		v, err := r.ReadNBitsAsUint32BE(20)
+		if err != nil || v != 0x12345 {
+			t.Fatalf("\nExpected: %#x\nActual:   %#x, %+v\n", 0x12345, v, err)
+		}
+		PutReader(r)
+
+		out.Reset()
+		w := GetWriter(out)
+		w.WriteNBitsOfUint32BE(20, v)
+		err = w.Finalize()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		PutWriter(w)
+		if !bytes.Equal(expected, out.Bytes()) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, out.Bytes())
+		}
+	}
+
+	decode()
+	allocs := testing.AllocsPerRun(100, decode)
+	if allocs != 0 && !raceEnabled {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, allocs)
+	}
+}
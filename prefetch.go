@@ -0,0 +1,85 @@
+package bitstream
+
+import (
+	"io"
+	"sync"
+)
+
+// prefetcher reads the source in a background goroutine so that the next buffer is ready when the current one is consumed.
+// It owns 2 buffers; one is held by the Reader and the other one is being filled.
+type prefetcher struct {
+	results  chan prefetchResult
+	free     chan []byte
+	done     chan struct{}
+	stopOnce sync.Once
+	err      error // the last error from the source, after which no more results are sent
+}
+
+type prefetchResult struct {
+	buf   []byte
+	n     int
+	calls int
+	err   error
+}
+
+// startPrefetch starts filling buffers of `size` bytes from `src` in the background.
+// `buf` is used as one of the two buffers if it has `size` bytes, e.g. the one given to NewReaderWithBuffer.
+func startPrefetch(src io.Reader, size uint, buf []byte) *prefetcher {
+	p := &prefetcher{
+		results: make(chan prefetchResult, 1),
+		free:    make(chan []byte, 2),
+		done:    make(chan struct{}),
+	}
+	if uint(len(buf)) != size {
+		buf = make([]byte, size)
+	}
+	p.free <- buf
+	p.free <- make([]byte, size)
+	go p.run(src)
+	return p
+}
+
+func (p *prefetcher) run(src io.Reader) {
+	for {
+		var buf []byte
+		select {
+		case buf = <-p.free:
+		case <-p.done:
+			return
+		}
+
+		n, calls, err := readSource(src, buf)
+		select {
+		case p.results <- prefetchResult{buf: buf, n: n, calls: calls, err: err}:
+		case <-p.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// next gives back the buffer `prev` which has been consumed, and returns the next one filled with the data from the source.
+// It also returns the number of calls to the Read method of the source it took.
+func (p *prefetcher) next(prev []byte) ([]byte, int, int, error) {
+	if p.err != nil {
+		return nil, 0, 0, p.err
+	}
+
+	if prev != nil {
+		p.free <- prev
+	}
+	res := <-p.results
+	if res.err != nil {
+		p.err = res.err
+	}
+	return res.buf, res.n, res.calls, res.err
+}
+
+func (p *prefetcher) stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+
+}
@@ -0,0 +1,174 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestPrefetch(t *testing.T) {
+	data := make([]byte, 4096)
+	for i := range data {
+		data[i] = byte(i*31 + i>>8)
+	}
+
+	testData := []struct {
+		Name       string
+		Src        io.Reader
+		BufferSize uint
+	}{
+		{Name: "default buffer size", Src: bytes.NewReader(data)},
+		{Name: "one byte buffer", Src: bytes.NewReader(data), BufferSize: 1},
+		{Name: "one byte reader", Src: iotest.OneByteReader(bytes.NewReader(data)), BufferSize: 7},
+		{Name: "half reader", Src: iotest.HalfReader(bytes.NewReader(data)), BufferSize: 100},
+		{Name: "data with EOF", Src: iotest.DataErrReader(bytes.NewReader(data)), BufferSize: 64},
+		{Name: "stuttering reader", Src: &stutteringReader{src: bytes.NewReader(data)}, BufferSize: 13},
+	}
+
+	for _, d := range testData {
+		d := d // capture
+		t.Run(d.Name, func(t *testing.T) {
+			r := NewReader(d.Src, &ReaderOptions{BufferSize: d.BufferSize, Prefetch: true})
+			defer r.Close()
+
+			pos := uint(0)
+			for i := 0; pos+uint(i%64) <= uint(len(data))*8; i++ {
+				nBits := uint8(i % 64)
+				v, err := r.ReadNBitsAsUint64BE(nBits)
+				if err != nil {
+					t.Fatalf("unexpected error at bit %d: %+v\n", pos, err)
+				}
+				if want := referenceBits(data, pos, uint(nBits)); v != want {
+					t.Fatalf("\nbit %d, %d bits\nExpected: %#x\nActual:   %#x\n", pos, nBits, want, v)
+				}
+				pos += uint(nBits)
+			}
+
+			err := r.Skip(uint(len(data))*8 - pos)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			_, err = r.ReadBit()
+			if err != io.EOF {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestPrefetchSourceError(t *testing.T) {
+	r := NewReader(iotest.TimeoutReader(bytes.NewReader([]byte{0xab, 0xcd})), &ReaderOptions{BufferSize: 1, Prefetch: true})
+	defer r.Close()
+
+	v, err := r.ReadUint8()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0xab {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0xab, v)
+	}
+
+	// the error is permanent in the prefetch mode
+	for i := 0; i < 2; i++ {
+		_, err = r.ReadUint8()
+		if !errors.Is(err, iotest.ErrTimeout) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", iotest.ErrTimeout, err)
+		}
+	}
+}
+
+// blockingReader returns the data in `src` and then blocks until `release` is closed.
+type blockingReader struct {
+	src     io.Reader
+	release chan struct{}
+}
+
+func (br *blockingReader) Read(p []byte) (int, error) {
+	n, err := br.src.Read(p)
+	if err == io.EOF {
+		<-br.release
+	}
+	return n, err
+}
+
+func TestPrefetchClose(t *testing.T) {
+	src := &blockingReader{src: bytes.NewReader([]byte{0x01, 0x23, 0x45}), release: make(chan struct{})}
+	r := NewReader(src, &ReaderOptions{BufferSize: 2, Prefetch: true})
+
+	v, err := r.ReadUint8()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0x01 {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x01, v)
+	}
+
+	err = r.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	close(src.release)
+
+	// the bits remaining in the buffer can be read after Close
+	v, err = r.ReadUint8()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0x23 {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x23, v)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := r.ReadUint8()
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrClosed, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reading a closed Reader blocks\n")
+	}
+}
+
+// slowReader sleeps before every read to simulate a network stream.
+type slowReader struct {
+	src   io.Reader
+	delay time.Duration
+}
+
+func (sr *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(sr.delay)
+	return sr.src.Read(p)
+}
+
+func benchmarkSlowSource(b *testing.B, prefetch bool) {
+	data := make([]byte, 64*1024)
+	for n := 0; n < b.N; n++ {
+		r := NewReader(&slowReader{src: bytes.NewReader(data), delay: 100 * time.Microsecond}, &ReaderOptions{BufferSize: 4096, Prefetch: prefetch})
+		for {
+			_, err := r.ReadNBitsAsUint16BE(13)
+			if err != nil {
+				break
+			}
+			// simulate processing of the field
+			for i := 0; i < 100; i++ {
+				toEliminateCompilerOptimizationByte += byte(i)
+			}
+		}
+		r.Close()
+	}
+}
+
+func BenchmarkSlowSourceWithoutPrefetch(b *testing.B) {
+	benchmarkSlowSource(b, false)
+}
+
+func BenchmarkSlowSourceWithPrefetch(b *testing.B) {
+	benchmarkSlowSource(b, true)
+}
@@ -0,0 +1,103 @@
+package bitstream
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// ReadPrefixedUint reads a variable-width unsigned integer which is preceded by a `prefixBits`-bit prefix selecting its width,
+// e.g. "k bits of length, then the k-bit value", and returns the value.
+// `widths` maps the value of the prefix to the width of the value, i.e. widths[prefix] bits follow the prefix.
+// If `widths` is nil, the value of the prefix is the width itself.
+// It returns ErrInvalidPrefix if the prefix is not in `widths`, or ErrTooManyBits if the width is larger than 64.
+func (r *Reader) ReadPrefixedUint(prefixBits uint8, widths []uint8) (uint64, error) {
+	pos := r.BitPosition()
+	v, err := r.readPrefixedUint(prefixBits, widths)
+	if err != nil {
+		return 0, r.wrapError("ReadPrefixedUint", pos, err)
+	}
+	if r.done(pos) {
+		r.trace("", pos, uint(r.BitPosition()-pos), v)
+	}
+	return v, nil
+}
+
+func (r *Reader) readPrefixedUint(prefixBits uint8, widths []uint8) (uint64, error) {
+	prefix, err := r.readUint(prefixBits, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	width := prefix
+	if widths != nil {
+		if prefix >= uint64(len(widths)) {
+			return 0, fmt.Errorf("%w: %d", ErrInvalidPrefix, prefix)
+		}
+		width = uint64(widths[prefix])
+	}
+	if width > 64 {
+		return 0, errTooManyBitsForUint64
+	}
+
+	v, err := r.readUint(uint8(width), 64)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return v, nil
+}
+
+// WritePrefixedUint writes `val` as a variable-width unsigned integer preceded by a `prefixBits`-bit prefix selecting its width,
+// which is read by ReadPrefixedUint with the same `prefixBits` and `widths`.
+// The prefix with the smallest width which can hold `val` is chosen; if `widths` is nil, the width is the bit length of `val`.
+// It returns ErrValueOutOfRange if no prefix of `prefixBits` bits selects a width which can hold `val`.
+func (w *Writer) WritePrefixedUint(prefixBits uint8, widths []uint8, val uint64) error {
+	pos := w.bitPosition()
+	prefix, width, err := choosePrefix(prefixBits, widths, val)
+	if err == nil {
+		err = w.writeUint(prefixBits, 64, prefix)
+	}
+	if err == nil {
+		err = w.writeUint(width, 64, val)
+	}
+	if err != nil {
+		return w.wrapError("WritePrefixedUint", pos, err)
+	}
+	if w.done(pos) {
+		w.trace("", pos, uint(prefixBits)+uint(width), val)
+	}
+	return nil
+}
+
+// choosePrefix returns the prefix which selects the smallest width which can hold `val`, and the width.
+func choosePrefix(prefixBits uint8, widths []uint8, val uint64) (uint64, uint8, error) {
+	if prefixBits > 64 {
+		return 0, 0, errTooManyBitsForUint64
+	}
+	n := uint8(bits.Len64(val))
+	maxPrefix := ^uint64(0) >> (64 - prefixBits)
+
+	if widths == nil {
+		if uint64(n) > maxPrefix {
+			return 0, 0, fmt.Errorf("%w: %d does not fit in the widths selected by %d bits", ErrValueOutOfRange, val, prefixBits)
+		}
+		return uint64(n), n, nil
+	}
+
+	found := false
+	var prefix uint64
+	var width uint8
+	for p, wd := range widths {
+		if uint64(p) > maxPrefix {
+			break
+		}
+		if wd >= n && wd <= 64 && (!found || wd < width) {
+			found = true
+			prefix = uint64(p)
+			width = wd
+		}
+	}
+	if !found {
+		return 0, 0, fmt.Errorf("%w: %d does not fit in the widths selected by %d bits", ErrValueOutOfRange, val, prefixBits)
+	}
+	return prefix, width, nil
+}
@@ -0,0 +1,101 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestPrefixedUint(t *testing.T) {
+	testData := []struct {
+		Name       string
+		PrefixBits uint8
+		Widths     []uint8
+		Value      uint64
+		Expected   []byte
+	}{
+		{
+			Name:       "width in prefix",
+			PrefixBits: 3,
+			Value:      0x15,
+			Expected:   []byte{0xb5}, // 101 10101
+		},
+		{
+			Name:       "zero",
+			PrefixBits: 3,
+			Value:      0,
+			Expected:   []byte{0x00}, // 000
+		},
+		{
+			Name:       "mapped widths",
+			PrefixBits: 2,
+			Widths:     []uint8{4, 8, 16, 32},
+			Value:      0x123,
+			Expected:   []byte{0x80, 0x48, 0xc0}, // 10 0000 0001 0010 0011
+		},
+		{
+			Name:       "smallest width is chosen",
+			PrefixBits: 2,
+			Widths:     []uint8{16, 4, 8},
+			Value:      0x0c,
+			Expected:   []byte{0x70}, // 01 1100
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			err := w.WritePrefixedUint(data.PrefixBits, data.Widths, data.Value)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.Close()
+			if !bytes.Equal(buf.Bytes(), data.Expected) {
+				t.Fatalf("\nExpected: %#v\nActual:   %#v\n", data.Expected, buf.Bytes())
+			}
+
+			r := NewReader(&buf, nil)
+			v, err := r.ReadPrefixedUint(data.PrefixBits, data.Widths)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if v != data.Value {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Value, v)
+			}
+		})
+	}
+}
+
+func TestPrefixedUintErrors(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+	err := w.WritePrefixedUint(2, nil, 0x0f)
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+	}
+	err = w.WritePrefixedUint(1, []uint8{4, 8, 16}, 0x100)
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+	}
+
+	r := NewReader(bytes.NewReader([]byte{0xc0}), nil)
+	_, err = r.ReadPrefixedUint(2, []uint8{4, 8, 16})
+	if !errors.Is(err, ErrInvalidPrefix) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidPrefix, err)
+	}
+
+	r = NewReader(bytes.NewReader([]byte{0xff}), nil)
+	_, err = r.ReadPrefixedUint(8, nil)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+
+	// the stream ends after the prefix
+	r = NewReader(bytes.NewReader([]byte{0x50}), nil)
+	_, err = r.ReadPrefixedUint(4, nil)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
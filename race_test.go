@@ -0,0 +1,6 @@
+//go:build race
+
+package bitstream
+
+// raceEnabled is true when the race detector is enabled, under which sync.Pool drops items at random.
+const raceEnabled = true
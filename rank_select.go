@@ -0,0 +1,133 @@
+package bitstream
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sort"
+)
+
+const (
+	wordsPerSuperblock = 8   // 512 bits
+	selectSampleRate   = 512 // a position is sampled every 512 '1' bits
+)
+
+// RankSelect is an index over a snapshot of a BitVector which answers rank and select queries.
+// Rank1 takes constant time with a two-level directory of the '1' bit counts (about 25% of the size of the bits).
+// Select1 starts from a sampled position and takes constant time on average.
+type RankSelect struct {
+	words   []uint64 // bits of the vector, MSB first
+	nBits   uint64
+	super   []uint64 // number of '1' bits before each superblock
+	block   []uint16 // number of '1' bits before each word in its superblock
+	samples []uint64 // index of the superblock which has the (i*selectSampleRate)-th '1' bit
+	ones    uint64
+}
+
+// NewRankSelect creates a new RankSelect instance which indexes the bits in `v`.
+// Modifying `v` afterwards does not affect the index.
+func NewRankSelect(v *BitVector) *RankSelect {
+	nWords := (v.nBits + 63) / 64
+	rs := &RankSelect{
+		words: make([]uint64, nWords),
+		nBits: v.nBits,
+		super: make([]uint64, (nWords+wordsPerSuperblock-1)/wordsPerSuperblock+1),
+		block: make([]uint16, nWords),
+	}
+
+	padded := make([]byte, nWords*8)
+	copy(padded, v.data)
+	for i := range rs.words {
+		rs.words[i] = binary.BigEndian.Uint64(padded[i*8:])
+	}
+
+	ones := uint64(0)
+	for i, w := range rs.words {
+		if i%wordsPerSuperblock == 0 {
+			rs.super[i/wordsPerSuperblock] = ones
+		}
+		rs.block[i] = uint16(ones - rs.super[i/wordsPerSuperblock])
+
+		c := uint64(bits.OnesCount64(w))
+		for s := uint64(len(rs.samples)) * selectSampleRate; s < ones+c; s += selectSampleRate {
+			rs.samples = append(rs.samples, uint64(i/wordsPerSuperblock))
+		}
+		ones += c
+	}
+	rs.super[len(rs.super)-1] = ones
+	rs.ones = ones
+	return rs
+}
+
+// Len returns the number of bits indexed.
+func (rs *RankSelect) Len() uint64 {
+	return rs.nBits
+}
+
+// Ones returns the number of '1' bits.
+func (rs *RankSelect) Ones() uint64 {
+	return rs.ones
+}
+
+// Get returns the `i`-th bit (0 or 1). It panics if `i` is out of range.
+func (rs *RankSelect) Get(i uint64) uint8 {
+	if i >= rs.nBits {
+		panic("bitstream: RankSelect index out of range")
+	}
+	return uint8(rs.words[i/64]>>(63-i%64)) & 0x01
+}
+
+// Rank1 returns the number of '1' bits in [0, i). It panics if `i` is larger than Len.
+func (rs *RankSelect) Rank1(i uint64) uint64 {
+	if i > rs.nBits {
+		panic("bitstream: RankSelect index out of range")
+	}
+	if i == rs.nBits {
+		return rs.ones
+	}
+
+	w := i / 64
+	r := rs.super[w/wordsPerSuperblock] + uint64(rs.block[w])
+	if n := i % 64; n > 0 {
+		r += uint64(bits.OnesCount64(rs.words[w] >> (64 - n)))
+	}
+	return r
+}
+
+// Rank0 returns the number of '0' bits in [0, i). It panics if `i` is larger than Len.
+func (rs *RankSelect) Rank0(i uint64) uint64 {
+	return i - rs.Rank1(i)
+}
+
+// Select1 returns the position of the `k`-th '1' bit (0 origin).
+// It returns false if there are not more than `k` '1' bits.
+func (rs *RankSelect) Select1(k uint64) (uint64, bool) {
+	if k >= rs.ones {
+		return 0, false
+	}
+
+	// find the last superblock which starts with k or fewer '1' bits, starting from the sampled one
+	lo := int(rs.samples[k/selectSampleRate])
+	hi := len(rs.super) - 1
+	if next := k/selectSampleRate + 1; next < uint64(len(rs.samples)) {
+		hi = int(rs.samples[next]) + 1
+	}
+	s := lo + sort.Search(hi-lo, func(j int) bool { return rs.super[lo+j+1] > k }) // superblock which has the k-th '1' bit
+
+	// find the word in the superblock
+	w := s * wordsPerSuperblock
+	end := w + wordsPerSuperblock
+	if end > len(rs.words) {
+		end = len(rs.words)
+	}
+	for w+1 < end && rs.super[s]+uint64(rs.block[w+1]) <= k {
+		w++
+	}
+
+	// find the bit in the word
+	r := k - rs.super[s] - uint64(rs.block[w])
+	word := rs.words[w]
+	for ; r > 0; r-- {
+		word &^= 1 << (63 - bits.LeadingZeros64(word))
+	}
+	return uint64(w)*64 + uint64(bits.LeadingZeros64(word)), true
+}
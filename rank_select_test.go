@@ -0,0 +1,78 @@
+package bitstream
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestRankSelect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, nBits := range []uint64{0, 1, 63, 64, 65, 513, 5000, 100000} {
+		for _, density := range []float64{0, 0.01, 0.5, 1} {
+			name := fmt.Sprintf("%d bits, density %v", nBits, density)
+			t.Run(name, func(t *testing.T) {
+				v := NewBitVector(nBits)
+				for i := uint64(0); i < nBits; i++ {
+					if rnd.Float64() < density {
+						v.Set(i, 1)
+					}
+				}
+				rs := NewRankSelect(v)
+
+				ones := uint64(0)
+				for i := uint64(0); i < nBits; i++ {
+					if rs.Rank1(i) != ones {
+						t.Fatalf("\nRank1(%d)\nExpected: %+v\nActual:   %+v\n", i, ones, rs.Rank1(i))
+					}
+					if rs.Get(i) != v.Get(i) {
+						t.Fatalf("\nGet(%d)\nExpected: %+v\nActual:   %+v\n", i, v.Get(i), rs.Get(i))
+					}
+					if v.Get(i) == 1 {
+						p, ok := rs.Select1(ones)
+						if !ok || p != i {
+							t.Fatalf("\nSelect1(%d)\nExpected: %+v, %+v\nActual:   %+v, %+v\n", ones, i, true, p, ok)
+						}
+						ones++
+					}
+				}
+				if rs.Rank1(nBits) != ones || rs.Ones() != ones {
+					t.Fatalf("\nExpected: %+v\nActual:   %+v, %+v\n", ones, rs.Rank1(nBits), rs.Ones())
+				}
+				if rs.Rank0(nBits) != nBits-ones {
+					t.Fatalf("\nExpected: %+v\nActual:   %+v\n", nBits-ones, rs.Rank0(nBits))
+				}
+				if _, ok := rs.Select1(ones); ok {
+					t.Fatalf("Select1(%d) should fail\n", ones)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkRank1(b *testing.B) {
+	v := NewBitVector(1 << 20)
+	for i := uint64(0); i < v.Len(); i += 3 {
+		v.Set(i, 1)
+	}
+	rs := NewRankSelect(v)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rs.Rank1(uint64(i) % v.Len())
+	}
+}
+
+func BenchmarkSelect1(b *testing.B) {
+	v := NewBitVector(1 << 20)
+	for i := uint64(0); i < v.Len(); i += 3 {
+		v.Set(i, 1)
+	}
+	rs := NewRankSelect(v)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rs.Select1(uint64(i) % rs.Ones())
+	}
+}
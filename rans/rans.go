@@ -0,0 +1,334 @@
+// Package rans implements an interleaved rANS (range asymmetric numeral systems) entropy coder with static frequencies.
+//
+// Two 32-bit rANS states are interleaved: the even-numbered symbols are coded with the first state and the odd-numbered
+// ones with the second, so that the decoding of adjacent symbols does not depend on each other.
+// The states are renormalized byte by byte, and all the bits go through bitstream.Writer / bitstream.Reader,
+// so the coded data does not need to be byte-aligned.
+//
+// rANS encodes symbols in the reverse order, so the Encoder buffers the symbols and codes them on Flush. The coded data is:
+//
+//	n        32 bits  number of symbols
+//	state0   32 bits  final state of the first coder
+//	state1   32 bits  final state of the second coder
+//	bytes    8 bits each, emitted by the renormalization
+//
+// The frequency table is not included; write it with FreqTable.WriteTable if the decoder does not know it.
+package rans
+
+import (
+	"errors"
+	"io"
+	"math/bits"
+	"sort"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+const (
+	// MaxScaleBits is the maximum precision of the frequencies.
+	MaxScaleBits = 16
+
+	// MaxSymbols is the maximum number of symbols in a frequency table.
+	MaxSymbols = 1 << 16
+
+	ransL = 1 << 23 // lower bound of the normalized state
+)
+
+var (
+	// ErrInvalidFreqs is returned when the frequencies do not sum up to 1 << scaleBits,
+	// or the precision is out of range.
+	ErrInvalidFreqs = errors.New("rans: invalid frequencies")
+
+	// ErrTooManySymbols is returned when there are more symbols than MaxSymbols or 1 << scaleBits.
+	ErrTooManySymbols = errors.New("rans: too many symbols")
+
+	// ErrInvalidSymbol is returned when a symbol to be encoded is out of range or has zero frequency.
+	ErrInvalidSymbol = errors.New("rans: invalid symbol")
+
+	// ErrInvalidState is returned when the coded data is broken.
+	ErrInvalidState = errors.New("rans: invalid state")
+)
+
+// NormalizeFreqs scales the symbol counts so that they sum up to 1 << `scaleBits`.
+// Every symbol with a non-zero count gets a non-zero frequency.
+func NormalizeFreqs(counts []uint64, scaleBits uint8) ([]uint32, error) {
+	if scaleBits == 0 || scaleBits > MaxScaleBits {
+		return nil, ErrInvalidFreqs
+	}
+	if len(counts) > MaxSymbols {
+		return nil, ErrTooManySymbols
+	}
+
+	total := uint64(0)
+	used := 0
+	for _, c := range counts {
+		total += c
+		if c > 0 {
+			used++
+		}
+	}
+	m := uint64(1) << scaleBits
+	if used == 0 {
+		return nil, ErrInvalidFreqs
+	}
+	if uint64(used) > m {
+		return nil, ErrTooManySymbols
+	}
+
+	freqs := make([]uint32, len(counts))
+	sum := uint64(0)
+	for i, c := range counts {
+		if c == 0 {
+			continue
+		}
+		hi, lo := bits.Mul64(c, m)
+		f, _ := bits.Div64(hi, lo, total)
+		if f == 0 {
+			f = 1
+		}
+		freqs[i] = uint32(f)
+		sum += f
+	}
+
+	// give the rest to or take the excess from the most frequent symbols
+	order := make([]int, len(counts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return freqs[order[i]] > freqs[order[j]] })
+	if sum < m {
+		freqs[order[0]] += uint32(m - sum)
+	}
+	for sum > m {
+		for _, i := range order {
+			if sum == m {
+				break
+			}
+			if freqs[i] > 1 {
+				freqs[i]--
+				sum--
+			}
+		}
+	}
+	return freqs, nil
+}
+
+// FreqTable is a table of the symbol frequencies which sum up to 1 << ScaleBits.
+type FreqTable struct {
+	freqs     []uint32
+	cum       []uint32 // cumulative frequency of the symbols before each symbol
+	scaleBits uint8
+	lookup    []uint16 // symbol of each slot in [0, 1 << scaleBits)
+}
+
+// NewFreqTable creates a frequency table. `freqs` must sum up to 1 << `scaleBits`.
+func NewFreqTable(freqs []uint32, scaleBits uint8) (*FreqTable, error) {
+	if scaleBits == 0 || scaleBits > MaxScaleBits {
+		return nil, ErrInvalidFreqs
+	}
+	if len(freqs) > MaxSymbols {
+		return nil, ErrTooManySymbols
+	}
+
+	t := &FreqTable{
+		freqs:     append([]uint32{}, freqs...),
+		cum:       make([]uint32, len(freqs)),
+		scaleBits: scaleBits,
+		lookup:    make([]uint16, 1<<scaleBits),
+	}
+	sum := uint64(0)
+	for s, f := range freqs {
+		t.cum[s] = uint32(sum)
+		sum += uint64(f)
+		if sum > 1<<scaleBits {
+			return nil, ErrInvalidFreqs
+		}
+		for i := t.cum[s]; i < uint32(sum); i++ {
+			t.lookup[i] = uint16(s)
+		}
+	}
+	if sum != 1<<scaleBits {
+		return nil, ErrInvalidFreqs
+	}
+	return t, nil
+}
+
+// FromCounts creates a frequency table from the symbol counts. See NormalizeFreqs for the details.
+func FromCounts(counts []uint64, scaleBits uint8) (*FreqTable, error) {
+	freqs, err := NormalizeFreqs(counts, scaleBits)
+	if err != nil {
+		return nil, err
+	}
+	return NewFreqTable(freqs, scaleBits)
+}
+
+// NumSymbols returns the number of symbols, including the ones which have zero frequency.
+func (t *FreqTable) NumSymbols() int {
+	return len(t.freqs)
+}
+
+// ScaleBits returns the precision of the frequencies.
+func (t *FreqTable) ScaleBits() uint8 {
+	return t.scaleBits
+}
+
+// Freq returns the frequency of `sym`.
+func (t *FreqTable) Freq(sym int) uint32 {
+	if sym < 0 || sym >= len(t.freqs) {
+		return 0
+	}
+	return t.freqs[sym]
+}
+
+// WriteTable writes the frequency table to `w` so that it can be read with ReadTable.
+// The format is the number of symbols - 1 (16 bits), the precision (8 bits), and the frequency of each symbol (precision + 1 bits).
+func (t *FreqTable) WriteTable(w *bitstream.Writer) error {
+	sw := bitstream.NewStickyWriter(w)
+	sw.WriteNamed("nSymbols", 16, uint64(len(t.freqs)-1)).
+		WriteNamed("scaleBits", 8, uint64(t.scaleBits))
+	for _, f := range t.freqs {
+		sw.WriteNamed("freq", t.scaleBits+1, uint64(f))
+	}
+	return sw.Err()
+}
+
+// ReadTable reads a frequency table written by WriteTable from `r`.
+func ReadTable(r *bitstream.Reader) (*FreqTable, error) {
+	n, err := r.ReadUint16BE()
+	if err != nil {
+		return nil, err
+	}
+	scaleBits, err := r.ReadUint8()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if scaleBits == 0 || scaleBits > MaxScaleBits {
+		return nil, ErrInvalidFreqs
+	}
+	freqs := make([]uint32, int(n)+1)
+	for i := range freqs {
+		freqs[i], err = r.ReadNBitsAsUint32BE(scaleBits + 1)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+	return NewFreqTable(freqs, scaleBits)
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Encoder is an interleaved rANS encoder.
+type Encoder struct {
+	t    *FreqTable
+	syms []uint16
+}
+
+// NewEncoder creates a new Encoder with the frequency table `t`.
+func NewEncoder(t *FreqTable) *Encoder {
+	return &Encoder{
+		t: t,
+	}
+}
+
+// Encode appends `sym` to the symbols to be encoded. It returns ErrInvalidSymbol if `sym` has zero frequency.
+func (e *Encoder) Encode(sym int) error {
+	if e.t.Freq(sym) == 0 {
+		return ErrInvalidSymbol
+	}
+	e.syms = append(e.syms, uint16(sym))
+	return nil
+}
+
+// Flush encodes the symbols appended so far and writes them to `w`. The Encoder is reset afterwards.
+func (e *Encoder) Flush(w *bitstream.Writer) error {
+	var out []byte // emitted in the reverse order
+	states := [2]uint32{ransL, ransL}
+	for i := len(e.syms) - 1; i >= 0; i-- {
+		s := e.syms[i]
+		freq := e.t.freqs[s]
+		x := states[i%2]
+		xMax := uint32(ransL>>e.t.scaleBits) << 8 * freq
+		for x >= xMax {
+			out = append(out, uint8(x))
+			x >>= 8
+		}
+		states[i%2] = (x/freq)<<e.t.scaleBits + x%freq + e.t.cum[s]
+	}
+
+	sw := bitstream.NewStickyWriter(w)
+	sw.WriteNamed("n", 32, uint64(len(e.syms))).
+		WriteNamed("state0", 32, uint64(states[0])).
+		WriteNamed("state1", 32, uint64(states[1]))
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	sw.WriteBytes(out)
+	e.syms = e.syms[:0]
+	return sw.Err()
+}
+
+// Decoder is an interleaved rANS decoder.
+type Decoder struct {
+	r      *bitstream.Reader
+	t      *FreqTable
+	n      uint32 // number of symbols left
+	i      int
+	states [2]uint32
+}
+
+// NewDecoder creates a new Decoder with the frequency table `t`. It reads the header of the coded data from `r`.
+func NewDecoder(r *bitstream.Reader, t *FreqTable) (*Decoder, error) {
+	d := &Decoder{
+		r: r,
+		t: t,
+	}
+	n, err := r.ReadUint32BE()
+	if err != nil {
+		return nil, err
+	}
+	d.n = n
+	for i := range d.states {
+		d.states[i], err = r.ReadUint32BE()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if d.states[i] < ransL {
+			return nil, ErrInvalidState
+		}
+	}
+	return d, nil
+}
+
+// Len returns the number of symbols which have not been decoded yet.
+func (d *Decoder) Len() int {
+	return int(d.n)
+}
+
+// Decode decodes the next symbol. It returns io.EOF after all the symbols have been decoded.
+func (d *Decoder) Decode() (int, error) {
+	if d.n == 0 {
+		return 0, io.EOF
+	}
+
+	t := d.t
+	x := d.states[d.i]
+	slot := x & (1<<t.scaleBits - 1)
+	s := t.lookup[slot]
+	x = t.freqs[s]*(x>>t.scaleBits) + slot - t.cum[s]
+	for x < ransL {
+		b, err := d.r.ReadUint8()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		x = x<<8 | uint32(b)
+	}
+	d.states[d.i] = x
+	d.i ^= 1
+	d.n--
+	return int(s), nil
+}
@@ -0,0 +1,227 @@
+package rans
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func TestNormalizeFreqs(t *testing.T) {
+	testData := []struct {
+		Name      string
+		Counts    []uint64
+		ScaleBits uint8
+		Expected  []uint32
+	}{
+		{Name: "pattern 1", Counts: []uint64{1, 1, 2}, ScaleBits: 4, Expected: []uint32{4, 4, 8}},
+		{Name: "pattern 2", Counts: []uint64{0, 7, 0}, ScaleBits: 4, Expected: []uint32{0, 16, 0}},
+		{Name: "pattern 3", Counts: []uint64{1, 1, 1}, ScaleBits: 4, Expected: []uint32{6, 5, 5}},          // 5 + 5 + 5, the rest goes to the first
+		{Name: "pattern 4", Counts: []uint64{1000, 1, 1, 1}, ScaleBits: 2, Expected: []uint32{1, 1, 1, 1}}, // 3 + 1 + 1 + 1
+		{Name: "pattern 5", Counts: []uint64{math.MaxUint64 / 2, math.MaxUint64 / 2}, ScaleBits: 16, Expected: []uint32{32768, 32768}},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			freqs, err := NormalizeFreqs(data.Counts, data.ScaleBits)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(data.Expected, freqs) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, freqs)
+			}
+		})
+	}
+
+	_, err := NormalizeFreqs([]uint64{1, 1, 1}, 1)
+	if !errors.Is(err, ErrTooManySymbols) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManySymbols, err)
+	}
+	_, err = NewFreqTable([]uint32{1, 2}, 2)
+	if !errors.Is(err, ErrInvalidFreqs) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidFreqs, err)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	testData := []struct {
+		Name      string
+		Counts    []uint64
+		ScaleBits uint8
+		N         int
+	}{
+		{Name: "pattern 1", Counts: []uint64{1, 2, 3, 4}, ScaleBits: 12, N: 0},
+		{Name: "pattern 2", Counts: []uint64{1, 2, 3, 4}, ScaleBits: 12, N: 1},
+		{Name: "pattern 3", Counts: []uint64{1, 2, 3, 4}, ScaleBits: 12, N: 10001},
+		{Name: "pattern 4", Counts: []uint64{0, 5}, ScaleBits: 1, N: 100},
+		{Name: "pattern 5", Counts: []uint64{1, 1000000}, ScaleBits: 16, N: 10000},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			tbl, err := FromCounts(data.Counts, data.ScaleBits)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+
+			// draw the symbols with the probabilities of the table
+			syms := make([]int, data.N)
+			for i := range syms {
+				x := uint32(rnd.Intn(1 << data.ScaleBits))
+				for s := range data.Counts {
+					if x < tbl.Freq(s) {
+						syms[i] = s
+						break
+					}
+					x -= tbl.Freq(s)
+				}
+			}
+
+			buf := bytes.NewBuffer([]byte{})
+			w := bitstream.NewWriter(buf)
+			w.WriteNBitsOfUint8(3, 0x5) // 101
+			err = tbl.WriteTable(w)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			e := NewEncoder(tbl)
+			for _, s := range syms {
+				err = e.Encode(s)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+			}
+			err = e.Flush(w)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.WriteNBitsOfUint8(5, 0x1b) // 1 1011
+			w.Finalize()
+
+			r := bitstream.NewReader(buf, nil)
+			r.Skip(3)
+			tbl2, err := ReadTable(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(tbl, tbl2) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", tbl.freqs, tbl2.freqs)
+			}
+			d, err := NewDecoder(r, tbl2)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if d.Len() != data.N {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.N, d.Len())
+			}
+			for i, s := range syms {
+				actual, err := d.Decode()
+				if err != nil {
+					t.Fatalf("unexpected error at %d: %+v\n", i, err)
+				}
+				if s != actual {
+					t.Fatalf("\nsymbol %d\nExpected: %+v\nActual:   %+v\n", i, s, actual)
+				}
+			}
+			_, err = d.Decode()
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+			}
+
+			// the bits after the coded data
+			trailer, err := r.ReadNBitsAsUint8(5)
+			if err != nil || trailer != 0x1b {
+				t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0x1b, nil, trailer, err)
+			}
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tbl, _ := FromCounts([]uint64{90, 5, 3, 2}, 12)
+
+	e := NewEncoder(tbl)
+	for i := 0; i < 100000; i++ {
+		x := rnd.Intn(100)
+		switch {
+		case x < 90:
+			e.Encode(0)
+		case x < 95:
+			e.Encode(1)
+		case x < 98:
+			e.Encode(2)
+		default:
+			e.Encode(3)
+		}
+	}
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	e.Flush(w)
+	w.Finalize()
+
+	// entropy is about 0.62 bits per symbol, i.e. 7750 bytes for 100000 symbols
+	if buf.Len() > 8000 {
+		t.Fatalf("\nExpected: <= %+v\nActual:   %+v\n", 8000, buf.Len())
+	}
+}
+
+func TestError(t *testing.T) {
+	tbl, _ := FromCounts([]uint64{1, 0, 1}, 4)
+	err := NewEncoder(tbl).Encode(1)
+	if !errors.Is(err, ErrInvalidSymbol) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidSymbol, err)
+	}
+
+	// n = 1, state0 < 1 << 23
+	header := []byte{0, 0, 0, 1, 0, 0x7f, 0xff, 0xff, 0, 0x80, 0, 0}
+	_, err = NewDecoder(bitstream.NewReader(bytes.NewReader(header), nil), tbl)
+	if !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidState, err)
+	}
+
+	// the renormalization bytes are missing
+	header = []byte{0, 0, 0, 1, 0, 0x80, 0, 0, 0, 0x80, 0, 0}
+	d, _ := NewDecoder(bitstream.NewReader(bytes.NewReader(header), nil), tbl)
+	_, err = d.Decode()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	counts := make([]uint64, 256)
+	for i := range counts {
+		counts[i] = uint64(rnd.Intn(1000)) + 1
+	}
+	tbl, _ := FromCounts(counts, 12)
+
+	e := NewEncoder(tbl)
+	for i := 0; i < 1<<16; i++ {
+		e.Encode(rnd.Intn(len(counts)))
+	}
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriterWithOptions(buf, &bitstream.WriterOptions{BufferSize: 4096})
+	e.Flush(w)
+	w.Finalize()
+	data := buf.Bytes()
+
+	b.ResetTimer()
+	var d *Decoder
+	for i := 0; i < b.N; i++ {
+		if i%(1<<16) == 0 {
+			d, _ = NewDecoder(bitstream.NewReader(bytes.NewReader(data), &bitstream.ReaderOptions{BufferSize: 4096}), tbl)
+		}
+		d.Decode()
+	}
+}
@@ -0,0 +1,66 @@
+package bitstream
+
+import (
+	"io"
+)
+
+// ReadFromIO reads bytes from `r` until EOF and writes them to the bit stream at the current, possibly unaligned, position.
+// The bytes are streamed in chunks, so `r` does not have to fit in memory.
+// It returns the number of bits written. EOF of `r` is not regarded as an error.
+//
+// Note that it is not named ReadFrom since it returns the number of bits, while io.ReaderFrom returns the number of bytes.
+func (w *Writer) ReadFromIO(r io.Reader) (uint64, error) {
+	pos := w.bitPosition()
+	n, err := w.readFromIO(r, 0, false)
+	if err != nil {
+		return n, w.wrapError("ReadFromIO", pos, err)
+	}
+	return n, nil
+}
+
+// ReadFromION reads `nBytes` bytes from `r` and writes them to the bit stream at the current, possibly unaligned, position.
+// It returns the number of bits written.
+// If `r` ends before `nBytes` bytes are read, it returns io.EOF if no bytes are read, io.ErrUnexpectedEOF otherwise.
+func (w *Writer) ReadFromION(r io.Reader, nBytes uint64) (uint64, error) {
+	pos := w.bitPosition()
+	n, err := w.readFromIO(r, nBytes, true)
+	if err != nil {
+		return n, w.wrapError("ReadFromION", pos, err)
+	}
+	return n, nil
+}
+
+func (w *Writer) readFromIO(r io.Reader, nBytes uint64, bounded bool) (uint64, error) {
+	chunkSize := uint64(runChunkSize)
+	if bounded && nBytes < chunkSize {
+		chunkSize = nBytes
+	}
+	chunk := make([]byte, chunkSize)
+
+	written := uint64(0)
+	for !bounded || written < nBytes*8 {
+		c := chunk
+		if bounded && nBytes-written/8 < uint64(len(c)) {
+			c = c[:nBytes-written/8]
+		}
+
+		n, err := r.Read(c)
+		if n > 0 {
+			werr := w.writeBytes(c[:n])
+			if werr != nil {
+				return written, werr
+			}
+			written += uint64(n) * 8
+		}
+		if err == io.EOF {
+			if bounded {
+				return written, eofAfter(written, err)
+			}
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
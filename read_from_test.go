@@ -0,0 +1,94 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadFromIO(t *testing.T) {
+	src := make([]byte, 10000)
+	rand.New(rand.NewSource(1)).Read(src)
+
+	for _, offset := range []uint8{0, 3} {
+		eb := bytes.NewBuffer([]byte{})
+		ew := NewWriter(eb)
+		ew.WriteNBitsOfUint8(offset, 0xff)
+		ew.WriteBytes(src)
+		ew.Finalize()
+
+		ab := bytes.NewBuffer([]byte{})
+		aw := NewWriter(ab)
+		aw.WriteNBitsOfUint8(offset, 0xff)
+		n, err := aw.ReadFromIO(iotest.HalfReader(bytes.NewReader(src)))
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if uint64(len(src))*8 != n {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", len(src)*8, n)
+		}
+		aw.Finalize()
+
+		if !bytes.Equal(eb.Bytes(), ab.Bytes()) {
+			t.Fatalf("\noffset: %d\nExpected: %+v\nActual:   %+v\n", offset, eb.Bytes(), ab.Bytes())
+		}
+	}
+}
+
+func TestReadFromION(t *testing.T) {
+	testData := []struct {
+		Name          string
+		NBytes        uint64
+		ExpectedBits  uint64
+		ExpectedBytes []byte
+		ExpectedErr   error
+	}{
+		{
+			// 101 0000 0001 0000 0010 | 00000
+			Name:          "pattern 1",
+			NBytes:        2,
+			ExpectedBits:  16,
+			ExpectedBytes: []byte{0xa0, 0x20, 0x40},
+			ExpectedErr:   nil,
+		},
+		{
+			// 101 0000 0001 0000 0010 0000 0011 | 00000
+			Name:          "pattern 2",
+			NBytes:        4,
+			ExpectedBits:  24,
+			ExpectedBytes: []byte{0xa0, 0x20, 0x40, 0x60},
+			ExpectedErr:   io.ErrUnexpectedEOF,
+		},
+		{
+			Name:          "pattern 3",
+			NBytes:        0,
+			ExpectedBits:  0,
+			ExpectedBytes: []byte{0xa0},
+			ExpectedErr:   nil,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			bw := NewWriter(buf)
+			bw.WriteNBitsOfUint8(3, 0x05)
+
+			n, err := bw.ReadFromION(bytes.NewReader([]byte{0x01, 0x02, 0x03}), data.NBytes)
+			if !errors.Is(err, data.ExpectedErr) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedErr, err)
+			}
+			if data.ExpectedBits != n {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedBits, n)
+			}
+			bw.Finalize()
+			if !bytes.Equal(data.ExpectedBytes, buf.Bytes()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedBytes, buf.Bytes())
+			}
+		})
+	}
+}
@@ -1,32 +1,103 @@
 package bitstream
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
-
-	"github.com/pkg/errors"
+	"log/slog"
+	"math/bits"
 )
 
 const (
 	DefaultBufferSize = 1024
+
+	// maxConsecutiveEmptyReads is the number of times fillBuf retries when the source returns no data and no error.
+	maxConsecutiveEmptyReads = 100
 )
 
 // Reader is a bit stream reader.
 // It does not have io.Reader interface.
+//
+// If the stream ends exactly before a field to be read, the read methods return io.EOF.
+// If the stream ends in the middle of a field, they return io.ErrUnexpectedEOF.
+//
+// A Reader is not safe for concurrent use by multiple goroutines.
+// Use SyncReader to share a Reader among goroutines.
 type Reader struct {
 	src           io.Reader
 	srcEOF        bool
+	srcErr        error // error returned from the source together with some data, reported by the next fillBuf
 	buf           []byte
 	bufLen        uint
 	currByteIndex uint  // starts from 0
 	currBitIndex  uint8 // MSB: 7, LSB: 0
 	consumedBytes uint
 	opt           *ReaderOptions
+	prefetch      *prefetcher
+	closed        bool
+	inMemory      bool         // buf holds the whole bit stream, see NewReaderBytes
+	release       func() error // called by Close to release buf, see NewReaderMmap
+	spare         []byte       // the buffer kept while the Reader reads bytes in place, see ResetBytes
+	origin        uint64       // number of padding bits before the bit stream in the first byte, see Lookahead
+	stats         ReaderStats
+	allocated     uint64 // number of bytes allocated for the values read, see ReaderLimits.MaxAllocBytes
+	lastBits      uint64 // number of bits consumed by the last read method, see LastBits
 }
 
+// EOFMode specifies how a Reader behaves when the stream ends in the middle of a field.
+type EOFMode int
+
+const (
+	// EOFStrict makes read methods return io.ErrUnexpectedEOF and no value when the stream ends in the middle of a field.
+	// This is the default.
+	EOFStrict EOFMode = iota
+
+	// EOFLenient makes read methods return the bits read so far, padded with zeros to the requested width,
+	// together with a *PartialFieldError which tells how many bits were actually read.
+	EOFLenient
+)
+
 // ReaderOptions is a set of options for creating a Reader.
 type ReaderOptions struct {
 	BufferSize uint
+	EOFMode    EOFMode
+
+	// TraceHook is called for each field read successfully from the bit stream, see TraceHook for the details.
+	TraceHook TraceHook
+
+	// Hooks is a set of callbacks to observe the I/O of the Reader.
+	Hooks *ReaderHooks
+
+	// Prefetch makes the Reader fill a second buffer in a background goroutine while the current one is consumed.
+	// The source must not be used by anyone else once reading has started, and Close should be called to stop the goroutine.
+	// An error from the source is permanent in this mode.
+	Prefetch bool
+
+	// PlainErrors makes the Reader return the errors as they are, e.g. io.ErrUnexpectedEOF, instead of wrapping them
+	// in a *PositionError, so that returning an error costs no allocations. It is useful for fuzzers and lenient parsers
+	// which hit errors frequently; call BitPosition before an operation if its position is needed.
+	PlainErrors bool
+
+	// BitOrder is the bit order used by ReadNBitsAsUint64. MSBFirstBigEndian is used if it is nil.
+	BitOrder BitOrder
+
+	// BitNumbering is the numbering of the bits in a byte used by BytePosition and the errors.
+	BitNumbering BitNumbering
+
+	// Logger logs the events of the Reader at the Debug level to diagnose a parse, each with its "bit_offset":
+	// "bitstream: refill" with "bytes" and "read_calls", "bitstream: unaligned bytes" with "bytes" for the bytes read
+	// while the bit stream is not byte aligned, and "bitstream: error" with "op" and "error" for each error returned, including io.EOF.
+	Logger *slog.Logger
+
+	// Limits bounds the sizes accepted by the Reader for untrusted input. See ReaderLimits.
+	Limits ReaderLimits
+
+	// ReadOptions is used by ReadNBits and ReadNBitsNamed when they are called with nil options,
+	// e.g. for a format whose fields are always padded with '1'. Options given to a call replace it as a whole.
+	// AlignRight is not implemented yet: if it is set here, every ReadNBits and ReadNBitsNamed call with nil options
+	// returns ErrNotImplemented, so only PadOne should be set.
+	ReadOptions *ReadOptions
 }
 
 // GetBufferSize gets configured buffer size.
@@ -37,6 +108,30 @@ func (opt *ReaderOptions) GetBufferSize() uint {
 	return opt.BufferSize
 }
 
+// GetEOFMode gets configured EOF mode.
+func (opt *ReaderOptions) GetEOFMode() EOFMode {
+	if opt == nil {
+		return EOFStrict
+	}
+	return opt.EOFMode
+}
+
+// GetPlainErrors gets whether the errors are returned without position information.
+func (opt *ReaderOptions) GetPlainErrors() bool {
+	if opt == nil {
+		return false
+	}
+	return opt.PlainErrors
+}
+
+// GetPrefetch gets whether the background prefetch is enabled or not.
+func (opt *ReaderOptions) GetPrefetch() bool {
+	if opt == nil {
+		return false
+	}
+	return opt.Prefetch
+}
+
 // NewReader creates a new Reader instance with options.
 func NewReader(src io.Reader, opt *ReaderOptions) *Reader {
 	return &Reader{
@@ -50,6 +145,76 @@ func NewReader(src io.Reader, opt *ReaderOptions) *Reader {
 	}
 }
 
+// NewReaderWithBuffer creates a new Reader instance which uses `buf` as its buffer instead of allocating one,
+// e.g. to take the buffers from a sync.Pool or an arena. The buffer size is len(buf) and opt.BufferSize is ignored.
+// `buf` must not be used by the caller until the Reader is no longer used. Peek replaces it with a larger buffer
+// if it needs more bytes than len(buf), and Prefetch allocates a second buffer of the same size.
+// If `buf` is empty, it is the same as NewReader.
+func NewReaderWithBuffer(src io.Reader, buf []byte, opt *ReaderOptions) *Reader {
+	if len(buf) == 0 {
+		return NewReader(src, opt)
+	}
+	o := ReaderOptions{}
+	if opt != nil {
+		o = *opt
+	}
+	o.BufferSize = uint(len(buf))
+	r := NewReader(src, &o)
+	r.buf = buf
+	return r
+}
+
+// Reset discards the state of the Reader and makes it read from `src`, keeping its options and buffer,
+// so that a Reader can be reused, e.g. for each message, without allocations.
+// The background prefetch, if running, is stopped; `src` must not be shared with the previous source then.
+// A Reader created by NewReaderMmap is unmapped by Reset if it has not been closed.
+func (r *Reader) Reset(src io.Reader) {
+	r.reset()
+	if r.inMemory {
+		r.buf = r.spare
+		r.spare = nil
+		r.inMemory = false
+	}
+	r.src = src
+}
+
+// ResetBytes is the same as Reset except that the Reader reads `data` in place like the one created by NewReaderBytes.
+// The buffer of the Reader is kept for a later Reset.
+func (r *Reader) ResetBytes(data []byte) {
+	r.reset()
+	if !r.inMemory {
+		r.spare = r.buf
+		r.inMemory = true
+	}
+	r.src = nil
+	r.buf = data
+	r.bufLen = uint(len(data))
+	r.srcEOF = true
+}
+
+func (r *Reader) reset() {
+	if r.prefetch != nil {
+		r.prefetch.stop()
+		r.prefetch = nil
+	}
+	if r.release != nil {
+		r.release()
+		r.release = nil
+		r.buf = nil
+	}
+	r.srcEOF = false
+	r.srcErr = nil
+	r.bufLen = 0
+	r.currByteIndex = 0
+	r.currBitIndex = 7
+	r.consumedBytes = 0
+	r.allocated = 0
+	r.lastBits = 0
+	r.origin = 0
+	r.closed = false
+	r.stats = ReaderStats{}
+}
+
 func (r *Reader) dump() {
 	fmt.Printf("srcEOF=%t, bufLen=%d, currByteIndex=%d, currBitIndex=%d\n", r.srcEOF, r.bufLen, r.currByteIndex, r.currBitIndex)
 }
@@ -66,10 +231,48 @@ func (r *Reader) isBufEmpty() bool {
 	return false
 }
 
+// fillBuf reads at least 1 byte from the source into the buffer.
+// Like io.ReadFull, it retries when the source returns no data without an error,
+// and keeps the data when the source returns some data together with an error.
+// In the latter case, the error is reported by the next call.
 func (r *Reader) fillBuf() error {
-	buf := make([]byte, r.opt.GetBufferSize())
-	n, err := r.src.Read(buf[:])
-	if err != nil {
+	if r.closed {
+		return ErrClosed
+	}
+
+	if r.srcErr != nil {
+		err := r.srcErr
+		r.srcErr = nil
+		return err
+	}
+
+	if r.srcEOF {
+		return io.EOF
+	}
+
+	var buf []byte
+	var n, calls int
+	var err error
+	if r.opt.GetPrefetch() {
+		if r.prefetch == nil {
+			r.prefetch = startPrefetch(r.src, r.opt.GetBufferSize(), r.buf)
+			r.buf = nil
+		}
+		buf, n, calls, err = r.prefetch.next(r.buf)
+	} else {
+		size := r.opt.GetBufferSize()
+		buf = r.buf
+		if uint(len(buf)) != size {
+			buf = make([]byte, size)
+		}
+		n, calls, err = readSource(r.src, buf)
+	}
+	r.countRefill(n, calls)
+
+	if err == io.EOF {
+		r.srcEOF = true
+	}
+	if n == 0 {
 		return err
 	}
 
@@ -77,9 +280,57 @@ func (r *Reader) fillBuf() error {
 	r.bufLen = uint(n)
 	r.currByteIndex = 0
 	r.currBitIndex = 7
+	if err != nil && err != io.EOF {
+		r.srcErr = err
+	}
+	return nil
+}
+
+// readSource reads at least 1 byte from `src` into `buf` unless an error occurs.
+// It retries when the source returns no data without an error.
+// It also returns the number of calls to the Read method of `src`.
+func readSource(src io.Reader, buf []byte) (int, int, error) {
+	for i := 0; i < maxConsecutiveEmptyReads; i++ {
+		n, err := src.Read(buf)
+		if n < 0 || n > len(buf) {
+			return 0, i + 1, ErrInvalidRead
+		}
+		if n > 0 || err != nil {
+			return n, i + 1, err
+		}
+	}
+	return 0, maxConsecutiveEmptyReads, io.ErrNoProgress
+}
+
+// Close stops the background prefetch if it is running.
+// It does not close the source.
+// The bits remaining in the buffer can still be read, but reading beyond them returns ErrClosed.
+// For a Reader created by NewReaderMmap, Close unmaps the file and no bits can be read any more.
+func (r *Reader) Close() error {
+	r.closed = true
+	if r.prefetch != nil {
+		r.prefetch.stop()
+	}
+	if r.release != nil {
+		release := r.release
+		r.release = nil
+		r.buf = nil
+		r.bufLen = 0
+		r.currByteIndex = 0
+		return release()
+	}
 	return nil
 }
 
+// unexpectedEOF converts io.EOF into io.ErrUnexpectedEOF.
+// It is used when the stream ends after some bits of a field have already been consumed.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
 func (r *Reader) fillBufIfNeeded() error {
 	if !r.isBufEmpty() {
 		return nil
@@ -87,32 +338,44 @@ func (r *Reader) fillBufIfNeeded() error {
 	return r.fillBuf()
 }
 
+// forwardIndecies advances the current position by `nBits` bits.
+// The bytes passed over must be in the buffer; the Read paths never pass the end of the current byte.
 func (r *Reader) forwardIndecies(nBits uint8) {
 	if nBits <= r.currBitIndex {
 		r.currBitIndex -= nBits
 		return
 	}
 
-	nBits = nBits - r.currBitIndex
+	nBits -= r.currBitIndex + 1 // bits to go after the current byte
 	nBytes := uint(nBits/8) + 1
 	r.currByteIndex += nBytes
 	r.consumedBytes += nBytes
+	r.currBitIndex = 7 - nBits%8
+}
 
-	bitsToGo := (nBits % 8)
-	r.currBitIndex = 8 - bitsToGo
+// BitPosition returns the number of bits that has been consumed, i.e. the offset of the next bit to be read.
+func (r *Reader) BitPosition() uint64 {
+	return uint64(r.consumedBytes)*8 + uint64(7-r.currBitIndex) - r.origin
 }
 
 // ConsumedBytes returns a number of bytes that has been consumed.
 func (r *Reader) ConsumedBytes() uint {
-	if r.currBitIndex != 7 {
-		return r.consumedBytes + 1
-	}
-	return r.consumedBytes
+	return uint((r.BitPosition() + 7) / 8)
 }
 
 // ReadBit reads a single bit from the bit stream.
 // The bit read from the stream will be set in the LSB of the return value.
 func (r *Reader) ReadBit() (byte, error) {
+	pos := r.BitPosition()
+	b, err := r.readBit()
+	if err != nil {
+		return 0, r.wrapError("ReadBit", pos, err)
+	}
+	r.trace("", pos, 1, b)
+	return b, nil
+}
+
+func (r *Reader) readBit() (byte, error) {
 	err := r.fillBufIfNeeded()
 	if err != nil {
 		return 0, err
@@ -134,110 +397,233 @@ func (r *Reader) ReadBool() (bool, error) {
 	return b != 0, nil
 }
 
-func (r *Reader) mustReadNBitsInCurrentByte(nBits uint8) byte {
-	if nBits == 0 {
-		return 0
-	}
+// ReadRun reads a run of identical bits from the bit stream.
+// It returns the value of the bits in the run (0 or 1) and the length of the run.
+// The first bit which differs from the run is not consumed.
+// Reaching the end of the stream terminates the run without an error as long as at least 1 bit has been read.
+func (r *Reader) ReadRun() (byte, uint64, error) {
+	return r.ReadRunN(0)
+}
 
-	if r.currBitIndex < (nBits - 1) {
-		panic(fmt.Sprintf("%+v", errors.New("insufficient bits to read")))
+// ReadRunN is the same as ReadRun except that at most `max` bits are consumed.
+// If `max` == 0, the length of the run is not limited.
+func (r *Reader) ReadRunN(max uint64) (byte, uint64, error) {
+	pos := r.BitPosition()
+	bit, length, err := r.readRunN(max)
+	if err != nil {
+		return 0, 0, r.wrapError("ReadRunN", pos, err)
 	}
-
-	b := r.buf[r.currByteIndex]
-	mask := uint8((1 << (r.currBitIndex + 1)) - 1)
-	result := (b & mask) >> (r.currBitIndex - (nBits - 1))
-	r.forwardIndecies(nBits)
-	return result
+	if r.done(pos) {
+		r.trace("", pos, uint(length), Run{Bit: bit, Length: length})
+	}
+	return bit, length, nil
 }
 
-// ReadNBitsAsUint8 reads `nBits` bits as a unsigned integer from the bit stream and returns it in uint8 (LSB aligned).
-// `nBits` must be less than or equal to 8, otherwise returns an error.
-// If `nBits` == 0, this function always returns 0.
-func (r *Reader) ReadNBitsAsUint8(nBits uint8) (uint8, error) {
-	if nBits == 0 {
-		return 0, nil
+func (r *Reader) readRunN(max uint64) (byte, uint64, error) {
+	bit, err := r.readBit()
+	if err != nil {
+		return 0, 0, err
 	}
 
-	if nBits > 8 {
-		return 0, errors.New("nBits too large for uint8")
+	fill := uint8(0x00)
+	if bit != 0 {
+		fill = 0xff
 	}
 
-	err := r.fillBufIfNeeded()
-	if err != nil {
-		return 0, err
-	}
+	length := uint64(1)
+	for max == 0 || length < max {
+		err := r.fillBufIfNeeded()
+		if err == io.EOF {
+			return bit, length, nil
+		}
+		if err != nil {
+			return bit, length, err
+		}
 
-	// remaining bits in current byte
-	rb := r.currBitIndex + 1
+		// remaining bits in current byte, left aligned. bits which differ from the run become 1.
+		rb := r.currBitIndex + 1
+		b := (r.buf[r.currByteIndex] ^ fill) << (7 - r.currBitIndex)
+		n := uint8(bits.LeadingZeros8(b))
+		if n > rb {
+			n = rb
+		}
+		if max != 0 && uint64(n) > max-length {
+			n = uint8(max - length)
+		}
 
-	if nBits <= rb { // can be read from the current byte
-		b := r.mustReadNBitsInCurrentByte(nBits)
-		return b, nil
+		r.forwardIndecies(n)
+		length += uint64(n)
+		if n < rb {
+			break
+		}
 	}
 
-	// 8 bits are distributed in 2 bytes
-	nBits1 := rb
-	nBits2 := nBits - rb
+	return bit, length, nil
+}
 
-	b1 := r.mustReadNBitsInCurrentByte(nBits1)
-	b2, err := r.ReadNBitsAsUint8(nBits2)
+// CountLeadingZeros reads bits until a '1' bit is found and returns the number of '0' bits preceding it.
+// The terminating '1' bit is consumed as well.
+// This is the building block of unary and Exp-Golomb decoders.
+func (r *Reader) CountLeadingZeros() (uint64, error) {
+	pos := r.BitPosition()
+	n, err := r.countLeadingBits(0x00)
 	if err != nil {
-		return 0, err
+		return 0, r.wrapError("CountLeadingZeros", pos, err)
+	}
+	if r.done(pos) {
+		r.trace("", pos, uint(n)+1, n)
 	}
+	return n, nil
+}
 
-	return (b1 << nBits2) | b2, nil
+// CountLeadingOnes reads bits until a '0' bit is found and returns the number of '1' bits preceding it.
+// The terminating '0' bit is consumed as well.
+func (r *Reader) CountLeadingOnes() (uint64, error) {
+	pos := r.BitPosition()
+	n, err := r.countLeadingBits(0xff)
+	if err != nil {
+		return 0, r.wrapError("CountLeadingOnes", pos, err)
+	}
+	if r.done(pos) {
+		r.trace("", pos, uint(n)+1, n)
+	}
+	return n, nil
 }
 
-// ReadUint8 reads 8 bits from the bit stream and returns it in uint8.
-func (r *Reader) ReadUint8() (uint8, error) {
-	return r.ReadNBitsAsUint8(8)
+// countLeadingBits counts the bits which are the same as the ones in `fill` (0x00 or 0xff) and consumes them and the terminating bit.
+func (r *Reader) countLeadingBits(fill uint8) (uint64, error) {
+	count := uint64(0)
+	for {
+		err := r.fillBufIfNeeded()
+		if err != nil {
+			if count > 0 {
+				return 0, unexpectedEOF(err)
+			}
+			return 0, err
+		}
+
+		// remaining bits in current byte, left aligned. bits which differ from `fill` become 1.
+		rb := r.currBitIndex + 1
+		b := (r.buf[r.currByteIndex] ^ fill) << (7 - r.currBitIndex)
+		n := uint8(bits.LeadingZeros8(b))
+		if n < rb {
+			r.forwardIndecies(n + 1)
+			return count + uint64(n), nil
+		}
+		r.forwardIndecies(rb)
+		count += uint64(rb)
+
+		// skip whole bytes in the buffer
+		for r.currByteIndex < r.bufLen && r.buf[r.currByteIndex] == fill {
+			r.currByteIndex++
+			r.consumedBytes++
+			count += 8
+		}
+	}
 }
 
-// ReadNBitsAsUint16BE reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint16 (LSB aligned).
-// `nBits` must be less than or equal to 16, otherwise returns an error.
-// If `nBits` == 0, this function always returns 0.
-func (r *Reader) ReadNBitsAsUint16BE(nBits uint8) (uint16, error) {
+// readBits reads `nBits` (<= 64) bits from the bit stream and returns them LSB aligned.
+// It also returns the number of bits actually read, which is less than `nBits` only when an error occurs.
+func (r *Reader) readBits(nBits uint8) (uint64, uint8, error) {
 	if nBits == 0 {
-		return 0, nil
+		return 0, 0, nil
+	}
+
+	// fast path: load 8 bytes from the buffer into a 64-bit register and extract the bits with shifts.
+	// the 9th byte is needed only when the field straddles it.
+	skip := uint(7 - r.currBitIndex) // bits already consumed in current byte
+	end := skip + uint(nBits)        // bits to be consumed from current byte
+	need := uint(8)
+	if end > 64 {
+		need = 9
+	}
+	if r.buf != nil && r.currByteIndex+need <= r.bufLen {
+		acc := binary.BigEndian.Uint64(r.buf[r.currByteIndex:])
+		v := (acc << skip) >> (64 - uint(nBits))
+		if end > 64 {
+			v |= uint64(r.buf[r.currByteIndex+8]) >> (72 - end)
+		}
+		r.currByteIndex += end / 8
+		r.consumedBytes += end / 8
+		r.currBitIndex = 7 - uint8(end%8)
+		return v, nBits, nil
 	}
 
-	if nBits <= 8 {
-		v, err := r.ReadNBitsAsUint8(nBits)
-		return uint16(v), err
+	// slow path: the field may straddle any number of refills, e.g. with a small buffer or a source which returns
+	// a byte at a time. each iteration consumes bits of the current byte only, and refills the buffer when it is used up.
+	v := uint64(0)
+	read := uint8(0)
+	for read < nBits {
+		err := r.fillBufIfNeeded()
+		if err != nil {
+			if read > 0 {
+				err = unexpectedEOF(err)
+			}
+			return v, read, err
+		}
+
+		// remaining bits in current byte
+		rb := r.currBitIndex + 1
+		n := min(nBits-read, rb)
+		b := r.buf[r.currByteIndex] & (1<<rb - 1) >> (rb - n)
+		r.forwardIndecies(n)
+		v = (v << n) | uint64(b)
+		read += n
 	}
+	return v, read, nil
+}
 
-	if nBits > 16 {
-		return 0, errors.New("nBits too large for uint16")
+// readUint reads `nBits` bits as an unsigned integer which has `maxBits` bits at most.
+// In the lenient EOF mode, a field which is cut off by the end of the stream is returned padded with zeros together with a PartialFieldError.
+func (r *Reader) readUint(nBits, maxBits uint8) (uint64, error) {
+	if nBits > maxBits {
+		return 0, tooManyBits(maxBits)
 	}
 
-	err := r.fillBufIfNeeded()
+	v, read, err := r.readBits(nBits)
 	if err != nil {
+		if read > 0 && r.opt.GetEOFMode() == EOFLenient && errors.Is(err, io.ErrUnexpectedEOF) {
+			return v << (nBits - read), &PartialFieldError{RequestedBits: uint(nBits), ReadBits: uint(read)}
+		}
 		return 0, err
 	}
+	return v, nil
+}
 
-	// remaining bits in current byte
-	rb := r.currBitIndex + 1
-
-	// 16 bits may be distributed in up to 3 bytes
-	nBits1 := rb         // count of bits in the first byte
-	nBits2 := nBits - rb // count of bits in the second byte
-	nBits3 := uint8(0)   // count of bits in the third byte
-	if nBits2 > 8 {
-		nBits3 = nBits2 - 8
-		nBits2 = 8
+// ReadNBitsAsUint8 reads `nBits` bits as a unsigned integer from the bit stream and returns it in uint8 (LSB aligned).
+// `nBits` must be less than or equal to 8, otherwise returns an error.
+// If `nBits` == 0, this function always returns 0.
+func (r *Reader) ReadNBitsAsUint8(nBits uint8) (uint8, error) {
+	pos := r.BitPosition()
+	v, err := r.readUint(nBits, 8)
+	if err == nil {
+		r.trace("", pos, uint(nBits), uint8(v))
 	}
+	return uint8(v), r.wrapError("ReadNBitsAsUint8", pos, err)
+}
 
-	b1 := r.mustReadNBitsInCurrentByte(nBits1)
-	b2, err := r.ReadNBitsAsUint8(nBits2)
-	if err != nil {
-		return 0, err
-	}
-	b3, err := r.ReadNBitsAsUint8(nBits3) // expects this function returns 0 if nBits3 == 0
-	if err != nil {
-		return 0, err
-	}
+// ReadUint8 reads 8 bits from the bit stream and returns it in uint8.
+func (r *Reader) ReadUint8() (uint8, error) {
+	return r.ReadNBitsAsUint8(8)
+}
 
-	return (uint16(b1) << (nBits2 + nBits3)) | (uint16(b2) << nBits3) | uint16(b3), nil
+// ReadNibble reads 4 bits from the bit stream and returns it in uint8 (LSB aligned), e.g. a BCD or hex digit.
+func (r *Reader) ReadNibble() (uint8, error) {
+	return r.ReadNBitsAsUint8(4)
+}
+
+// ReadNBitsAsUint16BE reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint16 (LSB aligned).
+// `nBits` must be less than or equal to 16, otherwise returns an error.
+// If `nBits` == 0, this function always returns 0.
+func (r *Reader) ReadNBitsAsUint16BE(nBits uint8) (uint16, error) {
+	pos := r.BitPosition()
+	v, err := r.readUint(nBits, 16)
+	if err == nil {
+		if r.done(pos) {
+			r.trace("", pos, uint(nBits), uint16(v))
+		}
+	}
+	return uint16(v), r.wrapError("ReadNBitsAsUint16BE", pos, err)
 }
 
 // ReadUint16BE reads 16 bits as a big endian unsigned integer from the bit stream and returns it in uint16.
@@ -249,61 +635,14 @@ func (r *Reader) ReadUint16BE() (uint16, error) {
 // `nBits` must be less than or equal to 32, otherwise returns an error.
 // If `nBits` == 0, this function always returns 0.
 func (r *Reader) ReadNBitsAsUint32BE(nBits uint8) (uint32, error) {
-	if nBits == 0 {
-		return 0, nil
-	}
-
-	if nBits <= 16 {
-		v, err := r.ReadNBitsAsUint16BE(nBits)
-		return uint32(v), err
-	}
-
-	if nBits > 32 {
-		return 0, errors.New("nBits too large for uint32")
-	}
-
-	err := r.fillBufIfNeeded()
-	if err != nil {
-		return 0, err
-	}
-
-	// remaining bits in current byte
-	rb := r.currBitIndex + 1
-
-	// 32 bits may be distributed in up to 5 bytes
-	nBits1 := rb
-	nBits2 := uint8(8)
-	nBits3 := nBits - rb - 8
-	nBits4 := uint8(0)
-	nBits5 := uint8(0)
-	if nBits3 > 8 {
-		nBits4 = nBits3 - 8
-		if nBits4 > 8 {
-			nBits5 = nBits4 - 8
-			nBits4 = 8
+	pos := r.BitPosition()
+	v, err := r.readUint(nBits, 32)
+	if err == nil {
+		if r.done(pos) {
+			r.trace("", pos, uint(nBits), uint32(v))
 		}
-		nBits3 = 8
-	}
-
-	b1 := r.mustReadNBitsInCurrentByte(nBits1)
-	b2, err := r.ReadNBitsAsUint8(nBits2)
-	if err != nil {
-		return 0, err
-	}
-	b3, err := r.ReadNBitsAsUint8(nBits3)
-	if err != nil {
-		return 0, err
-	}
-	b4, err := r.ReadNBitsAsUint8(nBits4)
-	if err != nil {
-		return 0, err
-	}
-	b5, err := r.ReadNBitsAsUint8(nBits5)
-	if err != nil {
-		return 0, err
 	}
-
-	return (uint32(b1) << (nBits2 + nBits3 + nBits4 + nBits5)) | (uint32(b2) << (nBits3 + nBits4 + nBits5)) | (uint32(b3) << (nBits4 + nBits5)) | (uint32(b4) << (nBits5)) | uint32(b5), nil
+	return uint32(v), r.wrapError("ReadNBitsAsUint32BE", pos, err)
 }
 
 // ReadUint32BE reads 32 bits as a big endian unsigned integer from the bit stream and returns it in uint32.
@@ -316,218 +655,349 @@ func (r *Reader) ReadUint32BE() (uint32, error) {
 // `nBits` must be less than or equal to 32, otherwise returns an error.
 // If `nBits` == 0, this function always returns 0.
 func (r *Reader) ReadNBitsAsInt32BE(nBits uint8) (int32, error) {
-	v, err := r.ReadNBitsAsUint32BE(nBits)
-	if err != nil {
-		return 0, err
+	pos := r.BitPosition()
+	v, err := r.readNBitsAsInt32BE(nBits)
+	if err == nil {
+		if r.done(pos) {
+			r.trace("", pos, uint(nBits), v)
+		}
 	}
+	return v, r.wrapError("ReadNBitsAsInt32BE", pos, err)
+}
+
+func (r *Reader) readNBitsAsInt32BE(nBits uint8) (int32, error) {
+	u, err := r.readUint(nBits, 32)
+	v := uint32(u)
 
 	//fmt.Printf("v   == %#08x\n", v)
 	msb := uint32(1) << (nBits - 1)
 	//fmt.Printf("msb == %#08x\n", msb)
 
 	if (v & msb) == 0 {
-		return int32(v), nil
+		return int32(v), err
 	}
 
 	f := 0xffffffff & ^(msb - 1)
 	//fmt.Printf("f   ==%#08x\n", f)
 	//fmt.Printf("f|v ==%#08x\n", f|v)
-	return int32(f | v), nil
+	return int32(f | v), err
 }
 
 // ReadNBitsAsUint64BE reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint64 (LSB aligned).
 // `nBits` must be less than or equal to 64, otherwise returns an error.
 // If `nBits` == 0, this function always returns 0.
 func (r *Reader) ReadNBitsAsUint64BE(nBits uint8) (uint64, error) {
-	if nBits == 0 {
-		return 0, nil
+	pos := r.BitPosition()
+	v, err := r.readUint(nBits, 64)
+	if err == nil {
+		if r.done(pos) {
+			r.trace("", pos, uint(nBits), v)
+		}
 	}
+	return v, r.wrapError("ReadNBitsAsUint64BE", pos, err)
+}
 
-	if nBits <= 32 {
-		v, err := r.ReadNBitsAsUint32BE(nBits)
-		return uint64(v), err
-	}
+// ReadUint64BE reads 64 bits as a big endian unsigned integer from the bit stream and returns it in uint64.
+func (r *Reader) ReadUint64BE() (uint64, error) {
+	return r.ReadNBitsAsUint64BE(64)
+}
 
-	if nBits > 64 {
-		return 0, errors.New("nBits too large for uint64")
-	}
+// ReadOptions is a set of options to read bits from the bit stream.
+type ReadOptions struct {
+	AlignRight bool // If true, returned value will be aligned to right (default: align to left). Not implemented yet: ReadNBits returns ErrNotImplemented
+	PadOne     bool // If true, returned value will be padded with '1' instead of '0' (default: pad with '0')
+}
 
-	err := r.fillBufIfNeeded()
-	if err != nil {
-		return 0, err
-	}
+// ReadNBits reads `nBits` bits from the bit stream and returns it as a slice of bytes.
+// `nBits` is not limited by the width of any integer type, so a large blob can be read in a single call.
+// If `nBits` == 0, this function always returns nil.
+// If `opt` is nil, ReaderOptions.ReadOptions of the Reader is used.
+func (r *Reader) ReadNBits(nBits uint, opt *ReadOptions) ([]byte, error) {
+	pos := r.BitPosition()
+	data, err := r.readNBits(nBits, r.readOptions(opt))
+	if err == nil {
+		r.trace("", pos, nBits, data)
+	}
+	return data, r.wrapError("ReadNBits", pos, err)
+}
 
-	// remaining bits in current byte
-	rb := r.currBitIndex + 1
-
-	// 64bit value may be distributed in 9 bytes
-	nBits1 := rb
-	nBits2 := uint8(8)
-	nBits3 := uint8(8)
-	nBits4 := uint8(8)
-	nBits5 := nBits - rb - 24
-	nBits6 := uint8(0)
-	nBits7 := uint8(0)
-	nBits8 := uint8(0)
-	nBits9 := uint8(0)
-	if nBits5 > 8 {
-		nBits6 = nBits5 - 8
-		if nBits6 > 8 {
-			nBits7 = nBits6 - 8
-			if nBits7 > 8 {
-				nBits8 = nBits7 - 8
-				if nBits8 > 8 {
-					nBits9 = nBits8 - 8
-					nBits8 = 8
-				}
-				nBits7 = 8
-			}
-			nBits6 = 8
-		}
-		nBits5 = 8
+// GetReadOptions gets configured default read options.
+func (opt *ReaderOptions) GetReadOptions() *ReadOptions {
+	if opt == nil {
+		return nil
 	}
+	return opt.ReadOptions
+}
 
-	b1 := r.mustReadNBitsInCurrentByte(nBits1)
-	b2, err := r.ReadNBitsAsUint8(nBits2)
-	if err != nil {
-		return 0, err
+// readOptions returns `opt`, or the default read options of the Reader if it is nil.
+func (r *Reader) readOptions(opt *ReadOptions) *ReadOptions {
+	if opt == nil {
+		return r.opt.GetReadOptions()
 	}
-	b3, err := r.ReadNBitsAsUint8(nBits3)
-	if err != nil {
-		return 0, err
+	return opt
+}
+
+// ReadBytes reads `nBytes` bytes from the bit stream.
+// The bit stream does not have to be byte-aligned, but reading is much faster when it is.
+func (r *Reader) ReadBytes(nBytes uint) ([]byte, error) {
+	pos := r.BitPosition()
+	data, err := r.readNBits(nBytes*8, nil)
+	if err == nil {
+		r.trace("", pos, nBytes*8, data)
 	}
-	b4, err := r.ReadNBitsAsUint8(nBits4)
-	if err != nil {
-		return 0, err
+	return data, r.wrapError("ReadBytes", pos, err)
+}
+
+// Skip discards `nBits` bits of the bit stream.
+// Whole bytes are skipped without being extracted bit by bit when the bit stream is byte-aligned.
+func (r *Reader) Skip(nBits uint) error {
+	pos := r.BitPosition()
+	err := r.skip(nBits)
+	if err == nil {
+		r.trace("", pos, nBits, nil)
 	}
-	b5, err := r.ReadNBitsAsUint8(nBits5)
+	return r.wrapError("Skip", pos, err)
+}
+
+func (r *Reader) skip(nBits uint) error {
+	err := r.checkSkip(uint64(nBits))
 	if err != nil {
-		return 0, err
+		return err
 	}
-	b6, err := r.ReadNBitsAsUint8(nBits6)
-	if err != nil {
-		return 0, err
+
+	skipped := uint(0)
+	for skipped < nBits {
+		err := r.fillBufIfNeeded()
+		if err != nil {
+			if skipped > 0 {
+				return unexpectedEOF(err)
+			}
+			return err
+		}
+
+		n := nBits - skipped
+		if r.currBitIndex == 7 && n >= 8 {
+			// byte-aligned: skip whole bytes in the buffer
+			nBytes := n / 8
+			if avail := r.bufLen - r.currByteIndex; nBytes > avail {
+				nBytes = avail
+			}
+			r.currByteIndex += nBytes
+			r.consumedBytes += nBytes
+			skipped += nBytes * 8
+			continue
+		}
+
+		// remaining bits in current byte
+		rb := uint(r.currBitIndex + 1)
+		if n > rb {
+			n = rb
+		}
+		r.forwardIndecies(uint8(n))
+		skipped += n
 	}
-	b7, err := r.ReadNBitsAsUint8(nBits7)
+	return nil
+}
+
+// ReadAll reads the remainder of the bit stream and returns it as a slice of bytes (left aligned).
+// `trailingBits` is the number of valid bits in the last byte (1 to 8), which is less than 8 only when the bit stream is not byte-aligned.
+// If the bit stream has been consumed completely, it returns nil and 0 without an error.
+// Like io.ReadAll, it returns the bits read so far together with the error from the source.
+func (r *Reader) ReadAll() ([]byte, uint8, error) {
+	pos := r.BitPosition()
+	data, trailingBits, err := r.readAll()
 	if err != nil {
-		return 0, err
+		return data, trailingBits, r.wrapError("ReadAll", pos, err)
 	}
-	b8, err := r.ReadNBitsAsUint8(nBits8)
-	if err != nil {
-		return 0, err
+	r.trace("", pos, uint(r.BitPosition()-pos), data)
+	return data, trailingBits, nil
+}
+
+func (r *Reader) readAll() ([]byte, uint8, error) {
+	skip := 7 - r.currBitIndex // bits already consumed in current byte
+
+	var data []byte
+	var err error
+	for {
+		err = r.fillBufIfNeeded()
+		if err == nil {
+			err = r.allocate(uint64(r.bufLen - r.currByteIndex))
+		}
+		if err != nil {
+			break
+		}
+
+		data = append(data, r.buf[r.currByteIndex:r.bufLen]...)
+		r.consumedBytes += r.bufLen - r.currByteIndex
+		r.currByteIndex = r.bufLen
+		r.currBitIndex = 7
 	}
-	b9, err := r.ReadNBitsAsUint8(nBits9)
-	if err != nil {
-		return 0, err
+	if err == io.EOF {
+		err = nil
 	}
 
-	return (uint64(b1) << (nBits2 + nBits3 + nBits4 + nBits5 + nBits6 + nBits7 + nBits8 + nBits9)) |
-		(uint64(b2) << (nBits3 + nBits4 + nBits5 + nBits6 + nBits7 + nBits8 + nBits9)) |
-		(uint64(b3) << (nBits4 + nBits5 + nBits6 + nBits7 + nBits8 + nBits9)) |
-		(uint64(b4) << (nBits5 + nBits6 + nBits7 + nBits8 + nBits9)) |
-		(uint64(b5) << (nBits6 + nBits7 + nBits8 + nBits9)) |
-		(uint64(b6) << (nBits7 + nBits8 + nBits9)) |
-		(uint64(b7) << (nBits8 + nBits9)) |
-		(uint64(b8) << (nBits9)) |
-		uint64(b9), nil
-}
+	if len(data) == 0 {
+		return nil, 0, err
+	}
 
-// ReadUint64BE reads 64 bits as a big endian unsigned integer from the bit stream and returns it in uint64.
-func (r *Reader) ReadUint64BE() (uint64, error) {
-	return r.ReadNBitsAsUint64BE(64)
-}
+	// left align
+	if skip > 0 {
+		for i := 0; i < len(data)-1; i++ {
+			data[i] = data[i]<<skip | data[i+1]>>(8-skip)
+		}
+		data[len(data)-1] <<= skip
+	}
+	nBits := uint(len(data))*8 - uint(skip)
+	data = data[:(nBits+7)/8]
 
-// ReadOptions is a set of options to read bits from the bit stream.
-type ReadOptions struct {
-	AlignRight bool // If true, returned value will be aligned to right (default: align to left)
-	PadOne     bool // If true, returned value will be padded with '1' instead of '0' (default: pad with '0')
+	trailingBits := uint8(nBits % 8)
+	if trailingBits == 0 {
+		trailingBits = 8
+	}
+	return data, trailingBits, err
 }
 
-// ReadNBits reads `nBits` bits from the bit stream and returns it as a slice of bytes.
-// If `nBits` == 0, this function always returns nil.
-func (r *Reader) ReadNBits(nBits uint8, opt *ReadOptions) ([]byte, error) {
+func (r *Reader) readNBits(nBits uint, opt *ReadOptions) ([]byte, error) {
 	if nBits == 0 {
 		return nil, nil
 	}
+	err := r.checkBitsPerCall(uint64(nBits))
+	if err != nil {
+		return nil, err
+	}
+	err = r.allocate(uint64(nBits+7) / 8)
+	if err != nil {
+		return nil, err
+	}
 
-	err := r.fillBufIfNeeded()
+	err = r.fillBufIfNeeded()
 	if err != nil {
 		return nil, err
 	}
 
+	total := nBits
 	padOne := (opt != nil && opt.PadOne)
 	alignRight := (opt != nil && opt.AlignRight)
 
 	maxByteLen := (nBits / 8) + 1
 	result := make([]byte, 0, maxByteLen)
 
-	// remaining bits in current byte
-	rb := r.currBitIndex + 1
-	var bitsToRead uint8
-	if nBits <= rb {
-		bitsToRead = nBits
-	} else {
-		bitsToRead = rb
-	}
-
-	tempByte := r.mustReadNBitsInCurrentByte(bitsToRead)
-	tempByte = tempByte << (8 - bitsToRead) // left align
-	tempBit := bitsToRead
-	nBits -= bitsToRead
-
-	if tempBit == 8 {
-		result = append(result, tempByte)
-		tempByte = 0
-		tempBit = 0
+	if nBits >= 8 {
+		result = result[:nBits/8]
+		var read uint
+		if r.currBitIndex == 7 {
+			// byte-aligned: whole bytes can be copied from the buffer as they are
+			var n int
+			n, err = r.readAlignedBytes(result)
+			read = uint(n) * 8
+		} else {
+			if r.logging() {
+				r.logDebug("bitstream: unaligned bytes", r.BitPosition(), "bytes", len(result))
+			}
+			read, err = r.readUnalignedBytes(result)
+		}
+		if err != nil {
+			return r.partialBytes(result[:(read+7)/8], total, read, unexpectedEOF(err))
+		}
+		nBits -= read
 	}
 
-	for nBits >= 8 {
-		err := r.fillBufIfNeeded()
+	// the trailing bits
+	if nBits > 0 {
+		v, read, err := r.readBits(uint8(nBits))
 		if err != nil {
-			return nil, err
+			result = appendLeftAligned(result, v, read)
+			return r.partialBytes(result, total, total-nBits+uint(read), unexpectedEOF(err))
 		}
+		result = appendLeftAligned(result, v, uint8(nBits))
+	}
 
-		bitsToRead = 8
-		b := r.mustReadNBitsInCurrentByte(bitsToRead)
-		b1 := b >> tempBit
-		b2 := b << (8 - tempBit)
-
-		tempByte = tempByte | b1
-		result = append(result, tempByte)
-		tempByte = b2
+	if padOne && total%8 != 0 {
+		result[len(result)-1] |= 0xff >> (total % 8)
+	}
 
-		nBits -= 8
+	if alignRight {
+		return nil, fmt.Errorf("%w: AlignRight", ErrNotImplemented)
 	}
 
-	if nBits > 0 {
+	return result, nil
+}
+
+// readAlignedBytes fills `p` with whole bytes from the bit stream.
+// The reader must be byte-aligned. It returns the number of bytes copied into `p`.
+func (r *Reader) readAlignedBytes(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
 		err := r.fillBufIfNeeded()
 		if err != nil {
-			return nil, err
+			return n, err
 		}
 
-		bitsToRead = nBits
-		b := r.mustReadNBitsInCurrentByte(bitsToRead)
-		b1 := b >> (bitsToRead - (8 - tempBit))       // wants to have (8 - tempBit) bits from b. b has bitsToRead bits
-		b2 := b << (8 - (bitsToRead - (8 - tempBit))) // wants to have (bitsToRead - <bits of b1>) left aligned.
-
-		tempByte = tempByte | b1
-		result = append(result, tempByte)
+		c := copy(p[n:], r.buf[r.currByteIndex:r.bufLen])
+		r.currByteIndex += uint(c)
+		r.consumedBytes += uint(c)
+		n += c
+	}
+	return n, nil
+}
 
-		if nBits > (8 - tempBit) {
-			if padOne {
-				b2 = b2 | (0xff >> tempBit)
+// readUnalignedBytes fills `p` with whole bytes from the bit stream which is not byte-aligned, and returns the number of bits read.
+// While the buffer has the bytes, 8 bytes are extracted at a time with a 64-bit load and shifts; the bytes which straddle
+// refills are read by readBits. If an error occurs, the bits read so far are left aligned in `p`.
+func (r *Reader) readUnalignedBytes(p []byte) (uint, error) {
+	n := 0
+	for n < len(p) {
+		skip := uint(7 - r.currBitIndex) // bits already consumed in current byte
+		if r.buf != nil && r.currByteIndex < r.bufLen {
+			src := r.buf[r.currByteIndex:r.bufLen]
+			i := 0
+			for ; len(p)-n >= 8 && len(src)-i >= 9; i += 8 {
+				v := binary.BigEndian.Uint64(src[i:])<<skip | uint64(src[i+8])>>(8-skip)
+				binary.BigEndian.PutUint64(p[n:], v)
+				n += 8
 			}
-			result = append(result, b2)
+			r.currByteIndex += uint(i)
+			r.consumedBytes += uint(i)
 		}
-	} else {
-		if tempBit > 0 {
-			result = append(result, tempByte)
+		if n == len(p) {
+			break
 		}
+
+		v, read, err := r.readBits(8)
+		if err != nil {
+			if read > 0 {
+				p[n] = uint8(v << (8 - read))
+			}
+			return uint(n)*8 + uint(read), err
+		}
+		p[n] = uint8(v)
+		n++
 	}
+	return uint(n) * 8, nil
+}
 
-	if alignRight {
-		return nil, errors.New("not implemented yet")
+// partialBytes handles an error which occurred after `read` bits of `requested` bits were read by readNBits.
+// In the lenient EOF mode, it returns the bits read so far (left aligned in `result`) padded with zeros.
+func (r *Reader) partialBytes(result []byte, requested, read uint, err error) ([]byte, error) {
+	if r.opt.GetEOFMode() != EOFLenient || !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
 	}
 
-	return result, nil
+	for uint(len(result)) < (requested+7)/8 {
+		result = append(result, 0)
+	}
+	return result, &PartialFieldError{RequestedBits: requested, ReadBits: read}
+}
+
+// appendLeftAligned appends the LSB `nBits` (<= 64) bits of `v` to `p` as (nBits+7)/8 bytes, left aligned and padded with zeros.
+func appendLeftAligned(p []byte, v uint64, nBits uint8) []byte {
+	if nBits == 0 {
+		return p
+	}
+	v <<= 64 - nBits
+	for i := uint8(0); i < nBits; i += 8 {
+		p = append(p, uint8(v>>56))
+		v <<= 8
+	}
+	return p
 }
@@ -3,8 +3,12 @@ package bitstream
 import (
 	"bytes"
 	"crypto/rand"
+	"errors"
+	"io"
+	mrand "math/rand"
 	"reflect"
 	"testing"
+	"testing/iotest"
 )
 
 type indecies struct {
@@ -12,6 +16,20 @@ type indecies struct {
 	ByteIndex uint
 }
 
+func BenchmarkReadNBitsUnaligned(b *testing.B) {
+	data := make([]byte, 1024*1024)
+	b.SetBytes(4096)
+	r := NewReader(bytes.NewReader(data), nil)
+	r.Skip(3)
+	for i := 0; i < b.N; i++ {
+		_, err := r.ReadNBits(4096*8, nil)
+		if err != nil {
+			r = NewReader(bytes.NewReader(data), nil)
+			r.Skip(3)
+		}
+	}
+}
+
 func TestForwardIndecies(t *testing.T) {
 	testData := []struct {
 		Name             string
@@ -62,6 +80,13 @@ func TestForwardIndecies(t *testing.T) {
 			NumBitsToForward: 10,
 			End:              indecies{BitIndex: 7, ByteIndex: 2}, //                          ^
 		},
+		{
+			Name:             "pattern 8",
+			Data:             []byte{0x08, 0x08, 0x08},            // b7654 3210 | 7654 3210 | 7654 3210
+			Start:            indecies{BitIndex: 0, ByteIndex: 0}, //          ^
+			NumBitsToForward: 8,
+			End:              indecies{BitIndex: 0, ByteIndex: 1}, //                     ^
+		},
 	}
 
 	for _, data := range testData {
@@ -290,6 +315,29 @@ func BenchmarkRead8BitsAsUint8(b *testing.B) {
 	benchmarkReadNBitsAsUint8(b, 8)
 }
 
+func TestReadNibble(t *testing.T) {
+	src := []byte{0x90, 0x13, 0x0f, 0xa8}
+	expected := []uint8{0x2, 0x0, 0x2, 0x6, 0x1, 0xf, 0x5}
+
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 2, Prefetch: true}} {
+		r := NewReader(bytes.NewReader(src), opt)
+		r.ReadBit()
+		for _, e := range expected {
+			v, err := r.ReadNibble()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if v != e {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", e, v)
+			}
+		}
+		_, err := r.ReadNibble()
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+		}
+	}
+}
+
 func TestReadNBitsAsUint16BE(t *testing.T) {
 	testData := []struct {
 		Name                  string
@@ -772,7 +820,7 @@ func TestReadNBits(t *testing.T) {
 		Name                  string
 		Data                  []byte
 		Start                 indecies
-		NBits                 uint8
+		NBits                 uint
 		AlignRight            bool
 		PadOne                bool
 		Expected              []byte
@@ -906,6 +954,32 @@ func TestReadNBits(t *testing.T) {
 			Expected:              []byte{0x1a, 0x2b, 0x00},                                     //          0   0011 0100   0101 0110 => 0001 1010 0010 1011 0 => 0x1A 0x2B 0x00
 			ExpectedConsumedBytes: 3,
 		},
+		{
+			Name:                  "pattern 17",                                                 // b7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210
+			Data:                  []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12}, //  0001 0010 | 0011 0100 | 0101 0110 | 0111 1000 | 1001 1010 | 1011 1100 | 1101 1110 | 1111 0000 | 0001 0010
+			Start:                 indecies{BitIndex: 7, ByteIndex: 0},                          //  ^
+			NBits:                 12,                                                           //  ^^^^ ^^^^   ^^^^
+			Expected:              []byte{0x12, 0x30},                                           //  0001 0010   0011 => 0001 0010 0011 0000 => 0x12 0x30
+			ExpectedConsumedBytes: 2,
+		},
+		{
+			Name:                  "pattern 18",                                                 // b7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210
+			Data:                  []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12}, //  0001 0010 | 0011 0100 | 0101 0110 | 0111 1000 | 1001 1010 | 1011 1100 | 1101 1110 | 1111 0000 | 0001 0010
+			Start:                 indecies{BitIndex: 7, ByteIndex: 0},                          //  ^
+			NBits:                 12,                                                           //  ^^^^ ^^^^   ^^^^
+			PadOne:                true,                                                         //
+			Expected:              []byte{0x12, 0x3f},                                           //  0001 0010   0011 => 0001 0010 0011 1111 => 0x12 0x3F
+			ExpectedConsumedBytes: 2,
+		},
+		{
+			Name:                  "pattern 19",                                                 // b7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210 | 7654 3210
+			Data:                  []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12}, //  0001 0010 | 0011 0100 | 0101 0110 | 0111 1000 | 1001 1010 | 1011 1100 | 1101 1110 | 1111 0000 | 0001 0010
+			Start:                 indecies{BitIndex: 5, ByteIndex: 0},                          //    ^
+			NBits:                 9,                                                            //    ^^ ^^^^   ^^^
+			PadOne:                true,                                                         //
+			Expected:              []byte{0x48, 0xff},                                           //    01 0010   001 => 0100 1000 1111 1111 => 0x48 0xFF
+			ExpectedConsumedBytes: 2,
+		},
 	}
 
 	for _, data := range testData {
@@ -971,3 +1045,1126 @@ func BenchmarkRead15Bits(b *testing.B) {
 func BenchmarkRead64Bits(b *testing.B) {
 	benchmarkReadNBits(b, 64)
 }
+
+func TestReadRun(t *testing.T) {
+	testData := []struct {
+		Name           string
+		Data           []byte
+		Start          indecies
+		Max            uint64
+		ExpectedBit    byte
+		ExpectedLength uint64
+		End            indecies
+	}{
+		{
+			Name:           "pattern 1",                         // b7654 3210
+			Data:           []byte{0x1f},                        //  0001 1111
+			Start:          indecies{BitIndex: 7, ByteIndex: 0}, //  ^
+			ExpectedBit:    0,                                   //  ^^^
+			ExpectedLength: 3,
+			End:            indecies{BitIndex: 4, ByteIndex: 0},
+		},
+		{
+			Name:           "pattern 2",                         // b7654 3210
+			Data:           []byte{0x1f},                        //  0001 1111
+			Start:          indecies{BitIndex: 4, ByteIndex: 0}, //     ^
+			ExpectedBit:    1,                                   //     ^ ^^^^ (end of stream)
+			ExpectedLength: 5,
+			End:            indecies{BitIndex: 7, ByteIndex: 1},
+		},
+		{
+			Name:           "pattern 3",                         // b7654 3210 | 7654 3210 | 7654 3210 | 7654 3210
+			Data:           []byte{0xf0, 0x00, 0x00, 0x01},      //  1111 0000 | 0000 0000 | 0000 0000 | 0000 0001
+			Start:          indecies{BitIndex: 3, ByteIndex: 0}, //       ^
+			ExpectedBit:    0,                                   //       ^^^^   ^^^^ ^^^^   ^^^^ ^^^^   ^^^^ ^^^
+			ExpectedLength: 27,
+			End:            indecies{BitIndex: 0, ByteIndex: 3},
+		},
+		{
+			Name:           "pattern 4",                         // b7654 3210 | 7654 3210 | 7654 3210 | 7654 3210
+			Data:           []byte{0xf0, 0x00, 0x00, 0x01},      //  1111 0000 | 0000 0000 | 0000 0000 | 0000 0001
+			Start:          indecies{BitIndex: 3, ByteIndex: 0}, //       ^
+			Max:            10,                                  //       ^^^^   ^^^^ ^^
+			ExpectedBit:    0,
+			ExpectedLength: 10,
+			End:            indecies{BitIndex: 1, ByteIndex: 1},
+		},
+		{
+			Name:           "pattern 5",                         // b7654 3210 | 7654 3210
+			Data:           []byte{0xaa, 0xff},                  //  1010 1010 | 1111 1111
+			Start:          indecies{BitIndex: 0, ByteIndex: 0}, //          ^
+			Max:            8,                                   //          ^   ^^^^ ^^^
+			ExpectedBit:    0,
+			ExpectedLength: 1,
+			End:            indecies{BitIndex: 7, ByteIndex: 1},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data.Data), nil)
+			r.fillBuf()
+			r.currBitIndex = data.Start.BitIndex
+			r.currByteIndex = data.Start.ByteIndex
+
+			bit, length, err := r.ReadRunN(data.Max)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.ExpectedBit != bit {
+				t.Fatalf("\nunexpected bit\nExpected: %+v\nActual:   %+v\n", data.ExpectedBit, bit)
+			}
+			if data.ExpectedLength != length {
+				t.Fatalf("\nunexpected length\nExpected: %+v\nActual:   %+v\n", data.ExpectedLength, length)
+			}
+			if data.End.BitIndex != r.currBitIndex {
+				t.Fatalf("\nunexpected bit index\nExpected: %+v\nActual:   %+v\n", data.End.BitIndex, r.currBitIndex)
+			}
+			if data.End.ByteIndex != r.currByteIndex {
+				t.Fatalf("\nunexpected byte index\nExpected: %+v\nActual:   %+v\n", data.End.ByteIndex, r.currByteIndex)
+			}
+		})
+	}
+}
+
+func TestReadRunAcrossBufferRefills(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x0f, 0xff, 0xff, 0xf0}
+	r := NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: 2})
+
+	expected := []struct {
+		Bit    byte
+		Length uint64
+	}{{0, 28}, {1, 24}, {0, 4}}
+	for i, e := range expected {
+		bit, length, err := r.ReadRun()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if e.Bit != bit || e.Length != length {
+			t.Fatalf("\nrun %d\nExpected: %d x %d\nActual:   %d x %d\n", i, e.Bit, e.Length, bit, length)
+		}
+	}
+
+	_, _, err := r.ReadRun()
+	if err == nil {
+		t.Fatal("error should occur but no error\n")
+	}
+}
+
+func TestCountLeadingZeros(t *testing.T) {
+	testData := []struct {
+		Name          string
+		Data          []byte
+		Start         indecies
+		Ones          bool
+		ExpectedCount uint64
+		End           indecies
+	}{
+		{
+			Name:          "pattern 1",                         // b7654 3210
+			Data:          []byte{0x1f},                        //  0001 1111
+			Start:         indecies{BitIndex: 7, ByteIndex: 0}, //  ^
+			ExpectedCount: 3,                                   //  ^^^^
+			End:           indecies{BitIndex: 3, ByteIndex: 0},
+		},
+		{
+			Name:          "pattern 2",                         // b7654 3210
+			Data:          []byte{0x80},                        //  1000 0000
+			Start:         indecies{BitIndex: 7, ByteIndex: 0}, //  ^
+			ExpectedCount: 0,                                   //  ^
+			End:           indecies{BitIndex: 6, ByteIndex: 0},
+		},
+		{
+			Name:          "pattern 3",                         // b7654 3210 | 7654 3210 | 7654 3210 | 7654 3210
+			Data:          []byte{0xf0, 0x00, 0x00, 0x01},      //  1111 0000 | 0000 0000 | 0000 0000 | 0000 0001
+			Start:         indecies{BitIndex: 3, ByteIndex: 0}, //       ^
+			ExpectedCount: 27,                                  //       ^^^^   ^^^^ ^^^^   ^^^^ ^^^^   ^^^^ ^^^^
+			End:           indecies{BitIndex: 7, ByteIndex: 4},
+		},
+		{
+			Name:          "pattern 4",                         // b7654 3210 | 7654 3210
+			Data:          []byte{0x07, 0xfe},                  //  0000 0111 | 1111 1110
+			Start:         indecies{BitIndex: 2, ByteIndex: 0}, //        ^
+			Ones:          true,                                //        ^^^   ^^^^ ^^^^
+			ExpectedCount: 10,
+			End:           indecies{BitIndex: 7, ByteIndex: 2},
+		},
+		{
+			Name:          "pattern 5",                         // b7654 3210 | 7654 3210
+			Data:          []byte{0x07, 0xfe},                  //  0000 0111 | 1111 1110
+			Start:         indecies{BitIndex: 7, ByteIndex: 1}, //              ^
+			Ones:          true,                                //              ^^^^ ^^^^
+			ExpectedCount: 7,
+			End:           indecies{BitIndex: 7, ByteIndex: 2},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data.Data), nil)
+			r.fillBuf()
+			r.currBitIndex = data.Start.BitIndex
+			r.currByteIndex = data.Start.ByteIndex
+
+			var count uint64
+			var err error
+			if data.Ones {
+				count, err = r.CountLeadingOnes()
+			} else {
+				count, err = r.CountLeadingZeros()
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.ExpectedCount != count {
+				t.Fatalf("\nunexpected count\nExpected: %+v\nActual:   %+v\n", data.ExpectedCount, count)
+			}
+			if data.End.BitIndex != r.currBitIndex {
+				t.Fatalf("\nunexpected bit index\nExpected: %+v\nActual:   %+v\n", data.End.BitIndex, r.currBitIndex)
+			}
+			if data.End.ByteIndex != r.currByteIndex {
+				t.Fatalf("\nunexpected byte index\nExpected: %+v\nActual:   %+v\n", data.End.ByteIndex, r.currByteIndex)
+			}
+		})
+	}
+}
+
+func TestCountLeadingZerosAcrossBufferRefills(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x10, 0x00, 0x00}
+	r := NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: 2})
+
+	count, err := r.CountLeadingZeros()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if count != 27 {
+		t.Fatalf("\nExpected: %d\nActual:   %d\n", 27, count)
+	}
+
+	_, err = r.CountLeadingZeros()
+	if err == nil {
+		t.Fatal("error should occur but no error\n")
+	}
+}
+
+func BenchmarkCountLeadingZeros(b *testing.B) {
+	var v uint64
+	r := NewReader(rand.Reader, nil)
+	for n := 0; n < b.N; n++ {
+		v, _ = r.CountLeadingZeros()
+	}
+	toEliminateCompilerOptimizationUint64 = v
+}
+
+func TestReadEOF(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Data     []byte
+		Read     func(r *Reader) error
+		Expected error
+	}{
+		{
+			Name: "bit at the end of stream",
+			Data: []byte{0xff},
+			Read: func(r *Reader) error {
+				r.ReadUint8()
+				_, err := r.ReadBit()
+				return err
+			},
+			Expected: io.EOF,
+		},
+		{
+			Name: "uint8 on empty stream",
+			Data: []byte{},
+			Read: func(r *Reader) error {
+				_, err := r.ReadUint8()
+				return err
+			},
+			Expected: io.EOF,
+		},
+		{
+			Name: "uint8 straddling the end of stream",
+			Data: []byte{0xff},
+			Read: func(r *Reader) error {
+				r.ReadNBitsAsUint8(3)
+				_, err := r.ReadUint8()
+				return err
+			},
+			Expected: io.ErrUnexpectedEOF,
+		},
+		{
+			Name: "uint16 straddling the end of stream",
+			Data: []byte{0xff},
+			Read: func(r *Reader) error {
+				_, err := r.ReadUint16BE()
+				return err
+			},
+			Expected: io.ErrUnexpectedEOF,
+		},
+		{
+			Name: "uint32 straddling the end of stream",
+			Data: []byte{0xff, 0xff, 0xff},
+			Read: func(r *Reader) error {
+				_, err := r.ReadUint32BE()
+				return err
+			},
+			Expected: io.ErrUnexpectedEOF,
+		},
+		{
+			Name: "uint64 at the end of stream",
+			Data: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			Read: func(r *Reader) error {
+				r.ReadUint64BE()
+				_, err := r.ReadUint64BE()
+				return err
+			},
+			Expected: io.EOF,
+		},
+		{
+			Name: "uint64 straddling the end of stream",
+			Data: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			Read: func(r *Reader) error {
+				r.ReadBit()
+				_, err := r.ReadUint64BE()
+				return err
+			},
+			Expected: io.ErrUnexpectedEOF,
+		},
+		{
+			Name: "bytes straddling the end of stream",
+			Data: []byte{0xff, 0xff},
+			Read: func(r *Reader) error {
+				_, err := r.ReadNBits(17, nil)
+				return err
+			},
+			Expected: io.ErrUnexpectedEOF,
+		},
+		{
+			Name: "leading zeros straddling the end of stream",
+			Data: []byte{0x00},
+			Read: func(r *Reader) error {
+				_, err := r.CountLeadingZeros()
+				return err
+			},
+			Expected: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data.Data), &ReaderOptions{BufferSize: 1})
+			err := data.Read(r)
+			if !errors.Is(err, data.Expected) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, err)
+			}
+		})
+	}
+}
+
+// stutteringReader returns no data and no error on every other call, and at most 3 bytes otherwise.
+type stutteringReader struct {
+	src   io.Reader
+	calls int
+}
+
+func (sr *stutteringReader) Read(p []byte) (int, error) {
+	sr.calls++
+	if sr.calls%2 == 1 {
+		return 0, nil
+	}
+	if len(p) > 3 {
+		p = p[:3]
+	}
+	return sr.src.Read(p)
+}
+
+// emptyReader always returns no data and no error.
+type emptyReader struct{}
+
+func (emptyReader) Read(p []byte) (int, error) {
+	return 0, nil
+}
+
+func TestFillBufShortReads(t *testing.T) {
+	data := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10}
+	testData := []struct {
+		Name string
+		Src  io.Reader
+	}{
+		{Name: "one byte reader", Src: iotest.OneByteReader(bytes.NewReader(data))},
+		{Name: "half reader", Src: iotest.HalfReader(bytes.NewReader(data))},
+		{Name: "data with EOF", Src: iotest.DataErrReader(bytes.NewReader(data))},
+		{Name: "one byte reader with EOF", Src: iotest.OneByteReader(iotest.DataErrReader(bytes.NewReader(data)))},
+		{Name: "stuttering reader", Src: &stutteringReader{src: bytes.NewReader(data)}},
+	}
+
+	for _, d := range testData {
+		d := d // capture
+		t.Run(d.Name, func(t *testing.T) {
+			r := NewReader(d.Src, nil)
+			v1, err := r.ReadUint64BE()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			v2, err := r.ReadUint64BE()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if v1 != 0x0123456789abcdef || v2 != 0xfedcba9876543210 {
+				t.Fatalf("\nunexpected values: %#x %#x\n", v1, v2)
+			}
+			_, err = r.ReadBit()
+			if err != io.EOF {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestFillBufSourceError(t *testing.T) {
+	r := NewReader(iotest.TimeoutReader(bytes.NewReader([]byte{0xab, 0xcd})), &ReaderOptions{BufferSize: 1})
+	v, err := r.ReadUint8()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0xab {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0xab, v)
+	}
+	_, err = r.ReadUint8()
+	if !errors.Is(err, iotest.ErrTimeout) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", iotest.ErrTimeout, err)
+	}
+}
+
+func TestFillBufNoProgress(t *testing.T) {
+	r := NewReader(emptyReader{}, nil)
+	_, err := r.ReadBit()
+	if !errors.Is(err, io.ErrNoProgress) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrNoProgress, err)
+	}
+}
+
+// referenceBits returns `nBits` bits from `pos` of `data` as a LSB aligned value.
+func referenceBits(data []byte, pos, nBits uint) uint64 {
+	v := uint64(0)
+	for i := uint(0); i < nBits; i++ {
+		bit := (data[(pos+i)/8] >> (7 - (pos+i)%8)) & 1
+		v = (v << 1) | uint64(bit)
+	}
+	return v
+}
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}, []byte{0x08, 0x21, 0x42, 0x63, 0x84}, uint8(1))
+	f.Add([]byte{0xff}, []byte{0xff, 0x00, 0x7f}, uint8(0))
+	f.Add([]byte{}, []byte{0x01}, uint8(3))
+
+	f.Fuzz(func(t *testing.T, data []byte, ops []byte, bufSize uint8) {
+		r := NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: uint(bufSize)})
+		total := uint(len(data)) * 8
+		pos := uint(0)
+
+		for _, op := range ops {
+			nBits := op >> 3
+			limit := uint8(64) // nBits larger than this must result in an error without consuming any bits
+			var v uint64
+			var err error
+			switch op & 0x07 {
+			case 0:
+				var b byte
+				b, err = r.ReadBit()
+				v, nBits = uint64(b), 1
+			case 1:
+				var b uint8
+				nBits = nBits % 10
+				limit = 8
+				b, err = r.ReadNBitsAsUint8(nBits)
+				v = uint64(b)
+			case 2:
+				var b uint16
+				nBits = nBits % 18
+				limit = 16
+				b, err = r.ReadNBitsAsUint16BE(nBits)
+				v = uint64(b)
+			case 3:
+				var b uint32
+				nBits += 4
+				limit = 32
+				b, err = r.ReadNBitsAsUint32BE(nBits)
+				v = uint64(b)
+			case 4:
+				nBits = nBits*2 + 3
+				v, err = r.ReadNBitsAsUint64BE(nBits)
+			case 5:
+				var b []byte
+				n := uint(nBits) * 19
+				b, err = r.ReadNBits(n, nil)
+				if err != nil {
+					if pos+n <= total {
+						t.Fatalf("unexpected error at bit %d reading %d bits of %d: %+v\n", pos, n, total, err)
+					}
+					return
+				}
+				if uint(len(b)) != (n+7)/8 {
+					t.Fatalf("unexpected length %d for %d bits\n", len(b), n)
+				}
+				for i := uint(0); i < n; i++ {
+					if got, want := referenceBits(b, i, 1), referenceBits(data, pos+i, 1); got != want {
+						t.Fatalf("\nbit %d of %d bits from %d\nExpected: %d\nActual:   %d\n", i, n, pos, want, got)
+					}
+				}
+				pos += n
+				continue
+			case 6:
+				var n uint64
+				n, err = r.CountLeadingZeros()
+				if err != nil {
+					return
+				}
+				pos += uint(n) + 1
+				continue
+			case 7:
+				var length uint64
+				_, length, err = r.ReadRunN(uint64(nBits))
+				if err != nil {
+					return
+				}
+				pos += uint(length)
+				continue
+			}
+
+			if err != nil && nBits > limit {
+				continue
+			}
+			if nBits > limit {
+				t.Fatalf("error should occur reading %d bits (limit: %d) but no error\n", nBits, limit)
+			}
+			if err != nil {
+				if pos+uint(nBits) <= total {
+					t.Fatalf("unexpected error at bit %d reading %d bits of %d: %+v\n", pos, nBits, total, err)
+				}
+				return
+			}
+			if pos+uint(nBits) > total {
+				t.Fatalf("error should occur reading %d bits at bit %d of %d but no error\n", nBits, pos, total)
+			}
+			if want := referenceBits(data, pos, uint(nBits)); want != v {
+				t.Fatalf("\nbit %d, %d bits\nExpected: %#x\nActual:   %#x\n", pos, nBits, want, v)
+			}
+			pos += uint(nBits)
+		}
+	})
+}
+
+func TestReadNBitsLargerThan255(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	r := NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: 7})
+	r.ReadNBitsAsUint8(4)
+	v, err := r.ReadNBits(700, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if len(v) != 88 {
+		t.Fatalf("\nunexpected length\nExpected: %d\nActual:   %d\n", 88, len(v))
+	}
+	for i := uint(0); i < 700; i++ {
+		if referenceBits(v, i, 1) != referenceBits(data, i+4, 1) {
+			t.Fatalf("unexpected bit at %d\n", i)
+		}
+	}
+	if r.BitPosition() != 704 {
+		t.Fatalf("\nunexpected bit position\nExpected: %d\nActual:   %d\n", 704, r.BitPosition())
+	}
+}
+
+func TestReadLenientEOF(t *testing.T) {
+	testData := []struct {
+		Name          string
+		Data          []byte
+		Read          func(r *Reader) (interface{}, error)
+		Expected      interface{}
+		ExpectedError *PartialFieldError
+	}{
+		{
+			Name: "uint16 straddling the end of stream",
+			Data: []byte{0xab},
+			Read: func(r *Reader) (interface{}, error) {
+				return r.ReadUint16BE()
+			},
+			Expected:      uint16(0xab00),
+			ExpectedError: &PartialFieldError{RequestedBits: 16, ReadBits: 8},
+		},
+		{
+			Name: "uint8 straddling the end of stream",
+			Data: []byte{0xff},
+			Read: func(r *Reader) (interface{}, error) {
+				r.ReadNBitsAsUint8(5)
+				return r.ReadNBitsAsUint8(5)
+			},
+			Expected:      uint8(0x1c),
+			ExpectedError: &PartialFieldError{RequestedBits: 5, ReadBits: 3},
+		},
+		{
+			Name: "int32 straddling the end of stream",
+			Data: []byte{0xff, 0xff},
+			Read: func(r *Reader) (interface{}, error) {
+				return r.ReadNBitsAsInt32BE(20)
+			},
+			Expected:      int32(-16),
+			ExpectedError: &PartialFieldError{RequestedBits: 20, ReadBits: 16},
+		},
+		{
+			Name: "bytes straddling the end of stream",
+			Data: []byte{0x12, 0x34},
+			Read: func(r *Reader) (interface{}, error) {
+				r.ReadNBitsAsUint8(3)
+				return r.ReadNBits(30, nil)
+			},
+			Expected:      []byte{0x91, 0xa0, 0x00, 0x00},
+			ExpectedError: &PartialFieldError{RequestedBits: 30, ReadBits: 13},
+		},
+		{
+			Name: "uint64 at the end of stream",
+			Data: []byte{0xab},
+			Read: func(r *Reader) (interface{}, error) {
+				r.ReadUint8()
+				return r.ReadUint64BE()
+			},
+			Expected:      uint64(0),
+			ExpectedError: nil,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data.Data), &ReaderOptions{BufferSize: 1, EOFMode: EOFLenient})
+			v, err := data.Read(r)
+			if !reflect.DeepEqual(data.Expected, v) {
+				t.Fatalf("\nExpected: %#v\nActual:   %#v\n", data.Expected, v)
+			}
+
+			if data.ExpectedError == nil {
+				if err != io.EOF {
+					t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+				}
+				return
+			}
+			var pfe *PartialFieldError
+			if !errors.As(err, &pfe) {
+				t.Fatalf("PartialFieldError is expected: %+v\n", err)
+			}
+			if !reflect.DeepEqual(data.ExpectedError, pfe) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedError, pfe)
+			}
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("error should match io.ErrUnexpectedEOF: %+v\n", err)
+			}
+		})
+	}
+}
+
+func TestReadStrictEOF(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0xab}), nil)
+	v, err := r.ReadUint16BE()
+	if v != 0 {
+		t.Fatalf("no value should be returned in the strict mode: %#x\n", v)
+	}
+	var pfe *PartialFieldError
+	if errors.As(err, &pfe) {
+		t.Fatalf("PartialFieldError should not be returned in the strict mode: %+v\n", err)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestReadBytes(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	testData := []struct {
+		Name     string
+		Skip     uint
+		NBytes   uint
+		BufSize  uint
+		Expected []byte
+	}{
+		{
+			Name:     "aligned",
+			Skip:     0,
+			NBytes:   10,
+			BufSize:  3,
+			Expected: data[:10],
+		},
+		{
+			Name:     "aligned across many buffer refills",
+			Skip:     8,
+			NBytes:   99,
+			BufSize:  1,
+			Expected: data[1:],
+		},
+		{
+			Name:     "unaligned",
+			Skip:     4,
+			NBytes:   2,
+			BufSize:  3,
+			Expected: []byte{0x00, 0x70},
+		},
+	}
+
+	for _, data2 := range testData {
+		data2 := data2 // capture
+		t.Run(data2.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: data2.BufSize})
+			err := r.Skip(data2.Skip)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+
+			v, err := r.ReadBytes(data2.NBytes)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !bytes.Equal(data2.Expected, v) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data2.Expected, v)
+			}
+		})
+	}
+}
+
+func TestReadBytesEOF(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x01, 0x02, 0x03}), &ReaderOptions{BufferSize: 2})
+	_, err := r.ReadBytes(4)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+
+	r = NewReader(bytes.NewReader([]byte{0x01, 0x02, 0x03}), &ReaderOptions{BufferSize: 2, EOFMode: EOFLenient})
+	v, err := r.ReadBytes(4)
+	if expected := []byte{0x01, 0x02, 0x03, 0x00}; !bytes.Equal(expected, v) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, v)
+	}
+	var pfe *PartialFieldError
+	if !errors.As(err, &pfe) || pfe.ReadBits != 24 {
+		t.Fatalf("PartialFieldError with 24 bits read is expected: %+v\n", err)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	data := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+
+	testData := []struct {
+		Name          string
+		Skips         []uint
+		ExpectedIndex uint64
+		Expected      uint8
+	}{
+		{
+			// 0000 0001 0010 0011 0100 0101 0110 0111 ...
+			// ^^^
+			Name:          "pattern 1",
+			Skips:         []uint{3},
+			ExpectedIndex: 3,
+			Expected:      0x09,
+		},
+		{
+			// 0000 0001 0010 0011 0100 0101 0110 0111 ...
+			// ^^^^ ^^^^ ^^^^ ^^^^ ^^
+			Name:          "pattern 2",
+			Skips:         []uint{18},
+			ExpectedIndex: 18,
+			Expected:      0x15,
+		},
+		{
+			// 0000 0001 0010 0011 0100 0101 0110 0111 ...
+			// ^^^^ ^^^^ ^^^^ ^^^^ ^^^^ ^^^^
+			Name:          "pattern 3",
+			Skips:         []uint{5, 19},
+			ExpectedIndex: 24,
+			Expected:      0x67,
+		},
+		{
+			Name:          "pattern 4",
+			Skips:         []uint{0, 56},
+			ExpectedIndex: 56,
+			Expected:      0xef,
+		},
+	}
+
+	for _, bufSize := range []uint{1, 3, 1024} {
+		for _, data2 := range testData {
+			r := NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: bufSize})
+			for _, n := range data2.Skips {
+				err := r.Skip(n)
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %+v\n", data2.Name, err)
+				}
+			}
+			if r.BitPosition() != data2.ExpectedIndex {
+				t.Fatalf("%s (buffer size %d)\nExpected: %+v\nActual:   %+v\n", data2.Name, bufSize, data2.ExpectedIndex, r.BitPosition())
+			}
+			v, err := r.ReadUint8()
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %+v\n", data2.Name, err)
+			}
+			if v != data2.Expected {
+				t.Fatalf("%s (buffer size %d)\nExpected: %#02x\nActual:   %#02x\n", data2.Name, bufSize, data2.Expected, v)
+			}
+		}
+	}
+}
+
+func TestSkipEOF(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x01, 0x23}), &ReaderOptions{BufferSize: 1})
+	err := r.Skip(17)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+
+	r = NewReader(bytes.NewReader([]byte{0x01, 0x23}), &ReaderOptions{BufferSize: 1})
+	err = r.Skip(16)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = r.Skip(1)
+	if err != io.EOF {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+	}
+}
+
+func BenchmarkReadBytes(b *testing.B) {
+	data := make([]byte, 1024*1024)
+	b.SetBytes(4096)
+	r := NewReader(bytes.NewReader(data), nil)
+	for i := 0; i < b.N; i++ {
+		_, err := r.ReadBytes(4096)
+		if err != nil {
+			r = NewReader(bytes.NewReader(data), nil)
+		}
+	}
+}
+
+func TestReadNBitsAsUint64BEAllOffsets(t *testing.T) {
+	data := make([]byte, 32)
+	for i := range data {
+		data[i] = byte(i*37 + 11)
+	}
+
+	// buffer sizes around 8 and 9 bytes exercise both the 64-bit fast path and the fallback near the end of the buffer
+	for _, bufSize := range []uint{1, 7, 8, 9, 10, 1024} {
+		for offset := uint8(0); offset < 8; offset++ {
+			for nBits := uint8(0); nBits <= 64; nBits++ {
+				r := NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: bufSize})
+				pos := uint(offset)
+				r.ReadNBitsAsUint8(offset)
+				for pos+uint(nBits) <= uint(len(data))*8 {
+					v, err := r.ReadNBitsAsUint64BE(nBits)
+					if err != nil {
+						t.Fatalf("unexpected error: %+v\n", err)
+					}
+					if want := referenceBits(data, pos, uint(nBits)); v != want {
+						t.Fatalf("\nbuffer size %d, bit %d, %d bits\nExpected: %#x\nActual:   %#x\n", bufSize, pos, nBits, want, v)
+					}
+					if r.BitPosition() != uint64(pos+uint(nBits)) {
+						t.Fatalf("\nExpected: %+v\nActual:   %+v\n", pos+uint(nBits), r.BitPosition())
+					}
+					pos += uint(nBits)
+					if nBits == 0 {
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestReadAcrossBufferRefills(t *testing.T) {
+	data := make([]byte, 512)
+	for i := range data {
+		data[i] = byte(i*53 + 7)
+	}
+
+	sources := map[string]func() io.Reader{
+		"bytes.Reader":   func() io.Reader { return bytes.NewReader(data) },
+		"OneByteReader":  func() io.Reader { return iotest.OneByteReader(bytes.NewReader(data)) },
+		"HalfReader":     func() io.Reader { return iotest.HalfReader(bytes.NewReader(data)) },
+		"DataErrReader":  func() io.Reader { return iotest.DataErrReader(bytes.NewReader(data)) },
+		"OneByteHalfErr": func() io.Reader { return iotest.DataErrReader(iotest.OneByteReader(bytes.NewReader(data))) },
+	}
+
+	rnd := mrand.New(mrand.NewSource(1))
+	bufSizes := []uint{1, 2, 3, 7, 8, 9}
+	for i := 0; i < 4; i++ {
+		bufSizes = append(bufSizes, uint(rnd.Intn(64)+1))
+	}
+
+	for name, src := range sources {
+		for _, bufSize := range bufSizes {
+			for _, prefetch := range []bool{false, true} {
+				r := NewReader(src(), &ReaderOptions{BufferSize: bufSize, Prefetch: prefetch})
+				pos := uint(0)
+				for op := 0; ; op++ {
+					nBits := uint(rnd.Intn(65))
+					if pos+nBits > uint(len(data))*8 {
+						break
+					}
+					var v uint64
+					var err error
+					switch op % 5 {
+					case 0:
+						v, err = r.ReadNBitsAsUint64BE(uint8(nBits))
+					case 1:
+						nBits %= 33
+						var v32 uint32
+						v32, err = r.ReadNBitsAsUint32BE(uint8(nBits))
+						v = uint64(v32)
+					case 2:
+						var n uint8
+						v, n, err = r.Peek(uint8(nBits))
+						if err == nil && uint(n) != nBits {
+							t.Fatalf("\nExpected: %+v\nActual:   %+v\n", nBits, n)
+						}
+						if err == nil {
+							err = r.Skip(nBits)
+						}
+					case 3:
+						nBits *= 3
+						if pos+nBits > uint(len(data))*8 {
+							nBits = uint(len(data))*8 - pos
+						}
+						var b []byte
+						b, err = r.ReadNBits(nBits, nil)
+						if err != nil {
+							break
+						}
+						if uint(len(b)) != (nBits+7)/8 {
+							t.Fatalf("\nExpected: %+v\nActual:   %+v\n", (nBits+7)/8, len(b))
+						}
+						for i := uint(0); i < nBits; i++ {
+							if got, want := referenceBits(b, i, 1), referenceBits(data, pos+i, 1); got != want {
+								t.Fatalf("\n%s, buffer size %d, bit %d of %d bits from %d\nExpected: %d\nActual:   %d\n", name, bufSize, i, nBits, pos, want, got)
+							}
+						}
+						pos += nBits
+						continue
+					case 4:
+						err = r.Skip(nBits)
+						if err == nil {
+							pos += nBits
+							continue
+						}
+					}
+					if err != nil {
+						t.Fatalf("%s, buffer size %d, prefetch %t: unexpected error at bit %d reading %d bits: %+v\n", name, bufSize, prefetch, pos, nBits, err)
+					}
+					if want := referenceBits(data, pos, nBits); v != want {
+						t.Fatalf("\n%s, buffer size %d, prefetch %t, bit %d, %d bits\nExpected: %#x\nActual:   %#x\n", name, bufSize, prefetch, pos, nBits, want, v)
+					}
+					pos += nBits
+					if r.BitPosition() != uint64(pos) {
+						t.Fatalf("\nExpected: %+v\nActual:   %+v\n", pos, r.BitPosition())
+					}
+				}
+				r.Close()
+			}
+		}
+	}
+}
+
+func TestNewReaderWithBuffer(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	for _, prefetch := range []bool{false, true} {
+		buf := make([]byte, 5)
+		r := NewReaderWithBuffer(bytes.NewReader(data), buf, &ReaderOptions{BufferSize: 1024, Prefetch: prefetch})
+		v, err := r.ReadNBitsAsUint32BE(20)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if v != 0x00010 {
+			t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x00010, v)
+		}
+		if !prefetch && &r.buf[0] != &buf[0] {
+			t.Fatalf("the given buffer is not used\n")
+		}
+		if r.opt.GetBufferSize() != 5 {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 5, r.opt.GetBufferSize())
+		}
+
+		rest, _, err := r.ReadAll()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		for i := uint(0); i < 780; i++ {
+			if got, want := referenceBits(rest, i, 1), referenceBits(data, 20+i, 1); got != want {
+				t.Fatalf("\nbit %d\nExpected: %d\nActual:   %d\n", 20+i, want, got)
+			}
+		}
+		r.Close()
+	}
+
+	r := NewReaderWithBuffer(bytes.NewReader(data), nil, nil)
+	if r.opt.GetBufferSize() != DefaultBufferSize {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", DefaultBufferSize, r.opt.GetBufferSize())
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	data := []byte{0x01, 0x23, 0x45, 0x67, 0x89}
+
+	testData := []struct {
+		Name                 string
+		Skip                 uint
+		BufSize              uint
+		Expected             []byte
+		ExpectedTrailingBits uint8
+	}{
+		{
+			Name:                 "from the beginning",
+			Skip:                 0,
+			BufSize:              2,
+			Expected:             []byte{0x01, 0x23, 0x45, 0x67, 0x89},
+			ExpectedTrailingBits: 8,
+		},
+		{
+			// 0000 0001 0010 0011 0100 0101 0110 0111 1000 1001
+			//    ^ ^^^^ ^^^^ ^^^^ ^^^^ ^^^^ ^^^^ ^^^^ ^^^^ ^^^^
+			Name:                 "unaligned",
+			Skip:                 3,
+			BufSize:              2,
+			Expected:             []byte{0x09, 0x1a, 0x2b, 0x3c, 0x48},
+			ExpectedTrailingBits: 5,
+		},
+		{
+			// 0000 0001 0010 0011 0100 0101 0110 0111 1000 1001
+			//                                      ^^ ^^^^ ^^^^
+			Name:                 "unaligned in the last buffer",
+			Skip:                 30,
+			BufSize:              1024,
+			Expected:             []byte{0xe2, 0x40},
+			ExpectedTrailingBits: 2,
+		},
+		{
+			Name:                 "byte aligned in the middle",
+			Skip:                 16,
+			BufSize:              1,
+			Expected:             []byte{0x45, 0x67, 0x89},
+			ExpectedTrailingBits: 8,
+		},
+		{
+			Name:                 "consumed completely",
+			Skip:                 40,
+			BufSize:              3,
+			Expected:             nil,
+			ExpectedTrailingBits: 0,
+		},
+	}
+
+	for _, data2 := range testData {
+		data2 := data2 // capture
+		t.Run(data2.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data), &ReaderOptions{BufferSize: data2.BufSize})
+			err := r.Skip(data2.Skip)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+
+			v, trailingBits, err := r.ReadAll()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(data2.Expected, v) || data2.ExpectedTrailingBits != trailingBits {
+				t.Fatalf("\nExpected: %+v (%d)\nActual:   %+v (%d)\n", data2.Expected, data2.ExpectedTrailingBits, v, trailingBits)
+			}
+			if r.BitPosition() != uint64(len(data))*8 {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", len(data)*8, r.BitPosition())
+			}
+
+			_, err = r.ReadBit()
+			if err != io.EOF {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestReadAllSourceError(t *testing.T) {
+	r := NewReader(iotest.TimeoutReader(bytes.NewReader([]byte{0xab, 0xcd})), &ReaderOptions{BufferSize: 1})
+	r.ReadNBitsAsUint8(4)
+	v, trailingBits, err := r.ReadAll()
+	if !errors.Is(err, iotest.ErrTimeout) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", iotest.ErrTimeout, err)
+	}
+	if !bytes.Equal(v, []byte{0xb0}) || trailingBits != 4 {
+		t.Fatalf("\nExpected: %+v (%d)\nActual:   %+v (%d)\n", []byte{0xb0}, 4, v, trailingBits)
+	}
+}
+
+func TestReaderDefaultReadOptions(t *testing.T) {
+	data := []byte{0xab, 0xcd, 0xef}
+	r := NewReader(bytes.NewReader(data), &ReaderOptions{ReadOptions: &ReadOptions{PadOne: true}})
+
+	v, err := r.ReadNBits(4, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual([]byte{0xaf}, v) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xaf}, v)
+	}
+	v, err = r.ReadNBitsNamed("b", 4, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual([]byte{0xbf}, v) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xbf}, v)
+	}
+
+	// the options of a call replace the default
+	v, err = r.ReadNBits(4, &ReadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual([]byte{0xc0}, v) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xc0}, v)
+	}
+
+	// inherited by Lookahead
+	lr, err := r.Lookahead(4)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	v, err = lr.ReadNBits(4, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual([]byte{0xdf}, v) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xdf}, v)
+	}
+
+	// ReadBytes is not affected
+	r = NewReader(bytes.NewReader(data), &ReaderOptions{ReadOptions: &ReadOptions{AlignRight: true}})
+	v, err = r.ReadBytes(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual([]byte{0xab}, v) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xab}, v)
+	}
+	_, err = r.ReadNBits(4, nil)
+	if !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNotImplemented, err)
+	}
+}
@@ -0,0 +1,134 @@
+package bitstream
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReportNode is a node of a decode report, which is a field or a group of fields.
+// Fields are grouped by the dotted prefixes of their names, e.g. "header.version" and "header.length" are
+// the children of the group "header", so that the report mirrors the structure of the decoded data.
+type ReportNode struct {
+	Name      string `json:"name"`
+	BitOffset uint64 `json:"bitOffset"` // offset of the first bit; the smallest offset of the children for a group
+	NBits     uint64 `json:"nBits"`     // number of bits; the span of the children for a group
+
+	// Raw is the bits of the field in binary (e.g. "0101") if it is 64 bits or shorter,
+	// or in hexadecimal (left aligned, padded with '0' bits) otherwise. It is empty for a group or if the data is not available.
+	Raw string `json:"raw,omitempty"`
+
+	// Value is the value of the field as reported to the trace hook, converted to be encoded in JSON:
+	// integers are json.Number, []byte values are in hexadecimal, and the values which implement fmt.Stringer are
+	// in their String form. Thus a report read by ReadReportJSON is equal to the one built by BuildReport.
+	Value any `json:"value,omitempty"`
+
+	Children []*ReportNode `json:"children,omitempty"`
+}
+
+// BuildReport builds the tree of `fields`, e.g. the ones collected by a FieldLog.
+// `data` is the bit stream from which the raw bits of the fields are taken; it may be nil.
+// The fields must have their bit offsets from the beginning of `data`.
+func BuildReport(fields []Field, data []byte) []*ReportNode {
+	root := &ReportNode{}
+	index := map[string]*ReportNode{} // full path -> node
+	isField := map[*ReportNode]bool{}
+	for _, f := range fields {
+		parent := root
+		path := splitFieldName(f.Name)
+		for i, name := range path {
+			key := strings.Join(path[:i+1], ".")
+			n, ok := index[key]
+			// a field read again with the same name, e.g. in a loop, is a new node
+			if !ok || i == len(path)-1 && isField[n] {
+				n = &ReportNode{Name: name}
+				parent.Children = append(parent.Children, n)
+				index[key] = n
+			}
+			parent = n
+		}
+		isField[parent] = true
+		parent.BitOffset = f.BitOffset
+		parent.NBits = uint64(f.NBits)
+		parent.Raw = rawBits(data, f.BitOffset, uint64(f.NBits))
+		parent.Value = reportValue(f.Value)
+	}
+	for _, n := range root.Children {
+		n.span()
+	}
+	return root.Children
+}
+
+// span sets the offset and width of the groups in the subtree.
+func (n *ReportNode) span() {
+	if len(n.Children) == 0 {
+		return
+	}
+	start, end := n.BitOffset, n.BitOffset+n.NBits
+	if n.NBits == 0 {
+		start, end = ^uint64(0), 0
+	}
+	for _, c := range n.Children {
+		c.span()
+		start = min(start, c.BitOffset)
+		end = max(end, c.BitOffset+c.NBits)
+	}
+	n.BitOffset = start
+	n.NBits = end - start
+}
+
+// splitFieldName splits a dotted field name. An unnamed field is a single element of "".
+func splitFieldName(name string) []string {
+	return strings.Split(name, ".")
+}
+
+func rawBits(data []byte, offset, nBits uint64) string {
+	if nBits == 0 || data == nil || offset+nBits > uint64(len(data))*8 {
+		return ""
+	}
+	bits := extractBits(data, offset, nBits)
+	if nBits > 64 {
+		return hex.EncodeToString(bits)
+	}
+	sb := &strings.Builder{}
+	for i := uint64(0); i < nBits; i++ {
+		sb.WriteByte('0' + (bits[i/8]>>(7-i%8))&0x01)
+	}
+	return sb.String()
+}
+
+func reportValue(v any) any {
+	switch v := v.(type) {
+	case nil, bool, string:
+		return v
+	case []byte:
+		return hex.EncodeToString(v)
+	case fmt.Stringer:
+		return v.String()
+	case uint8, uint16, uint32, uint64, int8, int16, int32, int64, uint, int:
+		return json.Number(fmt.Sprint(v))
+	}
+	return v
+}
+
+// WriteReportJSON writes the report built by BuildReport to `w` as indented JSON.
+func WriteReportJSON(w io.Writer, fields []Field, data []byte) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(BuildReport(fields, data))
+}
+
+// ReadReportJSON reads a report written by WriteReportJSON, e.g. to compare it with the one of another version.
+// Integer values are decoded as json.Number.
+func ReadReportJSON(r io.Reader) ([]*ReportNode, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var nodes []*ReportNode
+	err := dec.Decode(&nodes)
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
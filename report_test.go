@@ -0,0 +1,84 @@
+package bitstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestBuildReport(t *testing.T) {
+	// 0100 0101  0000 0000  0000 0000  0101 0100  1010 1011  1100 1101
+	data := []byte{0x45, 0x00, 0x00, 0x54, 0xab, 0xcd}
+
+	var log FieldLog
+	r := NewReader(bytes.NewReader(data), &ReaderOptions{TraceHook: log.Trace})
+	r.ReadNamed("header.version", 4)
+	r.ReadNamed("header.ihl", 4)
+	r.ReadNamed("header.length.value", 24)
+	r.Skip(4)
+	r.ReadNamed("item", 6)
+	r.ReadNamed("item", 6)
+
+	expected := []*ReportNode{
+		{Name: "header", BitOffset: 0, NBits: 32, Children: []*ReportNode{
+			{Name: "version", BitOffset: 0, NBits: 4, Raw: "0100", Value: json.Number("4")},
+			{Name: "ihl", BitOffset: 4, NBits: 4, Raw: "0101", Value: json.Number("5")},
+			{Name: "length", BitOffset: 8, NBits: 24, Children: []*ReportNode{
+				{Name: "value", BitOffset: 8, NBits: 24, Raw: "000000000000000001010100", Value: json.Number("84")},
+			}},
+		}},
+		{Name: "", BitOffset: 32, NBits: 4, Raw: "1010"},
+		{Name: "item", BitOffset: 36, NBits: 6, Raw: "101111", Value: json.Number("47")},
+		{Name: "item", BitOffset: 42, NBits: 6, Raw: "001101", Value: json.Number("13")},
+	}
+	actual := BuildReport(log, data)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, actual)
+	}
+
+	// the field beyond the data has no raw bits
+	short := BuildReport(log, data[:5])
+	if short[3].Raw != "" || short[3].Value != json.Number("13") {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "", short[3].Raw)
+	}
+
+	// round trip
+	buf := bytes.NewBuffer([]byte{})
+	err := WriteReportJSON(buf, log, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	decoded, err := ReadReportJSON(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual(actual, decoded) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", actual, decoded)
+	}
+}
+
+func TestReportValue(t *testing.T) {
+	bs, _ := NewBitSlice([]byte{0xa0}, 3)
+	testData := []struct {
+		Name     string
+		Value    any
+		Expected any
+	}{
+		{Name: "pattern 1", Value: []byte{0x12, 0xab}, Expected: "12ab"},
+		{Name: "pattern 2", Value: uint64(1 << 63), Expected: json.Number("9223372036854775808")},
+		{Name: "pattern 3", Value: bs, Expected: "101"},
+		{Name: "pattern 4", Value: true, Expected: true},
+		{Name: "pattern 5", Value: Run{Bit: 1, Length: 3}, Expected: Run{Bit: 1, Length: 3}},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			actual := reportValue(data.Value)
+			if !reflect.DeepEqual(data.Expected, actual) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, actual)
+			}
+		})
+	}
+}
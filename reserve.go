@@ -0,0 +1,252 @@
+package bitstream
+
+import (
+	"io"
+)
+
+// Reservation is a handle of the bits reserved by Writer.Reserve.
+type Reservation struct {
+	bitOffset uint64
+	nBits     uint8
+}
+
+// BitOffset returns the offset of the first reserved bit from the beginning of the bit stream.
+func (r Reservation) BitOffset() uint64 {
+	return r.bitOffset
+}
+
+// NBits returns the number of reserved bits.
+func (r Reservation) NBits() uint8 {
+	return r.nBits
+}
+
+// Reserve writes `nBits` '0' bits to the bit stream as a placeholder and returns a handle to fill them later with Patch,
+// e.g. for a length or CRC field which is not known until the following bits are written.
+// `nBits` must be less than or equal to 64, otherwise returns an error.
+//
+// If the destination implements io.WriteSeeker, the reserved bits are written to it as usual and Patch overwrites them.
+// Otherwise the Writer keeps the bytes from the first reservation which is not patched yet in memory,
+// and Finalize and Close fail with ErrPendingReservation until all the reservations are patched.
+func (w *Writer) Reserve(nBits uint) (Reservation, error) {
+	pos := w.bitPosition()
+	r, err := w.reserve(nBits)
+	if err != nil {
+		return Reservation{}, w.wrapError("Reserve", pos, err)
+	}
+	w.trace("", pos, nBits, nil)
+	return r, nil
+}
+
+func (w *Writer) reserve(nBits uint) (Reservation, error) {
+	if nBits > 64 {
+		return Reservation{}, ErrTooManyBits
+	}
+
+	r := Reservation{
+		bitOffset: w.writtenBits,
+		nBits:     uint8(nBits),
+	}
+	if nBits == 0 {
+		return r, nil
+	}
+
+	if len(w.reserved) == 0 {
+		w.seeker = probeSeeker(w.dst)
+	}
+	w.reserved = append(w.reserved, r.bitOffset)
+
+	err := w.writeRun(0, uint64(nBits))
+	if err != nil {
+		w.release(r.bitOffset)
+		return Reservation{}, err
+	}
+	return r, nil
+}
+
+// Patch fills the bits reserved by Reserve with `val` (the LSB `r.NBits()` bits are used).
+// It returns ErrNotPatchable if the reserved bits have already been written to a destination which cannot be overwritten.
+// In the strict mode, it returns ErrValueOutOfRange if `val` does not fit in the reserved bits.
+func (w *Writer) Patch(r Reservation, val uint64) error {
+	err := w.checkRange(r.nBits, val)
+	if err == nil {
+		err = w.patch(r, val)
+	}
+	if err != nil {
+		return w.wrapError("Patch", r.bitOffset, err)
+	}
+	return nil
+}
+
+func (w *Writer) patch(r Reservation, val uint64) error {
+	if r.nBits == 0 {
+		return nil
+	}
+
+	end := r.bitOffset + uint64(r.nBits)
+	if end > w.writtenBits {
+		return ErrNotPatchable
+	}
+
+	completed := (w.writtenBits - uint64(w.PendingBits())) / 8 // number of complete bytes
+	written := completed - uint64(len(w.buf))                  // number of bytes written to dst
+
+	pos := int64(-1) // offset of dst before patching, if it is seeked
+	for i := r.bitOffset / 8; i <= (end-1)/8; i++ {
+		// bits of the field in the i-th byte: [first, last)
+		first := i * 8
+		if first < r.bitOffset {
+			first = r.bitOffset
+		}
+		last := i*8 + 8
+		if last > end {
+			last = end
+		}
+
+		n := last - first
+		shift := i*8 + 8 - last
+		mask := uint8(1<<n-1) << shift
+		bits := uint8(val>>(end-last)) << shift & mask
+		w.patchSnapshots(i, mask, bits)
+
+		switch {
+		case i >= completed:
+			w.currByte[0] = w.currByte[0]&^mask | bits
+		case i >= written:
+			b := &w.buf[i-written]
+			*b = *b&^mask | bits
+		default:
+			if pos < 0 {
+				p, err := w.seekCurrent()
+				if err != nil {
+					return err
+				}
+				pos = p
+			}
+			err := w.patchDst(pos-int64(written-i), mask, bits)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	w.release(r.bitOffset)
+
+	if pos >= 0 {
+		_, err := w.seeker.Seek(pos, io.SeekStart)
+		return err
+	}
+	return nil
+}
+
+// patchSnapshots applies the patch of the i-th byte to the partial bytes saved by Begin,
+// so that Rollback keeps the patched bits written before the transaction.
+func (w *Writer) patchSnapshots(i uint64, mask, bits uint8) {
+	for k := range w.txns {
+		s := &w.txns[k]
+		if s.writtenBits/8 != i || s.writtenBits%8 == 0 {
+			continue
+		}
+		m := mask & ^uint8(0xff>>(s.writtenBits%8)) // the bits written before Begin
+		s.currByte = s.currByte&^m | bits&m
+	}
+}
+
+// patchDst overwrites the bits specified by `mask` of the byte at `offset` in the destination with `bits`.
+func (w *Writer) patchDst(offset int64, mask, bits uint8) error {
+	_, err := w.seeker.Seek(offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	b := []byte{bits}
+	if mask != 0xff {
+		// the other bits of the byte have to be read back from the destination
+		rws, ok := w.seeker.(io.ReadWriteSeeker)
+		if !ok {
+			return ErrNotPatchable
+		}
+		_, err = io.ReadFull(rws, b)
+		if err != nil {
+			return err
+		}
+		b[0] = b[0]&^mask | bits
+		_, err = w.seeker.Seek(offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.seeker.Write(b)
+	return err
+}
+
+// seekCurrent returns the current offset of the destination.
+func (w *Writer) seekCurrent() (int64, error) {
+	if w.seeker == nil {
+		return 0, ErrNotPatchable
+	}
+	return w.seeker.Seek(0, io.SeekCurrent)
+}
+
+// release removes the reservation at `bitOffset` from the pending reservations.
+func (w *Writer) release(bitOffset uint64) {
+	for i, o := range w.reserved {
+		if o == bitOffset {
+			w.reserved = append(w.reserved[:i], w.reserved[i+1:]...)
+			return
+		}
+	}
+}
+
+// holding returns true if the buffered bytes must be kept in the Writer to be patched or rolled back later.
+func (w *Writer) holding() bool {
+	_, ok := w.holdPoint()
+	return ok
+}
+
+// holdPoint returns the bit offset from which the bits must be kept in the Writer.
+func (w *Writer) holdPoint() (uint64, bool) {
+	if len(w.txns) > 0 {
+		p := w.txns[0].writtenBits
+		if w.seeker == nil && len(w.reserved) > 0 && w.reserved[0] < p {
+			p = w.reserved[0]
+		}
+		return p, true
+	}
+	if w.seeker == nil && len(w.reserved) > 0 {
+		return w.reserved[0], true
+	}
+	return 0, false
+}
+
+// writableBytes returns the number of buffered bytes which can be written to the destination.
+func (w *Writer) writableBytes() int {
+	p, ok := w.holdPoint()
+	if !ok {
+		return len(w.buf)
+	}
+
+	completed := (w.writtenBits - uint64(w.PendingBits())) / 8
+	written := completed - uint64(len(w.buf))
+	held := p / 8
+	if held < written {
+		return 0
+	}
+	if n := held - written; n < uint64(len(w.buf)) {
+		return int(n)
+	}
+	return len(w.buf)
+}
+
+// probeSeeker returns `dst` as an io.WriteSeeker if it can actually seek, otherwise nil.
+func probeSeeker(dst io.Writer) io.WriteSeeker {
+	ws, ok := dst.(io.WriteSeeker)
+	if !ok {
+		return nil
+	}
+	_, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil
+	}
+	return ws
+}
@@ -0,0 +1,244 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// seekBuffer is an in-memory io.ReadWriteSeeker.
+type seekBuffer struct {
+	data []byte
+	off  int
+}
+
+func (sb *seekBuffer) Read(p []byte) (int, error) {
+	if sb.off >= len(sb.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, sb.data[sb.off:])
+	sb.off += n
+	return n, nil
+}
+
+func (sb *seekBuffer) Write(p []byte) (int, error) {
+	if end := sb.off + len(p); end > len(sb.data) {
+		sb.data = append(sb.data, make([]byte, end-len(sb.data))...)
+	}
+	n := copy(sb.data[sb.off:], p)
+	sb.off += n
+	return n, nil
+}
+
+func (sb *seekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		sb.off = int(offset)
+	case io.SeekCurrent:
+		sb.off += int(offset)
+	case io.SeekEnd:
+		sb.off = len(sb.data) + int(offset)
+	}
+	return int64(sb.off), nil
+}
+
+// writeSeeker hides the Read method of seekBuffer.
+type writeSeeker struct {
+	sb *seekBuffer
+}
+
+func (ws writeSeeker) Write(p []byte) (int, error) {
+	return ws.sb.Write(p)
+}
+
+func (ws writeSeeker) Seek(offset int64, whence int) (int64, error) {
+	return ws.sb.Seek(offset, whence)
+}
+
+func TestReservePatch(t *testing.T) {
+	testData := []struct {
+		Name       string
+		BufferSize uint
+		Prefix     uint8 // number of '1' bits written before the reservation
+		NBits      uint
+		Value      uint64
+		Expected   []byte
+	}{
+		{
+			// 1111 1111 | 0000 0000 0000 0011 | 1010 1010 1010 1010 1010 1010
+			Name:       "pattern 1",
+			BufferSize: 0,
+			Prefix:     8,
+			NBits:      16,
+			Value:      3,
+			Expected:   []byte{0xff, 0x00, 0x03, 0xaa, 0xaa, 0xaa},
+		},
+		{
+			// 111 0 0000 0000 0011 | 1010 1010 1010 1010 1010 1010 | 0
+			Name:       "pattern 2",
+			BufferSize: 1,
+			Prefix:     3,
+			NBits:      13,
+			Value:      3,
+			Expected:   []byte{0xe0, 0x03, 0xaa, 0xaa, 0xaa},
+		},
+		{
+			// 11 101 | 1010 1010 1010 1010 1010 1010 | 000
+			Name:       "pattern 3",
+			BufferSize: 1,
+			Prefix:     2,
+			NBits:      3,
+			Value:      0xfd,
+			Expected:   []byte{0xed, 0x55, 0x55, 0x50},
+		},
+		{
+			// 1 000 0000 0000 0000 0000 0000 0000 0000 0000 0000 0000 0000 0000 0000 0000 0000 1 | 1010 1010 1010 1010 1010 1010 | 000 0000
+			Name:       "pattern 4",
+			BufferSize: 2,
+			Prefix:     1,
+			NBits:      64,
+			Value:      1,
+			Expected:   []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xd5, 0x55, 0x55, 0x00},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			for _, seekable := range []bool{false, true} {
+				var dst io.Writer = &bytes.Buffer{}
+				sb := &seekBuffer{}
+				if seekable {
+					dst = sb
+				}
+
+				bw := NewWriterWithOptions(dst, &WriterOptions{BufferSize: data.BufferSize})
+				bw.WriteRun(1, uint64(data.Prefix))
+				r, err := bw.Reserve(data.NBits)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if uint64(data.Prefix) != r.BitOffset() {
+					t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Prefix, r.BitOffset())
+				}
+				bw.WriteBytes([]byte{0xaa, 0xaa, 0xaa})
+
+				err = bw.Patch(r, data.Value)
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				err = bw.Finalize()
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+
+				actual := sb.data
+				if !seekable {
+					actual = dst.(*bytes.Buffer).Bytes()
+				}
+				if !bytes.Equal(data.Expected, actual) {
+					t.Fatalf("\nseekable: %v\nExpected: %+v\nActual:   %+v\n", seekable, data.Expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestReservePatchSmallBuffer(t *testing.T) {
+	for _, bufferSize := range []uint{1, 4} {
+		for _, seekable := range []bool{false, true} {
+			var dst io.Writer = &bytes.Buffer{}
+			sb := &seekBuffer{}
+			if seekable {
+				dst = sb
+			}
+
+			bw := NewWriterWithOptions(dst, &WriterOptions{BufferSize: bufferSize})
+			bw.WriteUint32BE(0x01020304)
+			bw.WriteNBitsOfUint8(3, 0x07)
+			r, _ := bw.Reserve(10)
+			bw.WriteUint16BE(0xaaaa)
+			bw.WriteNBitsOfUint32BE(27, 0x5555555)
+
+			err := bw.Patch(r, 0x3ff)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			err = bw.Finalize()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+
+			// 0x01020304 | 111 1 1111 | 1111 1 101 | 0101 0101 | 010 1 0101 | 0101 0101 | 0101 0101 | 0101 0101
+			expected := []byte{0x01, 0x02, 0x03, 0x04, 0xff, 0xfd, 0x55, 0x55, 0x55, 0x55, 0x55}
+			actual := sb.data
+			if !seekable {
+				actual = dst.(*bytes.Buffer).Bytes()
+			}
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("\nbuffer size: %d, seekable: %v\nExpected: %+v\nActual:   %+v\n", bufferSize, seekable, expected, actual)
+			}
+		}
+	}
+}
+
+func TestReserveHoldsBytes(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	bw := NewWriter(buf)
+
+	bw.WriteUint8(0x01)
+	r, _ := bw.Reserve(8)
+	bw.WriteBytes(bytes.Repeat([]byte{0xff}, 10))
+
+	// the bytes after the reservation are kept in the Writer
+	err := bw.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !bytes.Equal([]byte{0x01}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0x01}, buf.Bytes())
+	}
+
+	err = bw.Finalize()
+	if !errors.Is(err, ErrPendingReservation) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrPendingReservation, err)
+	}
+
+	bw.Patch(r, 10)
+	err = bw.Finalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expected := append([]byte{0x01, 0x0a}, bytes.Repeat([]byte{0xff}, 10)...)
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, buf.Bytes())
+	}
+}
+
+func TestReserveError(t *testing.T) {
+	bw := NewWriter(&bytes.Buffer{})
+	_, err := bw.Reserve(65)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+
+	bw.SetStrictValues(true)
+	r, _ := bw.Reserve(4)
+	err = bw.Patch(r, 16)
+	if !errors.Is(err, ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrValueOutOfRange, err)
+	}
+
+	// a partial byte written to a destination which cannot be read back
+	sb := &seekBuffer{}
+	bw = NewWriter(writeSeeker{sb: sb})
+	bw.WriteNBitsOfUint8(4, 0x0f)
+	r, _ = bw.Reserve(8)
+	bw.WriteUint8(0xff)
+
+	err = bw.Patch(r, 0xff)
+	if !errors.Is(err, ErrNotPatchable) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNotPatchable, err)
+	}
+}
@@ -0,0 +1,50 @@
+package bitstream
+
+// reverseTable maps a byte to the byte whose bits are in the reverse order.
+var reverseTable = func() (t [256]uint8) {
+	for i := range t {
+		for b := 0; b < 8; b++ {
+			t[i] |= uint8(i>>b&0x01) << (7 - b)
+		}
+	}
+	return t
+}()
+
+// ReverseBits8 returns `v` with its bits in the reverse order, e.g. 0b00000110 becomes 0b01100000.
+func ReverseBits8(v uint8) uint8 {
+	return reverseTable[v]
+}
+
+// ReverseBits16 returns `v` with its bits in the reverse order.
+func ReverseBits16(v uint16) uint16 {
+	return uint16(reverseTable[v&0xff])<<8 | uint16(reverseTable[v>>8])
+}
+
+// ReverseBits32 returns `v` with its bits in the reverse order.
+func ReverseBits32(v uint32) uint32 {
+	return uint32(ReverseBits16(uint16(v)))<<16 | uint32(ReverseBits16(uint16(v>>16)))
+}
+
+// ReverseBits64 returns `v` with its bits in the reverse order.
+func ReverseBits64(v uint64) uint64 {
+	return uint64(ReverseBits32(uint32(v)))<<32 | uint64(ReverseBits32(uint32(v>>32)))
+}
+
+// ReverseNBits returns the lower `nBits` bits of `v` in the reverse order; the upper bits are dropped.
+// It is useful for the reflected fields whose width is not a multiple of 8, e.g. a CRC-5 or a bit-reversed FFT index.
+// If `nBits` > 64, it is treated as 64.
+func ReverseNBits(v uint64, nBits uint8) uint64 {
+	if nBits == 0 {
+		return 0
+	}
+	return ReverseBits64(v) >> (64 - min(nBits, 64))
+}
+
+// ReverseBytesBitwise reverses the order of the bits in each byte of `p` in place, which converts a byte sequence
+// of a LSB-first (reflected) protocol into the MSB-first order of Reader and Writer, and vice versa.
+// The order of the bytes is not changed.
+func ReverseBytesBitwise(p []byte) {
+	for i, b := range p {
+		p[i] = reverseTable[b]
+	}
+}
@@ -0,0 +1,74 @@
+package bitstream
+
+import (
+	"bytes"
+	"math/bits"
+	"testing"
+)
+
+func TestReverseBits(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		if actual, expected := ReverseBits8(uint8(i)), bits.Reverse8(uint8(i)); expected != actual {
+			t.Fatalf("\nExpected: %#x\nActual:   %#x\n", expected, actual)
+		}
+	}
+
+	testData := []struct {
+		Name     string
+		Input    uint64
+		Expected uint64
+	}{
+		{Name: "pattern 1", Input: 0x0000000000000001, Expected: 0x8000000000000000},
+		{Name: "pattern 2", Input: 0x0123456789abcdef, Expected: 0xf7b3d591e6a2c480}, // 0000 0001 0010 ... -> ... 0100 1000 0000
+		{Name: "pattern 3", Input: 0xffff0000ffff0000, Expected: 0x0000ffff0000ffff},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			if actual := ReverseBits64(data.Input); data.Expected != actual {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Expected, actual)
+			}
+			if actual, expected := ReverseBits32(uint32(data.Input)), uint32(data.Expected>>32); expected != actual {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", expected, actual)
+			}
+			if actual, expected := ReverseBits16(uint16(data.Input)), uint16(data.Expected>>48); expected != actual {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", expected, actual)
+			}
+		})
+	}
+}
+
+func TestReverseNBits(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Input    uint64
+		NBits    uint8
+		Expected uint64
+	}{
+		{Name: "pattern 1", Input: 0x06, NBits: 3, Expected: 0x03}, // 110 -> 011
+		{Name: "pattern 2", Input: 0xf1, NBits: 5, Expected: 0x11}, // (111)1 0001 -> 1 0001
+		{Name: "pattern 3", Input: 0x01, NBits: 64, Expected: 0x8000000000000000},
+		{Name: "pattern 4", Input: 0x01, NBits: 100, Expected: 0x8000000000000000},
+		{Name: "pattern 5", Input: 0xff, NBits: 0, Expected: 0},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			actual := ReverseNBits(data.Input, data.NBits)
+			if data.Expected != actual {
+				t.Fatalf("\nExpected: %#x\nActual:   %#x\n", data.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestReverseBytesBitwise(t *testing.T) {
+	p := []byte{0x01, 0x80, 0x0f, 0xa5}
+	ReverseBytesBitwise(p)
+	expected := []byte{0x80, 0x01, 0xf0, 0xa5}
+	if !bytes.Equal(expected, p) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, p)
+	}
+}
@@ -0,0 +1,401 @@
+// Package rice implements Golomb-Rice codes for residuals of lossless audio and sensor data codecs,
+// on top of bitstream.Writer / bitstream.Reader.
+//
+// A non-negative value v is coded with the parameter k as the quotient v >> k in unary ('0' bits terminated by a '1' bit)
+// followed by the low k bits of v. Signed values are mapped to non-negative ones with the zigzag mapping
+// (0, -1, 1, -2, ... to 0, 1, 2, 3, ...).
+//
+// Encoder and Decoder estimate k from the running mean of the values coded so far (Shorten / LOCO-I style),
+// so no parameter needs to be transmitted. A value whose quotient would be 32 or more is escaped and written in 64 bits.
+// WritePartitioned and ReadPartitioned code a block of residuals split into partitions
+// with the parameter chosen for each partition, like FLAC. The partitioned format is:
+//
+//	order      4 bits  the block is split into 2^order partitions of the same size
+//	partition  repeated 2^order times:
+//	  k        5 bits  Rice parameter (0 - 30), or 31 for an escaped partition
+//	  width    7 bits  only for an escaped partition: number of bits of each value (0 - 64)
+//	  values   Rice coded with k, or zigzag mapped values in `width` bits for an escaped partition
+package rice
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+const (
+	// MaxPartitionOrder is the maximum partition order of WritePartitioned.
+	MaxPartitionOrder = 15
+
+	escapeK        = 31 // k which marks an escaped partition
+	adaptiveWindow = 64 // the running sum and count are halved when the count reaches this value
+	maxQuotient    = 32 // the adaptive coder writes a value whose quotient is this or larger in 64 bits after as many '0' bits
+)
+
+var (
+	// ErrInvalidPartition is returned when the partition order or the parameters of a partitioned block are invalid.
+	ErrInvalidPartition = errors.New("rice: invalid partition")
+)
+
+// Zigzag maps a signed value to a non-negative one: 0, -1, 1, -2, ... to 0, 1, 2, 3, ...
+func Zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// Unzigzag is the inverse of Zigzag.
+func Unzigzag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+// Write writes `v` with the Rice parameter `k` (<= 64).
+// The unary part takes v >> k bits, so `k` must be large enough for `v`; Encoder escapes such outliers.
+func Write(w *bitstream.Writer, k uint8, v uint64) error {
+	q, low := uint64(0), v
+	if k < 64 {
+		q, low = v>>k, v&(1<<k-1)
+	}
+	err := w.WriteRun(0, q)
+	if err != nil {
+		return err
+	}
+	err = w.WriteBit(1)
+	if err != nil {
+		return err
+	}
+	return w.WriteNamed("remainder", k, low)
+}
+
+// Read reads a value written with the Rice parameter `k` (<= 64).
+func Read(r *bitstream.Reader, k uint8) (uint64, error) {
+	q, err := r.CountLeadingZeros()
+	if err != nil {
+		return 0, err
+	}
+	return readRemainder(r, k, q)
+}
+
+// readRemainder reads the low `k` bits of a value whose quotient is `q`.
+func readRemainder(r *bitstream.Reader, k uint8, q uint64) (uint64, error) {
+	low, err := r.ReadNBitsAsUint64BE(k)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if k >= 64 {
+		if q > 0 {
+			return 0, bitstream.ErrValueOutOfRange
+		}
+		return low, nil
+	}
+	if q > math.MaxUint64>>k {
+		return 0, bitstream.ErrValueOutOfRange
+	}
+	return q<<k | low, nil
+}
+
+// WriteSigned writes `v` zigzag mapped with the Rice parameter `k`.
+func WriteSigned(w *bitstream.Writer, k uint8, v int64) error {
+	return Write(w, k, Zigzag(v))
+}
+
+// ReadSigned reads a value written by WriteSigned.
+func ReadSigned(r *bitstream.Reader, k uint8) (int64, error) {
+	u, err := Read(r, k)
+	if err != nil {
+		return 0, err
+	}
+	return Unzigzag(u), nil
+}
+
+// Bits returns the number of bits to code `v` with the Rice parameter `k`.
+// It saturates at math.MaxUint64.
+func Bits(k uint8, v uint64) uint64 {
+	if k >= 64 {
+		return 1 + uint64(k)
+	}
+	n, carry := bits.Add64(v>>k, 1+uint64(k), 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return n
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// estimator estimates the Rice parameter from the running mean of the values.
+type estimator struct {
+	sum   uint64
+	count uint64
+}
+
+func newEstimator(initialK uint8) estimator {
+	return estimator{
+		sum:   1 << min(initialK, 63),
+		count: 1,
+	}
+}
+
+// k returns the smallest k where count * 2^k >= sum, i.e. 2^k is about the mean.
+func (e *estimator) k() uint8 {
+	q := e.sum / e.count
+	if e.sum%e.count != 0 {
+		q++
+	}
+	if q == 0 {
+		return 0
+	}
+	return uint8(bits.Len64(q - 1))
+}
+
+func (e *estimator) update(u uint64) {
+	sum, carry := bits.Add64(e.sum, u, 0)
+	if carry != 0 {
+		sum = math.MaxUint64
+	}
+	e.sum = sum
+	e.count++
+	if e.count >= adaptiveWindow {
+		e.sum >>= 1
+		e.count >>= 1
+	}
+}
+
+// Encoder writes signed values with the Rice parameter adapted to the values written so far.
+type Encoder struct {
+	w   *bitstream.Writer
+	est estimator
+}
+
+// NewEncoder creates a new Encoder which writes to `w`. `initialK` is the Rice parameter for the first value.
+func NewEncoder(w *bitstream.Writer, initialK uint8) *Encoder {
+	return &Encoder{
+		w:   w,
+		est: newEstimator(initialK),
+	}
+}
+
+// K returns the Rice parameter for the next value.
+func (e *Encoder) K() uint8 {
+	return e.est.k()
+}
+
+// Encode writes `v`. An outlier which would take too many bits is written as it is,
+// so a value takes 64 + 33 bits at most.
+func (e *Encoder) Encode(v int64) error {
+	u := Zigzag(v)
+	k := e.est.k()
+	var err error
+	if k < 64 && u>>k >= maxQuotient {
+		err = e.writeEscaped(u)
+	} else {
+		err = Write(e.w, k, u)
+	}
+	if err != nil {
+		return err
+	}
+	e.est.update(u)
+	return nil
+}
+
+func (e *Encoder) writeEscaped(u uint64) error {
+	err := e.w.WriteRun(0, maxQuotient)
+	if err != nil {
+		return err
+	}
+	err = e.w.WriteBit(1)
+	if err != nil {
+		return err
+	}
+	return e.w.WriteNamed("escaped", 64, u)
+}
+
+// Decoder reads signed values written by Encoder.
+type Decoder struct {
+	r   *bitstream.Reader
+	est estimator
+}
+
+// NewDecoder creates a new Decoder which reads from `r`. `initialK` must be the same as the one given to the Encoder.
+func NewDecoder(r *bitstream.Reader, initialK uint8) *Decoder {
+	return &Decoder{
+		r:   r,
+		est: newEstimator(initialK),
+	}
+}
+
+// K returns the Rice parameter for the next value.
+func (d *Decoder) K() uint8 {
+	return d.est.k()
+}
+
+// Decode reads a value.
+func (d *Decoder) Decode() (int64, error) {
+	q, err := d.r.CountLeadingZeros()
+	if err != nil {
+		return 0, err
+	}
+	k := d.est.k()
+	var u uint64
+	switch {
+	case k < 64 && q == maxQuotient:
+		u, err = d.r.ReadUint64BE()
+		err = unexpectedEOF(err)
+	case k < 64 && q > maxQuotient:
+		err = bitstream.ErrValueOutOfRange
+	default:
+		u, err = readRemainder(d.r, k, q)
+	}
+	if err != nil {
+		return 0, err
+	}
+	d.est.update(u)
+	return Unzigzag(u), nil
+}
+
+// WritePartitioned writes `residuals` split into partitions, choosing the partition order (<= `maxOrder`)
+// and the Rice parameter of each partition which minimize the size.
+// Only the orders which divide the residuals into partitions of the same size are considered.
+func WritePartitioned(w *bitstream.Writer, residuals []int64, maxOrder uint8) error {
+	if maxOrder > MaxPartitionOrder {
+		return ErrInvalidPartition
+	}
+
+	u := make([]uint64, len(residuals))
+	for i, v := range residuals {
+		u[i] = Zigzag(v)
+	}
+
+	bestOrder := uint8(0)
+	var bestParams []partitionParam
+	bestSize := uint64(math.MaxUint64)
+	for order := uint8(0); order <= maxOrder; order++ {
+		if order > 0 && len(u)%(1<<order) != 0 {
+			break
+		}
+		params := make([]partitionParam, 1<<order)
+		size := uint64(4)
+		n := len(u) >> order
+		for p := range params {
+			params[p] = bestParam(u[p*n : (p+1)*n])
+			size = addSat(size, params[p].size)
+		}
+		if size < bestSize {
+			bestOrder, bestParams, bestSize = order, params, size
+		}
+	}
+
+	sw := bitstream.NewStickyWriter(w)
+	sw.WriteNamed("order", 4, uint64(bestOrder))
+	n := len(u) >> bestOrder
+	for p, param := range bestParams {
+		sw.WriteNamed("k", 5, uint64(param.k))
+		if param.k == escapeK {
+			sw.WriteNamed("width", 7, uint64(param.width))
+			for _, v := range u[p*n : (p+1)*n] {
+				sw.WriteNamed("", param.width, v)
+			}
+			continue
+		}
+		if sw.Err() != nil {
+			return sw.Err()
+		}
+		for _, v := range u[p*n : (p+1)*n] {
+			err := Write(w, param.k, v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return sw.Err()
+}
+
+// partitionParam is the parameter of a partition and the size of the partition coded with it.
+type partitionParam struct {
+	k     uint8
+	width uint8 // only for an escaped partition
+	size  uint64
+}
+
+// bestParam finds the Rice parameter which minimizes the size of `u`, or escapes the partition if it is smaller.
+func bestParam(u []uint64) partitionParam {
+	var or uint64
+	for _, v := range u {
+		or |= v
+	}
+	width := uint8(bits.Len64(or))
+	best := partitionParam{
+		k:     escapeK,
+		width: width,
+		size:  5 + 7 + uint64(len(u))*uint64(width),
+	}
+	for k := uint8(0); k < escapeK; k++ {
+		size := uint64(5)
+		for _, v := range u {
+			size = addSat(size, Bits(k, v))
+		}
+		if size < best.size {
+			best = partitionParam{k: k, size: size}
+		}
+	}
+	return best
+}
+
+func addSat(a, b uint64) uint64 {
+	s, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return s
+}
+
+// ReadPartitioned reads `n` residuals written by WritePartitioned.
+func ReadPartitioned(r *bitstream.Reader, n int) ([]int64, error) {
+	order, err := r.ReadNBitsAsUint8(4)
+	if err != nil {
+		return nil, err
+	}
+	if order > 0 && n%(1<<order) != 0 {
+		return nil, ErrInvalidPartition
+	}
+
+	residuals := make([]int64, 0, n)
+	size := n >> order
+	for p := 0; p < 1<<order; p++ {
+		k, err := r.ReadNBitsAsUint8(5)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if k == escapeK {
+			width, err := r.ReadNBitsAsUint8(7)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if width > 64 {
+				return nil, ErrInvalidPartition
+			}
+			for i := 0; i < size; i++ {
+				u, err := r.ReadNBitsAsUint64BE(width)
+				if err != nil {
+					return nil, unexpectedEOF(err)
+				}
+				residuals = append(residuals, Unzigzag(u))
+			}
+			continue
+		}
+		for i := 0; i < size; i++ {
+			v, err := ReadSigned(r, k)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			residuals = append(residuals, v)
+		}
+	}
+	return residuals, nil
+}
@@ -0,0 +1,207 @@
+package rice
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+func TestWrite(t *testing.T) {
+	testData := []struct {
+		Name     string
+		K        uint8
+		V        uint64
+		Expected []byte
+	}{
+		{
+			Name: "pattern 1",
+			// 1xxx xxxx
+			K:        0,
+			V:        0,
+			Expected: []byte{0x80},
+		},
+		{
+			Name: "pattern 2",
+			// 0001 10xx
+			K:        2,
+			V:        14, // 11 10
+			Expected: []byte{0x18},
+		},
+		{
+			Name: "pattern 3",
+			// 0000 0000 0110 1xxx
+			K:        3,
+			V:        77, // 1001 101
+			Expected: []byte{0x00, 0x68},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			w := bitstream.NewWriter(buf)
+			err := Write(w, data.K, data.V)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.Finalize()
+			if !bytes.Equal(data.Expected, buf.Bytes()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, buf.Bytes())
+			}
+
+			v, err := Read(bitstream.NewReader(buf, nil), data.K)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.V != v {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.V, v)
+			}
+		})
+	}
+}
+
+func TestZigzag(t *testing.T) {
+	for _, v := range []int64{0, -1, 1, -2, 2, math.MaxInt64, math.MinInt64} {
+		if Unzigzag(Zigzag(v)) != v {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", v, Unzigzag(Zigzag(v)))
+		}
+	}
+	if Zigzag(-3) != 5 || Zigzag(3) != 6 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []uint64{5, 6}, []uint64{Zigzag(-3), Zigzag(3)})
+	}
+}
+
+// residuals returns Laplacian-like residuals whose scale changes halfway.
+func residuals(rnd *rand.Rand, n int) []int64 {
+	v := make([]int64, n)
+	for i := range v {
+		scale := 4.0
+		if i >= n/2 {
+			scale = 1000
+		}
+		v[i] = int64(rnd.ExpFloat64() * scale)
+		if rnd.Intn(2) == 0 {
+			v[i] = -v[i]
+		}
+	}
+	return v
+}
+
+func TestAdaptive(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	values := residuals(rnd, 4096)
+	values = append(values, 0, math.MaxInt64, math.MinInt64, 0)
+
+	buf := bytes.NewBuffer([]byte{})
+	w := bitstream.NewWriter(buf)
+	e := NewEncoder(w, 4)
+	for _, v := range values {
+		err := e.Encode(v)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	if e.K() < 8 {
+		t.Fatalf("k should follow the mean\nExpected: >= %+v\nActual:   %+v\n", 8, e.K())
+	}
+	w.Finalize()
+
+	d := NewDecoder(bitstream.NewReader(buf, nil), 4)
+	for i, v := range values {
+		actual, err := d.Decode()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if v != actual {
+			t.Fatalf("\nvalue %d\nExpected: %+v\nActual:   %+v\n", i, v, actual)
+		}
+	}
+}
+
+func TestPartitioned(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	testData := []struct {
+		Name      string
+		Residuals []int64
+		MaxOrder  uint8
+	}{
+		{Name: "pattern 1", Residuals: []int64{}, MaxOrder: 4},
+		{Name: "pattern 2", Residuals: []int64{0, 0, 0}, MaxOrder: 4},
+		{Name: "pattern 3", Residuals: residuals(rnd, 4096), MaxOrder: 8},
+		{Name: "pattern 4", Residuals: []int64{math.MaxInt64, math.MinInt64, 1, -1}, MaxOrder: 2}, // escaped
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			w := bitstream.NewWriter(buf)
+			err := WritePartitioned(w, data.Residuals, data.MaxOrder)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			w.WriteNBitsOfUint8(5, 0x1b) // 1 1011
+			w.Finalize()
+
+			r := bitstream.NewReader(buf, nil)
+			actual, err := ReadPartitioned(r, len(data.Residuals))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(data.Residuals, actual) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Residuals, actual)
+			}
+			trailer, err := r.ReadNBitsAsUint8(5)
+			if err != nil || trailer != 0x1b {
+				t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0x1b, nil, trailer, err)
+			}
+		})
+	}
+}
+
+func TestPartitionedSize(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	values := residuals(rnd, 4096)
+
+	size := func(maxOrder uint8) int {
+		buf := bytes.NewBuffer([]byte{})
+		w := bitstream.NewWriter(buf)
+		WritePartitioned(w, values, maxOrder)
+		w.Finalize()
+		return buf.Len()
+	}
+
+	// the partitions follow the change of the scale
+	if size(0) <= size(4) {
+		t.Fatalf("\nExpected: %+v > %+v\n", size(0), size(4))
+	}
+}
+
+func TestError(t *testing.T) {
+	// 01, k = 64: the value does not fit in uint64
+	src := []byte{0x40, 0, 0, 0, 0, 0, 0, 0, 0}
+	_, err := Read(bitstream.NewReader(bytes.NewReader(src), nil), 64)
+	if !errors.Is(err, bitstream.ErrValueOutOfRange) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", bitstream.ErrValueOutOfRange, err)
+	}
+
+	// 01xx xxxx: the remainder is cut off
+	_, err = Read(bitstream.NewReader(bytes.NewReader([]byte{0x40}), nil), 8)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+
+	// order 1 for 3 residuals
+	_, err = ReadPartitioned(bitstream.NewReader(bytes.NewReader([]byte{0x10}), nil), 3)
+	if !errors.Is(err, ErrInvalidPartition) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidPartition, err)
+	}
+}
@@ -0,0 +1,40 @@
+package bitstream
+
+// Run is a run of identical bits, which is the unit of the run-length encoding of a bit stream.
+type Run struct {
+	Bit    uint8  // value of the bits in the run (0 or 1)
+	Length uint64 // number of bits in the run
+}
+
+// ReadRuns reads `nBits` bits from the bit stream and returns them run-length encoded.
+// Adjacent runs in the result always have different bit values.
+// If `nBits` == 0, this function always returns nil.
+func (r *Reader) ReadRuns(nBits uint64) ([]Run, error) {
+	pos := r.BitPosition()
+	total := nBits
+	var runs []Run
+	for nBits > 0 {
+		bit, length, err := r.readRunN(nBits)
+		if err != nil {
+			if len(runs) > 0 {
+				err = unexpectedEOF(err)
+			}
+			return nil, r.wrapError("ReadRuns", pos, err)
+		}
+		runs = append(runs, Run{Bit: bit, Length: length})
+		nBits -= length
+	}
+	r.trace("", pos, uint(total), runs)
+	return runs, nil
+}
+
+// WriteRuns decodes the run-length encoded bits and writes them to the bit stream.
+func (w *Writer) WriteRuns(runs []Run) error {
+	for _, run := range runs {
+		err := w.WriteRun(run.Bit, run.Length)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
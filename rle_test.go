@@ -0,0 +1,76 @@
+package bitstream
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestReadRuns(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Data     []byte
+		NBits    uint64
+		Expected []Run
+	}{
+		{
+			Name:     "pattern 1",        // b7654 3210 | 7654 3210
+			Data:     []byte{0x0f, 0x0f}, //  0000 1111 | 0000 1111
+			NBits:    16,                 //
+			Expected: []Run{{0, 4}, {1, 4}, {0, 4}, {1, 4}},
+		},
+		{
+			Name:     "pattern 2",              // b7654 3210 | 7654 3210 | 7654 3210
+			Data:     []byte{0xff, 0xff, 0x80}, //  1111 1111 | 1111 1111 | 1000 0000
+			NBits:    20,                       //  ^^^^ ^^^^   ^^^^ ^^^^   ^^^^
+			Expected: []Run{{1, 17}, {0, 3}},
+		},
+		{
+			Name:     "pattern 3",
+			Data:     []byte{0x00},
+			NBits:    0,
+			Expected: nil,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(data.Data), nil)
+			runs, err := r.ReadRuns(data.NBits)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !reflect.DeepEqual(data.Expected, runs) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, runs)
+			}
+		})
+	}
+}
+
+func TestRunsRoundTrip(t *testing.T) {
+	runs := []Run{{1, 3}, {0, 1000000}, {1, 13}, {0, 2}, {1, 64}, {0, 7}}
+	total := uint64(0)
+	for _, run := range runs {
+		total += run.Length
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	w := NewWriter(buf)
+	if err := w.WriteRuns(runs); err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if uint64(total) != w.WrittenBits() {
+		t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", total, w.WrittenBits())
+	}
+	w.Finalize()
+
+	r := NewReader(bytes.NewReader(buf.Bytes()), nil)
+	actual, err := r.ReadRuns(total)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual(runs, actual) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", runs, actual)
+	}
+}
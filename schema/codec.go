@@ -0,0 +1,318 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+// Decode reads a document described by the schema from `r`.
+// Each field is read with ReadNamed or ReadNBitsNamed with its path, so it can be observed with the trace hook.
+// On error, it returns the fields decoded so far together with a *FieldError.
+func (s *Schema) Decode(r *bitstream.Reader) (map[string]any, error) {
+	doc := map[string]any{}
+	d := &decoder{r: r}
+	err := d.body(s.body, &scope{vars: doc}, "")
+	return doc, err
+}
+
+// Encode writes `doc` to `w` as described by the schema.
+// Each field is written with WriteNamed or WriteNBitsNamed with its path, so it can be observed with the trace hook.
+func (s *Schema) Encode(w *bitstream.Writer, doc map[string]any) error {
+	e := &encoder{w: w}
+	return e.body(s.body, &scope{vars: doc}, "")
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// fieldError wraps `err` with the path unless it is already wrapped by the inner field.
+func fieldError(path string, err error) error {
+	if _, ok := err.(*FieldError); ok {
+		return err
+	}
+	return &FieldError{Path: path, Err: err}
+}
+
+// evalLength evaluates the length of an array or bytes.
+func evalLength(e expr, sc *scope) (int, error) {
+	n, err := e.eval(sc)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > math.MaxInt32 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidLength, n)
+	}
+	return int(n), nil
+}
+
+type decoder struct {
+	r *bitstream.Reader
+}
+
+func (d *decoder) body(body []stmt, sc *scope, prefix string) error {
+	for _, s := range body {
+		var err error
+		switch s := s.(type) {
+		case *field:
+			err = d.field(s, sc, prefix)
+		case *ifStmt:
+			var c int64
+			c, err = s.cond.eval(sc)
+			if err != nil {
+				return fieldError(join(prefix, "if"), err)
+			}
+			if c != 0 {
+				err = d.body(s.then, sc, prefix)
+			} else {
+				err = d.body(s.els, sc, prefix)
+			}
+		case *alignStmt:
+			if rem := d.r.BitPosition() % 8; rem != 0 {
+				err = d.r.Skip(uint(8 - rem))
+			}
+			if err != nil {
+				err = fieldError(join(prefix, "align"), err)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) field(f *field, sc *scope, prefix string) error {
+	path := join(prefix, f.name)
+	if f.count == nil {
+		v, err := d.value(f, sc, path)
+		if v != nil {
+			sc.vars[f.name] = v
+		}
+		return err
+	}
+
+	n, err := evalLength(f.count, sc)
+	if err != nil {
+		return fieldError(path, err)
+	}
+	arr := make([]any, 0, min(n, 1024)) // the count may be broken
+	for i := 0; i < n; i++ {
+		v, err := d.value(f, sc, fmt.Sprintf("%s[%d]", path, i))
+		if v != nil {
+			arr = append(arr, v)
+			sc.vars[f.name] = arr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	sc.vars[f.name] = arr
+	return nil
+}
+
+// value decodes a value of the field. It returns a partially decoded block together with an error.
+func (d *decoder) value(f *field, sc *scope, path string) (any, error) {
+	switch f.kind {
+	case kindUint:
+		v, err := d.r.ReadNamed(path, f.bits)
+		if err != nil {
+			return nil, fieldError(path, err)
+		}
+		return v, nil
+	case kindInt:
+		v, err := d.r.ReadNamed(path, f.bits)
+		if err != nil {
+			return nil, fieldError(path, err)
+		}
+		shift := 64 - f.bits
+		return int64(v<<shift) >> shift, nil
+	case kindBool:
+		v, err := d.r.ReadNamed(path, 1)
+		if err != nil {
+			return nil, fieldError(path, err)
+		}
+		return v == 1, nil
+	case kindBytes:
+		n, err := evalLength(f.size, sc)
+		if err != nil {
+			return nil, fieldError(path, err)
+		}
+		v, err := d.r.ReadNBitsNamed(path, uint(n)*8, nil)
+		if err != nil {
+			return nil, fieldError(path, err)
+		}
+		if v == nil {
+			v = []byte{}
+		}
+		return v, nil
+	}
+
+	m := map[string]any{}
+	return m, d.body(f.body, sc.child(m), path)
+}
+
+type encoder struct {
+	w *bitstream.Writer
+}
+
+func (e *encoder) body(body []stmt, sc *scope, prefix string) error {
+	for _, s := range body {
+		var err error
+		switch s := s.(type) {
+		case *field:
+			err = e.field(s, sc, prefix)
+		case *ifStmt:
+			var c int64
+			c, err = s.cond.eval(sc)
+			if err != nil {
+				return fieldError(join(prefix, "if"), err)
+			}
+			if c != 0 {
+				err = e.body(s.then, sc, prefix)
+			} else {
+				err = e.body(s.els, sc, prefix)
+			}
+		case *alignStmt:
+			_, err = e.w.AlignByte(0)
+			if err != nil {
+				err = fieldError(join(prefix, "align"), err)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) field(f *field, sc *scope, prefix string) error {
+	path := join(prefix, f.name)
+	v, ok := sc.vars[f.name]
+	if !ok {
+		return fieldError(path, ErrMissingField)
+	}
+	if f.count == nil {
+		return e.value(f, sc, v, path)
+	}
+
+	n, err := evalLength(f.count, sc)
+	if err != nil {
+		return fieldError(path, err)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return fieldError(path, ErrTypeMismatch)
+	}
+	if rv.Len() != n {
+		return fieldError(path, fmt.Errorf("%w: %d elements for %d", ErrLengthMismatch, rv.Len(), n))
+	}
+	for i := 0; i < n; i++ {
+		err := e.value(f, sc, rv.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) value(f *field, sc *scope, v any, path string) error {
+	var err error
+	switch f.kind {
+	case kindUint:
+		var u uint64
+		u, err = toUint64(v)
+		if err == nil && f.bits < 64 && u>>f.bits != 0 {
+			err = bitstream.ErrValueOutOfRange
+		}
+		if err == nil {
+			err = e.w.WriteNamed(path, f.bits, u)
+		}
+	case kindInt:
+		var n int64
+		n, err = toInt64(v)
+		if _, ok := v.(uint64); ok && n < 0 {
+			err = bitstream.ErrValueOutOfRange
+		}
+		if err == nil && (n < -1<<(f.bits-1) || n > 1<<(f.bits-1)-1) {
+			err = bitstream.ErrValueOutOfRange
+		}
+		if err == nil {
+			err = e.w.WriteNamed(path, f.bits, uint64(n)&(math.MaxUint64>>(64-f.bits)))
+		}
+	case kindBool:
+		var b bool
+		b, err = toBool(v)
+		if err == nil {
+			err = e.w.WriteNamed(path, 1, uint64(boolToInt(b)))
+		}
+	case kindBytes:
+		var p []byte
+		switch s := v.(type) {
+		case []byte:
+			p = s
+		case string:
+			p = []byte(s)
+		default:
+			err = ErrTypeMismatch
+		}
+		var n int
+		if err == nil {
+			n, err = evalLength(f.size, sc)
+		}
+		if err == nil && len(p) != n {
+			err = fmt.Errorf("%w: %d bytes for %d", ErrLengthMismatch, len(p), n)
+		}
+		if err == nil {
+			err = e.w.WriteNBitsNamed(path, uint(n)*8, p)
+		}
+	case kindBlock:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fieldError(path, ErrTypeMismatch)
+		}
+		return e.body(f.body, sc.child(m), path)
+	}
+	if err != nil {
+		return fieldError(path, err)
+	}
+	return nil
+}
+
+// toUint64 converts a non-negative integer of any Go type, or a float64 which holds one, to uint64.
+func toUint64(v any) (uint64, error) {
+	if u, ok := v.(uint64); ok {
+		return u, nil
+	}
+	n, err := toInt64(v)
+	if err != nil {
+		return 0, err
+	}
+	if f, ok := v.(float64); ok && f >= math.MaxInt64 {
+		return uint64(f), nil
+	}
+	if n < 0 {
+		return 0, bitstream.ErrValueOutOfRange
+	}
+	return uint64(n), nil
+}
+
+func toBool(v any) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	n, err := toInt64(v)
+	if err != nil {
+		return false, err
+	}
+	if n != 0 && n != 1 {
+		return false, ErrTypeMismatch
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,186 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+type expr interface {
+	eval(sc *scope) (int64, error)
+}
+
+// scope is the fields of a block, which are visible from the blocks in it.
+type scope struct {
+	vars   map[string]any
+	parent *scope
+}
+
+func (sc *scope) child(vars map[string]any) *scope {
+	return &scope{vars: vars, parent: sc}
+}
+
+// lookup finds the value of a (dotted) field name.
+// The first element is searched from the innermost block, and the rest are looked up in the blocks found.
+func (sc *scope) lookup(path []string) (any, bool) {
+	for s := sc; s != nil; s = s.parent {
+		v, ok := s.vars[path[0]]
+		if !ok {
+			continue
+		}
+		for _, name := range path[1:] {
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			v, ok = m[name]
+			if !ok {
+				return nil, false
+			}
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+type litExpr int64
+
+func (e litExpr) eval(sc *scope) (int64, error) {
+	return int64(e), nil
+}
+
+type refExpr struct {
+	name string
+	path []string
+}
+
+func (e *refExpr) eval(sc *scope) (int64, error) {
+	v, ok := sc.lookup(e.path)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUndefined, e.name)
+	}
+	if b, ok := v.(bool); ok {
+		return boolToInt(b), nil
+	}
+	n, err := toInt64(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", err, e.name)
+	}
+	return n, nil
+}
+
+type unaryExpr struct {
+	op string
+	x  expr
+}
+
+func (e *unaryExpr) eval(sc *scope) (int64, error) {
+	x, err := e.x.eval(sc)
+	if err != nil {
+		return 0, err
+	}
+	if e.op == "-" {
+		return -x, nil
+	}
+	return boolToInt(x == 0), nil
+}
+
+type binaryExpr struct {
+	op   string
+	x, y expr
+}
+
+var errDivisionByZero = errors.New("schema: division by zero")
+
+func (e *binaryExpr) eval(sc *scope) (int64, error) {
+	x, err := e.x.eval(sc)
+	if err != nil {
+		return 0, err
+	}
+
+	// short-circuit evaluation, so that `a != 0 && b / a > 1` works
+	switch {
+	case e.op == "&&" && x == 0:
+		return 0, nil
+	case e.op == "||" && x != 0:
+		return 1, nil
+	}
+
+	y, err := e.y.eval(sc)
+	if err != nil {
+		return 0, err
+	}
+	switch e.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/", "%":
+		if y == 0 {
+			return 0, errDivisionByZero
+		}
+		if e.op == "/" {
+			return x / y, nil
+		}
+		return x % y, nil
+	case "==":
+		return boolToInt(x == y), nil
+	case "!=":
+		return boolToInt(x != y), nil
+	case "<":
+		return boolToInt(x < y), nil
+	case "<=":
+		return boolToInt(x <= y), nil
+	case ">":
+		return boolToInt(x > y), nil
+	case ">=":
+		return boolToInt(x >= y), nil
+	}
+	// && and || whose left operand did not decide the result
+	return boolToInt(y != 0), nil
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// toInt64 converts an integer of any Go type, or a float64 which holds an integer, to int64.
+// uint64 values larger than math.MaxInt64 wrap around.
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint:
+		return int64(n), nil
+	case uint8:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case uint64:
+		return int64(n), nil
+	case float64:
+		if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxUint64 {
+			return 0, ErrTypeMismatch
+		}
+		if n >= math.MaxInt64 {
+			return int64(uint64(n)), nil
+		}
+		return int64(n), nil
+	}
+	return 0, ErrTypeMismatch
+}
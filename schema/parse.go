@@ -0,0 +1,342 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokOp // punctuation and operators
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case isLetter(c):
+			j := i + 1
+			for j < len(src) && (isLetter(src[j]) || isDigit(src[j]) || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[i:j], line: line})
+			i = j
+		case isDigit(c):
+			j := i + 1
+			for j < len(src) && (isLetter(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			toks = append(toks, token{kind: tokInt, text: src[i:j], line: line})
+			i = j
+		default:
+			op := ""
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			if op == "" && strings.IndexByte("{}[]()+-*/%<>!", c) >= 0 {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, &SyntaxError{Line: line, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			toks = append(toks, token{kind: tokOp, text: op, line: line})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, line: line}), nil
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func newParser(src string) (*parser, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{toks: toks}, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &SyntaxError{Line: t.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokOp || t.text != op {
+		return p.errorf(t, "expected %q, found %s", op, describe(t))
+	}
+	return nil
+}
+
+func describe(t token) string {
+	if t.kind == tokEOF {
+		return "end of schema"
+	}
+	return strconv.Quote(t.text)
+}
+
+// parseBody parses statements up to the end of the schema, or up to '}' if `inBlock` is true.
+// The closing '}' is not consumed.
+func (p *parser) parseBody(inBlock bool) ([]stmt, error) {
+	var body []stmt
+	names := map[string]bool{}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokEOF:
+			if inBlock {
+				return nil, p.errorf(t, "expected \"}\", found end of schema")
+			}
+			return body, nil
+		case t.kind == tokOp && t.text == "}":
+			if !inBlock {
+				return nil, p.errorf(t, "unexpected \"}\"")
+			}
+			return body, nil
+		}
+
+		s, err := p.parseStmt()
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := s.(*field); ok {
+			if names[f.name] {
+				return nil, p.errorf(t, "duplicate field %q", f.name)
+			}
+			names[f.name] = true
+		}
+		body = append(body, s)
+	}
+}
+
+func (p *parser) parseStmt() (stmt, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return nil, p.errorf(t, "expected a field name, found %s", describe(t))
+	}
+
+	switch t.text {
+	case "align":
+		return &alignStmt{}, nil
+	case "if":
+		return p.parseIf()
+	case "else":
+		return nil, p.errorf(t, "\"else\" without \"if\"")
+	}
+	if strings.Contains(t.text, ".") {
+		return nil, p.errorf(t, "invalid field name %q", t.text)
+	}
+
+	f := &field{name: t.text}
+	if n := p.peek(); n.kind == tokOp && n.text == "[" {
+		p.next()
+		count, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect("]")
+		if err != nil {
+			return nil, err
+		}
+		f.count = count
+	}
+
+	if n := p.peek(); n.kind == tokOp && n.text == "{" {
+		body, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		f.kind = kindBlock
+		f.body = body
+		return f, nil
+	}
+
+	err := p.parseType(f)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseBlock parses statements enclosed in braces.
+func (p *parser) parseBlock() ([]stmt, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.parseBody(true)
+	if err != nil {
+		return nil, err
+	}
+	return body, p.expect("}")
+}
+
+func (p *parser) parseIf() (stmt, error) {
+	cond, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	s := &ifStmt{cond: cond}
+	s.then, err = p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	if n := p.peek(); n.kind == tokIdent && n.text == "else" {
+		p.next()
+		if n := p.peek(); n.kind == tokIdent && n.text == "if" {
+			p.next()
+			elseIf, err := p.parseIf()
+			if err != nil {
+				return nil, err
+			}
+			s.els = []stmt{elseIf}
+			return s, nil
+		}
+		s.els, err = p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) parseType(f *field) error {
+	t := p.next()
+	if t.kind != tokIdent {
+		return p.errorf(t, "expected a type, found %s", describe(t))
+	}
+
+	switch {
+	case t.text == "bool":
+		f.kind = kindBool
+		return nil
+	case t.text == "bytes":
+		err := p.expect("(")
+		if err != nil {
+			return err
+		}
+		f.kind = kindBytes
+		f.size, err = p.parseExpr(0)
+		if err != nil {
+			return err
+		}
+		return p.expect(")")
+	case strings.HasPrefix(t.text, "u") || strings.HasPrefix(t.text, "s"):
+		n, err := strconv.ParseUint(t.text[1:], 10, 8)
+		if err != nil || n < 1 || n > 64 {
+			break
+		}
+		f.kind = kindUint
+		if t.text[0] == 's' {
+			f.kind = kindInt
+		}
+		f.bits = uint8(n)
+		return nil
+	}
+	return p.errorf(t, "unknown type %q", t.text)
+}
+
+var precedences = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+// parseExpr parses an expression whose binary operators bind tighter than `minPrec`.
+func (p *parser) parseExpr(minPrec int) (expr, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		prec, ok := precedences[t.text]
+		if t.kind != tokOp || !ok || prec <= minPrec {
+			return x, nil
+		}
+		p.next()
+		y, err := p.parseExpr(prec)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryExpr{op: t.text, x: x, y: y}
+	}
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokOp && (t.text == "-" || t.text == "!"):
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: t.text, x: x}, nil
+	case t.kind == tokOp && t.text == "(":
+		x, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case t.kind == tokInt:
+		v, err := strconv.ParseInt(t.text, 0, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %q", t.text)
+		}
+		return litExpr(v), nil
+	case t.kind == tokIdent:
+		return &refExpr{name: t.text, path: strings.Split(t.text, ".")}, nil
+	}
+	return nil, p.errorf(t, "expected an expression, found %s", describe(t))
+}
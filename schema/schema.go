@@ -0,0 +1,135 @@
+// Package schema decodes and encodes bit streams described by a compact declarative schema,
+// so that a format can be inspected and generated without writing Go code for it.
+//
+// A schema is a list of statements. Newlines are not significant, and '#' starts a comment.
+//
+//	version  u4              # unsigned integer of 4 bits (u1 - u64)
+//	delta    s12             # signed integer (two's complement) of 12 bits (s1 - s64)
+//	flag     bool            # 1 bit
+//	count    u8
+//	payload  bytes(count)    # `count` bytes
+//	items[count] {           # repeated `count` times
+//	    kind u2
+//	    if kind == 1 {       # conditional fields, an `else` block may follow
+//	        extra u6
+//	    } else {
+//	        small u2
+//	    }
+//	}
+//	align                    # skip to the next byte boundary ('0' bits are written)
+//	trailer[2] u16           # an array of scalar values
+//
+// An expression is an integer literal (decimal or 0x hexadecimal), a reference to a field decoded before,
+// or a combination of them with the operators + - * / % == != < <= > >= && || ! and parentheses.
+// A reference is resolved in the enclosing blocks from the innermost one, and a dotted name (e.g. `header.length`)
+// refers to a field in a block.
+//
+// A decoded document is a map[string]any where the values are uint64 (u), int64 (s), bool, []byte (bytes),
+// map[string]any (block) or []any (array). Encode accepts the same document; integers of the other Go types,
+// float64 values which hold integers (as decoded from JSON) and strings for bytes are accepted as well.
+// The fields of an `if` block belong to the enclosing block, and they are absent if the condition does not hold.
+package schema
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUndefined is returned when an expression refers to a field which has not been decoded or is not in the document.
+	ErrUndefined = errors.New("schema: undefined field")
+
+	// ErrMissingField is returned when a field to be encoded is not in the document.
+	ErrMissingField = errors.New("schema: missing field")
+
+	// ErrTypeMismatch is returned when a value in the document does not match the type of the field.
+	ErrTypeMismatch = errors.New("schema: type mismatch")
+
+	// ErrLengthMismatch is returned when the length of an array or bytes in the document differs from the one given by the schema.
+	ErrLengthMismatch = errors.New("schema: length mismatch")
+
+	// ErrInvalidLength is returned when the length given by an expression is negative.
+	ErrInvalidLength = errors.New("schema: invalid length")
+)
+
+// SyntaxError is returned when a schema cannot be parsed.
+type SyntaxError struct {
+	Line int // line number (1 origin)
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("schema: line %d: %s", e.Line, e.Msg)
+}
+
+// FieldError records an error and the path of the field where it occurred.
+type FieldError struct {
+	Path string // e.g. "items[2].extra"
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("schema: field %s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Schema is a parsed schema.
+type Schema struct {
+	body []stmt
+}
+
+// Parse parses a schema.
+func Parse(src string) (*Schema, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.parseBody(false)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{body: body}, nil
+}
+
+// MustParse is like Parse but panics if the schema cannot be parsed.
+func MustParse(src string) *Schema {
+	s, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+type kind int
+
+const (
+	kindUint kind = iota
+	kindInt
+	kindBool
+	kindBytes
+	kindBlock
+)
+
+type stmt interface{}
+
+// field is a field, an array of fields, a block or an array of blocks.
+type field struct {
+	name  string
+	count expr // nil unless it is an array
+	kind  kind
+	bits  uint8  // for kindUint and kindInt
+	size  expr   // number of bytes for kindBytes
+	body  []stmt // for kindBlock
+}
+
+type ifStmt struct {
+	cond expr
+	then []stmt
+	els  []stmt
+}
+
+type alignStmt struct{}
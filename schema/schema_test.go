@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+const testSchema = `
+version u4
+flags {
+	big    bool
+	signed bool
+	rsv    u2
+}
+count u8
+items[count] {
+	kind u2
+	if kind == 1 {
+		extra u6
+	} else {
+		small u2
+	}
+}
+align
+name    bytes(flags.big + 1) # 2 bytes
+delta   s12
+trailer[2] u16
+`
+
+// 0011 1001  0000 0010  0110 1010  1011 0000  'h' 'i'  1111 1111  1101 0001  0010 0011  0100 1010  1011 1100  1101 0000
+var testData = []byte{0x39, 0x02, 0x6a, 0xb0, 0x68, 0x69, 0xff, 0xd1, 0x23, 0x4a, 0xbc, 0xd0}
+
+var testDoc = map[string]any{
+	"version": uint64(3),
+	"flags":   map[string]any{"big": true, "signed": false, "rsv": uint64(1)},
+	"count":   uint64(2),
+	"items": []any{
+		map[string]any{"kind": uint64(1), "extra": uint64(42)},
+		map[string]any{"kind": uint64(2), "small": uint64(3)},
+	},
+	"name":    []byte("hi"),
+	"delta":   int64(-3),
+	"trailer": []any{uint64(0x1234), uint64(0xabcd)},
+}
+
+func TestParse(t *testing.T) {
+	testData := []struct {
+		Name string
+		Src  string
+		Line int
+	}{
+		{Name: "pattern 1", Src: "a u65", Line: 1},
+		{Name: "pattern 2", Src: "a u8\nb {\n c u1\n", Line: 4},
+		{Name: "pattern 3", Src: "a u8\na u8", Line: 2},
+		{Name: "pattern 4", Src: "a u8\nelse {}", Line: 2},
+		{Name: "pattern 5", Src: "a u8\nb bytes(a +)", Line: 2},
+		{Name: "pattern 6", Src: "a.b u8", Line: 1},
+		{Name: "pattern 7", Src: "a u8 $", Line: 1},
+		{Name: "pattern 8", Src: "}", Line: 1},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			_, err := Parse(data.Src)
+			var se *SyntaxError
+			if !errors.As(err, &se) || se.Line != data.Line {
+				t.Fatalf("\nExpected: line %+v\nActual:   %+v\n", data.Line, err)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	s := MustParse(testSchema)
+	r := bitstream.NewReader(bytes.NewReader(testData), nil)
+	doc, err := s.Decode(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !reflect.DeepEqual(testDoc, doc) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", testDoc, doc)
+	}
+	if r.BitPosition() != 92 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 92, r.BitPosition())
+	}
+}
+
+func TestEncode(t *testing.T) {
+	s := MustParse(testSchema)
+
+	// a document decoded from JSON has float64 numbers and a string for bytes
+	var jsonDoc map[string]any
+	err := json.Unmarshal([]byte(`{
+		"version": 3, "flags": {"big": true, "signed": 0, "rsv": 1}, "count": 2,
+		"items": [{"kind": 1, "extra": 42}, {"kind": 2, "small": 3}],
+		"name": "hi", "delta": -3, "trailer": [4660, 43981]
+	}`), &jsonDoc)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	for _, doc := range []map[string]any{testDoc, jsonDoc} {
+		buf := bytes.NewBuffer([]byte{})
+		w := bitstream.NewWriter(buf)
+		err := s.Encode(w, doc)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		w.Finalize()
+		if !bytes.Equal(testData, buf.Bytes()) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", testData, buf.Bytes())
+		}
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	s := MustParse(testSchema)
+	r := bitstream.NewReader(bytes.NewReader(testData[:10]), nil)
+	doc, err := s.Decode(r)
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Path != "trailer[1]" {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", "trailer[1]", err)
+	}
+	// the fields decoded so far are returned
+	if !reflect.DeepEqual([]any{uint64(0x1234)}, doc["trailer"]) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []any{uint64(0x1234)}, doc["trailer"])
+	}
+
+	_, err = MustParse("a u4\nb bytes(a - 8)").Decode(bitstream.NewReader(bytes.NewReader([]byte{0x10}), nil))
+	if !errors.Is(err, ErrInvalidLength) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidLength, err)
+	}
+
+	_, err = MustParse("a u4\nb bytes(c)").Decode(bitstream.NewReader(bytes.NewReader([]byte{0x10}), nil))
+	if !errors.Is(err, ErrUndefined) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrUndefined, err)
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	s := MustParse("a u4\nb[a] s4\nc { d bool }")
+
+	testData := []struct {
+		Name     string
+		Doc      map[string]any
+		Path     string
+		Expected error
+	}{
+		{Name: "pattern 1", Doc: map[string]any{"a": 16}, Path: "a", Expected: bitstream.ErrValueOutOfRange},
+		{Name: "pattern 2", Doc: map[string]any{"a": -1}, Path: "a", Expected: bitstream.ErrValueOutOfRange},
+		{Name: "pattern 3", Doc: map[string]any{"a": 2, "b": []int{1}}, Path: "b", Expected: ErrLengthMismatch},
+		{Name: "pattern 4", Doc: map[string]any{"a": 2, "b": []int{1, 8}}, Path: "b[1]", Expected: bitstream.ErrValueOutOfRange},
+		{Name: "pattern 5", Doc: map[string]any{"a": 1, "b": []int{-8}}, Path: "c", Expected: ErrMissingField},
+		{Name: "pattern 6", Doc: map[string]any{"a": 1, "b": []int{-8}, "c": 1}, Path: "c", Expected: ErrTypeMismatch},
+		{Name: "pattern 7", Doc: map[string]any{"a": 1, "b": []int{-8}, "c": map[string]any{"d": 2}}, Path: "c.d", Expected: ErrTypeMismatch},
+		{Name: "pattern 8", Doc: map[string]any{"a": 1.5}, Path: "a", Expected: ErrTypeMismatch},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			w := bitstream.NewWriter(bytes.NewBuffer([]byte{}))
+			err := s.Encode(w, data.Doc)
+			var fe *FieldError
+			if !errors.Is(err, data.Expected) || !errors.As(err, &fe) || fe.Path != data.Path {
+				t.Fatalf("\nExpected: %+v at %+v\nActual:   %+v\n", data.Expected, data.Path, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package bitstream
+
+import (
+	"fmt"
+	"io"
+)
+
+// SeekBit moves the read position to `offset` bits from the beginning of the bit stream.
+// It is supported by the Readers created by NewReaderBytes or NewReaderMmap, and by the Readers whose source
+// implements io.Seeker (in which case the offset is relative to the beginning of the source) unless Prefetch is enabled.
+// Otherwise it returns ErrNotSeekable.
+// Seeking beyond the end of the bit stream is allowed; the next read returns io.EOF.
+func (r *Reader) SeekBit(offset uint64) error {
+	pos := r.BitPosition()
+	return r.wrapError("SeekBit", pos, r.seekBit(offset))
+}
+
+func (r *Reader) seekBit(offset uint64) error {
+	if r.closed {
+		return ErrClosed
+	}
+
+	offset += r.origin
+	byteOffset := offset / 8
+	phase := uint8(offset % 8)
+
+	if r.inMemory {
+		r.currByteIndex = uint(min(byteOffset, uint64(r.bufLen)))
+		r.consumedBytes = uint(byteOffset)
+		r.currBitIndex = 7
+		if phase != 0 && byteOffset < uint64(r.bufLen) {
+			r.currBitIndex = 7 - phase
+		}
+		return nil
+	}
+
+	seeker, ok := r.src.(io.Seeker)
+	if !ok || r.opt.GetPrefetch() {
+		return ErrNotSeekable
+	}
+	_, err := seeker.Seek(int64(byteOffset), io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNotSeekable, err)
+	}
+
+	// discard the buffer
+	r.bufLen = 0
+	r.currByteIndex = 0
+	r.currBitIndex = 7
+	r.consumedBytes = uint(byteOffset)
+	r.srcEOF = false
+	r.srcErr = nil
+	if phase == 0 {
+		return nil
+	}
+
+	err = r.fillBuf()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.currBitIndex = 7 - phase
+	return nil
+}
@@ -0,0 +1,144 @@
+package bitstream
+
+// StickyWriter is a bit stream writer which records the first error and ignores the subsequent writes, like bufio.Writer.
+// Its write methods return the StickyWriter itself so that calls can be chained, and the error is checked once with Err:
+//
+//	sw := bitstream.NewStickyWriter(w)
+//	sw.WriteNBitsOfUint8(4, version).WriteNBitsOfUint8(4, ihl).WriteUint8(tos)
+//	if err := sw.Err(); err != nil {
+//		return err
+//	}
+type StickyWriter struct {
+	w   *Writer
+	err error
+}
+
+// NewStickyWriter creates a new StickyWriter instance which writes bits to `w`.
+func NewStickyWriter(w *Writer) *StickyWriter {
+	return &StickyWriter{
+		w: w,
+	}
+}
+
+// Writer returns the underlying Writer.
+func (sw *StickyWriter) Writer() *Writer {
+	return sw.w
+}
+
+// Err returns the first error occurred in the StickyWriter, or nil if no error has occurred.
+func (sw *StickyWriter) Err() error {
+	return sw.err
+}
+
+// do calls `f` unless an error has already occurred, and records the error returned from it.
+func (sw *StickyWriter) do(f func() error) *StickyWriter {
+	if sw.err != nil {
+		return sw
+	}
+	sw.err = f()
+	return sw
+}
+
+// WriteBit writes a single bit to the bit stream.
+func (sw *StickyWriter) WriteBit(bit uint8) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteBit(bit) })
+}
+
+// WriteBool writes a single bit to the bit stream. (true: 1, false: 0)
+func (sw *StickyWriter) WriteBool(b bool) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteBool(b) })
+}
+
+// WriteRun writes `n` copies of a bit (the LSB of `bit`) to the bit stream.
+func (sw *StickyWriter) WriteRun(bit uint8, n uint64) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteRun(bit, n) })
+}
+
+// WriteRuns decodes the run-length encoded bits and writes them to the bit stream.
+func (sw *StickyWriter) WriteRuns(runs []Run) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteRuns(runs) })
+}
+
+// WriteNBitsOfUint8 writes `nBits` bits to the bit stream.
+func (sw *StickyWriter) WriteNBitsOfUint8(nBits, val uint8) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteNBitsOfUint8(nBits, val) })
+}
+
+// WriteUint8 writes a uint8 value to the bit stream.
+func (sw *StickyWriter) WriteUint8(val uint8) *StickyWriter {
+	return sw.WriteNBitsOfUint8(8, val)
+}
+
+// WriteNBitsOfUint16BE writes `nBits` bits to the bit stream.
+func (sw *StickyWriter) WriteNBitsOfUint16BE(nBits uint8, val uint16) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteNBitsOfUint16BE(nBits, val) })
+}
+
+// WriteUint16BE writes a uint16 value to the bit stream in big endian.
+func (sw *StickyWriter) WriteUint16BE(val uint16) *StickyWriter {
+	return sw.WriteNBitsOfUint16BE(16, val)
+}
+
+// WriteNBitsOfUint32BE writes `nBits` bits to the bit stream.
+func (sw *StickyWriter) WriteNBitsOfUint32BE(nBits uint8, val uint32) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteNBitsOfUint32BE(nBits, val) })
+}
+
+// WriteUint32BE writes a uint32 value to the bit stream in big endian.
+func (sw *StickyWriter) WriteUint32BE(val uint32) *StickyWriter {
+	return sw.WriteNBitsOfUint32BE(32, val)
+}
+
+// WriteNBitsOfUint64BE writes `nBits` bits to the bit stream.
+func (sw *StickyWriter) WriteNBitsOfUint64BE(nBits uint8, val uint64) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteNBitsOfUint64BE(nBits, val) })
+}
+
+// WriteUint64BE writes a uint64 value to the bit stream in big endian.
+func (sw *StickyWriter) WriteUint64BE(val uint64) *StickyWriter {
+	return sw.WriteNBitsOfUint64BE(64, val)
+}
+
+// WriteNBits writes specified number of bits of the bytes to the bit stream.
+func (sw *StickyWriter) WriteNBits(nBits uint, data []byte) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteNBits(nBits, data) })
+}
+
+// WriteNamed writes the LSB `nBits` bits of `val` to the bit stream. `name` is passed to the trace hook.
+func (sw *StickyWriter) WriteNamed(name string, nBits uint8, val uint64) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteNamed(name, nBits, val) })
+}
+
+// WriteBytes writes all the bytes in `p` to the bit stream.
+func (sw *StickyWriter) WriteBytes(p []byte) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteBytes(p) })
+}
+
+// WriteString writes all the bytes in `s` to the bit stream.
+func (sw *StickyWriter) WriteString(s string) *StickyWriter {
+	return sw.do(func() error { return sw.w.WriteString(s) })
+}
+
+// AlignByte pads the current byte with `padBit` up to the byte boundary.
+func (sw *StickyWriter) AlignByte(padBit uint8) *StickyWriter {
+	return sw.do(func() error {
+		_, err := sw.w.AlignByte(padBit)
+		return err
+	})
+}
+
+// Flush writes the complete bytes to the destination.
+func (sw *StickyWriter) Flush() *StickyWriter {
+	return sw.do(sw.w.Flush)
+}
+
+// Finalize pads the last byte according to the padding policy and writes it to the destination.
+func (sw *StickyWriter) Finalize() *StickyWriter {
+	return sw.do(sw.w.Finalize)
+}
+
+// Close closes the underlying Writer unless an error has already occurred, and returns the first error.
+// It implements io.Closer.
+func (sw *StickyWriter) Close() error {
+	return sw.do(sw.w.Close).err
+}
@@ -0,0 +1,66 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestStickyWriter(t *testing.T) {
+	buf := &closeRecorder{}
+	sw := NewStickyWriter(NewWriter(buf))
+
+	sw.WriteBit(1).
+		WriteBool(false).
+		WriteNBitsOfUint8(2, 0x02).
+		WriteUint8(0x53).
+		WriteNBitsOfUint16BE(10, 0x032d).
+		WriteUint16BE(0x0f5a).
+		WriteRun(1, 3).
+		WriteString("a").
+		AlignByte(0).
+		WriteUint64BE(0x0123456789abcdef).
+		WriteNBitsOfUint64BE(36, 0xfedcba987)
+	err := sw.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	// 1010 0101 0011 1100 1011 0100 0011 1101 0110 1011 | 1011 0000 | 1000 0000 | 0x0123456789abcdef | 0xfedcba987 0000
+	expected := []byte{0xa5, 0x3c, 0xb4, 0x3d, 0x6b, 0xb0, 0x80, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0xfe, 0xdc, 0xba, 0x98, 0x70}
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, buf.Bytes())
+	}
+	if !buf.closed {
+		t.Fatalf("destination should be closed\n")
+	}
+}
+
+func TestStickyWriterError(t *testing.T) {
+	buf := &closeRecorder{}
+	sw := NewStickyWriter(NewWriter(buf))
+
+	sw.WriteUint8(0x01).
+		WriteNBitsOfUint8(9, 0x00). // fails
+		WriteUint8(0x02).
+		WriteBytes([]byte{0x03, 0x04}).
+		Finalize()
+
+	if !errors.Is(sw.Err(), ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, sw.Err())
+	}
+	if !bytes.Equal([]byte{0x01}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0x01}, buf.Bytes())
+	}
+	if sw.Writer().WrittenBits() != 8 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 8, sw.Writer().WrittenBits())
+	}
+
+	err := sw.Close()
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+	if buf.closed {
+		t.Fatalf("destination should not be closed\n")
+	}
+}
@@ -0,0 +1,417 @@
+package bitstream
+
+import (
+	"sync"
+)
+
+// SyncReader is a bit stream reader which can be shared among goroutines.
+// Each method call is serialized with a mutex, so a field is never split between goroutines.
+// Use Do to read several fields without being interleaved with other goroutines.
+type SyncReader struct {
+	mu sync.Mutex
+	r  *Reader
+}
+
+// NewSyncReader creates a new SyncReader instance which reads bits from `r`.
+// `r` must not be used directly afterwards.
+func NewSyncReader(r *Reader) *SyncReader {
+	return &SyncReader{
+		r: r,
+	}
+}
+
+// Do calls `f` with the underlying Reader while holding the lock.
+func (sr *SyncReader) Do(f func(r *Reader) error) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return f(sr.r)
+}
+
+// Close stops the background prefetch of the underlying Reader if it is running.
+func (sr *SyncReader) Close() error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.Close()
+}
+
+// BitPosition returns the number of bits that has been consumed, i.e. the offset of the next bit to be read.
+func (sr *SyncReader) BitPosition() uint64 {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.BitPosition()
+}
+
+// ConsumedBytes returns a number of bytes that has been consumed.
+func (sr *SyncReader) ConsumedBytes() uint {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ConsumedBytes()
+}
+
+// Stats returns the counters of the underlying Reader.
+func (sr *SyncReader) Stats() ReaderStats {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.Stats()
+}
+
+// ReadBit reads a single bit from the bit stream.
+func (sr *SyncReader) ReadBit() (byte, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadBit()
+}
+
+// ReadBool reads a single bit from the bit stream and return it as a bool.
+func (sr *SyncReader) ReadBool() (bool, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadBool()
+}
+
+// ReadRun reads a run of identical bits from the bit stream.
+func (sr *SyncReader) ReadRun() (byte, uint64, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadRun()
+}
+
+// ReadRunN is the same as ReadRun except that at most `max` bits are consumed.
+func (sr *SyncReader) ReadRunN(max uint64) (byte, uint64, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadRunN(max)
+}
+
+// ReadRuns reads `nBits` bits from the bit stream and returns them run-length encoded.
+func (sr *SyncReader) ReadRuns(nBits uint64) ([]Run, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadRuns(nBits)
+}
+
+// CountLeadingZeros reads bits until a '1' bit is found and returns the number of '0' bits preceding it.
+func (sr *SyncReader) CountLeadingZeros() (uint64, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.CountLeadingZeros()
+}
+
+// CountLeadingOnes reads bits until a '0' bit is found and returns the number of '1' bits preceding it.
+func (sr *SyncReader) CountLeadingOnes() (uint64, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.CountLeadingOnes()
+}
+
+// ReadNBitsAsUint8 reads `nBits` bits as a unsigned integer from the bit stream and returns it in uint8 (LSB aligned).
+func (sr *SyncReader) ReadNBitsAsUint8(nBits uint8) (uint8, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadNBitsAsUint8(nBits)
+}
+
+// ReadUint8 reads 8 bits from the bit stream and returns it in uint8.
+func (sr *SyncReader) ReadUint8() (uint8, error) {
+	return sr.ReadNBitsAsUint8(8)
+}
+
+// ReadNBitsAsUint16BE reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint16 (LSB aligned).
+func (sr *SyncReader) ReadNBitsAsUint16BE(nBits uint8) (uint16, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadNBitsAsUint16BE(nBits)
+}
+
+// ReadUint16BE reads 16 bits as a big endian unsigned integer from the bit stream and returns it in uint16.
+func (sr *SyncReader) ReadUint16BE() (uint16, error) {
+	return sr.ReadNBitsAsUint16BE(16)
+}
+
+// ReadNBitsAsUint32BE reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint32 (LSB aligned).
+func (sr *SyncReader) ReadNBitsAsUint32BE(nBits uint8) (uint32, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadNBitsAsUint32BE(nBits)
+}
+
+// ReadUint32BE reads 32 bits as a big endian unsigned integer from the bit stream and returns it in uint32.
+func (sr *SyncReader) ReadUint32BE() (uint32, error) {
+	return sr.ReadNBitsAsUint32BE(32)
+}
+
+// ReadNBitsAsInt32BE reads `nBits` bits as a big endian signed integer from the bit stream and returns it in int32 (LSB aligned).
+func (sr *SyncReader) ReadNBitsAsInt32BE(nBits uint8) (int32, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadNBitsAsInt32BE(nBits)
+}
+
+// ReadNBitsAsUint64BE reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint64 (LSB aligned).
+func (sr *SyncReader) ReadNBitsAsUint64BE(nBits uint8) (uint64, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadNBitsAsUint64BE(nBits)
+}
+
+// ReadUint64BE reads 64 bits as a big endian unsigned integer from the bit stream and returns it in uint64.
+func (sr *SyncReader) ReadUint64BE() (uint64, error) {
+	return sr.ReadNBitsAsUint64BE(64)
+}
+
+// ReadNamed reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint64 (LSB aligned).
+// `name` is passed to the trace hook of the underlying Reader.
+func (sr *SyncReader) ReadNamed(name string, nBits uint8) (uint64, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadNamed(name, nBits)
+}
+
+// ReadNBits reads `nBits` bits from the bit stream and returns it as a slice of bytes.
+func (sr *SyncReader) ReadNBits(nBits uint, opt *ReadOptions) ([]byte, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadNBits(nBits, opt)
+}
+
+// ReadBytes reads `nBytes` bytes from the bit stream.
+func (sr *SyncReader) ReadBytes(nBytes uint) ([]byte, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadBytes(nBytes)
+}
+
+// ReadAll reads the remainder of the bit stream and returns it as a slice of bytes (left aligned).
+// `trailingBits` is the number of valid bits in the last byte.
+func (sr *SyncReader) ReadAll() ([]byte, uint8, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.ReadAll()
+}
+
+// Skip discards `nBits` bits of the bit stream.
+func (sr *SyncReader) Skip(nBits uint) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.r.Skip(nBits)
+}
+
+// SyncWriter is a bit stream writer which can be shared among goroutines.
+// Each method call is serialized with a mutex, so a field is never split between goroutines.
+// Use Do to write several fields without being interleaved with other goroutines.
+type SyncWriter struct {
+	mu sync.Mutex
+	w  *Writer
+}
+
+// NewSyncWriter creates a new SyncWriter instance which writes bits to `w`.
+// `w` must not be used directly afterwards.
+func NewSyncWriter(w *Writer) *SyncWriter {
+	return &SyncWriter{
+		w: w,
+	}
+}
+
+// Do calls `f` with the underlying Writer while holding the lock.
+func (sw *SyncWriter) Do(f func(w *Writer) error) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return f(sw.w)
+}
+
+// WrittenBits returns the cumulative number of bits written to the Writer.
+func (sw *SyncWriter) WrittenBits() uint64 {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WrittenBits()
+}
+
+// PendingBits returns the number of bits in the current byte which is not completed yet.
+func (sw *SyncWriter) PendingBits() uint8 {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.PendingBits()
+}
+
+// IsByteAligned returns true if there are no pending bits.
+func (sw *SyncWriter) IsByteAligned() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.IsByteAligned()
+}
+
+// Stats returns the counters of the underlying Writer.
+func (sw *SyncWriter) Stats() WriterStats {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Stats()
+}
+
+// WriteBit writes a single bit to the bit stream.
+func (sw *SyncWriter) WriteBit(bit uint8) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteBit(bit)
+}
+
+// WriteBool writes a single bit to the bit stream. (true: 1, false: 0)
+func (sw *SyncWriter) WriteBool(b bool) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteBool(b)
+}
+
+// WriteRun writes `n` copies of a bit (the LSB of `bit`) to the bit stream.
+func (sw *SyncWriter) WriteRun(bit uint8, n uint64) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteRun(bit, n)
+}
+
+// WriteRuns decodes the run-length encoded bits and writes them to the bit stream.
+func (sw *SyncWriter) WriteRuns(runs []Run) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteRuns(runs)
+}
+
+// WriteNBitsOfUint8 writes `nBits` bits to the bit stream.
+func (sw *SyncWriter) WriteNBitsOfUint8(nBits, val uint8) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteNBitsOfUint8(nBits, val)
+}
+
+// WriteUint8 writes a uint8 value to the bit stream.
+func (sw *SyncWriter) WriteUint8(val uint8) error {
+	return sw.WriteNBitsOfUint8(8, val)
+}
+
+// WriteNBitsOfUint16BE writes `nBits` bits to the bit stream.
+func (sw *SyncWriter) WriteNBitsOfUint16BE(nBits uint8, val uint16) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteNBitsOfUint16BE(nBits, val)
+}
+
+// WriteUint16BE writes a uint16 value to the bit stream in big endian.
+func (sw *SyncWriter) WriteUint16BE(val uint16) error {
+	return sw.WriteNBitsOfUint16BE(16, val)
+}
+
+// WriteNBitsOfUint32BE writes `nBits` bits to the bit stream.
+func (sw *SyncWriter) WriteNBitsOfUint32BE(nBits uint8, val uint32) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteNBitsOfUint32BE(nBits, val)
+}
+
+// WriteUint32BE writes a uint32 value to the bit stream in big endian.
+func (sw *SyncWriter) WriteUint32BE(val uint32) error {
+	return sw.WriteNBitsOfUint32BE(32, val)
+}
+
+// WriteNBitsOfUint64BE writes `nBits` bits to the bit stream.
+func (sw *SyncWriter) WriteNBitsOfUint64BE(nBits uint8, val uint64) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteNBitsOfUint64BE(nBits, val)
+}
+
+// WriteUint64BE writes a uint64 value to the bit stream in big endian.
+func (sw *SyncWriter) WriteUint64BE(val uint64) error {
+	return sw.WriteNBitsOfUint64BE(64, val)
+}
+
+// WriteNBits writes specified number of bits of the bytes to the bit stream.
+func (sw *SyncWriter) WriteNBits(nBits uint, data []byte) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteNBits(nBits, data)
+}
+
+// WriteNamed writes the LSB `nBits` bits of `val` to the bit stream. `name` is passed to the trace hook.
+func (sw *SyncWriter) WriteNamed(name string, nBits uint8, val uint64) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteNamed(name, nBits, val)
+}
+
+// WriteBytes writes all the bytes in `p` to the bit stream.
+func (sw *SyncWriter) WriteBytes(p []byte) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteBytes(p)
+}
+
+// WriteString writes all the bytes in `s` to the bit stream.
+func (sw *SyncWriter) WriteString(s string) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.WriteString(s)
+}
+
+// AlignByte pads the current byte with `padBit` up to the byte boundary and writes it to the destination.
+func (sw *SyncWriter) AlignByte(padBit uint8) (uint8, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.AlignByte(padBit)
+}
+
+// Flush writes the complete bytes to the destination.
+func (sw *SyncWriter) Flush() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Flush()
+}
+
+// Reserve reserves `nBits` bits to be patched later.
+func (sw *SyncWriter) Reserve(nBits uint) (Reservation, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Reserve(nBits)
+}
+
+// Patch fills the reserved bits with `val`.
+func (sw *SyncWriter) Patch(r Reservation, val uint64) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Patch(r, val)
+}
+
+// Begin starts a transaction.
+// Note that other goroutines writing to the SyncWriter in the middle of the transaction are also committed or rolled back with it.
+// Use Do to make a transaction atomic.
+func (sw *SyncWriter) Begin() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.w.Begin()
+}
+
+// Commit ends the innermost transaction and keeps the bits written in it.
+func (sw *SyncWriter) Commit() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Commit()
+}
+
+// Rollback ends the innermost transaction and discards the bits written in it.
+func (sw *SyncWriter) Rollback() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Rollback()
+}
+
+// Finalize pads the last byte according to the padding policy and writes it to the destination.
+func (sw *SyncWriter) Finalize() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Finalize()
+}
+
+// Close pads the last byte according to the padding policy and closes the underlying Writer.
+func (sw *SyncWriter) Close() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Close()
+}
@@ -0,0 +1,154 @@
+package bitstream
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestSyncWriter(t *testing.T) {
+	const (
+		nGoroutines = 8
+		nRecords    = 500
+	)
+
+	buf := &bytes.Buffer{}
+	sw := NewSyncWriter(NewWriter(buf))
+
+	var wg sync.WaitGroup
+	for g := 0; g < nGoroutines; g++ {
+		wg.Add(1)
+		go func(id uint8) {
+			defer wg.Done()
+			for i := 0; i < nRecords; i++ {
+				// a field written by a single call is never split
+				err := sw.WriteNBitsOfUint16BE(11, uint16(id)<<8|uint16(id))
+				if err != nil {
+					t.Errorf("unexpected error: %+v\n", err)
+					return
+				}
+
+				// a record written in Do is never interleaved
+				err = sw.Do(func(w *Writer) error {
+					err := w.WriteNBitsOfUint16BE(11, uint16(id)<<8|0xff)
+					if err != nil {
+						return err
+					}
+					return w.WriteNBitsOfUint8(5, id)
+				})
+				if err != nil {
+					t.Errorf("unexpected error: %+v\n", err)
+					return
+				}
+			}
+		}(uint8(g))
+	}
+	wg.Wait()
+
+	err := sw.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if sw.WrittenBits() != nGoroutines*nRecords*27 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", nGoroutines*nRecords*27, sw.WrittenBits())
+	}
+
+	counts := make(map[uint8]int)
+	r := NewReader(bytes.NewReader(buf.Bytes()), nil)
+	for i := 0; i < nGoroutines*nRecords*2; i++ {
+		v, err := r.ReadNBitsAsUint16BE(11)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		id := uint8(v >> 8)
+		if v&0xff == 0xff {
+			v2, err := r.ReadNBitsAsUint8(5)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if v2 != id {
+				t.Fatalf("record is interleaved\nExpected: %+v\nActual:   %+v\n", id, v2)
+			}
+		} else if uint8(v) != id {
+			t.Fatalf("field is split: %#x\n", v)
+		}
+		counts[id]++
+	}
+	for g := uint8(0); g < nGoroutines; g++ {
+		if counts[g] != nRecords*2 {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", nRecords*2, counts[g])
+		}
+	}
+}
+
+func TestSyncWriterUint64(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sw := NewSyncWriter(NewWriter(buf))
+
+	sw.WriteNBitsOfUint64BE(4, 0x0a)
+	sw.WriteUint64BE(0x0123456789abcdef)
+	sw.WriteNBitsOfUint64BE(4, 0x0b)
+	err := sw.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	expected := []byte{0xa0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xfb}
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, buf.Bytes())
+	}
+}
+
+func TestSyncReader(t *testing.T) {
+	const (
+		nGoroutines = 8
+		nValues     = 4096
+	)
+
+	// 13 bits for each value, so most of the values are not byte aligned. the stream ends exactly after the last value.
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	for i := 0; i < nValues; i++ {
+		err := w.WriteNBitsOfUint16BE(13, uint16(i))
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+
+	sr := NewSyncReader(NewReader(bytes.NewReader(buf.Bytes()), &ReaderOptions{BufferSize: 3}))
+
+	var mu sync.Mutex
+	seen := make([]bool, nValues)
+	var wg sync.WaitGroup
+	for g := 0; g < nGoroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				v, err := sr.ReadNBitsAsUint16BE(13)
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					t.Errorf("unexpected error: %+v\n", err)
+					return
+				}
+
+				mu.Lock()
+				if seen[v] {
+					t.Errorf("value %d is read twice\n", v)
+				}
+				seen[v] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, s := range seen {
+		if !s {
+			t.Fatalf("value %d is not read\n", i)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+// Package timeseries implements the compression of time series of Facebook's Gorilla (TSZ) on top of
+// bitstream.Writer / bitstream.Reader: delta-of-delta coding of the timestamps and XOR coding of the float64 values.
+//
+// A block starts with the timestamp t0 in 64 bits, and each point (t, v) follows it:
+//
+//	the first point  t - t0 in 14 bits, and v in 64 bits
+//	the others       D = (t - t_prev) - (t_prev - t_prevprev), the delta of deltas, coded as
+//	                   '0'                   if D == 0
+//	                   '10'   + D in 7 bits  if -63 <= D <= 64
+//	                   '110'  + D in 9 bits  if -255 <= D <= 256
+//	                   '1110' + D in 12 bits if -2047 <= D <= 2048
+//	                   '1111' + D in 32 bits otherwise
+//	                 and X = v XOR v_prev coded as
+//	                   '0'  if X == 0
+//	                   '10' + the meaningful bits of X, if they are within the ones of the previous value
+//	                   '11' + the number of leading zeros in 5 bits + the number of meaningful bits in 6 bits
+//	                        + the meaningful bits, otherwise
+//
+// D is written in the two's complement form of its width. Encoder.Finish marks the end of the block with
+// '1111' + 0xffffffff, or with 0x3fff in 14 bits for an empty block, so that the padding bits are not decoded as points.
+package timeseries
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+const (
+	firstDeltaBits = 14
+	endOfBlock32   = 0xffffffff            // D in 32 bits which marks the end of a block
+	endOfBlock14   = 1<<firstDeltaBits - 1 // the first delta which marks the end of an empty block
+)
+
+var (
+	// ErrTimestampOutOfRange is returned when a timestamp cannot be coded, i.e. the first timestamp is before t0
+	// or 16383 or more after it, a timestamp is before the previous one, or the delta of deltas does not fit in 32 bits.
+	ErrTimestampOutOfRange = errors.New("timeseries: timestamp out of range")
+
+	// ErrFinished is returned when a point is written after Finish.
+	ErrFinished = errors.New("timeseries: block already finished")
+)
+
+// dodBuckets are the codes of the delta of deltas other than 0, from the shortest one.
+var dodBuckets = [4]struct {
+	prefix     uint64
+	prefixBits uint8
+	nBits      uint8
+}{
+	{prefix: 0x2, prefixBits: 2, nBits: 7},
+	{prefix: 0x6, prefixBits: 3, nBits: 9},
+	{prefix: 0xe, prefixBits: 4, nBits: 12},
+	{prefix: 0xf, prefixBits: 4, nBits: 32},
+}
+
+// Encoder writes the points of a time series to a block.
+type Encoder struct {
+	w        *bitstream.Writer
+	t0       int64
+	n        uint64 // number of points written
+	tPrev    int64
+	delta    int64
+	vPrev    uint64
+	leading  uint8 // leading zeros of the meaningful bits of the previous value, or 0xff if there is none
+	trailing uint8
+	finished bool
+}
+
+// NewEncoder creates a new Encoder which writes a block starting at `t0` to `w`, and writes the header.
+// `t0` is usually the first timestamp aligned to the duration of a block, e.g. 2 hours.
+func NewEncoder(w *bitstream.Writer, t0 int64) (*Encoder, error) {
+	err := w.WriteNamed("t0", 64, uint64(t0))
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{
+		w:       w,
+		t0:      t0,
+		leading: 0xff,
+	}, nil
+}
+
+// Encode writes a point. The timestamps must not decrease.
+// It returns ErrTimestampOutOfRange and writes nothing if `t` cannot be coded.
+func (e *Encoder) Encode(t int64, v float64) error {
+	if e.finished {
+		return ErrFinished
+	}
+
+	var err error
+	if e.n == 0 {
+		delta := t - e.t0
+		if t < e.t0 || delta < 0 || delta >= endOfBlock14 {
+			return fmt.Errorf("%w: %d is not in [%d, %d)", ErrTimestampOutOfRange, t, e.t0, e.t0+endOfBlock14)
+		}
+		err = e.w.WriteNBitsOfUint64BE(firstDeltaBits, uint64(delta))
+		if err == nil {
+			err = e.w.WriteUint64BE(math.Float64bits(v))
+		}
+		e.delta = delta
+	} else {
+		delta := t - e.tPrev
+		dod := delta - e.delta
+		if t < e.tPrev || delta < 0 || dod < -(1<<31-1) || dod > 1<<31 {
+			return fmt.Errorf("%w: %d after %d", ErrTimestampOutOfRange, t, e.tPrev)
+		}
+		err = e.writeDoD(dod)
+		if err == nil {
+			err = e.writeXOR(math.Float64bits(v) ^ e.vPrev)
+		}
+		e.delta = delta
+	}
+	if err != nil {
+		return err
+	}
+	e.tPrev = t
+	e.vPrev = math.Float64bits(v)
+	e.n++
+	return nil
+}
+
+func (e *Encoder) writeDoD(dod int64) error {
+	if dod == 0 {
+		return e.w.WriteBit(0)
+	}
+	for _, b := range dodBuckets {
+		if dod >= -(1<<(b.nBits-1)-1) && dod <= 1<<(b.nBits-1) {
+			err := e.w.WriteNBitsOfUint8(b.prefixBits, uint8(b.prefix))
+			if err != nil {
+				return err
+			}
+			return e.w.WriteNBitsOfUint64BE(b.nBits, uint64(dod)&(1<<b.nBits-1))
+		}
+	}
+	return ErrTimestampOutOfRange // not reached; Encode checks the range
+}
+
+func (e *Encoder) writeXOR(x uint64) error {
+	if x == 0 {
+		return e.w.WriteBit(0)
+	}
+
+	leading := uint8(min(bits.LeadingZeros64(x), 31))
+	trailing := uint8(bits.TrailingZeros64(x))
+	if e.leading != 0xff && leading >= e.leading && trailing >= e.trailing {
+		err := e.w.WriteNBitsOfUint8(2, 0x2)
+		if err != nil {
+			return err
+		}
+		return e.w.WriteNBitsOfUint64BE(64-e.leading-e.trailing, x>>e.trailing)
+	}
+
+	// the number of meaningful bits is 1 - 64, and 64 is written as 0 in 6 bits
+	sig := 64 - leading - trailing
+	err := e.w.WriteNBitsOfUint8(2, 0x3)
+	if err == nil {
+		err = e.w.WriteNBitsOfUint8(5, leading)
+	}
+	if err == nil {
+		err = e.w.WriteNBitsOfUint8(6, sig&0x3f)
+	}
+	if err == nil {
+		err = e.w.WriteNBitsOfUint64BE(sig, x>>trailing)
+	}
+	if err != nil {
+		return err
+	}
+	e.leading, e.trailing = leading, trailing
+	return nil
+}
+
+// Finish writes the end of the block. It does not close the Writer.
+func (e *Encoder) Finish() error {
+	if e.finished {
+		return ErrFinished
+	}
+	var err error
+	if e.n == 0 {
+		err = e.w.WriteNBitsOfUint64BE(firstDeltaBits, endOfBlock14)
+	} else {
+		err = e.w.WriteNBitsOfUint8(4, 0xf)
+		if err == nil {
+			err = e.w.WriteUint32BE(endOfBlock32)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	e.finished = true
+	return nil
+}
+
+// Decoder reads the points of a time series from a block written by Encoder.
+type Decoder struct {
+	r        *bitstream.Reader
+	t0       int64
+	n        uint64 // number of points read
+	tPrev    int64
+	delta    int64
+	vPrev    uint64
+	leading  uint8
+	trailing uint8
+	done     bool
+}
+
+// NewDecoder creates a new Decoder which reads a block from `r`, and reads the header.
+func NewDecoder(r *bitstream.Reader) (*Decoder, error) {
+	t0, err := r.ReadNamed("t0", 64)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{
+		r:  r,
+		t0: int64(t0),
+	}, nil
+}
+
+// T0 returns the timestamp in the header of the block.
+func (d *Decoder) T0() int64 {
+	return d.t0
+}
+
+// Decode reads a point. It returns io.EOF at the end of the block.
+func (d *Decoder) Decode() (int64, float64, error) {
+	if d.done {
+		return 0, 0, io.EOF
+	}
+
+	var t int64
+	var v uint64
+	if d.n == 0 {
+		delta, err := d.r.ReadNBitsAsUint64BE(firstDeltaBits)
+		if err != nil {
+			return 0, 0, err
+		}
+		if delta == endOfBlock14 {
+			d.done = true
+			return 0, 0, io.EOF
+		}
+		v, err = d.r.ReadUint64BE()
+		if err != nil {
+			return 0, 0, unexpectedEOF(err)
+		}
+		t = d.t0 + int64(delta)
+		d.delta = int64(delta)
+	} else {
+		dod, end, err := d.readDoD()
+		if err != nil {
+			return 0, 0, err
+		}
+		if end {
+			d.done = true
+			return 0, 0, io.EOF
+		}
+		x, err := d.readXOR()
+		if err != nil {
+			return 0, 0, unexpectedEOF(err)
+		}
+		d.delta += dod
+		t = d.tPrev + d.delta
+		v = d.vPrev ^ x
+	}
+
+	d.tPrev = t
+	d.vPrev = v
+	d.n++
+	return t, math.Float64frombits(v), nil
+}
+
+func (d *Decoder) readDoD() (int64, bool, error) {
+	// the number of '1' bits before a '0' bit (up to 4) selects the code
+	ones := 0
+	for ; ones < len(dodBuckets); ones++ {
+		bit, err := d.r.ReadBit()
+		if err != nil {
+			if ones > 0 {
+				err = unexpectedEOF(err)
+			}
+			return 0, false, err
+		}
+		if bit == 0 {
+			break
+		}
+	}
+	if ones == 0 {
+		return 0, false, nil
+	}
+
+	b := dodBuckets[ones-1]
+	u, err := d.r.ReadNBitsAsUint64BE(b.nBits)
+	if err != nil {
+		return 0, false, unexpectedEOF(err)
+	}
+	if b.nBits == 32 && u == endOfBlock32 {
+		return 0, true, nil
+	}
+	dod := int64(u)
+	if u > 1<<(b.nBits-1) {
+		dod -= 1 << b.nBits
+	}
+	return dod, false, nil
+}
+
+func (d *Decoder) readXOR() (uint64, error) {
+	bit, err := d.r.ReadBit()
+	if err != nil || bit == 0 {
+		return 0, err
+	}
+	bit, err = d.r.ReadBit()
+	if err != nil {
+		return 0, err
+	}
+	if bit == 1 {
+		leading, err := d.r.ReadNBitsAsUint8(5)
+		if err != nil {
+			return 0, err
+		}
+		sig, err := d.r.ReadNBitsAsUint8(6)
+		if err != nil {
+			return 0, err
+		}
+		if sig == 0 {
+			sig = 64
+		}
+		if leading+sig > 64 {
+			return 0, fmt.Errorf("%w: %d leading zeros and %d meaningful bits", bitstream.ErrValueOutOfRange, leading, sig)
+		}
+		d.leading, d.trailing = leading, 64-leading-sig
+	}
+
+	x, err := d.r.ReadNBitsAsUint64BE(64 - d.leading - d.trailing)
+	if err != nil {
+		return 0, err
+	}
+	return x << d.trailing, nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+package timeseries
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bearmini/bitstream-go"
+)
+
+type point struct {
+	t int64
+	v float64
+}
+
+func encode(t *testing.T, t0 int64, points []point) []byte {
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	e, err := NewEncoder(w, t0)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	for _, p := range points {
+		err := e.Encode(p.t, p.v)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	err = e.Finish()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func decode(t *testing.T, data []byte) (int64, []point) {
+	d, err := NewDecoder(bitstream.NewReaderBytes(data, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	var points []point
+	for {
+		ts, v, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		points = append(points, point{t: ts, v: v})
+	}
+	return d.T0(), points
+}
+
+func samePoints(a, b []point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].t != b[i].t || math.Float64bits(a[i].v) != math.Float64bits(b[i].v) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEncode(t *testing.T) {
+	points := []point{{10, 1.0}, {20, 1.0}, {31, 3.0}}
+	expected := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // t0
+		0x00, 0x28, 0xff, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 10 in 14 bits, 1.0 in 64 bits
+		0x80, 0xe1, 0x33, 0xff, // '0' '0', '10' + 1, '11' + 1 + 12 + 0xfff
+		0xff, 0xff, 0xff, 0xff, 0xfc, // end of block
+	}
+
+	data := encode(t, 0, points)
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, data)
+	}
+	t0, decoded := decode(t, data)
+	if t0 != 0 || !samePoints(decoded, points) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", points, decoded)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	testData := []struct {
+		Name   string
+		T0     int64
+		Points []point
+	}{
+		{
+			Name: "empty",
+			T0:   1700000000,
+		},
+		{
+			Name:   "single",
+			T0:     -7200,
+			Points: []point{{-7200, math.Inf(-1)}},
+		},
+		{
+			Name: "all the delta of deltas",
+			T0:   1700000000,
+			Points: []point{
+				{1700000000, 0}, {1700000060, 0}, {1700000120, 0}, // D: 0
+				{1700000244, 1}, {1700000305, 2}, // D: 64, -63
+				{1700000622, 3}, {1700000684, 4}, // D: 256, -255
+				{1700002794, 5}, {1700002857, 6}, // D: 2048, -2047
+				{1700102857, 7}, {1700102858, 8}, // D: 99937, -99999
+				{3847586505, 9}, {3847586505, -0.0}, // D: 2^31, -(2^31-1)
+			},
+		},
+		{
+			Name: "special values",
+			T0:   0,
+			Points: []point{
+				{0, math.NaN()}, {1, math.Inf(1)}, {2, math.MaxFloat64}, {3, math.SmallestNonzeroFloat64},
+				{4, -math.MaxFloat64}, {5, 0}, {6, math.Copysign(0, -1)}, {7, 1}, {8, 1}, {9, 1.5},
+			},
+		},
+	}
+
+	// a sensor-like series
+	rnd := rand.New(rand.NewSource(1))
+	var sensor []point
+	ts, v := int64(1700000000), 20.0
+	for i := 0; i < 1000; i++ {
+		ts += 60 + int64(rnd.Intn(3)) - 1
+		v += math.Round(rnd.NormFloat64()*10) / 100
+		sensor = append(sensor, point{ts, v})
+	}
+	testData = append(testData, struct {
+		Name   string
+		T0     int64
+		Points []point
+	}{Name: "sensor", T0: 1700000000, Points: sensor})
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			encoded := encode(t, data.T0, data.Points)
+			t0, decoded := decode(t, encoded)
+			if t0 != data.T0 || !samePoints(decoded, data.Points) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Points, decoded)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w := bitstream.NewWriter(&buf)
+	e, err := NewEncoder(w, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	for _, ts := range []int64{999, 1000 + 1<<14 - 1} {
+		err = e.Encode(ts, 0)
+		if !errors.Is(err, ErrTimestampOutOfRange) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTimestampOutOfRange, err)
+		}
+	}
+	e.Encode(1000, 0)
+	e.Encode(1010, 0)
+	for _, ts := range []int64{1009, 1010 + 10 + 1<<31 + 1} {
+		err = e.Encode(ts, 0)
+		if !errors.Is(err, ErrTimestampOutOfRange) {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTimestampOutOfRange, err)
+		}
+	}
+	if w.WrittenBits() != 64+14+64+9+1 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 64+14+64+9+1, w.WrittenBits())
+	}
+	e.Finish()
+	err = e.Encode(1020, 0)
+	if !errors.Is(err, ErrFinished) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrFinished, err)
+	}
+	w.Close()
+
+	// truncated in the middle of a point
+	data := buf.Bytes()[:18]
+	d, err := NewDecoder(bitstream.NewReaderBytes(data, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, _, err = d.Decode()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	_, _, err = d.Decode()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestCompressionRatio(t *testing.T) {
+	var points []point
+	for i := 0; i < 120; i++ {
+		points = append(points, point{int64(i) * 60, 42})
+	}
+	data := encode(t, 0, points)
+
+	// 64 + 14 + 64 bits for the first point, 9 + 1 bits for the second one, 2 bits for each of the others and the end of the block
+	if len(data) > (64+14+64+10+118*2+36+7)/8 {
+		t.Fatalf("too large: %d bytes\n", len(data))
+	}
+}
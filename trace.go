@@ -0,0 +1,137 @@
+package bitstream
+
+// TraceHook is a function which is called for each field read from or written to the bit stream.
+// `name` is the name given to ReadNamed, ReadNBitsNamed, WriteNamed or WriteNBitsNamed, and is empty for the other methods.
+// `bitOffset` is the offset of the first bit of the field and `nBits` is the number of bits consumed or written.
+// `value` is the value returned by the read method, e.g. uint16 for ReadNBitsAsUint16BE, []byte for ReadNBits and nil for Skip.
+// For the write methods, it is the value actually written, i.e. without the bits beyond `nBits`, and nil for AlignByte and Reserve.
+//
+// The hook is called only when the read or write succeeds.
+// Methods built on top of another method, e.g. ReadUint8 or WriteBool, call it only once.
+type TraceHook func(name string, bitOffset uint64, nBits uint, value any)
+
+// GetTraceHook gets configured trace hook.
+func (opt *ReaderOptions) GetTraceHook() TraceHook {
+	if opt == nil {
+		return nil
+	}
+	return opt.TraceHook
+}
+
+func (r *Reader) trace(name string, bitOffset uint64, nBits uint, value any) {
+	r.lastBits = r.BitPosition() - bitOffset
+	hook := r.opt.GetTraceHook()
+	if hook == nil {
+		return
+	}
+	hook(name, bitOffset, nBits, value)
+}
+
+// done records the bits consumed by the method started at `pos` for LastBits, and reports whether the trace hook is set.
+// Callers check it instead of calling trace with a value which would be allocated when converted to an interface.
+func (r *Reader) done(pos uint64) bool {
+	r.lastBits = r.BitPosition() - pos
+	return r.opt.GetTraceHook() != nil
+}
+
+// ReadNamed reads `nBits` bits as a big endian unsigned integer from the bit stream and returns it in uint64 (LSB aligned).
+// `name` is passed to the trace hook so that a decode log can be annotated with the field names.
+// `nBits` must be less than or equal to 64, otherwise returns an error.
+func (r *Reader) ReadNamed(name string, nBits uint8) (uint64, error) {
+	pos := r.BitPosition()
+	v, err := r.readUint(nBits, 64)
+	if err != nil {
+		return v, r.wrapError("ReadNamed "+name, pos, err)
+	}
+	if r.done(pos) {
+		r.trace(name, pos, uint(nBits), v)
+	}
+	return v, nil
+}
+
+// ReadNBitsNamed reads `nBits` bits from the bit stream and returns it as a slice of bytes.
+// `name` is passed to the trace hook so that a decode log can be annotated with the field names.
+// If `opt` is nil, ReaderOptions.ReadOptions of the Reader is used.
+func (r *Reader) ReadNBitsNamed(name string, nBits uint, opt *ReadOptions) ([]byte, error) {
+	pos := r.BitPosition()
+	data, err := r.readNBits(nBits, r.readOptions(opt))
+	if err != nil {
+		return data, r.wrapError("ReadNBitsNamed "+name, pos, err)
+	}
+	r.trace(name, pos, nBits, data)
+	return data, nil
+}
+
+// GetTraceHook gets configured trace hook.
+func (opt *WriterOptions) GetTraceHook() TraceHook {
+	if opt == nil {
+		return nil
+	}
+	return opt.TraceHook
+}
+
+// SetTraceHook sets the trace hook of the Writer, overriding WriterOptions.TraceHook.
+// Pass nil to remove it.
+func (w *Writer) SetTraceHook(hook TraceHook) {
+	w.traceHook = hook
+}
+
+func (w *Writer) trace(name string, bitOffset uint64, nBits uint, value any) {
+	w.lastBits = w.bitPosition() - bitOffset
+	if w.traceHook == nil {
+		return
+	}
+	w.traceHook(name, bitOffset, nBits, value)
+}
+
+// done records the bits written by the method started at `pos` for LastBits, and reports whether the trace hook is set.
+func (w *Writer) done(pos uint64) bool {
+	w.lastBits = w.bitPosition() - pos
+	return w.traceHook != nil
+}
+
+// maskBits returns the LSB `nBits` bits of `v`.
+func maskBits(nBits uint8, v uint64) uint64 {
+	if nBits >= 64 {
+		return v
+	}
+	return v & (1<<nBits - 1)
+}
+
+// WriteNamed writes the LSB `nBits` bits of `val` to the bit stream.
+// `name` is passed to the trace hook so that an encode log can be annotated with the field names.
+// `nBits` must be less than or equal to 64, otherwise returns an error.
+func (w *Writer) WriteNamed(name string, nBits uint8, val uint64) error {
+	pos := w.bitPosition()
+	err := w.writeNamed(nBits, val)
+	if err != nil {
+		return w.wrapError("WriteNamed "+name, pos, err)
+	}
+	if w.done(pos) {
+		w.trace(name, pos, uint(nBits), maskBits(nBits, val))
+	}
+	return nil
+}
+
+func (w *Writer) writeNamed(nBits uint8, val uint64) error {
+	if nBits > 64 {
+		return errTooManyBitsForUint64
+	}
+	err := w.checkRange(nBits, val)
+	if err != nil {
+		return err
+	}
+	return writeBits(w, val, nBits)
+}
+
+// WriteNBitsNamed writes `nBits` bits of `data` to the bit stream.
+// `name` is passed to the trace hook so that an encode log can be annotated with the field names.
+func (w *Writer) WriteNBitsNamed(name string, nBits uint, data []byte) error {
+	pos := w.bitPosition()
+	err := w.writeNBits(nBits, data)
+	if err != nil {
+		return w.wrapError("WriteNBitsNamed "+name, pos, err)
+	}
+	w.trace(name, pos, nBits, data)
+	return nil
+}
@@ -0,0 +1,99 @@
+package bitstream
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type traceEvent struct {
+	Name      string
+	BitOffset uint64
+	NBits     uint
+	Value     any
+}
+
+func TestTraceHook(t *testing.T) {
+	// 0000 0001 0010 0011 0100 0101 0110 0111 1000 1001 1010 1011 1100 1101 1110 1111
+	data := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+
+	var events []traceEvent
+	r := NewReader(bytes.NewReader(data), &ReaderOptions{
+		TraceHook: func(name string, bitOffset uint64, nBits uint, value any) {
+			events = append(events, traceEvent{Name: name, BitOffset: bitOffset, NBits: nBits, Value: value})
+		},
+	})
+
+	r.ReadNamed("version", 4)
+	r.ReadBool()
+	r.ReadNBitsAsUint8(3)
+	r.CountLeadingZeros()
+	r.ReadNBitsAsInt32BE(5)
+	r.Skip(3)
+	r.ReadNBitsNamed("payload", 12, nil)
+	r.ReadUint16BE()
+	r.ReadNBitsAsUint32BE(9)
+	r.ReadNBitsAsUint64BE(9) // fails
+
+	expected := []traceEvent{
+		{Name: "version", BitOffset: 0, NBits: 4, Value: uint64(0x0)},
+		{Name: "", BitOffset: 4, NBits: 1, Value: byte(0)},
+		{Name: "", BitOffset: 5, NBits: 3, Value: uint8(0x1)},
+		{Name: "", BitOffset: 8, NBits: 3, Value: uint64(2)},
+		{Name: "", BitOffset: 11, NBits: 5, Value: int32(3)},
+		{Name: "", BitOffset: 16, NBits: 3, Value: nil},
+		{Name: "payload", BitOffset: 19, NBits: 12, Value: []byte{0x2b, 0x30}},
+		{Name: "", BitOffset: 31, NBits: 16, Value: uint16(0xc4d5)},
+		{Name: "", BitOffset: 47, NBits: 9, Value: uint32(0x1cd)},
+	}
+	if !reflect.DeepEqual(expected, events) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, events)
+	}
+}
+
+func TestTraceHookNotSet(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x01, 0x23}), nil)
+	v, err := r.ReadNamed("version", 12)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0x012 {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x012, v)
+	}
+}
+
+func TestWriterTraceHook(t *testing.T) {
+	var log FieldLog
+	buf := bytes.NewBuffer([]byte{})
+	w := NewWriterWithOptions(buf, &WriterOptions{TraceHook: log.Trace})
+
+	w.WriteNamed("version", 4, 4)
+	w.WriteBool(true)
+	w.WriteNBitsOfUint8(3, 0xff)
+	w.WriteRun(0, 5)
+	w.WriteNBitsOfUint16BE(9, 0x1234)
+	w.WriteNBitsNamed("payload", 12, []byte{0xab, 0xcd})
+	w.AlignByte(0)
+	w.WriteString("a")
+	w.WriteNBitsOfUint32BE(33, 0) // fails
+	w.Finalize()
+
+	expected := FieldLog{
+		{Name: "version", BitOffset: 0, NBits: 4, Value: uint64(4)},
+		{Name: "", BitOffset: 4, NBits: 1, Value: uint8(1)},
+		{Name: "", BitOffset: 5, NBits: 3, Value: uint8(0x07)},
+		{Name: "", BitOffset: 8, NBits: 5, Value: Run{Bit: 0, Length: 5}},
+		{Name: "", BitOffset: 13, NBits: 9, Value: uint16(0x34)},
+		{Name: "payload", BitOffset: 22, NBits: 12, Value: []byte{0xab, 0xcd}},
+		{Name: "", BitOffset: 34, NBits: 6, Value: nil},
+		{Name: "", BitOffset: 40, NBits: 8, Value: "a"},
+	}
+	if !reflect.DeepEqual(expected, log) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, log)
+	}
+
+	// 0100 1111 0000 0000 1101 0010 1010 1111 0000 0000 0110 0001
+	if !bytes.Equal([]byte{0x4f, 0x00, 0xd2, 0xaf, 0x00, 0x61}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0x4f, 0x00, 0xd2, 0xaf, 0x00, 0x61}, buf.Bytes())
+	}
+}
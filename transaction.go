@@ -0,0 +1,82 @@
+package bitstream
+
+// writerState is a snapshot of a Writer taken by Begin.
+type writerState struct {
+	writtenBits  uint64
+	currByte     uint8
+	currBitIndex uint8
+}
+
+// Begin starts a transaction.
+// The bits written after Begin are kept in the Writer until Commit, so they can be discarded with Rollback,
+// e.g. when an encoder finds that a record does not fit in a packet.
+// Transactions can be nested; Commit and Rollback end the innermost one.
+func (w *Writer) Begin() {
+	w.txns = append(w.txns, writerState{
+		writtenBits:  w.writtenBits,
+		currByte:     w.currByte[0],
+		currBitIndex: w.currBitIndex,
+	})
+}
+
+// InTransaction returns true if a transaction is in progress.
+func (w *Writer) InTransaction() bool {
+	return len(w.txns) > 0
+}
+
+// Commit ends the innermost transaction and keeps the bits written in it.
+// The bits are written to the destination as usual once all the transactions are committed.
+func (w *Writer) Commit() error {
+	pos := w.bitPosition()
+	err := w.commit()
+	if err != nil {
+		return w.wrapError("Commit", pos, err)
+	}
+	return nil
+}
+
+func (w *Writer) commit() error {
+	if len(w.txns) == 0 {
+		return ErrNoTransaction
+	}
+	w.txns = w.txns[:len(w.txns)-1]
+
+	if len(w.buf) < w.bufSize {
+		return nil
+	}
+	return w.flushBuf()
+}
+
+// Rollback ends the innermost transaction and discards the bits written in it.
+// The Writer returns to the state at the corresponding Begin, except that the bits written before Begin
+// and patched in the transaction keep the patched value.
+func (w *Writer) Rollback() error {
+	pos := w.bitPosition()
+	err := w.rollback()
+	if err != nil {
+		return w.wrapError("Rollback", pos, err)
+	}
+	return nil
+}
+
+func (w *Writer) rollback() error {
+	if len(w.txns) == 0 {
+		return ErrNoTransaction
+	}
+	s := w.txns[len(w.txns)-1]
+	w.txns = w.txns[:len(w.txns)-1]
+
+	// the complete bytes before the state are kept in buf, or have been written to dst
+	completed := (w.writtenBits - uint64(w.PendingBits())) / 8
+	written := completed - uint64(len(w.buf))
+	w.buf = w.buf[:s.writtenBits/8-written]
+
+	w.writtenBits = s.writtenBits
+	w.currByte[0] = s.currByte
+	w.currBitIndex = s.currBitIndex
+
+	for len(w.reserved) > 0 && w.reserved[len(w.reserved)-1] >= s.writtenBits {
+		w.reserved = w.reserved[:len(w.reserved)-1]
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRollback(t *testing.T) {
+	testData := []struct {
+		Name       string
+		BufferSize uint
+		Before     uint8 // number of '1' bits written before Begin
+		InTxn      uint  // number of bytes of 0xaa written in the transaction
+		Expected   []byte
+	}{
+		{
+			// 1111 1111 | 1111 1111 + (rolled back) + 000
+			Name:       "pattern 1",
+			BufferSize: 1,
+			Before:     16,
+			InTxn:      3,
+			Expected:   []byte{0xff, 0xff, 0x00},
+		},
+		{
+			// 1111 1111 | 111 + (rolled back) + 0 0000
+			Name:       "pattern 2",
+			BufferSize: 1,
+			Before:     11,
+			InTxn:      1,
+			Expected:   []byte{0xff, 0xe0},
+		},
+		{
+			// 111 + (rolled back) + 0 0000
+			Name:       "pattern 3",
+			BufferSize: 0,
+			Before:     3,
+			InTxn:      5000,
+			Expected:   []byte{0xe0},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			bw := NewWriterWithOptions(buf, &WriterOptions{BufferSize: data.BufferSize})
+			bw.WriteRun(1, uint64(data.Before))
+
+			bw.Begin()
+			bw.WriteBytes(bytes.Repeat([]byte{0xaa}, int(data.InTxn)))
+			bw.WriteNBitsOfUint8(3, 0x07)
+			err := bw.Rollback()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if uint64(data.Before) != bw.WrittenBits() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Before, bw.WrittenBits())
+			}
+
+			bw.WriteNBitsOfUint8(3, 0x00)
+			err = bw.Finalize()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !bytes.Equal(data.Expected, buf.Bytes()) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestRollbackSmallBuffer(t *testing.T) {
+	for _, bufferSize := range []uint{1, 4} {
+		for nBits := uint8(8); nBits <= 32; nBits++ {
+			buf := bytes.NewBuffer([]byte{})
+			bw := NewWriterWithOptions(buf, &WriterOptions{BufferSize: bufferSize})
+			bw.WriteUint8(0x01)
+			bw.WriteNBitsOfUint8(5, 0x1f)
+
+			bw.Begin()
+			err := bw.WriteNBitsOfUint32BE(nBits, 0xffffffff)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			err = bw.Rollback()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if bw.WrittenBits() != 13 {
+				t.Fatalf("\nbuffer size: %d, nBits: %d\nExpected: %+v\nActual:   %+v\n", bufferSize, nBits, 13, bw.WrittenBits())
+			}
+
+			bw.WriteNBitsOfUint8(3, 0x00)
+			err = bw.Finalize()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			// 0000 0001 | 1111 1000
+			if !bytes.Equal([]byte{0x01, 0xf8}, buf.Bytes()) {
+				t.Fatalf("\nbuffer size: %d, nBits: %d\nExpected: %+v\nActual:   %+v\n", bufferSize, nBits, []byte{0x01, 0xf8}, buf.Bytes())
+			}
+		}
+	}
+}
+
+func TestCommit(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	bw := NewWriter(buf)
+
+	bw.WriteNBitsOfUint8(4, 0x0f)
+	bw.Begin()
+	bw.WriteUint16BE(0x1234)
+	bw.Begin()
+	bw.WriteUint8(0x56)
+	bw.Rollback()
+
+	// nothing after Begin is written to the destination
+	if !bytes.Equal([]byte{}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{}, buf.Bytes())
+	}
+
+	err := bw.Commit()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if bw.InTransaction() {
+		t.Fatalf("transaction should be ended\n")
+	}
+	// 1111 0001 | 0010 0011 | 0100 xxxx
+	if !bytes.Equal([]byte{0xf1, 0x23}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xf1, 0x23}, buf.Bytes())
+	}
+
+	bw.Finalize()
+	if !bytes.Equal([]byte{0xf1, 0x23, 0x40}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xf1, 0x23, 0x40}, buf.Bytes())
+	}
+}
+
+func TestRollbackKeepsPatch(t *testing.T) {
+	for _, bufferSize := range []uint{1, 0} {
+		buf := bytes.NewBuffer([]byte{})
+		bw := NewWriterWithOptions(buf, &WriterOptions{BufferSize: bufferSize})
+
+		bw.WriteBit(1)
+		r1, _ := bw.Reserve(10) // ends in the complete byte
+		r2, _ := bw.Reserve(3)  // ends in the partial byte
+		bw.Begin()
+		bw.WriteNBitsOfUint8(4, 0x0f)
+		bw.Patch(r1, 0x2aa)
+		bw.Patch(r2, 0x05)
+		err := bw.Rollback()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+
+		bw.WriteNBitsOfUint8(2, 0x00)
+		err = bw.Finalize()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		// 1 101 0101 | 010 101 00
+		expected := []byte{0xd5, 0x54}
+		if !bytes.Equal(expected, buf.Bytes()) {
+			t.Fatalf("\nbuffer size: %d\nExpected: %+v\nActual:   %+v\n", bufferSize, expected, buf.Bytes())
+		}
+	}
+}
+
+func TestTransactionError(t *testing.T) {
+	bw := NewWriter(&bytes.Buffer{})
+
+	err := bw.Commit()
+	if !errors.Is(err, ErrNoTransaction) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNoTransaction, err)
+	}
+	err = bw.Rollback()
+	if !errors.Is(err, ErrNoTransaction) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNoTransaction, err)
+	}
+
+	bw.Begin()
+	bw.Reserve(8)
+	err = bw.Finalize()
+	if !errors.Is(err, ErrInTransaction) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInTransaction, err)
+	}
+
+	// the reservation is discarded with the transaction
+	bw.Rollback()
+	err = bw.Finalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+}
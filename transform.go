@@ -0,0 +1,366 @@
+package bitstream
+
+import (
+	"errors"
+	"io"
+	"math/bits"
+
+	"github.com/bearmini/bitstream-go/crc"
+)
+
+// BitTransform is a stage of a transform pipeline stacked onto a Reader or a Writer by With,
+// e.g. bit stuffing, scrambling or emulation prevention. It converts a bit stream bit by bit and may insert or drop bits.
+//
+// A BitTransform holds the state of the stream it converts, so a new one is needed for each pipeline.
+type BitTransform interface {
+	// Transform takes the next input bit (0 or 1) and appends the output bits, if any, to `dst`.
+	Transform(bit uint8, dst []uint8) []uint8
+
+	// Flush appends the bits held by the transform, if any, to `dst` at the end of the stream.
+	Flush(dst []uint8) []uint8
+}
+
+// pipeline runs the bits through the transforms in order.
+type pipeline struct {
+	ts  []BitTransform
+	tmp [2][]uint8
+}
+
+// run runs `in` through the transforms from the `from`-th one, and appends the output to `out`.
+func (p *pipeline) run(from int, in []uint8, out []uint8) []uint8 {
+	cur := in
+	for i := from; i < len(p.ts); i++ {
+		next := p.tmp[i%2][:0]
+		for _, b := range cur {
+			next = p.ts[i].Transform(b, next)
+		}
+		p.tmp[i%2] = next
+		cur = next
+	}
+	return append(out, cur...)
+}
+
+// flush flushes the transforms in order, running the bits flushed by each of them through the rest.
+func (p *pipeline) flush(out []uint8) []uint8 {
+	for i, t := range p.ts {
+		out = p.run(i+1, t.Flush(nil), out)
+	}
+	return out
+}
+
+// With returns a new Reader which reads the bits of `r` converted by `transforms`, e.g.
+//
+//	NewReader(src, nil).With(NewDestuffer(StuffHDLC), NewDescrambler(poly))
+//
+// The bits read from `r` go through the transforms in the order given, so the Reader has the whole read API
+// on the converted bit stream. The converted bits are packed into bytes, and the last byte is padded with '0' bits.
+// The returned Reader inherits the EOF mode, PlainErrors, Limits and ReadOptions of `r`; reading from `r` directly while the returned
+// Reader is in use makes their bit streams inconsistent.
+func (r *Reader) With(transforms ...BitTransform) *Reader {
+	return NewReader(&transformReader{r: r, p: pipeline{ts: transforms}}, &ReaderOptions{
+		EOFMode:     r.opt.GetEOFMode(),
+		PlainErrors: r.opt.GetPlainErrors(),
+		Limits:      r.opt.GetLimits(),
+		ReadOptions: r.opt.GetReadOptions(),
+	})
+}
+
+// transformReader is an io.Reader which reads the bits from a Reader and converts them.
+type transformReader struct {
+	r    *Reader
+	p    pipeline
+	bits []uint8 // the converted bits which have not been returned yet
+	in   []uint8
+	done bool
+}
+
+func (tr *transformReader) Read(p []byte) (int, error) {
+	for !tr.done && len(tr.bits) < len(p)*8 {
+		err := tr.pull()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return 0, err
+			}
+			tr.bits = tr.p.flush(tr.bits)
+			tr.done = true
+		}
+	}
+
+	n, k := 0, 0
+	for ; n < len(p) && k < len(tr.bits); n++ {
+		b := uint8(0)
+		for i := 0; i < 8; i++ {
+			b <<= 1
+			if k < len(tr.bits) {
+				b |= tr.bits[k]
+				k++
+			}
+		}
+		p[n] = b
+	}
+	tr.bits = tr.bits[:copy(tr.bits, tr.bits[k:])]
+	if n == 0 && tr.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// pull reads a byte (or the bits left at the end of the stream) from the Reader and converts them.
+func (tr *transformReader) pull() error {
+	tr.in = tr.in[:0]
+	if v, ok := tr.r.TryReadNBitsAsUint8(8); ok {
+		for i := 7; i >= 0; i-- {
+			tr.in = append(tr.in, v>>i&0x01)
+		}
+	} else {
+		b, err := tr.r.ReadBit()
+		if err != nil {
+			return err
+		}
+		tr.in = append(tr.in, b)
+	}
+	tr.bits = tr.p.run(0, tr.in, tr.bits)
+	return nil
+}
+
+// With returns a new Writer which converts the bits by `transforms` and writes them to `w`, e.g.
+//
+//	w.With(NewScrambler(poly), NewStuffer(StuffHDLC))
+//
+// The bits written to the returned Writer go through the transforms in the order given, so the pipeline which undoes it
+// is built by Reader.With with the inverse transforms in the reverse order. Closing the returned Writer pads the last byte
+// with '0' bits (the padding bits are converted too), flushes the transforms and writes their bits to `w`,
+// but it does not close `w`.
+func (w *Writer) With(transforms ...BitTransform) *Writer {
+	return NewWriter(&transformWriter{w: w, p: pipeline{ts: transforms}})
+}
+
+// transformWriter is an io.Writer which converts the bits of the bytes written and writes them to a Writer.
+type transformWriter struct {
+	w    *Writer
+	p    pipeline
+	in   []uint8
+	bits []uint8
+}
+
+func (tw *transformWriter) Write(p []byte) (int, error) {
+	for i, b := range p {
+		tw.in = tw.in[:0]
+		for j := 7; j >= 0; j-- {
+			tw.in = append(tw.in, b>>j&0x01)
+		}
+		tw.bits = tw.p.run(0, tw.in, tw.bits[:0])
+		err := tw.emit()
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes the transforms. It does not close the underlying Writer.
+func (tw *transformWriter) Close() error {
+	tw.bits = tw.p.flush(tw.bits[:0])
+	return tw.emit()
+}
+
+func (tw *transformWriter) emit() error {
+	for i := 0; i < len(tw.bits); i += 64 {
+		chunk := tw.bits[i:min(i+64, len(tw.bits))]
+		v := uint64(0)
+		for _, b := range chunk {
+			v = v<<1 | uint64(b)
+		}
+		err := tw.w.writeUint(uint8(len(chunk)), 64, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StuffRule is a rule of bit stuffing: after `Run` consecutive `Bit` bits (or `Run` consecutive identical bits
+// of either value if `Both` is true), the complement bit is inserted. The inserted bit counts toward the next run.
+type StuffRule struct {
+	Run  int
+	Bit  uint8
+	Both bool
+}
+
+// Well-known bit stuffing rules.
+var (
+	StuffHDLC = StuffRule{Run: 5, Bit: 1}     // HDLC, AX.25: a '0' after five '1's
+	StuffUSB  = StuffRule{Run: 6, Bit: 1}     // USB (on the bits before NRZI): a '0' after six '1's
+	StuffCAN  = StuffRule{Run: 5, Both: true} // CAN: the complement after five identical bits
+)
+
+// runTracker tracks the current run of a StuffRule.
+type runTracker struct {
+	rule StuffRule
+	last uint8
+	run  int
+}
+
+// track counts `bit` and returns true if it completes a run after which a bit is stuffed.
+func (rt *runTracker) track(bit uint8) bool {
+	if rt.run > 0 && bit == rt.last {
+		rt.run++
+	} else {
+		rt.last, rt.run = bit, 1
+	}
+	return rt.run == rt.rule.Run && (rt.rule.Both || bit == rt.rule.Bit)
+}
+
+type stuffer struct {
+	runTracker
+}
+
+// NewStuffer returns a BitTransform which inserts the stuffed bits by `rule`.
+func NewStuffer(rule StuffRule) BitTransform {
+	return &stuffer{runTracker{rule: rule}}
+}
+
+func (s *stuffer) Transform(bit uint8, dst []uint8) []uint8 {
+	dst = append(dst, bit)
+	if s.track(bit) {
+		dst = append(dst, bit^1)
+		s.track(bit ^ 1)
+	}
+	return dst
+}
+
+func (s *stuffer) Flush(dst []uint8) []uint8 { return dst }
+
+type destuffer struct {
+	runTracker
+	drop bool
+}
+
+// NewDestuffer returns a BitTransform which removes the stuffed bits by `rule`.
+// The bit after a run is dropped whatever its value is, so a violation of the rule, e.g. an HDLC flag, is not detected.
+func NewDestuffer(rule StuffRule) BitTransform {
+	return &destuffer{runTracker: runTracker{rule: rule}}
+}
+
+func (d *destuffer) Transform(bit uint8, dst []uint8) []uint8 {
+	if d.drop {
+		d.drop = false
+		d.track(bit)
+		return dst
+	}
+	d.drop = d.track(bit)
+	return append(dst, bit)
+}
+
+func (d *destuffer) Flush(dst []uint8) []uint8 { return dst }
+
+type scrambler struct {
+	taps    uint64
+	state   uint64
+	inverse bool
+}
+
+// NewScrambler returns a BitTransform of the self-synchronizing (multiplicative) scrambler with the polynomial `poly`,
+// where bit k-1 of `poly` is the coefficient of x^k, e.g. 1<<57 | 1<<38 for x^58 + x^39 + 1 of 64b/66b.
+// Each output bit is the input bit XOR the output bits at the taps. The initial state is all zeros.
+func NewScrambler(poly uint64) BitTransform {
+	return &scrambler{taps: poly}
+}
+
+// NewDescrambler returns a BitTransform which undoes NewScrambler with the same `poly`.
+// As the state is made of the input bits, it synchronizes with the scrambler after the degree of `poly` bits
+// whatever the initial states are.
+func NewDescrambler(poly uint64) BitTransform {
+	return &scrambler{taps: poly, inverse: true}
+}
+
+func (s *scrambler) Transform(bit uint8, dst []uint8) []uint8 {
+	out := bit ^ uint8(bits.OnesCount64(s.state&s.taps)&0x01)
+	if s.inverse {
+		s.state = s.state<<1 | uint64(bit)
+	} else {
+		s.state = s.state<<1 | uint64(out)
+	}
+	return append(dst, out)
+}
+
+func (s *scrambler) Flush(dst []uint8) []uint8 { return dst }
+
+type emulationPrevention struct {
+	remove bool
+	zeros  int // number of consecutive 0x00 bytes before the current one
+	acc    uint8
+	nAcc   int
+}
+
+// NewEmulationPrevention returns a BitTransform which inserts the emulation prevention byte 0x03 of H.264/H.265 NAL units,
+// i.e. 0x03 is inserted after two 0x00 bytes if the next byte is 0x00 - 0x03. The bits are converted byte by byte,
+// so the bit stream must be byte aligned where the transform starts; the bits of an incomplete last byte are flushed as they are.
+func NewEmulationPrevention() BitTransform {
+	return &emulationPrevention{}
+}
+
+// NewEmulationPreventionRemover returns a BitTransform which removes the emulation prevention bytes
+// inserted by NewEmulationPrevention, i.e. a 0x03 byte after two 0x00 bytes.
+func NewEmulationPreventionRemover() BitTransform {
+	return &emulationPrevention{remove: true}
+}
+
+func (e *emulationPrevention) Transform(bit uint8, dst []uint8) []uint8 {
+	e.acc = e.acc<<1 | bit
+	e.nAcc++
+	if e.nAcc < 8 {
+		return dst
+	}
+	b := e.acc
+	e.acc, e.nAcc = 0, 0
+
+	if e.zeros >= 2 {
+		switch {
+		case e.remove && b == 0x03:
+			e.zeros = 0
+			return dst
+		case !e.remove && b <= 0x03:
+			dst = appendByteBits(dst, 0x03, 8)
+			e.zeros = 0
+		}
+	}
+	if b == 0 {
+		e.zeros++
+	} else {
+		e.zeros = 0
+	}
+	return appendByteBits(dst, b, 8)
+}
+
+func (e *emulationPrevention) Flush(dst []uint8) []uint8 {
+	dst = appendByteBits(dst, e.acc, e.nAcc)
+	e.acc, e.nAcc = 0, 0
+	return dst
+}
+
+// appendByteBits appends the LSB `nBits` bits of `b` to `dst`, MSB first.
+func appendByteBits(dst []uint8, b uint8, nBits int) []uint8 {
+	for i := nBits - 1; i >= 0; i-- {
+		dst = append(dst, b>>i&0x01)
+	}
+	return dst
+}
+
+type crcTap struct {
+	c *crc.CRC
+}
+
+// NewCRCTap returns a BitTransform which passes the bits through as they are and feeds them to `c`,
+// e.g. to compute the CRC of the bits before scrambling. Note that the Reader returned by Reader.With pulls the bits
+// through the pipeline ahead of the reads to fill its buffer, so use CRCReader to cover exactly the bits read.
+func NewCRCTap(c *crc.CRC) BitTransform {
+	return &crcTap{c: c}
+}
+
+func (t *crcTap) Transform(bit uint8, dst []uint8) []uint8 {
+	t.c.UpdateBit(bit)
+	return append(dst, bit)
+}
+
+func (t *crcTap) Flush(dst []uint8) []uint8 { return dst }
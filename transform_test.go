@@ -0,0 +1,183 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	mathrand "math/rand"
+	"testing"
+
+	"github.com/bearmini/bitstream-go/crc"
+)
+
+// writeTransformed writes `data` through the transforms and returns the bytes written to the underlying Writer.
+func writeTransformed(t *testing.T, data []byte, transforms ...BitTransform) ([]byte, uint64) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	tw := w.With(transforms...)
+	err := tw.WriteBytes(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = tw.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	nBits := w.WrittenBits()
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	return buf.Bytes(), nBits
+}
+
+func TestTransformStuffing(t *testing.T) {
+	testData := []struct {
+		Name         string
+		Rule         StuffRule
+		Data         []byte
+		Expected     []byte
+		ExpectedBits uint64
+	}{
+		{
+			Name:         "HDLC",
+			Rule:         StuffHDLC,
+			Data:         []byte{0xff, 0xff},
+			Expected:     []byte{0xfb, 0xef, 0xa0}, // 11111 0 11111 0 11111 0 1
+			ExpectedBits: 19,
+		},
+		{
+			Name:         "HDLC flag",
+			Rule:         StuffHDLC,
+			Data:         []byte{0x7e},
+			Expected:     []byte{0x7d, 0x00}, // 0 11111 0 1 0
+			ExpectedBits: 9,
+		},
+		{
+			Name:         "USB",
+			Rule:         StuffUSB,
+			Data:         []byte{0xff},
+			Expected:     []byte{0xfd, 0x80}, // 111111 0 11
+			ExpectedBits: 9,
+		},
+		{
+			Name:         "CAN",
+			Rule:         StuffCAN,
+			Data:         []byte{0x00, 0x0f},
+			Expected:     []byte{0x04, 0x13, 0xc0}, // 00000 1 00000 1 00 1111
+			ExpectedBits: 18,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			actual, nBits := writeTransformed(t, data.Data, NewStuffer(data.Rule))
+			if !bytes.Equal(actual, data.Expected) || nBits != data.ExpectedBits {
+				t.Fatalf("\nExpected: %#v (%d bits)\nActual:   %#v (%d bits)\n", data.Expected, data.ExpectedBits, actual, nBits)
+			}
+
+			r := NewReaderBytes(actual, nil).With(NewDestuffer(data.Rule))
+			p, err := r.ReadBytes(uint(len(data.Data)))
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !bytes.Equal(p, data.Data) {
+				t.Fatalf("\nExpected: %#v\nActual:   %#v\n", data.Data, p)
+			}
+		})
+	}
+}
+
+func TestTransformScrambler(t *testing.T) {
+	const poly = 1<<57 | 1<<38 // x^58 + x^39 + 1
+	data := make([]byte, 1000)
+	mathrand.New(mathrand.NewSource(1)).Read(data[500:]) // the first half is all zeros
+
+	scrambled, _ := writeTransformed(t, data, NewScrambler(poly))
+	if bytes.Equal(scrambled[:len(data)], data) {
+		t.Fatalf("not scrambled\n")
+	}
+
+	r := NewReaderBytes(scrambled, nil).With(NewDescrambler(poly))
+	p, err := r.ReadBytes(uint(len(data)))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !bytes.Equal(p, data) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", data, p)
+	}
+
+	// the descrambler synchronizes itself after 58 bits even if it starts in the middle of the stream
+	r = NewReaderBytes(scrambled[100:], nil).With(NewDescrambler(poly))
+	p, err = r.ReadBytes(uint(len(data) - 100))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !bytes.Equal(p[8:], data[108:]) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", data[108:], p[8:])
+	}
+}
+
+func TestTransformEmulationPrevention(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03}
+	expected := []byte{0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00, 0x03}
+
+	actual, _ := writeTransformed(t, data, NewEmulationPrevention())
+	if !bytes.Equal(actual, expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, actual)
+	}
+
+	r := NewReaderBytes(actual, nil).With(NewEmulationPreventionRemover())
+	p, _, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !bytes.Equal(p, data) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", data, p)
+	}
+}
+
+func TestTransformPipeline(t *testing.T) {
+	const poly = 1<<6 | 1<<5 // x^7 + x^6 + 1
+	data := make([]byte, 300)
+	mathrand.New(mathrand.NewSource(2)).Read(data)
+
+	// compute the CRC of the bits written, scramble, then stuff; destuff, then descramble
+	c, err := crc.New(crc.CRC16IBM3740)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	encoded, _ := writeTransformed(t, data, NewCRCTap(c), NewScrambler(poly), NewStuffer(StuffHDLC))
+	expected, _ := crc.Checksum(crc.CRC16IBM3740, data, uint64(len(data))*8)
+	if c.Sum64() != expected {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", expected, c.Sum64())
+	}
+
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 2, Prefetch: true}} {
+		r := NewReader(bytes.NewReader(encoded), opt).With(NewDestuffer(StuffHDLC), NewDescrambler(poly))
+		p, err := r.ReadBytes(uint(len(data)))
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if !bytes.Equal(p, data) {
+			t.Fatalf("\nExpected: %#v\nActual:   %#v\n", data, p)
+		}
+	}
+}
+
+func TestTransformReaderEOF(t *testing.T) {
+	r := NewReaderBytes([]byte{0xff}, nil).With(NewDestuffer(StuffHDLC))
+	v, err := r.ReadNBitsAsUint8(7)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0x7f {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x7f, v)
+	}
+	// the 7 bits are padded to a byte
+	_, err = r.ReadNBitsAsUint8(2)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrUnexpectedEOF, err)
+	}
+}
@@ -0,0 +1,94 @@
+package bitstream
+
+// tryRead reads `nBits` (<= 64) bits if the bit stream has them, without constructing an error.
+// Otherwise it returns false and the position is not advanced.
+func (r *Reader) tryRead(nBits uint8) (uint64, bool) {
+	if r.refill(uint(nBits)) != nil {
+		return 0, false
+	}
+	return r.ReadBits64(uint(nBits)), true
+}
+
+// TryReadBit reads a single bit from the bit stream like ReadBit, but reports the end of the stream with `ok` instead of an error,
+// for the loops which read until the stream ends:
+//
+//	for {
+//		bit, ok := r.TryReadBit()
+//		if !ok {
+//			break
+//		}
+//		...
+//	}
+//
+// If `ok` is false, no bits are consumed. It is also false when the source returns an error or the Reader is closed;
+// the next call of a read method which returns an error tells why.
+func (r *Reader) TryReadBit() (byte, bool) {
+	pos := r.BitPosition()
+	v, ok := r.tryRead(1)
+	if ok {
+		r.trace("", pos, 1, byte(v))
+	}
+	return byte(v), ok
+}
+
+// TryReadBool is the same as TryReadBit except that the bit is returned as a bool.
+func (r *Reader) TryReadBool() (bool, bool) {
+	b, ok := r.TryReadBit()
+	return b != 0, ok
+}
+
+// TryReadNBitsAsUint8 is the same as ReadNBitsAsUint8 except that it returns false instead of an error, see TryReadBit.
+// It also returns false if `nBits` > 8.
+func (r *Reader) TryReadNBitsAsUint8(nBits uint8) (uint8, bool) {
+	if nBits > 8 {
+		return 0, false
+	}
+	pos := r.BitPosition()
+	v, ok := r.tryRead(nBits)
+	if ok {
+		r.trace("", pos, uint(nBits), uint8(v))
+	}
+	return uint8(v), ok
+}
+
+// TryReadNBitsAsUint16BE is the same as ReadNBitsAsUint16BE except that it returns false instead of an error, see TryReadBit.
+// It also returns false if `nBits` > 16.
+func (r *Reader) TryReadNBitsAsUint16BE(nBits uint8) (uint16, bool) {
+	if nBits > 16 {
+		return 0, false
+	}
+	pos := r.BitPosition()
+	v, ok := r.tryRead(nBits)
+	if ok && r.done(pos) {
+		r.trace("", pos, uint(nBits), uint16(v))
+	}
+	return uint16(v), ok
+}
+
+// TryReadNBitsAsUint32BE is the same as ReadNBitsAsUint32BE except that it returns false instead of an error, see TryReadBit.
+// It also returns false if `nBits` > 32.
+func (r *Reader) TryReadNBitsAsUint32BE(nBits uint8) (uint32, bool) {
+	if nBits > 32 {
+		return 0, false
+	}
+	pos := r.BitPosition()
+	v, ok := r.tryRead(nBits)
+	if ok && r.done(pos) {
+		r.trace("", pos, uint(nBits), uint32(v))
+	}
+	return uint32(v), ok
+}
+
+// TryReadNBitsAsUint64BE is the same as ReadNBitsAsUint64BE except that it returns false instead of an error, see TryReadBit.
+// It also returns false if `nBits` > 64.
+func (r *Reader) TryReadNBitsAsUint64BE(nBits uint8) (uint64, bool) {
+	if nBits > 64 {
+		return 0, false
+	}
+	pos := r.BitPosition()
+	v, ok := r.tryRead(nBits)
+	if ok && r.done(pos) {
+		r.trace("", pos, uint(nBits), v)
+	}
+	return v, ok
+}
@@ -0,0 +1,102 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestTryReadBit(t *testing.T) {
+	src := []byte{0xa5, 0x3c}
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}, {BufferSize: 1, Prefetch: true}} {
+		r := NewReader(bytes.NewReader(src), opt)
+		v := uint16(0)
+		n := 0
+		for {
+			bit, ok := r.TryReadBit()
+			if !ok {
+				break
+			}
+			v = v<<1 | uint16(bit)
+			n++
+		}
+		r.Close()
+		if n != 16 || v != 0xa53c {
+			t.Fatalf("\nExpected: %+v, %#x\nActual:   %+v, %#x\n", 16, 0xa53c, n, v)
+		}
+	}
+}
+
+func TestTryReadNBits(t *testing.T) {
+	// 1010 0101 0011 1100 1111
+	r := NewReader(bytes.NewReader([]byte{0xa5, 0x3c, 0xf0}), &ReaderOptions{BufferSize: 1})
+
+	v8, ok := r.TryReadNBitsAsUint8(4)
+	if !ok || v8 != 0x0a {
+		t.Fatalf("\nExpected: %#x, true\nActual:   %#x, %t\n", 0x0a, v8, ok)
+	}
+	v16, ok := r.TryReadNBitsAsUint16BE(12)
+	if !ok || v16 != 0x53c {
+		t.Fatalf("\nExpected: %#x, true\nActual:   %#x, %t\n", 0x53c, v16, ok)
+	}
+
+	// only 8 bits are left; nothing is consumed
+	v32, ok := r.TryReadNBitsAsUint32BE(9)
+	if ok {
+		t.Fatalf("\nExpected: false\nActual:   %#x, %t\n", v32, ok)
+	}
+	if r.BitPosition() != 16 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 16, r.BitPosition())
+	}
+
+	_, ok = r.TryReadNBitsAsUint8(9)
+	if ok {
+		t.Fatalf("\nExpected: false\nActual:   %t\n", ok)
+	}
+
+	v64, ok := r.TryReadNBitsAsUint64BE(8)
+	if !ok || v64 != 0xf0 {
+		t.Fatalf("\nExpected: %#x, true\nActual:   %#x, %t\n", 0xf0, v64, ok)
+	}
+	_, ok = r.TryReadBool()
+	if ok {
+		t.Fatalf("\nExpected: false\nActual:   %t\n", ok)
+	}
+}
+
+func TestTryReadSourceError(t *testing.T) {
+	errSrc := errors.New("source error")
+	r := NewReader(io.MultiReader(bytes.NewReader([]byte{0xff}), iotest.ErrReader(errSrc)), &ReaderOptions{BufferSize: 1})
+
+	v, ok := r.TryReadNBitsAsUint16BE(8)
+	if !ok || v != 0xff {
+		t.Fatalf("\nExpected: %#x, true\nActual:   %#x, %t\n", 0xff, v, ok)
+	}
+	_, ok = r.TryReadBit()
+	if ok {
+		t.Fatalf("\nExpected: false\nActual:   %t\n", ok)
+	}
+
+	// the error is reported by the checked read methods
+	_, err := r.ReadBit()
+	if !errors.Is(err, errSrc) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", errSrc, err)
+	}
+}
+
+func TestTryReadTrace(t *testing.T) {
+	var fields []Field
+	opt := &ReaderOptions{TraceHook: func(name string, bitOffset uint64, nBits uint, value any) {
+		fields = append(fields, Field{Name: name, BitOffset: bitOffset, NBits: nBits})
+	}}
+	r := NewReader(bytes.NewReader([]byte{0xa5}), opt)
+	r.TryReadBit()
+	r.TryReadNBitsAsUint32BE(7)
+	r.TryReadBit()
+
+	if len(fields) != 2 || fields[1].BitOffset != 1 || fields[1].NBits != 7 {
+		t.Fatalf("unexpected fields: %+v\n", fields)
+	}
+}
@@ -0,0 +1,41 @@
+package bitstream
+
+// UnreadBit unreads the last bit read, so that the next read returns it again. See UnreadNBits.
+func (r *Reader) UnreadBit() error {
+	return r.UnreadNBits(1)
+}
+
+// UnreadNBits moves the read position back by `nBits` bits, like bufio.Reader.UnreadByte, so that a tokenizer which has
+// read a bit or a code too many can push it back.
+// For a Reader created by NewReaderBytes or NewReaderMmap, any bits up to the beginning of the bit stream can be unread.
+// Otherwise the bits must still be in the buffer: unreading is bounded by the bits read since the last refill, and Peek, Refill and
+// the Try read methods may discard the bits read before them when they refill. Otherwise it returns ErrInvalidUnread
+// and the position is not changed. The trace hook is not notified of unreading.
+func (r *Reader) UnreadNBits(nBits uint8) error {
+	pos := r.BitPosition()
+	return r.wrapError("UnreadNBits", pos, r.unreadNBits(uint(nBits)))
+}
+
+func (r *Reader) unreadNBits(nBits uint) error {
+	if r.inMemory && !r.closed {
+		// the whole bit stream is in the buffer, and the position may be beyond its end after SeekBit
+		pos := r.BitPosition()
+		if uint64(nBits) > pos {
+			return ErrInvalidUnread
+		}
+		return r.seekBit(pos - uint64(nBits))
+	}
+
+	// bits read from the current buffer
+	back := r.currByteIndex*8 + uint(7-r.currBitIndex)
+	if nBits > back {
+		return ErrInvalidUnread
+	}
+
+	back -= nBits
+	byteIndex := back / 8
+	r.consumedBytes -= r.currByteIndex - byteIndex
+	r.currByteIndex = byteIndex
+	r.currBitIndex = 7 - uint8(back%8)
+	return nil
+}
@@ -0,0 +1,92 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestUnreadNBits(t *testing.T) {
+	// 1010 0101 0011 1100
+	src := []byte{0xa5, 0x3c}
+	for _, opt := range []*ReaderOptions{nil, {BufferSize: 1}} {
+		r := NewReader(bytes.NewReader(src), opt)
+		v, err := r.ReadNBitsAsUint8(5)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		err = r.UnreadNBits(3)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if r.BitPosition() != 2 {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 2, r.BitPosition())
+		}
+		v, err = r.ReadNBitsAsUint8(6)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if v != 0x25 { // 10 0101
+			t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x25, v)
+		}
+		err = r.UnreadBit()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		b, err := r.ReadBit()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		if b != 1 || r.BitPosition() != 8 {
+			t.Fatalf("\nExpected: 1, 8\nActual:   %+v, %+v\n", b, r.BitPosition())
+		}
+	}
+}
+
+func TestUnreadNBitsAcrossRefill(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0xa5, 0x3c}), &ReaderOptions{BufferSize: 1})
+	_, err := r.ReadNBitsAsUint16BE(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	// only the 2 bits of the second byte are in the buffer
+	err = r.UnreadNBits(3)
+	if !errors.Is(err, ErrInvalidUnread) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidUnread, err)
+	}
+	if r.BitPosition() != 10 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 10, r.BitPosition())
+	}
+	err = r.UnreadNBits(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if r.BitPosition() != 8 || r.ConsumedBytes() != 1 {
+		t.Fatalf("\nExpected: 8, 1\nActual:   %+v, %+v\n", r.BitPosition(), r.ConsumedBytes())
+	}
+}
+
+func TestUnreadNBitsBytes(t *testing.T) {
+	r := NewReaderBytes([]byte{0xa5, 0x3c}, nil)
+	err := r.SeekBit(24) // beyond the end
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = r.UnreadNBits(16)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	v, err := r.ReadNBitsAsUint8(8)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if v != 0x3c {
+		t.Fatalf("\nExpected: %#x\nActual:   %#x\n", 0x3c, v)
+	}
+
+	err = r.UnreadNBits(17)
+	if !errors.Is(err, ErrInvalidUnread) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInvalidUnread, err)
+	}
+}
@@ -1,42 +1,269 @@
 package bitstream
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"log/slog"
+)
+
+const (
+	DefaultWriterBufferSize = 4096
 
-	"github.com/pkg/errors"
+	runChunkSize = 4096
 )
 
 // Writer is a bit stream writer.
 // It does not have io.Writer interface
+//
+// A Writer is not safe for concurrent use by multiple goroutines.
+// Use SyncWriter to share a Writer among goroutines.
 type Writer struct {
 	dst          io.Writer
+	buf          []byte // complete bytes which have not been written to dst yet
+	bufSize      int    // number of bytes in buf to trigger writing to dst
 	currByte     []uint8
-	currBitIndex uint8 // MSB: 7, LSB: 0
-	writtenBits  uint
+	currBitIndex uint8  // MSB: 7, LSB: 0
+	writtenBits  uint64 // cumulative number of bits written to the Writer, including the ones not written to dst yet
+	stats        WriterStats
+	hooks        *WriterHooks
+	padding      PaddingPolicy
+	pattern      PaddingPattern // pattern of the padding with PadPattern
+	strict       bool           // reject values which do not fit in nBits
+	traceHook    TraceHook
+	reserved     []uint64       // bit offsets of the reservations which have not been patched yet
+	seeker       io.WriteSeeker // dst, if it is seekable
+	txns         []writerState  // states at the beginning of the transactions in progress
+	plainErrors  bool           // return the errors without wrapping them
+	order        BitOrder       // bit order of WriteNBitsOfUint64
+	numbering    BitNumbering   // bit numbering of the positions in the errors
+	logger       *slog.Logger
+	lastBits     uint64 // number of bits written by the last write method, see LastBits
+}
+
+// PaddingPolicy specifies how a Writer fills the last byte when the bit stream is closed in the middle of a byte.
+type PaddingPolicy int
+
+const (
+	// PadZeros pads the last byte with '0' bits. This is the default.
+	PadZeros PaddingPolicy = iota
+
+	// PadOnes pads the last byte with '1' bits.
+	PadOnes
+
+	// PadNone makes Close fail with ErrNotAligned if the bit stream is not byte aligned.
+	PadNone
+
+	// PadPattern pads the last byte with the bits of the PaddingPattern, e.g. the flag or the idle fill of a link layer.
+	PadPattern
+)
+
+// PaddingPattern is a pattern of bits repeated by the PadPattern padding policy, e.g. {Bits: 0x7e, NBits: 8} for
+// the HDLC flag fill or {Bits: 0x55, NBits: 8} for the alternating fill. The padding bit at offset `p` of the bit stream
+// is the bit p % NBits of the pattern from its MSB, so the pattern keeps its phase across the bit stream;
+// a pattern of 8 bits is aligned to the bytes, i.e. a padding bit is the bit of the pattern at the same position.
+type PaddingPattern struct {
+	Bits  uint64 // the pattern, LSB aligned
+	NBits uint8  // number of bits in the pattern (1 - 64). If it is 0, the pattern is a '0' bit
+}
+
+// fill returns the byte which starts at the bit offset `byteOffset` filled with the pattern.
+func (p PaddingPattern) fill(byteOffset uint64) uint8 {
+	n := uint64(min(p.NBits, 64))
+	if n == 0 {
+		return 0
+	}
+	b := uint8(0)
+	for i := uint64(0); i < 8; i++ {
+		k := (byteOffset + i) % n
+		b = b<<1 | uint8(p.Bits>>(n-1-k)&1)
+	}
+	return b
+}
+
+// WriterOptions is a set of options for creating a Writer.
+type WriterOptions struct {
+	BufferSize uint // number of complete bytes kept in the Writer before being written to the destination. 1 means no buffering
+	Padding    PaddingPolicy
+	Hooks      *WriterHooks
+
+	// PaddingPattern is the pattern of the padding with the PadPattern padding policy.
+	PaddingPattern PaddingPattern
+
+	// TraceHook is called for each field written to the bit stream. See TraceHook for the details.
+	TraceHook TraceHook
+
+	// StrictValues makes WriteNBitsOfUint8/16BE/32BE fail with ErrValueOutOfRange if the value does not fit in nBits,
+	// instead of silently dropping the upper bits.
+	StrictValues bool
+
+	// Buffer is used to keep the complete bytes instead of allocating a buffer, e.g. to take the buffers from a sync.Pool
+	// or an arena. Its capacity is the buffer size and BufferSize is ignored; its contents are overwritten.
+	// It must not be used by the caller until the Writer is no longer used. While bytes are held for a reservation or
+	// a transaction, the Writer may replace it with a larger buffer.
+	Buffer []byte
+
+	// PlainErrors makes the Writer return the errors as they are, e.g. ErrValueOutOfRange, instead of wrapping them
+	// in a *PositionError or adding the details to them, so that returning an error costs no allocations.
+	PlainErrors bool
+
+	// BitOrder is the bit order used by WriteNBitsOfUint64. MSBFirstBigEndian is used if it is nil.
+	BitOrder BitOrder
+
+	// BitNumbering is the numbering of the bits in a byte used by BytePosition and the errors.
+	BitNumbering BitNumbering
+
+	// Logger logs the events of the Writer at the Debug level, each with its "bit_offset": "bitstream: write" with "bytes"
+	// and "error" for each write to the destination, "bitstream: flush" with "buffered" bytes for Flush, Finalize and Close,
+	// "bitstream: align" with "pad_bits" for AlignByte and the padding, and "bitstream: error" with "op" and "error".
+	Logger *slog.Logger
+}
+
+// GetBufferSize gets configured buffer size.
+func (opt *WriterOptions) GetBufferSize() uint {
+	if opt == nil || opt.BufferSize == 0 {
+		return DefaultWriterBufferSize
+	}
+	return opt.BufferSize
+}
+
+// GetPadding gets configured padding policy.
+func (opt *WriterOptions) GetPadding() PaddingPolicy {
+	if opt == nil {
+		return PadZeros
+	}
+	return opt.Padding
+}
+
+// GetPaddingPattern gets configured padding pattern.
+func (opt *WriterOptions) GetPaddingPattern() PaddingPattern {
+	if opt == nil {
+		return PaddingPattern{}
+	}
+	return opt.PaddingPattern
+}
+
+// GetStrictValues gets whether the values are validated.
+func (opt *WriterOptions) GetStrictValues() bool {
+	if opt == nil {
+		return false
+	}
+	return opt.StrictValues
+}
+
+// GetBuffer gets configured buffer.
+func (opt *WriterOptions) GetBuffer() []byte {
+	if opt == nil {
+		return nil
+	}
+	return opt.Buffer
+}
+
+// GetPlainErrors gets whether the errors are returned without position information.
+func (opt *WriterOptions) GetPlainErrors() bool {
+	if opt == nil {
+		return false
+	}
+	return opt.PlainErrors
+}
+
+// GetHooks gets configured callbacks.
+func (opt *WriterOptions) GetHooks() *WriterHooks {
+	if opt == nil {
+		return nil
+	}
+	return opt.Hooks
 }
 
 // NewWriter creates a new Writer instance.
+// Each byte is written to the destination as soon as it is completed.
+// Use NewWriterWithOptions to batch the writes to the destination.
 func NewWriter(dst io.Writer) *Writer {
+	return NewWriterWithOptions(dst, &WriterOptions{BufferSize: 1})
+}
+
+// NewWriterWithOptions creates a new Writer instance with options.
+// Complete bytes are kept in the buffer until it gets full or Flush, Finalize or Close is called.
+func NewWriterWithOptions(dst io.Writer, opt *WriterOptions) *Writer {
+	buf := opt.GetBuffer()[:0]
+	if cap(buf) == 0 {
+		buf = make([]byte, 0, opt.GetBufferSize())
+	}
 	return &Writer{
 		dst:          dst,
+		buf:          buf,
+		bufSize:      cap(buf),
 		currByte:     []byte{0},
 		currBitIndex: 7,
 		writtenBits:  0,
+		hooks:        opt.GetHooks(),
+		strict:       opt.GetStrictValues(),
+		traceHook:    opt.GetTraceHook(),
+		padding:      opt.GetPadding(),
+		pattern:      opt.GetPaddingPattern(),
+		plainErrors:  opt.GetPlainErrors(),
+		order:        opt.GetBitOrder(),
+		numbering:    opt.GetBitNumbering(),
+		logger:       opt.GetLogger(),
 	}
 }
 
+// Reset discards the pending bits and the buffered bytes and makes the Writer write to `dst` from the beginning,
+// keeping its options, padding policy and buffer, so that a Writer can be reused, e.g. for each message, without allocations.
+// Pending reservations and transactions are discarded as well.
+func (w *Writer) Reset(dst io.Writer) {
+	w.dst = dst
+	w.buf = w.buf[:0]
+	w.currByte[0] = 0
+	w.currBitIndex = 7
+	w.writtenBits = 0
+	w.stats = WriterStats{}
+	w.reserved = w.reserved[:0]
+	w.seeker = nil
+	w.txns = w.txns[:0]
+	w.lastBits = 0
+}
+
 func (w *Writer) dump() string {
 	return fmt.Sprintf("currByte: %02x, currBitIndex: %d", w.currByte[0], w.currBitIndex)
 }
 
-func (w *Writer) WrittenBits() uint {
+// WrittenBits returns the cumulative number of bits written to the Writer since it was created.
+// It includes the bits which have not been written to the destination yet, i.e. the pending bits and the buffered bytes.
+// Bits which could not be written due to an error are not counted.
+func (w *Writer) WrittenBits() uint64 {
+	return w.writtenBits
+}
+
+// PendingBits returns the number of bits in the current byte which is not completed yet (0 to 7).
+func (w *Writer) PendingBits() uint8 {
+	return 7 - w.currBitIndex
+}
+
+// IsByteAligned returns true if the next bit will be written at the byte boundary, i.e. there are no pending bits.
+func (w *Writer) IsByteAligned() bool {
+	return w.currBitIndex == 7
+}
+
+// bitPosition returns the offset of the next bit to be written.
+func (w *Writer) bitPosition() uint64 {
 	return w.writtenBits
 }
 
 // WriteBit writes a single bit to the bit stream.
 // Uses the LSB bit in `bit`.
 func (w *Writer) WriteBit(bit uint8) error {
+	pos := w.bitPosition()
+	err := w.writeBit(bit)
+	if err != nil {
+		return w.wrapError("WriteBit", pos, err)
+	}
+	w.trace("", pos, 1, bit&0x01)
+	return nil
+}
+
+func (w *Writer) writeBit(bit uint8) error {
 	if bit&0x01 != 0 {
 		w.currByte[0] |= ((bit & 0x01) << w.currBitIndex)
 	}
@@ -47,7 +274,7 @@ func (w *Writer) WriteBit(bit uint8) error {
 		return nil
 	}
 
-	return w.Flush()
+	return w.flush()
 }
 
 // WriteBool writes a single bit to the bit stream.
@@ -61,30 +288,103 @@ func (w *Writer) WriteBool(b bool) error {
 	return w.WriteBit(bit)
 }
 
+// WriteRun writes `n` copies of a bit (the LSB of `bit`) to the bit stream.
+// Whole bytes of 0x00 or 0xff are written to the destination at once while the stream is byte aligned.
+func (w *Writer) WriteRun(bit uint8, n uint64) error {
+	pos := w.bitPosition()
+	err := w.writeRun(bit, n)
+	if err != nil {
+		return w.wrapError("WriteRun", pos, err)
+	}
+	if w.done(pos) {
+		w.trace("", pos, uint(n), Run{Bit: bit & 0x01, Length: n})
+	}
+	return nil
+}
+
+func (w *Writer) writeRun(bit uint8, n uint64) error {
+	fill := uint8(0x00)
+	if bit&0x01 != 0 {
+		fill = 0xff
+	}
+
+	// complete the current byte first
+	if w.currBitIndex != 7 {
+		k := uint64(w.currBitIndex + 1)
+		if n < k {
+			k = n
+		}
+		err := w.writeNBitsOfUint8(uint8(k), fill)
+		if err != nil {
+			return err
+		}
+		n -= k
+	}
+
+	nBytes := n / 8
+	if nBytes > 0 {
+		chunkSize := nBytes
+		if chunkSize > runChunkSize {
+			chunkSize = runChunkSize
+		}
+		chunk := bytes.Repeat([]byte{fill}, int(chunkSize))
+
+		for nBytes > 0 {
+			c := chunk
+			if uint64(len(c)) > nBytes {
+				c = c[:nBytes]
+			}
+			nWritten, err := w.emit(c)
+			w.writtenBits += uint64(nWritten) * 8
+			if err != nil {
+				return err
+			}
+			if nWritten != len(c) {
+				return ErrShortWrite
+			}
+			nBytes -= uint64(nWritten)
+		}
+	}
+
+	return w.writeNBitsOfUint8(uint8(n%8), fill)
+}
+
 // WriteNBitsOfUint8 writes `nBits` bits to the bit stream.
 // `nBits` must be less than or equal to 8, otherwise returns an error.
 //
 // This function uses n bits from `val`'s LSB.
 // i.e.)
-//   if you have the following status of bit stream before calling WriteNBitsOfUint8,
-//   currByte: 0101xxxxb
-//   currBitIndex: 3
 //
-//   and if you calls WriteNBitsOfUint8(3, 0xaa),
-//     where nBits == 3, val == 0xaa (10101010b)
+//	if you have the following status of bit stream before calling WriteNBitsOfUint8,
+//	currByte: 0101xxxxb
+//	currBitIndex: 3
 //
-//   WriteNBitsOfUint8 uses the 3 bits from `val`'s LSB, i.e.) xxxxx010b and as a result, status of the bit stream become:
-//   currByte: 0101010xb (0101xxxxb | xxxx010xb)
-//   currBitIndex: 0
+//	and if you calls WriteNBitsOfUint8(3, 0xaa),
+//	  where nBits == 3, val == 0xaa (10101010b)
+//
+//	WriteNBitsOfUint8 uses the 3 bits from `val`'s LSB, i.e.) xxxxx010b and as a result, status of the bit stream become:
+//	currByte: 0101010xb (0101xxxxb | xxxx010xb)
+//	currBitIndex: 0
 func (w *Writer) WriteNBitsOfUint8(nBits, val uint8) error {
-	defer func() { w.writtenBits += uint(nBits) }()
+	pos := w.bitPosition()
+	err := w.checkRange(nBits, uint64(val))
+	if err == nil {
+		err = w.writeNBitsOfUint8(nBits, val)
+	}
+	if err != nil {
+		return w.wrapError("WriteNBitsOfUint8", pos, err)
+	}
+	w.trace("", pos, uint(nBits), uint8(maskBits(nBits, uint64(val))))
+	return nil
+}
 
+func (w *Writer) writeNBitsOfUint8(nBits, val uint8) error {
 	if nBits == 0 {
 		return nil
 	}
 
 	if nBits > 8 {
-		return errors.New("nBits too large for uint8")
+		return errTooManyBitsForUint8
 	}
 
 	// wb: bits can be written in currByte
@@ -93,8 +393,9 @@ func (w *Writer) WriteNBitsOfUint8(nBits, val uint8) error {
 	if nBits <= wb { // all the bits can be written in the currByte
 		mask := uint8(1<<(nBits) - 1) // create a mask to make sure val has exactly n bits (to set 0's to upper bits)
 		w.currByte[0] |= (val & mask) << (wb - nBits)
+		w.writtenBits += uint64(nBits)
 		if nBits == wb {
-			return w.Flush()
+			return w.flush()
 		}
 		w.currBitIndex -= nBits
 		return nil
@@ -105,11 +406,13 @@ func (w *Writer) WriteNBitsOfUint8(nBits, val uint8) error {
 	b2 := val << (8 - (nBits - wb)) // part 2: should be written in the next byte (MSB aligned)
 	b1Mask := uint8((1 << (w.currBitIndex + 1)) - 1)
 	w.currByte[0] |= (b1 & b1Mask)
-	err := w.Flush()
+	w.writtenBits += uint64(wb)
+	err := w.flush()
 	if err != nil {
 		return err
 	}
 	w.currByte[0] = b2
+	w.writtenBits += uint64(nBits - wb)
 	w.currBitIndex = 7 - (nBits - wb)
 
 	return nil
@@ -120,219 +423,495 @@ func (w *Writer) WriteUint8(val uint8) error {
 	return w.WriteNBitsOfUint8(8, val)
 }
 
+// WriteNibble writes the LSB 4 bits of `val` to the bit stream, e.g. a BCD or hex digit.
+// The upper bits are ignored unless the strict mode is enabled, in which case a value larger than 0xf is an error.
+func (w *Writer) WriteNibble(val uint8) error {
+	return w.WriteNBitsOfUint8(4, val)
+}
+
 // WriteNBitsOfUint16 writes `nBits` bits to the bit stream.
 // `nBits` must be less than or equal to 16, otherwise returns an error.
 func (w *Writer) WriteNBitsOfUint16BE(nBits uint8, val uint16) error {
-	if nBits == 0 {
-		return nil
+	pos := w.bitPosition()
+	err := w.checkRange(nBits, uint64(val))
+	if err == nil {
+		err = w.writeNBitsOfUint16BE(nBits, val)
 	}
-
-	if nBits <= 8 {
-		return w.WriteNBitsOfUint8(nBits, uint8(val))
+	if err != nil {
+		return w.wrapError("WriteNBitsOfUint16BE", pos, err)
 	}
-
-	if nBits > 16 {
-		return errors.New("nBits too large for uint16")
+	if w.done(pos) {
+		w.trace("", pos, uint(nBits), uint16(maskBits(nBits, uint64(val))))
 	}
+	return nil
+}
 
-	defer func() { w.writtenBits += uint(nBits) }()
+func (w *Writer) writeNBitsOfUint16BE(nBits uint8, val uint16) error {
+	return w.writeUint(nBits, 16, uint64(val))
+}
 
-	// wb: bits can be written in currByte
-	wb := w.currBitIndex + 1
+// WriteUint16 writes a uint16 value to the bit stream.
+func (w *Writer) WriteUint16BE(val uint16) error {
+	return w.WriteNBitsOfUint16BE(16, val)
+}
 
-	// 16 bits may be distributed in 3 bytes
-	b1Bits := wb
-	b2Bits := uint8(nBits - b1Bits)
-	b3Bits := uint8(0)
-	if b2Bits > 8 {
-		b3Bits = b2Bits - 8
-		b2Bits = 8
+// WriteNBitsOfUint32 writes `nBits` bits to the bit stream.
+// `nBits` must be less than or equal to 32, otherwise returns an error.
+func (w *Writer) WriteNBitsOfUint32BE(nBits uint8, val uint32) error {
+	pos := w.bitPosition()
+	err := w.checkRange(nBits, uint64(val))
+	if err == nil {
+		err = w.writeNBitsOfUint32BE(nBits, val)
 	}
-
-	b1Mask := uint16(((1 << b1Bits) - 1) << (b2Bits + b3Bits))
-	b2Mask := uint16(((1 << b2Bits) - 1) << b3Bits)
-	b3Mask := uint16((1 << b3Bits) - 1)
-
-	b1 := uint8((val & b1Mask) >> (b2Bits + b3Bits))
-	b2 := uint8(((val & b2Mask) >> b3Bits) << (8 - b2Bits)) // left aligned
-	b3 := uint8((val & b3Mask) << (8 - b3Bits))             // left aligned
-
-	w.currByte[0] |= b1
-	err := w.Flush()
 	if err != nil {
-		return err
+		return w.wrapError("WriteNBitsOfUint32BE", pos, err)
 	}
-
-	if b3Bits == 0 {
-		w.currByte[0] = b2
-		if b2Bits == 8 {
-			return w.Flush()
-		}
-		w.currBitIndex = 7 - b2Bits
-		return nil
+	if w.done(pos) {
+		w.trace("", pos, uint(nBits), uint32(maskBits(nBits, uint64(val))))
 	}
+	return nil
+}
 
-	w.currByte[0] = b2
-	err = w.Flush()
+func (w *Writer) writeNBitsOfUint32BE(nBits uint8, val uint32) error {
+	return w.writeUint(nBits, 32, uint64(val))
+}
+
+// WriteUint32 writes a uint32 value to the bit stream.
+func (w *Writer) WriteUint32BE(val uint32) error {
+	return w.WriteNBitsOfUint32BE(32, val)
+}
+
+// WriteNBitsOfUint64BE writes `nBits` bits to the bit stream.
+// `nBits` must be less than or equal to 64, otherwise returns an error.
+func (w *Writer) WriteNBitsOfUint64BE(nBits uint8, val uint64) error {
+	pos := w.bitPosition()
+	err := w.checkRange(nBits, val)
+	if err == nil {
+		err = w.writeUint(nBits, 64, val)
+	}
 	if err != nil {
-		return err
+		return w.wrapError("WriteNBitsOfUint64BE", pos, err)
+	}
+	if w.done(pos) {
+		w.trace("", pos, uint(nBits), maskBits(nBits, val))
 	}
-	w.currByte[0] = b3
-	w.currBitIndex = 7 - b3Bits
-
 	return nil
 }
 
-// WriteUint16 writes a uint16 value to the bit stream.
-func (w *Writer) WriteUint16BE(val uint16) error {
-	return w.WriteNBitsOfUint16BE(16, val)
+// WriteUint64BE writes a uint64 value to the bit stream.
+func (w *Writer) WriteUint64BE(val uint64) error {
+	return w.WriteNBitsOfUint64BE(64, val)
 }
 
-// WriteNBitsOfUint32 writes `nBits` bits to the bit stream.
-// `nBits` must be less than or equal to 32, otherwise returns an error.
-func (w *Writer) WriteNBitsOfUint32BE(nBits uint8, val uint32) error {
+// writeUint writes the LSB `nBits` bits of `val`, which has `maxBits` bits at most, to the bit stream.
+// The bits are assembled together with the ones in currByte in a 64-bit register,
+// and the completed bytes (8 bytes at most) are appended to the buffer at once.
+func (w *Writer) writeUint(nBits, maxBits uint8, val uint64) error {
 	if nBits == 0 {
 		return nil
 	}
-
-	if nBits <= 16 {
-		return w.WriteNBitsOfUint16BE(nBits, uint16(val))
+	if nBits > maxBits {
+		return tooManyBits(maxBits)
 	}
-
-	if nBits > 32 {
-		return errors.New("nBits too large for uint32")
+	if nBits < 64 {
+		val &= 1<<nBits - 1
 	}
 
-	defer func() { w.writtenBits += uint(nBits) }()
-
 	// wb: bits can be written in currByte
 	wb := w.currBitIndex + 1
-
-	// 32 bits may be distributed in 5 bytes
-	b1Bits := wb
-	b2Bits := uint8(8)
-	b3Bits := uint8(nBits - 8 - wb)
-	b4Bits := uint8(0)
-	b5Bits := uint8(0)
-	if b3Bits > 8 {
-		b4Bits = b3Bits - 8
-		if b4Bits > 8 {
-			b5Bits = b4Bits - 8
-			b4Bits = 8
-		}
-		b3Bits = 8
+	if nBits < wb {
+		w.currByte[0] |= uint8(val) << (wb - nBits)
+		w.currBitIndex -= nBits
+		w.writtenBits += uint64(nBits)
+		return nil
 	}
 
-	b1Mask := uint32(((1 << b1Bits) - 1) << (b2Bits + b3Bits + b4Bits + b5Bits))
-	b2Mask := uint32(((1 << b2Bits) - 1) << (b3Bits + b4Bits + b5Bits))
-	b3Mask := uint32(((1 << b3Bits) - 1) << (b4Bits + b5Bits))
-	b4Mask := uint32(((1 << b4Bits) - 1) << b5Bits)
-	b5Mask := uint32((1 << b5Bits) - 1)
+	// the bits in currByte followed by the first `nBits - rest` bits of val complete `n` bytes, which are 64 bits at most.
+	// the last `rest` bits of val are left in currByte.
+	rest := (8 - wb + nBits) % 8
+	n := (8 - wb + nBits) / 8
+	reg := uint64(w.currByte[0])<<56 | val>>rest<<(64-8*uint(n))
 
-	b1 := uint8((val & b1Mask) >> (b2Bits + b3Bits + b4Bits + b5Bits))
-	b2 := uint8(((val & b2Mask) >> (b3Bits + b4Bits + b5Bits)) << (8 - b2Bits)) // left aligned
-	b3 := uint8(((val & b3Mask) >> (b4Bits + b5Bits)) << (8 - b3Bits))          // left aligned
-	b4 := uint8(((val & b4Mask) >> b5Bits) << (8 - b4Bits))                     // left aligned
-	b5 := uint8((val & b5Mask) << (8 - b5Bits))                                 // left aligned
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], reg)
 
-	w.currByte[0] |= b1
-	err := w.Flush()
+	// the bits in currByte move to the first byte of b, and appendBuf counts the bits of each byte appended to the buffer,
+	// so that the counters are consistent with the buffer whenever it is flushed.
+	w.currByte[0] = 0x00
+	w.currBitIndex = 7
+	w.writtenBits -= uint64(8 - wb)
+	err := w.appendBuf(b[:n])
 	if err != nil {
+		// only the bits in the bytes appended to the buffer are counted
 		return err
 	}
+	w.currByte[0] = uint8(val << (8 - rest))
+	w.currBitIndex = 7 - rest
+	w.writtenBits += uint64(rest)
+	return nil
+}
 
-	w.currByte[0] = b2
-	err = w.Flush()
+// WriteNBits writes specified number of bits of the bytes to the bit stream.
+// `nBits` is not limited by the width of any integer type, so a large blob can be written in a single call.
+func (w *Writer) WriteNBits(nBits uint, data []byte) error {
+	pos := w.bitPosition()
+	err := w.writeNBits(nBits, data)
 	if err != nil {
-		return err
+		return w.wrapError("WriteNBits", pos, err)
 	}
+	w.trace("", pos, nBits, data)
+	return nil
+}
 
-	w.currByte[0] = b3
-	if b3Bits == 8 {
-		err = w.Flush()
+func (w *Writer) writeNBits(nBits uint, data []byte) error {
+	// 8 bytes at a time
+	for nBits >= 64 && len(data) >= 8 {
+		err := w.writeUint(64, 64, binary.BigEndian.Uint64(data))
 		if err != nil {
 			return err
 		}
+		data = data[8:]
+		nBits -= 64
 	}
-	if b4Bits == 0 {
-		if b3Bits != 8 {
-			w.currBitIndex = 7 - b3Bits
+
+	// the rest is less than 64 bits
+	if k := (nBits + 7) / 8; nBits < 64 && uint(len(data)) >= k {
+		var v uint64
+		for _, b := range data[:k] {
+			v = v<<8 | uint64(b)
 		}
-		return nil
+		return w.writeUint(uint8(nBits), 64, v>>(k*8-nBits))
 	}
 
-	w.currByte[0] = b4
-	if b4Bits == 8 {
-		err = w.Flush()
+	// data is short: the bytes available are written
+	for nBits > 0 {
+		if len(data) == 0 {
+			return ErrInsufficientData
+		}
+
+		n := uint8(min(nBits, 8))
+		err := w.writeNBitsOfUint8(n, data[0]>>(8-n))
 		if err != nil {
 			return err
 		}
-	}
-	if b5Bits == 0 {
-		if b4Bits != 8 {
-			w.currBitIndex = 7 - b4Bits
-		}
-		return nil
+		data = data[1:]
+		nBits -= uint(n)
 	}
 
-	w.currByte[0] = b5
-	w.currBitIndex = 7 - b5Bits
+	return nil
+}
 
+// WriteBytes writes all the bytes in `p` to the bit stream.
+// The bit stream does not have to be byte aligned. If it is, `p` is written to the destination as it is.
+func (w *Writer) WriteBytes(p []byte) error {
+	pos := w.bitPosition()
+	err := w.writeBytes(p)
+	if err != nil {
+		return w.wrapError("WriteBytes", pos, err)
+	}
+	w.trace("", pos, uint(len(p))*8, p)
 	return nil
 }
 
-// WriteUint32 writes a uint32 value to the bit stream.
-func (w *Writer) WriteUint32BE(val uint32) error {
-	return w.WriteNBitsOfUint32BE(32, val)
+// WriteString writes all the bytes in `s` to the bit stream.
+// The bit stream does not have to be byte aligned.
+func (w *Writer) WriteString(s string) error {
+	pos := w.bitPosition()
+	err := w.writeBytes([]byte(s))
+	if err != nil {
+		return w.wrapError("WriteString", pos, err)
+	}
+	w.trace("", pos, uint(len(s))*8, s)
+	return nil
 }
 
-// WriteNBits writes specified number of bits of the bytes to the bit stream.
-func (w *Writer) WriteNBits(nBits uint, data []byte) error {
-	if nBits == 0 {
+func (w *Writer) writeBytes(p []byte) error {
+	if len(p) == 0 {
 		return nil
 	}
 
-	for nBits > 8 {
-		if len(data) == 0 {
-			return errors.New("insufficient data")
-		}
-
-		b := data[0]
-		err := w.WriteNBitsOfUint8(8, b)
+	if w.currBitIndex == 7 {
+		nWritten, err := w.emit(p)
+		w.writtenBits += uint64(nWritten) * 8
 		if err != nil {
 			return err
 		}
-		data = data[1:]
-		nBits -= 8
+		if nWritten != len(p) {
+			return ErrShortWrite
+		}
+		return nil
 	}
 
-	if nBits > 0 {
-		if len(data) == 0 {
-			return errors.New("insufficient data")
+	// shift the bytes by the number of bits in currByte, which are combined with the first byte.
+	// the last `shift` bits of p are left in currByte.
+	shift := 7 - w.currBitIndex
+	chunkSize := len(p)
+	if chunkSize > runChunkSize {
+		chunkSize = runChunkSize
+	}
+	chunk := make([]byte, chunkSize)
+	for len(p) > 0 {
+		c := chunk
+		if len(c) > len(p) {
+			c = c[:len(p)]
 		}
-		b := data[0]
-		b = b >> (8 - nBits)
-		err := w.WriteNBitsOfUint8(uint8(nBits), b)
+		rest := w.currByte[0]
+		for i := range c {
+			c[i] = rest | p[i]>>shift
+			rest = p[i] << (8 - shift)
+		}
+		w.currByte[0] = rest
+
+		nWritten, err := w.emit(c)
+		w.writtenBits += uint64(nWritten) * 8
 		if err != nil {
 			return err
 		}
+		if nWritten != len(c) {
+			return ErrShortWrite
+		}
+		p = p[len(c):]
 	}
 
 	return nil
 }
 
-// Flush ensures the bufferred bits (bits not writen to the stream because it has less than 8 bits) to the destination writer.
-func (w *Writer) Flush() error {
-	nWritten, err := w.dst.Write(w.currByte)
+// AlignByte pads the current byte with `padBit` (the LSB is used) up to the byte boundary and writes it to the destination.
+// It returns the number of pad bits written, which is 0 if the bit stream is already byte aligned.
+func (w *Writer) AlignByte(padBit uint8) (uint8, error) {
+	pos := w.bitPosition()
+	padded, err := w.alignByte(padBit)
+	if err != nil {
+		return 0, w.wrapError("AlignByte", pos, err)
+	}
+	w.trace("", pos, uint(padded), nil)
+	return padded, nil
+}
+
+func (w *Writer) alignByte(padBit uint8) (uint8, error) {
+	fill := uint8(0x00)
+	if padBit&0x01 != 0 {
+		fill = 0xff
+	}
+	return w.alignByteWith(fill)
+}
+
+// alignByteWith pads the current byte with the bits of `fill` at the same positions.
+func (w *Writer) alignByteWith(fill uint8) (uint8, error) {
+	if w.currBitIndex == 7 {
+		return 0, nil
+	}
+
+	padded := w.currBitIndex + 1
+	pos := w.bitPosition()
+	err := w.writeNBitsOfUint8(padded, fill)
+	if err != nil {
+		return 0, err
+	}
+	if w.logging() {
+		w.logDebug("bitstream: align", pos, "pad_bits", padded)
+	}
+	return padded, nil
+}
+
+// SetStrictValues enables or disables the validation of the values, overriding WriterOptions.StrictValues.
+func (w *Writer) SetStrictValues(strict bool) {
+	w.strict = strict
+}
+
+// StrictValues reports whether the values are validated.
+func (w *Writer) StrictValues() bool {
+	return w.strict
+}
+
+// checkRange returns ErrValueOutOfRange if `val` does not fit in `nBits` bits in the strict mode.
+func (w *Writer) checkRange(nBits uint8, val uint64) error {
+	if !w.strict || nBits >= 64 || val>>nBits == 0 {
+		return nil
+	}
+	if w.plainErrors {
+		return ErrValueOutOfRange
+	}
+	return fmt.Errorf("%w: %d does not fit in %d bits", ErrValueOutOfRange, val, nBits)
+}
+
+// SetPaddingPolicy sets the padding policy used by Finalize, Close and Pad, overriding WriterOptions.Padding.
+func (w *Writer) SetPaddingPolicy(p PaddingPolicy) {
+	w.padding = p
+}
+
+// SetPaddingPattern sets the padding policy to PadPattern with the pattern `p`, overriding WriterOptions.Padding
+// and WriterOptions.PaddingPattern.
+func (w *Writer) SetPaddingPattern(p PaddingPattern) {
+	w.padding = PadPattern
+	w.pattern = p
+}
+
+// Finalize ends the bit stream; it pads the last byte according to the padding policy and writes it to the destination.
+// Nothing is written if the bit stream is byte aligned.
+// If the policy is PadNone and the bit stream is not byte aligned, it returns ErrNotAligned.
+func (w *Writer) Finalize() error {
+	pos := w.bitPosition()
+	err := w.finalize()
+	if err != nil {
+		return w.wrapError("Finalize", pos, err)
+	}
+	return nil
+}
+
+func (w *Writer) finalize() error {
+	w.countFlush()
+	if len(w.txns) > 0 {
+		return ErrInTransaction
+	}
+	_, err := w.pad()
 	if err != nil {
 		return err
 	}
-	if nWritten != 1 {
-		return errors.New("unable to write 1 byte")
+	if w.holding() {
+		return ErrPendingReservation
 	}
+	return w.flushBuf()
+}
 
+// Close finalizes the bit stream in the same way as Finalize, and then closes the destination if it implements io.Closer.
+// The destination is not closed if Finalize fails.
+func (w *Writer) Close() error {
+	pos := w.bitPosition()
+	err := w.close()
+	if err != nil {
+		return w.wrapError("Close", pos, err)
+	}
+	return nil
+}
+
+func (w *Writer) close() error {
+	err := w.finalize()
+	if err != nil {
+		return err
+	}
+
+	if c, ok := w.dst.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Pad pads the current byte according to the padding policy up to the byte boundary, in the same way as Finalize
+// pads the last byte, e.g. to fill the end of each frame. It returns the number of pad bits written, which is 0 if
+// the bit stream is already byte aligned. If the policy is PadNone and the bit stream is not byte aligned,
+// it returns ErrNotAligned.
+func (w *Writer) Pad() (uint8, error) {
+	pos := w.bitPosition()
+	padded, err := w.pad()
+	if err != nil {
+		return 0, w.wrapError("Pad", pos, err)
+	}
+	w.trace("", pos, uint(padded), nil)
+	return padded, nil
+}
+
+// pad pads the current byte according to the padding policy.
+func (w *Writer) pad() (uint8, error) {
+	if w.currBitIndex == 7 {
+		return 0, nil
+	}
+
+	switch w.padding {
+	case PadOnes:
+		return w.alignByte(1)
+	case PadNone:
+		return 0, ErrNotAligned
+	case PadPattern:
+		return w.alignByteWith(w.pattern.fill(w.bitPosition() - uint64(7-w.currBitIndex)))
+	default:
+		return w.alignByte(0)
+	}
+}
+
+// Flush writes the complete bytes to the destination.
+// The bits in the current byte which is not completed yet are kept in the Writer, so Flush can be called in the middle of the bit stream.
+// Use Finalize or Close to write them at the end of the bit stream.
+func (w *Writer) Flush() error {
+	pos := w.bitPosition()
+	w.countFlush()
+	err := w.flushBuf()
+	if err != nil {
+		return w.wrapError("Flush", pos, err)
+	}
+	return nil
+}
+
+// appendBuf appends the completed bytes to the buffer, and writes the buffer to the destination whenever it gets full
+// in the same way as flush does for each byte. The bits of each byte are counted in writtenBits before the buffer is written.
+func (w *Writer) appendBuf(p []byte) error {
+	for len(p) > 0 {
+		k := min(max(w.bufSize-len(w.buf), 1), len(p))
+		w.buf = append(w.buf, p[:k]...)
+		w.writtenBits += uint64(k) * 8
+		p = p[k:]
+
+		if len(w.buf) >= w.bufSize {
+			err := w.flushBuf()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush moves the current byte to the buffer and starts a new byte.
+// The buffer is written to the destination when it gets full.
+func (w *Writer) flush() error {
+	w.buf = append(w.buf, w.currByte[0])
 	w.currByte[0] = 0x00
 	w.currBitIndex = 7
 
+	if len(w.buf) < w.bufSize {
+		return nil
+	}
+	return w.flushBuf()
+}
+
+// flushBuf writes the buffer to the destination.
+// The bytes which could not be written and the ones which have to be kept for Patch remain in the buffer.
+func (w *Writer) flushBuf() error {
+	n := w.writableBytes()
+	if n == 0 {
+		return nil
+	}
+
+	nWritten, err := w.write(w.buf[:n])
+	if nWritten > 0 && nWritten < len(w.buf) {
+		copy(w.buf, w.buf[nWritten:])
+	}
+	w.buf = w.buf[:len(w.buf)-nWritten]
+	if err != nil {
+		return err
+	}
+	if nWritten < n {
+		return ErrShortWrite
+	}
 	return nil
 }
+
+// emit writes complete bytes to the destination through the buffer.
+// Large data is written directly once the buffer is flushed.
+// It returns the number of bytes of `p` accepted by the Writer.
+func (w *Writer) emit(p []byte) (int, error) {
+	if len(p) < w.bufSize-len(w.buf) || w.holding() {
+		w.buf = append(w.buf, p...)
+		return len(p), nil
+	}
+
+	err := w.flushBuf()
+	if err != nil {
+		return 0, err
+	}
+	if len(p) < w.bufSize {
+		w.buf = append(w.buf, p...)
+		return len(p), nil
+	}
+	return w.write(p)
+}
@@ -0,0 +1,154 @@
+package bitstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// WriterAt writes bits at arbitrary bit offsets of a destination, e.g. to update flags or checksums in headers already written.
+//
+// If the bits to be written do not cover whole bytes, the other bits of the first and last bytes are read back from the destination,
+// so it has to implement io.ReaderAt too. Otherwise WriteNBitsAt returns ErrNotPatchable.
+type WriterAt struct {
+	dst io.WriterAt
+}
+
+// NewWriterAt creates a new WriterAt instance which writes bits to `dst`.
+func NewWriterAt(dst io.WriterAt) *WriterAt {
+	return &WriterAt{
+		dst: dst,
+	}
+}
+
+// NewBytesWriterAt creates a new WriterAt instance which writes bits to `b` in place.
+// Writing beyond the end of `b` returns io.ErrShortWrite.
+func NewBytesWriterAt(b []byte) *WriterAt {
+	return NewWriterAt(bytesAt(b))
+}
+
+// WriteBitAt writes a single bit (the LSB of `bit`) at `bitOffset`.
+func (wa *WriterAt) WriteBitAt(bitOffset uint64, bit uint8) error {
+	err := wa.writeNBitsAt(bitOffset, 1, []byte{bit << 7})
+	if err != nil {
+		return wrapError("WriteBitAt", bitOffset, err)
+	}
+	return nil
+}
+
+// WriteNBitsOfUint64At writes the LSB `nBits` bits of `val` at `bitOffset`.
+// `nBits` must be less than or equal to 64, otherwise returns an error.
+func (wa *WriterAt) WriteNBitsOfUint64At(bitOffset uint64, nBits uint8, val uint64) error {
+	err := wa.writeNBitsOfUint64At(bitOffset, nBits, val)
+	if err != nil {
+		return wrapError("WriteNBitsOfUint64At", bitOffset, err)
+	}
+	return nil
+}
+
+func (wa *WriterAt) writeNBitsOfUint64At(bitOffset uint64, nBits uint8, val uint64) error {
+	if nBits == 0 {
+		return nil
+	}
+	if nBits > 64 {
+		return ErrTooManyBits
+	}
+
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, val<<(64-nBits))
+	return wa.writeNBitsAt(bitOffset, uint(nBits), b)
+}
+
+// WriteNBitsAt writes `nBits` bits of `data` (from the MSB of the first byte) at `bitOffset`.
+func (wa *WriterAt) WriteNBitsAt(bitOffset uint64, nBits uint, data []byte) error {
+	err := wa.writeNBitsAt(bitOffset, nBits, data)
+	if err != nil {
+		return wrapError("WriteNBitsAt", bitOffset, err)
+	}
+	return nil
+}
+
+func (wa *WriterAt) writeNBitsAt(bitOffset uint64, nBits uint, data []byte) error {
+	if nBits == 0 {
+		return nil
+	}
+	if uint(len(data))*8 < nBits {
+		return ErrInsufficientData
+	}
+
+	shift := uint(bitOffset % 8)
+	n := (shift + nBits + 7) / 8
+	out := make([]byte, n)
+	for i := uint(0); i < (nBits+7)/8; i++ {
+		out[i] |= data[i] >> shift
+		if shift > 0 && i+1 < n {
+			out[i+1] |= data[i] << (8 - shift)
+		}
+	}
+
+	// masks of the bits to be written in the first and the last byte
+	headMask := uint8(0xff >> shift)
+	tailMask := uint8(0xff)
+	if tail := (shift + nBits) % 8; tail != 0 {
+		tailMask = uint8(0xff << (8 - tail))
+	}
+	if n == 1 {
+		headMask &= tailMask
+		tailMask = headMask
+	}
+	out[n-1] &= tailMask
+
+	off := int64(bitOffset / 8)
+	if headMask != 0xff {
+		err := wa.merge(out[:1], off, headMask)
+		if err != nil {
+			return err
+		}
+	}
+	if tailMask != 0xff && n > 1 {
+		err := wa.merge(out[n-1:], off+int64(n-1), tailMask)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := wa.dst.WriteAt(out, off)
+	return err
+}
+
+// merge fills the bits of `b` which are not specified by `mask` with the ones of the byte at `off` in the destination.
+func (wa *WriterAt) merge(b []byte, off int64, mask uint8) error {
+	ra, ok := wa.dst.(io.ReaderAt)
+	if !ok {
+		return ErrNotPatchable
+	}
+
+	old := []byte{0}
+	_, err := ra.ReadAt(old, off)
+	if err != nil && !errors.Is(err, io.EOF) { // bits beyond the end are regarded as 0
+		return err
+	}
+	b[0] = old[0]&^mask | b[0]
+	return nil
+}
+
+// bytesAt is a fixed size in-memory io.WriterAt and io.ReaderAt.
+type bytesAt []byte
+
+func (b bytesAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (b bytesAt) WriteAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > int64(len(b)) {
+		return 0, io.ErrShortWrite
+	}
+	return copy(b[off:], p), nil
+}
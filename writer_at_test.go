@@ -0,0 +1,132 @@
+package bitstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// writerAtOnly hides the ReadAt method of the destination.
+type writerAtOnly struct {
+	w io.WriterAt
+}
+
+func (w writerAtOnly) WriteAt(p []byte, off int64) (int, error) {
+	return w.w.WriteAt(p, off)
+}
+
+func TestWriteNBitsAt(t *testing.T) {
+	testData := []struct {
+		Name      string
+		Init      []byte
+		BitOffset uint64
+		NBits     uint
+		Data      []byte
+		Expected  []byte
+	}{
+		{
+			// 1111 1111 | 1111 1111 => 1111 1111 | 0000 0000
+			Name:      "pattern 1",
+			Init:      []byte{0xff, 0xff},
+			BitOffset: 8,
+			NBits:     8,
+			Data:      []byte{0x00},
+			Expected:  []byte{0xff, 0x00},
+		},
+		{
+			// 1111 1111 | 1111 1111 => 1110 1011 | 1111 1111
+			Name:      "pattern 2",
+			Init:      []byte{0xff, 0xff},
+			BitOffset: 3,
+			NBits:     3,
+			Data:      []byte{0x40}, // 010x xxxx
+			Expected:  []byte{0xeb, 0xff},
+		},
+		{
+			// 0000 0000 | 0000 0000 | 0000 0000 => 0000 0101 | 0101 0110 | 0000 0000
+			Name:      "pattern 3",
+			Init:      []byte{0x00, 0x00, 0x00},
+			BitOffset: 5,
+			NBits:     12,
+			Data:      []byte{0xaa, 0xcf}, // 1010 1010 1100 xxxx
+			Expected:  []byte{0x05, 0x56, 0x00},
+		},
+		{
+			// 1111 1111 | 1111 1111 | 1111 1111 => 1111 0000 | 0000 0000 | 0011 1111
+			Name:      "pattern 4",
+			Init:      []byte{0xff, 0xff, 0xff},
+			BitOffset: 4,
+			NBits:     14,
+			Data:      []byte{0x00, 0x00},
+			Expected:  []byte{0xf0, 0x00, 0x3f},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			b := append([]byte{}, data.Init...)
+			wa := NewBytesWriterAt(b)
+			err := wa.WriteNBitsAt(data.BitOffset, data.NBits, data.Data)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !bytes.Equal(data.Expected, b) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Expected, b)
+			}
+		})
+	}
+}
+
+func TestWriteNBitsOfUint64At(t *testing.T) {
+	b := make([]byte, 10)
+	wa := NewBytesWriterAt(b)
+
+	err := wa.WriteNBitsOfUint64At(4, 64, 0x0123456789abcdef)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = wa.WriteBitAt(79, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+
+	expected := []byte{0x00, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01}
+	if !bytes.Equal(expected, b) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, b)
+	}
+
+	err = wa.WriteNBitsOfUint64At(0, 65, 0)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+}
+
+func TestWriteNBitsAtError(t *testing.T) {
+	b := make([]byte, 2)
+	wa := NewBytesWriterAt(b)
+
+	err := wa.WriteNBitsAt(12, 8, []byte{0xff})
+	if !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrShortWrite, err)
+	}
+	err = wa.WriteNBitsAt(0, 9, []byte{0xff})
+	if !errors.Is(err, ErrInsufficientData) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrInsufficientData, err)
+	}
+
+	// whole bytes can be written without reading back
+	wa = NewWriterAt(writerAtOnly{w: bytesAt(b)})
+	err = wa.WriteNBitsAt(8, 8, []byte{0xab})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = wa.WriteNBitsAt(1, 8, []byte{0xab})
+	if !errors.Is(err, ErrNotPatchable) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNotPatchable, err)
+	}
+	if !bytes.Equal([]byte{0x00, 0xab}, b) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0x00, 0xab}, b)
+	}
+}
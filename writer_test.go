@@ -2,7 +2,10 @@ package bitstream
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"math/rand"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -33,7 +36,7 @@ func TestWriteBit(t *testing.T) {
 	if !reflect.DeepEqual(buf.Bytes(), expected) {
 		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, buf.Bytes())
 	}
-	if uint(16) != bw.WrittenBits() {
+	if uint64(16) != bw.WrittenBits() {
 		t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", 16, bw.WrittenBits())
 	}
 }
@@ -106,7 +109,7 @@ func TestWriteNBitsOfUint8(t *testing.T) {
 			if err != nil {
 				t.Fatalf("unexpected error: %+v\n", err)
 			}
-			if uint(data.NBits) != bw.WrittenBits() {
+			if uint64(data.NBits) != bw.WrittenBits() {
 				t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", data.NBits, bw.WrittenBits())
 			}
 			if data.Expected.currByte != bw.currByte[0] {
@@ -165,6 +168,25 @@ func BenchmarkWrite8BitsOfUint8(b *testing.B) {
 	benchmarkWriteNBitsOfUint8(8, b)
 }
 
+func TestWriteNibble(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteBit(1)
+	for _, d := range []uint8{0x2, 0x0, 0x2, 0x6, 0x1, 0xf} {
+		err := w.WriteNibble(d)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	w.WriteNibble(0xa5) // the upper bits are ignored
+	w.Close()
+
+	expected := []byte{0x90, 0x13, 0x0f, 0xa8} // 1 0010 0000 0010 0110 0001 1111 0101
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("\nExpected: %#v\nActual:   %#v\n", expected, buf.Bytes())
+	}
+}
+
 func TestWriteNBitsOfUint16BE(t *testing.T) {
 	testData := []struct {
 		Name     string
@@ -225,7 +247,7 @@ func TestWriteNBitsOfUint16BE(t *testing.T) {
 			if err != nil {
 				t.Fatalf("unexpected error: %+v\n", err)
 			}
-			if uint(data.NBits) != bw.WrittenBits() {
+			if uint64(data.NBits) != bw.WrittenBits() {
 				t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", data.NBits, bw.WrittenBits())
 			}
 			if data.Expected.currByte != bw.currByte[0] {
@@ -378,7 +400,7 @@ func TestWriteNBitsOfUint32BE(t *testing.T) {
 			if err != nil {
 				t.Fatalf("unexpected error: %+v\n", err)
 			}
-			if uint(data.NBits) != bw.WrittenBits() {
+			if uint64(data.NBits) != bw.WrittenBits() {
 				t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", data.NBits, bw.WrittenBits())
 			}
 			if data.Expected.currByte != bw.currByte[0] {
@@ -429,6 +451,99 @@ func BenchmarkWrite32BitsOfUint32BE(b *testing.B) {
 	benchmarkWriteNBitsOfUint32BE(32, b)
 }
 
+func TestWriteNBitsOfUint64BE(t *testing.T) {
+	testData := []struct {
+		Name     string
+		NBits    uint8
+		Value    uint64
+		Start    writerStatus
+		Expected writerStatus
+	}{
+		{
+			Name:     "pattern 1",
+			NBits:    0,
+			Value:    0xffffffffffffffff,
+			Start:    writerStatus{currByte: 0x80, currBitIndex: 6, buf: []byte{}},
+			Expected: writerStatus{currByte: 0x80, currBitIndex: 6, buf: []byte{}},
+		},
+		{
+			Name:     "pattern 2",
+			NBits:    33,
+			Value:    0xffffffffffffffff,                                                                 // 1111 1111 1111 1111 1111 1111 1111 1111 1
+			Start:    writerStatus{currByte: 0x00, currBitIndex: 7, buf: []byte{}},                       // xxxx xxxx
+			Expected: writerStatus{currByte: 0x80, currBitIndex: 6, buf: []byte{0xff, 0xff, 0xff, 0xff}}, // 1111 1111 1111 1111 1111 1111 1111 1111 1xxx xxxx
+		},
+		{
+			Name:     "pattern 3",
+			NBits:    64,
+			Value:    0x0123456789abcdef,                                                                                         // 0000 0001 0010 0011 ... 1100 1101 1110 1111
+			Start:    writerStatus{currByte: 0x00, currBitIndex: 7, buf: []byte{}},                                               // xxxx xxxx
+			Expected: writerStatus{currByte: 0x00, currBitIndex: 7, buf: []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}}, // 0000 0001 0010 0011 ... 1100 1101 1110 1111
+		},
+		{
+			Name:     "pattern 4",
+			NBits:    64,
+			Value:    0x0123456789abcdef,                                                                                         //  000 0000 1001 0001 ... 1110 0110 1111 0111 1
+			Start:    writerStatus{currByte: 0x80, currBitIndex: 6, buf: []byte{}},                                               // 1xxx xxxx
+			Expected: writerStatus{currByte: 0x80, currBitIndex: 6, buf: []byte{0x80, 0x91, 0xa2, 0xb3, 0xc4, 0xd5, 0xe6, 0xf7}}, // 1000 0000 1001 0001 ... 1110 0110 1111 0111 1xxx xxxx
+		},
+		{
+			Name:     "pattern 5",
+			NBits:    60,
+			Value:    0xffedcba987654321,                                                                                         //        11 1111 1011 ... 0101 0000 1100 1000 01
+			Start:    writerStatus{currByte: 0xfc, currBitIndex: 1, buf: []byte{}},                                               // 1111 11xx
+			Expected: writerStatus{currByte: 0x40, currBitIndex: 5, buf: []byte{0xff, 0xfb, 0x72, 0xea, 0x61, 0xd9, 0x50, 0xc8}}, // 1111 1111 1111 1011 ... 0101 0000 1100 1000 01xx xxxx
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer(data.Start.buf)
+			bw := NewWriter(buf)
+
+			bw.currByte[0] = data.Start.currByte
+			bw.currBitIndex = data.Start.currBitIndex
+
+			err := bw.WriteNBitsOfUint64BE(data.NBits, data.Value)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if uint64(data.NBits) != bw.WrittenBits() {
+				t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", data.NBits, bw.WrittenBits())
+			}
+			if data.Expected.currByte != bw.currByte[0] {
+				t.Fatalf("\nunexpected currByte\nExpected: %+v\nActual:   %+v\n", data.Expected.currByte, bw.currByte[0])
+			}
+			if data.Expected.currBitIndex != bw.currBitIndex {
+				t.Fatalf("\nunexpected currBitIndex\nExpected: %+v\nActual:   %+v\n", data.Expected.currBitIndex, bw.currBitIndex)
+			}
+			if !reflect.DeepEqual(data.Expected.buf, buf.Bytes()) {
+				t.Fatalf("\nunexpected flushed data\nExpected: %+v\nActual:   %+v\n", data.Expected.buf, buf.Bytes())
+			}
+		})
+	}
+}
+
+func BenchmarkWrite64BitsOfUint64BE(b *testing.B) {
+	bw := NewWriterWithOptions(io.Discard, &WriterOptions{})
+	bw.WriteBit(1)
+	b.SetBytes(8)
+	for n := 0; n < b.N; n++ {
+		_ = bw.WriteNBitsOfUint64BE(64, uint64(n))
+	}
+}
+
+func BenchmarkWriteNBitsUnaligned(b *testing.B) {
+	data := make([]byte, 4096)
+	bw := NewWriterWithOptions(io.Discard, &WriterOptions{})
+	bw.WriteBit(1)
+	b.SetBytes(int64(len(data)))
+	for n := 0; n < b.N; n++ {
+		_ = bw.WriteNBits(uint(len(data))*8, data)
+	}
+}
+
 func TestWriteNBits(t *testing.T) {
 	testData := []struct {
 		Name     string
@@ -531,7 +646,7 @@ func TestWriteNBits(t *testing.T) {
 			if err != nil {
 				t.Fatalf("unexpected error: %+v\n", err)
 			}
-			if data.NBits != bw.WrittenBits() {
+			if uint64(data.NBits) != bw.WrittenBits() {
 				t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", data.NBits, bw.WrittenBits())
 			}
 			if data.Expected.currByte != bw.currByte[0] {
@@ -548,3 +663,665 @@ func TestWriteNBits(t *testing.T) {
 	}
 
 }
+
+func TestWriteRun(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Bit      uint8
+		N        uint64
+		Start    writerStatus
+		Expected writerStatus
+	}{
+		{
+			Name:     "pattern 1",
+			Bit:      1,
+			N:        3,
+			Start:    writerStatus{currByte: 0x00, currBitIndex: 7, buf: []byte{}},
+			Expected: writerStatus{currByte: 0xe0, currBitIndex: 4, buf: []byte{}},
+		},
+		{
+			Name:     "pattern 2",
+			Bit:      1,
+			N:        20,
+			Start:    writerStatus{currByte: 0x40, currBitIndex: 5, buf: []byte{}},           // 01xx xxxx
+			Expected: writerStatus{currByte: 0xfc, currBitIndex: 1, buf: []byte{0x7f, 0xff}}, // 0111 1111 | 1111 1111 | 1111 11xx
+		},
+		{
+			Name:     "pattern 3",
+			Bit:      0,
+			N:        26,
+			Start:    writerStatus{currByte: 0xc0, currBitIndex: 5, buf: []byte{}},                 // 11xx xxxx
+			Expected: writerStatus{currByte: 0x00, currBitIndex: 3, buf: []byte{0xc0, 0x00, 0x00}}, // 1100 0000 | 0000 0000 | 0000 0000 | 0000 xxxx
+		},
+		{
+			Name:     "pattern 4",
+			Bit:      1,
+			N:        0,
+			Start:    writerStatus{currByte: 0x00, currBitIndex: 2, buf: []byte{}},
+			Expected: writerStatus{currByte: 0x00, currBitIndex: 2, buf: []byte{}},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer(data.Start.buf)
+			bw := NewWriter(buf)
+
+			bw.currByte[0] = data.Start.currByte
+			bw.currBitIndex = data.Start.currBitIndex
+
+			err := bw.WriteRun(data.Bit, data.N)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if uint64(data.N) != bw.WrittenBits() {
+				t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", data.N, bw.WrittenBits())
+			}
+			if data.Expected.currByte != bw.currByte[0] {
+				t.Fatalf("\nunexpected currByte\nExpected: %+v\nActual:   %+v\n", data.Expected.currByte, bw.currByte[0])
+			}
+			if data.Expected.currBitIndex != bw.currBitIndex {
+				t.Fatalf("\nunexpected currBitIndex\nExpected: %+v\nActual:   %+v\n", data.Expected.currBitIndex, bw.currBitIndex)
+			}
+			if !reflect.DeepEqual(data.Expected.buf, buf.Bytes()) {
+				t.Fatalf("\nunexpected flushed data\nExpected: %+v\nActual:   %+v\n", data.Expected.buf, buf.Bytes())
+			}
+		})
+	}
+}
+
+func BenchmarkWriteRun(b *testing.B) {
+	buf := bytes.NewBuffer([]byte{})
+	bw := NewWriter(buf)
+	for n := 0; n < b.N; n++ {
+		buf.Reset()
+		_ = bw.WriteRun(uint8(n), 1000003)
+	}
+}
+
+func TestWriteNBitsLargerThan255(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	bw := NewWriter(buf)
+	bw.WriteNBitsOfUint8(4, 0x0f)
+	err := bw.WriteNBits(700, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	bw.Flush()
+
+	if uint64(704) != bw.WrittenBits() {
+		t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", 704, bw.WrittenBits())
+	}
+	r := NewReader(bytes.NewReader(buf.Bytes()), nil)
+	r.ReadNBitsAsUint8(4)
+	v, err := r.ReadNBits(700, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expected := append([]byte{}, data[:88]...)
+	expected[87] &= 0xf0
+	if !reflect.DeepEqual(expected, v) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, v)
+	}
+}
+
+func TestWriteBytes(t *testing.T) {
+	data := make([]byte, runChunkSize*2+3)
+	for i := range data {
+		data[i] = byte(i*13 + i>>8)
+	}
+
+	for offset := uint8(0); offset < 8; offset++ {
+		for _, n := range []int{0, 1, 2, 5, len(data)} {
+			for _, useString := range []bool{false, true} {
+				buf := bytes.NewBuffer([]byte{})
+				bw := NewWriter(buf)
+				bw.WriteNBitsOfUint8(offset, 0xff)
+
+				var err error
+				if useString {
+					err = bw.WriteString(string(data[:n]))
+				} else {
+					err = bw.WriteBytes(data[:n])
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if uint64(offset)+uint64(n)*8 != bw.WrittenBits() {
+					t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", uint64(offset)+uint64(n)*8, bw.WrittenBits())
+				}
+				bw.Finalize()
+
+				r := NewReader(bytes.NewReader(buf.Bytes()), nil)
+				v, _ := r.ReadNBitsAsUint8(offset)
+				if expected := uint8(0xff >> (8 - offset)); offset > 0 && v != expected {
+					t.Fatalf("\nExpected: %#x\nActual:   %#x\n", expected, v)
+				}
+				p, err := r.ReadBytes(uint(n))
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+				if !bytes.Equal(data[:n], p) {
+					t.Fatalf("offset %d, %d bytes: unexpected data\n", offset, n)
+				}
+			}
+		}
+	}
+}
+
+func TestWriteBytesShortWrite(t *testing.T) {
+	bw := NewWriter(shortWriter{})
+	err := bw.WriteBytes([]byte{0x01, 0x02})
+	if !errors.Is(err, ErrShortWrite) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrShortWrite, err)
+	}
+}
+
+func BenchmarkWriteBytesUnaligned(b *testing.B) {
+	data := make([]byte, 4096)
+	bw := NewWriter(io.Discard)
+	bw.WriteBit(1)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		bw.WriteBytes(data)
+	}
+}
+
+func TestAlignByte(t *testing.T) {
+	testData := []struct {
+		Name           string
+		PadBit         uint8
+		Start          writerStatus
+		ExpectedPadded uint8
+		Expected       writerStatus
+	}{
+		{
+			Name:           "pattern 1",
+			PadBit:         0,
+			Start:          writerStatus{currByte: 0xe0, currBitIndex: 4, buf: []byte{}}, // 111x xxxx
+			ExpectedPadded: 5,
+			Expected:       writerStatus{currByte: 0x00, currBitIndex: 7, buf: []byte{0xe0}}, // 1110 0000
+		},
+		{
+			Name:           "pattern 2",
+			PadBit:         1,
+			Start:          writerStatus{currByte: 0x40, currBitIndex: 5, buf: []byte{}}, // 01xx xxxx
+			ExpectedPadded: 6,
+			Expected:       writerStatus{currByte: 0x00, currBitIndex: 7, buf: []byte{0x7f}}, // 0111 1111
+		},
+		{
+			Name:           "pattern 3",
+			PadBit:         0xfe,
+			Start:          writerStatus{currByte: 0xfe, currBitIndex: 0, buf: []byte{}}, // 1111 111x
+			ExpectedPadded: 1,
+			Expected:       writerStatus{currByte: 0x00, currBitIndex: 7, buf: []byte{0xfe}}, // 1111 1110
+		},
+		{
+			Name:           "already aligned",
+			PadBit:         1,
+			Start:          writerStatus{currByte: 0x00, currBitIndex: 7, buf: []byte{}},
+			ExpectedPadded: 0,
+			Expected:       writerStatus{currByte: 0x00, currBitIndex: 7, buf: []byte{}},
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := bytes.NewBuffer(data.Start.buf)
+			bw := NewWriter(buf)
+
+			bw.currByte[0] = data.Start.currByte
+			bw.currBitIndex = data.Start.currBitIndex
+
+			padded, err := bw.AlignByte(data.PadBit)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.ExpectedPadded != padded {
+				t.Fatalf("\nunexpected padded bits\nExpected: %+v\nActual:   %+v\n", data.ExpectedPadded, padded)
+			}
+			if uint64(padded) != bw.WrittenBits() {
+				t.Fatalf("\nunexpected writtenBits\nExpected: %+v\nActual:   %+v\n", padded, bw.WrittenBits())
+			}
+			if data.Expected.currByte != bw.currByte[0] {
+				t.Fatalf("\nunexpected currByte\nExpected: %+v\nActual:   %+v\n", data.Expected.currByte, bw.currByte[0])
+			}
+			if data.Expected.currBitIndex != bw.currBitIndex {
+				t.Fatalf("\nunexpected currBitIndex\nExpected: %+v\nActual:   %+v\n", data.Expected.currBitIndex, bw.currBitIndex)
+			}
+			if !reflect.DeepEqual(data.Expected.buf, buf.Bytes()) {
+				t.Fatalf("\nunexpected buf\nExpected: %+v\nActual:   %+v\n", data.Expected.buf, buf.Bytes())
+			}
+		})
+	}
+}
+
+// closeRecorder is a bytes.Buffer which records whether it is closed or not.
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (cr *closeRecorder) Close() error {
+	cr.closed = true
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	testData := []struct {
+		Name           string
+		Padding        PaddingPolicy
+		Start          writerStatus
+		Expected       []byte
+		ExpectedError  error
+		ExpectedClosed bool
+	}{
+		{
+			Name:           "pad zeros",
+			Padding:        PadZeros,
+			Start:          writerStatus{currByte: 0xe0, currBitIndex: 4}, // 111x xxxx
+			Expected:       []byte{0xe0},                                  // 1110 0000
+			ExpectedClosed: true,
+		},
+		{
+			Name:           "pad ones",
+			Padding:        PadOnes,
+			Start:          writerStatus{currByte: 0x40, currBitIndex: 5}, // 01xx xxxx
+			Expected:       []byte{0x7f},                                  // 0111 1111
+			ExpectedClosed: true,
+		},
+		{
+			Name:           "no padding",
+			Padding:        PadNone,
+			Start:          writerStatus{currByte: 0x40, currBitIndex: 5}, // 01xx xxxx
+			Expected:       nil,
+			ExpectedError:  ErrNotAligned,
+			ExpectedClosed: false,
+		},
+		{
+			Name:           "aligned",
+			Padding:        PadNone,
+			Start:          writerStatus{currByte: 0x00, currBitIndex: 7},
+			Expected:       nil,
+			ExpectedClosed: true,
+		},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			dst := &closeRecorder{}
+			bw := NewWriter(dst)
+			bw.SetPaddingPolicy(data.Padding)
+
+			bw.currByte[0] = data.Start.currByte
+			bw.currBitIndex = data.Start.currBitIndex
+
+			err := bw.Close()
+			if !errors.Is(err, data.ExpectedError) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedError, err)
+			}
+			if !bytes.Equal(data.Expected, dst.Bytes()) {
+				t.Fatalf("\nunexpected buf\nExpected: %+v\nActual:   %+v\n", data.Expected, dst.Bytes())
+			}
+			if data.ExpectedClosed != dst.closed {
+				t.Fatalf("\nunexpected closed\nExpected: %+v\nActual:   %+v\n", data.ExpectedClosed, dst.closed)
+			}
+		})
+	}
+}
+
+func TestPaddingPattern(t *testing.T) {
+	testData := []struct {
+		Name     string
+		Pattern  PaddingPattern
+		NBits    uint8 // number of '0' bits written before the padding
+		Expected []byte
+	}{
+		{Name: "HDLC flag", Pattern: PaddingPattern{Bits: 0x7e, NBits: 8}, NBits: 3, Expected: []byte{0x1e}}, // 000 1 1110
+		{Name: "alternating", Pattern: PaddingPattern{Bits: 0x55, NBits: 8}, NBits: 12, Expected: []byte{0x00, 0x05}},
+		{Name: "bit pattern", Pattern: PaddingPattern{Bits: 0x6, NBits: 3}, NBits: 10, Expected: []byte{0x00, 0x2d}}, // offsets 10-15 % 3: 1 2 0 1 2 0 -> 00 101101
+		{Name: "empty pattern", Pattern: PaddingPattern{}, NBits: 1, Expected: []byte{0x00}},
+		{Name: "aligned", Pattern: PaddingPattern{Bits: 0x7e, NBits: 8}, NBits: 8, Expected: []byte{0x00}},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			bw := NewWriterWithOptions(buf, &WriterOptions{Padding: PadPattern, PaddingPattern: data.Pattern})
+			err := bw.WriteNBitsOfUint64BE(data.NBits, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			err = bw.Close()
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if !bytes.Equal(data.Expected, buf.Bytes()) {
+				t.Fatalf("\nExpected: %08b\nActual:   %08b\n", data.Expected, buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestPad(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var traced []uint
+	bw := NewWriterWithOptions(buf, &WriterOptions{TraceHook: func(name string, bitOffset uint64, nBits uint, value any) {
+		traced = append(traced, nBits)
+	}})
+	bw.SetPaddingPattern(PaddingPattern{Bits: 0x7e, NBits: 8})
+
+	// each frame is padded with the flag pattern
+	for _, frame := range []uint8{0x5, 0x3} {
+		err := bw.WriteNBitsOfUint8(3, frame)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+		n, err := bw.Pad()
+		if err != nil || n != 5 {
+			t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 5, nil, n, err)
+		}
+	}
+	n, err := bw.Pad()
+	if err != nil || n != 0 {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0, nil, n, err)
+	}
+	err = bw.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	expected := []byte{0xbe, 0x7e} // 101 1 1110, 011 1 1110
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, buf.Bytes())
+	}
+	if len(traced) != 5 || traced[1] != 5 || traced[4] != 0 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []uint{3, 5, 3, 5, 0}, traced)
+	}
+
+	// PadNone
+	bw = NewWriterWithOptions(&bytes.Buffer{}, &WriterOptions{Padding: PadNone})
+	bw.WriteBit(1)
+	_, err = bw.Pad()
+	if !errors.Is(err, ErrNotAligned) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrNotAligned, err)
+	}
+}
+
+func TestCloseNotCloser(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	bw := NewWriter(buf)
+	bw.WriteNBitsOfUint8(4, 0x0a)
+	err := bw.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if !bytes.Equal([]byte{0xa0}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xa0}, buf.Bytes())
+	}
+}
+
+func TestFlushInTheMiddle(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	bw := NewWriter(buf)
+
+	bw.WriteNBitsOfUint16BE(12, 0xabc)
+	err := bw.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	// the partial byte is kept
+	if !bytes.Equal([]byte{0xab}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xab}, buf.Bytes())
+	}
+
+	bw.WriteNBitsOfUint8(6, 0x3f)
+	err = bw.Finalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	// 1010 1011 | 1100 1111 | 11xx xxxx
+	if !bytes.Equal([]byte{0xab, 0xcf, 0xc0}, buf.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", []byte{0xab, 0xcf, 0xc0}, buf.Bytes())
+	}
+
+	// nothing is written when the bit stream is byte aligned
+	bw.WriteNBitsOfUint8(6, 0x00)
+	bw.Finalize()
+	bw.Finalize()
+	if buf.Len() != 4 {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 4, buf.Len())
+	}
+}
+
+func TestStrictValues(t *testing.T) {
+	testData := []struct {
+		Name        string
+		Strict      bool
+		Write       func(w *Writer) error
+		ExpectedErr error
+	}{
+		{Name: "pattern 1", Strict: true, Write: func(w *Writer) error { return w.WriteNBitsOfUint8(3, 0x07) }, ExpectedErr: nil},
+		{Name: "pattern 2", Strict: true, Write: func(w *Writer) error { return w.WriteNBitsOfUint8(3, 0x08) }, ExpectedErr: ErrValueOutOfRange},
+		{Name: "pattern 3", Strict: false, Write: func(w *Writer) error { return w.WriteNBitsOfUint8(3, 0x08) }, ExpectedErr: nil},
+		{Name: "pattern 4", Strict: true, Write: func(w *Writer) error { return w.WriteNBitsOfUint8(9, 0x08) }, ExpectedErr: ErrTooManyBits},
+		{Name: "pattern 5", Strict: true, Write: func(w *Writer) error { return w.WriteNBitsOfUint16BE(12, 0x0fff) }, ExpectedErr: nil},
+		{Name: "pattern 6", Strict: true, Write: func(w *Writer) error { return w.WriteNBitsOfUint16BE(12, 0x1000) }, ExpectedErr: ErrValueOutOfRange},
+		{Name: "pattern 7", Strict: true, Write: func(w *Writer) error { return w.WriteUint32BE(0xffffffff) }, ExpectedErr: nil},
+		{Name: "pattern 8", Strict: true, Write: func(w *Writer) error { return w.WriteNBitsOfUint32BE(0, 1) }, ExpectedErr: ErrValueOutOfRange},
+		{Name: "pattern 9", Strict: true, Write: func(w *Writer) error { return w.WriteRun(1, 3) }, ExpectedErr: nil},
+		{Name: "pattern 10", Strict: true, Write: func(w *Writer) error { return w.WriteNibble(0x0f) }, ExpectedErr: nil},
+		{Name: "pattern 11", Strict: true, Write: func(w *Writer) error { return w.WriteNibble(0x10) }, ExpectedErr: ErrValueOutOfRange},
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			bw := NewWriterWithOptions(&bytes.Buffer{}, &WriterOptions{StrictValues: data.Strict})
+			if data.Strict != bw.StrictValues() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.Strict, bw.StrictValues())
+			}
+			err := data.Write(bw)
+			if !errors.Is(err, data.ExpectedErr) {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedErr, err)
+			}
+			if err != nil && bw.WrittenBits() != 0 {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 0, bw.WrittenBits())
+			}
+		})
+	}
+}
+
+func TestPendingBits(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	bw := NewWriter(buf)
+
+	if bw.PendingBits() != 0 || !bw.IsByteAligned() {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0, true, bw.PendingBits(), bw.IsByteAligned())
+	}
+
+	testData := []struct {
+		Name            string
+		NBits           uint8
+		ExpectedPending uint8
+		ExpectedWritten uint64
+	}{
+		{Name: "pattern 1", NBits: 3, ExpectedPending: 3, ExpectedWritten: 3},  // xxx. ....
+		{Name: "pattern 2", NBits: 5, ExpectedPending: 0, ExpectedWritten: 8},  // xxxx xxxx
+		{Name: "pattern 3", NBits: 7, ExpectedPending: 7, ExpectedWritten: 15}, // xxxx xxx.
+		{Name: "pattern 4", NBits: 8, ExpectedPending: 7, ExpectedWritten: 23}, // x | xxxx xxx.
+		{Name: "pattern 5", NBits: 9, ExpectedPending: 0, ExpectedWritten: 32}, // x | xxxx xxxx
+	}
+
+	for _, data := range testData {
+		data := data // capture
+		t.Run(data.Name, func(t *testing.T) {
+			err := bw.WriteNBitsOfUint16BE(data.NBits, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v\n", err)
+			}
+			if data.ExpectedPending != bw.PendingBits() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedPending, bw.PendingBits())
+			}
+			if (data.ExpectedPending == 0) != bw.IsByteAligned() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedPending == 0, bw.IsByteAligned())
+			}
+			if data.ExpectedWritten != bw.WrittenBits() {
+				t.Fatalf("\nExpected: %+v\nActual:   %+v\n", data.ExpectedWritten, bw.WrittenBits())
+			}
+		})
+	}
+}
+
+func TestWrittenBitsOnError(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	bw := NewWriter(buf)
+
+	bw.WriteNBitsOfUint8(3, 0x07)
+	err := bw.WriteNBitsOfUint8(9, 0x00)
+	if !errors.Is(err, ErrTooManyBits) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrTooManyBits, err)
+	}
+	if uint64(3) != bw.WrittenBits() {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 3, bw.WrittenBits())
+	}
+
+	// only the bits placed before the write error are counted
+	ew := NewWriter(errWriter{err: io.ErrClosedPipe})
+	ew.WriteNBitsOfUint8(4, 0x0f)
+	err = ew.WriteNBitsOfUint16BE(12, 0x0fff)
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", io.ErrClosedPipe, err)
+	}
+	if uint64(8) != ew.WrittenBits() {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", 8, ew.WrittenBits())
+	}
+}
+
+func TestBufferedWriter(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+
+	for _, bufSize := range []uint{1, 2, 3, 7, 64, 0} {
+		expected := bytes.NewBuffer([]byte{})
+		ew := NewWriter(expected)
+
+		actual := bytes.NewBuffer([]byte{})
+		aw := NewWriterWithOptions(actual, &WriterOptions{BufferSize: bufSize})
+
+		for i := 0; i < 1000; i++ {
+			op := rand.Intn(5)
+			n := rand.Intn(17)
+			v := uint16(rand.Intn(0x10000))
+			p := make([]byte, rand.Intn(3*cap(aw.buf)+2))
+			rand.Read(p)
+			for _, w := range []*Writer{ew, aw} {
+				var err error
+				switch op {
+				case 0:
+					err = w.WriteNBitsOfUint16BE(uint8(n), v)
+				case 1:
+					err = w.WriteRun(uint8(v), uint64(v%100))
+				case 2:
+					err = w.WriteBytes(p)
+				case 3:
+					err = w.WriteBit(uint8(v))
+				case 4:
+					err = w.Flush()
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %+v\n", err)
+				}
+			}
+
+			// the complete bytes are written to the destination by Flush
+			if op == 4 && !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+				t.Fatalf("buffer size %d: unexpected data after Flush\n", bufSize)
+			}
+		}
+
+		ew.Finalize()
+		aw.Finalize()
+		if !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+			t.Fatalf("buffer size %d: unexpected data\n", bufSize)
+		}
+		if ew.WrittenBits() != aw.WrittenBits() {
+			t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ew.WrittenBits(), aw.WrittenBits())
+		}
+	}
+}
+
+func TestWriterWithBuffer(t *testing.T) {
+	buf := make([]byte, 0, 4) // BufferSize is ignored in favor of the capacity of Buffer
+	actual := bytes.NewBuffer([]byte{})
+	w := NewWriterWithOptions(actual, &WriterOptions{BufferSize: 1024, Buffer: buf[:2]})
+
+	for i := 0; i < 3; i++ {
+		err := w.WriteUint8(uint8(i + 1))
+		if err != nil {
+			t.Fatalf("unexpected error: %+v\n", err)
+		}
+	}
+	// the complete bytes are kept in the given buffer
+	if actual.Len() != 0 || !bytes.Equal([]byte{0x01, 0x02, 0x03}, buf[:3]) {
+		t.Fatalf("\nExpected: %+v, %+v\nActual:   %+v, %+v\n", 0, []byte{0x01, 0x02, 0x03}, actual.Len(), buf[:3])
+	}
+
+	err := w.WriteUint8(0x04)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	if expected := []byte{0x01, 0x02, 0x03, 0x04}; !bytes.Equal(expected, actual.Bytes()) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", expected, actual.Bytes())
+	}
+	if &w.buf[:1][0] != &buf[:1][0] {
+		t.Fatalf("the given buffer is not used\n")
+	}
+}
+
+func TestBufferedWriterShortWrite(t *testing.T) {
+	bw := NewWriterWithOptions(shortWriter{}, nil)
+	err := bw.WriteUint16BE(0xabcd)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v\n", err)
+	}
+	err = bw.Flush()
+	if !errors.Is(err, ErrShortWrite) {
+		t.Fatalf("\nExpected: %+v\nActual:   %+v\n", ErrShortWrite, err)
+	}
+}
+
+func benchmarkWriterToFile(b *testing.B, opt *WriterOptions) {
+	f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Skip(err)
+	}
+	defer f.Close()
+
+	bw := NewWriter(f)
+	if opt != nil {
+		bw = NewWriterWithOptions(f, opt)
+	}
+	b.SetBytes(13) // 8 fields of 13 bits
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 8; j++ {
+			bw.WriteNBitsOfUint16BE(13, uint16(i))
+		}
+	}
+	bw.Finalize()
+}
+
+func BenchmarkUnbufferedWriterToFile(b *testing.B) {
+	benchmarkWriterToFile(b, nil)
+}
+
+func BenchmarkBufferedWriterToFile(b *testing.B) {
+	benchmarkWriterToFile(b, &WriterOptions{})
+}